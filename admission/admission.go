@@ -0,0 +1,175 @@
+// Package admission calls an external webhook with the changes of each write before merlin commits them, so
+// central policy engines can allow, deny or mutate them.
+//
+// The webhook is POSTed a JSON review of the changes, each encoded as a types.Change:
+//
+//	{"dry_run": false, "changes": [{"action": "CREATE", "service": {...}}]}
+//
+// and responds with whether they're allowed, why, and optionally the changes to make instead:
+//
+//	{"allowed": true, "reason": "", "changes": [...]}
+package admission
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/jsonpb"
+	log "github.com/sirupsen/logrus"
+	"github.com/sky-uk/merlin/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxResponseSize of a webhook response.
+const maxResponseSize = 4 << 20
+
+// Review is sent to the webhook.
+type Review struct {
+	// DryRun is true if the changes won't be committed even if they're allowed.
+	DryRun  bool              `json:"dry_run"`
+	Changes []json.RawMessage `json:"changes"`
+}
+
+// Response of the webhook.
+type Response struct {
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason,omitempty"`
+	// Changes replace the reviewed changes if set, which must have the same actions and IDs.
+	Changes []json.RawMessage `json:"changes,omitempty"`
+}
+
+// Webhook calls an admission endpoint.
+type Webhook struct {
+	url      string
+	client   *http.Client
+	failOpen bool
+}
+
+// NewWebhook calls the http or https url, waiting up to timeout for it to respond. If caFile is set, https urls
+// are verified with its certificates instead of the system's. If failOpen is true, changes are allowed when the
+// webhook can't be called, instead of denied.
+func NewWebhook(url, caFile string, timeout time.Duration, failOpen bool) (*Webhook, error) {
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return nil, fmt.Errorf("webhook url %q must be http or https", url)
+	}
+	transport := &http.Transport{}
+	if caFile != "" {
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read CA certificate: %v", err)
+		}
+		config := &tls.Config{RootCAs: x509.NewCertPool()}
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", caFile)
+		}
+		transport.TLSClientConfig = config
+	}
+	return &Webhook{
+		url:      url,
+		client:   &http.Client{Transport: transport, Timeout: timeout},
+		failOpen: failOpen,
+	}, nil
+}
+
+// Admit returns the changes to make, which are the given changes unless the webhook mutates them. It returns a
+// PermissionDenied status error if the webhook denies them, or an Unavailable error if it can't be called and the
+// webhook doesn't fail open.
+func (w *Webhook) Admit(ctx context.Context, changes []*types.Change, dryRun bool) ([]*types.Change, error) {
+	resp, err := w.call(ctx, changes, dryRun)
+	if err != nil {
+		if w.failOpen {
+			log.Warnf("Allowing %d changes, as the admission webhook failed: %v", len(changes), err)
+			return changes, nil
+		}
+		return nil, status.Errorf(codes.Unavailable, "admission webhook failed: %v", err)
+	}
+	if !resp.Allowed {
+		reason := resp.Reason
+		if reason == "" {
+			reason = "no reason given"
+		}
+		return nil, status.Errorf(codes.PermissionDenied, "denied by admission webhook: %s", reason)
+	}
+	if len(resp.Changes) == 0 {
+		return changes, nil
+	}
+
+	mutated := make([]*types.Change, len(resp.Changes))
+	for i, raw := range resp.Changes {
+		mutated[i] = &types.Change{}
+		if err := jsonpb.Unmarshal(bytes.NewReader(raw), mutated[i]); err != nil {
+			return nil, status.Errorf(codes.Internal, "admission webhook returned an invalid change: %v", err)
+		}
+	}
+	if err := checkMutated(changes, mutated); err != nil {
+		return nil, status.Errorf(codes.Internal, "admission webhook returned invalid changes: %v", err)
+	}
+	return mutated, nil
+}
+
+func (w *Webhook) call(ctx context.Context, changes []*types.Change, dryRun bool) (*Response, error) {
+	review := Review{DryRun: dryRun, Changes: []json.RawMessage{}}
+	var m jsonpb.Marshaler
+	for _, change := range changes {
+		s, err := m.MarshalToString(change)
+		if err != nil {
+			return nil, err
+		}
+		review.Changes = append(review.Changes, json.RawMessage(s))
+	}
+	body, err := json.Marshal(review)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	httpResp, err := w.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+	data, err := ioutil.ReadAll(io.LimitReader(httpResp.Body, maxResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read response: %v", err)
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", httpResp.Status, strings.TrimSpace(string(data)))
+	}
+	var resp Response
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("unable to decode response: %v", err)
+	}
+	return &resp, nil
+}
+
+// checkMutated returns an error unless the mutated changes have the same actions and IDs as the reviewed ones, so
+// a webhook can only change what's being done to each service and server.
+func checkMutated(changes, mutated []*types.Change) error {
+	if len(mutated) != len(changes) {
+		return fmt.Errorf("%d changes were reviewed, but %d returned", len(changes), len(mutated))
+	}
+	for i, change := range changes {
+		m := mutated[i]
+		if m.Action != change.Action || m.Service.GetId() != change.Service.GetId() ||
+			m.Server.GetServiceID() != change.Server.GetServiceID() ||
+			m.Server.GetKey().PrettyString() != change.Server.GetKey().PrettyString() ||
+			(m.Service == nil) != (change.Service == nil) || (m.Server == nil) != (change.Server == nil) {
+			return fmt.Errorf("change %d must have the same action and ID as the reviewed change", i+1)
+		}
+	}
+	return nil
+}
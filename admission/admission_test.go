@@ -0,0 +1,120 @@
+package admission
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/wrappers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sky-uk/merlin/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestAdmission(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Admission Suite")
+}
+
+var _ = Describe("Webhook", func() {
+	var (
+		ctx      = context.Background()
+		ts       *httptest.Server
+		reviews  []Review
+		response string
+		changes  []*types.Change
+	)
+
+	BeforeEach(func() {
+		reviews = nil
+		ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var review Review
+			Expect(json.NewDecoder(r.Body).Decode(&review)).To(Succeed())
+			reviews = append(reviews, review)
+			w.Write([]byte(response))
+		}))
+		changes = []*types.Change{{
+			Action: types.Change_CREATE,
+			Server: &types.RealServer{
+				ServiceID: "svc",
+				Key:       &types.RealServer_Key{Ip: "172.16.1.1", Port: 8080},
+				Config:    &types.RealServer_Config{Weight: &wrappers.UInt32Value{Value: 1}},
+			},
+		}}
+	})
+
+	AfterEach(func() {
+		ts.Close()
+	})
+
+	admit := func(failOpen bool) ([]*types.Change, error) {
+		webhook, err := NewWebhook(ts.URL, "", time.Second, failOpen)
+		Expect(err).ToNot(HaveOccurred())
+		return webhook.Admit(ctx, changes, true)
+	}
+
+	It("should send the changes and allow them", func() {
+		response = `{"allowed": true}`
+
+		admitted, err := admit(false)
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(admitted).To(Equal(changes))
+		Expect(reviews).To(HaveLen(1))
+		Expect(reviews[0].DryRun).To(BeTrue())
+		Expect(reviews[0].Changes).To(HaveLen(1))
+		Expect(string(reviews[0].Changes[0])).To(ContainSubstring(`"action":"CREATE"`))
+	})
+
+	It("should deny changes with the reason", func() {
+		response = `{"allowed": false, "reason": "weights must be 10"}`
+
+		_, err := admit(false)
+
+		Expect(status.Code(err)).To(Equal(codes.PermissionDenied))
+		Expect(status.Convert(err).Message()).To(ContainSubstring("weights must be 10"))
+	})
+
+	It("should return mutated changes", func() {
+		response = `{"allowed": true, "changes": [{"action": "CREATE", "server": {"serviceID": "svc",
+			"key": {"ip": "172.16.1.1", "port": 8080}, "config": {"weight": 10}}}]}`
+
+		admitted, err := admit(false)
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(admitted).To(HaveLen(1))
+		Expect(admitted[0].Server.Config.Weight.GetValue()).To(Equal(uint32(10)))
+	})
+
+	It("should reject mutations of the action or ID", func() {
+		response = `{"allowed": true, "changes": [{"action": "CREATE", "server": {"serviceID": "other",
+			"key": {"ip": "172.16.1.1", "port": 8080}}}]}`
+
+		_, err := admit(false)
+
+		Expect(status.Code(err)).To(Equal(codes.Internal))
+		Expect(status.Convert(err).Message()).To(ContainSubstring("same action and ID"))
+	})
+
+	It("should deny changes if the webhook fails, unless failing open", func() {
+		ts.Close()
+
+		_, err := admit(false)
+		Expect(status.Code(err)).To(Equal(codes.Unavailable))
+
+		admitted, err := admit(true)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(admitted).To(Equal(changes))
+	})
+
+	It("should refuse urls which aren't http", func() {
+		_, err := NewWebhook("ftp://policy", "", time.Second, false)
+
+		Expect(err).To(MatchError(ContainSubstring("must be http or https")))
+	})
+})
@@ -0,0 +1,156 @@
+// Package backup writes and verifies archives of the services and servers in the store, for disaster recovery.
+// An archive is a gzipped tar of a manifest and the snapshot, so it can be checked without restoring it.
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/sky-uk/merlin/types"
+	"github.com/sky-uk/merlin/validation"
+	"google.golang.org/grpc/status"
+)
+
+// SchemaVersion of the archives written, which is bumped when the layout changes incompatibly.
+const SchemaVersion = 1
+
+const (
+	manifestName = "manifest.json"
+	snapshotName = "snapshot.pb"
+)
+
+// Manifest describes the snapshot in an archive.
+type Manifest struct {
+	SchemaVersion int `json:"schema_version"`
+	// APIVersion of the merlin which wrote the archive.
+	APIVersion string    `json:"api_version"`
+	Created    time.Time `json:"created"`
+	Services   int       `json:"services"`
+	Servers    int       `json:"servers"`
+	// SHA256 of the snapshot, hex encoded.
+	SHA256 string `json:"sha256"`
+}
+
+// Write an archive of the snapshot to w.
+func Write(w io.Writer, snapshot *types.Snapshot, created time.Time) error {
+	data, err := proto.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("unable to encode snapshot: %v", err)
+	}
+	sum := sha256.Sum256(data)
+	manifest, err := json.MarshalIndent(&Manifest{
+		SchemaVersion: SchemaVersion,
+		APIVersion:    types.APIVersion,
+		Created:       created.UTC(),
+		Services:      len(snapshot.Services),
+		Servers:       len(snapshot.Servers),
+		SHA256:        hex.EncodeToString(sum[:]),
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to encode manifest: %v", err)
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	for _, file := range []struct {
+		name string
+		data []byte
+	}{{manifestName, manifest}, {snapshotName, data}} {
+		hdr := &tar.Header{Name: file.name, Mode: 0644, Size: int64(len(file.data)), ModTime: created}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(file.data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// Read an archive, checking its integrity and schema version.
+func Read(r io.Reader) (*Manifest, *types.Snapshot, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("not a backup archive: %v", err)
+	}
+	files := make(map[string][]byte)
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("archive is corrupt: %v", err)
+		}
+		if files[hdr.Name], err = ioutil.ReadAll(tr); err != nil {
+			return nil, nil, fmt.Errorf("archive is corrupt: %v", err)
+		}
+	}
+
+	data, ok := files[manifestName]
+	if !ok {
+		return nil, nil, fmt.Errorf("archive has no %s", manifestName)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, nil, fmt.Errorf("unable to decode %s: %v", manifestName, err)
+	}
+	if manifest.SchemaVersion != SchemaVersion {
+		return nil, nil, fmt.Errorf("unsupported schema version %d, must be %d", manifest.SchemaVersion,
+			SchemaVersion)
+	}
+	if err := types.CheckAPIVersion(manifest.APIVersion); err != nil {
+		return nil, nil, err
+	}
+
+	if data, ok = files[snapshotName]; !ok {
+		return nil, nil, fmt.Errorf("archive has no %s", snapshotName)
+	}
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != manifest.SHA256 {
+		return nil, nil, fmt.Errorf("checksum of %s doesn't match the manifest, the archive is corrupt", snapshotName)
+	}
+	var snapshot types.Snapshot
+	if err := proto.Unmarshal(data, &snapshot); err != nil {
+		return nil, nil, fmt.Errorf("unable to decode %s: %v", snapshotName, err)
+	}
+	if len(snapshot.Services) != manifest.Services || len(snapshot.Servers) != manifest.Servers {
+		return nil, nil, fmt.Errorf("archive has %d services and %d servers, but the manifest has %d and %d",
+			len(snapshot.Services), len(snapshot.Servers), manifest.Services, manifest.Servers)
+	}
+	return &manifest, &snapshot, nil
+}
+
+// Verify reads an archive, and checks the snapshot is consistent: every service and server is valid and unique,
+// every server's service exists, and no two services have the same key.
+func Verify(r io.Reader) (*Manifest, error) {
+	manifest, snapshot, err := Read(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := validation.Snapshot(snapshot, nil); err != nil {
+		return nil, fmt.Errorf("snapshot is inconsistent: %s", status.Convert(err).Message())
+	}
+	keys := make(map[string]string)
+	for _, svc := range snapshot.Services {
+		key := svc.Key.PrettyString()
+		if id, ok := keys[key]; ok {
+			return nil, fmt.Errorf("snapshot is inconsistent: services %s and %s have the same key %s", id, svc.Id,
+				key)
+		}
+		keys[key] = svc.Id
+	}
+	return manifest, nil
+}
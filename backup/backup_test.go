@@ -0,0 +1,135 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/wrappers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sky-uk/merlin/types"
+)
+
+func TestBackup(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Backup Suite")
+}
+
+var _ = Describe("Backup", func() {
+	var snapshot *types.Snapshot
+
+	write := func() []byte {
+		var b bytes.Buffer
+		Expect(Write(&b, snapshot, time.Now())).To(Succeed())
+		return b.Bytes()
+	}
+
+	// rewrite the archive, replacing the contents of files by name
+	rewrite := func(archive []byte, replace map[string][]byte) []byte {
+		gz, err := gzip.NewReader(bytes.NewReader(archive))
+		Expect(err).ToNot(HaveOccurred())
+		tr := tar.NewReader(gz)
+		var b bytes.Buffer
+		out := gzip.NewWriter(&b)
+		tw := tar.NewWriter(out)
+		for {
+			hdr, err := tr.Next()
+			if err != nil {
+				break
+			}
+			var data bytes.Buffer
+			data.ReadFrom(tr)
+			if r, ok := replace[hdr.Name]; ok {
+				data.Reset()
+				data.Write(r)
+			}
+			hdr.Size = int64(data.Len())
+			Expect(tw.WriteHeader(hdr)).To(Succeed())
+			tw.Write(data.Bytes())
+		}
+		tw.Close()
+		out.Close()
+		return b.Bytes()
+	}
+
+	BeforeEach(func() {
+		snapshot = &types.Snapshot{
+			Services: []*types.VirtualService{{
+				Id:     "service1",
+				Key:    &types.VirtualService_Key{Ip: "10.1.1.1", Port: 80, Protocol: types.Protocol_TCP},
+				Config: &types.VirtualService_Config{Scheduler: "sh"},
+			}},
+			Servers: []*types.RealServer{{
+				ServiceID: "service1",
+				Key:       &types.RealServer_Key{Ip: "172.16.1.1", Port: 8080},
+				Config: &types.RealServer_Config{
+					Weight:  &wrappers.UInt32Value{Value: 1},
+					Forward: types.ForwardMethod_MASQ,
+				},
+			}},
+		}
+	})
+
+	It("should verify an archive it wrote", func() {
+		manifest, err := Verify(bytes.NewReader(write()))
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(manifest.SchemaVersion).To(Equal(SchemaVersion))
+		Expect(manifest.APIVersion).To(Equal(types.APIVersion))
+		Expect(manifest.Services).To(Equal(1))
+		Expect(manifest.Servers).To(Equal(1))
+	})
+
+	It("should reject files which aren't archives", func() {
+		_, err := Verify(bytes.NewReader([]byte("services: []")))
+
+		Expect(err).To(MatchError(ContainSubstring("not a backup archive")))
+	})
+
+	It("should reject truncated archives", func() {
+		archive := write()
+
+		_, err := Verify(bytes.NewReader(archive[:len(archive)/2]))
+
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should reject snapshots which don't match the checksum", func() {
+		archive := rewrite(write(), map[string][]byte{snapshotName: []byte("corrupt")})
+
+		_, err := Verify(bytes.NewReader(archive))
+
+		Expect(err).To(MatchError(ContainSubstring("checksum")))
+	})
+
+	It("should reject unsupported schema versions", func() {
+		manifest, _ := json.Marshal(&Manifest{SchemaVersion: SchemaVersion + 1, APIVersion: types.APIVersion})
+		archive := rewrite(write(), map[string][]byte{manifestName: manifest})
+
+		_, err := Verify(bytes.NewReader(archive))
+
+		Expect(err).To(MatchError(ContainSubstring("unsupported schema version")))
+	})
+
+	It("should reject orphan servers", func() {
+		snapshot.Servers[0].ServiceID = "service2"
+
+		_, err := Verify(bytes.NewReader(write()))
+
+		Expect(err).To(MatchError(ContainSubstring("doesn't exist")))
+	})
+
+	It("should reject services with duplicate keys", func() {
+		duplicate := *snapshot.Services[0]
+		duplicate.Id = "service2"
+		snapshot.Services = append(snapshot.Services, &duplicate)
+
+		_, err := Verify(bytes.NewReader(write()))
+
+		Expect(err).To(MatchError(ContainSubstring("have the same key")))
+	})
+})
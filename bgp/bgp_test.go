@@ -0,0 +1,226 @@
+package bgp
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestBGP(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "BGP Suite")
+}
+
+// parsedUpdate is a parsed UPDATE message.
+type parsedUpdate struct {
+	withdrawn []byte
+	attrs     map[byte][]byte
+	nlri      []byte
+}
+
+func parseUpdate(body []byte) parsedUpdate {
+	u := parsedUpdate{attrs: make(map[byte][]byte)}
+	l := int(binary.BigEndian.Uint16(body))
+	u.withdrawn, body = body[2:2+l], body[2+l:]
+	l = int(binary.BigEndian.Uint16(body))
+	attrs := body[2 : 2+l]
+	u.nlri = body[2+l:]
+	for len(attrs) > 0 {
+		flags, typ := attrs[0], attrs[1]
+		var vl, hl int
+		if flags&attrFlagExtended != 0 {
+			vl, hl = int(binary.BigEndian.Uint16(attrs[2:4])), 4
+		} else {
+			vl, hl = int(attrs[2]), 3
+		}
+		u.attrs[typ] = attrs[hl : hl+vl]
+		attrs = attrs[hl+vl:]
+	}
+	return u
+}
+
+// fakePeer accepts a session, sending the OPEN and UPDATE messages it receives on opens and updates.
+type fakePeer struct {
+	lis     net.Listener
+	as      uint32
+	opens   chan *open
+	updates chan parsedUpdate
+	done    chan struct{}
+}
+
+func newFakePeer(as uint32) *fakePeer {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	Expect(err).ToNot(HaveOccurred())
+	p := &fakePeer{
+		lis:     lis,
+		as:      as,
+		opens:   make(chan *open, 1),
+		updates: make(chan parsedUpdate, 10),
+		done:    make(chan struct{}),
+	}
+	go p.serve()
+	return p
+}
+
+// serve a session until it's closed, which the test fails on if the handshake doesn't finish.
+func (p *fakePeer) serve() {
+	defer close(p.done)
+	conn, err := p.lis.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	body, err := expectMessage(conn, msgOpen)
+	if err != nil {
+		return
+	}
+	o, err := parseOpen(body)
+	if err != nil {
+		return
+	}
+	p.opens <- o
+	if write(conn, openMessage(p.as, 30, net.ParseIP("10.0.0.254"))) != nil || write(conn, keepaliveMessage()) != nil {
+		return
+	}
+	for {
+		typ, body, err := readMessage(conn)
+		if err != nil {
+			return
+		}
+		if typ == msgUpdate {
+			p.updates <- parseUpdate(body)
+		}
+	}
+}
+
+func (p *fakePeer) nextUpdate() parsedUpdate {
+	var u parsedUpdate
+	Eventually(p.updates, 5*time.Second).Should(Receive(&u))
+	return u
+}
+
+var _ = Describe("Speaker", func() {
+	var (
+		peer   *fakePeer
+		config Config
+	)
+
+	BeforeEach(func() {
+		peer = newFakePeer(65000)
+		config = Config{
+			AS:       65001,
+			RouterID: net.ParseIP("10.0.0.1"),
+			Peers:    []Peer{{Address: peer.lis.Addr().String(), AS: 65000}},
+		}
+	})
+
+	AfterEach(func() {
+		peer.lis.Close()
+		Eventually(peer.done).Should(BeClosed())
+	})
+
+	It("should announce and withdraw host routes of IPv4 VIPs", func() {
+		s, err := New(config)
+		Expect(err).ToNot(HaveOccurred())
+		defer s.Close()
+
+		s.Announce([]net.IP{net.ParseIP("10.10.10.10")})
+
+		u := peer.nextUpdate()
+		Expect(u.nlri).To(Equal([]byte{32, 10, 10, 10, 10}))
+		Expect(u.attrs[attrNextHop]).To(Equal([]byte{127, 0, 0, 1}))
+		Expect(u.attrs[attrASPath]).To(Equal([]byte{asSequence, 1, 0, 0, 0xfd, 0xe9}))
+		Expect(u.attrs).ToNot(HaveKey(byte(attrLocalPref)))
+		Eventually(s.Established).Should(Equal(map[string]bool{peer.lis.Addr().String(): true}))
+
+		s.Announce(nil)
+
+		u = peer.nextUpdate()
+		Expect(u.withdrawn).To(Equal([]byte{32, 10, 10, 10, 10}))
+		Expect(u.nlri).To(BeEmpty())
+	})
+
+	It("should announce IPv6 VIPs with multiprotocol extensions", func() {
+		config.NextHopIPv6 = net.ParseIP("2001:db8::1")
+		s, err := New(config)
+		Expect(err).ToNot(HaveOccurred())
+		defer s.Close()
+
+		s.Announce([]net.IP{net.ParseIP("2001:db8::10")})
+
+		u := peer.nextUpdate()
+		reach := u.attrs[attrMPReach]
+		Expect(reach[:4]).To(Equal([]byte{0, afiIPv6, safiUnicast, 16}))
+		Expect(net.IP(reach[4:20]).String()).To(Equal("2001:db8::1"))
+		Expect(reach[21]).To(Equal(byte(128)))
+		Expect(net.IP(reach[22:38]).String()).To(Equal("2001:db8::10"))
+	})
+
+	It("should send the local preference and an empty AS path to iBGP peers", func() {
+		config.AS = 65000
+		s, err := New(config)
+		Expect(err).ToNot(HaveOccurred())
+		defer s.Close()
+
+		s.Announce([]net.IP{net.ParseIP("10.10.10.10")})
+
+		u := peer.nextUpdate()
+		Expect(u.attrs[attrASPath]).To(BeEmpty())
+		Expect(u.attrs[attrLocalPref]).To(Equal([]byte{0, 0, 0, 100}))
+	})
+
+	It("should open with 4 octet AS numbers", func() {
+		config.AS = 4200000000
+		s, err := New(config)
+		Expect(err).ToNot(HaveOccurred())
+		defer s.Close()
+
+		var o *open
+		Eventually(peer.opens).Should(Receive(&o))
+		Expect(o.AS).To(Equal(uint32(4200000000)))
+		Expect(o.FourOctetAS).To(BeTrue())
+		Expect(o.IPv6).To(BeTrue())
+		Expect(o.HoldTime).To(Equal(uint16(90)))
+		Expect(o.RouterID.String()).To(Equal("10.0.0.1"))
+	})
+
+	It("should refuse invalid configs", func() {
+		_, err := New(Config{AS: 65001, RouterID: net.ParseIP("10.0.0.1")})
+		Expect(err).To(MatchError("bgp requires peers"))
+
+		config.RouterID = net.ParseIP("2001:db8::1")
+		_, err = New(config)
+		Expect(err).To(MatchError("bgp router ID must be an IPv4 address"))
+
+		config.RouterID = net.ParseIP("10.0.0.1")
+		config.Peers[0].Address = "router"
+		_, err = New(config)
+		Expect(err).To(MatchError("bgp peer router must be an ip or ip:port"))
+	})
+})
+
+var _ = Describe("Messages", func() {
+	It("should use AS_TRANS in the OPEN of 4 octet AS numbers", func() {
+		body := openMessage(4200000000, 90, net.ParseIP("10.0.0.1"))[headerLen:]
+
+		Expect(binary.BigEndian.Uint16(body[1:3])).To(Equal(uint16(asTrans)))
+	})
+
+	It("should split updates of many prefixes", func() {
+		ips := make([]net.IP, maxIPv4Prefixes+1)
+		for i := range ips {
+			ips[i] = net.IPv4(10, 0, byte(i>>8), byte(i))
+		}
+
+		msgs := updateMessages(route{AS: 65001, NextHop: net.ParseIP("10.0.0.1")}, ips, nil, false)
+
+		Expect(msgs).To(HaveLen(2))
+		for _, msg := range msgs {
+			Expect(len(msg)).To(BeNumerically("<=", maxMsgLen))
+		}
+	})
+})
@@ -0,0 +1,289 @@
+package bgp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+)
+
+// Message values are found in RFC 4271, with capabilities in RFC 4760 and RFC 6793.
+const (
+	headerLen  = 19
+	maxMsgLen  = 4096
+	bgpVersion = 4
+
+	msgOpen         = 1
+	msgUpdate       = 2
+	msgNotification = 3
+	msgKeepalive    = 4
+
+	optParamCapabilities = 2
+	capMultiprotocol     = 1
+	capFourOctetAS       = 65
+	afiIPv4              = 1
+	afiIPv6              = 2
+	safiUnicast          = 1
+	asTrans              = 23456
+
+	attrFlagOptional = 0x80
+	attrFlagTransit  = 0x40
+	attrFlagExtended = 0x10
+	attrOrigin       = 1
+	attrASPath       = 2
+	attrNextHop      = 3
+	attrLocalPref    = 5
+	attrMPReach      = 14
+	attrMPUnreach    = 15
+	originIGP        = 0
+	asSequence       = 2
+	defaultLocalPref = 100
+
+	notifyOpenError        = 2
+	notifyBadPeerAS        = 2
+	notifyUnacceptableHold = 6
+	notifyHoldTimerExpired = 4
+	notifyCease            = 6
+)
+
+// maxPrefixes of each family in an UPDATE keeps it well within maxMsgLen.
+const (
+	maxIPv4Prefixes = 500
+	maxIPv6Prefixes = 200
+)
+
+// open is the OPEN message of a peer.
+type open struct {
+	AS       uint32
+	HoldTime uint16
+	RouterID net.IP
+	// FourOctetAS is true if the peer supports 4 octet AS numbers.
+	FourOctetAS bool
+	// IPv6 is true if the peer supports IPv6 unicast routes.
+	IPv6 bool
+}
+
+func message(typ byte, body []byte) []byte {
+	msg := make([]byte, headerLen, headerLen+len(body))
+	for i := 0; i < 16; i++ {
+		msg[i] = 0xff
+	}
+	binary.BigEndian.PutUint16(msg[16:18], uint16(headerLen+len(body)))
+	msg[18] = typ
+	return append(msg, body...)
+}
+
+// readMessage returns the type and body of the next message.
+func readMessage(r io.Reader) (byte, []byte, error) {
+	header := make([]byte, headerLen)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, nil, err
+	}
+	for _, b := range header[:16] {
+		if b != 0xff {
+			return 0, nil, errors.New("message without a marker")
+		}
+	}
+	l := int(binary.BigEndian.Uint16(header[16:18]))
+	if l < headerLen || l > maxMsgLen {
+		return 0, nil, fmt.Errorf("message with invalid length %d", l)
+	}
+	body := make([]byte, l-headerLen)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+	return header[18], body, nil
+}
+
+// openMessage offers IPv4 and IPv6 unicast routes, and 4 octet AS numbers.
+func openMessage(as uint32, holdTime uint16, routerID net.IP) []byte {
+	caps := append(capability(capMultiprotocol, afiSafi(afiIPv4)), capability(capMultiprotocol, afiSafi(afiIPv6))...)
+	caps = append(caps, capability(capFourOctetAS, uint32Bytes(as))...)
+	body := []byte{bgpVersion}
+	body = append(body, uint16Bytes(uint16(twoOctetAS(as)))...)
+	body = append(body, uint16Bytes(holdTime)...)
+	body = append(body, routerID.To4()...)
+	body = append(body, byte(len(caps)+2), optParamCapabilities, byte(len(caps)))
+	return message(msgOpen, append(body, caps...))
+}
+
+func parseOpen(body []byte) (*open, error) {
+	if len(body) < 10 || body[0] != bgpVersion {
+		return nil, errors.New("unsupported OPEN message")
+	}
+	o := &open{
+		AS:       uint32(binary.BigEndian.Uint16(body[1:3])),
+		HoldTime: binary.BigEndian.Uint16(body[3:5]),
+		RouterID: net.IP(body[5:9]),
+	}
+	params := body[10:]
+	if len(params) < int(body[9]) {
+		return nil, errors.New("truncated OPEN message")
+	}
+	params = params[:body[9]]
+	for len(params) >= 2 {
+		typ, l := params[0], int(params[1])
+		if len(params) < 2+l {
+			return nil, errors.New("truncated OPEN parameter")
+		}
+		if typ == optParamCapabilities {
+			parseCapabilities(o, params[2:2+l])
+		}
+		params = params[2+l:]
+	}
+	return o, nil
+}
+
+func parseCapabilities(o *open, caps []byte) {
+	for len(caps) >= 2 {
+		code, l := caps[0], int(caps[1])
+		if len(caps) < 2+l {
+			return
+		}
+		value := caps[2 : 2+l]
+		switch {
+		case code == capFourOctetAS && l == 4:
+			o.FourOctetAS = true
+			o.AS = binary.BigEndian.Uint32(value)
+		case code == capMultiprotocol && l == 4 && binary.BigEndian.Uint16(value) == afiIPv6 &&
+			value[3] == safiUnicast:
+			o.IPv6 = true
+		}
+		caps = caps[2+l:]
+	}
+}
+
+func notificationMessage(code, subcode byte) []byte {
+	return message(msgNotification, []byte{code, subcode})
+}
+
+func keepaliveMessage() []byte {
+	return message(msgKeepalive, nil)
+}
+
+// route attributes of the UPDATE messages to a peer.
+type route struct {
+	// AS of this speaker, which is prepended to the AS path of eBGP peers.
+	AS uint32
+	// IBGP is true if the peer is in the same AS, so the AS path is empty and the local preference is sent.
+	IBGP bool
+	// FourOctetAS is true if the peer supports 4 octet AS numbers.
+	FourOctetAS bool
+	NextHop     net.IP
+	NextHopIPv6 net.IP
+}
+
+// updateMessages announce and withdraw host routes to the prefixes of a family.
+func updateMessages(r route, announce, withdraw []net.IP, ipv6 bool) [][]byte {
+	batch := maxIPv4Prefixes
+	if ipv6 {
+		batch = maxIPv6Prefixes
+	}
+	var msgs [][]byte
+	for len(withdraw) > 0 {
+		n := min(len(withdraw), batch)
+		if ipv6 {
+			unreach := append(mpFamily(afiIPv6), prefixes(withdraw[:n])...)
+			msgs = append(msgs, update(nil, attribute(attrFlagOptional, attrMPUnreach, unreach), nil))
+		} else {
+			msgs = append(msgs, update(prefixes(withdraw[:n]), nil, nil))
+		}
+		withdraw = withdraw[n:]
+	}
+	for len(announce) > 0 {
+		n := min(len(announce), batch)
+		attrs := r.attributes()
+		if ipv6 {
+			reach := append(mpFamily(afiIPv6), byte(net.IPv6len))
+			reach = append(append(reach, r.NextHopIPv6.To16()...), 0)
+			attrs = append(attrs, attribute(attrFlagOptional, attrMPReach, append(reach, prefixes(announce[:n])...))...)
+			msgs = append(msgs, update(nil, attrs, nil))
+		} else {
+			attrs = append(attrs, attribute(attrFlagTransit, attrNextHop, r.NextHop.To4())...)
+			msgs = append(msgs, update(nil, attrs, prefixes(announce[:n])))
+		}
+		announce = announce[n:]
+	}
+	return msgs
+}
+
+// attributes of every announced route.
+func (r route) attributes() []byte {
+	attrs := attribute(attrFlagTransit, attrOrigin, []byte{originIGP})
+	if r.IBGP {
+		attrs = append(attrs, attribute(attrFlagTransit, attrASPath, nil)...)
+		return append(attrs, attribute(attrFlagTransit, attrLocalPref, uint32Bytes(defaultLocalPref))...)
+	}
+	path := []byte{asSequence, 1}
+	if r.FourOctetAS {
+		path = append(path, uint32Bytes(r.AS)...)
+	} else {
+		path = append(path, uint16Bytes(uint16(twoOctetAS(r.AS)))...)
+	}
+	return append(attrs, attribute(attrFlagTransit, attrASPath, path)...)
+}
+
+func update(withdrawn, attrs, nlri []byte) []byte {
+	body := append(uint16Bytes(uint16(len(withdrawn))), withdrawn...)
+	body = append(append(body, uint16Bytes(uint16(len(attrs)))...), attrs...)
+	return message(msgUpdate, append(body, nlri...))
+}
+
+func attribute(flags, typ byte, value []byte) []byte {
+	return append([]byte{flags | attrFlagExtended, typ}, append(uint16Bytes(uint16(len(value))), value...)...)
+}
+
+// prefixes encodes the host routes of ips.
+func prefixes(ips []net.IP) []byte {
+	var b []byte
+	for _, ip := range ips {
+		if ip4 := ip.To4(); ip4 != nil {
+			b = append(append(b, 8*net.IPv4len), ip4...)
+		} else {
+			b = append(append(b, 8*net.IPv6len), ip.To16()...)
+		}
+	}
+	return b
+}
+
+func capability(code byte, value []byte) []byte {
+	return append([]byte{code, byte(len(value))}, value...)
+}
+
+// afiSafi is the value of a multiprotocol capability.
+func afiSafi(afi uint16) []byte {
+	return append(uint16Bytes(afi), 0, safiUnicast)
+}
+
+// mpFamily starts the value of MP_REACH_NLRI and MP_UNREACH_NLRI attributes.
+func mpFamily(afi uint16) []byte {
+	return append(uint16Bytes(afi), safiUnicast)
+}
+
+// twoOctetAS returns as, or AS_TRANS if it needs 4 octets.
+func twoOctetAS(as uint32) uint32 {
+	if as > 0xffff {
+		return asTrans
+	}
+	return as
+}
+
+func uint16Bytes(v uint16) []byte {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, v)
+	return b
+}
+
+func uint32Bytes(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, v)
+	return b
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
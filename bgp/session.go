@@ -0,0 +1,280 @@
+package bgp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// connectTimeout is how long connecting to a peer can take.
+	connectTimeout = 10 * time.Second
+	// openHoldTime is how long to wait for the peer's OPEN and KEEPALIVE, from RFC 4271.
+	openHoldTime = 4 * time.Minute
+	// writeTimeout is how long sending a message can take.
+	writeTimeout = 10 * time.Second
+)
+
+var (
+	errStopped          = errors.New("speaker closed")
+	errHoldTimerExpired = errors.New("hold timer expired")
+)
+
+// session with a peer.
+type session struct {
+	speaker  *Speaker
+	peer     Peer
+	addr     string
+	updateCh chan struct{}
+
+	mu          sync.Mutex
+	established bool
+}
+
+func (s *session) isEstablished() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.established
+}
+
+func (s *session) setEstablished(established bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.established = established
+}
+
+// run the session until the speaker is closed, reconnecting when it fails.
+func (s *session) run() {
+	defer s.speaker.wg.Done()
+	ctx := s.speaker.ctx
+	for {
+		err := s.connect()
+		s.setEstablished(false)
+		if err == errStopped {
+			return
+		}
+		log.Warnf("BGP session with %s failed, retrying in %v: %v", s.addr, s.speaker.config.ConnectRetry, err)
+		select {
+		case <-time.After(s.speaker.config.ConnectRetry):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// connect to the peer, announcing the routes until the session fails or the speaker is closed.
+func (s *session) connect() error {
+	ctx := s.speaker.ctx
+	config := s.speaker.config
+	dialer := &net.Dialer{Timeout: connectTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		if ctx.Err() != nil {
+			return errStopped
+		}
+		return err
+	}
+	defer conn.Close()
+	// interrupt the handshake if the speaker is closed, after which ctx is selected on instead
+	handshakeDone := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-handshakeDone:
+		}
+	}()
+	peerOpen, err := s.handshake(conn)
+	close(handshakeDone)
+	if err != nil {
+		if ctx.Err() != nil {
+			return errStopped
+		}
+		return err
+	}
+	holdTime := config.HoldTime
+	if peerHold := time.Duration(peerOpen.HoldTime) * time.Second; peerHold < holdTime {
+		holdTime = peerHold
+	}
+	r := route{
+		AS:          config.AS,
+		IBGP:        s.peer.AS == config.AS,
+		FourOctetAS: peerOpen.FourOctetAS,
+		NextHop:     config.NextHop,
+		NextHopIPv6: config.NextHopIPv6,
+	}
+	local := conn.LocalAddr().(*net.TCPAddr).IP
+	if r.NextHop == nil && local.To4() != nil {
+		r.NextHop = local.To4()
+	}
+	if r.NextHopIPv6 == nil && local.To4() == nil {
+		r.NextHopIPv6 = local
+	}
+	s.setEstablished(true)
+	log.Infof("BGP session with %s established, hold time %v", s.addr, holdTime)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- readMessages(conn, holdTime)
+	}()
+	var keepalives <-chan time.Time
+	if holdTime > 0 {
+		ticker := time.NewTicker(holdTime / 3)
+		defer ticker.Stop()
+		keepalives = ticker.C
+	}
+	announced := make(map[string]net.IP)
+	if err := s.update(conn, r, peerOpen.IPv6, announced); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-keepalives:
+			if err := write(conn, keepaliveMessage()); err != nil {
+				return err
+			}
+		case <-s.updateCh:
+			if err := s.update(conn, r, peerOpen.IPv6, announced); err != nil {
+				return err
+			}
+		case err := <-errCh:
+			if err == errHoldTimerExpired {
+				write(conn, notificationMessage(notifyHoldTimerExpired, 0))
+			}
+			return err
+		case <-ctx.Done():
+			write(conn, notificationMessage(notifyCease, 0))
+			return errStopped
+		}
+	}
+}
+
+// handshake exchanges OPEN and KEEPALIVE messages with the peer, returning its OPEN.
+func (s *session) handshake(conn net.Conn) (*open, error) {
+	config := s.speaker.config
+	if err := write(conn, openMessage(config.AS, uint16(config.HoldTime/time.Second), config.RouterID)); err != nil {
+		return nil, err
+	}
+	conn.SetReadDeadline(time.Now().Add(openHoldTime))
+	body, err := expectMessage(conn, msgOpen)
+	if err != nil {
+		return nil, err
+	}
+	peerOpen, err := parseOpen(body)
+	if err != nil {
+		return nil, err
+	}
+	if peerOpen.AS != s.peer.AS {
+		write(conn, notificationMessage(notifyOpenError, notifyBadPeerAS))
+		return nil, fmt.Errorf("peer is AS %d instead of %d", peerOpen.AS, s.peer.AS)
+	}
+	if peerOpen.HoldTime == 1 || peerOpen.HoldTime == 2 {
+		write(conn, notificationMessage(notifyOpenError, notifyUnacceptableHold))
+		return nil, fmt.Errorf("peer proposed an invalid hold time of %ds", peerOpen.HoldTime)
+	}
+	if err := write(conn, keepaliveMessage()); err != nil {
+		return nil, err
+	}
+	if _, err := expectMessage(conn, msgKeepalive); err != nil {
+		return nil, err
+	}
+	return peerOpen, nil
+}
+
+// update the routes announced to the peer to those of the speaker.
+func (s *session) update(conn net.Conn, r route, ipv6 bool, announced map[string]net.IP) error {
+	routes := s.speaker.announced()
+	var announce4, withdraw4, announce6, withdraw6 []net.IP
+	for key, ip := range routes {
+		if _, ok := announced[key]; ok {
+			continue
+		}
+		if ip.To4() == nil && ipv6 && r.NextHopIPv6 != nil {
+			announce6 = append(announce6, ip)
+		} else if ip.To4() != nil && r.NextHop != nil {
+			announce4 = append(announce4, ip)
+		} else {
+			continue
+		}
+		announced[key] = ip
+	}
+	for key, ip := range announced {
+		if _, ok := routes[key]; ok {
+			continue
+		}
+		if ip.To4() == nil {
+			withdraw6 = append(withdraw6, ip)
+		} else {
+			withdraw4 = append(withdraw4, ip)
+		}
+		delete(announced, key)
+	}
+	for _, ips := range [][]net.IP{announce4, withdraw4, announce6, withdraw6} {
+		sort.Slice(ips, func(i, j int) bool { return ips[i].String() < ips[j].String() })
+	}
+	msgs := append(updateMessages(r, announce4, withdraw4, false), updateMessages(r, announce6, withdraw6, true)...)
+	for _, msg := range msgs {
+		if err := write(conn, msg); err != nil {
+			return err
+		}
+	}
+	if len(announce4)+len(announce6) > 0 || len(withdraw4)+len(withdraw6) > 0 {
+		log.Infof("Announced %d and withdrew %d routes to %s", len(announce4)+len(announce6),
+			len(withdraw4)+len(withdraw6), s.addr)
+	}
+	return nil
+}
+
+// readMessages from the peer until the session fails, ignoring the routes it sends.
+func readMessages(conn net.Conn, holdTime time.Duration) error {
+	for {
+		if holdTime > 0 {
+			conn.SetReadDeadline(time.Now().Add(holdTime))
+		} else {
+			conn.SetReadDeadline(time.Time{})
+		}
+		typ, body, err := readMessage(conn)
+		if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+			return errHoldTimerExpired
+		}
+		if err != nil {
+			return err
+		}
+		if typ == msgNotification {
+			return notificationError(body)
+		}
+	}
+}
+
+// expectMessage returns the body of the next message, which must be of type typ.
+func expectMessage(conn net.Conn, typ byte) ([]byte, error) {
+	msgType, body, err := readMessage(conn)
+	if err != nil {
+		return nil, err
+	}
+	if msgType == msgNotification {
+		return nil, notificationError(body)
+	}
+	if msgType != typ {
+		return nil, fmt.Errorf("expected message type %d, got %d", typ, msgType)
+	}
+	return body, nil
+}
+
+func notificationError(body []byte) error {
+	if len(body) < 2 {
+		return errors.New("peer sent a notification")
+	}
+	return fmt.Errorf("peer sent notification code %d subcode %d", body[0], body[1])
+}
+
+func write(conn net.Conn, msg []byte) error {
+	conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	_, err := conn.Write(msg)
+	return err
+}
@@ -0,0 +1,162 @@
+// Package bgp is a minimal BGP speaker which announces VIPs as host routes to upstream routers, so they can spread
+// traffic across merlin nodes with ECMP and stop sending it to a node which withdraws them. It only announces
+// routes, ignoring those its peers send.
+package bgp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Port BGP peers listen on.
+const Port = 179
+
+// Peer is a router the VIPs are announced to.
+type Peer struct {
+	// Address of the peer, as ip or ip:port. The port defaults to Port.
+	Address string
+	// AS of the peer, which is iBGP if it's the AS of the speaker.
+	AS uint32
+}
+
+// Config of a speaker.
+type Config struct {
+	// AS of the speaker.
+	AS uint32
+	// RouterID identifies the speaker to its peers, as an IPv4 address.
+	RouterID net.IP
+	// Peers the VIPs are announced to.
+	Peers []Peer
+	// HoldTime proposed to peers, which close the session if nothing is received for this long, defaults to
+	// 90 seconds.
+	HoldTime time.Duration
+	// NextHop of IPv4 routes, defaults to the local address of each session if it's IPv4.
+	NextHop net.IP
+	// NextHopIPv6 of IPv6 routes, defaults to the local address of each session if it's IPv6. IPv6 routes aren't
+	// announced to peers without one.
+	NextHopIPv6 net.IP
+	// ConnectRetry is how long to wait before reconnecting to a peer, defaults to 10 seconds.
+	ConnectRetry time.Duration
+}
+
+func (c *Config) setDefaults() {
+	if c.HoldTime == 0 {
+		c.HoldTime = 90 * time.Second
+	}
+	if c.ConnectRetry == 0 {
+		c.ConnectRetry = 10 * time.Second
+	}
+}
+
+func (c *Config) validate() error {
+	if c.AS == 0 {
+		return errors.New("bgp requires an AS")
+	}
+	if c.RouterID.To4() == nil {
+		return errors.New("bgp router ID must be an IPv4 address")
+	}
+	if len(c.Peers) == 0 {
+		return errors.New("bgp requires peers")
+	}
+	for _, peer := range c.Peers {
+		if peer.AS == 0 {
+			return fmt.Errorf("bgp peer %s requires an AS", peer.Address)
+		}
+		if net.ParseIP(peerAddress(peer)) == nil {
+			return fmt.Errorf("bgp peer %s must be an ip or ip:port", peer.Address)
+		}
+	}
+	if c.HoldTime < 3*time.Second || c.HoldTime > 0xffff*time.Second {
+		return errors.New("bgp hold time must be from 3 seconds to 18 hours")
+	}
+	if c.NextHop != nil && c.NextHop.To4() == nil {
+		return errors.New("bgp next hop must be an IPv4 address")
+	}
+	if c.NextHopIPv6 != nil && c.NextHopIPv6.To4() != nil {
+		return errors.New("bgp ipv6 next hop must be an IPv6 address")
+	}
+	return nil
+}
+
+// peerAddress returns the ip of the peer's address.
+func peerAddress(peer Peer) string {
+	if host, _, err := net.SplitHostPort(peer.Address); err == nil {
+		return host
+	}
+	return peer.Address
+}
+
+// Speaker announces VIPs to its peers, reconnecting to them while it's open.
+type Speaker struct {
+	config   Config
+	mu       sync.Mutex
+	routes   map[string]net.IP
+	sessions []*session
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// New speaker, which connects to its peers in the background. Call Close() to withdraw its routes and disconnect.
+func New(config Config) (*Speaker, error) {
+	config.setDefaults()
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	s := &Speaker{config: config, routes: make(map[string]net.IP)}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	for _, peer := range config.Peers {
+		addr := peer.Address
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			addr = net.JoinHostPort(addr, strconv.Itoa(Port))
+		}
+		sess := &session{speaker: s, peer: peer, addr: addr, updateCh: make(chan struct{}, 1)}
+		s.sessions = append(s.sessions, sess)
+		s.wg.Add(1)
+		go sess.run()
+	}
+	return s, nil
+}
+
+// Announce host routes to ips, withdrawing those previously announced which aren't in ips.
+func (s *Speaker) Announce(ips []net.IP) {
+	routes := make(map[string]net.IP)
+	for _, ip := range ips {
+		routes[ip.String()] = ip
+	}
+	s.mu.Lock()
+	s.routes = routes
+	s.mu.Unlock()
+	for _, sess := range s.sessions {
+		select {
+		case sess.updateCh <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (s *Speaker) announced() map[string]net.IP {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.routes
+}
+
+// Established returns whether the session with each peer is established, by peer address.
+func (s *Speaker) Established() map[string]bool {
+	established := make(map[string]bool)
+	for _, sess := range s.sessions {
+		established[sess.peer.Address] = sess.isEstablished()
+	}
+	return established
+}
+
+// Close the sessions with every peer, which withdraws the routes.
+func (s *Speaker) Close() {
+	s.cancel()
+	s.wg.Wait()
+}
@@ -0,0 +1,622 @@
+// Package clientfake is an in-memory MerlinClient for unit testing code which uses merlin, without running etcd or
+// a merlin server. Requests are handled by the real server implementation backed by an in-memory store, so
+// validation and errors match merlin's, and every call is recorded for inspection.
+package clientfake
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/sky-uk/merlin/server"
+	"github.com/sky-uk/merlin/store"
+	"github.com/sky-uk/merlin/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// NodeName is the name of the node the fake reports requests are handled by.
+const NodeName = "clientfake"
+
+// Call is a request made to the fake.
+type Call struct {
+	// Method is the name of the RPC, such as CreateService.
+	Method string
+	// Request is a copy of the request.
+	Request proto.Message
+}
+
+// Client is a fake MerlinClient. It's safe for concurrent use.
+type Client struct {
+	store  store.Store
+	server types.MerlinServer
+	mu     sync.Mutex
+	calls  []Call
+	errors map[string]error
+}
+
+var _ types.MerlinClient = &Client{}
+
+// New fake client with an empty store.
+func New() *Client {
+	c := &Client{store: store.NewMemory(), errors: make(map[string]error)}
+	c.server = server.New(c.store, nil, c.node, c.health, c.reconcileErrors, server.Options{})
+	return c
+}
+
+// Store backing the fake, for setting up state without making calls.
+func (c *Client) Store() store.Store {
+	return c.store
+}
+
+// Calls returns every call made, oldest first.
+func (c *Client) Calls() []Call {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Call(nil), c.calls...)
+}
+
+// CallsTo returns the calls made to method, oldest first.
+func (c *Client) CallsTo(method string) []Call {
+	var calls []Call
+	for _, call := range c.Calls() {
+		if call.Method == method {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// Reset forgets the recorded calls and errors, leaving the store as it is.
+func (c *Client) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls = nil
+	c.errors = make(map[string]error)
+}
+
+// SetError makes calls to method fail with err, until it's set to nil. Errors without a grpc status are returned
+// with codes.Internal, as merlin does.
+func (c *Client) SetError(method string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err == nil {
+		delete(c.errors, method)
+		return
+	}
+	if _, ok := status.FromError(err); !ok {
+		err = status.Errorf(codes.Internal, "%v", err)
+	}
+	c.errors[method] = err
+}
+
+// record the call, returning a copy of the request for the server so it can't modify the caller's.
+func (c *Client) record(method string, req proto.Message) (proto.Message, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls = append(c.calls, Call{Method: method, Request: proto.Clone(req)})
+	return proto.Clone(req), c.errors[method]
+}
+
+// result converts errors from the server to grpc statuses, as the server's interceptor does.
+func result(resp proto.Message, err error) (proto.Message, error) {
+	if err == nil {
+		return resp, nil
+	}
+	if _, ok := status.FromError(err); !ok {
+		err = status.Errorf(codes.Internal, "%v", err)
+	}
+	return nil, err
+}
+
+func (c *Client) node() *types.Node {
+	return &types.Node{Name: NodeName}
+}
+
+func (c *Client) health(string, *types.RealServer_Key) types.Health {
+	return types.Health_UNCHECKED
+}
+
+func (c *Client) reconcileErrors(string) []string {
+	return nil
+}
+
+func (c *Client) call(ctx context.Context, method string, in proto.Message,
+	handle func(context.Context, proto.Message) (proto.Message, error)) (proto.Message, error) {
+	req, err := c.record(method, in)
+	if err != nil {
+		return nil, err
+	}
+	return result(handle(ctx, req))
+}
+
+func (c *Client) callEmpty(ctx context.Context, method string, in proto.Message,
+	handle func(context.Context, proto.Message) (*empty.Empty, error)) (*empty.Empty, error) {
+	resp, err := c.call(ctx, method, in, func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return handle(ctx, req)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*empty.Empty), nil
+}
+
+// CreateService fakes MerlinClient.CreateService.
+func (c *Client) CreateService(ctx context.Context, in *types.VirtualService,
+	_ ...grpc.CallOption) (*empty.Empty, error) {
+	return c.callEmpty(ctx, "CreateService", in, func(ctx context.Context, req proto.Message) (*empty.Empty, error) {
+		return c.server.CreateService(ctx, req.(*types.VirtualService))
+	})
+}
+
+// UpdateService fakes MerlinClient.UpdateService.
+func (c *Client) UpdateService(ctx context.Context, in *types.VirtualService,
+	_ ...grpc.CallOption) (*empty.Empty, error) {
+	return c.callEmpty(ctx, "UpdateService", in, func(ctx context.Context, req proto.Message) (*empty.Empty, error) {
+		return c.server.UpdateService(ctx, req.(*types.VirtualService))
+	})
+}
+
+// DeleteService fakes MerlinClient.DeleteService.
+func (c *Client) DeleteService(ctx context.Context, in *wrappers.StringValue,
+	_ ...grpc.CallOption) (*empty.Empty, error) {
+	return c.callEmpty(ctx, "DeleteService", in, func(ctx context.Context, req proto.Message) (*empty.Empty, error) {
+		return c.server.DeleteService(ctx, req.(*wrappers.StringValue))
+	})
+}
+
+// CloneService fakes MerlinClient.CloneService.
+func (c *Client) CloneService(ctx context.Context, in *types.CloneServiceRequest,
+	_ ...grpc.CallOption) (*empty.Empty, error) {
+	return c.callEmpty(ctx, "CloneService", in, func(ctx context.Context, req proto.Message) (*empty.Empty, error) {
+		return c.server.CloneService(ctx, req.(*types.CloneServiceRequest))
+	})
+}
+
+// RenameService fakes MerlinClient.RenameService.
+func (c *Client) RenameService(ctx context.Context, in *types.RenameServiceRequest,
+	_ ...grpc.CallOption) (*empty.Empty, error) {
+	return c.callEmpty(ctx, "RenameService", in, func(ctx context.Context, req proto.Message) (*empty.Empty, error) {
+		return c.server.RenameService(ctx, req.(*types.RenameServiceRequest))
+	})
+}
+
+// SwapServers fakes MerlinClient.SwapServers.
+func (c *Client) SwapServers(ctx context.Context, in *types.SwapServersRequest,
+	_ ...grpc.CallOption) (*empty.Empty, error) {
+	return c.callEmpty(ctx, "SwapServers", in, func(ctx context.Context, req proto.Message) (*empty.Empty, error) {
+		return c.server.SwapServers(ctx, req.(*types.SwapServersRequest))
+	})
+}
+
+// CreateServer fakes MerlinClient.CreateServer.
+func (c *Client) CreateServer(ctx context.Context, in *types.RealServer,
+	_ ...grpc.CallOption) (*empty.Empty, error) {
+	return c.callEmpty(ctx, "CreateServer", in, func(ctx context.Context, req proto.Message) (*empty.Empty, error) {
+		return c.server.CreateServer(ctx, req.(*types.RealServer))
+	})
+}
+
+// UpdateServer fakes MerlinClient.UpdateServer.
+func (c *Client) UpdateServer(ctx context.Context, in *types.RealServer,
+	_ ...grpc.CallOption) (*empty.Empty, error) {
+	return c.callEmpty(ctx, "UpdateServer", in, func(ctx context.Context, req proto.Message) (*empty.Empty, error) {
+		return c.server.UpdateServer(ctx, req.(*types.RealServer))
+	})
+}
+
+// DeleteServer fakes MerlinClient.DeleteServer.
+func (c *Client) DeleteServer(ctx context.Context, in *types.RealServer,
+	_ ...grpc.CallOption) (*empty.Empty, error) {
+	return c.callEmpty(ctx, "DeleteServer", in, func(ctx context.Context, req proto.Message) (*empty.Empty, error) {
+		return c.server.DeleteServer(ctx, req.(*types.RealServer))
+	})
+}
+
+// DrainServer fakes MerlinClient.DrainServer.
+func (c *Client) DrainServer(ctx context.Context, in *types.RealServer,
+	_ ...grpc.CallOption) (*empty.Empty, error) {
+	return c.callEmpty(ctx, "DrainServer", in, func(ctx context.Context, req proto.Message) (*empty.Empty, error) {
+		return c.server.DrainServer(ctx, req.(*types.RealServer))
+	})
+}
+
+// UndrainServer fakes MerlinClient.UndrainServer.
+func (c *Client) UndrainServer(ctx context.Context, in *types.RealServer,
+	_ ...grpc.CallOption) (*empty.Empty, error) {
+	return c.callEmpty(ctx, "UndrainServer", in, func(ctx context.Context, req proto.Message) (*empty.Empty, error) {
+		return c.server.UndrainServer(ctx, req.(*types.RealServer))
+	})
+}
+
+// List fakes MerlinClient.List.
+func (c *Client) List(ctx context.Context, in *types.ListRequest,
+	_ ...grpc.CallOption) (*types.ListResponse, error) {
+	resp, err := c.call(ctx, "List", in, func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return c.server.List(ctx, req.(*types.ListRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*types.ListResponse), nil
+}
+
+// Stats fakes MerlinClient.Stats. The fake has no IPVS, so it always fails with codes.FailedPrecondition.
+func (c *Client) Stats(ctx context.Context, in *empty.Empty, _ ...grpc.CallOption) (*types.StatsResponse, error) {
+	resp, err := c.call(ctx, "Stats", in, func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return c.server.Stats(ctx, req.(*empty.Empty))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*types.StatsResponse), nil
+}
+
+// GetNodeState fakes MerlinClient.GetNodeState. The fake has no IPVS, so it always fails with
+// codes.FailedPrecondition.
+func (c *Client) GetNodeState(ctx context.Context, in *empty.Empty,
+	_ ...grpc.CallOption) (*types.NodeState, error) {
+	resp, err := c.call(ctx, "GetNodeState", in, func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return c.server.GetNodeState(ctx, req.(*empty.Empty))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*types.NodeState), nil
+}
+
+// DescribeService fakes MerlinClient.DescribeService.
+func (c *Client) DescribeService(ctx context.Context, in *types.DescribeServiceRequest,
+	_ ...grpc.CallOption) (*types.DescribeServiceResponse, error) {
+	resp, err := c.call(ctx, "DescribeService", in, func(ctx context.Context, req proto.Message) (proto.Message,
+		error) {
+		return c.server.DescribeService(ctx, req.(*types.DescribeServiceRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*types.DescribeServiceResponse), nil
+}
+
+// GetSnapshot fakes MerlinClient.GetSnapshot.
+func (c *Client) GetSnapshot(ctx context.Context, in *empty.Empty, _ ...grpc.CallOption) (*types.Snapshot, error) {
+	resp, err := c.call(ctx, "GetSnapshot", in, func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return c.server.GetSnapshot(ctx, req.(*empty.Empty))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*types.Snapshot), nil
+}
+
+// ApplySnapshot fakes MerlinClient.ApplySnapshot.
+func (c *Client) ApplySnapshot(ctx context.Context, in *types.ApplySnapshotRequest,
+	_ ...grpc.CallOption) (*types.ApplySnapshotResponse, error) {
+	resp, err := c.call(ctx, "ApplySnapshot", in, func(ctx context.Context, req proto.Message) (proto.Message,
+		error) {
+		return c.server.ApplySnapshot(ctx, req.(*types.ApplySnapshotRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*types.ApplySnapshotResponse), nil
+}
+
+// Info fakes MerlinClient.Info.
+func (c *Client) Info(ctx context.Context, in *empty.Empty, _ ...grpc.CallOption) (*types.InfoResponse, error) {
+	resp, err := c.call(ctx, "Info", in, func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return c.server.Info(ctx, req.(*empty.Empty))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*types.InfoResponse), nil
+}
+
+// ListNodes fakes MerlinClient.ListNodes. No nodes are registered unless put in the store.
+func (c *Client) ListNodes(ctx context.Context, in *empty.Empty,
+	_ ...grpc.CallOption) (*types.ListNodesResponse, error) {
+	resp, err := c.call(ctx, "ListNodes", in, func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return c.server.ListNodes(ctx, req.(*empty.Empty))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*types.ListNodesResponse), nil
+}
+
+// SetMaintenance fakes MerlinClient.SetMaintenance.
+func (c *Client) SetMaintenance(ctx context.Context, in *types.SetMaintenanceRequest,
+	_ ...grpc.CallOption) (*empty.Empty, error) {
+	return c.callEmpty(ctx, "SetMaintenance", in, func(ctx context.Context, req proto.Message) (*empty.Empty, error) {
+		return c.server.SetMaintenance(ctx, req.(*types.SetMaintenanceRequest))
+	})
+}
+
+// RolloutService fakes MerlinClient.RolloutService. Rollouts aren't progressed, as the fake has no nodes to
+// reconcile them.
+func (c *Client) RolloutService(ctx context.Context, in *types.RolloutServiceRequest,
+	_ ...grpc.CallOption) (*empty.Empty, error) {
+	return c.callEmpty(ctx, "RolloutService", in, func(ctx context.Context, req proto.Message) (*empty.Empty, error) {
+		return c.server.RolloutService(ctx, req.(*types.RolloutServiceRequest))
+	})
+}
+
+// PutPool fakes MerlinClient.PutPool.
+func (c *Client) PutPool(ctx context.Context, in *types.VIPPool, _ ...grpc.CallOption) (*empty.Empty, error) {
+	return c.callEmpty(ctx, "PutPool", in, func(ctx context.Context, req proto.Message) (*empty.Empty, error) {
+		return c.server.PutPool(ctx, req.(*types.VIPPool))
+	})
+}
+
+// DeletePool fakes MerlinClient.DeletePool.
+func (c *Client) DeletePool(ctx context.Context, in *wrappers.StringValue,
+	_ ...grpc.CallOption) (*empty.Empty, error) {
+	return c.callEmpty(ctx, "DeletePool", in, func(ctx context.Context, req proto.Message) (*empty.Empty, error) {
+		return c.server.DeletePool(ctx, req.(*wrappers.StringValue))
+	})
+}
+
+// ListPools fakes MerlinClient.ListPools.
+func (c *Client) ListPools(ctx context.Context, in *empty.Empty,
+	_ ...grpc.CallOption) (*types.ListPoolsResponse, error) {
+	resp, err := c.call(ctx, "ListPools", in, func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return c.server.ListPools(ctx, req.(*empty.Empty))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*types.ListPoolsResponse), nil
+}
+
+// GetCandidate fakes MerlinClient.GetCandidate. Requests with the candidate header aren't staged by the fake, so
+// the candidate is only changed through the Store.
+func (c *Client) GetCandidate(ctx context.Context, in *empty.Empty,
+	_ ...grpc.CallOption) (*types.GetCandidateResponse, error) {
+	resp, err := c.call(ctx, "GetCandidate", in, func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return c.server.GetCandidate(ctx, req.(*empty.Empty))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*types.GetCandidateResponse), nil
+}
+
+// Commit fakes MerlinClient.Commit. Unconfirmed commits aren't rolled back, as the fake has no nodes to check
+// them.
+func (c *Client) Commit(ctx context.Context, in *types.CommitRequest,
+	_ ...grpc.CallOption) (*types.CommitResponse, error) {
+	resp, err := c.call(ctx, "Commit", in, func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return c.server.Commit(ctx, req.(*types.CommitRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*types.CommitResponse), nil
+}
+
+// ConfirmCommit fakes MerlinClient.ConfirmCommit.
+func (c *Client) ConfirmCommit(ctx context.Context, in *empty.Empty, _ ...grpc.CallOption) (*empty.Empty, error) {
+	return c.callEmpty(ctx, "ConfirmCommit", in, func(ctx context.Context, req proto.Message) (*empty.Empty, error) {
+		return c.server.ConfirmCommit(ctx, req.(*empty.Empty))
+	})
+}
+
+// DiscardCandidate fakes MerlinClient.DiscardCandidate.
+func (c *Client) DiscardCandidate(ctx context.Context, in *empty.Empty,
+	_ ...grpc.CallOption) (*empty.Empty, error) {
+	return c.callEmpty(ctx, "DiscardCandidate", in, func(ctx context.Context, req proto.Message) (*empty.Empty,
+		error) {
+		return c.server.DiscardCandidate(ctx, req.(*empty.Empty))
+	})
+}
+
+// ListAuditEvents fakes MerlinClient.ListAuditEvents. Writes through the fake aren't audited, so only events added
+// to the Store are listed.
+func (c *Client) ListAuditEvents(ctx context.Context, in *types.ListAuditEventsRequest,
+	_ ...grpc.CallOption) (*types.ListAuditEventsResponse, error) {
+	resp, err := c.call(ctx, "ListAuditEvents", in, func(ctx context.Context, req proto.Message) (proto.Message,
+		error) {
+		return c.server.ListAuditEvents(ctx, req.(*types.ListAuditEventsRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*types.ListAuditEventsResponse), nil
+}
+
+// GetService fakes MerlinClient.GetService.
+func (c *Client) GetService(ctx context.Context, in *wrappers.StringValue,
+	_ ...grpc.CallOption) (*types.GetServiceResponse, error) {
+	resp, err := c.call(ctx, "GetService", in, func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return c.server.GetService(ctx, req.(*wrappers.StringValue))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*types.GetServiceResponse), nil
+}
+
+// GetServer fakes MerlinClient.GetServer.
+func (c *Client) GetServer(ctx context.Context, in *types.RealServer, _ ...grpc.CallOption) (*types.RealServer,
+	error) {
+	resp, err := c.call(ctx, "GetServer", in, func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return c.server.GetServer(ctx, req.(*types.RealServer))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*types.RealServer), nil
+}
+
+// GetServerStats fakes MerlinClient.GetServerStats.
+func (c *Client) GetServerStats(ctx context.Context, in *wrappers.StringValue,
+	_ ...grpc.CallOption) (*types.GetServerStatsResponse, error) {
+	resp, err := c.call(ctx, "GetServerStats", in, func(ctx context.Context, req proto.Message) (proto.Message,
+		error) {
+		return c.server.GetServerStats(ctx, req.(*wrappers.StringValue))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*types.GetServerStatsResponse), nil
+}
+
+// Apply fakes MerlinClient.Apply.
+func (c *Client) Apply(ctx context.Context, in *types.ApplyRequest, _ ...grpc.CallOption) (*types.ApplyResponse,
+	error) {
+	resp, err := c.call(ctx, "Apply", in, func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return c.server.Apply(ctx, req.(*types.ApplyRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*types.ApplyResponse), nil
+}
+
+// Watch fakes MerlinClient.Watch, streaming changes to the fake's store until ctx is cancelled.
+func (c *Client) Watch(ctx context.Context, in *types.WatchRequest, _ ...grpc.CallOption) (types.Merlin_WatchClient,
+	error) {
+	req, err := c.record("Watch", in)
+	if err != nil {
+		return nil, err
+	}
+	w := &watch{ctx: ctx, events: make(chan *types.WatchEvent), done: make(chan struct{})}
+	go func() {
+		_, w.err = result(nil, c.server.Watch(req.(*types.WatchRequest), &watchServer{w: w}))
+		close(w.done)
+	}()
+	return &watchClient{w: w}, nil
+}
+
+// watch passes the events of a Watch stream from the server to the client.
+type watch struct {
+	ctx    context.Context
+	events chan *types.WatchEvent
+	done   chan struct{}
+	// err the server returned, set before done is closed.
+	err error
+}
+
+type watchServer struct {
+	grpc.ServerStream
+	w *watch
+}
+
+func (s *watchServer) Context() context.Context {
+	return s.w.ctx
+}
+
+func (s *watchServer) Send(event *types.WatchEvent) error {
+	select {
+	case s.w.events <- proto.Clone(event).(*types.WatchEvent):
+		return nil
+	case <-s.w.ctx.Done():
+		return status.FromContextError(s.w.ctx.Err()).Err()
+	}
+}
+
+type watchClient struct {
+	grpc.ClientStream
+	w *watch
+}
+
+func (c *watchClient) Context() context.Context {
+	return c.w.ctx
+}
+
+// Recv returns the next event, or once ctx is cancelled its status, as a grpc stream does.
+func (c *watchClient) Recv() (*types.WatchEvent, error) {
+	select {
+	case event := <-c.w.events:
+		return event, nil
+	case <-c.w.done:
+		if c.w.err == nil {
+			return nil, status.FromContextError(c.w.ctx.Err()).Err()
+		}
+		return nil, c.w.err
+	}
+}
+
+// GetSyncDaemons fakes MerlinClient.GetSyncDaemons. The fake has no IPVS, so it always fails with
+// codes.FailedPrecondition.
+func (c *Client) GetSyncDaemons(ctx context.Context, in *empty.Empty,
+	_ ...grpc.CallOption) (*types.SyncDaemons, error) {
+	resp, err := c.call(ctx, "GetSyncDaemons", in, func(ctx context.Context, req proto.Message) (proto.Message,
+		error) {
+		return c.server.GetSyncDaemons(ctx, req.(*empty.Empty))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*types.SyncDaemons), nil
+}
+
+// SetSyncRole fakes MerlinClient.SetSyncRole. The fake has no IPVS, so it always fails with
+// codes.FailedPrecondition.
+func (c *Client) SetSyncRole(ctx context.Context, in *types.SetSyncRoleRequest,
+	_ ...grpc.CallOption) (*types.SyncDaemons, error) {
+	resp, err := c.call(ctx, "SetSyncRole", in, func(ctx context.Context, req proto.Message) (proto.Message,
+		error) {
+		return c.server.SetSyncRole(ctx, req.(*types.SetSyncRoleRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*types.SyncDaemons), nil
+}
+
+// ListConnections fakes MerlinClient.ListConnections. The fake has no IPVS, so it fails as merlin does with IPVS
+// disabled.
+func (c *Client) ListConnections(ctx context.Context, in *types.ListConnectionsRequest,
+	_ ...grpc.CallOption) (types.Merlin_ListConnectionsClient, error) {
+	req, err := c.record("ListConnections", in)
+	if err != nil {
+		return nil, err
+	}
+	s := &connectionsServer{ctx: ctx}
+	if _, err := result(nil, c.server.ListConnections(req.(*types.ListConnectionsRequest), s)); err != nil {
+		return nil, err
+	}
+	return &connectionsClient{ctx: ctx, conns: s.conns}, nil
+}
+
+// connectionsServer collects the connections sent by the server, for the client to return after it finishes.
+type connectionsServer struct {
+	grpc.ServerStream
+	ctx   context.Context
+	conns []*types.Connection
+}
+
+func (s *connectionsServer) Context() context.Context {
+	return s.ctx
+}
+
+func (s *connectionsServer) Send(conn *types.Connection) error {
+	s.conns = append(s.conns, proto.Clone(conn).(*types.Connection))
+	return nil
+}
+
+type connectionsClient struct {
+	grpc.ClientStream
+	ctx   context.Context
+	conns []*types.Connection
+}
+
+func (c *connectionsClient) Context() context.Context {
+	return c.ctx
+}
+
+// Recv returns the next connection, or io.EOF after the last, as a grpc stream does.
+func (c *connectionsClient) Recv() (*types.Connection, error) {
+	if len(c.conns) == 0 {
+		return nil, io.EOF
+	}
+	conn := c.conns[0]
+	c.conns = c.conns[1:]
+	return conn, nil
+}
@@ -0,0 +1,117 @@
+package clientfake
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/protobuf/ptypes/wrappers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sky-uk/merlin/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestClientFake(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "ClientFake Suite")
+}
+
+var _ = Describe("Client", func() {
+	var (
+		ctx    context.Context
+		client *Client
+		svc    *types.VirtualService
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		client = New()
+		svc = &types.VirtualService{
+			Id:     "svc1",
+			Key:    &types.VirtualService_Key{Ip: "10.1.1.1", Port: 80, Protocol: types.Protocol_TCP},
+			Config: &types.VirtualService_Config{Scheduler: "sh"},
+		}
+	})
+
+	It("should store services in memory", func() {
+		_, err := client.CreateService(ctx, svc)
+		Expect(err).ToNot(HaveOccurred())
+
+		resp, err := client.List(ctx, &types.ListRequest{})
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.Items).To(HaveLen(1))
+		Expect(resp.Items[0].Service.Id).To(Equal("svc1"))
+	})
+
+	It("should stream changes to watches until they're cancelled", func() {
+		watchCtx, cancel := context.WithCancel(ctx)
+		stream, err := client.Watch(watchCtx, &types.WatchRequest{})
+		Expect(err).ToNot(HaveOccurred())
+		event, err := stream.Recv()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(event.Initial).To(BeTrue())
+
+		_, err = client.CreateService(ctx, svc)
+		Expect(err).ToNot(HaveOccurred())
+		event, err = stream.Recv()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(event.Changes).To(HaveLen(1))
+		Expect(event.Changes[0].Service.Id).To(Equal("svc1"))
+
+		cancel()
+		_, err = stream.Recv()
+		Expect(status.Code(err)).To(Equal(codes.Canceled))
+	})
+
+	It("should return merlin's errors", func() {
+		_, err := client.UpdateService(ctx, svc)
+
+		Expect(status.Code(err)).To(Equal(codes.NotFound))
+	})
+
+	It("should delete services", func() {
+		client.CreateService(ctx, svc)
+
+		_, err := client.DeleteService(ctx, &wrappers.StringValue{Value: "svc1"})
+
+		Expect(err).ToNot(HaveOccurred())
+		resp, _ := client.List(ctx, &types.ListRequest{})
+		Expect(resp.Items).To(BeEmpty())
+	})
+
+	It("should record calls", func() {
+		client.CreateService(ctx, svc)
+		client.List(ctx, &types.ListRequest{})
+		svc.Id = "changed"
+
+		Expect(client.Calls()).To(HaveLen(2))
+		calls := client.CallsTo("CreateService")
+		Expect(calls).To(HaveLen(1))
+		Expect(calls[0].Request.(*types.VirtualService).Id).To(Equal("svc1"))
+	})
+
+	It("should fail calls with the set error", func() {
+		client.SetError("CreateService", errors.New("boom"))
+
+		_, err := client.CreateService(ctx, svc)
+
+		Expect(status.Code(err)).To(Equal(codes.Internal))
+		Expect(client.CallsTo("CreateService")).To(HaveLen(1))
+		resp, _ := client.List(ctx, &types.ListRequest{})
+		Expect(resp.Items).To(BeEmpty())
+	})
+
+	It("should forget calls and errors when reset", func() {
+		client.SetError("CreateService", errors.New("boom"))
+		client.CreateService(ctx, svc)
+
+		client.Reset()
+
+		Expect(client.Calls()).To(BeEmpty())
+		_, err := client.CreateService(ctx, svc)
+		Expect(err).ToNot(HaveOccurred())
+	})
+})
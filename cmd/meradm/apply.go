@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/sky-uk/merlin/types"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc/status"
+)
+
+var applyCmd = &cobra.Command{
+	Use:   "apply [file|-]",
+	Short: "Make a list of changes to services and servers, all or nothing",
+	Long: `Make a list of changes to services and servers in a single write, so either all of them are made or, if
+any fails, none are. It needs the etcd3 store, and at most 128 changes, counting the servers of deleted
+services, fit in a write. Each change is made as by the command of its action, so an UPDATE only needs the fields it
+changes, and a DELETE the service ID or server key:
+
+  changes:
+  - action: CREATE
+    service: {id: web, key: {protocol: TCP, ip: 10.1.1.1, port: 80}, config: {scheduler: wrr}}
+  - action: CREATE
+    server: {serviceID: web, key: {ip: 172.16.0.1, port: 8080}, config: {weight: 1, forward: ROUTE}}
+  - action: DELETE
+    server: {serviceID: web, key: {ip: 172.16.0.2, port: 8080}}`,
+	Args: cobra.ExactArgs(1),
+	RunE: applyChanges,
+}
+
+var applyDryRun bool
+
+func init() {
+	rootCmd.AddCommand(applyCmd)
+	applyCmd.Flags().BoolVar(&applyDryRun, "dry-run", false, "check the changes without making them")
+}
+
+func applyChanges(_ *cobra.Command, args []string) error {
+	data, err := readInput(args[0])
+	if err != nil {
+		return err
+	}
+	req := &types.ApplyRequest{}
+	if err := unmarshalYAML(data, req); err != nil {
+		return invalidf("unable to decode %s: %v", args[0], err)
+	}
+	req.DryRun = applyDryRun
+
+	return client(func(c types.MerlinClient) error {
+		ctx, cancel := clientContext()
+		defer cancel()
+		resp, err := c.Apply(ctx, req)
+		if err != nil {
+			// the result of every change is in the details, so the ones which failed can be fixed together
+			for _, detail := range status.Convert(err).Details() {
+				if failed, ok := detail.(*types.ApplyResponse); ok {
+					printApplyResults(req, failed)
+				}
+			}
+			return err
+		}
+
+		prefix := ""
+		if applyDryRun {
+			prefix = "(dry run) "
+		}
+		for _, result := range resp.Results {
+			fmt.Printf("%s%s\n", prefix, result.Change.PrettyString())
+		}
+		return nil
+	})
+}
+
+// printApplyResults prints the result of each change of a failed request.
+func printApplyResults(req *types.ApplyRequest, resp *types.ApplyResponse) {
+	for i, result := range resp.Results {
+		if i >= len(req.Changes) {
+			break
+		}
+		if result.Error == "" {
+			fmt.Printf("%d: ok, %s\n", i+1, req.Changes[i].PrettyString())
+		} else {
+			fmt.Printf("%d: %s, %s: %s\n", i+1, result.Code, req.Changes[i].PrettyString(), result.Error)
+		}
+	}
+}
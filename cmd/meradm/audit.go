@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/sky-uk/merlin/types"
+	"github.com/spf13/cobra"
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "List the writes made through the API, oldest first, with the client which made each",
+	Args:  cobra.NoArgs,
+	RunE:  listAuditEvents,
+}
+
+var (
+	auditSince  time.Duration
+	auditLimit  uint32
+	auditOutput string
+)
+
+func init() {
+	rootCmd.AddCommand(auditCmd)
+	f := auditCmd.Flags()
+	f.DurationVar(&auditSince, "since", 0, "only list writes made within this long, e.g. 1h")
+	f.Uint32Var(&auditLimit, "limit", 0, "most recent writes to list, 0 for all")
+	addOutputFlag(auditCmd, &auditOutput)
+}
+
+func listAuditEvents(_ *cobra.Command, _ []string) error {
+	req := &types.ListAuditEventsRequest{Limit: auditLimit}
+	if auditSince > 0 {
+		req.Since, _ = ptypes.TimestampProto(time.Now().Add(-auditSince))
+	}
+	return client(func(c types.MerlinClient) error {
+		ctx, cancel := clientContext()
+		defer cancel()
+		resp, err := c.ListAuditEvents(ctx, req)
+		if err != nil {
+			return err
+		}
+		if ok, err := writeOutput(auditOutput, resp); ok {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
+		fmt.Fprintln(w, "Time\tClient\tNode\tMethod\tResult\tRequest\t")
+		for _, event := range resp.Events {
+			t, _ := ptypes.Timestamp(event.Time)
+			caller := event.Client
+			if caller == "" {
+				caller = "-"
+			}
+			node := event.Node
+			if event.ForwardedBy != "" {
+				node += " (from " + event.ForwardedBy + ")"
+			}
+			method := event.Method
+			if event.Candidate {
+				method += " (staged)"
+			}
+			if event.DryRun {
+				method += " (dry run)"
+			}
+			result := event.Code
+			if event.Error != "" {
+				result += ": " + event.Error
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t\n", t.Local().Format(time.RFC3339), caller, node, method, result,
+				event.Request)
+		}
+		return w.Flush()
+	})
+}
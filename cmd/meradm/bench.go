@@ -0,0 +1,285 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/sky-uk/merlin/types"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var benchCmd = &cobra.Command{
+	Use:   "bench",
+	Short: "Measure the throughput of merlin by creating, listing and deleting synthetic services and servers",
+	Long: `Measure the throughput of merlin by creating, listing and deleting synthetic services and servers, and the
+time for IPVS on the connected node to converge with the store.
+
+Services are created on 198.18.0.0/16 and servers on 198.19.0.0/16, the range reserved for benchmarks, and are
+labelled ` + benchLabel + `. If bench is interrupted, remove them with:
+
+  meradm delete services -l ` + benchLabel + ` --servers`,
+	Args: cobra.NoArgs,
+	RunE: bench,
+}
+
+const (
+	benchLabel = "meradm-bench"
+	// benchMaxAddresses is the number of addresses in each of the service and server ranges.
+	benchMaxAddresses = 1 << 16
+	convergePoll      = 100 * time.Millisecond
+)
+
+var (
+	benchServices        int
+	benchServers         int
+	benchConcurrency     int
+	benchListIterations  int
+	benchConvergeTimeout time.Duration
+	benchKeep            bool
+)
+
+func init() {
+	rootCmd.AddCommand(benchCmd)
+
+	f := benchCmd.Flags()
+	f.IntVar(&benchServices, "services", 10, "number of services to create")
+	f.IntVar(&benchServers, "servers", 2, "number of servers to create in each service")
+	f.IntVarP(&benchConcurrency, "concurrency", "c", 4, "number of concurrent requests")
+	f.IntVar(&benchListIterations, "list-iterations", 10, "number of times to list all services")
+	f.DurationVar(&benchConvergeTimeout, "converge-timeout", time.Minute,
+		"time to wait for IPVS to converge, 0 to skip")
+	f.BoolVar(&benchKeep, "keep", false, "keep the services and servers rather than deleting them")
+}
+
+// benchResult is the time taken by a phase of the benchmark.
+type benchResult struct {
+	phase    string
+	ops      int
+	duration time.Duration
+	// latency is the mean time taken by each request.
+	latency time.Duration
+	note    string
+}
+
+func bench(_ *cobra.Command, _ []string) error {
+	if benchServices < 1 || benchServers < 0 || benchConcurrency < 1 || benchListIterations < 0 {
+		return withExitCode(exitUsage, errors.New("--services and --concurrency must be positive, "+
+			"and --servers and --list-iterations not negative"))
+	}
+	if benchServices > benchMaxAddresses || benchServices*benchServers > benchMaxAddresses {
+		return withExitCode(exitUsage, fmt.Errorf("at most %d services and servers can be created",
+			benchMaxAddresses))
+	}
+
+	services := make([]*types.VirtualService, benchServices)
+	var servers []*types.RealServer
+	for i := range services {
+		services[i] = &types.VirtualService{
+			Id:     fmt.Sprintf("%s-%d", benchLabel, i),
+			Key:    &types.VirtualService_Key{Ip: benchIP(198, 18, i), Port: 80, Protocol: types.Protocol_TCP},
+			Config: &types.VirtualService_Config{Scheduler: "rr"},
+			Labels: map[string]string{benchLabel: "true"},
+		}
+		for j := 0; j < benchServers; j++ {
+			servers = append(servers, &types.RealServer{
+				ServiceID: services[i].Id,
+				Key:       &types.RealServer_Key{Ip: benchIP(198, 19, i*benchServers+j), Port: 8080},
+				Config: &types.RealServer_Config{
+					Weight:  &wrappers.UInt32Value{Value: 1},
+					Forward: types.ForwardMethod_ROUTE,
+				},
+				HealthCheck: &types.RealServer_HealthCheck{},
+			})
+		}
+	}
+
+	return client(func(c types.MerlinClient) error {
+		var results []benchResult
+		run := func(phase string, ops int, fn func(i int) error) error {
+			var mu sync.Mutex
+			var total time.Duration
+			d, err := runConcurrently(ops, func(i int) error {
+				start := time.Now()
+				err := fn(i)
+				mu.Lock()
+				total += time.Since(start)
+				mu.Unlock()
+				return err
+			})
+			if err != nil {
+				return wrapError(err, "%s", phase)
+			}
+			result := benchResult{phase: phase, ops: ops, duration: d}
+			if ops > 0 {
+				result.latency = total / time.Duration(ops)
+			}
+			results = append(results, result)
+			return nil
+		}
+		defer func() { printBenchResults(results) }()
+
+		err := run("create services", len(services), func(i int) error {
+			return benchCall(func(ctx context.Context) error {
+				_, err := c.CreateService(ctx, services[i])
+				return err
+			})
+		})
+		if err == nil {
+			err = run("create servers", len(servers), func(i int) error {
+				return benchCall(func(ctx context.Context) error {
+					_, err := c.CreateServer(ctx, servers[i])
+					return err
+				})
+			})
+		}
+		if err == nil && benchConvergeTimeout > 0 {
+			var result benchResult
+			result, err = converge(c, len(services), len(servers))
+			results = append(results, result)
+		}
+		if err == nil {
+			err = run("list", benchListIterations, func(_ int) error {
+				return benchCall(func(ctx context.Context) error {
+					_, err := c.List(ctx, &types.ListRequest{LabelSelector: benchLabel})
+					return err
+				})
+			})
+		}
+
+		if benchKeep {
+			return err
+		}
+		// clean up even if the benchmark failed, ignoring what was never created
+		cleanup := func(phase string, ops int, fn func(ctx context.Context, i int) error) {
+			if cleanupErr := run(phase, ops, func(i int) error {
+				return benchCall(func(ctx context.Context) error { return fn(ctx, i) })
+			}); cleanupErr != nil && err == nil {
+				err = cleanupErr
+			}
+		}
+		cleanup("delete servers", len(servers), func(ctx context.Context, i int) error {
+			_, err := c.DeleteServer(ctx, servers[i])
+			if status.Code(err) == codes.NotFound {
+				return nil
+			}
+			return err
+		})
+		cleanup("delete services", len(services), func(ctx context.Context, i int) error {
+			_, err := c.DeleteService(ctx, &wrappers.StringValue{Value: services[i].Id})
+			return err
+		})
+		return err
+	})
+}
+
+// converge waits for the stats of the node to include the benchmark services and servers.
+func converge(c types.MerlinClient, services, servers int) (benchResult, error) {
+	result := benchResult{phase: "converge", ops: services + servers}
+	start := time.Now()
+	for {
+		var stats *types.StatsResponse
+		err := benchCall(func(ctx context.Context) error {
+			var err error
+			stats, err = c.Stats(ctx, &empty.Empty{})
+			return err
+		})
+		if status.Code(err) == codes.FailedPrecondition {
+			result.note = "skipped, IPVS is disabled on " + host
+			return result, nil
+		}
+		if err != nil {
+			return result, wrapError(err, "converge")
+		}
+
+		var seenServices, seenServers int
+		for _, svc := range stats.Services {
+			if strings.HasPrefix(svc.Id, benchLabel+"-") {
+				seenServices++
+				seenServers += len(svc.Servers)
+			}
+		}
+		result.duration = time.Since(start)
+		if seenServices == services && seenServers == servers {
+			result.note = "on " + stats.Node
+			return result, nil
+		}
+		if result.duration > benchConvergeTimeout {
+			return result, fmt.Errorf("converge: %s has %d of %d services and %d of %d servers after %v",
+				stats.Node, seenServices, services, seenServers, servers, benchConvergeTimeout)
+		}
+		time.Sleep(convergePoll)
+	}
+}
+
+// runConcurrently calls fn for 0 to n-1 with --concurrency goroutines, returning the time taken and the
+// first error. Remaining calls are skipped after an error.
+func runConcurrently(n int, fn func(i int) error) (time.Duration, error) {
+	start := time.Now()
+	work := make(chan int)
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	failed := make(chan struct{})
+
+	for w := 0; w < benchConcurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				if err := fn(i); err != nil {
+					once.Do(func() {
+						firstErr = err
+						close(failed)
+					})
+				}
+			}
+		}()
+	}
+
+send:
+	for i := 0; i < n; i++ {
+		select {
+		case work <- i:
+		case <-failed:
+			break send
+		}
+	}
+	close(work)
+	wg.Wait()
+	return time.Since(start), firstErr
+}
+
+func benchCall(fn func(ctx context.Context) error) error {
+	ctx, cancel := clientContext()
+	defer cancel()
+	return fn(ctx)
+}
+
+func benchIP(a, b, i int) string {
+	return fmt.Sprintf("%d.%d.%d.%d", a, b, i/256, i%256)
+}
+
+func printBenchResults(results []benchResult) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
+	fmt.Fprintln(w, "Phase\tOps\tDuration\tOps/s\tLatency\t")
+	for _, r := range results {
+		rate, latency := "-", "-"
+		if r.latency > 0 {
+			rate = fmt.Sprintf("%.1f", float64(r.ops)/r.duration.Seconds())
+			latency = r.latency.Round(time.Microsecond).String()
+		}
+		fmt.Fprintf(w, "%s\t%d\t%v\t%s\t%s\t%s\n", r.phase, r.ops, r.duration.Round(time.Millisecond), rate,
+			latency, r.note)
+	}
+	w.Flush()
+}
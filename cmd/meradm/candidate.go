@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/sky-uk/merlin/types"
+	"github.com/spf13/cobra"
+)
+
+var candidateCmd = &cobra.Command{
+	Use:   "candidate [discard]",
+	Short: "Show the changes staged in the candidate config by commands run with --candidate",
+	Args:  cobra.NoArgs,
+	RunE:  showCandidate,
+}
+
+var discardCandidateCmd = &cobra.Command{
+	Use:   "discard",
+	Short: "Clear the candidate config without applying it",
+	Args:  cobra.NoArgs,
+	RunE:  discardCandidate,
+}
+
+var commitCmd = &cobra.Command{
+	Use:   "commit [confirmed [timeout]|confirm]",
+	Short: "Apply the candidate config in a single write",
+	Args:  cobra.NoArgs,
+	RunE:  commit,
+}
+
+var commitConfirmedCmd = &cobra.Command{
+	Use:   "confirmed [timeout]",
+	Short: "Apply the candidate config, rolling it back unless it's confirmed within the timeout, 10m by default",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  commit,
+}
+
+var confirmCommitCmd = &cobra.Command{
+	Use:   "confirm",
+	Short: "Keep the last commit confirmed, so it isn't rolled back",
+	Args:  cobra.NoArgs,
+	RunE:  confirmCommit,
+}
+
+var (
+	useCandidate bool
+	commitDryRun bool
+)
+
+func init() {
+	rootCmd.PersistentFlags().BoolVar(&useCandidate, "candidate", false,
+		"stage changes in the candidate config, which is applied by commit, and list it instead of the current config")
+	rootCmd.AddCommand(candidateCmd)
+	candidateCmd.AddCommand(discardCandidateCmd)
+	rootCmd.AddCommand(commitCmd)
+	commitCmd.AddCommand(commitConfirmedCmd)
+	commitCmd.AddCommand(confirmCommitCmd)
+	for _, cmd := range []*cobra.Command{commitCmd, commitConfirmedCmd} {
+		cmd.Flags().BoolVar(&commitDryRun, "dry-run", false, "print the changes without applying them")
+	}
+}
+
+func showCandidate(_ *cobra.Command, _ []string) error {
+	return client(func(c types.MerlinClient) error {
+		ctx, cancel := clientContext()
+		defer cancel()
+		resp, err := c.GetCandidate(ctx, &empty.Empty{})
+		if err != nil {
+			return err
+		}
+		if resp.ConfirmBy != nil {
+			confirmBy, _ := ptypes.Timestamp(resp.ConfirmBy)
+			fmt.Printf("The last commit is rolled back at %s unless confirmed with meradm commit confirm\n",
+				confirmBy.Local().Format(time.RFC3339))
+		}
+		if len(resp.Changes) == 0 {
+			fmt.Println("No changes are staged")
+		}
+		for _, change := range resp.Changes {
+			fmt.Println(change.PrettyString())
+		}
+		return nil
+	})
+}
+
+func discardCandidate(_ *cobra.Command, _ []string) error {
+	return client(func(c types.MerlinClient) error {
+		ctx, cancel := clientContext()
+		defer cancel()
+		_, err := c.DiscardCandidate(ctx, &empty.Empty{})
+		return err
+	})
+}
+
+func commit(cmd *cobra.Command, args []string) error {
+	req := &types.CommitRequest{DryRun: commitDryRun}
+	var confirmTimeout time.Duration
+	if cmd.Name() == "confirmed" {
+		confirmTimeout = 10 * time.Minute
+		if len(args) > 0 {
+			var err error
+			if confirmTimeout, err = time.ParseDuration(args[0]); err != nil {
+				return invalidf("invalid timeout: %v", err)
+			}
+		}
+		req.ConfirmTimeout = ptypes.DurationProto(confirmTimeout)
+	}
+
+	return client(func(c types.MerlinClient) error {
+		ctx, cancel := clientContext()
+		defer cancel()
+		resp, err := c.Commit(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		prefix := ""
+		if commitDryRun {
+			prefix = "(dry run) "
+		}
+		for _, change := range resp.Changes {
+			fmt.Printf("%s%s\n", prefix, change.PrettyString())
+		}
+		if confirmTimeout > 0 && !commitDryRun {
+			fmt.Printf("Run meradm commit confirm within %v, or the commit will be rolled back\n", confirmTimeout)
+		}
+		return nil
+	})
+}
+
+func confirmCommit(_ *cobra.Command, _ []string) error {
+	return client(func(c types.MerlinClient) error {
+		ctx, cancel := clientContext()
+		defer cancel()
+		_, err := c.ConfirmCommit(ctx, &empty.Empty{})
+		return err
+	})
+}
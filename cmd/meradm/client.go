@@ -2,22 +2,53 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"time"
 
 	"fmt"
 
+	"github.com/cenkalti/backoff"
 	log "github.com/sirupsen/logrus"
 	"github.com/sky-uk/merlin/types"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
 )
 
+// clientContext returns the context for a request, which allows time for each retry. Writes are staged in the
+// candidate config if --candidate is set.
 func clientContext() (context.Context, context.CancelFunc) {
-	return context.WithTimeout(context.Background(), timeout)
+	ctx := context.Background()
+	if useCandidate {
+		ctx = types.WithCandidate(ctx)
+	}
+	if dryRun {
+		ctx = types.WithDryRun(ctx)
+	}
+	return context.WithTimeout(ctx, timeout*time.Duration(retries+1))
 }
 
+// sharedConn is used by client instead of dialing merlin for each command, when set by the shell.
+var sharedConn *grpc.ClientConn
+
 func client(fn func(client types.MerlinClient) error) error {
-	dest := fmt.Sprintf("%s:%d", host, port)
-	log.Debugf("Dialing %s", dest)
-	conn, err := grpc.Dial(dest, grpc.WithInsecure())
+	if sharedConn != nil {
+		return fn(types.NewMerlinClient(sharedConn))
+	}
+	return clientFor(fmt.Sprintf("%s:%d", host, port), fn)
+}
+
+// clientFor calls fn with a client connected to the merlin instance at dest (host:port).
+func clientFor(dest string, fn func(client types.MerlinClient) error) error {
+	conn, err := dial(dest)
 	if err != nil {
 		return err
 	}
@@ -26,3 +57,179 @@ func client(fn func(client types.MerlinClient) error) error {
 
 	return fn(c)
 }
+
+// dial connects to the merlin instance at dest (host:port), using the TLS and token flags.
+func dial(dest string) (*grpc.ClientConn, error) {
+	log.Debugf("Dialing %s", dest)
+	transport, err := transportOption()
+	if err != nil {
+		return nil, err
+	}
+	versionCheck := types.VersionCheckInterceptor(strictVersion, func(err error) { log.Warn(err) })
+	warnings := types.WarningInterceptor(func(warning string) { log.Warnf("merlin: %s", warning) })
+	dryRuns := types.DryRunInterceptor(printDryRun)
+	opts := []grpc.DialOption{transport,
+		grpc.WithUnaryInterceptor(chainInterceptors(versionCheck, warnings, dryRuns, retryInterceptor))}
+	if waitForReady {
+		opts = append(opts, grpc.WithDefaultCallOptions(grpc.WaitForReady(true)))
+	}
+	creds, err := tokenCredentials()
+	if err != nil {
+		return nil, err
+	}
+	if creds != nil {
+		opts = append(opts, grpc.WithPerRPCCredentials(creds))
+	}
+	return grpc.Dial(dest, opts...)
+}
+
+// idempotentMethods are the RPCs which are safe to retry, as repeating them has the same result.
+var idempotentMethods = map[string]bool{
+	"/types.Merlin/UpdateService":   true,
+	"/types.Merlin/DeleteService":   true,
+	"/types.Merlin/UpdateServer":    true,
+	"/types.Merlin/DeleteServer":    true,
+	"/types.Merlin/DrainServer":     true,
+	"/types.Merlin/UndrainServer":   true,
+	"/types.Merlin/List":            true,
+	"/types.Merlin/Stats":           true,
+	"/types.Merlin/GetServerStats":  true,
+	"/types.Merlin/GetNodeState":    true,
+	"/types.Merlin/DescribeService": true,
+	"/types.Merlin/GetSnapshot":     true,
+	"/types.Merlin/ApplySnapshot":   true,
+	"/types.Merlin/Info":            true,
+	"/types.Merlin/ListNodes":       true,
+	"/types.Merlin/SetMaintenance":  true,
+	"/types.Merlin/ListPools":       true,
+	"/types.Merlin/GetCandidate":    true,
+	"/types.Merlin/ListAuditEvents": true,
+	"/types.Merlin/GetSyncDaemons":  true,
+	"/types.Merlin/SetSyncRole":     true,
+	"/types.Merlin/GetService":      true,
+	"/types.Merlin/GetServer":       true,
+}
+
+// retryInterceptor limits each attempt of a request to --timeout, retrying idempotent requests up to --retries
+// times if merlin is unavailable or the attempt times out.
+func retryInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn,
+	invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+
+	attempt := func() error {
+		attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		err := invoker(attemptCtx, method, req, reply, cc, opts...)
+		code := status.Code(err)
+		if code == codes.Unavailable || (code == codes.DeadlineExceeded && ctx.Err() == nil) {
+			return err
+		}
+		return &backoff.PermanentError{Err: err}
+	}
+	if retries == 0 || !idempotentMethods[method] {
+		return unwrapPermanent(attempt())
+	}
+
+	b := backoff.WithContext(backoff.WithMaxRetries(backoff.NewExponentialBackOff(), uint64(retries)), ctx)
+	return unwrapPermanent(backoff.RetryNotify(attempt, b, func(err error, wait time.Duration) {
+		log.Warnf("Retrying %s in %v: %v", path.Base(method), wait.Round(time.Millisecond), err)
+	}))
+}
+
+// chainInterceptors chains interceptors, as grpc only supports one. The first is the outermost.
+func chainInterceptors(interceptors ...grpc.UnaryClientInterceptor) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		next := invoker
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, inner := interceptors[i], next
+			next = func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn,
+				opts ...grpc.CallOption) error {
+				return interceptor(ctx, method, req, reply, cc, inner, opts...)
+			}
+		}
+		return next(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+func unwrapPermanent(err error) error {
+	if permanent, ok := err.(*backoff.PermanentError); ok {
+		return permanent.Err
+	}
+	return err
+}
+
+// transportOption returns the dial option for plaintext or TLS, depending on the --tls flags.
+func transportOption() (grpc.DialOption, error) {
+	if !useTLS && tlsCA == "" && tlsCert == "" && tlsKey == "" && !insecureSkipVerify {
+		return grpc.WithInsecure(), nil
+	}
+
+	config := &tls.Config{InsecureSkipVerify: insecureSkipVerify}
+
+	if tlsCA != "" {
+		pem, err := ioutil.ReadFile(tlsCA)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read CA certificate: %v", err)
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", tlsCA)
+		}
+	}
+
+	if tlsCert != "" || tlsKey != "" {
+		if tlsCert == "" || tlsKey == "" {
+			return nil, errors.New("--tls-cert and --tls-key must be set together")
+		}
+		cert, err := tls.LoadX509KeyPair(tlsCert, tlsKey)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load client certificate: %v", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return grpc.WithTransportCredentials(credentials.NewTLS(config)), nil
+}
+
+// tokenEnv is the environment variable meradm reads the bearer token from.
+const tokenEnv = "MERADM_TOKEN"
+
+// bearerToken sends a bearer token in the authorization metadata of each request.
+type bearerToken struct {
+	token string
+}
+
+func (t *bearerToken) GetRequestMetadata(_ context.Context, _ ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + t.token}, nil
+}
+
+func (t *bearerToken) RequireTransportSecurity() bool {
+	// allowed over plaintext so tokens work with servers behind a TLS terminating proxy
+	return false
+}
+
+// tokenCredentials returns the bearer token credentials from --token, $MERADM_TOKEN or --token-command,
+// or nil if none are set.
+func tokenCredentials() (credentials.PerRPCCredentials, error) {
+	t := token
+	if t == "" {
+		t = os.Getenv(tokenEnv)
+	}
+	if t == "" && tokenCommand != "" {
+		log.Debugf("Running token command %q", tokenCommand)
+		cmd := exec.Command("sh", "-c", tokenCommand)
+		cmd.Stderr = os.Stderr
+		out, err := cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("token command failed: %v", err)
+		}
+		t = strings.TrimSpace(string(out))
+		if t == "" {
+			return nil, errors.New("token command printed an empty token")
+		}
+	}
+	if t == "" {
+		return nil, nil
+	}
+	return &bearerToken{token: t}, nil
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	log "github.com/sirupsen/logrus"
+	"github.com/sky-uk/merlin/types"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var completionCmd = &cobra.Command{
+	Use:   "completion [bash|zsh|fish]",
+	Short: "Print a shell completion script",
+	Long: `Print a shell completion script, which completes commands and flags, and service IDs, server addresses,
+node names and contexts by querying merlin. To load completions:
+
+  bash: source <(meradm completion bash)
+  zsh:  meradm completion zsh > "${fpath[1]}/_meradm"
+  fish: meradm completion fish > ~/.config/fish/completions/meradm.fish`,
+	Args:      cobra.ExactValidArgs(1),
+	ValidArgs: []string{"bash", "zsh", "fish"},
+	RunE:      completion,
+	// contexts are applied when completing, not when printing the script
+	PersistentPreRunE: func(_ *cobra.Command, _ []string) error { return nil },
+}
+
+// completeCmd is called by the completion scripts with the words of the command line, the last being the
+// word to complete, and prints the candidates one per line.
+var completeCmd = &cobra.Command{
+	Use:                completeCmdName,
+	Hidden:             true,
+	DisableFlagParsing: true,
+	PersistentPreRunE:  func(_ *cobra.Command, _ []string) error { return nil },
+	Run:                complete,
+}
+
+const (
+	completeCmdName = "__complete"
+	// completionTimeout bounds queries to merlin, so a slow server doesn't hang the shell.
+	completionTimeout = 2 * time.Second
+)
+
+// completer returns the candidates for a positional argument, given the preceding arguments.
+type completer func(args []string) []string
+
+var (
+	// argCompleters complete each positional argument of a command.
+	argCompleters map[*cobra.Command][]completer
+	// flagCompleters complete the values of global flags.
+	flagCompleters map[string]completer
+)
+
+func init() {
+	rootCmd.AddCommand(completionCmd)
+	rootCmd.AddCommand(completeCmd)
+
+	argCompleters = map[*cobra.Command][]completer{
+		editServiceCmd:     {serviceIDs},
+		deleteServiceCmd:   {serviceIDs},
+		cloneServiceCmd:    {serviceIDs},
+		renameServiceCmd:   {serviceIDs},
+		describeServiceCmd: {serviceIDs},
+		serverStatsCmd:     {serviceIDs},
+		connsCmd:           {serviceIDs},
+		getServiceCmd:      {serviceIDs},
+		getServerCmd:       {serviceIDs, serverAddresses},
+		addServerCmd:       {serviceIDs},
+		editServerCmd:      {serviceIDs, serverAddresses},
+		deleteServerCmd:    {serviceIDs, serverAddresses},
+		drainServerCmd:     {serviceIDs, serverAddresses},
+		undrainServerCmd:   {serviceIDs, serverAddresses},
+		maintenanceCmd:     {nodeNames, values("on", "off")},
+		roleSyncdCmd:       {values(roleSyncdCmd.ValidArgs...)},
+		useContextCmd:      {contextNames},
+		completionCmd:      {values(completionCmd.ValidArgs...)},
+	}
+	flagCompleters = map[string]completer{
+		"context": contextNames,
+	}
+}
+
+func completion(_ *cobra.Command, args []string) error {
+	name := rootCmd.Name()
+	var script string
+	switch args[0] {
+	case "bash":
+		script = fmt.Sprintf(`# bash completion for %[1]s
+_%[1]s() {
+    local cur words cword
+    # keep ip:port as one word if bash-completion is installed
+    if declare -F _get_comp_words_by_ref >/dev/null; then
+        _get_comp_words_by_ref -n =: cur words cword
+    else
+        cur="${COMP_WORDS[COMP_CWORD]}" words=("${COMP_WORDS[@]}") cword=$COMP_CWORD
+    fi
+    local IFS=$'\n'
+    COMPREPLY=($(%[1]s %[2]s "${words[@]:1:cword-1}" "$cur" 2>/dev/null))
+    if declare -F __ltrim_colon_completions >/dev/null; then
+        __ltrim_colon_completions "$cur"
+    fi
+}
+complete -o default -F _%[1]s %[1]s
+`, name, completeCmdName)
+	case "zsh":
+		script = fmt.Sprintf(`#compdef %[1]s
+_%[1]s() {
+    local -a candidates
+    candidates=("${(@f)$(%[1]s %[2]s "${(@)words[2,CURRENT-1]}" "${words[CURRENT]}" 2>/dev/null)}")
+    if [[ -n "${candidates[1]}" ]]; then
+        compadd -a candidates
+    else
+        _files
+    fi
+}
+compdef _%[1]s %[1]s
+`, name, completeCmdName)
+	case "fish":
+		script = fmt.Sprintf(`# fish completion for %[1]s
+complete -c %[1]s -f -a '(%[1]s %[2]s (commandline -opc)[2..-1] (commandline -ct) 2>/dev/null)'
+`, name, completeCmdName)
+	}
+	_, err := fmt.Fprint(os.Stdout, script)
+	return err
+}
+
+func complete(_ *cobra.Command, args []string) {
+	if len(args) == 0 {
+		return
+	}
+	for _, candidate := range candidates(args[:len(args)-1], args[len(args)-1]) {
+		fmt.Println(candidate)
+	}
+}
+
+// candidates returns the completions of toComplete, which follows the words of the command line.
+func candidates(words []string, toComplete string) []string {
+	// complete the value of a flag given as a separate word
+	var flagValue completer
+	if n := len(words); n > 0 && strings.HasPrefix(words[n-1], "-") && !strings.Contains(words[n-1], "=") {
+		flagValue = flagCompleters[strings.TrimLeft(words[n-1], "-")]
+		if flagValue != nil {
+			words = words[:n-1]
+		}
+	}
+
+	cmd, rest, err := rootCmd.Find(words)
+	if err != nil {
+		log.Debugf("Unable to find command: %v", err)
+		return nil
+	}
+	if err := cmd.ParseFlags(rest); err != nil {
+		log.Debugf("Unable to parse flags: %v", err)
+		return nil
+	}
+	// flags aren't parsed until now, so --debug must be applied again
+	initLogs()
+	// use the connection settings of the context, as the command would
+	if err := applyContext(cmd, nil); err != nil {
+		log.Debugf("Unable to apply context: %v", err)
+		return nil
+	}
+	args := cmd.Flags().Args()
+
+	var all []string
+	switch {
+	case flagValue != nil:
+		all = flagValue(nil)
+	case strings.HasPrefix(toComplete, "-"):
+		all = flagNames(cmd)
+	case cmd.HasAvailableSubCommands() && len(args) == 0:
+		for _, sub := range cmd.Commands() {
+			if sub.IsAvailableCommand() {
+				all = append(all, sub.Name())
+			}
+		}
+	case len(args) < len(argCompleters[cmd]):
+		all = argCompleters[cmd][len(args)](args)
+	}
+
+	var matches []string
+	for _, candidate := range all {
+		if strings.HasPrefix(candidate, toComplete) {
+			matches = append(matches, candidate)
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
+
+func flagNames(cmd *cobra.Command) []string {
+	var names []string
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if !flag.Hidden {
+			names = append(names, "--"+flag.Name)
+		}
+	})
+	return names
+}
+
+func values(vals ...string) completer {
+	return func(_ []string) []string {
+		return vals
+	}
+}
+
+// completionClient queries merlin, logging rather than returning errors as there is nowhere to show them.
+func completionClient(fn func(ctx context.Context, c types.MerlinClient) error) {
+	err := client(func(c types.MerlinClient) error {
+		ctx, cancel := context.WithTimeout(context.Background(), completionTimeout)
+		defer cancel()
+		return fn(ctx, c)
+	})
+	if err != nil {
+		log.Debugf("Unable to query merlin for completions: %v", err)
+	}
+}
+
+func serviceIDs(_ []string) []string {
+	var ids []string
+	completionClient(func(ctx context.Context, c types.MerlinClient) error {
+		resp, err := c.List(ctx, &types.ListRequest{})
+		if err != nil {
+			return err
+		}
+		for _, item := range resp.Items {
+			ids = append(ids, item.Service.Id)
+		}
+		return nil
+	})
+	return ids
+}
+
+// serverAddresses completes the ip:port of the servers of the service given as the first argument.
+func serverAddresses(args []string) []string {
+	var addrs []string
+	completionClient(func(ctx context.Context, c types.MerlinClient) error {
+		resp, err := c.List(ctx, &types.ListRequest{FieldSelector: "id=" + args[0]})
+		if err != nil {
+			return err
+		}
+		for _, item := range resp.Items {
+			for _, server := range item.Servers {
+				addrs = append(addrs, fmt.Sprintf("%s:%d", server.Key.Ip, server.Key.Port))
+			}
+		}
+		return nil
+	})
+	return addrs
+}
+
+func nodeNames(_ []string) []string {
+	var names []string
+	completionClient(func(ctx context.Context, c types.MerlinClient) error {
+		resp, err := c.ListNodes(ctx, &empty.Empty{})
+		if err != nil {
+			return err
+		}
+		for _, node := range resp.Nodes {
+			names = append(names, node.Name)
+		}
+		return nil
+	})
+	return names
+}
+
+func contextNames(_ []string) []string {
+	config, err := loadConfig()
+	if err != nil {
+		log.Debugf("Unable to load config for completions: %v", err)
+		return nil
+	}
+	var names []string
+	for name := range config.Contexts {
+		names = append(names, name)
+	}
+	return names
+}
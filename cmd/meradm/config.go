@@ -0,0 +1,176 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+// clientConfig is the meradm config file. Each context maps global flag names to their values, e.g.
+//
+//	current-context: prod
+//	contexts:
+//	  prod:
+//	    host: lb.prod.example.com
+//	    port: 4282
+//
+// Flags given on the command line take precedence over the context.
+type clientConfig struct {
+	CurrentContext string                       `yaml:"current-context,omitempty"`
+	Contexts       map[string]map[string]string `yaml:"contexts"`
+}
+
+var contextCmd = &cobra.Command{
+	Use:   "context [list|use]",
+	Short: "Manage the named contexts in the meradm config file",
+	// contexts shouldn't be applied when managing them
+	PersistentPreRunE: func(_ *cobra.Command, _ []string) error { return nil },
+}
+
+var listContextsCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the contexts, marking the current one with *",
+	Args:  cobra.NoArgs,
+	RunE:  listContexts,
+}
+
+var useContextCmd = &cobra.Command{
+	Use:   "use [name]",
+	Short: "Set the current context",
+	Args:  cobra.ExactArgs(1),
+	RunE:  useContext,
+}
+
+var (
+	configFile  string
+	contextName string
+)
+
+// flags which can't be set by a context
+var contextExcludedFlags = map[string]bool{"config": true, "context": true, "help": true}
+
+func init() {
+	rootCmd.AddCommand(contextCmd)
+	contextCmd.AddCommand(listContextsCmd)
+	contextCmd.AddCommand(useContextCmd)
+
+	f := rootCmd.PersistentFlags()
+	f.StringVar(&configFile, "config", defaultConfigFile(), "meradm config file containing named contexts")
+	f.StringVar(&contextName, "context", "", "context to use from the config file, defaults to current-context")
+
+	rootCmd.PersistentPreRunE = applyContext
+}
+
+func defaultConfigFile() string {
+	home := os.Getenv("HOME")
+	if home == "" {
+		return ""
+	}
+	return filepath.Join(home, ".meradm", "config")
+}
+
+// loadConfig reads the config file. A missing file is only an error if it was set explicitly.
+func loadConfig() (*clientConfig, error) {
+	config := &clientConfig{}
+	if configFile == "" {
+		return config, nil
+	}
+	data, err := ioutil.ReadFile(configFile)
+	if os.IsNotExist(err) && !rootCmd.PersistentFlags().Changed("config") {
+		return config, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %v", configFile, err)
+	}
+	return config, nil
+}
+
+func applyContext(_ *cobra.Command, _ []string) error {
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	name := contextName
+	if name == "" {
+		name = config.CurrentContext
+	}
+	if name == "" {
+		return nil
+	}
+	settings, ok := config.Contexts[name]
+	if !ok {
+		return fmt.Errorf("context %q not found in %s", name, configFile)
+	}
+
+	log.Debugf("Using context %s", name)
+	for key, value := range settings {
+		flag := rootCmd.PersistentFlags().Lookup(key)
+		if flag == nil || contextExcludedFlags[key] {
+			return fmt.Errorf("context %q: unknown setting %q", name, key)
+		}
+		if flag.Changed {
+			continue
+		}
+		if err := flag.Value.Set(value); err != nil {
+			return fmt.Errorf("context %q: invalid %s: %v", name, key, err)
+		}
+	}
+	return nil
+}
+
+func listContexts(_ *cobra.Command, _ []string) error {
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	for name := range config.Contexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
+	fmt.Fprintln(w, "Current\tName\tHost\tPort\t")
+	for _, name := range names {
+		current := ""
+		if name == config.CurrentContext {
+			current = "*"
+		}
+		settings := config.Contexts[name]
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t\n", current, name, settings["host"], settings["port"])
+	}
+	return w.Flush()
+}
+
+func useContext(_ *cobra.Command, args []string) error {
+	if configFile == "" {
+		return errors.New("no config file, set --config")
+	}
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if _, ok := config.Contexts[args[0]]; !ok {
+		return fmt.Errorf("context %q not found in %s", args[0], configFile)
+	}
+
+	config.CurrentContext = args[0]
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(configFile, data, 0600)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/sky-uk/merlin/types"
+	"github.com/spf13/cobra"
+)
+
+var connsCmd = &cobra.Command{
+	Use:   "conns [serviceID]",
+	Short: "List the IPVS connections of the node meradm is connected to, like ipvsadm -Lcn",
+	Long: `List the IPVS connections of the node meradm is connected to, like ipvsadm -Lcn, optionally only those of
+a service. Persistence templates are listed with a client port of 0, to debug which server clients are
+persisted to, and --server lists the connections left on a server being drained.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: conns,
+}
+
+var (
+	connsServer string
+	connsOutput string
+)
+
+func init() {
+	rootCmd.AddCommand(connsCmd)
+	connsCmd.Flags().StringVar(&connsServer, "server", "", "only list the connections to the real server ip:port")
+	addOutputFlag(connsCmd, &connsOutput)
+}
+
+func conns(_ *cobra.Command, args []string) error {
+	req := &types.ListConnectionsRequest{}
+	if len(args) > 0 {
+		req.ServiceId = args[0]
+	}
+	if connsServer != "" {
+		ip, port, err := resolveAddress(connsServer)
+		if err != nil {
+			return err
+		}
+		req.Server = &types.RealServer_Key{Ip: ip, Port: port}
+	}
+
+	return client(func(c types.MerlinClient) error {
+		ctx, cancel := clientContext()
+		defer cancel()
+		stream, err := c.ListConnections(ctx, req)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
+		if connsOutput == "" {
+			fmt.Fprintln(w, "Pro\tExpires\tState\tClient\tVirtual\tServer\tService\t")
+		}
+		for {
+			conn, err := stream.Recv()
+			if err == io.EOF {
+				return w.Flush()
+			}
+			if err != nil {
+				return err
+			}
+			if ok, err := writeOutput(connsOutput, conn); ok {
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s:%d\t%s:%d\t%s\t%s\t\n",
+				conn.Service.Protocol,
+				connExpiry(conn),
+				conn.State,
+				conn.ClientIp, conn.ClientPort,
+				conn.Service.Ip, conn.Service.Port,
+				conn.Server.PrettyString(),
+				conn.ServiceId)
+		}
+	})
+}
+
+// connExpiry formats the time left of a connection as mm:ss, as ipvsadm does.
+func connExpiry(conn *types.Connection) string {
+	expires, err := ptypes.Duration(conn.Expires)
+	if err != nil {
+		return "-"
+	}
+	expires = expires.Round(time.Second)
+	return fmt.Sprintf("%02d:%02d", int(expires/time.Minute), int(expires%time.Minute/time.Second))
+}
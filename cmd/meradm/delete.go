@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/sky-uk/merlin/types"
+	"github.com/spf13/cobra"
+)
+
+var deleteCmd = &cobra.Command{
+	Use:   "delete [services|servers]",
+	Short: "Delete resources in bulk",
+}
+
+var deleteServicesCmd = &cobra.Command{
+	Use:   "services",
+	Short: "Delete all virtual services matching a label selector",
+	Args:  cobra.NoArgs,
+	RunE:  deleteServices,
+}
+
+var deleteServersCmd = &cobra.Command{
+	Use:   "servers",
+	Short: "Delete all real servers matching a label selector, of every service",
+	Args:  cobra.NoArgs,
+	RunE:  deleteServers,
+}
+
+var (
+	deleteSelector    string
+	deleteWithServers bool
+	deleteDryRun      bool
+	deleteYes         bool
+)
+
+func init() {
+	rootCmd.AddCommand(deleteCmd)
+	deleteCmd.AddCommand(deleteServicesCmd)
+	deleteCmd.AddCommand(deleteServersCmd)
+
+	for _, cmd := range []*cobra.Command{deleteServicesCmd, deleteServersCmd} {
+		f := cmd.Flags()
+		f.StringVarP(&deleteSelector, "selector", "l", "",
+			"label selector, e.g. 'team=payments,env!=prod', supports =, ==, !=, key and !key")
+		f.BoolVar(&deleteDryRun, "dry-run", false, "print what would be deleted without deleting it")
+		f.BoolVarP(&deleteYes, "yes", "y", false, "don't prompt for confirmation")
+		cmd.MarkFlagRequired("selector")
+	}
+	deleteServicesCmd.Flags().BoolVar(&deleteWithServers, "servers", false,
+		"also delete the real servers of each service")
+}
+
+func deleteServices(_ *cobra.Command, _ []string) error {
+	selector, err := types.ParseSelector(deleteSelector)
+	if err != nil {
+		return withExitCode(exitInvalid, err)
+	}
+	if selector.Empty() {
+		return withExitCode(exitUsage, errors.New("refusing to delete every service, the selector is empty"))
+	}
+
+	return client(func(c types.MerlinClient) error {
+		ctx, cancel := clientContext()
+		defer cancel()
+		resp, err := c.List(ctx, &types.ListRequest{LabelSelector: selector.String()})
+		if err != nil {
+			return err
+		}
+
+		// match again so an older server ignoring the selector can't cause everything to be deleted
+		var matched []*types.ListResponse_Item
+		for _, item := range resp.Items {
+			if selector.Matches(item.Service.Labels) {
+				matched = append(matched, item)
+			}
+		}
+		if len(matched) == 0 {
+			fmt.Printf("No services match %s\n", selector)
+			return nil
+		}
+
+		servers := 0
+		for _, item := range matched {
+			fmt.Printf("%s (%d servers)\n", item.Service.PrettyString(), len(item.Servers))
+			servers += len(item.Servers)
+		}
+		summary := fmt.Sprintf("%d services", len(matched))
+		if deleteWithServers {
+			summary += fmt.Sprintf(" and %d servers", servers)
+		}
+
+		if deleteDryRun {
+			fmt.Printf("Would delete %s\n", summary)
+			return nil
+		}
+		if !deleteYes && !confirm(fmt.Sprintf("Delete %s?", summary)) {
+			return errors.New("aborted")
+		}
+
+		for _, item := range matched {
+			if deleteWithServers {
+				for _, server := range item.Servers {
+					ctx, cancel := clientContext()
+					_, err := c.DeleteServer(ctx, server)
+					cancel()
+					if err != nil {
+						return wrapError(err, "unable to delete server %s", server.PrettyString())
+					}
+				}
+			}
+			ctx, cancel := clientContext()
+			_, err := c.DeleteService(ctx, &wrappers.StringValue{Value: item.Service.Id})
+			cancel()
+			if err != nil {
+				return wrapError(err, "unable to delete service %s", item.Service.Id)
+			}
+		}
+		fmt.Printf("Deleted %s\n", summary)
+		return nil
+	})
+}
+
+func deleteServers(_ *cobra.Command, _ []string) error {
+	selector, err := types.ParseSelector(deleteSelector)
+	if err != nil {
+		return withExitCode(exitInvalid, err)
+	}
+	if selector.Empty() {
+		return withExitCode(exitUsage, errors.New("refusing to delete every server, the selector is empty"))
+	}
+
+	return client(func(c types.MerlinClient) error {
+		ctx, cancel := clientContext()
+		defer cancel()
+		resp, err := c.List(ctx, &types.ListRequest{ServerLabelSelector: selector.String()})
+		if err != nil {
+			return err
+		}
+
+		// match again so an older server ignoring the selector can't cause every server to be deleted
+		var matched []*types.RealServer
+		for _, item := range resp.Items {
+			for _, server := range item.Servers {
+				if selector.Matches(server.Labels) {
+					matched = append(matched, server)
+				}
+			}
+		}
+		if len(matched) == 0 {
+			fmt.Printf("No servers match %s\n", selector)
+			return nil
+		}
+
+		for _, server := range matched {
+			fmt.Println(server.PrettyString())
+		}
+		summary := fmt.Sprintf("%d servers", len(matched))
+
+		if deleteDryRun {
+			fmt.Printf("Would delete %s\n", summary)
+			return nil
+		}
+		if !deleteYes && !confirm(fmt.Sprintf("Delete %s?", summary)) {
+			return errors.New("aborted")
+		}
+
+		for _, server := range matched {
+			ctx, cancel := clientContext()
+			_, err := c.DeleteServer(ctx, server)
+			cancel()
+			if err != nil {
+				return wrapError(err, "unable to delete server %s", server.PrettyString())
+			}
+		}
+		fmt.Printf("Deleted %s\n", summary)
+		return nil
+	})
+}
+
+// confirm prompts on stdout and returns true if the user answers yes on stdin.
+func confirm(prompt string) bool {
+	fmt.Printf("%s [y/N] ", prompt)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/sky-uk/merlin/types"
+	"github.com/spf13/cobra"
+)
+
+var describeCmd = &cobra.Command{
+	Use:   "describe [service]",
+	Short: "Show the details of a resource",
+}
+
+var describeServiceCmd = &cobra.Command{
+	Use:   "service [id]",
+	Short: "Show a virtual service with its real servers, recent changes and reconcile errors",
+	Long: `Show a virtual service with its config, its real servers with their weights and health, the most recent
+changes to the service and its servers, and any errors reconciling it. Health and errors are those of the node
+meradm is connected to, and changes are kept for 7 days.`,
+	Args: cobra.ExactArgs(1),
+	RunE: describeService,
+}
+
+var (
+	describeHistory uint32
+	describeOutput  string
+)
+
+func init() {
+	rootCmd.AddCommand(describeCmd)
+	describeCmd.AddCommand(describeServiceCmd)
+
+	describeServiceCmd.Flags().Uint32Var(&describeHistory, "history", 10, "number of recent changes to show")
+	addOutputFlag(describeServiceCmd, &describeOutput)
+}
+
+func describeService(_ *cobra.Command, args []string) error {
+	return client(func(c types.MerlinClient) error {
+		ctx, cancel := clientContext()
+		defer cancel()
+		resp, err := c.DescribeService(ctx, &types.DescribeServiceRequest{Id: args[0], History: describeHistory})
+		if err != nil {
+			return err
+		}
+		if ok, err := writeOutput(describeOutput, resp); ok {
+			return err
+		}
+		_, err = fmt.Fprint(os.Stdout, formatDescription(resp))
+		return err
+	})
+}
+
+// formatDescription renders a service description as a readable block of sections.
+func formatDescription(resp *types.DescribeServiceResponse) string {
+	var b bytes.Buffer
+	svc := resp.Service
+
+	w := tabwriter.NewWriter(&b, 0, 0, 1, ' ', 0)
+	fmt.Fprintf(w, "ID:\t%s\n", svc.Id)
+	fmt.Fprintf(w, "Protocol:\t%s\n", svc.Key.GetProtocol())
+	fmt.Fprintf(w, "Address:\t%s:%d\n", svc.Key.GetIp(), svc.Key.GetPort())
+	fmt.Fprintf(w, "Scheduler:\t%s\n", svc.Config.GetScheduler())
+	fmt.Fprintf(w, "Flags:\t%s\n", noneIfEmpty(serviceFlags(svc.Config)))
+	fmt.Fprintf(w, "Labels:\t%s\n", noneIfEmpty(types.PrettyLabels(svc.Labels)))
+	fmt.Fprintf(w, "Node Selector:\t%s\n", noneIfEmpty(svc.NodeSelector))
+	fmt.Fprintf(w, "Rollout:\t%s\n", formatRollout(svc.Rollout))
+	fmt.Fprintf(w, "Node:\t%s\n", resp.Node)
+	w.Flush()
+
+	fmt.Fprintf(&b, "\nServers:\n")
+	if len(resp.Servers) == 0 {
+		fmt.Fprintln(&b, "  <none>")
+	} else {
+		var table bytes.Buffer
+		colors := make(map[int]string)
+		w = tabwriter.NewWriter(&table, 0, 0, 1, ' ', 0)
+		fmt.Fprintln(w, "  RemoteAddress:Port\tForward\tWeight\tHealth\tHealthCheck\t")
+		for i, s := range resp.Servers {
+			server := s.Server
+			weight := strconv.FormatUint(uint64(server.Config.GetWeight().GetValue()), 10)
+			if server.DrainedWeight != nil {
+				weight = fmt.Sprintf("%s (drained from %d)", weight, server.DrainedWeight.Value)
+				colors[i+1] = colorYellow
+			}
+			health := "-"
+			switch s.Health {
+			case types.Health_UP:
+				colors[i+1] = colorGreen
+				health = strings.ToLower(s.Health.String())
+			case types.Health_DOWN:
+				colors[i+1] = colorRed
+				health = strings.ToLower(s.Health.String())
+			case types.Health_UNCHECKED:
+				health = strings.ToLower(s.Health.String())
+			}
+			fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s\t\n", serverStatsKey(server.Key), serverForward(server.Config), weight,
+				health, healthCheckString(server.HealthCheck))
+		}
+		w.Flush()
+		b.WriteString(colorLines(table.String(), colors))
+	}
+
+	if describeHistory > 0 {
+		fmt.Fprintf(&b, "\nHistory:\n")
+		if len(resp.History) == 0 {
+			fmt.Fprintln(&b, "  <none>")
+		}
+		w = tabwriter.NewWriter(&b, 0, 0, 1, ' ', 0)
+		for _, entry := range resp.History {
+			t, _ := ptypes.Timestamp(entry.Time)
+			fmt.Fprintf(w, "  %s\t%s\t%s\n", t.Local().Format(time.RFC3339), entry.Node, historyString(entry.Change))
+		}
+		w.Flush()
+	}
+
+	fmt.Fprintf(&b, "\nErrors on %s:\n", resp.Node)
+	if len(resp.Errors) == 0 {
+		fmt.Fprintln(&b, "  <none>")
+	}
+	var errs bytes.Buffer
+	colors := make(map[int]string)
+	for i, e := range resp.Errors {
+		fmt.Fprintf(&errs, "  %s\n", e)
+		colors[i] = colorRed
+	}
+	b.WriteString(colorLines(errs.String(), colors))
+	return b.String()
+}
+
+func healthCheckString(check *types.RealServer_HealthCheck) string {
+	if check.GetEndpoint().GetValue() == "" {
+		return "-"
+	}
+	period, _ := ptypes.Duration(check.Period)
+	timeout, _ := ptypes.Duration(check.Timeout)
+	return fmt.Sprintf("%s every %v, timeout %v, up/down %d/%d", check.Endpoint.Value, period, timeout,
+		check.UpThreshold, check.DownThreshold)
+}
+
+// historyString describes a change concisely, as the service is already shown.
+func historyString(change *types.Change) string {
+	action := strings.ToLower(change.GetAction().String())
+	switch {
+	case change.GetService() != nil && change.Action == types.Change_DELETE:
+		return fmt.Sprintf("%s service %s", action, change.Service.Id)
+	case change.GetService() != nil:
+		return fmt.Sprintf("%s service %s %s", action, change.Service.Id, change.Service.Config.PrettyString())
+	case change.Action == types.Change_DELETE:
+		return fmt.Sprintf("%s server %s", action, change.GetServer().GetKey().PrettyString())
+	default:
+		return fmt.Sprintf("%s server %s %s", action, change.GetServer().GetKey().PrettyString(),
+			change.GetServer().GetConfig().PrettyString())
+	}
+}
+
+func noneIfEmpty(s string) string {
+	if s == "" {
+		return "<none>"
+	}
+	return s
+}
+
+// formatRollout describes the state of a rollout in a line.
+func formatRollout(r *types.Rollout) string {
+	if r == nil {
+		return "<none>"
+	}
+	desc := fmt.Sprintf("%s [%s] to canaries %s", r.State, r.Config.PrettyString(), strings.Join(r.CanaryNodes, ","))
+	switch {
+	case r.State == types.Rollout_HALTED:
+		desc += ": " + r.Message
+	case r.Converged != nil:
+		desc += ", converged " + since(r.Converged)
+	}
+	return desc
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/sky-uk/merlin/hostcheck"
+	"github.com/sky-uk/merlin/types"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the connection to merlin and, on an IPVS node, the local kernel setup",
+	Long: `Check the connection to merlin, version compatibility and store health. With --local, also check the
+ip_vs kernel module, sysctls and the capabilities of the local merlin process. Each problem is printed with
+how to fix it, and meradm exits non-zero if any check fails.`,
+	Args: cobra.NoArgs,
+	RunE: runDoctor,
+}
+
+var doctorLocal bool
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+
+	doctorCmd.Flags().BoolVar(&doctorLocal, "local", runtime.GOOS == "linux",
+		"check the kernel setup of this host, for running on an IPVS node")
+}
+
+// doctor prints the result of each check, counting the failures.
+type doctor struct {
+	failures int
+}
+
+func (d *doctor) ok(format string, args ...interface{}) {
+	fmt.Printf("[ok]   "+format+"\n", args...)
+}
+
+func (d *doctor) warn(format string, args ...interface{}) {
+	fmt.Printf("[warn] "+format+"\n", args...)
+}
+
+func (d *doctor) fail(format string, args ...interface{}) {
+	d.failures++
+	fmt.Printf("[fail] "+format+"\n", args...)
+}
+
+func runDoctor(_ *cobra.Command, _ []string) error {
+	d := &doctor{}
+	d.checkServer()
+	if doctorLocal {
+		d.checkLocal()
+	}
+
+	if d.failures > 0 {
+		return fmt.Errorf("%d checks failed", d.failures)
+	}
+	return nil
+}
+
+func (d *doctor) checkServer() {
+	dest := fmt.Sprintf("%s:%d", host, port)
+	err := clientFor(dest, func(c types.MerlinClient) error {
+		ctx, cancel := clientContext()
+		defer cancel()
+		info, err := c.Info(ctx, &empty.Empty{})
+		if err != nil {
+			return err
+		}
+
+		d.ok("connected to merlin %s at %s", info.Node.Name, dest)
+		d.checkVersion(info.Node.Version)
+		if info.StoreError != "" {
+			d.fail("%s store is unhealthy, check merlin can reach it: %s", info.Node.StoreBackend, info.StoreError)
+		} else {
+			d.ok("%s store is healthy", info.Node.StoreBackend)
+		}
+		return nil
+	})
+	if err == nil {
+		return
+	}
+
+	switch status.Code(err) {
+	case codes.Unavailable:
+		d.fail("unable to connect to merlin at %s, check --host, --port and the --tls flags: %s", dest,
+			status.Convert(err).Message())
+	case codes.Unauthenticated, codes.PermissionDenied:
+		d.fail("merlin at %s rejected the credentials, check --token or --token-command: %s", dest,
+			status.Convert(err).Message())
+	case codes.Unimplemented:
+		d.fail("merlin at %s is older than meradm %s and doesn't support status checks, upgrade merlin", dest,
+			Version)
+	default:
+		d.fail("unable to get the status of merlin at %s: %v", dest, err)
+	}
+}
+
+func (d *doctor) checkVersion(serverVersion string) {
+	switch {
+	case serverVersion == Version:
+		d.ok("meradm and merlin are both version %s", Version)
+	case majorMinor(Version) != "" && majorMinor(Version) == majorMinor(serverVersion):
+		d.ok("meradm %s is compatible with merlin %s", Version, serverVersion)
+	case majorMinor(Version) == "" || majorMinor(serverVersion) == "":
+		d.warn("unable to check compatibility of meradm %q with merlin %q, use released versions of both",
+			Version, serverVersion)
+	default:
+		d.warn("meradm %s may be incompatible with merlin %s, use a meradm with the same minor version",
+			Version, serverVersion)
+	}
+}
+
+var versionRegex = regexp.MustCompile(`^v?(\d+)\.(\d+)(\.\d+)?$`)
+
+// majorMinor returns the major.minor of a release version, or "" for other builds.
+func majorMinor(version string) string {
+	m := versionRegex.FindStringSubmatch(version)
+	if m == nil {
+		return ""
+	}
+	return m[1] + "." + m[2]
+}
+
+func (d *doctor) checkLocal() {
+	if !hostcheck.IPVSLoaded() {
+		d.fail("ip_vs kernel module isn't loaded, load it with `modprobe ip_vs`")
+	} else {
+		d.ok("ip_vs kernel module is loaded")
+	}
+
+	for _, s := range hostcheck.RecommendedSysctls {
+		value, err := s.Read()
+		switch {
+		case err != nil:
+			d.warn("unable to read sysctl %s: %v", s.Name, err)
+		case value != s.Value:
+			d.warn("sysctl %s is %s, set it to %s with `sysctl -w %s=%s`, it %s", s.Name, value, s.Value, s.Name,
+				s.Value, s.Reason)
+		default:
+			d.ok("sysctl %s is %s", s.Name, s.Value)
+		}
+	}
+
+	pids, err := merlinPids()
+	if err != nil {
+		d.warn("unable to find local merlin processes: %v", err)
+		return
+	}
+	if len(pids) == 0 {
+		d.warn("merlin isn't running on this host")
+		return
+	}
+	for _, pid := range pids {
+		netAdmin, err := hostcheck.HasNetAdmin(pid)
+		switch {
+		case err != nil:
+			d.warn("unable to read capabilities of merlin (pid %d): %v", pid, err)
+		case !netAdmin:
+			d.fail("merlin (pid %d) lacks CAP_NET_ADMIN, run it as root or grant it with "+
+				"`setcap cap_net_admin+ep $(which merlin)`", pid)
+		default:
+			d.ok("merlin (pid %d) has CAP_NET_ADMIN", pid)
+		}
+	}
+}
+
+// merlinPids returns the pids of local merlin processes.
+func merlinPids() ([]int, error) {
+	comms, err := filepath.Glob("/proc/[0-9]*/comm")
+	if err != nil {
+		return nil, err
+	}
+	var pids []int
+	for _, comm := range comms {
+		name, err := ioutil.ReadFile(comm)
+		if err != nil || strings.TrimSpace(string(name)) != "merlin" {
+			continue
+		}
+		pid, err := strconv.Atoi(filepath.Base(filepath.Dir(comm)))
+		if err != nil {
+			continue
+		}
+		pids = append(pids, pid)
+	}
+	return pids, nil
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/sky-uk/merlin/types"
+	"github.com/spf13/cobra"
+)
+
+// dryRun makes the writes of a command dry runs, which print the changes they would make.
+var dryRun bool
+
+func addDryRunFlag(cmds ...*cobra.Command) {
+	for _, cmd := range cmds {
+		cmd.Flags().BoolVar(&dryRun, "dry-run", false,
+			"validate the change and print what it would change, without making it")
+	}
+}
+
+// printDryRun prints the changes of a dry run.
+func printDryRun(changes []*types.Change) {
+	if len(changes) == 0 {
+		fmt.Println("(dry run) no changes")
+	}
+	for _, change := range changes {
+		fmt.Printf("(dry run) %s\n", change.PrettyString())
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Exit codes of meradm, so scripts can handle failures without parsing error messages.
+const (
+	exitError            = 1
+	exitUsage            = 2
+	exitInvalid          = 3
+	exitNotFound         = 4
+	exitAlreadyExists    = 5
+	exitPermissionDenied = 6
+	exitUnavailable      = 7
+)
+
+const exitCodesHelp = `Exit codes:
+  0  success
+  1  other error
+  2  invalid command line usage, such as an unknown flag or missing argument
+  3  validation failure, such as an invalid service or selector
+  4  not found
+  5  already exists
+  6  permission denied or unauthenticated
+  7  merlin unavailable or timed out`
+
+// exitCodeError is an error which exits meradm with a specific code.
+type exitCodeError struct {
+	code int
+	err  error
+}
+
+func (e *exitCodeError) Error() string {
+	return e.err.Error()
+}
+
+func withExitCode(code int, err error) error {
+	return &exitCodeError{code: code, err: err}
+}
+
+// invalidf returns a validation failure.
+func invalidf(format string, args ...interface{}) error {
+	return withExitCode(exitInvalid, fmt.Errorf(format, args...))
+}
+
+// wrapError adds context to err, keeping its exit code.
+func wrapError(err error, format string, args ...interface{}) error {
+	return withExitCode(exitCode(err), fmt.Errorf(format+": %v", append(args, err)...))
+}
+
+// exitCode returns the code meradm exits with for err.
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	if e, ok := err.(*exitCodeError); ok {
+		return e.code
+	}
+	// cobra doesn't distinguish these usage errors
+	msg := err.Error()
+	if strings.HasPrefix(msg, "unknown command") || strings.HasPrefix(msg, "required flag(s)") {
+		return exitUsage
+	}
+
+	switch status.Code(err) {
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return exitInvalid
+	case codes.NotFound:
+		return exitNotFound
+	case codes.AlreadyExists:
+		return exitAlreadyExists
+	case codes.PermissionDenied, codes.Unauthenticated:
+		return exitPermissionDenied
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return exitUnavailable
+	default:
+		return exitError
+	}
+}
+
+// markUsageErrors gives flag and argument errors of cmd and its subcommands the usage exit code.
+func markUsageErrors(cmd *cobra.Command) {
+	cmd.SetFlagErrorFunc(func(_ *cobra.Command, err error) error {
+		return withExitCode(exitUsage, err)
+	})
+	if validArgs := cmd.Args; validArgs != nil {
+		cmd.Args = func(c *cobra.Command, args []string) error {
+			if err := validArgs(c, args); err != nil {
+				return withExitCode(exitUsage, err)
+			}
+			return nil
+		}
+	}
+	for _, sub := range cmd.Commands() {
+		markUsageErrors(sub)
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/sky-uk/merlin/types"
+	"github.com/spf13/cobra"
+)
+
+var getCmd = &cobra.Command{
+	Use:   "get [service|server]",
+	Short: "Print a resource, looked up by its ID",
+	Long: `Print a resource, looked up by its ID rather than by listing every service, as YAML unless --output is
+set.`,
+}
+
+var getServiceCmd = &cobra.Command{
+	Use:   "service [id]",
+	Short: "Print a virtual service with its real servers",
+	Args:  cobra.ExactArgs(1),
+	RunE:  getService,
+}
+
+var getServerCmd = &cobra.Command{
+	Use:   "server [serviceID] [ip:port]",
+	Short: "Print a real server of a virtual service",
+	Args:  validServiceIDIPPort,
+	RunE:  getServer,
+}
+
+var getOutput string
+
+func init() {
+	rootCmd.AddCommand(getCmd)
+	getCmd.AddCommand(getServiceCmd)
+	getCmd.AddCommand(getServerCmd)
+
+	addOutputFlag(getServiceCmd, &getOutput)
+	addOutputFlag(getServerCmd, &getOutput)
+}
+
+func getService(_ *cobra.Command, args []string) error {
+	return client(func(c types.MerlinClient) error {
+		ctx, cancel := clientContext()
+		defer cancel()
+		resp, err := c.GetService(ctx, &wrappers.StringValue{Value: args[0]})
+		if err != nil {
+			return err
+		}
+		_, err = writeOutput(getOutputFormat(), resp)
+		return err
+	})
+}
+
+func getServer(_ *cobra.Command, args []string) error {
+	ip, port, err := resolveAddress(args[1])
+	if err != nil {
+		return err
+	}
+	return client(func(c types.MerlinClient) error {
+		ctx, cancel := clientContext()
+		defer cancel()
+		server, err := c.GetServer(ctx, &types.RealServer{
+			ServiceID: args[0],
+			Key:       &types.RealServer_Key{Ip: ip, Port: port},
+		})
+		if err != nil {
+			return err
+		}
+		_, err = writeOutput(getOutputFormat(), server)
+		return err
+	})
+}
+
+// getOutputFormat returns --output, or yaml if it isn't set, as get has no table to print.
+func getOutputFormat() string {
+	if getOutput == "" {
+		return "yaml"
+	}
+	return getOutput
+}
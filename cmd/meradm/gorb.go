@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	log "github.com/sirupsen/logrus"
+	"github.com/sky-uk/merlin/types"
+)
+
+// Defaults of gorb when a service or backend doesn't set them.
+const (
+	gorbProtocol      = "tcp"
+	gorbScheduler     = "wrr"
+	gorbForwardMethod = "nat"
+	gorbWeight        = 100
+	gorbPulseInterval = time.Minute
+	gorbPulseTimeout  = 5 * time.Second
+)
+
+var gorbForwardMethods = map[string]types.ForwardMethod{
+	"dr":     types.ForwardMethod_ROUTE,
+	"tunnel": types.ForwardMethod_TUNNEL,
+	"ipip":   types.ForwardMethod_TUNNEL,
+	"nat":    types.ForwardMethod_MASQ,
+}
+
+var gorbProtocols = map[string]types.Protocol{
+	"tcp": types.Protocol_TCP,
+	"udp": types.Protocol_UDP,
+}
+
+// gorbService is the JSON gorb stores under services/<id>.
+type gorbService struct {
+	Host       string `json:"host"`
+	Port       uint16 `json:"port"`
+	Protocol   string `json:"protocol"`
+	Method     string `json:"method"`
+	Persistent bool   `json:"persistent"`
+	Flags      string `json:"flags"`
+}
+
+// gorbBackend is the JSON gorb stores under backends/<id>.
+type gorbBackend struct {
+	Host   string     `json:"host"`
+	Port   uint16     `json:"port"`
+	Weight *int32     `json:"weight"`
+	Method string     `json:"method"`
+	Pulse  *gorbPulse `json:"pulse"`
+	VsID   string     `json:"vs_id"`
+}
+
+type gorbPulse struct {
+	Type     string `json:"type"`
+	Interval string `json:"interval"`
+	Args     struct {
+		Path string `json:"path"`
+	} `json:"args"`
+}
+
+// parseGorb converts the keys of gorb's store, as printed by `etcdctl get --prefix`, to a snapshot. The input
+// alternates between a key and its value, and keys are read by their parent directory, services or backends.
+// Services keep their gorb ID, unless they're in ids.
+func parseGorb(data []byte, ids map[string]string) (*types.Snapshot, error) {
+	kvs := make(map[string]string)
+	var keys []string
+	in := bufio.NewScanner(bytes.NewReader(data))
+	in.Buffer(nil, 1<<20)
+	for in.Scan() {
+		key := strings.TrimSpace(in.Text())
+		if key == "" {
+			continue
+		}
+		if !in.Scan() {
+			return nil, invalidf("key %s has no value", key)
+		}
+		kvs[key] = in.Text()
+		keys = append(keys, key)
+	}
+	if err := in.Err(); err != nil {
+		return nil, err
+	}
+	sort.Strings(keys)
+
+	snapshot := &types.Snapshot{}
+	services := make(map[string]*types.VirtualService)
+	for _, key := range keys {
+		if path.Base(path.Dir(key)) != "services" {
+			continue
+		}
+		var gs gorbService
+		if err := json.Unmarshal([]byte(kvs[key]), &gs); err != nil {
+			return nil, invalidf("%s: %v", key, err)
+		}
+		svc, err := gorbToService(path.Base(key), &gs, ids)
+		if err != nil {
+			return nil, invalidf("%s: %v", key, err)
+		}
+		services[path.Base(key)] = svc
+		snapshot.Services = append(snapshot.Services, svc)
+	}
+	for _, key := range keys {
+		switch path.Base(path.Dir(key)) {
+		case "services":
+		case "backends":
+			var gb gorbBackend
+			if err := json.Unmarshal([]byte(kvs[key]), &gb); err != nil {
+				return nil, invalidf("%s: %v", key, err)
+			}
+			svc := services[gb.VsID]
+			if svc == nil {
+				return nil, invalidf("%s: backend of unknown service %q", key, gb.VsID)
+			}
+			server, err := gorbToServer(svc, &gb)
+			if err != nil {
+				return nil, invalidf("%s: %v", key, err)
+			}
+			snapshot.Servers = append(snapshot.Servers, server)
+		default:
+			log.Warnf("Ignoring %s, which isn't a gorb service or backend", key)
+		}
+	}
+	return snapshot, nil
+}
+
+func gorbToService(id string, gs *gorbService, ids map[string]string) (*types.VirtualService, error) {
+	if net.ParseIP(gs.Host) == nil {
+		return nil, fmt.Errorf("host %q must be an IP address", gs.Host)
+	}
+	if gs.Persistent {
+		return nil, fmt.Errorf("persistent services are not supported")
+	}
+	if gs.Protocol == "" {
+		gs.Protocol = gorbProtocol
+	}
+	protocol, ok := gorbProtocols[strings.ToLower(gs.Protocol)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported protocol %s", gs.Protocol)
+	}
+	if gs.Method == "" {
+		gs.Method = gorbScheduler
+	}
+	svc := &types.VirtualService{
+		Id:     id,
+		Key:    &types.VirtualService_Key{Ip: gs.Host, Port: uint32(gs.Port), Protocol: protocol},
+		Config: &types.VirtualService_Config{Scheduler: gs.Method},
+	}
+	for _, flag := range strings.FieldsFunc(gs.Flags, func(r rune) bool { return r == '|' || r == ',' }) {
+		f, ok := ipvsadmSchedulerFlags[flag]
+		if !ok {
+			return nil, fmt.Errorf("unsupported scheduler flag %s", flag)
+		}
+		svc.Config.Flags = append(svc.Config.Flags, f)
+	}
+	if mapped := ids[idMapKey(svc.Key)]; mapped != "" {
+		svc.Id = mapped
+	}
+	return svc, nil
+}
+
+func gorbToServer(svc *types.VirtualService, gb *gorbBackend) (*types.RealServer, error) {
+	if net.ParseIP(gb.Host) == nil {
+		return nil, fmt.Errorf("host %q must be an IP address", gb.Host)
+	}
+	if gb.Method == "" {
+		gb.Method = gorbForwardMethod
+	}
+	forward, ok := gorbForwardMethods[strings.ToLower(gb.Method)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported method %s", gb.Method)
+	}
+	weight := uint32(gorbWeight)
+	if gb.Weight != nil {
+		if *gb.Weight < 0 {
+			return nil, fmt.Errorf("invalid weight %d", *gb.Weight)
+		}
+		weight = uint32(*gb.Weight)
+	}
+	server := &types.RealServer{
+		ServiceID:   svc.Id,
+		Key:         &types.RealServer_Key{Ip: gb.Host, Port: uint32(gb.Port)},
+		Config:      &types.RealServer_Config{Weight: &wrappers.UInt32Value{Value: weight}, Forward: forward},
+		HealthCheck: &types.RealServer_HealthCheck{},
+	}
+
+	pulse := gb.Pulse
+	if pulse == nil {
+		pulse = &gorbPulse{}
+	}
+	switch strings.ToLower(pulse.Type) {
+	case "", "none":
+	case "http":
+		interval := gorbPulseInterval
+		if pulse.Interval != "" {
+			var err error
+			if interval, err = time.ParseDuration(pulse.Interval); err != nil {
+				return nil, fmt.Errorf("invalid pulse interval: %v", err)
+			}
+		}
+		timeout := gorbPulseTimeout
+		if timeout > interval {
+			timeout = interval
+		}
+		p := pulse.Args.Path
+		if !strings.HasPrefix(p, "/") {
+			p = "/" + p
+		}
+		server.HealthCheck = &types.RealServer_HealthCheck{
+			Endpoint:      &wrappers.StringValue{Value: fmt.Sprintf("http://:%d%s", gb.Port, p)},
+			Period:        ptypes.DurationProto(interval),
+			Timeout:       ptypes.DurationProto(timeout),
+			UpThreshold:   1,
+			DownThreshold: 1,
+		}
+	default:
+		log.Warnf("Importing %s of %s without a health check, as %s pulses are not supported", gb.Host, svc.Id,
+			pulse.Type)
+	}
+	return server, nil
+}
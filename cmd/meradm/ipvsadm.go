@@ -0,0 +1,326 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/sky-uk/merlin/types"
+	"gopkg.in/yaml.v2"
+)
+
+// ipvsadm scheduler flag names, which merlin calls flag-1, flag-2 and flag-3.
+var ipvsadmSchedulerFlags = map[string]string{
+	"flag-1":      "flag-1",
+	"flag-2":      "flag-2",
+	"flag-3":      "flag-3",
+	"sh-fallback": "flag-1",
+	"sh-port":     "flag-2",
+	"mh-fallback": "flag-1",
+	"mh-port":     "flag-2",
+}
+
+var ipvsadmForwardMethods = map[string]types.ForwardMethod{
+	"-g": types.ForwardMethod_ROUTE,
+	"-i": types.ForwardMethod_TUNNEL,
+	"-m": types.ForwardMethod_MASQ,
+}
+
+// tunnelTypeNames and tunnelChecksumNames are the names of tunnel options in ipvsadm and keepalived.
+var (
+	tunnelTypeNames = map[string]types.TunnelType{
+		"ipip": types.TunnelType_IPIP,
+		"gue":  types.TunnelType_GUE,
+	}
+	tunnelChecksumNames = map[string]types.TunnelChecksum{
+		"nocsum":  types.TunnelChecksum_NO_CHECKSUM,
+		"csum":    types.TunnelChecksum_CHECKSUM,
+		"remcsum": types.TunnelChecksum_REMOTE_CHECKSUM,
+	}
+)
+
+var ipvsadmProtocols = map[string]types.Protocol{
+	"-t": types.Protocol_TCP,
+	"-u": types.Protocol_UDP,
+}
+
+// idMapKey identifies a virtual service in an ID map, e.g. tcp/10.1.1.1:80.
+func idMapKey(key *types.VirtualService_Key) string {
+	return strings.ToLower(key.Protocol.String()) + "/" + net.JoinHostPort(key.Ip, strconv.Itoa(int(key.Port)))
+}
+
+// mappedServiceID returns the ID of an imported service from the ID map, defaulting to e.g. tcp-10-1-1-1-80.
+func mappedServiceID(key *types.VirtualService_Key, ids map[string]string) string {
+	if id := ids[idMapKey(key)]; id != "" {
+		return id
+	}
+	ip := strings.NewReplacer(".", "-", ":", "-").Replace(key.Ip)
+	return fmt.Sprintf("%s-%s-%d", strings.ToLower(key.Protocol.String()), ip, key.Port)
+}
+
+// readIDMap reads a YAML map of service names, as returned by idMapKey, to service IDs.
+func readIDMap(file string) (map[string]string, error) {
+	ids := make(map[string]string)
+	if file == "" {
+		return ids, nil
+	}
+	data, err := readInput(file)
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.UnmarshalStrict(data, &ids); err != nil {
+		return nil, invalidf("unable to decode %s: %v", file, err)
+	}
+	return ids, nil
+}
+
+// parseIpvsadmSave converts the rules printed by `ipvsadm -S -n` to a snapshot. Services are given the
+// IDs in ids, or a default ID if they're missing.
+func parseIpvsadmSave(data []byte, ids map[string]string) (*types.Snapshot, error) {
+	snapshot := &types.Snapshot{}
+	services := make(map[string]*types.VirtualService)
+
+	in := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; in.Scan(); n++ {
+		fields := strings.Fields(in.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		var err error
+		switch fields[0] {
+		case "-A":
+			var svc *types.VirtualService
+			if svc, err = parseIpvsadmService(fields[1:], ids); err == nil {
+				name := idMapKey(svc.Key)
+				if services[name] != nil {
+					err = fmt.Errorf("duplicate service %s", name)
+				}
+				services[name] = svc
+				snapshot.Services = append(snapshot.Services, svc)
+			}
+		case "-a":
+			var server *types.RealServer
+			if server, err = parseIpvsadmServer(fields[1:], services); err == nil {
+				snapshot.Servers = append(snapshot.Servers, server)
+			}
+		default:
+			err = fmt.Errorf("unsupported command %s, expected -A or -a", fields[0])
+		}
+		if err != nil {
+			return nil, invalidf("line %d: %v", n, err)
+		}
+	}
+	return snapshot, in.Err()
+}
+
+func parseIpvsadmService(args []string, ids map[string]string) (*types.VirtualService, error) {
+	svc := &types.VirtualService{Config: &types.VirtualService_Config{}}
+	err := parseIpvsadmOptions(args, func(opt, val string) error {
+		switch opt {
+		case "-t", "-u":
+			key, err := parseIpvsadmKey(opt, val)
+			svc.Key = key
+			return err
+		case "-s":
+			svc.Config.Scheduler = val
+		case "-o":
+			svc.Config.Ops = true
+		case "-b":
+			for _, flag := range strings.Split(val, ",") {
+				f, ok := ipvsadmSchedulerFlags[flag]
+				if !ok {
+					return fmt.Errorf("unsupported scheduler flag %s", flag)
+				}
+				svc.Config.Flags = append(svc.Config.Flags, f)
+			}
+		default:
+			return fmt.Errorf("unsupported service option %s", opt)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if svc.Key == nil {
+		return nil, fmt.Errorf("service requires -t or -u")
+	}
+
+	svc.Id = mappedServiceID(svc.Key, ids)
+	return svc, nil
+}
+
+func parseIpvsadmServer(args []string, services map[string]*types.VirtualService) (*types.RealServer, error) {
+	server := &types.RealServer{
+		Config:      &types.RealServer_Config{},
+		HealthCheck: &types.RealServer_HealthCheck{},
+	}
+	err := parseIpvsadmOptions(args, func(opt, val string) error {
+		switch opt {
+		case "-t", "-u":
+			key, err := parseIpvsadmKey(opt, val)
+			if err != nil {
+				return err
+			}
+			svc := services[idMapKey(key)]
+			if svc == nil {
+				return fmt.Errorf("server of %s precedes its service", idMapKey(key))
+			}
+			server.ServiceID = svc.Id
+		case "-r":
+			ip, port, err := splitIpvsadmAddress(val)
+			server.Key = &types.RealServer_Key{Ip: ip, Port: port}
+			return err
+		case "-g", "-i", "-m":
+			server.Config.Forward = ipvsadmForwardMethods[opt]
+		case "-w":
+			w, err := strconv.ParseUint(val, 10, 32)
+			server.Config.Weight = &wrappers.UInt32Value{Value: uint32(w)}
+			return err
+		case "--tun-type":
+			tunType, ok := tunnelTypeNames[val]
+			if !ok {
+				return fmt.Errorf("unsupported tunnel type %s", val)
+			}
+			server.Config.TunnelType = tunType
+		case "--tun-port":
+			p, err := strconv.ParseUint(val, 10, 16)
+			server.Config.TunnelPort = uint32(p)
+			return err
+		case "--tun-nocsum", "--tun-csum", "--tun-remcsum":
+			server.Config.TunnelChecksum = tunnelChecksumNames[strings.TrimPrefix(opt, "--tun-")]
+		default:
+			return fmt.Errorf("unsupported server option %s", opt)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if server.ServiceID == "" {
+		return nil, fmt.Errorf("server requires -t or -u")
+	}
+	return server, nil
+}
+
+// parseIpvsadmOptions calls fn with each option and its value, which is empty for the forward method options, -o
+// and the tunnel checksum options.
+func parseIpvsadmOptions(args []string, fn func(opt, val string) error) error {
+	for i := 0; i < len(args); i++ {
+		opt, val := args[i], ""
+		_, isForward := ipvsadmForwardMethods[opt]
+		_, isChecksum := tunnelChecksumNames[strings.TrimPrefix(opt, "--tun-")]
+		isChecksum = isChecksum && strings.HasPrefix(opt, "--tun-")
+		if !isForward && !isChecksum && opt != "-o" {
+			if i+1 >= len(args) {
+				return fmt.Errorf("option %s requires a value", opt)
+			}
+			i++
+			val = args[i]
+		}
+		if err := fn(opt, val); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseIpvsadmKey(opt, addr string) (*types.VirtualService_Key, error) {
+	ip, port, err := splitIpvsadmAddress(addr)
+	if err != nil {
+		return nil, err
+	}
+	return &types.VirtualService_Key{Ip: ip, Port: port, Protocol: ipvsadmProtocols[opt]}, nil
+}
+
+// splitIpvsadmAddress splits ip:port or [ipv6]:port, resolving hostnames if ipvsadm was run without -n.
+func splitIpvsadmAddress(addr string) (string, uint32, error) {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", 0, err
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid port %s, use ipvsadm -S -n for numeric ports", portStr)
+	}
+	ip, err := resolveHost(host)
+	return ip, uint32(port), err
+}
+
+// writeIpvsadmSave converts a snapshot to rules which can be restored with `ipvsadm -R`.
+func writeIpvsadmSave(snapshot *types.Snapshot) ([]byte, error) {
+	protocols := make(map[types.Protocol]string)
+	for opt, protocol := range ipvsadmProtocols {
+		protocols[protocol] = opt
+	}
+	forwardMethods := make(map[types.ForwardMethod]string)
+	for opt, forward := range ipvsadmForwardMethods {
+		forwardMethods[forward] = opt
+	}
+
+	servers := make(map[string][]*types.RealServer)
+	for _, server := range snapshot.Servers {
+		servers[server.ServiceID] = append(servers[server.ServiceID], server)
+	}
+
+	var out bytes.Buffer
+	for _, svc := range snapshot.Services {
+		protocol, ok := protocols[svc.Key.Protocol]
+		if !ok {
+			return nil, fmt.Errorf("service %s has unsupported protocol %s", svc.Id, svc.Key.Protocol)
+		}
+		addr := net.JoinHostPort(svc.Key.Ip, strconv.Itoa(int(svc.Key.Port)))
+		fmt.Fprintf(&out, "-A %s %s -s %s", protocol, addr, svc.Config.Scheduler)
+		if len(svc.Config.Flags) > 0 {
+			fmt.Fprintf(&out, " -b %s", strings.Join(svc.Config.Flags, ","))
+		}
+		if svc.Config.Ops {
+			out.WriteString(" -o")
+		}
+		out.WriteString("\n")
+
+		for _, server := range servers[svc.Id] {
+			forward, ok := forwardMethods[server.Config.Forward]
+			if !ok {
+				return nil, fmt.Errorf("server %s has unsupported forward method %s", server.PrettyString(),
+					server.Config.Forward)
+			}
+			var weight uint32
+			if server.Config.Weight != nil {
+				weight = server.Config.Weight.Value
+			}
+			fmt.Fprintf(&out, "-a %s %s -r %s %s -w %d", protocol, addr,
+				net.JoinHostPort(server.Key.Ip, strconv.Itoa(int(server.Key.Port))), forward, weight)
+			if server.Config.TunnelType == types.TunnelType_GUE {
+				fmt.Fprintf(&out, " --tun-type %s --tun-port %d --tun-%s", tunnelName(server.Config.TunnelType),
+					server.Config.TunnelPort, tunnelChecksumName(server.Config.TunnelChecksum))
+			}
+			out.WriteString("\n")
+		}
+	}
+	return out.Bytes(), nil
+}
+
+// tunnelName returns the ipvsadm and keepalived name of a tunnel type.
+func tunnelName(tunType types.TunnelType) string {
+	for name, t := range tunnelTypeNames {
+		if t == tunType {
+			return name
+		}
+	}
+	return strings.ToLower(tunType.String())
+}
+
+// tunnelChecksumName returns the ipvsadm and keepalived name of a tunnel checksum.
+func tunnelChecksumName(checksum types.TunnelChecksum) string {
+	for name, c := range tunnelChecksumNames {
+		if c == checksum {
+			return name
+		}
+	}
+	return strings.ToLower(checksum.String())
+}
@@ -0,0 +1,513 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	log "github.com/sirupsen/logrus"
+	"github.com/sky-uk/merlin/types"
+)
+
+// Defaults of keepalived when a health check doesn't set them.
+const (
+	keepalivedDelayLoop      = 60 * time.Second
+	keepalivedConnectTimeout = 5 * time.Second
+	keepalivedRetry          = 1
+)
+
+var keepalivedForwardMethods = map[string]types.ForwardMethod{
+	"DR":  types.ForwardMethod_ROUTE,
+	"TUN": types.ForwardMethod_TUNNEL,
+	"NAT": types.ForwardMethod_MASQ,
+}
+
+var keepalivedSchedulerFlags = map[string]string{
+	"sh-fallback": "flag-1",
+	"sh-port":     "flag-2",
+	"mh-fallback": "flag-1",
+	"mh-port":     "flag-2",
+}
+
+// keepalivedStatement is a keyword with its arguments, and the statements of its block if it has one.
+type keepalivedStatement struct {
+	line     int
+	args     []string
+	children []*keepalivedStatement
+}
+
+func (s *keepalivedStatement) keyword() string {
+	return s.args[0]
+}
+
+// value returns the single argument of the statement.
+func (s *keepalivedStatement) value() (string, error) {
+	if len(s.args) != 2 {
+		return "", s.errorf("%s requires a single value", s.keyword())
+	}
+	return s.args[1], nil
+}
+
+func (s *keepalivedStatement) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("line %d: %s", s.line, fmt.Sprintf(format, args...))
+}
+
+func (s *keepalivedStatement) ignore() {
+	log.Warnf("Line %d: ignoring unsupported %s", s.line, s.keyword())
+}
+
+// parseKeepalived converts the virtual_server blocks of keepalived.conf to a snapshot, ignoring the rest of the
+// configuration such as VRRP. Services are given the IDs in ids, or a default ID if they're missing.
+func parseKeepalived(data []byte, ids map[string]string) (*types.Snapshot, error) {
+	statements, err := parseKeepalivedStatements(data)
+	if err != nil {
+		return nil, invalidf("%v", err)
+	}
+
+	snapshot := &types.Snapshot{}
+	seen := make(map[string]bool)
+	for _, s := range statements {
+		switch s.keyword() {
+		case "virtual_server":
+			svc, servers, err := keepalivedService(s, ids)
+			if err != nil {
+				return nil, invalidf("%v", err)
+			}
+			name := idMapKey(svc.Key)
+			if seen[name] {
+				return nil, invalidf("%v", s.errorf("duplicate virtual_server %s", name))
+			}
+			seen[name] = true
+			snapshot.Services = append(snapshot.Services, svc)
+			snapshot.Servers = append(snapshot.Servers, servers...)
+		case "virtual_server_group", "include":
+			return nil, invalidf("%v", s.errorf("%s is not supported", s.keyword()))
+		}
+	}
+	return snapshot, nil
+}
+
+func keepalivedService(s *keepalivedStatement, ids map[string]string) (*types.VirtualService,
+	[]*types.RealServer, error) {
+	if len(s.args) != 3 || s.args[1] == "fwmark" || s.args[1] == "group" {
+		return nil, nil, s.errorf("virtual_server requires an ip and port, fwmark and group aren't supported")
+	}
+	key, err := keepalivedKey(s)
+	if err != nil {
+		return nil, nil, err
+	}
+	svc := &types.VirtualService{
+		Key:    &types.VirtualService_Key{Ip: key.Ip, Port: key.Port, Protocol: types.Protocol_TCP},
+		Config: &types.VirtualService_Config{},
+	}
+
+	forward := &types.RealServer_Config{}
+	delayLoop := keepalivedDelayLoop
+	var realServers []*keepalivedStatement
+	for _, c := range s.children {
+		var err error
+		switch c.keyword() {
+		case "lb_algo", "lvs_sched":
+			svc.Config.Scheduler, err = c.value()
+		case "lb_kind", "lvs_method":
+			err = keepalivedForward(c, forward)
+		case "protocol":
+			var protocol string
+			if protocol, err = c.value(); err == nil {
+				p, ok := types.Protocol_value[strings.ToUpper(protocol)]
+				if !ok || p == int32(types.Protocol_UNSET_PROTOCOL) {
+					err = c.errorf("unsupported protocol %s", protocol)
+				}
+				svc.Key.Protocol = types.Protocol(p)
+			}
+		case "delay_loop":
+			delayLoop, err = keepalivedSeconds(c)
+		case "sh-fallback", "sh-port", "mh-fallback", "mh-port":
+			svc.Config.Flags = append(svc.Config.Flags, keepalivedSchedulerFlags[c.keyword()])
+		case "ops":
+			svc.Config.Ops = true
+		case "persistence_timeout", "persistence_granularity", "sorry_server":
+			err = c.errorf("%s is not supported", c.keyword())
+		case "real_server":
+			realServers = append(realServers, c)
+		default:
+			c.ignore()
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	if svc.Config.Scheduler == "" {
+		return nil, nil, s.errorf("virtual_server requires lb_algo")
+	}
+	svc.Id = mappedServiceID(svc.Key, ids)
+
+	var servers []*types.RealServer
+	for _, r := range realServers {
+		server, err := keepalivedServer(r, forward, delayLoop)
+		if err != nil {
+			return nil, nil, err
+		}
+		server.ServiceID = svc.Id
+		servers = append(servers, server)
+	}
+	return svc, servers, nil
+}
+
+func keepalivedServer(s *keepalivedStatement, forward *types.RealServer_Config, delayLoop time.Duration) (
+	*types.RealServer, error) {
+	if len(s.args) != 3 {
+		return nil, s.errorf("real_server requires an ip and port")
+	}
+	key, err := keepalivedKey(s)
+	if err != nil {
+		return nil, err
+	}
+	server := &types.RealServer{
+		Key:         &types.RealServer_Key{Ip: key.Ip, Port: key.Port},
+		Config:      proto.Clone(forward).(*types.RealServer_Config),
+		HealthCheck: &types.RealServer_HealthCheck{},
+	}
+	server.Config.Weight = &wrappers.UInt32Value{Value: 1}
+
+	for _, c := range s.children {
+		var err error
+		switch c.keyword() {
+		case "weight":
+			var weight string
+			if weight, err = c.value(); err == nil {
+				var w uint64
+				w, err = strconv.ParseUint(weight, 10, 32)
+				server.Config.Weight.Value = uint32(w)
+			}
+		case "lb_kind", "lvs_method":
+			err = keepalivedForward(c, server.Config)
+		case "HTTP_GET":
+			server.HealthCheck, err = keepalivedHealthCheck(c, server.Key.Port, delayLoop)
+		case "SSL_GET", "TCP_CHECK", "UDP_CHECK", "SMTP_CHECK", "DNS_CHECK", "MISC_CHECK", "BFD_CHECK",
+			"PING_CHECK", "FILE_CHECK":
+			log.Warnf("Line %d: ignoring unsupported %s, only HTTP_GET health checks are supported", c.line,
+				c.keyword())
+		default:
+			c.ignore()
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if server.Config.Forward == types.ForwardMethod_UNSET_FORWARD_METHOD {
+		return nil, s.errorf("real_server requires lb_kind, on it or its virtual_server")
+	}
+	return server, nil
+}
+
+func keepalivedHealthCheck(s *keepalivedStatement, port uint32, delayLoop time.Duration) (
+	*types.RealServer_HealthCheck, error) {
+	path := "/"
+	timeout := keepalivedConnectTimeout
+	retry := uint64(keepalivedRetry)
+	for _, c := range s.children {
+		var err error
+		switch c.keyword() {
+		case "url":
+			// url blocks are often written on one line, so options are read in pairs
+			for _, u := range c.children {
+				for i := 0; i+1 < len(u.args); i += 2 {
+					if u.args[i] == "path" {
+						path = u.args[i+1]
+					}
+				}
+			}
+		case "connect_port":
+			var p string
+			if p, err = c.value(); err == nil {
+				var n uint64
+				n, err = strconv.ParseUint(p, 10, 16)
+				port = uint32(n)
+			}
+		case "connect_timeout":
+			timeout, err = keepalivedSeconds(c)
+		case "delay_loop":
+			delayLoop, err = keepalivedSeconds(c)
+		case "retry", "nb_get_retry":
+			var r string
+			if r, err = c.value(); err == nil {
+				retry, err = strconv.ParseUint(r, 10, 32)
+			}
+		case "delay_before_retry":
+		default:
+			c.ignore()
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	if retry == 0 {
+		retry = 1
+	}
+
+	return &types.RealServer_HealthCheck{
+		Endpoint:      &wrappers.StringValue{Value: fmt.Sprintf("http://:%d%s", port, path)},
+		Period:        ptypes.DurationProto(delayLoop),
+		Timeout:       ptypes.DurationProto(timeout),
+		UpThreshold:   1,
+		DownThreshold: uint32(retry),
+	}, nil
+}
+
+func keepalivedKey(s *keepalivedStatement) (*types.VirtualService_Key, error) {
+	if net.ParseIP(s.args[1]) == nil {
+		return nil, s.errorf("invalid ip %s", s.args[1])
+	}
+	port, err := strconv.ParseUint(s.args[2], 10, 16)
+	if err != nil {
+		return nil, s.errorf("invalid port %s", s.args[2])
+	}
+	return &types.VirtualService_Key{Ip: s.args[1], Port: uint32(port)}, nil
+}
+
+// keepalivedForward sets the forward method of config, and its tunnel if it's TUN with options such as
+// "TUN type gue port 6080 csum".
+func keepalivedForward(s *keepalivedStatement, config *types.RealServer_Config) error {
+	if len(s.args) < 2 {
+		return s.errorf("%s requires a value", s.keyword())
+	}
+	kind := s.args[1]
+	forward, ok := keepalivedForwardMethods[strings.ToUpper(kind)]
+	if !ok {
+		return s.errorf("unsupported %s %s", s.keyword(), kind)
+	}
+	options := s.args[2:]
+	if len(options) > 0 && forward != types.ForwardMethod_TUNNEL {
+		return s.errorf("%s %s requires a single value", s.keyword(), kind)
+	}
+	config.Forward = forward
+	config.TunnelType = types.TunnelType_IPIP
+	config.TunnelPort = 0
+	config.TunnelChecksum = types.TunnelChecksum_NO_CHECKSUM
+	for i := 0; i < len(options); i++ {
+		option := options[i]
+		if checksum, ok := tunnelChecksumNames[option]; ok {
+			config.TunnelChecksum = checksum
+			continue
+		}
+		if i+1 >= len(options) {
+			return s.errorf("%s %s requires a value", s.keyword(), option)
+		}
+		i++
+		switch option {
+		case "type":
+			tunType, ok := tunnelTypeNames[options[i]]
+			if !ok {
+				return s.errorf("unsupported tunnel type %s", options[i])
+			}
+			config.TunnelType = tunType
+		case "port":
+			port, err := strconv.ParseUint(options[i], 10, 16)
+			if err != nil {
+				return s.errorf("invalid tunnel port %s", options[i])
+			}
+			config.TunnelPort = uint32(port)
+		default:
+			return s.errorf("unsupported %s option %s", s.keyword(), option)
+		}
+	}
+	return nil
+}
+
+// keepalivedSeconds parses a duration in seconds, which keepalived allows to be fractional.
+func keepalivedSeconds(s *keepalivedStatement) (time.Duration, error) {
+	v, err := s.value()
+	if err != nil {
+		return 0, err
+	}
+	seconds, err := strconv.ParseFloat(v, 64)
+	if err != nil || seconds <= 0 {
+		return 0, s.errorf("invalid %s %s", s.keyword(), v)
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// parseKeepalivedStatements splits keepalived.conf into statements, one per line, with blocks in braces.
+func parseKeepalivedStatements(data []byte) ([]*keepalivedStatement, error) {
+	root := &keepalivedStatement{}
+	stack := []*keepalivedStatement{root}
+	var last *keepalivedStatement
+
+	in := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; in.Scan(); n++ {
+		line := in.Text()
+		if i := strings.IndexAny(line, "#!"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.NewReplacer("{", " { ", "}", " } ").Replace(line)
+
+		var current *keepalivedStatement
+		for _, token := range strings.Fields(line) {
+			parent := stack[len(stack)-1]
+			switch token {
+			case "{":
+				// the brace may be on the line after its keyword
+				if current == nil {
+					current = last
+				}
+				if current == nil || current.children != nil {
+					return nil, fmt.Errorf("line %d: unexpected {", n)
+				}
+				current.children = []*keepalivedStatement{}
+				stack = append(stack, current)
+				current = nil
+			case "}":
+				if len(stack) == 1 {
+					return nil, fmt.Errorf("line %d: unexpected }", n)
+				}
+				stack = stack[:len(stack)-1]
+				current, last = nil, nil
+			default:
+				if current == nil {
+					current = &keepalivedStatement{line: n}
+					parent.children = append(parent.children, current)
+					last = current
+				}
+				current.args = append(current.args, token)
+			}
+		}
+	}
+	if err := in.Err(); err != nil {
+		return nil, err
+	}
+	if len(stack) > 1 {
+		return nil, fmt.Errorf("line %d: missing }", stack[len(stack)-1].line)
+	}
+	return root.children, nil
+}
+
+// writeKeepalived converts a snapshot to virtual_server blocks of keepalived.conf. Health check up thresholds
+// and scheduler flags without a keepalived equivalent are dropped with a warning.
+func writeKeepalived(snapshot *types.Snapshot) ([]byte, error) {
+	kinds := make(map[types.ForwardMethod]string)
+	for kind, forward := range keepalivedForwardMethods {
+		kinds[forward] = kind
+	}
+	servers := make(map[string][]*types.RealServer)
+	for _, server := range snapshot.Servers {
+		servers[server.ServiceID] = append(servers[server.ServiceID], server)
+	}
+
+	var out bytes.Buffer
+	out.WriteString("# Generated by meradm export\n")
+	for _, svc := range snapshot.Services {
+		if svc.Key.Protocol == types.Protocol_UNSET_PROTOCOL {
+			return nil, fmt.Errorf("service %s has no protocol", svc.Id)
+		}
+		fmt.Fprintf(&out, "\n# %s\nvirtual_server %s %d {\n", svc.Id, svc.Key.Ip, svc.Key.Port)
+		fmt.Fprintf(&out, "    lb_algo %s\n", svc.Config.Scheduler)
+		fmt.Fprintf(&out, "    protocol %s\n", svc.Key.Protocol)
+		for _, flag := range svc.Config.Flags {
+			option := keepalivedSchedulerOption(svc.Config.Scheduler, flag)
+			if option == "" {
+				log.Warnf("Dropping scheduler flag %s of service %s, which keepalived doesn't support with %s",
+					flag, svc.Id, svc.Config.Scheduler)
+				continue
+			}
+			fmt.Fprintf(&out, "    %s\n", option)
+		}
+		if svc.Config.Ops {
+			out.WriteString("    ops\n")
+		}
+
+		for _, server := range servers[svc.Id] {
+			kind, ok := kinds[server.Config.Forward]
+			if !ok {
+				return nil, fmt.Errorf("server %s has unsupported forward method %s", server.PrettyString(),
+					server.Config.Forward)
+			}
+			var weight uint32
+			if server.Config.Weight != nil {
+				weight = server.Config.Weight.Value
+			}
+			fmt.Fprintf(&out, "    real_server %s %d {\n", server.Key.Ip, server.Key.Port)
+			if server.Config.TunnelType == types.TunnelType_GUE {
+				kind += fmt.Sprintf(" type %s port %d %s", tunnelName(server.Config.TunnelType),
+					server.Config.TunnelPort, tunnelChecksumName(server.Config.TunnelChecksum))
+			}
+			fmt.Fprintf(&out, "        lb_kind %s\n", kind)
+			fmt.Fprintf(&out, "        weight %d\n", weight)
+			if err := writeKeepalivedHealthCheck(&out, server); err != nil {
+				return nil, err
+			}
+			out.WriteString("    }\n")
+		}
+		out.WriteString("}\n")
+	}
+	return out.Bytes(), nil
+}
+
+func writeKeepalivedHealthCheck(out *bytes.Buffer, server *types.RealServer) error {
+	check := server.HealthCheck
+	if check.GetEndpoint().GetValue() == "" {
+		return nil
+	}
+	u, err := url.Parse(check.Endpoint.Value)
+	if err != nil {
+		return fmt.Errorf("server %s has invalid health check endpoint: %v", server.PrettyString(), err)
+	}
+	period, err := ptypes.Duration(check.Period)
+	if err != nil {
+		return err
+	}
+	timeout, err := ptypes.Duration(check.Timeout)
+	if err != nil {
+		return err
+	}
+	if check.UpThreshold > 1 {
+		log.Warnf("Dropping health check up threshold of server %s, which keepalived doesn't support",
+			server.PrettyString())
+	}
+
+	path := u.EscapedPath()
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	fmt.Fprintf(out, "        HTTP_GET {\n")
+	fmt.Fprintf(out, "            url {\n                path %s\n                status_code 200\n            }\n", path)
+	if u.Port() != "" {
+		fmt.Fprintf(out, "            connect_port %s\n", u.Port())
+	}
+	fmt.Fprintf(out, "            connect_timeout %s\n", formatSeconds(timeout))
+	fmt.Fprintf(out, "            retry %d\n", check.DownThreshold)
+	fmt.Fprintf(out, "            delay_loop %s\n", formatSeconds(period))
+	fmt.Fprintf(out, "        }\n")
+	return nil
+}
+
+// keepalivedSchedulerOption returns the keepalived option of a scheduler flag, or empty if there isn't one.
+func keepalivedSchedulerOption(scheduler, flag string) string {
+	if scheduler != "sh" && scheduler != "mh" {
+		return ""
+	}
+	if f, ok := types.SchedulerFlag(scheduler, flag); ok {
+		flag = f
+	}
+	for option, f := range keepalivedSchedulerFlags {
+		if f == flag && strings.HasPrefix(option, scheduler+"-") {
+			return option
+		}
+	}
+	return ""
+}
+
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+}
@@ -1,7 +1,11 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
+	"net"
+	"sort"
+	"strconv"
 	"strings"
 
 	"os"
@@ -9,6 +13,7 @@ import (
 
 	"github.com/golang/protobuf/ptypes"
 	"github.com/golang/protobuf/ptypes/empty"
+	log "github.com/sirupsen/logrus"
 	"github.com/sky-uk/merlin/types"
 	"github.com/spf13/cobra"
 )
@@ -16,62 +21,344 @@ import (
 var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List IPVS services and servers",
-	RunE:  list,
+	Long: `List IPVS services and servers, with the connections and health of servers on the node meradm is
+connected to. Unhealthy servers are shown in red and drained servers in yellow, unless --no-color is set.`,
+	RunE: list,
 }
 
+var (
+	listSelector       string
+	listFieldSelector  string
+	listServerSelector string
+	listIDPrefix       string
+	listSortBy         string
+	listOutput         string
+	listLimit          int
+	listContinue       string
+	listAllNamespaces  bool
+)
+
+// listPageSize is how many services list requests at a time, so large lists don't time out.
+const listPageSize = 500
+
 func init() {
 	rootCmd.AddCommand(listCmd)
+
+	f := listCmd.Flags()
+	f.StringVarP(&listSelector, "selector", "l", "",
+		"label selector, e.g. 'team=payments,env!=prod', supports =, ==, !=, key and !key")
+	f.StringVar(&listFieldSelector, "field-selector", "",
+		"field selector on "+strings.Join(types.ServiceFields, ", ")+", e.g. 'protocol=tcp,port=80'")
+	f.StringVar(&listIDPrefix, "id-prefix", "", "only list services whose IDs start with this")
+	f.StringVar(&listServerSelector, "server-selector", "",
+		"label selector of the servers to list with each service, e.g. 'rack=r1'")
+	f.StringVar(&listSortBy, "sort-by", "id", "sort services by one of "+strings.Join(types.ServiceFields, ", ")+
+		", within the listed page if --limit is set")
+	f.IntVar(&listLimit, "limit", 0, "most services to list, 0 for all; if there are more, a token to list the rest "+
+		"with --continue is printed")
+	f.StringVar(&listContinue, "continue", "", "continue a list from the token printed by a previous --limit")
+	f.BoolVarP(&listAllNamespaces, "all-namespaces", "A", false, "list the services of every namespace, "+
+		"instead of only those in --namespace if it's set")
+	addOutputFlag(listCmd, &listOutput)
+}
+
+// namespaceSelector adds the --namespace to selector, unless allNamespaces is set.
+func namespaceSelector(selector string, allNamespaces bool) string {
+	if namespace == "" || allNamespaces {
+		return selector
+	}
+	if selector != "" {
+		selector += ","
+	}
+	return selector + namespaceLabel + "=" + namespace
 }
 
 func list(_ *cobra.Command, _ []string) error {
+	selector := namespaceSelector(listSelector, listAllNamespaces)
+	labelSelector, err := types.ParseSelector(selector)
+	if err != nil {
+		return withExitCode(exitInvalid, err)
+	}
+	fieldSelector, err := types.ParseFieldSelector(listFieldSelector)
+	if err != nil {
+		return withExitCode(exitInvalid, err)
+	}
+	serverSelector, err := types.ParseSelector(listServerSelector)
+	if err != nil {
+		return withExitCode(exitInvalid, err)
+	}
+	less, err := serviceOrder(listSortBy)
+	if err != nil {
+		return err
+	}
+	if listLimit < 0 {
+		return invalidf("--limit must not be negative")
+	}
+	if _, err := types.ParsePageToken(listContinue); err != nil {
+		return invalidf("invalid --continue: %v", err)
+	}
+
 	return client(func(c types.MerlinClient) error {
-		ctx, cancel := clientContext()
-		defer cancel()
-		resp, err := c.List(ctx, &empty.Empty{})
+		page, next, total, err := listPages(c, &types.ListRequest{
+			LabelSelector:       selector,
+			FieldSelector:       listFieldSelector,
+			PageToken:           listContinue,
+			IdPrefix:            listIDPrefix,
+			ServerLabelSelector: listServerSelector,
+		}, listLimit)
 		if err != nil {
 			return err
 		}
 
-		w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
+		// filter again in case the server predates selectors
+		var items []*types.ListResponse_Item
+		for _, item := range page {
+			if strings.HasPrefix(item.Service.Id, listIDPrefix) && labelSelector.Matches(item.Service.Labels) &&
+				fieldSelector.Matches(types.FieldsOf(item.Service)) {
+				items = append(items, item)
+			}
+			servers := item.Servers[:0]
+			for _, server := range item.Servers {
+				if serverSelector.Matches(server.Labels) {
+					servers = append(servers, server)
+				}
+			}
+			item.Servers = servers
+		}
+		sort.SliceStable(items, func(i, j int) bool {
+			return less(items[i].Service, items[j].Service)
+		})
+		resp := &types.ListResponse{Items: items, NextPageToken: next, TotalSize: total}
+		if ok, err := writeOutput(listOutput, resp); ok {
+			return err
+		}
+
+		// stats are only available if IPVS is enabled on the node
+		ctx, cancel := clientContext()
+		defer cancel()
+		var stats map[string]map[string]*types.ServerStats
+		if resp, err := c.Stats(ctx, &empty.Empty{}); err != nil {
+			log.Debugf("Unable to get stats, connections and health won't be shown: %v", err)
+		} else {
+			stats = serverStatsByService(resp)
+		}
+
+		var table bytes.Buffer
+		colors := make(map[int]string)
+		line := 3
+		w := tabwriter.NewWriter(&table, 0, 0, 1, ' ', 0)
 
-		fmt.Fprintln(w, "ID\tProt\tLocalAddress:Port\tScheduler\tFlags\t\t")
-		fmt.Fprintln(w, "\t  ->\tRemoteAddress:Port\tForward\tWeight\t\t")
-		fmt.Fprintln(w, "\t    \tHealthEndpoint\tPeriod\tTimeout\tUp/Down\t")
+		fmt.Fprintln(w, "ID\tProt\tLocalAddress:Port\tScheduler\tFlags\tLabels\tActiveConn\tInActConn\tHealth\t")
+		fmt.Fprintln(w, "\t  ->\tRemoteAddress:Port\tForward\tWeight\t\t\t\t\t")
+		fmt.Fprintln(w, "\t    \tHealthEndpoint\tPeriod\tTimeout\tUp/Down\t\t\t\t")
 
-		for _, item := range resp.Items {
+		for _, item := range items {
 			svc := item.Service
+			svcLine := line
 
-			fmt.Fprintf(w, "%s\t%s\t%s:%d\t%s\t(%s)\t\t\n",
+			var active, inactive uint64
+			var healthy, down int
+			for _, server := range item.Servers {
+				if s := stats[svc.Id][serverStatsKey(server.Key)]; s != nil {
+					active += uint64(s.ActiveConnections)
+					inactive += uint64(s.InactiveConnections)
+					if s.Health == types.Health_DOWN {
+						down++
+					} else {
+						healthy++
+					}
+				}
+			}
+			conns, health := "-\t-", "-"
+			if stats != nil {
+				conns = fmt.Sprintf("%d\t%d", active, inactive)
+				health = fmt.Sprintf("%d/%d healthy", healthy, len(item.Servers))
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%s:%d\t%s\t(%s)\t%s\t%s\t%s\t\n",
 				svc.Id,
 				svc.Key.Protocol.String(),
 				svc.Key.Ip,
 				svc.Key.Port,
 				svc.Config.Scheduler,
-				strings.Join(svc.Config.Flags, ","))
+				serviceFlags(svc.Config),
+				types.PrettyLabels(svc.Labels),
+				conns,
+				health)
+			line++
 
 			for _, server := range item.Servers {
-				fmt.Fprintf(w, "\t  ->\t%s:%d\t%s\t%d\t\t\n",
+				weight := strconv.FormatUint(uint64(server.Config.GetWeight().GetValue()), 10)
+				if server.DrainedWeight != nil {
+					weight = fmt.Sprintf("%s (drained from %d)", weight, server.DrainedWeight.Value)
+					colors[line] = colorYellow
+				}
+				conns, health := "-\t-", "-"
+				if s := stats[svc.Id][serverStatsKey(server.Key)]; s != nil {
+					conns = fmt.Sprintf("%d\t%d", s.ActiveConnections, s.InactiveConnections)
+					health = strings.ToLower(s.Health.String())
+					switch s.Health {
+					case types.Health_UNSET_HEALTH:
+						health = "-"
+					case types.Health_UP:
+						colors[line] = colorGreen
+					case types.Health_DOWN:
+						colors[line] = colorRed
+					}
+				}
+				fmt.Fprintf(w, "\t  ->\t%s:%d\t%s\t%s\t%s\t%s\t%s\t\n",
 					server.Key.GetIp(),
 					server.Key.GetPort(),
-					server.Config.GetForward(),
-					server.Config.GetWeight().GetValue())
+					serverForward(server.Config),
+					weight,
+					types.PrettyLabels(server.Labels),
+					conns,
+					health)
+				line++
 
 				check := server.HealthCheck
 				if check.Endpoint.GetValue() != "" {
 					period, _ := ptypes.Duration(check.Period)
 					timeout, _ := ptypes.Duration(check.Timeout)
-					fmt.Fprintf(w, "\t    \t%s\t%v\t%v\t%d/%d\t\n",
+					fmt.Fprintf(w, "\t    \t%s\t%v\t%v\t%d/%d\t\t\t\t\n",
 						check.Endpoint.Value,
 						period,
 						timeout,
 						check.UpThreshold,
 						check.DownThreshold)
+					line++
 				}
+			}
 
+			switch {
+			case down > 0 && healthy == 0:
+				colors[svcLine] = colorRed
+			case down > 0:
+				colors[svcLine] = colorYellow
 			}
 		}
 
 		w.Flush()
+		if _, err := fmt.Fprint(os.Stdout, colorLines(table.String(), colors)); err != nil {
+			return err
+		}
+		if next != "" && total > 0 {
+			fmt.Fprintf(os.Stderr, "Listed %d of %d services, list more with --continue %s\n", len(items), total, next)
+		} else if next != "" {
+			fmt.Fprintf(os.Stderr, "More services are available, list them with --continue %s\n", next)
+		}
 		return nil
 	})
 }
+
+// listPages lists the services matching req a page at a time, continuing from its page token, so each request
+// stays small. If limit is set, at most limit services are listed, with the token to continue from if there are
+// more. It also returns the total number of matching services, or 0 if the server predates counting them.
+func listPages(c types.MerlinClient, req *types.ListRequest, limit int) ([]*types.ListResponse_Item, string, int32,
+	error) {
+	var items []*types.ListResponse_Item
+	for {
+		req.PageSize = listPageSize
+		if limit > 0 && limit-len(items) < listPageSize {
+			req.PageSize = int32(limit - len(items))
+		}
+		ctx, cancel := clientContext()
+		resp, err := c.List(ctx, req)
+		cancel()
+		if err != nil {
+			return nil, "", 0, err
+		}
+
+		page, next := resp.Items, resp.NextPageToken
+		if next == "" {
+			// servers predating paging return every service, so page them here; this is a no-op on the last page
+			// of newer servers
+			page, next = pageItems(page, req.PageToken, int(req.PageSize))
+		}
+		items = append(items, page...)
+		if next == "" || (limit > 0 && len(items) >= limit) {
+			return items, next, resp.TotalSize, nil
+		}
+		req.PageToken = next
+	}
+}
+
+// pageItems returns up to size items, ordered by ID, after the service a page token was created from, with the
+// token of the next page if there are more.
+func pageItems(items []*types.ListResponse_Item, token string, size int) ([]*types.ListResponse_Item, string) {
+	after, _ := types.ParsePageToken(token)
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Service.GetId() < items[j].Service.GetId()
+	})
+	var page []*types.ListResponse_Item
+	for _, item := range items {
+		if after != "" && item.Service.GetId() <= after {
+			continue
+		}
+		if len(page) == size {
+			return page, types.PageToken(page[len(page)-1].Service.GetId())
+		}
+		page = append(page, item)
+	}
+	return page, ""
+}
+
+// serverStatsByService indexes server stats by service ID and server address.
+func serverStatsByService(resp *types.StatsResponse) map[string]map[string]*types.ServerStats {
+	stats := make(map[string]map[string]*types.ServerStats)
+	for _, svc := range resp.Services {
+		if svc.Id == "" {
+			continue
+		}
+		stats[svc.Id] = make(map[string]*types.ServerStats)
+		for _, server := range svc.Servers {
+			stats[svc.Id][serverStatsKey(server.Key)] = server
+		}
+	}
+	return stats
+}
+
+func serverStatsKey(key *types.RealServer_Key) string {
+	return net.JoinHostPort(key.GetIp(), strconv.Itoa(int(key.GetPort())))
+}
+
+// serviceFlags lists the scheduler flags of a service, and ops if it has one-packet scheduling.
+func serviceFlags(config *types.VirtualService_Config) string {
+	flags := config.GetFlags()
+	if config.GetOps() {
+		flags = append(append([]string(nil), flags...), "ops")
+	}
+	return strings.Join(flags, ",")
+}
+
+// serverForward returns the forward method of a server, with its tunnel if it isn't IPIP, e.g. TUNNEL(gue:6080,csum).
+func serverForward(config *types.RealServer_Config) string {
+	forward := config.GetForward().String()
+	if config.GetTunnelType() != types.TunnelType_IPIP {
+		forward += fmt.Sprintf("(%s:%d,%s)", tunnelName(config.GetTunnelType()), config.GetTunnelPort(),
+			tunnelChecksumName(config.GetTunnelChecksum()))
+	}
+	return forward
+}
+
+// serviceOrder returns a comparison of services by the given field.
+func serviceOrder(field string) (func(a, b *types.VirtualService) bool, error) {
+	switch field {
+	case "port":
+		return func(a, b *types.VirtualService) bool {
+			return a.GetKey().GetPort() < b.GetKey().GetPort()
+		}, nil
+	case "ip":
+		return func(a, b *types.VirtualService) bool {
+			return bytes.Compare(net.ParseIP(a.GetKey().GetIp()), net.ParseIP(b.GetKey().GetIp())) < 0
+		}, nil
+	}
+	for _, f := range types.ServiceFields {
+		if f == field {
+			return func(a, b *types.VirtualService) bool {
+				return types.FieldsOf(a)[field] < types.FieldsOf(b)[field]
+			}, nil
+		}
+	}
+	return nil, invalidf("can't sort by %q, must be one of %s", field, strings.Join(types.ServiceFields, ", "))
+}
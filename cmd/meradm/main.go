@@ -8,19 +8,35 @@ import (
 	"fmt"
 
 	log "github.com/sirupsen/logrus"
+	"github.com/sky-uk/merlin/types"
 	"github.com/spf13/cobra"
 )
 
 var rootCmd = &cobra.Command{
 	Use:   "meradm",
 	Short: "admin tool for merlin - distributed IPVS manager",
+	Long:  "admin tool for merlin - distributed IPVS manager\n\n" + exitCodesHelp,
 }
 
 var (
-	debug   bool
-	host    string
-	port    uint16
-	timeout time.Duration
+	debug              bool
+	host               string
+	port               uint16
+	timeout            time.Duration
+	retries            uint
+	waitForReady       bool
+	resolveVersion     uint
+	useTLS             bool
+	tlsCA              string
+	tlsCert            string
+	tlsKey             string
+	insecureSkipVerify bool
+	token              string
+	tokenCommand       string
+	noColor            bool
+	strictVersion      bool
+	namespace          string
+	namespaceLabel     string
 	// Version of meradm.
 	Version string
 	// BuildTime of meradm.
@@ -29,12 +45,33 @@ var (
 
 func init() {
 	cobra.OnInitialize(initLogs)
-	rootCmd.Version = fmt.Sprintf("%s (%s)", Version, BuildTime)
+	rootCmd.Version = fmt.Sprintf("%s (%s), API %s", Version, BuildTime, types.APIVersion)
 	f := rootCmd.PersistentFlags()
 	f.BoolVarP(&debug, "debug", "X", false, "enable debug logging")
 	f.StringVarP(&host, "host", "H", "localhost", "merlin host to connect to")
 	f.Uint16VarP(&port, "port", "P", 4282, "merlin port to connect to")
-	f.DurationVar(&timeout, "timeout", 10*time.Second, "client timeout")
+	f.DurationVar(&timeout, "timeout", 10*time.Second, "client timeout of each request attempt")
+	f.UintVar(&retries, "retries", 0,
+		"number of times to retry idempotent requests, with backoff, if merlin is unavailable or times out")
+	f.BoolVar(&waitForReady, "wait-for-ready", false,
+		"wait up to --timeout for merlin to accept connections, instead of failing immediately")
+	f.UintVar(&resolveVersion, "resolve", 4, "IP version, 4 or 6, to prefer when resolving hostnames in addresses")
+	f.BoolVar(&useTLS, "tls", false, "connect to merlin using TLS, implied by the other --tls flags")
+	f.StringVar(&tlsCA, "tls-ca", "", "CA certificate file to verify merlin with, defaults to the system roots")
+	f.StringVar(&tlsCert, "tls-cert", "", "client certificate file for mutual TLS")
+	f.StringVar(&tlsKey, "tls-key", "", "client private key file for mutual TLS")
+	f.BoolVar(&insecureSkipVerify, "insecure-skip-verify", false,
+		"use TLS without verifying the merlin certificate, only for testing")
+	f.StringVar(&token, "token", "", "bearer token to authenticate with, defaults to $"+tokenEnv)
+	f.StringVar(&tokenCommand, "token-command", "",
+		"command which prints the bearer token to stdout, used if --token and $"+tokenEnv+" are unset")
+	f.BoolVar(&noColor, "no-color", false, "disable colored output, which is also disabled if $NO_COLOR is set "+
+		"or the output isn't a terminal")
+	f.BoolVar(&strictVersion, "strict", false,
+		"refuse to talk to merlin if its API version is outside the supported skew, instead of warning")
+	f.StringVar(&namespace, "namespace", "", "only list the services in this namespace, unless --all-namespaces")
+	f.StringVar(&namespaceLabel, "namespace-label", "namespace",
+		"label of services with their namespace, as set by merlin's --namespace-label")
 }
 
 func initLogs() {
@@ -44,7 +81,12 @@ func initLogs() {
 }
 
 func main() {
+	markUsageErrors(rootCmd)
+	if ok, err := runPlugin(os.Args[1:]); ok {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitCode(err))
+	}
 	if err := rootCmd.Execute(); err != nil {
-		os.Exit(1)
+		os.Exit(exitCode(err))
 	}
 }
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/sky-uk/merlin/types"
+	"github.com/spf13/cobra"
+)
+
+var nodesCmd = &cobra.Command{
+	Use:   "nodes",
+	Short: "List the merlin nodes registered in the store",
+	Args:  cobra.NoArgs,
+	RunE:  listNodes,
+}
+
+var maintenanceCmd = &cobra.Command{
+	Use:   "maintenance [node] [on|off]",
+	Short: "Pause or resume reconciliation of IPVS on a node, taking effect on its next heartbeat",
+	Args: func(_ *cobra.Command, args []string) error {
+		if len(args) != 2 || (args[1] != "on" && args[1] != "off") {
+			return errors.New("requires a node and on or off")
+		}
+		return nil
+	},
+	RunE: setMaintenance,
+}
+
+var nodesOutput string
+
+func init() {
+	rootCmd.AddCommand(nodesCmd)
+	nodesCmd.AddCommand(maintenanceCmd)
+	addOutputFlag(nodesCmd, &nodesOutput)
+}
+
+func listNodes(_ *cobra.Command, _ []string) error {
+	return client(func(c types.MerlinClient) error {
+		ctx, cancel := clientContext()
+		defer cancel()
+		resp, err := c.ListNodes(ctx, &empty.Empty{})
+		if err != nil {
+			return err
+		}
+		if ok, err := writeOutput(nodesOutput, resp); ok {
+			return err
+		}
+		writeNodes(resp.Nodes)
+		return nil
+	})
+}
+
+func writeNodes(nodes []*types.Node) {
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Name < nodes[j].Name })
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
+	fmt.Fprintln(w, "Node\tVersion\tRole\tLastSync\tDrift\tMaintenance\tLabels\tLastHeartbeat\t")
+	for _, node := range nodes {
+		lastSync := since(node.LastSync)
+		if !node.Reconcile {
+			lastSync = "reconcile disabled"
+		}
+		role := "-"
+		switch {
+		case node.Leader:
+			role = "leader"
+		case node.Passive:
+			role = "passive"
+		case node.Mode == "agent":
+			role = "agent"
+		}
+		maintenance := "-"
+		if node.Maintenance {
+			maintenance = "paused"
+		}
+		labels := "-"
+		if len(node.Labels) > 0 {
+			labels = types.PrettyLabels(node.Labels)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\t%s\t%s\t\n", node.Name, node.Version, role, lastSync, node.Drift,
+			maintenance, labels, since(node.LastHeartbeat))
+	}
+	w.Flush()
+}
+
+func setMaintenance(_ *cobra.Command, args []string) error {
+	return client(func(c types.MerlinClient) error {
+		ctx, cancel := clientContext()
+		defer cancel()
+		_, err := c.SetMaintenance(ctx, &types.SetMaintenanceRequest{Node: args[0], Enabled: args[1] == "on"})
+		return err
+	})
+}
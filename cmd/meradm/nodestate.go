@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/sky-uk/merlin/types"
+	"github.com/spf13/cobra"
+)
+
+var nodeStateCmd = &cobra.Command{
+	Use:   "state [host[:port]]",
+	Short: "Compare the services and servers in the store with IPVS on a node",
+	Long: `Compare the desired services and servers in the store with those programmed in IPVS on a merlin node,
+which defaults to --host. Each service and server has a status of:
+
+  ok         IPVS matches the store
+  differs    IPVS doesn't match the store
+  missing    in the store but not in IPVS
+  unmanaged  in IPVS but not in the store
+  down       weight is 0 in IPVS as the health check of the server is failing`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: nodeState,
+}
+
+// Statuses of a service or server, comparing the store with IPVS.
+const (
+	stateOK        = "ok"
+	stateDiffers   = "differs"
+	stateMissing   = "missing"
+	stateUnmanaged = "unmanaged"
+	stateDown      = "down"
+)
+
+var stateColors = map[string]string{
+	stateDiffers:   colorRed,
+	stateMissing:   colorRed,
+	stateUnmanaged: colorYellow,
+	stateDown:      colorYellow,
+}
+
+func init() {
+	rootCmd.AddCommand(nodeStateCmd)
+}
+
+func nodeState(_ *cobra.Command, args []string) error {
+	dest := nodeAddresses(args)[0]
+	return clientFor(dest, func(c types.MerlinClient) error {
+		ctx, cancel := clientContext()
+		defer cancel()
+		desired, err := c.GetSnapshot(ctx, &empty.Empty{})
+		if err != nil {
+			return err
+		}
+		actual, err := c.GetNodeState(ctx, &empty.Empty{})
+		if err != nil {
+			return wrapError(err, "unable to get the state of %s", dest)
+		}
+		if info, err := c.Info(ctx, &empty.Empty{}); err == nil && info.Node.GetMaintenance() {
+			fmt.Printf("%s is in maintenance, IPVS isn't being reconciled with the store\n", actual.Node)
+		}
+
+		fmt.Printf("Node: %s\n\n", actual.Node)
+		_, err = fmt.Fprint(os.Stdout, compareState(desired, actual))
+		return err
+	})
+}
+
+// compareState renders a table of each service and server in the store and IPVS, with its status.
+func compareState(desired *types.Snapshot, actual *types.NodeState) string {
+	actualServices := make(map[string]*types.NodeState_Service)
+	for _, svc := range actual.Services {
+		actualServices[idMapKey(svc.Service.Key)] = svc
+	}
+	desiredServers := make(map[string][]*types.RealServer)
+	for _, server := range desired.Servers {
+		desiredServers[server.ServiceID] = append(desiredServers[server.ServiceID], server)
+	}
+
+	var table bytes.Buffer
+	colors := make(map[int]string)
+	line := 2
+	w := tabwriter.NewWriter(&table, 0, 0, 1, ' ', 0)
+	row := func(status, format string, args ...interface{}) {
+		fmt.Fprintf(w, format+"\t%s\t\n", append(args, status)...)
+		colors[line] = stateColors[status]
+		line++
+	}
+
+	fmt.Fprintln(w, "ID\tProt\tLocalAddress:Port\tDesired\tActual\tStatus\t")
+	fmt.Fprintln(w, "\t  ->\tRemoteAddress:Port\t\t\t\t")
+
+	seen := make(map[string]bool)
+	for _, svc := range desired.Services {
+		name := idMapKey(svc.Key)
+		seen[name] = true
+		act := actualServices[name]
+
+		status, actualConfig := stateMissing, "-"
+		if act != nil {
+			actualConfig = serviceConfigString(act.Service.Config)
+			status = stateOK
+			if !sameServiceConfig(svc.Config, act.Service.Config) {
+				status = stateDiffers
+			}
+		}
+		row(status, "%s\t%s\t%s:%d\t%s\t%s", svc.Id, svc.Key.Protocol, svc.Key.Ip, svc.Key.Port,
+			serviceConfigString(svc.Config), actualConfig)
+
+		var actualServers []*types.NodeState_Server
+		if act != nil {
+			actualServers = act.Servers
+		}
+		seenServers := make(map[string]bool)
+		for _, server := range desiredServers[svc.Id] {
+			addr := serverStatsKey(server.Key)
+			seenServers[addr] = true
+			var actServer *types.NodeState_Server
+			for _, s := range actualServers {
+				if proto.Equal(s.Server.Key, server.Key) {
+					actServer = s
+					break
+				}
+			}
+
+			status, actualConfig := stateMissing, "-"
+			if actServer != nil {
+				actualConfig = serverConfigString(actServer.Server.Config)
+				switch {
+				case proto.Equal(server.Config, actServer.Server.Config):
+					status = stateOK
+				case actServer.Health == types.Health_DOWN && actServer.Server.Config.GetWeight().GetValue() == 0 &&
+					server.Config.GetForward() == actServer.Server.Config.GetForward():
+					status = stateDown
+				default:
+					status = stateDiffers
+				}
+			}
+			row(status, "\t  ->\t%s\t%s\t%s", addr, serverConfigString(server.Config), actualConfig)
+		}
+		for _, s := range actualServers {
+			if addr := serverStatsKey(s.Server.Key); !seenServers[addr] {
+				row(stateUnmanaged, "\t  ->\t%s\t-\t%s", addr, serverConfigString(s.Server.Config))
+			}
+		}
+	}
+
+	for _, act := range actual.Services {
+		svc := act.Service
+		if seen[idMapKey(svc.Key)] {
+			continue
+		}
+		row(stateUnmanaged, "-\t%s\t%s:%d\t-\t%s", svc.Key.Protocol, svc.Key.Ip, svc.Key.Port,
+			serviceConfigString(svc.Config))
+		for _, s := range act.Servers {
+			row(stateUnmanaged, "\t  ->\t%s\t-\t%s", serverStatsKey(s.Server.Key), serverConfigString(s.Server.Config))
+		}
+	}
+
+	w.Flush()
+	return colorLines(table.String(), colors)
+}
+
+func serviceConfigString(config *types.VirtualService_Config) string {
+	return fmt.Sprintf("%s (%s)", config.GetScheduler(), serviceFlags(config))
+}
+
+func serverConfigString(config *types.RealServer_Config) string {
+	return fmt.Sprintf("%s %d", serverForward(config), config.GetWeight().GetValue())
+}
+
+// sameServiceConfig compares service configs, ignoring the order of flags and whether they're named, such as
+// sh-port, or numbered as IPVS reports them.
+func sameServiceConfig(a, b *types.VirtualService_Config) bool {
+	normalized := func(config *types.VirtualService_Config) string {
+		svc := &types.VirtualService{Config: &types.VirtualService_Config{
+			Scheduler: config.GetScheduler(),
+			Flags:     append([]string(nil), config.GetFlags()...),
+		}}
+		svc.NormalizeFlags()
+		return strings.Join(svc.Config.Flags, ",")
+	}
+	return a.GetScheduler() == b.GetScheduler() && normalized(a) == normalized(b) && a.GetOps() == b.GetOps()
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/spf13/cobra"
+)
+
+// addOutputFlag adds the --output flag of read commands to cmd.
+func addOutputFlag(cmd *cobra.Command, output *string) {
+	cmd.Flags().StringVarP(output, "output", "o", "",
+		"output json, yaml, go-template='{{...}}', go-template-file=file or jsonpath='{...}' instead of a table; "+
+			"fields are named as in export")
+}
+
+// writeOutput writes pb to stdout in the format of --output, returning false if it's empty so the command should
+// write its usual table. Templates and jsonpath are evaluated against the JSON mapping of pb, so fields have the
+// names used by export.
+func writeOutput(output string, pb proto.Message) (bool, error) {
+	if output == "" {
+		return false, nil
+	}
+	out, err := formatOutput(output, pb)
+	if err != nil {
+		return true, err
+	}
+	_, err = os.Stdout.Write(out)
+	return true, err
+}
+
+func formatOutput(output string, pb proto.Message) ([]byte, error) {
+	format, arg := output, ""
+	if i := strings.Index(output, "="); i >= 0 {
+		format, arg = output[:i], output[i+1:]
+	}
+
+	m := jsonpb.Marshaler{OrigName: true}
+	js, err := m.MarshalToString(pb)
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case "json":
+		var b bytes.Buffer
+		if err := json.Indent(&b, []byte(js), "", "  "); err != nil {
+			return nil, err
+		}
+		b.WriteString("\n")
+		return b.Bytes(), nil
+	case "yaml":
+		return marshalYAML(pb)
+	}
+
+	d := json.NewDecoder(strings.NewReader(js))
+	d.UseNumber()
+	var obj interface{}
+	if err := d.Decode(&obj); err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	switch format {
+	case "go-template", "go-template-file":
+		if format == "go-template-file" {
+			data, err := ioutil.ReadFile(arg)
+			if err != nil {
+				return nil, withExitCode(exitUsage, err)
+			}
+			arg = string(data)
+		}
+		t, err := template.New("output").Option("missingkey=zero").Parse(arg)
+		if err != nil {
+			return nil, withExitCode(exitUsage, fmt.Errorf("invalid go-template: %v", err))
+		}
+		if err := t.Execute(&b, obj); err != nil {
+			return nil, fmt.Errorf("unable to execute go-template: %v", err)
+		}
+	case "jsonpath":
+		nodes, err := parseJSONPath(arg)
+		if err != nil {
+			return nil, withExitCode(exitUsage, fmt.Errorf("invalid jsonpath: %v", err))
+		}
+		if err := evalJSONPath(&b, nodes, obj, obj); err != nil {
+			return nil, fmt.Errorf("unable to evaluate jsonpath: %v", err)
+		}
+	default:
+		return nil, withExitCode(exitUsage, fmt.Errorf("unknown output %q, must be json, yaml, go-template=, "+
+			"go-template-file= or jsonpath=", output))
+	}
+	return b.Bytes(), nil
+}
+
+// jsonPathNode is part of a jsonpath template: literal text, a path whose values are written, or a range over the
+// values of a path.
+type jsonPathNode struct {
+	text     string
+	path     []string
+	isPath   bool
+	isRange  bool
+	children []jsonPathNode
+}
+
+// parseJSONPath parses the subset of kubectl's jsonpath templates used in scripts: text, with expressions in braces
+// of {.field.field[0]}, {.list[*].field}, {"literal"} and {range .list[*]}...{end}.
+func parseJSONPath(s string) ([]jsonPathNode, error) {
+	nodes, _, err := parseJSONPathNodes(s, false)
+	return nodes, err
+}
+
+// parseJSONPathNodes parses nodes until the end of s, or an {end} if inRange, returning what follows it.
+func parseJSONPathNodes(s string, inRange bool) ([]jsonPathNode, string, error) {
+	var nodes []jsonPathNode
+	for s != "" {
+		open := strings.Index(s, "{")
+		if open < 0 {
+			nodes = append(nodes, jsonPathNode{text: s})
+			s = ""
+			break
+		}
+		if open > 0 {
+			nodes = append(nodes, jsonPathNode{text: s[:open]})
+		}
+		end := strings.Index(s[open:], "}")
+		if end < 0 {
+			return nil, "", fmt.Errorf("unclosed {")
+		}
+		expr := strings.TrimSpace(s[open+1 : open+end])
+		s = s[open+end+1:]
+
+		switch {
+		case expr == "end":
+			if !inRange {
+				return nil, "", fmt.Errorf("{end} without {range}")
+			}
+			return nodes, s, nil
+		case strings.HasPrefix(expr, "range "):
+			path, err := parseJSONPathExpr(strings.TrimSpace(strings.TrimPrefix(expr, "range ")))
+			if err != nil {
+				return nil, "", err
+			}
+			var children []jsonPathNode
+			if children, s, err = parseJSONPathNodes(s, true); err != nil {
+				return nil, "", err
+			}
+			nodes = append(nodes, jsonPathNode{path: path, isPath: true, isRange: true, children: children})
+		case strings.HasPrefix(expr, `"`):
+			text, err := strconv.Unquote(expr)
+			if err != nil {
+				return nil, "", fmt.Errorf("invalid literal %s", expr)
+			}
+			nodes = append(nodes, jsonPathNode{text: text})
+		default:
+			path, err := parseJSONPathExpr(expr)
+			if err != nil {
+				return nil, "", err
+			}
+			nodes = append(nodes, jsonPathNode{path: path, isPath: true})
+		}
+	}
+	if inRange {
+		return nil, "", fmt.Errorf("{range} without {end}")
+	}
+	return nodes, "", nil
+}
+
+// parseJSONPathExpr splits an expression like .items[*].service.id into the steps items, *, service and id. Steps
+// from the root start with $.
+func parseJSONPathExpr(expr string) ([]string, error) {
+	var steps []string
+	if strings.HasPrefix(expr, "$") {
+		steps = append(steps, "$")
+		expr = expr[1:]
+	}
+	if expr != "" && !strings.HasPrefix(expr, ".") && !strings.HasPrefix(expr, "[") {
+		return nil, fmt.Errorf("expression %q must start with . or $", expr)
+	}
+	for expr != "" {
+		switch expr[0] {
+		case '.':
+			expr = expr[1:]
+			i := strings.IndexAny(expr, ".[")
+			if i < 0 {
+				i = len(expr)
+			}
+			if i > 0 {
+				steps = append(steps, expr[:i])
+			}
+			expr = expr[i:]
+		case '[':
+			i := strings.Index(expr, "]")
+			if i < 0 {
+				return nil, fmt.Errorf("unclosed [ in %q", expr)
+			}
+			index := expr[1:i]
+			if _, err := strconv.Atoi(index); err != nil && index != "*" {
+				return nil, fmt.Errorf("index %q must be a number or *", index)
+			}
+			steps = append(steps, "["+index+"]")
+			expr = expr[i+1:]
+		default:
+			return nil, fmt.Errorf("unexpected %q", expr)
+		}
+	}
+	return steps, nil
+}
+
+func evalJSONPath(b *bytes.Buffer, nodes []jsonPathNode, root, current interface{}) error {
+	for _, node := range nodes {
+		if !node.isPath {
+			b.WriteString(node.text)
+			continue
+		}
+		values := jsonPathValues(node.path, root, current)
+		if node.isRange {
+			for _, v := range values {
+				if err := evalJSONPath(b, node.children, root, v); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		for i, v := range values {
+			if i > 0 {
+				b.WriteString(" ")
+			}
+			switch v := v.(type) {
+			case string:
+				b.WriteString(v)
+			case json.Number:
+				b.WriteString(v.String())
+			default:
+				js, err := json.Marshal(v)
+				if err != nil {
+					return err
+				}
+				b.Write(js)
+			}
+		}
+	}
+	return nil
+}
+
+// jsonPathValues returns the values a path selects, which are none if a field is missing.
+func jsonPathValues(path []string, root, current interface{}) []interface{} {
+	values := []interface{}{current}
+	for _, step := range path {
+		var next []interface{}
+		for _, v := range values {
+			switch {
+			case step == "$":
+				next = append(next, root)
+			case step == "[*]":
+				switch v := v.(type) {
+				case []interface{}:
+					next = append(next, v...)
+				case map[string]interface{}:
+					var keys []string
+					for k := range v {
+						keys = append(keys, k)
+					}
+					sort.Strings(keys)
+					for _, k := range keys {
+						next = append(next, v[k])
+					}
+				}
+			case strings.HasPrefix(step, "["):
+				i, _ := strconv.Atoi(step[1 : len(step)-1])
+				if list, ok := v.([]interface{}); ok {
+					if i < 0 {
+						i += len(list)
+					}
+					if i >= 0 && i < len(list) {
+						next = append(next, list[i])
+					}
+				}
+			default:
+				if m, ok := v.(map[string]interface{}); ok {
+					if field, ok := m[step]; ok {
+						next = append(next, field)
+					}
+				}
+			}
+		}
+		values = next
+	}
+	return values
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// pluginPrefix of the executables in PATH which add commands to meradm. meradm failover-dc runs
+// meradm-failover-dc, if failover-dc isn't a meradm command.
+const pluginPrefix = "meradm-"
+
+var pluginCmd = &cobra.Command{
+	Use:   "plugin [list]",
+	Short: "Manage plugins, executables in PATH which add commands to meradm",
+	Long: `Plugins are executables in PATH named meradm-<command>, which are run by meradm <command> if it isn't a
+meradm command, like kubectl plugins. Global flags given before the command are applied with the context, then
+passed to the plugin as MERADM_<FLAG> environment variables, such as MERADM_HOST and MERADM_PORT. The arguments after
+the command are passed to the plugin as they are.`,
+}
+
+var listPluginsCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the plugins in PATH",
+	Args:  cobra.NoArgs,
+	RunE:  listPlugins,
+}
+
+func init() {
+	rootCmd.AddCommand(pluginCmd)
+	pluginCmd.AddCommand(listPluginsCmd)
+}
+
+// runPlugin runs the plugin of the command in args if it isn't a meradm command, returning false if there's no
+// such plugin. The plugin replaces meradm, so it only returns if the plugin can't be run.
+func runPlugin(args []string) (bool, error) {
+	if _, _, err := rootCmd.Find(args); err == nil {
+		return false, nil
+	}
+	// parse the global flags before the command, sharing them with rootCmd so the context can be applied
+	flags := pflag.NewFlagSet(rootCmd.Name(), pflag.ContinueOnError)
+	flags.AddFlagSet(rootCmd.PersistentFlags())
+	flags.SetInterspersed(false)
+	flags.SetOutput(ioutil.Discard)
+	if err := flags.Parse(args); err != nil || flags.NArg() == 0 {
+		return false, nil
+	}
+	path, err := exec.LookPath(pluginPrefix + flags.Arg(0))
+	if err != nil {
+		return false, nil
+	}
+
+	initLogs()
+	if err := applyContext(rootCmd, nil); err != nil {
+		return true, err
+	}
+	values := make(map[string]string)
+	rootCmd.PersistentFlags().VisitAll(func(flag *pflag.Flag) {
+		if value := flag.Value.String(); value != "" && flag.Name != "help" {
+			values[pluginEnv(flag.Name)] = value
+		}
+	})
+	var env []string
+	for _, kv := range os.Environ() {
+		if _, ok := values[strings.SplitN(kv, "=", 2)[0]]; !ok {
+			env = append(env, kv)
+		}
+	}
+	for key, value := range values {
+		env = append(env, key+"="+value)
+	}
+
+	log.Debugf("Running plugin %s", path)
+	if err := syscall.Exec(path, append([]string{path}, flags.Args()[1:]...), env); err != nil {
+		return true, fmt.Errorf("unable to run plugin %s: %v", path, err)
+	}
+	return true, nil
+}
+
+// pluginEnv returns the environment variable a global flag is passed to plugins in.
+func pluginEnv(flag string) string {
+	return "MERADM_" + strings.ToUpper(strings.Replace(flag, "-", "_", -1))
+}
+
+func listPlugins(_ *cobra.Command, _ []string) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
+	fmt.Fprintln(w, "Command\tPath\t")
+	found := make(map[string]bool)
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, file := range files {
+			name := strings.TrimPrefix(file.Name(), pluginPrefix)
+			if name == file.Name() || name == "" || file.IsDir() || file.Mode()&0111 == 0 {
+				continue
+			}
+			path := filepath.Join(dir, file.Name())
+			if cmd, _, err := rootCmd.Find([]string{name}); err == nil && cmd != rootCmd {
+				log.Warnf("Plugin %s is never run, as %s is a meradm command", path, name)
+				continue
+			}
+			if found[name] {
+				log.Warnf("Plugin %s is never run, as it's shadowed by an earlier plugin in PATH", path)
+				continue
+			}
+			found[name] = true
+			fmt.Fprintf(w, "%s\t%s\t\n", name, path)
+		}
+	}
+	return w.Flush()
+}
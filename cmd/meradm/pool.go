@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/sky-uk/merlin/types"
+	"github.com/spf13/cobra"
+)
+
+var poolCmd = &cobra.Command{
+	Use:   "pool [set|del|list]",
+	Short: "Manage the pools which services are allocated IPs from",
+}
+
+var setPoolCmd = &cobra.Command{
+	Use:   "set [name] [cidr...]",
+	Short: "Create a pool, or replace the CIDRs of an existing one",
+	Args:  cobra.MinimumNArgs(2),
+	RunE:  setPool,
+}
+
+var deletePoolCmd = &cobra.Command{
+	Use:   "del [name]",
+	Short: "Delete a pool which has no services allocated from it",
+	Args:  cobra.ExactArgs(1),
+	RunE:  deletePool,
+}
+
+var listPoolsCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List pools and the IPs allocated from them",
+	Args:  cobra.NoArgs,
+	RunE:  listPools,
+}
+
+var poolsOutput string
+
+func init() {
+	rootCmd.AddCommand(poolCmd)
+	poolCmd.AddCommand(setPoolCmd)
+	poolCmd.AddCommand(deletePoolCmd)
+	poolCmd.AddCommand(listPoolsCmd)
+	addOutputFlag(listPoolsCmd, &poolsOutput)
+}
+
+func setPool(_ *cobra.Command, args []string) error {
+	return client(func(c types.MerlinClient) error {
+		ctx, cancel := clientContext()
+		defer cancel()
+		_, err := c.PutPool(ctx, &types.VIPPool{Name: args[0], Cidrs: args[1:]})
+		return err
+	})
+}
+
+func deletePool(_ *cobra.Command, args []string) error {
+	return client(func(c types.MerlinClient) error {
+		ctx, cancel := clientContext()
+		defer cancel()
+		_, err := c.DeletePool(ctx, &wrappers.StringValue{Value: args[0]})
+		return err
+	})
+}
+
+func listPools(_ *cobra.Command, _ []string) error {
+	return client(func(c types.MerlinClient) error {
+		ctx, cancel := clientContext()
+		defer cancel()
+		resp, err := c.ListPools(ctx, &empty.Empty{})
+		if err != nil {
+			return err
+		}
+		if ok, err := writeOutput(poolsOutput, resp); ok {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
+		fmt.Fprintln(w, "Pool\tCIDRs\tAllocated\t")
+		for _, p := range resp.Pools {
+			allocated := "-"
+			if len(p.Allocated) > 0 {
+				allocated = strings.Join(p.Allocated, ",")
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t\n", p.Pool.Name, strings.Join(p.Pool.Cidrs, ","), allocated)
+		}
+		return w.Flush()
+	})
+}
@@ -3,6 +3,8 @@ package main
 import (
 	"errors"
 
+	"os"
+
 	"strconv"
 
 	"fmt"
@@ -11,6 +13,7 @@ import (
 
 	"time"
 
+	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/ptypes"
 	"github.com/golang/protobuf/ptypes/wrappers"
 	"github.com/sky-uk/merlin/types"
@@ -19,7 +22,7 @@ import (
 )
 
 var serverCmd = &cobra.Command{
-	Use:   "server [add|edit|del]",
+	Use:   "server [add|edit|del|drain|undrain|stats]",
 	Short: "Modify a real server",
 }
 
@@ -55,59 +58,114 @@ var deleteServerCmd = &cobra.Command{
 	RunE:  deleteServer,
 }
 
+var drainServerCmd = &cobra.Command{
+	Use:   "drain [serviceID] [ip:port]",
+	Short: "Drain a real server by setting its weight to 0",
+	Args:  validServiceIDIPPort,
+	RunE:  drainServer,
+}
+
+var undrainServerCmd = &cobra.Command{
+	Use:   "undrain [serviceID] [ip:port]",
+	Short: "Restore the weight a real server had before it was drained",
+	Args:  validServiceIDIPPort,
+	RunE:  undrainServer,
+}
+
 var (
 	weight              string
 	forwardMethod       string
+	tunnelType          string
+	tunnelPort          uint16
+	tunnelChecksum      string
 	healthEndpoint      string
 	healthPeriod        time.Duration
 	healthTimeout       time.Duration
 	healthUpThreshold   uint16
 	healthDownThreshold uint16
+	healthStatus        uint16
+	serverLabels        map[string]string
+	drainWait           bool
+	drainWaitTimeout    time.Duration
+	drainNodes          []string
 )
 
+// serverFields are the fields of a server set by each flag of edit.
+var serverFields = map[string]string{
+	"weight":          "config.weight",
+	"forward-method":  "config.forward",
+	"tunnel-type":     "config.tunnel_type",
+	"tunnel-port":     "config.tunnel_port",
+	"tunnel-checksum": "config.tunnel_checksum",
+	"health-endpoint": "health_check.endpoint",
+	"health-period":   "health_check.period",
+	"health-timeout":  "health_check.timeout",
+	"health-up":       "health_check.up_threshold",
+	"health-down":     "health_check.down_threshold",
+	"health-status":   "health_check.expected_status",
+	"label":           "labels",
+}
+
 func init() {
 	rootCmd.AddCommand(serverCmd)
 	serverCmd.AddCommand(addServerCmd)
 	serverCmd.AddCommand(editServerCmd)
 	serverCmd.AddCommand(deleteServerCmd)
+	serverCmd.AddCommand(drainServerCmd)
+	serverCmd.AddCommand(undrainServerCmd)
 
 	for _, f := range []*pflag.FlagSet{addServerCmd.Flags(), editServerCmd.Flags()} {
 		f.StringVarP(&weight, "weight", "w", "", "weight of the real server")
-		f.StringVarP(&forwardMethod, "forward-method", "f", "", "one of [route|tunnel|masq]")
+		f.StringVarP(&forwardMethod, "forward-method", "f", "", "one of [route|tunnel|masq|localnode], "+
+			"only masq allows a different port to the service")
+		f.StringVar(&tunnelType, "tunnel-type", "", "one of [ipip|gue], to encapsulate packets of tunnel servers in")
+		f.Uint16Var(&tunnelPort, "tunnel-port", 0, "UDP port of the server to send gue packets to")
+		f.StringVar(&tunnelChecksum, "tunnel-checksum", "",
+			"one of [no_checksum|checksum|remote_checksum], of the UDP header of gue packets")
 		f.StringVar(&healthEndpoint, "health-endpoint", "",
-			"endpoint for health checks, should be a valid URL 'http://:8080/health' or empty to disable")
+			"endpoint for health checks, should be a valid URL 'http://:8080/health', 'tcp://:8080' or 'udp://:53', "+
+				"or empty to disable")
 		f.DurationVar(&healthPeriod, "health-period", 0, "time period between health checks")
 		f.DurationVar(&healthTimeout, "health-timeout", 0, "timeout for health checks")
 		f.Uint16Var(&healthUpThreshold, "health-up", 0, "threshold of successful health checks")
 		f.Uint16Var(&healthDownThreshold, "health-down", 0, "Threshold of failed health checks")
+		f.Uint16Var(&healthStatus, "health-status", 0, "status http health checks expect, defaults to any 2xx")
+		f.VarP(&labelsValue{&serverLabels}, "label", "l",
+			"labels as key=value, on edit these replace all existing labels")
 	}
 
+	f := drainServerCmd.Flags()
+	f.BoolVar(&drainWait, "wait", false, "wait for active connections to the server to drop to 0")
+	f.DurationVar(&drainWaitTimeout, "wait-timeout", 5*time.Minute, "maximum time to wait for connections to drain")
+	f.StringSliceVar(&drainNodes, "nodes", nil,
+		"comma delimited list of merlin nodes as host[:port] to check for active connections, defaults to --host")
+
 	addServerCmd.MarkFlagRequired("weight")
 	addServerCmd.MarkFlagRequired("forward")
+	addDryRunFlag(addServerCmd, editServerCmd, deleteServerCmd)
 }
 
 func initServer(cmd *cobra.Command, serviceID string, ipPort string) (*types.RealServer, error) {
-	matches := ipPortRegex.FindSubmatch([]byte(ipPort))
-	ip := string(matches[1])
-	port, err := strconv.ParseUint(string(matches[2]), 10, 16)
+	ip, port, err := resolveAddress(ipPort)
 	if err != nil {
-		return nil, fmt.Errorf("unable to parse port: %v", err)
+		return nil, err
 	}
 
 	server := &types.RealServer{
 		ServiceID: serviceID,
 		Key: &types.RealServer_Key{
 			Ip:   ip,
-			Port: uint32(port),
+			Port: port,
 		},
 		Config:      &types.RealServer_Config{},
 		HealthCheck: &types.RealServer_HealthCheck{},
+		Labels:      serverLabels,
 	}
 
 	if weight != "" {
 		w, err := strconv.ParseUint(weight, 10, 32)
 		if err != nil {
-			return nil, fmt.Errorf("unable to convert weight to uint32: %v", err)
+			return nil, invalidf("unable to convert weight to uint32: %v", err)
 		}
 		server.Config.Weight = &wrappers.UInt32Value{Value: uint32(w)}
 	}
@@ -115,11 +173,27 @@ func initServer(cmd *cobra.Command, serviceID string, ipPort string) (*types.Rea
 	if forwardMethod != "" {
 		f, ok := types.ForwardMethod_value[strings.ToUpper(forwardMethod)]
 		if !ok {
-			return nil, fmt.Errorf("unrecognized forward method")
+			return nil, invalidf("unrecognized forward method")
 		}
 		server.Config.Forward = types.ForwardMethod(f)
 	}
 
+	if tunnelType != "" {
+		t, ok := types.TunnelType_value[strings.ToUpper(tunnelType)]
+		if !ok {
+			return nil, invalidf("unrecognized tunnel type")
+		}
+		server.Config.TunnelType = types.TunnelType(t)
+	}
+	server.Config.TunnelPort = uint32(tunnelPort)
+	if tunnelChecksum != "" {
+		c, ok := types.TunnelChecksum_value[strings.ToUpper(tunnelChecksum)]
+		if !ok {
+			return nil, invalidf("unrecognized tunnel checksum")
+		}
+		server.Config.TunnelChecksum = types.TunnelChecksum(c)
+	}
+
 	endpointFlag := cmd.Flag("health-endpoint")
 	if endpointFlag != nil && endpointFlag.Changed {
 		server.HealthCheck.Endpoint = &wrappers.StringValue{Value: healthEndpoint}
@@ -136,6 +210,9 @@ func initServer(cmd *cobra.Command, serviceID string, ipPort string) (*types.Rea
 	if healthDownThreshold != 0 {
 		server.HealthCheck.DownThreshold = uint32(healthDownThreshold)
 	}
+	if healthStatus != 0 {
+		server.HealthCheck.ExpectedStatus = uint32(healthStatus)
+	}
 
 	return server, nil
 }
@@ -160,6 +237,7 @@ func editServer(cmd *cobra.Command, args []string) error {
 		if err != nil {
 			return err
 		}
+		server.UpdateMask = updateMask(cmd, serverFields)
 		ctx, cancel := clientContext()
 		defer cancel()
 		_, err = c.UpdateServer(ctx, server)
@@ -179,3 +257,70 @@ func deleteServer(cmd *cobra.Command, args []string) error {
 		return err
 	})
 }
+
+func drainServer(cmd *cobra.Command, args []string) error {
+	server, err := initServer(cmd, args[0], args[1])
+	if err != nil {
+		return err
+	}
+	err = client(func(c types.MerlinClient) error {
+		ctx, cancel := clientContext()
+		defer cancel()
+		_, err := c.DrainServer(ctx, server)
+		return err
+	})
+	if err != nil || !drainWait {
+		return err
+	}
+	return waitForDrain(server.ServiceID, server.Key)
+}
+
+// waitForDrain polls the stats of every node until the server has no active connections.
+func waitForDrain(serviceID string, key *types.RealServer_Key) error {
+	nodes := nodeAddresses(drainNodes)
+	deadline := time.Now().Add(drainWaitTimeout)
+	for {
+		services, errs := fetchStats(nodes)
+		for _, err := range errs {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+		}
+		if len(errs) == len(nodes) {
+			return errors.New("unable to read stats from any node")
+		}
+
+		var active uint64
+		for _, svc := range services {
+			if svc.id != serviceID {
+				continue
+			}
+			for _, srv := range svc.servers {
+				if proto.Equal(srv.key, key) {
+					active += srv.active
+				}
+			}
+		}
+		if active == 0 && len(errs) == 0 {
+			fmt.Printf("%s/%s drained\n", serviceID, key.PrettyString())
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting for %s/%s to drain, %d active connections remain",
+				serviceID, key.PrettyString(), active)
+		}
+		fmt.Printf("waiting for %d active connections to drain\n", active)
+		time.Sleep(time.Second)
+	}
+}
+
+func undrainServer(cmd *cobra.Command, args []string) error {
+	return client(func(c types.MerlinClient) error {
+		server, err := initServer(cmd, args[0], args[1])
+		if err != nil {
+			return err
+		}
+		ctx, cancel := clientContext()
+		defer cancel()
+		_, err = c.UndrainServer(ctx, server)
+		return err
+	})
+}
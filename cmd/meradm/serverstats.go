@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/sky-uk/merlin/types"
+	"github.com/spf13/cobra"
+)
+
+var serverStatsCmd = &cobra.Command{
+	Use:   "stats [serviceID]",
+	Short: "Show the IPVS counters of each real server of a virtual service",
+	Long: `Show the IPVS counters of each real server of a virtual service, read from the kernel of the node meradm is
+connected to. Share is each server's share of the connections scheduled to the service, to compare against its
+share of the weight when balancing is uneven.`,
+	Args: cobra.ExactArgs(1),
+	RunE: serverStats,
+}
+
+var serverStatsOutput string
+
+func init() {
+	serverCmd.AddCommand(serverStatsCmd)
+	addOutputFlag(serverStatsCmd, &serverStatsOutput)
+}
+
+func serverStats(_ *cobra.Command, args []string) error {
+	return client(func(c types.MerlinClient) error {
+		ctx, cancel := clientContext()
+		defer cancel()
+		resp, err := c.GetServerStats(ctx, &wrappers.StringValue{Value: args[0]})
+		if err != nil {
+			return err
+		}
+		if ok, err := writeOutput(serverStatsOutput, resp); ok {
+			return err
+		}
+		_, err = fmt.Fprint(os.Stdout, formatServerStats(resp))
+		return err
+	})
+}
+
+// formatServerStats renders the counters of each server as a table, with their shares of the weight and of the
+// connections of the service.
+func formatServerStats(resp *types.GetServerStatsResponse) string {
+	svc := resp.Service
+	var weights uint64
+	for _, server := range svc.Servers {
+		weights += uint64(server.Weight)
+	}
+	share := func(n, total uint64) string {
+		if total == 0 {
+			return "-"
+		}
+		return fmt.Sprintf("%.1f%%", 100*float64(n)/float64(total))
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%s [%s] on %s\n\n", svc.Id, svc.Key.PrettyString(), resp.Node)
+	if len(svc.Servers) == 0 {
+		fmt.Fprintln(&b, "  <none>")
+		return b.String()
+	}
+	var table bytes.Buffer
+	colors := make(map[int]string)
+	w := tabwriter.NewWriter(&table, 0, 0, 1, ' ', 0)
+	fmt.Fprintln(w, "RemoteAddress:Port\tWeight\tWeight%\tActiveConn\tInActConn\tConns\tShare\tInPkts\tOutPkts\t"+
+		"InBytes\tOutBytes\tHealth\t")
+	for i, server := range svc.Servers {
+		health := "-"
+		if server.Health != types.Health_UNSET_HEALTH {
+			health = strings.ToLower(server.Health.String())
+		}
+		if server.Health == types.Health_DOWN {
+			colors[i+1] = colorRed
+		}
+		stats := server.Stats
+		fmt.Fprintf(w, "%s\t%d\t%s\t%d\t%d\t%d\t%s\t%d\t%d\t%d\t%d\t%s\t\n",
+			serverStatsKey(server.Key),
+			server.Weight,
+			share(uint64(server.Weight), weights),
+			server.ActiveConnections,
+			server.InactiveConnections,
+			stats.GetConnections(),
+			share(stats.GetConnections(), svc.Stats.GetConnections()),
+			stats.GetPacketsIn(),
+			stats.GetPacketsOut(),
+			stats.GetBytesIn(),
+			stats.GetBytesOut(),
+			health)
+	}
+	w.Flush()
+	b.WriteString(colorLines(table.String(), colors))
+	return b.String()
+}
@@ -3,11 +3,10 @@ package main
 import (
 	"errors"
 
-	"strconv"
-
-	"fmt"
 	"strings"
+	"time"
 
+	"github.com/golang/protobuf/ptypes"
 	"github.com/golang/protobuf/ptypes/wrappers"
 	"github.com/sky-uk/merlin/types"
 	"github.com/spf13/cobra"
@@ -15,7 +14,7 @@ import (
 )
 
 var serviceCmd = &cobra.Command{
-	Use:   "service [add|edit|del]",
+	Use:   "service [add|edit|del|clone|rename|swap|rollout]",
 	Short: "Modify a virtual service",
 }
 
@@ -25,13 +24,13 @@ func validIDProtocolIPPort(_ *cobra.Command, args []string) error {
 	}
 	b := []byte(args[2])
 	if !ipPortRegex.Match(b) {
-		return errors.New("must be ip:port")
+		return errors.New("must be ip:port, or :port with --pool")
 	}
 	return nil
 }
 
 var addServiceCmd = &cobra.Command{
-	Use:   "add [id] [protocol] [ip:port]",
+	Use:   "add [id] [protocol] [ip:port|:port]",
 	Short: "Add a virtual service",
 	Args:  validIDProtocolIPPort,
 	RunE:  addService,
@@ -51,23 +50,102 @@ var deleteServiceCmd = &cobra.Command{
 	RunE:  deleteService,
 }
 
+var cloneServiceCmd = &cobra.Command{
+	Use:   "clone [id] [new-id]",
+	Short: "Copy a virtual service and its real servers to a new service with a different ip, port or protocol",
+	Args:  cobra.ExactArgs(2),
+	RunE:  cloneService,
+}
+
+var renameServiceCmd = &cobra.Command{
+	Use:   "rename [id] [new-id]",
+	Short: "Change the id of a virtual service and its real servers",
+	Args:  cobra.ExactArgs(2),
+	RunE:  renameService,
+}
+
+var swapServersCmd = &cobra.Command{
+	Use:   "swap [id] [other-id]",
+	Short: "Exchange the real servers of two virtual services, for blue/green cutovers",
+	Args:  cobra.ExactArgs(2),
+	RunE:  swapServers,
+}
+
+var rolloutServiceCmd = &cobra.Command{
+	Use:   "rollout [id]",
+	Short: "Change the config of a virtual service on canary nodes first, then every node once they are healthy",
+	Args:  cobra.ExactArgs(1),
+	RunE:  rolloutService,
+}
+
 var (
 	scheduler      string
 	schedulerFlags []string
+	ops            bool
+	serviceLabels  map[string]string
+	nodeSelector   string
+	servicePool    string
+	cloneIP        string
+	clonePort      uint16
+	cloneProtocol  string
+	canaryNodes    []string
+	canaries       uint32
+	rolloutBake    time.Duration
+	rolloutTimeout time.Duration
+	rolloutAbort   bool
 )
 
+// serviceFields are the fields of a service set by each flag of edit.
+var serviceFields = map[string]string{
+	"scheduler":       "config.scheduler",
+	"scheduler-flags": "config.flags",
+	"ops":             "config.ops",
+	"label":           "labels",
+	"node-selector":   "node_selector",
+	"pool":            "pool",
+}
+
 func init() {
 	rootCmd.AddCommand(serviceCmd)
 	serviceCmd.AddCommand(addServiceCmd)
 	serviceCmd.AddCommand(editServiceCmd)
 	serviceCmd.AddCommand(deleteServiceCmd)
+	serviceCmd.AddCommand(cloneServiceCmd)
+	serviceCmd.AddCommand(renameServiceCmd)
+	serviceCmd.AddCommand(swapServersCmd)
+	serviceCmd.AddCommand(rolloutServiceCmd)
 
 	for _, f := range []*pflag.FlagSet{addServiceCmd.Flags(), editServiceCmd.Flags()} {
 		f.StringVarP(&scheduler, "scheduler", "s", "", "scheduler for new connections")
-		f.StringSliceVarP(&schedulerFlags, "scheduler-flags", "b", nil, "scheduler flags")
+		f.StringSliceVarP(&schedulerFlags, "scheduler-flags", "b", nil,
+			"scheduler flags, flag-1 to flag-3, or sh-fallback and sh-port with sh, mh-fallback and mh-port with mh")
+		f.BoolVar(&ops, "ops", false, "one-packet scheduling, which schedules each packet of a UDP service separately")
+		f.VarP(&labelsValue{&serviceLabels}, "label", "l",
+			"labels as key=value, on edit these replace all existing labels")
+		f.StringVar(&nodeSelector, "node-selector", "",
+			"only reconcile the service onto nodes with matching labels, e.g. pool=edge")
+		f.StringVar(&servicePool, "pool", "", "pool the IP of the service is in, allocated from it if the IP is empty")
 	}
 
 	addServiceCmd.MarkFlagRequired("scheduler")
+	addDryRunFlag(addServiceCmd, editServiceCmd, deleteServiceCmd)
+
+	f := cloneServiceCmd.Flags()
+	f.StringVar(&cloneIP, "ip", "", "ip or hostname of the new service, defaults to the ip of the cloned service")
+	f.Uint16Var(&clonePort, "port", 0, "port of the new service, defaults to the port of the cloned service")
+	f.StringVar(&cloneProtocol, "protocol", "",
+		"protocol of the new service, defaults to the protocol of the cloned service")
+
+	f = rolloutServiceCmd.Flags()
+	f.StringVarP(&scheduler, "scheduler", "s", "", "scheduler for new connections")
+	f.StringSliceVarP(&schedulerFlags, "scheduler-flags", "b", nil, "scheduler flags")
+	f.StringSliceVar(&canaryNodes, "canary", nil, "nodes to roll out to first, defaults to --canaries nodes")
+	f.Uint32Var(&canaries, "canaries", 1, "number of canary nodes to choose, if --canary isn't set")
+	f.DurationVar(&rolloutBake, "bake", time.Minute,
+		"how long the canaries must stay healthy after converging, before rolling out to every node")
+	f.DurationVar(&rolloutTimeout, "converge-timeout", 5*time.Minute,
+		"how long the canaries have to converge before the rollout halts")
+	f.BoolVar(&rolloutAbort, "abort", false, "cancel the rollout, leaving the config of the service unchanged")
 }
 
 func serviceFromFlags(cmd *cobra.Command, id string) *types.VirtualService {
@@ -76,7 +154,11 @@ func serviceFromFlags(cmd *cobra.Command, id string) *types.VirtualService {
 		Config: &types.VirtualService_Config{
 			Scheduler: scheduler,
 			Flags:     schedulerFlags,
+			Ops:       ops,
 		},
+		Labels:       serviceLabels,
+		NodeSelector: nodeSelector,
+		Pool:         servicePool,
 	}
 
 	return svc
@@ -88,19 +170,17 @@ func addService(cmd *cobra.Command, args []string) error {
 
 		proto, ok := types.Protocol_value[strings.ToUpper(args[1])]
 		if !ok {
-			return errors.New("unrecognized protocol")
+			return invalidf("unrecognized protocol")
 		}
-		matches := ipPortRegex.FindSubmatch([]byte(args[2]))
-		ip := string(matches[1])
-		port, err := strconv.ParseUint(string(matches[2]), 10, 16)
+		ip, port, err := resolveAddress(args[2])
 		if err != nil {
-			return fmt.Errorf("unable to parse port: %v", err)
+			return err
 		}
 
 		svc.Key = &types.VirtualService_Key{
 			Protocol: types.Protocol(proto),
 			Ip:       ip,
-			Port:     uint32(port),
+			Port:     port,
 		}
 
 		ctx, cancel := clientContext()
@@ -113,6 +193,7 @@ func addService(cmd *cobra.Command, args []string) error {
 func editService(cmd *cobra.Command, args []string) error {
 	return client(func(c types.MerlinClient) error {
 		svc := serviceFromFlags(cmd, args[0])
+		svc.UpdateMask = updateMask(cmd, serviceFields)
 		ctx, cancel := clientContext()
 		defer cancel()
 		_, err := c.UpdateService(ctx, svc)
@@ -129,3 +210,67 @@ func deleteService(_ *cobra.Command, args []string) error {
 		return err
 	})
 }
+
+func cloneService(_ *cobra.Command, args []string) error {
+	ip, err := resolveHost(cloneIP)
+	if err != nil {
+		return err
+	}
+	key := &types.VirtualService_Key{Ip: ip, Port: uint32(clonePort)}
+	if cloneProtocol != "" {
+		proto, ok := types.Protocol_value[strings.ToUpper(cloneProtocol)]
+		if !ok {
+			return invalidf("unrecognized protocol")
+		}
+		key.Protocol = types.Protocol(proto)
+	}
+
+	return client(func(c types.MerlinClient) error {
+		ctx, cancel := clientContext()
+		defer cancel()
+		_, err := c.CloneService(ctx, &types.CloneServiceRequest{Id: args[0], NewId: args[1], Key: key})
+		return err
+	})
+}
+
+func renameService(_ *cobra.Command, args []string) error {
+	return client(func(c types.MerlinClient) error {
+		ctx, cancel := clientContext()
+		defer cancel()
+		_, err := c.RenameService(ctx, &types.RenameServiceRequest{Id: args[0], NewId: args[1]})
+		return err
+	})
+}
+
+func swapServers(_ *cobra.Command, args []string) error {
+	return client(func(c types.MerlinClient) error {
+		ctx, cancel := clientContext()
+		defer cancel()
+		_, err := c.SwapServers(ctx, &types.SwapServersRequest{Id: args[0], OtherId: args[1]})
+		return err
+	})
+}
+
+func rolloutService(_ *cobra.Command, args []string) error {
+	req := &types.RolloutServiceRequest{
+		Id:          args[0],
+		Abort:       rolloutAbort,
+		CanaryNodes: canaryNodes,
+		Canaries:    canaries,
+		Bake:        ptypes.DurationProto(rolloutBake),
+		Timeout:     ptypes.DurationProto(rolloutTimeout),
+	}
+	if !rolloutAbort {
+		if scheduler == "" && len(schedulerFlags) == 0 {
+			return invalidf("requires --scheduler or --scheduler-flags to roll out")
+		}
+		req.Config = &types.VirtualService_Config{Scheduler: scheduler, Flags: schedulerFlags}
+	}
+
+	return client(func(c types.MerlinClient) error {
+		ctx, cancel := clientContext()
+		defer cancel()
+		_, err := c.RolloutService(ctx, req)
+		return err
+	})
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var shellCmd = &cobra.Command{
+	Use:   "shell",
+	Short: "Run meradm commands interactively over a single connection to merlin",
+	Long: `Run meradm commands interactively, e.g. "list" or "server drain service1 10.0.0.1:80", over a single
+connection to merlin. Global flags such as --host and --context are taken from the shell invocation.
+
+History is saved to ~/.meradm/history. "history" lists it, "!!" repeats the last command and "!N" repeats
+command N. Use "exit" or Ctrl-D to quit. For line editing, run the shell with rlwrap: rlwrap meradm shell`,
+	Args: cobra.NoArgs,
+	RunE: runShell,
+}
+
+// shellHistorySize is the number of commands kept in the history file.
+const shellHistorySize = 1000
+
+func init() {
+	rootCmd.AddCommand(shellCmd)
+}
+
+func runShell(_ *cobra.Command, _ []string) error {
+	dest := fmt.Sprintf("%s:%d", host, port)
+	conn, err := dial(dest)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	sharedConn = conn
+	defer func() { sharedConn = nil }()
+
+	globals := globalFlagValues()
+	history := loadHistory()
+	defer func() { saveHistory(history) }()
+	// errors are shown without the usage, which would bury them when running many commands
+	rootCmd.SilenceUsage = true
+
+	interactive := isTerminal(os.Stdin)
+	in := bufio.NewScanner(os.Stdin)
+	for {
+		if interactive {
+			fmt.Printf("meradm %s> ", dest)
+		}
+		if !in.Scan() {
+			if interactive {
+				fmt.Println()
+			}
+			return in.Err()
+		}
+		line := strings.TrimSpace(in.Text())
+
+		switch {
+		case line == "":
+			continue
+		case line == "exit" || line == "quit":
+			return nil
+		case line == "history":
+			for i, cmd := range history {
+				fmt.Printf("%4d  %s\n", i+1, cmd)
+			}
+			continue
+		case strings.HasPrefix(line, "!"):
+			if line, err = recall(history, line); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				continue
+			}
+			fmt.Println(line)
+		}
+		history = append(history, line)
+
+		args, err := splitArgs(line)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			continue
+		}
+		if args[0] == "shell" {
+			fmt.Fprintln(os.Stderr, "already in a shell")
+			continue
+		}
+
+		// errors are printed by cobra
+		rootCmd.SetArgs(args)
+		rootCmd.Execute()
+		resetFlags(rootCmd, globals)
+	}
+}
+
+// recall returns the command referenced by !! or !N.
+func recall(history []string, ref string) (string, error) {
+	if ref == "!!" {
+		if len(history) == 0 {
+			return "", errors.New("no previous command")
+		}
+		return history[len(history)-1], nil
+	}
+	n, err := strconv.Atoi(ref[1:])
+	if err != nil || n < 1 || n > len(history) {
+		return "", fmt.Errorf("%s: not in history", ref)
+	}
+	return history[n-1], nil
+}
+
+// splitArgs splits a command line into arguments like a POSIX shell, supporting quotes and backslash escapes.
+func splitArgs(line string) ([]string, error) {
+	var args []string
+	var arg strings.Builder
+	var quote rune
+	inArg, escaped := false, false
+	for _, r := range line {
+		switch {
+		case escaped:
+			arg.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inArg = true, true
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			arg.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, errors.New("unterminated quote or escape")
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
+
+// globalFlagValues returns the values of the global flags, as set when starting the shell.
+func globalFlagValues() map[string]string {
+	values := make(map[string]string)
+	rootCmd.PersistentFlags().VisitAll(func(flag *pflag.Flag) {
+		values[flag.Name] = flag.Value.String()
+	})
+	return values
+}
+
+// resetFlags restores the flags of cmd and its subcommands, as cobra keeps flag values between executions.
+// Global flags are restored to the values they had when the shell started, and other flags to their defaults.
+func resetFlags(cmd *cobra.Command, globals map[string]string) {
+	reset := func(flag *pflag.Flag) {
+		value, ok := globals[flag.Name]
+		if !ok {
+			value = flag.DefValue
+		}
+		var err error
+		if slice, ok := flag.Value.(pflag.SliceValue); ok {
+			// slice flags default to empty
+			err = slice.Replace(nil)
+		} else {
+			err = flag.Value.Set(value)
+		}
+		if err != nil {
+			log.Warnf("Unable to reset --%s: %v", flag.Name, err)
+		}
+		flag.Changed = false
+	}
+	if cmd == rootCmd {
+		cmd.PersistentFlags().VisitAll(reset)
+	}
+	cmd.LocalNonPersistentFlags().VisitAll(reset)
+	for _, sub := range cmd.Commands() {
+		resetFlags(sub, globals)
+	}
+}
+
+func historyFile() string {
+	if configFile == "" {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(configFile), "history")
+}
+
+func loadHistory() []string {
+	f, err := os.Open(historyFile())
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	var history []string
+	in := bufio.NewScanner(f)
+	for in.Scan() {
+		history = append(history, in.Text())
+	}
+	return history
+}
+
+func saveHistory(history []string) {
+	path := historyFile()
+	if path == "" {
+		return
+	}
+	if len(history) > shellHistorySize {
+		history = history[len(history)-shellHistorySize:]
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		log.Warnf("Unable to save history: %v", err)
+		return
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		log.Warnf("Unable to save history: %v", err)
+		return
+	}
+	defer f.Close()
+	for _, line := range history {
+		io.WriteString(f, line+"\n")
+	}
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
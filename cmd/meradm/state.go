@@ -0,0 +1,199 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/sky-uk/merlin/types"
+	"github.com/spf13/cobra"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export all services and servers as YAML, as ipvsadm rules or as keepalived configuration",
+	Long: `Export all services and servers as YAML, or as a fallback if merlin is down:
+
+  --format ipvsadm-save  rules which can be restored with "ipvsadm -R"
+  --format keepalived    virtual_server blocks of keepalived.conf, with HTTP_GET health checks`,
+	Args: cobra.NoArgs,
+	RunE: export,
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import [file|-]",
+	Short: "Import services and servers from YAML produced by export, from ipvsadm rules, keepalived or gorb",
+	Long: `Import services and servers from YAML produced by export, or to onboard an existing director:
+
+  --format ipvsadm-save  the rules printed by "ipvsadm -S -n"
+  --format keepalived    the virtual_server blocks of keepalived.conf, including HTTP_GET health checks
+  --format gorb          gorb's store printed by "etcdctl get --prefix /gorb", including http pulses
+
+Services imported from ipvsadm or keepalived are given IDs like tcp-10-1-1-1-80, and services imported from gorb
+keep their gorb IDs. To choose the IDs, pass --id-map a YAML file mapping protocol/ip:port to the ID:
+
+  tcp/10.1.1.1:80: web
+  udp/[2001:db8::1]:53: dns`,
+	Args: cobra.ExactArgs(1),
+	RunE: importState,
+}
+
+// Formats of import and export.
+const (
+	formatYAML        = "yaml"
+	formatIpvsadmSave = "ipvsadm-save"
+	formatKeepalived  = "keepalived"
+	formatGorb        = "gorb"
+)
+
+var (
+	importFormats = []string{formatYAML, formatIpvsadmSave, formatKeepalived, formatGorb}
+	exportFormats = []string{formatYAML, formatIpvsadmSave, formatKeepalived}
+)
+
+var (
+	exportOutput string
+	exportFormat string
+	importPrune  bool
+	importDryRun bool
+	importFormat string
+	importIDMap  string
+)
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(importCmd)
+
+	exportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "file to write to, defaults to stdout")
+	exportCmd.Flags().StringVar(&exportFormat, "format", formatYAML,
+		fmt.Sprintf("format of the output, one of %s", strings.Join(exportFormats, ", ")))
+	importCmd.Flags().BoolVar(&importPrune, "prune", false,
+		"delete services and servers that aren't in the file")
+	importCmd.Flags().BoolVar(&importDryRun, "dry-run", false, "print the changes without applying them")
+	importCmd.Flags().StringVar(&importFormat, "format", formatYAML,
+		fmt.Sprintf("format of the file, one of %s", strings.Join(importFormats, ", ")))
+	importCmd.Flags().StringVar(&importIDMap, "id-map", "",
+		"YAML file mapping protocol/ip:port to service IDs, for the ipvsadm-save, keepalived and gorb formats")
+}
+
+func export(_ *cobra.Command, _ []string) error {
+	if err := checkFormat(exportFormat, exportFormats); err != nil {
+		return err
+	}
+	return client(func(c types.MerlinClient) error {
+		ctx, cancel := clientContext()
+		defer cancel()
+		snapshot, err := c.GetSnapshot(ctx, &empty.Empty{})
+		if err != nil {
+			return err
+		}
+
+		out, err := encodeSnapshot(snapshot)
+		if err != nil {
+			return fmt.Errorf("unable to encode snapshot: %v", err)
+		}
+		if exportOutput == "" {
+			_, err = os.Stdout.Write(out)
+			return err
+		}
+		return ioutil.WriteFile(exportOutput, out, 0644)
+	})
+}
+
+func readInput(file string) ([]byte, error) {
+	if file == "-" {
+		return ioutil.ReadAll(os.Stdin)
+	}
+	return ioutil.ReadFile(file)
+}
+
+func importState(_ *cobra.Command, args []string) error {
+	data, err := readInput(args[0])
+	if err != nil {
+		return err
+	}
+	snapshot, err := decodeSnapshot(args[0], data)
+	if err != nil {
+		return err
+	}
+	if len(snapshot.Services) == 0 && importPrune {
+		return errors.New("refusing to prune everything, the input has no services")
+	}
+
+	return client(func(c types.MerlinClient) error {
+		ctx, cancel := clientContext()
+		defer cancel()
+		resp, err := c.ApplySnapshot(ctx, &types.ApplySnapshotRequest{
+			Snapshot: snapshot,
+			Prune:    importPrune,
+			DryRun:   importDryRun,
+		})
+		if err != nil {
+			return err
+		}
+
+		prefix := ""
+		if importDryRun {
+			prefix = "(dry run) "
+		}
+		for _, change := range resp.Changes {
+			fmt.Printf("%s%s\n", prefix, change.PrettyString())
+		}
+		return nil
+	})
+}
+
+func decodeSnapshot(file string, data []byte) (*types.Snapshot, error) {
+	switch importFormat {
+	case formatYAML:
+		snapshot := &types.Snapshot{}
+		if err := unmarshalYAML(data, snapshot); err != nil {
+			return nil, invalidf("unable to decode %s: %v", file, err)
+		}
+		return snapshot, nil
+	case formatIpvsadmSave, formatKeepalived, formatGorb:
+		ids, err := readIDMap(importIDMap)
+		if err != nil {
+			return nil, err
+		}
+		var snapshot *types.Snapshot
+		switch importFormat {
+		case formatKeepalived:
+			snapshot, err = parseKeepalived(data, ids)
+		case formatGorb:
+			snapshot, err = parseGorb(data, ids)
+		default:
+			snapshot, err = parseIpvsadmSave(data, ids)
+		}
+		if err != nil {
+			return nil, wrapError(err, "unable to decode %s", file)
+		}
+		return snapshot, nil
+	default:
+		return nil, checkFormat(importFormat, importFormats)
+	}
+}
+
+func encodeSnapshot(snapshot *types.Snapshot) ([]byte, error) {
+	switch exportFormat {
+	case formatIpvsadmSave:
+		return writeIpvsadmSave(snapshot)
+	case formatKeepalived:
+		return writeKeepalived(snapshot)
+	default:
+		return marshalYAML(snapshot)
+	}
+}
+
+func checkFormat(format string, formats []string) error {
+	for _, f := range formats {
+		if format == f {
+			return nil
+		}
+	}
+	return withExitCode(exitUsage, fmt.Errorf("unknown format %q, must be one of %s", format,
+		strings.Join(formats, ", ")))
+}
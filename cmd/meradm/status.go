@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/sky-uk/merlin/types"
+	"github.com/spf13/cobra"
+)
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the state of the merlin cluster",
+	Args:  cobra.NoArgs,
+	RunE:  showStatus,
+}
+
+var statusOutput string
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+	addOutputFlag(statusCmd, &statusOutput)
+}
+
+func showStatus(_ *cobra.Command, _ []string) error {
+	return client(func(c types.MerlinClient) error {
+		ctx, cancel := clientContext()
+		defer cancel()
+		info, err := c.Info(ctx, &empty.Empty{})
+		if err != nil {
+			return err
+		}
+		if ok, err := writeOutput(statusOutput, info); ok {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
+		fmt.Fprintf(w, "Node:\t%s\n", info.Node.Name)
+		fmt.Fprintf(w, "Version:\t%s\n", info.Node.Version)
+		if info.StoreError != "" {
+			fmt.Fprintf(w, "Store:\t%s, unhealthy: %s\n", info.Node.StoreBackend, info.StoreError)
+		} else {
+			fmt.Fprintf(w, "Store:\t%s, healthy\n", info.Node.StoreBackend)
+			fmt.Fprintf(w, "Services:\t%d\n", info.Services)
+			fmt.Fprintf(w, "Servers:\t%d\n", info.Servers)
+		}
+		w.Flush()
+
+		if info.StoreError != "" {
+			return nil
+		}
+		nodes, err := c.ListNodes(ctx, &empty.Empty{})
+		if err != nil {
+			return err
+		}
+		fmt.Println()
+		writeNodes(nodes.Nodes)
+		return nil
+	})
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/sky-uk/merlin/types"
+	"github.com/spf13/cobra"
+)
+
+var syncdCmd = &cobra.Command{
+	Use:   "syncd [show|role]",
+	Short: "Manage the IPVS connection sync daemon of the node meradm is connected to",
+}
+
+var showSyncdCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show the sync daemons running on the node, like ipvsadm -L --daemon",
+	Args:  cobra.NoArgs,
+	RunE:  showSyncd,
+}
+
+var roleSyncdCmd = &cobra.Command{
+	Use:   "role [master|backup|none]",
+	Short: "Switch the sync daemon of the node to master or backup, or stop it",
+	Long: `Switch the sync daemon of the node to master or backup, or stop it with none. The master multicasts its
+connections to the backups with the same sync ID, so a backup which takes over the VIPs keeps the established
+connections. The node must be started with --sync-daemon-interface, and the role reverts to its
+--sync-daemon-role when merlin restarts.`,
+	Args:      cobra.ExactValidArgs(1),
+	ValidArgs: []string{"master", "backup", "none"},
+	RunE:      roleSyncd,
+}
+
+var syncdOutput string
+
+func init() {
+	rootCmd.AddCommand(syncdCmd)
+	syncdCmd.AddCommand(showSyncdCmd)
+	syncdCmd.AddCommand(roleSyncdCmd)
+	addOutputFlag(showSyncdCmd, &syncdOutput)
+}
+
+func showSyncd(_ *cobra.Command, _ []string) error {
+	return client(func(c types.MerlinClient) error {
+		ctx, cancel := clientContext()
+		defer cancel()
+		resp, err := c.GetSyncDaemons(ctx, &empty.Empty{})
+		if err != nil {
+			return err
+		}
+		if ok, err := writeOutput(syncdOutput, resp); ok {
+			return err
+		}
+		return printSyncDaemons(resp)
+	})
+}
+
+func roleSyncd(_ *cobra.Command, args []string) error {
+	role := types.SyncDaemon_UNSET_SYNC_ROLE
+	if args[0] != "none" {
+		role = types.SyncDaemon_Role(types.SyncDaemon_Role_value[strings.ToUpper(args[0])])
+	}
+	return client(func(c types.MerlinClient) error {
+		ctx, cancel := clientContext()
+		defer cancel()
+		resp, err := c.SetSyncRole(ctx, &types.SetSyncRoleRequest{Role: role})
+		if err != nil {
+			return err
+		}
+		return printSyncDaemons(resp)
+	})
+}
+
+func printSyncDaemons(resp *types.SyncDaemons) error {
+	if len(resp.Daemons) == 0 {
+		fmt.Printf("No sync daemons are running on %s\n", resp.Node)
+		return nil
+	}
+	fmt.Printf("Node: %s\n\n", resp.Node)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
+	fmt.Fprintln(w, "Role\tInterface\tSyncID\t")
+	for _, d := range resp.Daemons {
+		fmt.Fprintf(w, "%s\t%s\t%d\t\n", strings.ToLower(d.Role.String()), d.Interface, d.SyncId)
+	}
+	return w.Flush()
+}
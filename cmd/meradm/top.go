@@ -0,0 +1,286 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/sky-uk/merlin/types"
+	"github.com/spf13/cobra"
+)
+
+var topCmd = &cobra.Command{
+	Use:   "top",
+	Short: "Display live IPVS traffic of services and servers",
+	Long: `Periodically display the IPVS traffic of every virtual service and real server, summed across the
+given merlin nodes. Servers whose share of active connections deviates from their share of the service
+weight by more than --imbalance are highlighted. The weight of a server the nodes disagree on, such as during a
+rollout, is shown as mixed, and its service isn't checked for imbalance.`,
+	Args: cobra.NoArgs,
+	RunE: top,
+}
+
+var (
+	topInterval   time.Duration
+	topIterations int
+	topNodes      []string
+	topImbalance  float64
+)
+
+func init() {
+	rootCmd.AddCommand(topCmd)
+	f := topCmd.Flags()
+	f.DurationVarP(&topInterval, "interval", "i", 2*time.Second, "time between refreshes")
+	f.IntVarP(&topIterations, "iterations", "n", 0, "number of refreshes before exiting, 0 to run until interrupted")
+	f.StringSliceVar(&topNodes, "nodes", nil,
+		"comma delimited list of merlin nodes as host[:port] to aggregate, defaults to --host")
+	f.Float64Var(&topImbalance, "imbalance", 0.25,
+		"highlight servers whose share of active connections deviates from their weight share by this fraction")
+}
+
+const (
+	clearScreen   = "\033[H\033[2J"
+	highlightLine = "\033[7m"
+	resetLine     = "\033[0m"
+)
+
+type topService struct {
+	id      string
+	key     *types.VirtualService_Key
+	stats   types.Stats
+	servers []*topServer
+}
+
+type topServer struct {
+	key    *types.RealServer_Key
+	weight uint32
+	// mixedWeight is set if the nodes have different weights for the server, such as during a rollout
+	mixedWeight bool
+	active      uint64
+	inactive    uint64
+	stats       types.Stats
+	imbalanced  bool
+}
+
+func top(_ *cobra.Command, _ []string) error {
+	nodes := nodeAddresses(topNodes)
+
+	t := time.NewTicker(topInterval)
+	defer t.Stop()
+	for i := 1; ; i++ {
+		services, errs := fetchStats(nodes)
+		if len(errs) == len(nodes) {
+			return wrapError(errs[0], "unable to read stats from any node")
+		}
+		for _, svc := range services {
+			markImbalanced(svc, topImbalance)
+		}
+
+		var out bytes.Buffer
+		out.WriteString(clearScreen)
+		fmt.Fprintf(&out, "merlin top - %s - %d/%d nodes - every %v\n",
+			time.Now().Format("15:04:05"), len(nodes)-len(errs), len(nodes), topInterval)
+		for _, err := range errs {
+			fmt.Fprintf(&out, "%v\n", err)
+		}
+		out.WriteString("\n")
+		writeTopTable(&out, services)
+		os.Stdout.Write(out.Bytes())
+
+		if topIterations > 0 && i >= topIterations {
+			return nil
+		}
+		<-t.C
+	}
+}
+
+// fetchStats from every node concurrently, merging them into a single view of the cluster.
+func fetchStats(nodes []string) ([]*topService, []error) {
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		resps []*types.StatsResponse
+		errs  []error
+	)
+	for _, node := range nodes {
+		wg.Add(1)
+		go func(node string) {
+			defer wg.Done()
+			err := clientFor(node, func(c types.MerlinClient) error {
+				ctx, cancel := clientContext()
+				defer cancel()
+				resp, err := c.Stats(ctx, &empty.Empty{})
+				if err != nil {
+					return err
+				}
+				mu.Lock()
+				resps = append(resps, resp)
+				mu.Unlock()
+				return nil
+			})
+			if err != nil {
+				mu.Lock()
+				errs = append(errs, wrapError(err, "%s", node))
+				mu.Unlock()
+			}
+		}(node)
+	}
+	wg.Wait()
+	return mergeStats(resps), errs
+}
+
+func mergeStats(resps []*types.StatsResponse) []*topService {
+	byKey := make(map[string]*topService)
+	for _, resp := range resps {
+		for _, s := range resp.Services {
+			k := s.Key.PrettyString()
+			svc, ok := byKey[k]
+			if !ok {
+				svc = &topService{id: s.Id, key: s.Key}
+				byKey[k] = svc
+			}
+			addStats(&svc.stats, s.Stats)
+
+			for _, srv := range s.Servers {
+				var match *topServer
+				for _, existing := range svc.servers {
+					if existing.key.PrettyString() == srv.Key.PrettyString() {
+						match = existing
+						break
+					}
+				}
+				if match == nil {
+					match = &topServer{key: srv.Key, weight: srv.Weight}
+					svc.servers = append(svc.servers, match)
+				} else if match.weight != srv.Weight {
+					match.mixedWeight = true
+				}
+				match.active += uint64(srv.ActiveConnections)
+				match.inactive += uint64(srv.InactiveConnections)
+				addStats(&match.stats, srv.Stats)
+			}
+		}
+	}
+
+	var services []*topService
+	for _, svc := range byKey {
+		sort.Slice(svc.servers, func(i, j int) bool {
+			return svc.servers[i].key.PrettyString() < svc.servers[j].key.PrettyString()
+		})
+		services = append(services, svc)
+	}
+	sort.Slice(services, func(i, j int) bool {
+		if services[i].id != services[j].id {
+			return services[i].id < services[j].id
+		}
+		return services[i].key.PrettyString() < services[j].key.PrettyString()
+	})
+	return services
+}
+
+func addStats(dst *types.Stats, src *types.Stats) {
+	if src == nil {
+		return
+	}
+	dst.Connections += src.Connections
+	dst.PacketsIn += src.PacketsIn
+	dst.PacketsOut += src.PacketsOut
+	dst.BytesIn += src.BytesIn
+	dst.BytesOut += src.BytesOut
+	dst.Cps += src.Cps
+	dst.PpsIn += src.PpsIn
+	dst.PpsOut += src.PpsOut
+	dst.BpsIn += src.BpsIn
+	dst.BpsOut += src.BpsOut
+}
+
+// markImbalanced flags servers whose share of the service's active connections deviates from their
+// share of the service's weight by more than threshold. Services whose nodes disagree on a server's weight aren't
+// checked, as there's no single share to compare with.
+func markImbalanced(svc *topService, threshold float64) {
+	var totalWeight, totalActive float64
+	for _, srv := range svc.servers {
+		if srv.mixedWeight {
+			return
+		}
+		totalWeight += float64(srv.weight)
+		totalActive += float64(srv.active)
+	}
+	if totalWeight == 0 || totalActive == 0 {
+		return
+	}
+	for _, srv := range svc.servers {
+		expected := float64(srv.weight) / totalWeight
+		actual := float64(srv.active) / totalActive
+		if expected == 0 {
+			srv.imbalanced = actual > 0
+			continue
+		}
+		srv.imbalanced = math.Abs(actual-expected)/expected > threshold
+	}
+}
+
+func writeTopTable(out *bytes.Buffer, services []*topService) {
+	var table bytes.Buffer
+	var highlighted []int
+	line := 2
+	w := tabwriter.NewWriter(&table, 0, 0, 1, ' ', 0)
+
+	fmt.Fprintln(w, "ID\tProt\tLocalAddress:Port\tWeight\tActiveConn\tInActConn\tCPS\tInPPS\tOutPPS\tInBPS\tOutBPS\t")
+	fmt.Fprintln(w, "\t  ->\tRemoteAddress:Port\t\t\t\t\t\t\t\t\t")
+	for _, svc := range services {
+		var active, inactive uint64
+		for _, srv := range svc.servers {
+			active += srv.active
+			inactive += srv.inactive
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s:%d\t\t%d\t%d\t%s\t\n",
+			svc.id,
+			svc.key.Protocol.String(),
+			svc.key.Ip,
+			svc.key.Port,
+			active,
+			inactive,
+			formatRates(&svc.stats))
+		line++
+
+		for _, srv := range svc.servers {
+			weight := fmt.Sprint(srv.weight)
+			if srv.mixedWeight {
+				weight = "mixed"
+			}
+			fmt.Fprintf(w, "\t  ->\t%s:%d\t%s\t%d\t%d\t%s\t\n",
+				srv.key.Ip,
+				srv.key.Port,
+				weight,
+				srv.active,
+				srv.inactive,
+				formatRates(&srv.stats))
+			if srv.imbalanced {
+				highlighted = append(highlighted, line)
+			}
+			line++
+		}
+	}
+	w.Flush()
+
+	lines := strings.Split(strings.TrimSuffix(table.String(), "\n"), "\n")
+	for _, i := range highlighted {
+		if useColor() {
+			lines[i] = highlightLine + lines[i] + resetLine
+		}
+	}
+	out.WriteString(strings.Join(lines, "\n"))
+	out.WriteString("\n")
+}
+
+func formatRates(s *types.Stats) string {
+	return fmt.Sprintf("%d\t%d\t%d\t%d\t%d", s.Cps, s.PpsIn, s.PpsOut, s.BpsIn, s.BpsOut)
+}
@@ -1,6 +1,194 @@
 package main
 
-import "regexp"
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
-// Simple regex to ensure we have something:port. We rely on merlin to perform proper validation.
-var ipPortRegex = regexp.MustCompile(`^([^:]+):(\d+)$`)
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	log "github.com/sirupsen/logrus"
+	"github.com/sky-uk/merlin/types"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"google.golang.org/genproto/protobuf/field_mask"
+)
+
+// Simple regex to ensure we have something:port. Hostnames are resolved by meradm, and we rely on merlin to
+// perform proper validation of the IP, which may be empty for services allocated from a pool.
+var ipPortRegex = regexp.MustCompile(`^([^:]*):(\d+)$`)
+
+// resolveAddress splits host:port, resolving the host if it's a hostname.
+func resolveAddress(addr string) (string, uint32, error) {
+	matches := ipPortRegex.FindStringSubmatch(addr)
+	if matches == nil {
+		return "", 0, invalidf("%s must be host:port", addr)
+	}
+	port, err := strconv.ParseUint(matches[2], 10, 16)
+	if err != nil {
+		return "", 0, invalidf("unable to parse port: %v", err)
+	}
+	ip, err := resolveHost(matches[1])
+	return ip, uint32(port), err
+}
+
+// resolveHost returns the IP address of host, preferring the IP version set by --resolve.
+// Empty hosts and IP addresses are returned as is.
+func resolveHost(host string) (string, error) {
+	if host == "" || net.ParseIP(host) != nil {
+		return host, nil
+	}
+	if resolveVersion != 4 && resolveVersion != 6 {
+		return "", withExitCode(exitUsage, fmt.Errorf("--resolve must be 4 or 6, not %d", resolveVersion))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return "", fmt.Errorf("unable to resolve %s: %v", host, err)
+	}
+	var preferred, other []net.IP
+	for _, addr := range addrs {
+		if (addr.IP.To4() != nil) == (resolveVersion == 4) {
+			preferred = append(preferred, addr.IP)
+		} else {
+			other = append(other, addr.IP)
+		}
+	}
+	ips := append(preferred, other...)
+	if len(ips) == 0 {
+		return "", fmt.Errorf("%s has no IP addresses", host)
+	}
+
+	if len(ips) > 1 {
+		log.Warnf("%s resolves to %d addresses, using the first", host, len(ips))
+	}
+	fmt.Fprintf(os.Stderr, "Resolved %s to %s\n", host, ips[0])
+	return ips[0].String(), nil
+}
+
+// nodeAddresses returns the host:port of each node, defaulting to --host and --port.
+func nodeAddresses(nodes []string) []string {
+	if len(nodes) == 0 {
+		nodes = []string{host}
+	}
+	var addrs []string
+	for _, node := range nodes {
+		if _, _, err := net.SplitHostPort(node); err != nil {
+			node = net.JoinHostPort(node, strconv.Itoa(int(port)))
+		}
+		addrs = append(addrs, node)
+	}
+	return addrs
+}
+
+// since formats the time elapsed since ts, such as "5s ago", or "never" if ts is unset.
+func since(ts *timestamp.Timestamp) string {
+	if ts == nil {
+		return "never"
+	}
+	t, err := ptypes.Timestamp(ts)
+	if err != nil {
+		return "invalid"
+	}
+	return time.Since(t).Round(time.Second).String() + " ago"
+}
+
+// labelsValue is a flag of comma delimited key=value labels. Unlike pflag's StringToString, it implements
+// pflag.SliceValue so the shell can reset it between commands.
+type labelsValue struct {
+	labels *map[string]string
+}
+
+func (v *labelsValue) Set(val string) error {
+	for _, pair := range strings.Split(val, ",") {
+		if err := v.Append(pair); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (v *labelsValue) Type() string {
+	return "stringToString"
+}
+
+func (v *labelsValue) String() string {
+	return "[" + types.PrettyLabels(*v.labels) + "]"
+}
+
+func (v *labelsValue) Append(pair string) error {
+	kv := strings.SplitN(pair, "=", 2)
+	if len(kv) != 2 {
+		return fmt.Errorf("%s must be formatted as key=value", pair)
+	}
+	if *v.labels == nil {
+		*v.labels = make(map[string]string)
+	}
+	(*v.labels)[kv[0]] = kv[1]
+	return nil
+}
+
+func (v *labelsValue) Replace(pairs []string) error {
+	*v.labels = nil
+	for _, pair := range pairs {
+		if err := v.Append(pair); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (v *labelsValue) GetSlice() []string {
+	if len(*v.labels) == 0 {
+		return nil
+	}
+	return strings.Split(types.PrettyLabels(*v.labels), ",")
+}
+
+// Terminal colors of output lines.
+const (
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+	colorReset  = "\033[0m"
+)
+
+// useColor returns true if output to stdout should be colored.
+func useColor() bool {
+	_, noColorEnv := os.LookupEnv("NO_COLOR")
+	return !noColor && !noColorEnv && isTerminal(os.Stdout)
+}
+
+// colorLines colors the given lines of table, which is rendered by a tabwriter, as escape codes would
+// otherwise break its alignment.
+func colorLines(table string, colors map[int]string) string {
+	if !useColor() {
+		return table
+	}
+	lines := strings.Split(table, "\n")
+	for i, color := range colors {
+		if color != "" && i < len(lines) {
+			lines[i] = color + lines[i] + colorReset
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// updateMask returns the mask of the fields set by the flags given on the command line, so an update only changes
+// those fields, even to empty values, and doesn't overwrite changes made to the others since.
+func updateMask(cmd *cobra.Command, fields map[string]string) *field_mask.FieldMask {
+	mask := &field_mask.FieldMask{}
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		if field, ok := fields[f.Name]; ok {
+			mask.Paths = append(mask.Paths, field)
+		}
+	})
+	return mask
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/sky-uk/merlin/types"
+	"github.com/sky-uk/merlin/validation"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc/status"
+)
+
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate services and servers in YAML produced by export, without contacting merlin",
+	Args:  cobra.NoArgs,
+	RunE:  validate,
+	// validation is offline, so a broken context shouldn't fail it
+	PersistentPreRunE: func(_ *cobra.Command, _ []string) error { return nil },
+}
+
+var validateFile string
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+
+	validateCmd.Flags().StringVarP(&validateFile, "file", "f", "", "file to validate, or - for stdin")
+	validateCmd.MarkFlagRequired("file")
+}
+
+func validate(_ *cobra.Command, _ []string) error {
+	data, err := readInput(validateFile)
+	if err != nil {
+		return err
+	}
+	snapshot := &types.Snapshot{}
+	if err := unmarshalYAML(data, snapshot); err != nil {
+		return invalidf("unable to decode %s: %v", validateFile, err)
+	}
+
+	if err := validation.Snapshot(snapshot, nil); err != nil {
+		return invalidf("%s is invalid: %s", validateFile, status.Convert(err).Message())
+	}
+	fmt.Printf("%s is valid: %d services, %d servers\n", validateFile, len(snapshot.Services),
+		len(snapshot.Servers))
+	return nil
+}
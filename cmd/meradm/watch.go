@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sky-uk/merlin/types"
+	"github.com/spf13/cobra"
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Print the services and servers, then each change made to them until interrupted",
+	Args:  cobra.NoArgs,
+	RunE:  watch,
+}
+
+var (
+	watchSelector      string
+	watchFieldSelector string
+	watchAllNamespaces bool
+	watchOutput        string
+)
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+
+	f := watchCmd.Flags()
+	f.StringVarP(&watchSelector, "selector", "l", "",
+		"label selector, e.g. 'team=payments,env!=prod', supports =, ==, !=, key and !key")
+	f.StringVar(&watchFieldSelector, "field-selector", "",
+		"field selector on "+strings.Join(types.ServiceFields, ", ")+", e.g. 'protocol=tcp,port=80'")
+	f.BoolVarP(&watchAllNamespaces, "all-namespaces", "A", false, "watch the services of every namespace, "+
+		"instead of only those in --namespace if it's set")
+	addOutputFlag(watchCmd, &watchOutput)
+}
+
+func watch(_ *cobra.Command, _ []string) error {
+	selector := namespaceSelector(watchSelector, watchAllNamespaces)
+	if _, err := types.ParseSelector(selector); err != nil {
+		return withExitCode(exitInvalid, err)
+	}
+	if _, err := types.ParseFieldSelector(watchFieldSelector); err != nil {
+		return withExitCode(exitInvalid, err)
+	}
+
+	return client(func(c types.MerlinClient) error {
+		// the watch runs until interrupted, so it has no timeout
+		stream, err := c.Watch(context.Background(), &types.WatchRequest{
+			LabelSelector: selector,
+			FieldSelector: watchFieldSelector,
+		})
+		if err != nil {
+			return err
+		}
+		for {
+			event, err := stream.Recv()
+			if err != nil {
+				return err
+			}
+			if ok, err := writeOutput(watchOutput, event); ok {
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			now := time.Now().Format(time.RFC3339)
+			for _, change := range event.Changes {
+				serviceID := change.GetService().GetId()
+				if change.Server != nil {
+					serviceID = change.Server.ServiceID
+				}
+				fmt.Printf("%s %s %s\n", now, serviceID, historyString(change))
+			}
+		}
+	})
+}
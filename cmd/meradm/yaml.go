@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"gopkg.in/yaml.v2"
+)
+
+// marshalYAML converts a proto message to YAML, using the same field names as its JSON mapping.
+func marshalYAML(pb proto.Message) ([]byte, error) {
+	m := jsonpb.Marshaler{OrigName: true}
+	js, err := m.MarshalToString(pb)
+	if err != nil {
+		return nil, err
+	}
+	var obj yaml.MapSlice
+	if err := yaml.Unmarshal([]byte(js), &obj); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(obj)
+}
+
+// unmarshalYAML reads YAML into a proto message, using the same field names as its JSON mapping.
+func unmarshalYAML(data []byte, pb proto.Message) error {
+	var obj interface{}
+	if err := yaml.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+	obj, err := jsonCompatible(obj)
+	if err != nil {
+		return err
+	}
+	js, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	return jsonpb.Unmarshal(bytes.NewReader(js), pb)
+}
+
+// jsonCompatible converts the generic maps produced by yaml into maps with string keys.
+func jsonCompatible(obj interface{}) (interface{}, error) {
+	switch v := obj.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			key, ok := k.(string)
+			if !ok {
+				return nil, fmt.Errorf("unsupported key %v, keys must be strings", k)
+			}
+			converted, err := jsonCompatible(val)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = converted
+		}
+		return m, nil
+	case []interface{}:
+		for i, val := range v {
+			converted, err := jsonCompatible(val)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = converted
+		}
+		return v, nil
+	default:
+		return obj, nil
+	}
+}
@@ -0,0 +1,15 @@
+package main
+
+var (
+	activeElection   string
+	passiveReconcile bool
+)
+
+func init() {
+	f := rootCmd.PersistentFlags()
+	f.StringVar(&activeElection, "active-election", "",
+		"elect one node of an active/passive pair sharing this name as active, which is the only one to configure and "+
+			"announce the VIPs and run the master sync daemon")
+	f.BoolVar(&passiveReconcile, "passive-reconcile", false,
+		"keep reconciling ipvs while passive, so taking over only brings up the VIPs")
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sky-uk/merlin/admission"
+	"github.com/sky-uk/merlin/server"
+)
+
+var (
+	admissionWebhook         string
+	admissionWebhookCA       string
+	admissionWebhookTimeout  time.Duration
+	admissionWebhookFailOpen bool
+)
+
+func init() {
+	f := rootCmd.PersistentFlags()
+	f.StringVar(&admissionWebhook, "admission-webhook", "",
+		"http(s) url POSTed the changes of every write before they're committed, which can allow, deny or mutate them")
+	f.StringVar(&admissionWebhookCA, "admission-webhook-ca", "",
+		"CA certificate to verify the admission webhook with, instead of the system's")
+	f.DurationVar(&admissionWebhookTimeout, "admission-webhook-timeout", 10*time.Second,
+		"how long to wait for the admission webhook to respond")
+	f.BoolVar(&admissionWebhookFailOpen, "admission-webhook-fail-open", false,
+		"allow writes when the admission webhook can't be called, instead of rejecting them")
+}
+
+// admitter returns the admission set by the --admission-webhook flags, or nil if there isn't a webhook.
+func admitter() (server.AdmitFunc, error) {
+	if admissionWebhook == "" {
+		return nil, nil
+	}
+	webhook, err := admission.NewWebhook(admissionWebhook, admissionWebhookCA, admissionWebhookTimeout,
+		admissionWebhookFailOpen)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --admission-webhook: %v", err)
+	}
+	return webhook.Admit, nil
+}
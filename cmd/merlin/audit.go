@@ -0,0 +1,32 @@
+package main
+
+import (
+	"io"
+	"time"
+)
+
+var (
+	auditLog       string
+	auditRetention time.Duration
+)
+
+func init() {
+	f := rootCmd.PersistentFlags()
+	f.StringVar(&auditLog, "audit-log", "",
+		"file to append a JSON line to for each write made through the API, reopened on SIGUSR1 and never rotated")
+	f.DurationVar(&auditRetention, "audit-retention", 30*24*time.Hour,
+		"how long the writes made through the API are kept in the store, for meradm audit")
+}
+
+// openAuditLog opens --audit-log, or returns nil if it isn't set.
+func openAuditLog() (io.Writer, error) {
+	if auditLog == "" {
+		return nil, nil
+	}
+	f, err := newRotatingFile(auditLog, 0, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	reopenOnSignal(f)
+	return f, nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"gopkg.in/yaml.v2"
+)
+
+// sharedClient is the name of the client authenticated by --api-token.
+const sharedClient = "shared"
+
+var (
+	apiToken       string
+	apiTokenFile   string
+	anonymousReads bool
+	rbacPolicy     string
+)
+
+func init() {
+	f := rootCmd.PersistentFlags()
+	f.StringVar(&apiToken, "api-token", "",
+		"bearer token API clients must authenticate with, such as meradm --token; every node needs the same tokens")
+	f.StringVar(&apiTokenFile, "api-token-file", "",
+		"YAML file of API client names to the token each authenticates with, e.g. 'deploy: s3cret'")
+	f.BoolVar(&anonymousReads, "anonymous-reads", false,
+		"allow API requests which don't change the store without a token")
+	f.StringVar(&rbacPolicy, "rbac-policy", "",
+		"YAML file of rules allowing API clients, by token name or TLS client certificate common name, to read or "+
+			"write services")
+}
+
+// apiTokens returns the API clients set by --api-token and --api-token-file, or nil if neither is set.
+func apiTokens() (map[string]string, error) {
+	tokens := make(map[string]string)
+	if apiTokenFile != "" {
+		data, err := ioutil.ReadFile(apiTokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read --api-token-file: %v", err)
+		}
+		if err := yaml.UnmarshalStrict(data, &tokens); err != nil {
+			return nil, fmt.Errorf("invalid --api-token-file: %v", err)
+		}
+	}
+	if apiToken != "" {
+		if _, ok := tokens[sharedClient]; ok {
+			return nil, fmt.Errorf("--api-token-file can't have a client named %q with --api-token", sharedClient)
+		}
+		tokens[sharedClient] = apiToken
+	}
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+	return tokens, nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sky-uk/merlin/backup"
+	"github.com/sky-uk/merlin/store"
+	"github.com/sky-uk/merlin/types"
+	"github.com/spf13/cobra"
+)
+
+var backupCmd = &cobra.Command{
+	Use:   "backup [create|verify]",
+	Short: "Back up the services and servers in the store, for disaster recovery",
+}
+
+var backupCreateCmd = &cobra.Command{
+	Use:   "create [archive]",
+	Short: "Write an archive of the services and servers in the store",
+	Args:  cobra.ExactArgs(1),
+	RunE:  createBackup,
+}
+
+var backupVerifyCmd = &cobra.Command{
+	Use:   "verify [archive]",
+	Short: "Check the integrity, schema version and consistency of an archive, without restoring it",
+	Args:  cobra.ExactArgs(1),
+	RunE:  verifyBackup,
+}
+
+func init() {
+	rootCmd.AddCommand(backupCmd)
+	backupCmd.AddCommand(backupCreateCmd)
+	backupCmd.AddCommand(backupVerifyCmd)
+}
+
+func createBackup(_ *cobra.Command, args []string) error {
+	st, err := store.NewStore(storeBackend, strings.Split(storeEndpoints, ","), storePrefix,
+		store.Encoding(storeEncoding))
+	if err != nil {
+		return fmt.Errorf("unable to start store client: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	snapshot, err := readSnapshot(ctx, st)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(args[0])
+	if err != nil {
+		return err
+	}
+	if err := backup.Write(f, snapshot, time.Now()); err != nil {
+		f.Close()
+		return fmt.Errorf("unable to write %s: %v", args[0], err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("Backed up %d services and %d servers to %s\n", len(snapshot.Services), len(snapshot.Servers),
+		args[0])
+	return nil
+}
+
+// readSnapshot returns every service and server in the store.
+func readSnapshot(ctx context.Context, st store.Store) (*types.Snapshot, error) {
+	svcs, err := st.ListServices(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list services: %v", err)
+	}
+	snapshot := &types.Snapshot{Services: svcs}
+	for _, svc := range svcs {
+		servers, err := st.ListServers(ctx, svc.Id)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list servers of %s: %v", svc.Id, err)
+		}
+		snapshot.Servers = append(snapshot.Servers, servers...)
+	}
+	return snapshot, nil
+}
+
+func verifyBackup(_ *cobra.Command, args []string) error {
+	f, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	manifest, err := backup.Verify(f)
+	if err != nil {
+		return fmt.Errorf("%s is invalid: %v", args[0], err)
+	}
+	fmt.Printf("%s is valid: schema %d, API %s, created %s, %d services, %d servers\n", args[0],
+		manifest.SchemaVersion, manifest.APIVersion, manifest.Created.Format(time.RFC3339), manifest.Services,
+		manifest.Servers)
+	return nil
+}
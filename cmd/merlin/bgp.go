@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/sky-uk/merlin/bgp"
+)
+
+var (
+	bgpAS          uint32
+	bgpRouterID    string
+	bgpPeers       []string
+	bgpPeerAS      uint32
+	bgpNextHop     string
+	bgpNextHopIPv6 string
+	bgpHoldTime    time.Duration
+)
+
+func init() {
+	f := rootCmd.PersistentFlags()
+	f.StringSliceVar(&bgpPeers, "bgp-peers", nil,
+		"announce the VIPs of services with healthy servers to these BGP peers, as ip or ip:port")
+	f.Uint32Var(&bgpAS, "bgp-as", 0, "local AS of the BGP sessions")
+	f.Uint32Var(&bgpPeerAS, "bgp-peer-as", 0, "AS of the BGP peers, defaults to --bgp-as for iBGP")
+	f.StringVar(&bgpRouterID, "bgp-router-id", "", "IPv4 router ID identifying merlin to its BGP peers")
+	f.StringVar(&bgpNextHop, "bgp-next-hop", "",
+		"next hop of announced IPv4 VIPs, defaults to the local address of each session")
+	f.StringVar(&bgpNextHopIPv6, "bgp-next-hop-ipv6", "",
+		"next hop of announced IPv6 VIPs, defaults to the local address of IPv6 sessions")
+	f.DurationVar(&bgpHoldTime, "bgp-hold-time", 90*time.Second, "hold time proposed to the BGP peers")
+}
+
+// bgpConfig returns the config set by the bgp flags, or nil if no peers are set.
+func bgpConfig() (*bgp.Config, error) {
+	if len(bgpPeers) == 0 {
+		return nil, nil
+	}
+	c := &bgp.Config{AS: bgpAS, HoldTime: bgpHoldTime}
+	peerAS := bgpPeerAS
+	if peerAS == 0 {
+		peerAS = bgpAS
+	}
+	for _, peer := range bgpPeers {
+		c.Peers = append(c.Peers, bgp.Peer{Address: peer, AS: peerAS})
+	}
+	var err error
+	if c.RouterID, err = parseIP("--bgp-router-id", bgpRouterID); err != nil {
+		return nil, err
+	}
+	if c.NextHop, err = parseIP("--bgp-next-hop", bgpNextHop); err != nil {
+		return nil, err
+	}
+	if c.NextHopIPv6, err = parseIP("--bgp-next-hop-ipv6", bgpNextHopIPv6); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func parseIP(flag, s string) (net.IP, error) {
+	if s == "" {
+		return nil, nil
+	}
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid %s %q, must be an ip", flag, s)
+	}
+	return ip, nil
+}
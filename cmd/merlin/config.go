@@ -0,0 +1,241 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v2"
+)
+
+// envPrefix is prepended to a flag name to give its environment variable, e.g. MERLIN_STORE_ENDPOINTS.
+const envPrefix = "MERLIN_"
+
+var (
+	configFile string
+	// configSources records where each flag was set from, for /config.
+	configSources = make(map[string]string)
+)
+
+// flags which can't be set by the config file or environment
+var configExcludedFlags = map[string]bool{"config": true, "help": true, "version": true}
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "",
+		"YAML or TOML (.toml) file of flag names to values, e.g. 'store-endpoints: http://etcd:2379'")
+}
+
+// initConfig sets each flag not given on the command line from its environment variable, or otherwise from the
+// config file. Flags on the command line take precedence over the environment, which takes precedence over
+// the config file.
+func initConfig() {
+	if err := applyConfig(rootCmd.PersistentFlags()); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid config: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func applyConfig(flags *pflag.FlagSet) error {
+	settings := make(map[string]string)
+	if configFile != "" {
+		var err error
+		if settings, err = readConfigFile(configFile); err != nil {
+			return err
+		}
+		for key := range settings {
+			if flags.Lookup(key) == nil || configExcludedFlags[key] {
+				return fmt.Errorf("%s: unknown setting %q", configFile, key)
+			}
+		}
+	}
+
+	var err error
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if err != nil {
+			return
+		}
+		if flag.Changed {
+			configSources[flag.Name] = "flag"
+			return
+		}
+		if configExcludedFlags[flag.Name] {
+			configSources[flag.Name] = "default"
+			return
+		}
+		source := configFile
+		value, ok := settings[flag.Name]
+		if env := envName(flag.Name); os.Getenv(env) != "" {
+			source, value, ok = "$"+env, os.Getenv(env), true
+		}
+		if !ok {
+			configSources[flag.Name] = "default"
+			return
+		}
+		configSources[flag.Name] = source
+		if setErr := flags.Set(flag.Name, value); setErr != nil {
+			err = fmt.Errorf("%s: invalid %s: %v", source, flag.Name, setErr)
+		}
+	})
+	return err
+}
+
+// configSetting is the effective value of a flag and where it was set from: flag, default, the config file, or
+// an environment variable.
+type configSetting struct {
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// effectiveConfig returns the value of every flag, with secrets redacted.
+func effectiveConfig(flags *pflag.FlagSet) map[string]configSetting {
+	config := make(map[string]configSetting)
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if flag.Name == "help" {
+			return
+		}
+		value := redactURLPasswords(flag.Value.String())
+		if isSecret(flag.Name) && value != "" {
+			value = redacted
+		}
+		config[flag.Name] = configSetting{Value: value, Source: configSources[flag.Name]}
+	})
+	return config
+}
+
+const redacted = "<redacted>"
+
+// isSecret returns true if a flag is a credential, which shouldn't be shown.
+func isSecret(name string) bool {
+	for _, word := range []string{"password", "secret", "token", "key"} {
+		for _, part := range strings.Split(name, "-") {
+			if part == word {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// redactURLPasswords redacts the password of each URL in a comma delimited list, such as store-endpoints.
+func redactURLPasswords(value string) string {
+	parts := strings.Split(value, ",")
+	for i, part := range parts {
+		u, err := url.Parse(part)
+		if err != nil || u.User == nil {
+			continue
+		}
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), "xxxxx")
+			parts[i] = u.String()
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+func envName(flag string) string {
+	return envPrefix + strings.ToUpper(strings.Replace(flag, "-", "_", -1))
+}
+
+// readConfigFile returns the settings in a YAML or TOML file. Lists are joined with commas, so they can set
+// flags such as store-endpoints.
+func readConfigFile(file string) (map[string]string, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	if filepath.Ext(file) == ".toml" {
+		return parseTOML(file, data)
+	}
+
+	var raw map[string]interface{}
+	if err := yaml.UnmarshalStrict(data, &raw); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %v", file, err)
+	}
+	settings := make(map[string]string)
+	for key, value := range raw {
+		if list, ok := value.([]interface{}); ok {
+			var values []string
+			for _, v := range list {
+				values = append(values, fmt.Sprint(v))
+			}
+			settings[key] = strings.Join(values, ",")
+		} else {
+			settings[key] = fmt.Sprint(value)
+		}
+	}
+	return settings, nil
+}
+
+// parseTOML parses the top level key/value pairs of a TOML file, which is all that flags need. Tables aren't
+// supported, and arrays may only contain strings or numbers on a single line.
+func parseTOML(file string, data []byte) (map[string]string, error) {
+	settings := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			return nil, fmt.Errorf("%s:%d: tables aren't supported", file, n)
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected key = value", file, n)
+		}
+		key := strings.Trim(strings.TrimSpace(parts[0]), `"`)
+		value, err := parseTOMLValue(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", file, n, err)
+		}
+		settings[key] = value
+	}
+	return settings, scanner.Err()
+}
+
+func parseTOMLValue(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, "["):
+		end := strings.LastIndex(value, "]")
+		if end < 0 {
+			return "", fmt.Errorf("unterminated array %s", value)
+		}
+		var values []string
+		for _, v := range strings.Split(value[1:end], ",") {
+			if v = strings.TrimSpace(v); v == "" {
+				continue
+			}
+			v, err := parseTOMLValue(v)
+			if err != nil {
+				return "", err
+			}
+			values = append(values, v)
+		}
+		return strings.Join(values, ","), nil
+	case strings.HasPrefix(value, `"`):
+		end := strings.Index(value[1:], `"`)
+		if end < 0 {
+			return "", fmt.Errorf("unterminated string %s", value)
+		}
+		return strconv.Unquote(value[:end+2])
+	case strings.HasPrefix(value, "'"):
+		end := strings.Index(value[1:], "'")
+		if end < 0 {
+			return "", fmt.Errorf("unterminated string %s", value)
+		}
+		return value[1 : end+1], nil
+	default:
+		// bare values, such as numbers and booleans, end at a comment
+		if i := strings.Index(value, "#"); i >= 0 {
+			value = strings.TrimSpace(value[:i])
+		}
+		return value, nil
+	}
+}
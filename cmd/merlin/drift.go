@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+	"github.com/sky-uk/merlin/reconciler"
+)
+
+// webhookTimeout is how long to wait for the drift alert webhook to respond.
+const webhookTimeout = 10 * time.Second
+
+var (
+	driftAlertSyncs   int
+	driftAlertWebhook string
+)
+
+var driftAlerts = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "merlin_persistent_drift_alerts_total",
+	Help: "Alerts of services which drifted from the store in consecutive reconciles.",
+}, []string{"service_id"})
+
+var manualChanges = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "merlin_manual_changes_total",
+	Help: "Changes made to IPVS by something other than merlin, such as ipvsadm, which merlin reverted.",
+}, []string{"service_id", "action"})
+
+func init() {
+	f := rootCmd.PersistentFlags()
+	f.IntVar(&driftAlertSyncs, "drift-alert-syncs", 3,
+		"alert when a service drifts from the store in this many consecutive reconciles, 0 to disable")
+	f.StringVar(&driftAlertWebhook, "drift-alert-webhook", "",
+		"URL to POST drift alerts and manual changes to IPVS to as JSON, in addition to logging them and counting "+
+			"them in metrics")
+	prometheus.MustRegister(driftAlerts, manualChanges)
+}
+
+// Types of driftAlertPayload.
+const (
+	payloadPersistentDrift = "persistent-drift"
+	payloadManualChange    = "manual-change"
+)
+
+// driftAlertPayload is the JSON posted to the drift alert webhook.
+type driftAlertPayload struct {
+	Type      string   `json:"type"`
+	Node      string   `json:"node"`
+	ServiceID string   `json:"serviceID"`
+	Service   string   `json:"service"`
+	Syncs     int      `json:"syncs"`
+	Reasons   []string `json:"reasons"`
+}
+
+// alertDrift logs and counts a service which isn't converging, and posts it to the webhook if set.
+func alertDrift(alert reconciler.DriftAlert) {
+	log.Warnf("Service %s (%s) has drifted from the store in %d consecutive reconciles: %s", alert.ServiceID,
+		alert.Service.PrettyString(), alert.Syncs, strings.Join(alert.Reasons, "; "))
+	driftAlerts.WithLabelValues(alert.ServiceID).Inc()
+	if driftAlertWebhook == "" {
+		return
+	}
+
+	payload := driftAlertPayload{
+		Type:      payloadPersistentDrift,
+		Node:      nodeName,
+		ServiceID: alert.ServiceID,
+		Service:   alert.Service.PrettyString(),
+		Syncs:     alert.Syncs,
+		Reasons:   alert.Reasons,
+	}
+	// posted in the background, so a slow webhook doesn't hold up reconciling
+	go func() {
+		if err := postJSON(driftAlertWebhook, payload); err != nil {
+			log.Warnf("Unable to post drift alert of %s: %v", alert.ServiceID, err)
+		}
+	}()
+}
+
+// alertManualChange counts a change made to IPVS by something other than merlin, which the reconciler has already
+// logged, and posts it to the drift alert webhook if set.
+func alertManualChange(change reconciler.ManualChange) {
+	manualChanges.WithLabelValues(change.ServiceID, strings.ToLower(change.Action.String())).Inc()
+	if driftAlertWebhook == "" {
+		return
+	}
+
+	payload := driftAlertPayload{
+		Type:      payloadManualChange,
+		Node:      nodeName,
+		ServiceID: change.ServiceID,
+		Service:   change.Service.PrettyString(),
+		Reasons:   []string{change.String()},
+	}
+	go func() {
+		if err := postJSON(driftAlertWebhook, payload); err != nil {
+			log.Warnf("Unable to post manual change of %s: %v", change.Service.PrettyString(), err)
+		}
+	}()
+}
+
+func postJSON(url string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: webhookTimeout}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return nil
+}
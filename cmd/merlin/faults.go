@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/sky-uk/merlin/faults"
+)
+
+var (
+	faultInjection bool
+	faultConfig    faults.Config
+)
+
+func init() {
+	f := rootCmd.PersistentFlags()
+	f.BoolVar(&faultInjection, "fault-injection", false,
+		"inject the faults set by the --fault-* flags, for testing alerting and recovery; never enable in production")
+	f.Float64Var(&faultConfig.StoreErrorRate, "fault-store-error-rate", 0,
+		"fraction of store requests to fail with a timeout, from 0 to 1")
+	f.DurationVar(&faultConfig.StoreDelay, "fault-store-delay", 0, "delay to add to every store request")
+	f.DurationVar(&faultConfig.WatchDelay, "fault-watch-delay", 0, "delay to add to notifications of store changes")
+	f.Float64Var(&faultConfig.IPVSErrorRate, "fault-ipvs-error-rate", 0,
+		"fraction of ipvs requests to fail with a netlink error, from 0 to 1")
+}
+
+// validateFaults checks the --fault-* flags, which are only allowed with --fault-injection.
+func validateFaults() error {
+	if !faultInjection {
+		if faultConfig != (faults.Config{}) {
+			return fmt.Errorf("--fault-* flags require --fault-injection")
+		}
+		return nil
+	}
+	for name, rate := range map[string]float64{
+		"--fault-store-error-rate": faultConfig.StoreErrorRate,
+		"--fault-ipvs-error-rate":  faultConfig.IPVSErrorRate,
+	} {
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("%s must be between 0 and 1", name)
+		}
+	}
+	log.Warnf("Injecting faults: store error rate %v, store delay %v, watch delay %v, ipvs error rate %v",
+		faultConfig.StoreErrorRate, faultConfig.StoreDelay, faultConfig.WatchDelay, faultConfig.IPVSErrorRate)
+	return nil
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/sky-uk/merlin/daemon"
+	"github.com/sky-uk/merlin/gateway"
+)
+
+var restGateway bool
+
+func init() {
+	rootCmd.PersistentFlags().BoolVar(&restGateway, "rest-gateway", false,
+		"serve the service and server API methods as REST with JSON bodies under "+gateway.Prefix+
+			" of the health port")
+}
+
+// validateGateway checks --rest-gateway, which can't bypass client certificates as its clients can't present them.
+func validateGateway() error {
+	if !restGateway {
+		return nil
+	}
+	if mode == daemon.ModeAgent {
+		return errors.New("--rest-gateway can't be used with --mode=agent, as agents don't serve the API")
+	}
+	if tlsClientCA != "" && apiToken == "" && apiTokenFile == "" {
+		return errors.New("--rest-gateway with --tls-client-ca requires --api-token or --api-token-file, " +
+			"as its clients can't present certificates")
+	}
+	return nil
+}
+
+// addGateway serves the REST gateway of d's API on the health port, if --rest-gateway is set.
+func addGateway(d *daemon.Daemon) {
+	if restGateway {
+		http.Handle(gateway.Prefix, gateway.New(d.Invoke))
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sky-uk/merlin/ipam"
+	"github.com/sky-uk/merlin/server"
+)
+
+var (
+	ipamName     string
+	ipamURL      string
+	ipamToken    string
+	ipamUsername string
+	ipamPassword string
+	ipamCA       string
+	ipamTimeout  time.Duration
+)
+
+func init() {
+	f := rootCmd.PersistentFlags()
+	f.StringVar(&ipamName, "ipam", "",
+		"external registry to allocate the IPs of services in pools from, netbox or infoblox, instead of merlin")
+	f.StringVar(&ipamURL, "ipam-url", "", "http(s) url of the --ipam registry")
+	f.StringVar(&ipamToken, "ipam-token", "", "API token of --ipam=netbox")
+	f.StringVar(&ipamUsername, "ipam-username", "", "username of --ipam=infoblox")
+	f.StringVar(&ipamPassword, "ipam-password", "", "password of --ipam=infoblox")
+	f.StringVar(&ipamCA, "ipam-ca", "", "CA certificate to verify the --ipam registry with, instead of the system's")
+	f.DurationVar(&ipamTimeout, "ipam-timeout", 10*time.Second, "how long to wait for each --ipam request")
+}
+
+// ipamDriver returns the IPAM set by the --ipam flags, or nil if merlin allocates IPs itself.
+func ipamDriver() (server.IPAM, error) {
+	var driver server.IPAM
+	var err error
+	switch ipamName {
+	case "":
+		return nil, nil
+	case "netbox":
+		driver, err = ipam.NewNetBox(ipamURL, ipamToken, ipamCA, ipamTimeout)
+	case "infoblox":
+		driver, err = ipam.NewInfoblox(ipamURL, ipamUsername, ipamPassword, ipamCA, ipamTimeout)
+	default:
+		return nil, fmt.Errorf("--ipam must be netbox or infoblox, not %q", ipamName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid --ipam-url: %v", err)
+	}
+	return driver, nil
+}
@@ -0,0 +1,16 @@
+package main
+
+import "github.com/sky-uk/merlin/validation"
+
+var limits validation.Limits
+
+func init() {
+	f := rootCmd.PersistentFlags()
+	f.IntVar(&limits.MaxIDLength, "max-id-length", 253, "longest service ID which can be written, 0 is unlimited")
+	f.IntVar(&limits.MaxLabels, "max-labels", 64, "most labels a service or server can have, 0 is unlimited")
+	f.IntVar(&limits.MaxLabelSize, "max-label-size", 253, "longest label key or value, 0 is unlimited")
+	f.IntVar(&limits.MaxObjectSize, "max-object-size", 64<<10,
+		"largest service or server in bytes, encoded as protobuf, which can be written; 0 is unlimited")
+	f.IntVar(&limits.MaxServersPerService, "max-servers-per-service", 10000,
+		"most servers a service can have, 0 is unlimited")
+}
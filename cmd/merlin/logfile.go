@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	logFile       string
+	logMaxSize    int
+	logMaxAge     time.Duration
+	logMaxBackups int
+)
+
+func init() {
+	f := rootCmd.PersistentFlags()
+	f.StringVar(&logFile, "log-file", "", "write logs to this file rather than stderr, reopened on SIGUSR1")
+	f.IntVar(&logMaxSize, "log-max-size", 100, "rotate the log file when it reaches this many megabytes, 0 to disable")
+	f.DurationVar(&logMaxAge, "log-max-age", 24*time.Hour, "rotate the log file when it's this old, 0 to disable")
+	f.IntVar(&logMaxBackups, "log-max-backups", 5, "number of rotated log files to keep")
+}
+
+// rotatingFile is a log file which is rotated when it exceeds a size or age. Rotated files are renamed with
+// the time of rotation, e.g. merlin.log.20191231-235959.000.
+type rotatingFile struct {
+	path       string
+	maxSize    int64
+	maxAge     time.Duration
+	maxBackups int
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	opened time.Time
+}
+
+func newRotatingFile(path string, maxSize int64, maxAge time.Duration, maxBackups int) (*rotatingFile, error) {
+	f := &rotatingFile{path: path, maxSize: maxSize, maxAge: maxAge, maxBackups: maxBackups}
+	return f, f.open()
+}
+
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("unable to open log file: %v", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("unable to open log file: %v", err)
+	}
+	f.file = file
+	f.size = info.Size()
+	// the age of an existing file is unknown, so it's counted from when it was opened
+	f.opened = time.Now()
+	return nil
+}
+
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	tooBig := f.maxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.maxSize
+	tooOld := f.maxAge > 0 && time.Since(f.opened) > f.maxAge
+	if tooBig || tooOld {
+		if err := f.rotate(); err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to rotate %s: %v\n", f.path, err)
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// rotate renames the current file, opens a new one, and removes the oldest rotated files.
+func (f *rotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	// reopen even if the rename fails, so logging continues
+	renameErr := os.Rename(f.path, f.path+"."+time.Now().Format("20060102-150405.000"))
+	if err := f.open(); err != nil {
+		return err
+	}
+	if renameErr != nil {
+		return renameErr
+	}
+
+	backups, err := filepath.Glob(f.path + ".*")
+	if err != nil {
+		return err
+	}
+	// timestamps sort in the order they were rotated
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+	for i, backup := range backups {
+		if i >= f.maxBackups {
+			if err := os.Remove(backup); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Reopen closes and reopens the log file, for when it has been moved by an external tool such as logrotate.
+func (f *rotatingFile) Reopen() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.file.Close()
+	return f.open()
+}
+
+// initLogFile sends logs to --log-file, if set.
+func initLogFile() error {
+	if logFile == "" {
+		return nil
+	}
+	f, err := newRotatingFile(logFile, int64(logMaxSize)*1024*1024, logMaxAge, logMaxBackups)
+	if err != nil {
+		return err
+	}
+	log.SetOutput(f)
+	reopenOnSignal(f)
+	return nil
+}
+
+// reopenOnSignal reopens the file on every SIGUSR1.
+func reopenOnSignal(f *rotatingFile) {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGUSR1)
+	go func() {
+		for range c {
+			if err := f.Reopen(); err != nil {
+				fmt.Fprintf(os.Stderr, "Unable to reopen %s: %v\n", f.path, err)
+				continue
+			}
+			log.Infof("Reopened %s", f.path)
+		}
+	}()
+}
@@ -1,11 +1,10 @@
 package main
 
 import (
-	_ "net/http/pprof"
-
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -16,18 +15,20 @@ import (
 
 	"context"
 
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
 	"github.com/onrik/logrus/filename"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	log "github.com/sirupsen/logrus"
+	"github.com/sky-uk/merlin/daemon"
 	"github.com/sky-uk/merlin/ipvs"
+	"github.com/sky-uk/merlin/rbac"
 	"github.com/sky-uk/merlin/reconciler"
-	"github.com/sky-uk/merlin/server"
 	"github.com/sky-uk/merlin/store"
-	"github.com/sky-uk/merlin/types"
+	"github.com/sky-uk/merlin/validation"
+	"github.com/sky-uk/merlin/vip"
 	"github.com/spf13/cobra"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
+	"github.com/spf13/pflag"
 )
 
 var rootCmd = &cobra.Command{
@@ -43,8 +44,34 @@ var (
 	storeBackend        string
 	storeEndpoints      string
 	storePrefix         string
+	storeEncoding       string
 	reconcileSyncPeriod time.Duration
 	reconcile           bool
+	vipInterface        string
+	nodeName            string
+	nodeLabels          map[string]string
+	heartbeatPeriod     time.Duration
+	shutdownTimeout     time.Duration
+	healthMaxSyncAge    time.Duration
+	mode                string
+	storeWaitTimeout    time.Duration
+	storeWriteRate      float64
+	storeWriteBurst     int
+	storeBatchSize      int
+	slowRequests        time.Duration
+	singlePort          bool
+	electLeader         bool
+	advertiseAddress    string
+	nodeToken           string
+	ipvsMetrics         bool
+	ipvsMetricLabels    []string
+	ipvsMetricIDBuckets int
+	selfTestEnabled     bool
+	selfTestStrict      bool
+	fakeIPVS            bool
+	validationRules     string
+	orphanPolicy        string
+	orphanGracePeriod   time.Duration
 	// Version of merlin.
 	Version string
 	// BuildTime of merlin.
@@ -52,17 +79,91 @@ var (
 )
 
 func init() {
-	cobra.OnInitialize(initLogs)
+	cobra.OnInitialize(initConfig, initLogs)
 	rootCmd.Version = fmt.Sprintf("%s (%s)", Version, BuildTime)
 	f := rootCmd.PersistentFlags()
 	f.BoolVar(&debugLogs, "debug", false, "enable debug logs")
 	f.IntVar(&port, "port", 4282, "server port")
-	f.IntVar(&healthPort, "health-port", 4283, "/health, /alive, /metrics, and /debug endpoints")
+	f.IntVar(&healthPort, "health-port", 4283, "/health, /alive, /ready, /state, /config, /metrics, and /debug endpoints")
+	f.BoolVar(&singlePort, "single-port", false,
+		"serve the --health-port endpoints on --port alongside the API, so merlin needs only one port; not in agent mode")
 	f.StringVar(&storeBackend, "store-backend", "etcd2", "controls which storage backend to use; supports etcd2 or etcd3")
 	f.StringVar(&storeEndpoints, "store-endpoints", "", "comma delimited list of etcd2 / etcd3 endpoints")
 	f.StringVar(&storePrefix, "store-prefix", "/merlin", "prefix to store state")
+	f.StringVar(&storeEncoding, "store-encoding", string(store.EncodingProto),
+		"encoding to write values in: 'proto', 'binary' for protobuf with a version header, or 'json' for tooling; "+
+			"any encoding is read")
+	f.DurationVar(&storeWaitTimeout, "store-wait-timeout", time.Minute,
+		"how long to retry connecting to the store at startup before exiting, 0 to exit on the first failure")
+	f.Float64Var(&storeWriteRate, "store-write-rate", 0,
+		"limit of write requests to the store per second, so bulk changes don't destabilize etcd; 0 is unlimited")
+	f.IntVar(&storeWriteBurst, "store-write-burst", 0,
+		"how many writes can exceed --store-write-rate in a burst, defaults to the rate")
+	f.IntVar(&storeBatchSize, "store-batch-size", 100,
+		"how many changes of a snapshot to apply in each write, within etcd3's limit of operations per transaction")
+	f.DurationVar(&slowRequests, "slow-request-threshold", time.Second,
+		"log API requests and store operations which take at least this long, with their store latencies; 0 disables")
 	f.DurationVar(&reconcileSyncPeriod, "reconcile-sync-period", time.Minute, "how often to periodically sync ipvs state")
 	f.BoolVar(&reconcile, "reconcile", true, "if enabled, merlin will reconcile local ipvs with store state")
+	f.StringVar(&vipInterface, "vip-interface", "",
+		"configure the VIPs of services on this interface, creating it as a dummy interface if it doesn't exist; "+
+			"other host addresses on it are deleted")
+	hostname, _ := os.Hostname()
+	f.StringVar(&nodeName, "node-name", hostname, "name this node registers in the store with, must be unique")
+	f.StringToStringVar(&nodeLabels, "node-labels", nil,
+		"labels of this node as key=value, only services with a node selector matching them are reconciled")
+	f.DurationVar(&heartbeatPeriod, "heartbeat-period", 10*time.Second,
+		"how often to register this node in the store, it expires after 3 missed heartbeats")
+	f.DurationVar(&shutdownTimeout, "shutdown-timeout", 30*time.Second,
+		"how long to wait for in-flight requests and reconciles to finish when shutting down")
+	f.DurationVar(&healthMaxSyncAge, "health-max-sync-age", 0,
+		"fail /health if ipvs hasn't been reconciled for this long, defaults to 3 reconcile sync periods")
+	f.StringVar(&mode, "mode", daemon.ModeAll,
+		"'all' to serve the API and reconcile, or 'agent' to only reconcile, leaving the API to other nodes")
+	f.BoolVar(&electLeader, "leader-election", false,
+		"elect a leader among merlin nodes, which performs all writes; other nodes serve reads and forward writes to it")
+	f.StringVar(&advertiseAddress, "advertise-address", "",
+		"host:port other nodes forward writes to when this node is the leader, defaults to node-name:port")
+	f.StringVar(&nodeToken, "node-token", "",
+		"secret shared by every node, sent with forwarded writes so the leader trusts which node forwarded them")
+	f.BoolVar(&ipvsMetrics, "ipvs-metrics", true,
+		"export the traffic counters of every ipvs service and real server as metrics")
+	f.StringSliceVar(&ipvsMetricLabels, "ipvs-metrics-labels",
+		[]string{daemon.LabelServiceID, daemon.LabelProtocol, daemon.LabelAddress, daemon.LabelServer},
+		"labels of the ipvs metrics; series differing only by dropped labels are summed, e.g. drop server on large estates")
+	f.IntVar(&ipvsMetricIDBuckets, "ipvs-metrics-id-buckets", 0,
+		"hash service IDs into this many buckets in the ipvs metrics to bound their cardinality, 0 to export the IDs")
+	f.BoolVar(&selfTestEnabled, "self-test", true,
+		"check the kernel, capabilities, store and clock at startup, logging a report")
+	f.BoolVar(&selfTestStrict, "self-test-strict", false, "refuse to start if a self-test check fails")
+	f.StringVar(&validationRules, "validation-rules", "",
+		"yaml file of site rules services and servers must follow, such as allowed VIP CIDRs and required labels")
+	f.StringVar(&orphanPolicy, "orphan-policy", daemon.OrphanReport,
+		"for servers whose service doesn't exist: 'report' them, 'delete' them after the grace period, "+
+			"or 'block' deleting services with servers")
+	f.DurationVar(&orphanGracePeriod, "orphan-grace-period", 10*time.Minute,
+		"how long servers are orphaned before --orphan-policy=delete deletes them")
+	f.BoolVar(&fakeIPVS, "fake-ipvs", false,
+		"reconcile an in-memory ipvs and vip interface instead of the kernel's, for tests")
+	f.MarkHidden("fake-ipvs")
+}
+
+// validateMode checks --mode, and that it's compatible with the other flags.
+func validateMode() error {
+	switch mode {
+	case daemon.ModeAll:
+		return nil
+	case daemon.ModeAgent:
+		if !reconcile {
+			return errors.New("--mode=agent requires --reconcile, as agents only reconcile")
+		}
+		if electLeader {
+			return errors.New("--mode=agent can't be used with --leader-election, as agents don't serve writes")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown --mode %q, must be %s or %s", mode, daemon.ModeAll, daemon.ModeAgent)
+	}
 }
 
 func main() {
@@ -72,6 +173,10 @@ func main() {
 }
 
 func initLogs() {
+	if err := initLogFile(); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
 	if debugLogs {
 		log.SetLevel(log.DebugLevel)
 		log.Debug("Debug logs on")
@@ -81,115 +186,167 @@ func initLogs() {
 	log.AddHook(filenameHook)
 }
 
-func startMerlin(_ *cobra.Command, _ []string) {
-	srv := &srv{}
-	srv.Start()
-	addSignalHandler(srv)
-	addHealthPort(srv)
-	select {}
-}
-
-type srv struct {
-	grpcServer      *grpc.Server
-	ipvs            ipvs.IPVS
-	reconciler      reconciler.Reconciler
-	subscribeStopCh chan struct{}
-}
-
-func (s *srv) Health() error {
-	return nil
-}
-
-func (s *srv) Start() {
-	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
-	if err != nil {
-		log.Fatalf("Failed to listen: %v", err)
+func startMerlin(cmd *cobra.Command, _ []string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	addSignalHandler(cancel)
+	if err := validateMode(); err != nil {
+		log.Fatal(err)
+	}
+	if err := validateFaults(); err != nil {
+		log.Fatal(err)
+	}
+	if err := validateGateway(); err != nil {
+		log.Fatal(err)
 	}
-	log.Infof("Starting merlin")
 
-	etcdStore, err := store.NewStore(storeBackend, strings.Split(storeEndpoints, ","), storePrefix)
+	opts := options()
+	quotas, err := parseQuotas()
 	if err != nil {
-		log.Fatalf("Unable to start store client: %v", err)
+		log.Fatal(err)
 	}
-
-	if reconcile {
-		ipvs, err := ipvs.New()
-		if err != nil {
-			log.Fatalf("Unable to init IPVS: %v", err)
+	opts.Quotas = quotas
+	opts.Limits = limits
+	if validationRules != "" {
+		if opts.Rules, err = validation.LoadRules(validationRules); err != nil {
+			log.Fatalf("Unable to load --validation-rules: %v", err)
 		}
-
-		s.ipvs = ipvs
-		s.reconciler = reconciler.New(reconcileSyncPeriod, etcdStore, ipvs)
-	} else {
-		s.reconciler = reconciler.NewStub()
 	}
-
-	if err := s.reconciler.Start(); err != nil {
-		log.Fatalf("Unable to start reconciler: %v", err)
+	if opts.Admit, err = admitter(); err != nil {
+		log.Fatal(err)
 	}
-	s.reconciler.Sync()
-
-	s.subscribeStopCh = make(chan struct{})
-	etcdStore.Subscribe(func() {
-		log.Info("Store updated, starting sync")
-		s.reconciler.Sync()
-	}, s.subscribeStopCh)
-
-	server := server.New(etcdStore)
-
-	s.grpcServer = grpc.NewServer(
-		grpc.UnaryInterceptor(logRequests),
-	)
-	types.RegisterMerlinServer(s.grpcServer, server)
-	go func() {
-		if err := s.grpcServer.Serve(lis); err != nil {
-			log.Error(err)
+	if opts.IPAM, err = ipamDriver(); err != nil {
+		log.Fatal(err)
+	}
+	if opts.SyncDaemonRole, err = parseSyncDaemonRole(); err != nil {
+		log.Fatal(err)
+	}
+	if opts.BGP, err = bgpConfig(); err != nil {
+		log.Fatal(err)
+	}
+	if opts.APITokens, err = apiTokens(); err != nil {
+		log.Fatal(err)
+	}
+	opts.AnonymousReads = anonymousReads
+	opts.AuditRetention = auditRetention
+	if opts.AuditLog, err = openAuditLog(); err != nil {
+		log.Fatal(err)
+	}
+	if rbacPolicy != "" {
+		if opts.Policy, err = rbac.LoadPolicy(rbacPolicy); err != nil {
+			log.Fatalf("Unable to load --rbac-policy: %v", err)
 		}
-	}()
-}
+	}
+
+	d := daemon.New(opts)
+	if err := d.Start(); err != nil {
+		log.Fatal(err)
+	}
+	addHealthPort(d, cmd.PersistentFlags())
+	if err := startStatsd(ctx.Done()); err != nil {
+		log.Fatal(err)
+	}
 
-func (s *srv) Stop() error {
-	close(s.subscribeStopCh)
-	s.reconciler.Stop()
-	if s.ipvs != nil {
-		s.ipvs.Close()
+	<-ctx.Done()
+	if err := d.Stop(shutdownTimeout); err != nil {
+		log.Errorf("Error while stopping: %v", err)
+		os.Exit(-1)
 	}
-	s.grpcServer.GracefulStop()
-	log.Infof("Stopped merlin")
-	return nil
 }
 
-func logRequests(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-	resp, err := handler(ctx, req)
-	// catch any internal errors and wrap in the correct status code
-	if _, ok := status.FromError(err); !ok {
-		log.Error(err)
-		err = status.Errorf(codes.Internal, "%v", err)
+// options returns the daemon options set by the flags.
+func options() daemon.Options {
+	opts := daemon.Options{
+		Port:                port,
+		Mode:                mode,
+		TLSCertFile:         tlsCert,
+		TLSKeyFile:          tlsKey,
+		TLSCAFile:           tlsCA,
+		TLSClientCAFile:     tlsClientCA,
+		TLSReloadPeriod:     tlsReloadPeriod,
+		TLSRenewCommand:     tlsRenewCommand,
+		TLSRenewBefore:      tlsRenewBefore,
+		StoreBackend:        storeBackend,
+		StoreEndpoints:      strings.Split(storeEndpoints, ","),
+		StorePrefix:         storePrefix,
+		StoreEncoding:       store.Encoding(storeEncoding),
+		StoreWaitTimeout:    storeWaitTimeout,
+		StoreWriteRate:      storeWriteRate,
+		StoreWriteBurst:     storeWriteBurst,
+		StoreBatchSize:      storeBatchSize,
+		Reconcile:           reconcile,
+		ReconcileSyncPeriod: reconcileSyncPeriod,
+		HealthMaxSyncAge:    healthMaxSyncAge,
+		NodeName:            nodeName,
+		NodeLabels:          nodeLabels,
+		HeartbeatPeriod:     heartbeatPeriod,
+		Version:             Version,
+		LeaderElection:      electLeader,
+		AdvertiseAddress:    advertiseAddress,
+		NodeToken:           nodeToken,
+		DriftAlertSyncs:     driftAlertSyncs,
+		DriftAlert:          alertDrift,
+		ManualChangeAlert:   alertManualChange,
+		IPVSMetrics:         ipvsMetrics,
+		IPVSMetricLabels:    ipvsMetricLabels,
+		IPVSMetricIDBuckets: ipvsMetricIDBuckets,
+		SelfTest:            selfTestEnabled,
+		SelfTestStrict:      selfTestStrict,
+		OrphanPolicy:        orphanPolicy,
+		OrphanGracePeriod:   orphanGracePeriod,
+		SyncDaemonInterface: syncDaemonInterface,
+		SyncDaemonID:        syncDaemonID,
+		VIPInterface:        vipInterface,
+		ActiveElection:      activeElection,
+		PassiveReconcile:    passiveReconcile,
+	}
+	if faultInjection {
+		opts.Faults = &faultConfig
+	}
+	opts.SlowRequestThreshold = slowRequests
+	if sharesPort() {
+		opts.HTTPHandler = http.DefaultServeMux
+	}
+	if fakeIPVS {
+		opts.IPVS = ipvs.NewMemory()
+		opts.VIPs = vip.NewMemory()
 	}
-	return resp, err
+	return opts
 }
 
-func addSignalHandler(s *srv) {
+// addSignalHandler cancels the context on the first SIGINT or SIGTERM, and exits immediately on the second.
+func addSignalHandler(cancel context.CancelFunc) {
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, syscall.SIGINT, syscall.SIGTERM)
 
 	go func() {
-		for sig := range c {
-			log.Infof("Received %v signal, shutting down...", sig)
-			err := s.Stop()
-			if err != nil {
-				log.Errorf("Error while stopping: %v", err)
-				os.Exit(-1)
-			}
-			os.Exit(0)
-		}
+		sig := <-c
+		log.Infof("Received %v signal, shutting down...", sig)
+		cancel()
+		sig = <-c
+		log.Warnf("Received %v signal while shutting down, exiting immediately", sig)
+		os.Exit(-1)
 	}()
 }
 
-func addHealthPort(s *srv) {
-	http.HandleFunc("/health", healthHandler(s))
-	http.Handle("/metrics", promhttp.Handler())
+// sharesPort returns true if the health port's endpoints are served on the API port.
+func sharesPort() bool {
+	return singlePort && mode != daemon.ModeAgent
+}
+
+func addHealthPort(d *daemon.Daemon, flags *pflag.FlagSet) {
+	http.HandleFunc("/health", checkHandler(d.Health))
+	http.HandleFunc("/ready", checkHandler(d.Ready))
+	http.HandleFunc("/state", stateHandler(d.Reconciler()))
+	http.HandleFunc("/config", configHandler(flags))
+	addProfileHandlers(http.DefaultServeMux)
+	if servePrometheus {
+		http.Handle("/metrics", promhttp.Handler())
+	}
 	http.HandleFunc("/alive", okHandler)
+	addGateway(d)
+	if sharesPort() {
+		return
+	}
 
 	go func() {
 		if err := http.ListenAndServe(":"+strconv.Itoa(healthPort), nil); err != nil {
@@ -198,9 +355,10 @@ func addHealthPort(s *srv) {
 	}()
 }
 
-func healthHandler(s *srv) func(w http.ResponseWriter, r *http.Request) {
+// checkHandler returns 200 if check succeeds, or 500 with its error.
+func checkHandler(check func() error) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if err := s.Health(); err != nil {
+		if err := check(); err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			io.WriteString(w, fmt.Sprintf("%v\n", err))
 			return
@@ -211,6 +369,76 @@ func healthHandler(s *srv) func(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// stateHandler returns the desired state from the last reconcile, the actual state of IPVS, and the changes the
+// next reconcile would make, as JSON.
+func stateHandler(rec reconciler.Reconciler) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := stateJSON(rec)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			io.WriteString(w, fmt.Sprintf("%v\n", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(body)
+	}
+}
+
+func stateJSON(rec reconciler.Reconciler) ([]byte, error) {
+	dump, err := rec.Dump()
+	if err != nil {
+		return nil, err
+	}
+
+	// protos are marshalled with jsonpb, so enums and wrappers are readable
+	var m jsonpb.Marshaler
+	state := struct {
+		LastSync time.Time         `json:"lastSync"`
+		Desired  json.RawMessage   `json:"desired"`
+		Actual   json.RawMessage   `json:"actual"`
+		Diff     []json.RawMessage `json:"diff"`
+	}{LastSync: rec.State().LastSync, Diff: []json.RawMessage{}}
+	if state.Desired, err = marshalJSON(&m, dump.Desired); err != nil {
+		return nil, err
+	}
+	if state.Actual, err = marshalJSON(&m, dump.Actual); err != nil {
+		return nil, err
+	}
+	for _, change := range dump.Diff {
+		c, err := marshalJSON(&m, change)
+		if err != nil {
+			return nil, err
+		}
+		state.Diff = append(state.Diff, c)
+	}
+
+	b, err := json.MarshalIndent(state, "", "  ")
+	return append(b, '\n'), err
+}
+
+// configHandler returns the value of every flag and where it was set from, as JSON.
+func configHandler(flags *pflag.FlagSet) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		b, err := json.MarshalIndent(effectiveConfig(flags), "", "  ")
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			io.WriteString(w, fmt.Sprintf("%v\n", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(append(b, '\n'))
+	}
+}
+
+func marshalJSON(m *jsonpb.Marshaler, pb proto.Message) (json.RawMessage, error) {
+	s, err := m.MarshalToString(pb)
+	return json.RawMessage(s), err
+}
+
 func okHandler(w http.ResponseWriter, _ *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	io.WriteString(w, "ok\n")
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sky-uk/merlin/store"
+	"github.com/spf13/cobra"
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate-encoding",
+	Short: "Rewrite the services and servers in the store in --store-encoding",
+	Long: "Rewrite the services and servers in the store in --store-encoding. Merlin reads values in any encoding, " +
+		"so change --store-encoding on every node first, then migrate the values already in the store.",
+	Args: cobra.NoArgs,
+	RunE: migrateEncoding,
+}
+
+func init() {
+	rootCmd.AddCommand(migrateCmd)
+}
+
+func migrateEncoding(_ *cobra.Command, _ []string) error {
+	encoding, err := store.ParseEncoding(storeEncoding)
+	if err != nil {
+		return err
+	}
+	st, err := store.NewStore(storeBackend, strings.Split(storeEndpoints, ","), storePrefix, encoding)
+	if err != nil {
+		return fmt.Errorf("unable to start store client: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	n, err := store.Migrate(ctx, st)
+	if err != nil {
+		return fmt.Errorf("unable to migrate after rewriting %d services and servers: %v", n, err)
+	}
+	fmt.Printf("Rewrote %d services and servers as %s\n", n, encoding)
+	return nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	rpprof "runtime/pprof"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const maxProfileDuration = 5 * time.Minute
+
+var (
+	adminToken  string
+	enablePprof bool
+)
+
+func init() {
+	f := rootCmd.PersistentFlags()
+	f.StringVar(&adminToken, "admin-token", "",
+		"bearer token required by /debug/profile, which is disabled if unset")
+	f.BoolVar(&enablePprof, "pprof", true,
+		"serve the unauthenticated /debug/pprof endpoints, disable to only allow /debug/profile")
+}
+
+// addProfileHandlers registers /debug/profile, and /debug/pprof if enabled.
+func addProfileHandlers(mux *http.ServeMux) {
+	mux.HandleFunc("/debug/profile", profileHandler)
+	if enablePprof {
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	}
+}
+
+// profileHandler captures a profile, requiring the admin token. The type parameter is one of:
+//
+//	cpu        CPU profile for the given seconds, 30 by default
+//	heap       heap profile after a garbage collection
+//	goroutine  stack traces of all goroutines, as text
+func profileHandler(w http.ResponseWriter, r *http.Request) {
+	if adminToken == "" {
+		http.Error(w, "profiling is disabled, set --admin-token to enable it", http.StatusNotFound)
+		return
+	}
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) != 1 {
+		http.Error(w, "invalid or missing bearer token", http.StatusUnauthorized)
+		return
+	}
+
+	profile := r.FormValue("type")
+	if profile == "" {
+		profile = "cpu"
+	}
+	seconds := 30
+	if s := r.FormValue("seconds"); s != "" {
+		var err error
+		if seconds, err = strconv.Atoi(s); err != nil || seconds <= 0 ||
+			time.Duration(seconds)*time.Second > maxProfileDuration {
+			http.Error(w, fmt.Sprintf("seconds must be between 1 and %.0f", maxProfileDuration.Seconds()),
+				http.StatusBadRequest)
+			return
+		}
+	}
+
+	switch profile {
+	case "cpu":
+		setAttachment(w, "cpu.pprof", "application/octet-stream")
+		if err := rpprof.StartCPUProfile(w); err != nil {
+			// only one CPU profile can run at a time
+			w.Header().Del("Content-Disposition")
+			http.Error(w, fmt.Sprintf("unable to start cpu profile: %v", err), http.StatusConflict)
+			return
+		}
+		select {
+		case <-time.After(time.Duration(seconds) * time.Second):
+		case <-r.Context().Done():
+		}
+		rpprof.StopCPUProfile()
+	case "heap":
+		setAttachment(w, "heap.pprof", "application/octet-stream")
+		runtime.GC()
+		writeProfile(w, "heap", 0)
+	case "goroutine":
+		setAttachment(w, "goroutine.txt", "text/plain; charset=utf-8")
+		writeProfile(w, "goroutine", 2)
+	default:
+		http.Error(w, fmt.Sprintf("unknown profile type %q, must be cpu, heap or goroutine", profile),
+			http.StatusBadRequest)
+	}
+}
+
+func setAttachment(w http.ResponseWriter, filename, contentType string) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+}
+
+func writeProfile(w io.Writer, name string, debug int) {
+	if err := rpprof.Lookup(name).WriteTo(w, debug); err != nil {
+		fmt.Fprintf(w, "unable to write %s profile: %v\n", name, err)
+	}
+}
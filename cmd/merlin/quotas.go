@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sky-uk/merlin/server"
+)
+
+var (
+	namespaceLabel  string
+	defaultQuota    string
+	namespaceQuotas []string
+)
+
+func init() {
+	f := rootCmd.PersistentFlags()
+	f.StringVar(&namespaceLabel, "namespace-label", "namespace",
+		"label of services with the namespace their quota is counted against")
+	f.StringVar(&defaultQuota, "default-quota", "",
+		"quota of namespaces without their own, e.g. services=10,servers=50 where servers is per service")
+	f.StringArrayVar(&namespaceQuotas, "quota", nil,
+		"quota of a namespace, e.g. payments:services=10,servers=50; can be repeated")
+}
+
+// parseQuotas returns the quotas set by the --*quota flags.
+func parseQuotas() (server.Quotas, error) {
+	quotas := server.Quotas{Label: namespaceLabel, Namespaces: make(map[string]server.Quota)}
+	if defaultQuota != "" {
+		q, err := server.ParseQuota(defaultQuota)
+		if err != nil {
+			return quotas, fmt.Errorf("invalid --default-quota: %v", err)
+		}
+		quotas.Default = q
+	}
+	for _, namespaceQuota := range namespaceQuotas {
+		parts := strings.SplitN(namespaceQuota, ":", 2)
+		if len(parts) != 2 {
+			return quotas, fmt.Errorf("invalid --quota %q, must be namespace:limits", namespaceQuota)
+		}
+		q, err := server.ParseQuota(parts[1])
+		if err != nil {
+			return quotas, fmt.Errorf("invalid --quota %q: %v", namespaceQuota, err)
+		}
+		quotas.Namespaces[parts[0]] = q
+	}
+	return quotas, nil
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	log "github.com/sirupsen/logrus"
+)
+
+// statsdPacketSize keeps each packet within a typical MTU, so it isn't fragmented.
+const statsdPacketSize = 1432
+
+var (
+	servePrometheus bool
+	statsdAddress   string
+	statsdPrefix    string
+	statsdTags      bool
+	statsdPeriod    time.Duration
+)
+
+func init() {
+	f := rootCmd.PersistentFlags()
+	f.BoolVar(&servePrometheus, "prometheus", true, "serve prometheus metrics on /metrics")
+	f.StringVar(&statsdAddress, "statsd-address", "",
+		"host:port of a StatsD or DogStatsD agent to push metrics to over UDP, disabled if unset")
+	f.StringVar(&statsdPrefix, "statsd-prefix", "merlin.", "prefix of metric names pushed to StatsD")
+	f.BoolVar(&statsdTags, "statsd-tags", false,
+		"push labels as DogStatsD tags, rather than appending their values to the metric name")
+	f.DurationVar(&statsdPeriod, "statsd-period", 10*time.Second, "how often to push metrics to StatsD")
+}
+
+// statsdSink pushes the metrics served on /metrics to StatsD. Counters are pushed as the increase since the
+// previous push, gauges as their value, and summaries and histograms as counters of their sum and count.
+type statsdSink struct {
+	conn     net.Conn
+	gatherer prometheus.Gatherer
+	prefix   string
+	tags     bool
+	// counters is the value of each counter at the previous push
+	counters map[string]float64
+}
+
+// startStatsd pushes metrics to --statsd-address every --statsd-period until stopCh is closed, if it's set.
+func startStatsd(stopCh <-chan struct{}) error {
+	if statsdAddress == "" {
+		return nil
+	}
+	conn, err := net.Dial("udp", statsdAddress)
+	if err != nil {
+		return fmt.Errorf("unable to connect to statsd: %v", err)
+	}
+	sink := &statsdSink{
+		conn:     conn,
+		gatherer: prometheus.DefaultGatherer,
+		prefix:   statsdPrefix,
+		tags:     statsdTags,
+		counters: make(map[string]float64),
+	}
+
+	go func() {
+		defer conn.Close()
+		ticker := time.NewTicker(statsdPeriod)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := sink.push(); err != nil {
+					log.Warnf("Unable to push metrics to statsd: %v", err)
+				}
+			case <-stopCh:
+				return
+			}
+		}
+	}()
+	log.Infof("Pushing metrics to statsd at %s every %v", statsdAddress, statsdPeriod)
+	return nil
+}
+
+func (s *statsdSink) push() error {
+	families, err := s.gatherer.Gather()
+	if err != nil {
+		return err
+	}
+
+	var lines []string
+	for _, family := range families {
+		for _, m := range family.Metric {
+			name := family.GetName()
+			switch family.GetType() {
+			case dto.MetricType_COUNTER:
+				lines = append(lines, s.count(name, m.Label, m.Counter.GetValue()))
+			case dto.MetricType_GAUGE:
+				lines = append(lines, s.line(name, m.Label, m.Gauge.GetValue(), "g"))
+			case dto.MetricType_UNTYPED:
+				lines = append(lines, s.line(name, m.Label, m.Untyped.GetValue(), "g"))
+			case dto.MetricType_SUMMARY:
+				lines = append(lines, s.count(name+"_sum", m.Label, m.Summary.GetSampleSum()),
+					s.count(name+"_count", m.Label, float64(m.Summary.GetSampleCount())))
+			case dto.MetricType_HISTOGRAM:
+				lines = append(lines, s.count(name+"_sum", m.Label, m.Histogram.GetSampleSum()),
+					s.count(name+"_count", m.Label, float64(m.Histogram.GetSampleCount())))
+			}
+		}
+	}
+	return s.send(lines)
+}
+
+// count returns the line for the increase of a counter since the previous push.
+func (s *statsdSink) count(name string, labels []*dto.LabelPair, value float64) string {
+	line := s.line(name, labels, 0, "c")
+	delta := value - s.counters[line]
+	if delta < 0 {
+		// the counter was reset
+		delta = value
+	}
+	s.counters[line] = value
+	return s.line(name, labels, delta, "c")
+}
+
+// line formats a metric as name:value|type, with its labels as tags or appended to its name.
+func (s *statsdSink) line(name string, labels []*dto.LabelPair, value float64, metricType string) string {
+	sorted := append([]*dto.LabelPair(nil), labels...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].GetName() < sorted[j].GetName() })
+
+	var tags []string
+	name = s.prefix + name
+	for _, label := range sorted {
+		if s.tags {
+			tags = append(tags, statsdEscape(label.GetName())+":"+statsdEscape(label.GetValue()))
+		} else {
+			name += "." + statsdEscape(label.GetValue())
+		}
+	}
+	line := fmt.Sprintf("%s:%s|%s", statsdEscape(name), strconv.FormatFloat(value, 'f', -1, 64), metricType)
+	if len(tags) > 0 {
+		line += "|#" + strings.Join(tags, ",")
+	}
+	return line
+}
+
+// send writes lines in as few packets as possible.
+func (s *statsdSink) send(lines []string) error {
+	var packet bytes.Buffer
+	flush := func() error {
+		if packet.Len() == 0 {
+			return nil
+		}
+		_, err := s.conn.Write(packet.Bytes())
+		packet.Reset()
+		return err
+	}
+	for _, line := range lines {
+		if packet.Len() > 0 && packet.Len()+len(line)+1 > statsdPacketSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		packet.WriteString(line)
+		packet.WriteByte('\n')
+	}
+	return flush()
+}
+
+// statsdEscape replaces the characters which delimit the StatsD line protocol.
+func statsdEscape(s string) string {
+	return strings.NewReplacer(":", "_", "|", "_", "@", "_", "#", "_", ",", "_", "\n", "_").Replace(s)
+}
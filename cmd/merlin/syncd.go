@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sky-uk/merlin/types"
+)
+
+var (
+	syncDaemonInterface string
+	syncDaemonID        uint32
+	syncDaemonRole      string
+)
+
+func init() {
+	f := rootCmd.PersistentFlags()
+	f.StringVar(&syncDaemonInterface, "sync-daemon-interface", "",
+		"manage the ipvs connection sync daemon, multicasting connections on this interface, so a backup which "+
+			"takes over the VIPs keeps them")
+	f.Uint32Var(&syncDaemonID, "sync-daemon-id", 0,
+		"sync ID from 0 to 255 pairing the sync daemon of a master with those of its backups")
+	f.StringVar(&syncDaemonRole, "sync-daemon-role", "",
+		"'master' or 'backup' to start the sync daemon in, or empty to wait for meradm syncd role")
+}
+
+// parseSyncDaemonRole returns the role of --sync-daemon-role.
+func parseSyncDaemonRole() (types.SyncDaemon_Role, error) {
+	switch syncDaemonRole {
+	case "":
+		return types.SyncDaemon_UNSET_SYNC_ROLE, nil
+	case "master", "backup":
+		return types.SyncDaemon_Role(types.SyncDaemon_Role_value[strings.ToUpper(syncDaemonRole)]), nil
+	default:
+		return 0, fmt.Errorf("unknown --sync-daemon-role %q, must be master or backup", syncDaemonRole)
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import "time"
+
+var (
+	tlsCert         string
+	tlsKey          string
+	tlsCA           string
+	tlsClientCA     string
+	tlsReloadPeriod time.Duration
+	tlsRenewCommand string
+	tlsRenewBefore  time.Duration
+)
+
+func init() {
+	f := rootCmd.PersistentFlags()
+	f.StringVar(&tlsCert, "tls-cert", "", "certificate file to serve the API over TLS with, reloaded when it changes")
+	f.StringVar(&tlsKey, "tls-key", "", "private key file of --tls-cert, reloaded when it changes")
+	f.StringVar(&tlsCA, "tls-ca", "",
+		"CA certificate file to verify the leader with when forwarding writes, defaults to the system roots")
+	f.StringVar(&tlsClientCA, "tls-client-ca", "",
+		"CA certificate file to require API clients to present a certificate signed by; writes forwarded to the "+
+			"leader present --tls-cert, which must allow client authentication")
+	f.DurationVar(&tlsReloadPeriod, "tls-reload-period", 10*time.Second,
+		"how often to check --tls-cert and --tls-key for changes")
+	f.StringVar(&tlsRenewCommand, "tls-renew-command", "",
+		"shell command, such as an ACME client, which writes --tls-cert and --tls-key; run at startup if they don't "+
+			"exist, and when the certificate is due for renewal")
+	f.DurationVar(&tlsRenewBefore, "tls-renew-before", 0,
+		"how long before the certificate expires to run --tls-renew-command, defaults to a third of its lifetime")
+}
@@ -0,0 +1,177 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+	"github.com/sky-uk/merlin/store"
+	"github.com/sky-uk/merlin/types"
+)
+
+// activeElectionPrefix separates the elections of active/passive pairs from the leader election.
+const activeElectionPrefix = "active/"
+
+// activePassive tracks whether this node is the active node of its active/passive pair.
+type activePassive struct {
+	mu     sync.Mutex
+	active bool
+	// renewed is when this node last campaigned successfully to be active
+	renewed time.Time
+	gauge   prometheus.Gauge
+	changes prometheus.Counter
+}
+
+func newActivePassive(election string) *activePassive {
+	labels := prometheus.Labels{"election": election}
+	return &activePassive{
+		gauge: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name:        "merlin_active",
+			Help:        "Whether this node is the active node of its active/passive pair.",
+			ConstLabels: labels,
+		}),
+		changes: prometheus.NewCounter(prometheus.CounterOpts{
+			Name:        "merlin_active_changes_total",
+			Help:        "Number of times this node became active or passive.",
+			ConstLabels: labels,
+		}),
+	}
+}
+
+func (a *activePassive) isActive() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.active
+}
+
+func (a *activePassive) renewedAt() time.Time {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.renewed
+}
+
+func (a *activePassive) renew(at time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.renewed = at
+}
+
+// set whether this node is active, returning true if it changed.
+func (a *activePassive) set(active bool) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if active == a.active {
+		return false
+	}
+	a.active = active
+	a.changes.Inc()
+	if active {
+		a.gauge.Set(1)
+	} else {
+		a.gauge.Set(0)
+	}
+	return true
+}
+
+// startActiveElection campaigns to be active before anything is reconciled, so a passive node never brings up the
+// VIPs, even briefly.
+func (d *Daemon) startActiveElection(st store.Store) error {
+	d.active = newActivePassive(d.opts.ActiveElection)
+	if err := d.opts.Registerer.Register(d.active.gauge); err != nil {
+		return fmt.Errorf("unable to register active metrics: %v", err)
+	}
+	if err := d.opts.Registerer.Register(d.active.changes); err != nil {
+		return fmt.Errorf("unable to register active metrics: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), d.opts.HeartbeatPeriod)
+	defer cancel()
+	d.campaignActive(ctx, st)
+	if !d.IsActive() {
+		log.Infof("Starting passive in %s", d.opts.ActiveElection)
+	}
+	d.reconciler.SetPaused(d.pausedWhilePassive())
+	return nil
+}
+
+// IsActive returns true if this node is the active node of its active/passive pair, which configures and announces
+// the VIPs. Without an active election every node is active.
+func (d *Daemon) IsActive() bool {
+	if d.opts.ActiveElection == "" {
+		return true
+	}
+	return d.active.isActive()
+}
+
+// pausedWhilePassive returns true if reconciling is paused because this node is passive.
+func (d *Daemon) pausedWhilePassive() bool {
+	return !d.opts.PassiveReconcile && !d.IsActive()
+}
+
+// campaignActive campaigns to be the active node of the pair, or renews it if this node is active. It's called on
+// every heartbeat, so a failed active node is taken over after 3 missed heartbeats.
+func (d *Daemon) campaignActive(ctx context.Context, st store.Store) {
+	election := activeElectionPrefix + d.opts.ActiveElection
+	ttl := 3 * d.opts.HeartbeatPeriod
+	start := time.Now()
+	leader, err := st.CampaignLeader(ctx, election, d.opts.NodeName, ttl)
+	if err != nil {
+		log.Warnf("Unable to campaign to be active: %v", err)
+		// the passive node can't take over until the active node's lease expires, so the active node keeps the VIPs
+		// through a store blip, stepping down a heartbeat before it could expire, as both nodes having the VIPs is
+		// worse than neither
+		if !d.active.isActive() || time.Since(d.active.renewedAt()) < ttl-d.opts.HeartbeatPeriod {
+			return
+		}
+		leader = ""
+	}
+	if leader == d.opts.NodeName {
+		d.active.renew(start)
+	}
+	active := leader == d.opts.NodeName
+	if !d.active.set(active) {
+		return
+	}
+	if active {
+		log.Infof("Became active in %s", d.opts.ActiveElection)
+	} else {
+		log.Warnf("Became passive in %s, the active node is %q", d.opts.ActiveElection, leader)
+	}
+	d.activeChanged(ctx)
+}
+
+// resignActive steps down as the active node, so the passive node takes over without waiting for it to expire.
+func (d *Daemon) resignActive() {
+	if d.opts.ActiveElection == "" || !d.active.set(false) {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), d.opts.HeartbeatPeriod)
+	defer cancel()
+	if err := d.store.ResignLeader(ctx, activeElectionPrefix+d.opts.ActiveElection, d.opts.NodeName); err != nil {
+		log.Warnf("Unable to resign as active: %v", err)
+	} else {
+		log.Infof("Resigned as active in %s", d.opts.ActiveElection)
+	}
+	d.activeChanged(ctx)
+}
+
+// activeChanged moves the VIPs and the master sync daemon to or from this node.
+func (d *Daemon) activeChanged(ctx context.Context) {
+	if d.syncDaemon != nil {
+		role := types.SyncDaemon_BACKUP
+		if d.active.isActive() {
+			role = types.SyncDaemon_MASTER
+		}
+		if err := d.syncDaemon.setRole(ctx, role); err != nil {
+			log.Warnf("Unable to switch the sync daemon: %v", err)
+		}
+	}
+	if d.vips != nil {
+		d.vips.sync()
+	}
+	if d.bgp != nil {
+		d.bgp.sync()
+	}
+}
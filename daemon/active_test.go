@@ -0,0 +1,161 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sky-uk/merlin/ipvs"
+	"github.com/sky-uk/merlin/store"
+	"github.com/sky-uk/merlin/types"
+	"github.com/sky-uk/merlin/vip"
+)
+
+// flakyElectionStore fails campaigns while failures is positive, counting it down on each one.
+type flakyElectionStore struct {
+	store.Store
+	mu       sync.Mutex
+	failures int
+}
+
+func (s *flakyElectionStore) fail(failures int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = failures
+}
+
+func (s *flakyElectionStore) CampaignLeader(ctx context.Context, election, candidate string,
+	ttl time.Duration) (string, error) {
+	s.mu.Lock()
+	failing := s.failures > 0
+	s.failures--
+	s.mu.Unlock()
+	if failing {
+		return "", errors.New("etcd timed out")
+	}
+	return s.Store.CampaignLeader(ctx, election, candidate, ttl)
+}
+
+var _ = Describe("Active election", func() {
+	var (
+		ctx = context.Background()
+		st  store.Store
+	)
+
+	pairNode := func(name string, vips vip.Interface) *Daemon {
+		return New(Options{
+			Mode:            ModeAgent,
+			Store:           st,
+			IPVS:            ipvs.NewMemory(),
+			Reconcile:       true,
+			NodeName:        name,
+			HeartbeatPeriod: 50 * time.Millisecond,
+			VIPInterface:    "merlin0",
+			VIPs:            vips,
+			ActiveElection:  "pair",
+			Registerer:      prometheus.NewRegistry(),
+		})
+	}
+	configured := func(vips vip.Interface) func() []string {
+		return func() []string {
+			ips, err := vips.List(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			var addrs []string
+			for _, ip := range ips {
+				addrs = append(addrs, ip.String())
+			}
+			return addrs
+		}
+	}
+
+	BeforeEach(func() {
+		st = store.NewMemory()
+		Expect(st.PutService(ctx, &types.VirtualService{
+			Id:     "web",
+			Key:    &types.VirtualService_Key{Ip: "10.0.0.1", Port: 80, Protocol: types.Protocol_TCP},
+			Config: &types.VirtualService_Config{Scheduler: "wrr"},
+		})).To(Succeed())
+	})
+
+	It("should only configure the VIPs on the active node, which the passive node takes over when it stops", func() {
+		vips1, vips2 := vip.NewMemory(), vip.NewMemory()
+		d1, d2 := pairNode("node1", vips1), pairNode("node2", vips2)
+		Expect(d1.Start()).To(Succeed())
+		Expect(d2.Start()).To(Succeed())
+		defer d2.Stop(time.Second)
+
+		Expect(d1.IsActive()).To(BeTrue())
+		Expect(d2.IsActive()).To(BeFalse())
+		Expect(d2.node().Passive).To(BeTrue())
+		Expect(d2.node().Maintenance).To(BeTrue())
+		Eventually(configured(vips1)).Should(ConsistOf("10.0.0.1"))
+		Consistently(configured(vips2), 200*time.Millisecond).Should(BeEmpty())
+
+		Expect(d1.Stop(time.Second)).To(Succeed())
+
+		Expect(configured(vips1)()).To(BeEmpty())
+		Eventually(d2.IsActive).Should(BeTrue())
+		Eventually(configured(vips2)).Should(ConsistOf("10.0.0.1"))
+		Expect(d2.node().Maintenance).To(BeFalse())
+	})
+
+	Context("when the store fails", func() {
+		var (
+			flaky *flakyElectionStore
+			vips  vip.Interface
+			d     *Daemon
+		)
+
+		BeforeEach(func() {
+			flaky = &flakyElectionStore{Store: st}
+			st = flaky
+			vips = vip.NewMemory()
+			d = pairNode("node1", vips)
+			Expect(d.Start()).To(Succeed())
+			Eventually(configured(vips)).Should(ConsistOf("10.0.0.1"))
+		})
+
+		AfterEach(func() {
+			Expect(d.Stop(time.Second)).To(Succeed())
+		})
+
+		It("should stay active for a heartbeat", func() {
+			flaky.fail(1)
+
+			Consistently(d.IsActive, 300*time.Millisecond).Should(BeTrue())
+			Expect(configured(vips)()).To(ConsistOf("10.0.0.1"))
+		})
+
+		It("should step down before its lease could expire", func() {
+			flaky.fail(1000)
+
+			Eventually(d.IsActive, 150*time.Millisecond, 10*time.Millisecond).Should(BeFalse())
+			Eventually(configured(vips)).Should(BeEmpty())
+		})
+	})
+
+	It("should keep reconciling while passive with passive reconcile", func() {
+		d1 := pairNode("node1", vip.NewMemory())
+		d2 := pairNode("node2", vip.NewMemory())
+		d2.opts.PassiveReconcile = true
+		Expect(d1.Start()).To(Succeed())
+		defer d1.Stop(time.Second)
+		Expect(d2.Start()).To(Succeed())
+		defer d2.Stop(time.Second)
+
+		Expect(d2.IsActive()).To(BeFalse())
+		Consistently(func() bool { return d2.node().Maintenance }, 200*time.Millisecond).Should(BeFalse())
+	})
+
+	It("should refuse a sync daemon role", func() {
+		d := pairNode("node1", vip.NewMemory())
+		d.opts.SyncDaemonInterface = "eth0"
+		d.opts.SyncDaemonRole = types.SyncDaemon_MASTER
+
+		Expect(d.Start()).ToNot(Succeed())
+	})
+})
@@ -0,0 +1,86 @@
+package daemon
+
+import (
+	"context"
+	"path"
+	"time"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	log "github.com/sirupsen/logrus"
+	"github.com/sky-uk/merlin/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// auditTimeout is how long recording an audit event can take, which is independent of the request so events are
+// recorded even if the client has gone away.
+const auditTimeout = 5 * time.Second
+
+var auditMarshaler = &jsonpb.Marshaler{OrigName: true}
+
+// audit is an interceptor which records every write, with the client which made it and its result, in the store
+// and AuditLog if it's set. Writes forwarded to the leader are recorded by both nodes, with the leader's event
+// saying which node forwarded it, and writes staged in the candidate config are marked as such. Events which can't
+// be recorded are logged. Dry runs are recorded as such, as they changed nothing.
+func (d *Daemon) audit(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	if !isWrite(info.FullMethod) {
+		return handler(ctx, req)
+	}
+	resp, err := handler(ctx, req)
+
+	event := &types.AuditEvent{
+		Time:      ptypes.TimestampNow(),
+		Client:    clientIdentity(ctx),
+		Node:      d.opts.NodeName,
+		Method:    path.Base(info.FullMethod),
+		Code:      codes.OK.String(),
+		Candidate: types.IsCandidate(ctx),
+		DryRun:    isDryRun(ctx, req),
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get(forwardedKey)) > 0 {
+		event.ForwardedBy = md.Get(forwardedKey)[0]
+	}
+	if msg, ok := req.(proto.Message); ok {
+		event.Request, _ = auditMarshaler.MarshalToString(msg)
+	}
+	if err != nil {
+		// errors without a status are returned as internal errors by logRequests
+		s, ok := status.FromError(err)
+		if !ok {
+			s = status.New(codes.Internal, err.Error())
+		}
+		event.Code, event.Error = s.Code().String(), s.Message()
+	}
+	d.recordAudit(event)
+	return resp, err
+}
+
+// isDryRun returns true if a request is a dry run, by its metadata or its dry_run field.
+func isDryRun(ctx context.Context, req interface{}) bool {
+	if r, ok := req.(interface{ GetDryRun() bool }); ok && r.GetDryRun() {
+		return true
+	}
+	return types.IsDryRun(ctx)
+}
+
+func (d *Daemon) recordAudit(event *types.AuditEvent) {
+	if d.opts.AuditLog != nil {
+		line, _ := auditMarshaler.MarshalToString(event)
+		d.auditMu.Lock()
+		_, err := d.opts.AuditLog.Write([]byte(line + "\n"))
+		d.auditMu.Unlock()
+		if err != nil {
+			log.Errorf("Unable to write audit event of %s to the audit log: %v", event.Method, err)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), auditTimeout)
+	defer cancel()
+	if err := d.store.AddAuditEvent(ctx, event, d.opts.AuditRetention); err != nil {
+		log.Errorf("Unable to record audit event of %s: %v", event.Method, err)
+	}
+}
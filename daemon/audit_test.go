@@ -0,0 +1,104 @@
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sky-uk/merlin/store"
+	"github.com/sky-uk/merlin/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+var _ = Describe("Audit", func() {
+	var (
+		d   *Daemon
+		ctx context.Context
+		log *bytes.Buffer
+	)
+
+	call := func(method string, req interface{}, err error) {
+		info := &grpc.UnaryServerInfo{FullMethod: "/types.Merlin/" + method}
+		_, returned := d.audit(ctx, req, info, func(context.Context, interface{}) (interface{}, error) {
+			return nil, err
+		})
+		if err == nil {
+			Expect(returned).ToNot(HaveOccurred())
+		} else {
+			Expect(returned).To(Equal(err))
+		}
+	}
+	events := func() []*types.AuditEvent {
+		events, err := d.store.ListAuditEvents(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		return events
+	}
+
+	BeforeEach(func() {
+		log = &bytes.Buffer{}
+		d = New(Options{NodeName: "node1", AuditLog: log})
+		d.store = store.NewMemory()
+		ctx = context.WithValue(context.Background(), clientKey{}, "deploy")
+	})
+
+	It("should record writes with the client which made them", func() {
+		call("CreateService", &types.VirtualService{Id: "web"}, nil)
+
+		Expect(events()).To(HaveLen(1))
+		event := events()[0]
+		Expect(event.Client).To(Equal("deploy"))
+		Expect(event.Node).To(Equal("node1"))
+		Expect(event.Method).To(Equal("CreateService"))
+		Expect(event.Request).To(Equal(`{"id":"web"}`))
+		Expect(event.Code).To(Equal("OK"))
+		Expect(log.String()).To(ContainSubstring(`"method":"CreateService"`))
+	})
+
+	It("should record the result of failed writes", func() {
+		call("DeleteService", nil, errors.New("store is down"))
+
+		Expect(events()[0].Code).To(Equal("Internal"))
+		Expect(events()[0].Error).To(Equal("store is down"))
+	})
+
+	It("should record which node forwarded a write", func() {
+		ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(forwardedKey, "node2"))
+
+		call("UpdateService", &types.VirtualService{Id: "web"}, nil)
+
+		Expect(events()[0].ForwardedBy).To(Equal("node2"))
+	})
+
+	It("should record writes staged in the candidate config as staged", func() {
+		ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(types.CandidateHeader, "true"))
+
+		call("CreateService", &types.VirtualService{Id: "web"}, nil)
+
+		Expect(events()[0].Candidate).To(BeTrue())
+	})
+
+	It("should record dry runs as dry runs", func() {
+		call("ApplySnapshot", &types.ApplySnapshotRequest{DryRun: true}, nil)
+		ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(types.DryRunHeader, "true"))
+		call("CreateService", &types.VirtualService{Id: "web"}, nil)
+
+		Expect(events()).To(HaveLen(2))
+		Expect(events()[0].DryRun).To(BeTrue())
+		Expect(events()[1].DryRun).To(BeTrue())
+	})
+
+	It("should not record reads", func() {
+		call("List", &types.ListRequest{}, nil)
+
+		Expect(events()).To(BeEmpty())
+		Expect(log.Len()).To(BeZero())
+	})
+
+	It("should keep events for the audit retention", func() {
+		Expect(d.opts.AuditRetention).To(Equal(30 * 24 * time.Hour))
+	})
+})
@@ -0,0 +1,172 @@
+package daemon
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/sky-uk/merlin/rbac"
+	"github.com/sky-uk/merlin/server"
+	"github.com/sky-uk/merlin/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// clientKey is the context key of the name of the client a request authenticated as.
+type clientKey struct{}
+
+// clientIdentity returns the name of the token the request authenticated with, or else the common name of its
+// verified TLS client certificate, or "" if it has neither.
+func clientIdentity(ctx context.Context) string {
+	if name, ok := ctx.Value(clientKey{}).(string); ok {
+		return name
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(info.State.VerifiedChains) > 0 {
+			return info.State.VerifiedChains[0][0].Subject.CommonName
+		}
+	}
+	return ""
+}
+
+// validateTokens checks every client has a name and a token, and no two clients share a token, so each request
+// authenticates as a single client.
+func validateTokens(tokens map[string]string) error {
+	clients := make(map[string]string)
+	for name, token := range tokens {
+		if name == "" || token == "" {
+			return fmt.Errorf("api client %q must have a name and a token", name)
+		}
+		if other, ok := clients[token]; ok {
+			return fmt.Errorf("api clients %q and %q have the same token", other, name)
+		}
+		clients[token] = name
+	}
+	return nil
+}
+
+// authenticate is an interceptor which requires requests to have the bearer token of an API client, if any are
+// set, adding the client's name to the context. Reads are allowed without a token if AnonymousReads is set, and
+// health checks always are.
+func (d *Daemon) authenticate(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := d.authenticated(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// authenticated returns ctx with the name of the client whose token the request has, or an error if it needs one
+// and doesn't have a valid one.
+func (d *Daemon) authenticated(ctx context.Context, fullMethod string) (context.Context, error) {
+	if len(d.opts.APITokens) == 0 || healthMethods[fullMethod] {
+		return ctx, nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	auth := md.Get("authorization")
+	if len(auth) == 0 {
+		if d.opts.AnonymousReads && !isWrite(fullMethod) {
+			return ctx, nil
+		}
+		return nil, status.Error(codes.Unauthenticated, "a bearer token is required")
+	}
+	token := strings.TrimPrefix(auth[0], "Bearer ")
+	var name string
+	// every token is compared in constant time, so the time taken doesn't reveal how close a guess is
+	for client, clientToken := range d.opts.APITokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(clientToken)) == 1 {
+			name = client
+		}
+	}
+	if name == "" {
+		return nil, status.Error(codes.Unauthenticated, "invalid bearer token")
+	}
+	return context.WithValue(ctx, clientKey{}, name), nil
+}
+
+// authorize is an interceptor which denies requests the policy doesn't allow the client to make, if it's set.
+// Requests which aren't for particular services need the operation on every service, except List, which only
+// returns the services the client can read.
+func (d *Daemon) authorize(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	policy := d.opts.Policy
+	if policy == nil || healthMethods[info.FullMethod] {
+		return handler(ctx, req)
+	}
+	client := clientIdentity(ctx)
+	op := rbac.Read
+	if isWrite(info.FullMethod) {
+		op = rbac.Write
+	}
+	method := path.Base(info.FullMethod)
+
+	if method == "List" && !policy.Allowed(client, rbac.Read, "") {
+		// filtered by the server, so pages and the total count only include the services the client can read
+		return handler(server.WithServiceFilter(ctx, func(serviceID string) bool {
+			return policy.Allowed(client, rbac.Read, serviceID)
+		}), req)
+	}
+
+	ids := requestServices(method, req)
+	if ids == nil {
+		ids = []string{""}
+	}
+	for _, id := range ids {
+		if policy.Allowed(client, op, id) {
+			continue
+		}
+		if client == "" {
+			client = "anonymous client"
+		}
+		if id == "" {
+			return nil, status.Errorf(codes.PermissionDenied, "%s can't %s every service, which %s needs",
+				client, op, method)
+		}
+		return nil, status.Errorf(codes.PermissionDenied, "%s can't %s service %s", client, op, id)
+	}
+	return handler(ctx, req)
+}
+
+// requestServices returns the IDs of the services a request is for, or nil if it isn't for particular services.
+func requestServices(method string, req interface{}) []string {
+	switch r := req.(type) {
+	case *types.CloneServiceRequest:
+		return []string{r.Id, r.NewId}
+	case *types.RenameServiceRequest:
+		return []string{r.Id, r.NewId}
+	case *types.SwapServersRequest:
+		return []string{r.Id, r.OtherId}
+	case *types.RealServer:
+		return []string{r.ServiceID}
+	case *wrappers.StringValue:
+		// pools are deleted by name
+		if method == "DeleteService" || method == "GetService" || method == "GetServerStats" {
+			return []string{r.Value}
+		}
+	case *types.VirtualService:
+		return []string{r.Id}
+	case *types.DescribeServiceRequest:
+		return []string{r.Id}
+	case *types.RolloutServiceRequest:
+		return []string{r.Id}
+	case *types.ApplyRequest:
+		ids := []string{}
+		for _, change := range r.Changes {
+			if change.Service != nil {
+				ids = append(ids, change.Service.Id)
+			} else {
+				ids = append(ids, change.GetServer().GetServiceID())
+			}
+		}
+		return ids
+	}
+	return nil
+}
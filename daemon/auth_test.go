@@ -0,0 +1,158 @@
+package daemon
+
+import (
+	"context"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sky-uk/merlin/rbac"
+	"github.com/sky-uk/merlin/server"
+	"github.com/sky-uk/merlin/store"
+	"github.com/sky-uk/merlin/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var _ = Describe("Authentication", func() {
+	var (
+		d      *Daemon
+		client string
+	)
+
+	call := func(method, token string) error {
+		ctx := context.Background()
+		if token != "" {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer "+token))
+		}
+		info := &grpc.UnaryServerInfo{FullMethod: "/types.Merlin/" + method}
+		_, err := d.authenticate(ctx, nil, info, func(ctx context.Context, _ interface{}) (interface{}, error) {
+			client, _ = ctx.Value(clientKey{}).(string)
+			return nil, nil
+		})
+		return err
+	}
+
+	BeforeEach(func() {
+		client = ""
+		d = &Daemon{opts: Options{APITokens: map[string]string{"deploy": "s3cret", "ops": "0ps"}}}
+	})
+
+	It("should authenticate requests as the client with the token", func() {
+		Expect(call("CreateService", "0ps")).To(Succeed())
+		Expect(client).To(Equal("ops"))
+	})
+
+	It("should reject requests without a valid token", func() {
+		Expect(status.Code(call("CreateService", ""))).To(Equal(codes.Unauthenticated))
+		Expect(status.Code(call("CreateService", "wrong"))).To(Equal(codes.Unauthenticated))
+		Expect(status.Code(call("List", ""))).To(Equal(codes.Unauthenticated))
+	})
+
+	It("should allow reads without a token if anonymous reads are allowed", func() {
+		d.opts.AnonymousReads = true
+
+		Expect(call("List", "")).To(Succeed())
+		Expect(status.Code(call("CreateService", ""))).To(Equal(codes.Unauthenticated))
+		Expect(status.Code(call("List", "wrong"))).To(Equal(codes.Unauthenticated))
+	})
+
+	It("should allow every request without tokens", func() {
+		d.opts.APITokens = nil
+
+		Expect(call("CreateService", "")).To(Succeed())
+	})
+
+	It("should refuse clients with the same token", func() {
+		Expect(validateTokens(map[string]string{"deploy": "s3cret", "ops": "s3cret"})).
+			To(MatchError(ContainSubstring("have the same token")))
+		Expect(validateTokens(map[string]string{"deploy": ""})).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Authorization", func() {
+	var d *Daemon
+
+	call := func(client, method string, req interface{}) (interface{}, error) {
+		ctx := context.WithValue(context.Background(), clientKey{}, client)
+		info := &grpc.UnaryServerInfo{FullMethod: "/types.Merlin/" + method}
+		return d.authorize(ctx, req, info, func(context.Context, interface{}) (interface{}, error) {
+			return nil, nil
+		})
+	}
+
+	BeforeEach(func() {
+		policy, err := rbac.ParsePolicy([]byte(`
+rules:
+- clients: [payments]
+  operations: [read, write]
+  services: [payments-]
+- clients: [ops]
+  operations: [read, write]
+`))
+		Expect(err).ToNot(HaveOccurred())
+		d = &Daemon{opts: Options{Policy: policy}}
+	})
+
+	It("should only allow clients to change the services they can write", func() {
+		_, err := call("payments", "CreateServer", &types.RealServer{ServiceID: "payments-web"})
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = call("payments", "DeleteService", &wrappers.StringValue{Value: "search-web"})
+		Expect(status.Code(err)).To(Equal(codes.PermissionDenied))
+		_, err = call("payments", "RenameService", &types.RenameServiceRequest{Id: "payments-web", NewId: "web"})
+		Expect(status.Code(err)).To(Equal(codes.PermissionDenied))
+	})
+
+	It("should need write access to the service of every change applied", func() {
+		changes := []*types.Change{
+			{Action: types.Change_CREATE, Service: &types.VirtualService{Id: "payments-web"}},
+			{Action: types.Change_CREATE, Server: &types.RealServer{ServiceID: "payments-web"}},
+		}
+		_, err := call("payments", "Apply", &types.ApplyRequest{Changes: changes})
+		Expect(err).ToNot(HaveOccurred())
+
+		changes = append(changes, &types.Change{Action: types.Change_DELETE,
+			Server: &types.RealServer{ServiceID: "web"}})
+		_, err = call("payments", "Apply", &types.ApplyRequest{Changes: changes})
+		Expect(status.Code(err)).To(Equal(codes.PermissionDenied))
+	})
+
+	It("should require access to every service for requests which aren't for particular services", func() {
+		_, err := call("payments", "ApplySnapshot", &types.ApplySnapshotRequest{})
+		Expect(status.Code(err)).To(Equal(codes.PermissionDenied))
+		_, err = call("ops", "ApplySnapshot", &types.ApplySnapshotRequest{})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should only list the services the client can read", func() {
+		st := store.NewMemory()
+		srv := server.New(st, nil, func() *types.Node { return &types.Node{Name: "node1"} }, nil, nil,
+			server.Options{})
+		for _, id := range []string{"payments-api", "payments-web", "search-web"} {
+			Expect(st.PutService(context.Background(), &types.VirtualService{Id: id})).To(Succeed())
+		}
+		ctx := context.WithValue(context.Background(), clientKey{}, "payments")
+		info := &grpc.UnaryServerInfo{FullMethod: "/types.Merlin/List"}
+
+		resp, err := d.authorize(ctx, &types.ListRequest{PageSize: 1}, info,
+			func(ctx context.Context, req interface{}) (interface{}, error) {
+				return srv.List(ctx, req.(*types.ListRequest))
+			})
+
+		Expect(err).ToNot(HaveOccurred())
+		list := resp.(*types.ListResponse)
+		Expect(list.Items).To(HaveLen(1))
+		Expect(list.Items[0].Service.Id).To(Equal("payments-api"))
+		Expect(list.TotalSize).To(BeEquivalentTo(2))
+	})
+
+	It("should deny clients without a rule", func() {
+		_, err := call("", "GetSnapshot", &empty.Empty{})
+		Expect(status.Code(err)).To(Equal(codes.PermissionDenied))
+		Expect(err.Error()).To(ContainSubstring("anonymous client can't read every service"))
+	})
+})
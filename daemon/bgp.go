@@ -0,0 +1,161 @@
+package daemon
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+	"github.com/sky-uk/merlin/bgp"
+	"github.com/sky-uk/merlin/reconciler"
+	"github.com/sky-uk/merlin/server"
+	"github.com/sky-uk/merlin/types"
+)
+
+// bgpHealthPeriod is how often the health of servers is checked, to withdraw VIPs which have no healthy servers.
+const bgpHealthPeriod = time.Second
+
+// bgpAnnouncer announces the VIP of every service reconciled onto this node which has a server that isn't down, so
+// routers withdraw traffic from VIPs which are deleted or have no healthy servers, and from every VIP of this node
+// when merlin stops. Nothing is announced while this node is passive. The services are listed when the store changes
+// and every period.
+type bgpAnnouncer struct {
+	speaker speaker
+	store   reconciler.Store
+	health  server.HealthFunc
+	period  time.Duration
+	active  func() bool
+	peerUp  *prometheus.GaugeVec
+	// services and their servers, from the last time they were listed
+	services []*announcedService
+	syncCh   chan struct{}
+	stopCh   chan struct{}
+	doneCh   chan struct{}
+}
+
+// speaker announces routes to the VIPs to peers, such as a *bgp.Speaker.
+type speaker interface {
+	Announce(vips []net.IP)
+	Established() map[string]bool
+	Close()
+}
+
+var _ speaker = &bgp.Speaker{}
+
+type announcedService struct {
+	id      string
+	vip     net.IP
+	servers []*types.RealServer
+}
+
+func newBGPAnnouncer(speaker speaker, st reconciler.Store, health server.HealthFunc, period time.Duration,
+	active func() bool) *bgpAnnouncer {
+	return &bgpAnnouncer{
+		speaker: speaker,
+		store:   st,
+		health:  health,
+		period:  period,
+		active:  active,
+		peerUp: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "merlin_bgp_peer_up",
+			Help: "Whether the BGP session with each peer is established.",
+		}, []string{"peer"}),
+		syncCh: make(chan struct{}, 1),
+		stopCh: make(chan struct{}),
+		doneCh: make(chan struct{}),
+	}
+}
+
+func (a *bgpAnnouncer) start() {
+	a.sync()
+	go a.run()
+}
+
+// sync lists the services again in the background, unless it's already pending.
+func (a *bgpAnnouncer) sync() {
+	select {
+	case a.syncCh <- struct{}{}:
+	default:
+	}
+}
+
+func (a *bgpAnnouncer) run() {
+	defer close(a.doneCh)
+	ticker := time.NewTicker(a.period)
+	defer ticker.Stop()
+	healthTicker := time.NewTicker(bgpHealthPeriod)
+	defer healthTicker.Stop()
+	for {
+		select {
+		case <-a.syncCh:
+			a.listServices()
+		case <-ticker.C:
+			a.listServices()
+		case <-healthTicker.C:
+		case <-a.stopCh:
+			return
+		}
+		a.speaker.Announce(a.vips())
+		for peer, established := range a.speaker.Established() {
+			up := 0.0
+			if established {
+				up = 1
+			}
+			a.peerUp.WithLabelValues(peer).Set(up)
+		}
+	}
+}
+
+func (a *bgpAnnouncer) listServices() {
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	defer cancel()
+	svcs, err := a.store.ListServices(ctx)
+	if err != nil {
+		log.Warnf("Unable to list services to announce: %v", err)
+		return
+	}
+	var services []*announcedService
+	for _, svc := range svcs {
+		vip := net.ParseIP(svc.GetKey().GetIp())
+		if vip == nil {
+			continue
+		}
+		servers, err := a.store.ListServers(ctx, svc.Id)
+		if err != nil {
+			log.Warnf("Unable to list servers of %s to announce: %v", svc.Id, err)
+			return
+		}
+		services = append(services, &announcedService{id: svc.Id, vip: vip, servers: servers})
+	}
+	a.services = services
+}
+
+// vips returns the VIPs of the services which have a server that isn't down, or none if this node is passive.
+func (a *bgpAnnouncer) vips() []net.IP {
+	if !a.active() {
+		return nil
+	}
+	var vips []net.IP
+	up := make(map[string]bool)
+	for _, svc := range a.services {
+		if up[svc.vip.String()] {
+			continue
+		}
+		for _, srv := range svc.servers {
+			if a.health(svc.id, srv.Key) != types.Health_DOWN {
+				up[svc.vip.String()] = true
+				vips = append(vips, svc.vip)
+				break
+			}
+		}
+	}
+	return vips
+}
+
+// stop announcing, which withdraws every VIP.
+func (a *bgpAnnouncer) stop() {
+	close(a.stopCh)
+	<-a.doneCh
+	a.speaker.Close()
+}
@@ -0,0 +1,148 @@
+package daemon
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sky-uk/merlin/store"
+	"github.com/sky-uk/merlin/types"
+)
+
+// stubSpeaker records the VIPs announced to it.
+type stubSpeaker struct {
+	mu     sync.Mutex
+	vips   []string
+	closed bool
+}
+
+func (s *stubSpeaker) Announce(vips []net.IP) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.vips = nil
+	for _, vip := range vips {
+		s.vips = append(s.vips, vip.String())
+	}
+}
+
+func (s *stubSpeaker) Established() map[string]bool {
+	return map[string]bool{"10.1.0.1": true}
+}
+
+func (s *stubSpeaker) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+}
+
+func (s *stubSpeaker) announced() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.vips
+}
+
+var _ = Describe("BGP", func() {
+	var (
+		ctx     = context.Background()
+		st      store.Store
+		speaker *stubSpeaker
+		mu      sync.Mutex
+		down    map[string]bool
+		active  bool
+		a       *bgpAnnouncer
+	)
+
+	service := func(id, ip string) *types.VirtualService {
+		return &types.VirtualService{Id: id, Key: &types.VirtualService_Key{Ip: ip, Port: 80}}
+	}
+	server := func(serviceID, ip string) *types.RealServer {
+		return &types.RealServer{ServiceID: serviceID, Key: &types.RealServer_Key{Ip: ip, Port: 8080}}
+	}
+	setDown := func(ip string, isDown bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		down[ip] = isDown
+	}
+
+	BeforeEach(func() {
+		st = store.NewMemory()
+		speaker = &stubSpeaker{}
+		down = make(map[string]bool)
+		active = true
+		health := func(_ string, key *types.RealServer_Key) types.Health {
+			mu.Lock()
+			defer mu.Unlock()
+			if down[key.Ip] {
+				return types.Health_DOWN
+			}
+			return types.Health_UP
+		}
+		a = newBGPAnnouncer(speaker, st, health, time.Hour, func() bool { return active })
+		Expect(st.PutService(ctx, service("web", "10.0.0.1"))).To(Succeed())
+		Expect(st.PutService(ctx, service("web-tls", "10.0.0.1"))).To(Succeed())
+		Expect(st.PutService(ctx, service("api", "10.0.0.2"))).To(Succeed())
+		Expect(st.PutServer(ctx, server("web", "172.16.1.1"))).To(Succeed())
+		Expect(st.PutServer(ctx, server("web-tls", "172.16.1.2"))).To(Succeed())
+		Expect(st.PutServer(ctx, server("api", "172.16.1.3"))).To(Succeed())
+	})
+
+	AfterEach(func() {
+		if a != nil {
+			a.stop()
+		}
+	})
+
+	It("should announce the VIPs of services with healthy servers", func() {
+		a.start()
+
+		Eventually(speaker.announced).Should(ConsistOf("10.0.0.1", "10.0.0.2"))
+
+		Expect(st.PutService(ctx, service("dns", "10.0.0.3"))).To(Succeed())
+		Expect(st.PutServer(ctx, server("dns", "172.16.1.4"))).To(Succeed())
+		a.sync()
+
+		Eventually(speaker.announced).Should(ConsistOf("10.0.0.1", "10.0.0.2", "10.0.0.3"))
+	})
+
+	It("should withdraw the VIPs of services without healthy servers", func() {
+		a.start()
+		Eventually(speaker.announced).Should(ConsistOf("10.0.0.1", "10.0.0.2"))
+
+		setDown("172.16.1.1", true)
+		setDown("172.16.1.3", true)
+
+		Eventually(speaker.announced, 3*time.Second).Should(ConsistOf("10.0.0.1"))
+
+		setDown("172.16.1.2", true)
+
+		Eventually(speaker.announced, 3*time.Second).Should(BeEmpty())
+	})
+
+	It("should withdraw the VIPs of deleted services", func() {
+		a.start()
+		Eventually(speaker.announced).Should(ConsistOf("10.0.0.1", "10.0.0.2"))
+
+		Expect(st.DeleteService(ctx, "api")).To(Succeed())
+		a.sync()
+
+		Eventually(speaker.announced).Should(ConsistOf("10.0.0.1"))
+	})
+
+	It("should announce nothing while passive", func() {
+		active = false
+		a.start()
+
+		Consistently(speaker.announced, 100*time.Millisecond).Should(BeEmpty())
+	})
+
+	It("should close the speaker when stopped", func() {
+		a.start()
+		a.stop()
+		a = nil
+
+		Expect(speaker.closed).To(BeTrue())
+	})
+})
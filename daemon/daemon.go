@@ -0,0 +1,885 @@
+// Package daemon runs merlin in-process: the API server, the store client, and the reconciler of local IPVS.
+// The merlin binary is a thin wrapper over it, so it can also be embedded in other control-plane binaries.
+package daemon
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+	"github.com/sky-uk/merlin/bgp"
+	"github.com/sky-uk/merlin/faults"
+	"github.com/sky-uk/merlin/ipvs"
+	"github.com/sky-uk/merlin/rbac"
+	"github.com/sky-uk/merlin/reconciler"
+	"github.com/sky-uk/merlin/rollout"
+	"github.com/sky-uk/merlin/server"
+	"github.com/sky-uk/merlin/store"
+	"github.com/sky-uk/merlin/types"
+	"github.com/sky-uk/merlin/validation"
+	"github.com/sky-uk/merlin/vip"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// ModeAll serves the API and reconciles IPVS.
+	ModeAll = "all"
+	// ModeAgent only reconciles IPVS, for fleets where a few central nodes serve the API.
+	ModeAgent = "agent"
+)
+
+// checkTimeout is how long health checks, metrics and the self-test wait for the store to respond.
+const checkTimeout = 5 * time.Second
+
+// Options of a merlin instance. Zero values are replaced by the defaults given for each option.
+type Options struct {
+	// Port the API listens on, unless Listener is set.
+	Port int
+	// Listener to serve the API on, instead of listening on Port. It's closed when merlin stops.
+	Listener net.Listener
+	// HTTPHandler is served on the API port alongside gRPC if set, such as the health and metrics endpoints, so
+	// merlin can be reached through a single port.
+	HTTPHandler http.Handler
+	// Mode is ModeAll or ModeAgent, defaults to ModeAll.
+	Mode string
+	// TLSCertFile and TLSKeyFile serve the API over TLS with their certificate if set. They're reloaded when they
+	// change, so certificates can be rotated without restarting merlin.
+	TLSCertFile string
+	TLSKeyFile  string
+	// TLSCAFile verifies the leader's certificate when forwarding writes to it, defaults to the system roots.
+	TLSCAFile string
+	// TLSClientCAFile requires API clients to present a certificate signed by it, for mutual TLS. Writes forwarded
+	// to the leader present this node's certificate, which must allow client authentication.
+	TLSClientCAFile string
+	// TLSReloadPeriod is how often the certificate files are checked for changes, defaults to 10 seconds.
+	TLSReloadPeriod time.Duration
+	// TLSRenewCommand is run by sh to obtain the certificate if TLSCertFile doesn't exist, and to renew it when it's
+	// within TLSRenewBefore of expiring, such as an ACME client which writes TLSCertFile and TLSKeyFile.
+	TLSRenewCommand string
+	// TLSRenewBefore is how long before the certificate expires to renew it, defaults to a third of its lifetime.
+	TLSRenewBefore time.Duration
+
+	// APITokens maps the name of each API client to the bearer token, or API key, it authenticates with. If set,
+	// requests must authenticate with one of them, except reads if AnonymousReads is set. Forwarded writes are
+	// authenticated again by the leader, so every node needs the same tokens.
+	APITokens map[string]string
+	// AnonymousReads allows requests which don't change the store without a token.
+	AnonymousReads bool
+	// Policy authorizes API clients, as the name of their token or else the common name of their TLS client
+	// certificate, if set. Forwarded writes are authorized again by the leader, as the forwarding node unless they
+	// have a token.
+	Policy *rbac.Policy
+	// AuditRetention is how long the writes made through the API are kept in the store, defaults to 30 days.
+	AuditRetention time.Duration
+	// AuditLog is written a JSON line for each write made through the API, as well as it being recorded in the
+	// store, if set.
+	AuditLog io.Writer
+
+	// StoreBackend is etcd2 or etcd3, defaults to etcd2.
+	StoreBackend string
+	// StoreEndpoints of the etcd cluster.
+	StoreEndpoints []string
+	// StorePrefix to store state under, defaults to /merlin.
+	StorePrefix string
+	// StoreEncoding values are written in, defaults to store.EncodingProto. Values in any encoding are read, so it
+	// can be changed while running, then existing values rewritten with store.Migrate.
+	StoreEncoding store.Encoding
+	// StoreWaitTimeout is how long to retry connecting to the store at startup, 0 to try once.
+	StoreWaitTimeout time.Duration
+	// Store to use instead of connecting to StoreEndpoints.
+	Store store.Store
+	// StoreWriteRate limits the write requests to the store per second, so bulk changes don't destabilize a shared
+	// etcd cluster. 0 is unlimited.
+	StoreWriteRate float64
+	// StoreWriteBurst is how many writes can exceed StoreWriteRate in a burst, defaults to StoreWriteRate.
+	StoreWriteBurst int
+	// StoreBatchSize is how many changes of a snapshot are applied in each write, defaults to 100. It must be within
+	// the operations etcd3 allows in a transaction, which is 128 by default.
+	StoreBatchSize int
+	// SlowRequestThreshold logs API requests and store operations which take at least this long, with the store
+	// operations of each request, 0 to disable.
+	SlowRequestThreshold time.Duration
+
+	// Reconcile local IPVS with the store.
+	Reconcile bool
+	// ReconcileSyncPeriod is how often to periodically sync IPVS, defaults to 1 minute.
+	ReconcileSyncPeriod time.Duration
+	// IPVS to reconcile instead of the kernel's, such as a stub for tests. It's closed when merlin stops.
+	IPVS ipvs.IPVS
+	// HealthMaxSyncAge fails Health if IPVS hasn't been reconciled for this long, defaults to 3 sync periods.
+	HealthMaxSyncAge time.Duration
+	// SyncDaemonInterface manages the IPVS connection sync daemon of this node if set, which multicasts connections
+	// on this interface, e.g. eth0. The daemon is checked every heartbeat, and its role switched by SetSyncRole.
+	SyncDaemonInterface string
+	// SyncDaemonID pairs the sync daemon of a master with those of its backups, from 0 to 255.
+	SyncDaemonID uint32
+	// SyncDaemonRole the sync daemon starts in, MASTER or BACKUP, or unset to not start it until SetSyncRole is
+	// called. Roles set by SetSyncRole aren't kept when merlin restarts.
+	SyncDaemonRole types.SyncDaemon_Role
+	// VIPInterface configures the VIP of every service reconciled onto this node on this interface if set, so the
+	// node accepts their traffic, and deletes the VIPs of services which are deleted. It's created as a dummy
+	// interface if it doesn't exist. Other host addresses on it are deleted, so it must only be used for VIPs, or be
+	// lo.
+	VIPInterface string
+	// VIPs to configure instead of VIPInterface, such as a stub for tests. It's closed when merlin stops.
+	VIPs vip.Interface
+	// BGP announces the VIP of every service reconciled onto this node to routers if set, withdrawing the VIPs of
+	// services which are deleted or have no healthy servers, and every VIP when merlin stops.
+	BGP *bgp.Config
+
+	// NodeName this node registers in the store with, defaults to the hostname.
+	NodeName string
+	// NodeLabels of this node. Only services whose node selector matches them are reconciled onto it.
+	NodeLabels map[string]string
+	// HeartbeatPeriod is how often to register this node in the store, defaults to 10 seconds.
+	HeartbeatPeriod time.Duration
+	// Version reported by this node.
+	Version string
+
+	// LeaderElection elects a leader among merlin nodes, which performs all writes.
+	LeaderElection bool
+	// AdvertiseAddress other nodes forward writes to when this node is the leader, defaults to NodeName:Port.
+	AdvertiseAddress string
+	// NodeToken is a secret shared by the merlin nodes, sent with the writes they forward to the leader so it trusts
+	// which node forwarded them. Without it, the leader can't tell a forwarded write from one a client marked as
+	// forwarded, so it records neither as forwarded.
+	NodeToken string
+	// ActiveElection is the name of an election between the nodes of an active/passive pair, if set. Only the active
+	// node configures and announces the VIPs and runs the master sync daemon, and a passive node takes over within 3
+	// heartbeats of the active node failing, or straight away when it stops.
+	ActiveElection string
+	// PassiveReconcile keeps reconciling IPVS on passive nodes, so a node taking over only has to bring up the VIPs.
+	// Otherwise passive nodes pause reconciling like maintenance until they become active.
+	PassiveReconcile bool
+
+	// DriftAlertSyncs is how many consecutive reconciles a service can drift in before DriftAlert is called.
+	DriftAlertSyncs int
+	// DriftAlert is called for services which persistently drift from the store.
+	DriftAlert reconciler.DriftAlertFunc
+	// ManualChangeAlert is called with each change made to IPVS by something other than merlin, such as ipvsadm,
+	// before it's reverted. The changes are logged whether it's set or not.
+	ManualChangeAlert reconciler.ManualChangeFunc
+
+	// IPVSMetrics exports the traffic counters of every IPVS service and real server to Registerer.
+	IPVSMetrics bool
+	// IPVSMetricLabels are the labels of the IPVS metrics, defaults to every label: LabelServiceID, LabelProtocol,
+	// LabelAddress and LabelServer. Series which only differ by the other labels are summed, so dropping LabelServer
+	// exports one series per service instead of per server.
+	IPVSMetricLabels []string
+	// IPVSMetricIDBuckets hashes service IDs into this many buckets in the IPVS metrics, 0 to export the IDs.
+	IPVSMetricIDBuckets int
+	// Registerer of metrics, defaults to the prometheus default registerer.
+	Registerer prometheus.Registerer
+
+	// SelfTest checks the kernel, capabilities, store and clock at startup, logging a report.
+	SelfTest bool
+	// SelfTestStrict refuses to start if a self-test check fails.
+	SelfTestStrict bool
+
+	// Faults to inject into the store and IPVS, for testing alerting and recovery. Never set in production.
+	Faults *faults.Config
+
+	// Quotas limit the services and servers of each namespace, unlimited by default.
+	Quotas server.Quotas
+	// Limits on the size of services and servers, unlimited by default.
+	Limits validation.Limits
+	// Rules of the site which services and servers must follow, such as those loaded by validation.LoadRules.
+	Rules []validation.Rule
+	// Admit reviews every write before it's committed, such as admission.Webhook.Admit.
+	Admit server.AdmitFunc
+	// IPAM allocates the IPs of services in pools, such as ipam.NetBox, instead of merlin choosing them.
+	IPAM server.IPAM
+
+	// OrphanPolicy for servers whose service no longer exists is OrphanReport, OrphanDelete or OrphanBlock,
+	// defaults to OrphanReport. Orphans are counted by the merlin_orphaned_servers metric.
+	OrphanPolicy string
+	// OrphanGracePeriod is how long servers are orphaned before OrphanDelete deletes them, defaults to 10 minutes,
+	// so a service can be recreated without losing its servers.
+	OrphanGracePeriod time.Duration
+}
+
+func (o *Options) setDefaults() {
+	if o.Mode == "" {
+		o.Mode = ModeAll
+	}
+	if o.StoreBackend == "" {
+		o.StoreBackend = "etcd2"
+	}
+	if o.StorePrefix == "" {
+		o.StorePrefix = "/merlin"
+	}
+	if o.StoreEncoding == "" {
+		o.StoreEncoding = store.EncodingProto
+	}
+	if o.StoreWriteBurst == 0 {
+		o.StoreWriteBurst = int(math.Ceil(o.StoreWriteRate))
+	}
+	if o.StoreBatchSize == 0 {
+		o.StoreBatchSize = 100
+	}
+	if o.ReconcileSyncPeriod == 0 {
+		o.ReconcileSyncPeriod = time.Minute
+	}
+	if o.HealthMaxSyncAge == 0 {
+		o.HealthMaxSyncAge = 3 * o.ReconcileSyncPeriod
+	}
+	if o.NodeName == "" {
+		o.NodeName, _ = os.Hostname()
+	}
+	if o.HeartbeatPeriod == 0 {
+		o.HeartbeatPeriod = 10 * time.Second
+	}
+	if o.TLSReloadPeriod == 0 {
+		o.TLSReloadPeriod = 10 * time.Second
+	}
+	if o.AdvertiseAddress == "" {
+		o.AdvertiseAddress = fmt.Sprintf("%s:%d", o.NodeName, o.Port)
+	}
+	if o.Registerer == nil {
+		o.Registerer = prometheus.DefaultRegisterer
+	}
+	if o.OrphanPolicy == "" {
+		o.OrphanPolicy = OrphanReport
+	}
+	if o.OrphanGracePeriod == 0 {
+		o.OrphanGracePeriod = 10 * time.Minute
+	}
+	if o.AuditRetention == 0 {
+		o.AuditRetention = 30 * 24 * time.Hour
+	}
+}
+
+func (o *Options) validate() error {
+	switch o.Mode {
+	case ModeAll:
+	case ModeAgent:
+		if !o.Reconcile {
+			return errors.New("agent mode requires reconciling, as agents only reconcile")
+		}
+		if o.LeaderElection {
+			return errors.New("agent mode can't be used with leader election, as agents don't serve writes")
+		}
+	default:
+		return fmt.Errorf("unknown mode %q, must be %s or %s", o.Mode, ModeAll, ModeAgent)
+	}
+	if (o.TLSCertFile == "") != (o.TLSKeyFile == "") {
+		return errors.New("tls cert and key must be set together")
+	}
+	if o.TLSRenewCommand != "" && o.TLSCertFile == "" {
+		return errors.New("tls renew command requires a tls cert and key to write")
+	}
+	if o.TLSClientCAFile != "" && o.TLSCertFile == "" {
+		return errors.New("tls client ca requires a tls cert and key to serve the API with")
+	}
+	if err := validateTokens(o.APITokens); err != nil {
+		return err
+	}
+	if o.Store == nil && o.StoreBackend != "etcd2" && o.StoreBackend != "etcd3" {
+		return fmt.Errorf("unknown store backend: %s", o.StoreBackend)
+	}
+	if _, err := store.ParseEncoding(string(o.StoreEncoding)); err != nil {
+		return err
+	}
+	if o.StoreWriteRate < 0 || o.StoreWriteBurst < 0 || o.StoreBatchSize < 0 {
+		return errors.New("store write rate, burst and batch size can't be negative")
+	}
+	if o.SlowRequestThreshold < 0 {
+		return errors.New("slow request threshold can't be negative")
+	}
+	if o.SyncDaemonInterface != "" && !o.Reconcile {
+		return errors.New("the sync daemon requires reconciling, as it's managed through ipvs")
+	}
+	if o.VIPInterface != "" && !o.Reconcile {
+		return errors.New("the vip interface requires reconciling, as vips are configured for ipvs")
+	}
+	if o.BGP != nil && !o.Reconcile {
+		return errors.New("bgp requires reconciling, as vips are announced for ipvs")
+	}
+	if o.ActiveElection != "" && !o.Reconcile {
+		return errors.New("an active election requires reconciling, as the active node is the one reconciling ipvs")
+	}
+	if o.ActiveElection != "" && o.SyncDaemonRole != types.SyncDaemon_UNSET_SYNC_ROLE {
+		return errors.New("a sync daemon role can't be set with an active election, as it follows the election")
+	}
+	if o.SyncDaemonRole != types.SyncDaemon_UNSET_SYNC_ROLE && o.SyncDaemonInterface == "" {
+		return errors.New("a sync daemon role requires a sync daemon interface")
+	}
+	if _, ok := types.SyncDaemon_Role_name[int32(o.SyncDaemonRole)]; !ok {
+		return fmt.Errorf("unknown sync daemon role %v", o.SyncDaemonRole)
+	}
+	if o.SyncDaemonID > 255 {
+		return fmt.Errorf("sync daemon ID %d must be from 0 to 255", o.SyncDaemonID)
+	}
+	if err := validateMetricLabels(o.IPVSMetricLabels); err != nil {
+		return err
+	}
+	if o.IPVSMetricIDBuckets < 0 {
+		return errors.New("ipvs metric ID buckets can't be negative")
+	}
+	if err := types.ValidateLabels(o.NodeLabels); err != nil {
+		return fmt.Errorf("invalid node labels: %v", err)
+	}
+	switch o.OrphanPolicy {
+	case OrphanReport, OrphanDelete, OrphanBlock:
+	default:
+		return fmt.Errorf("unknown orphan policy %q, must be %s, %s or %s", o.OrphanPolicy, OrphanReport,
+			OrphanDelete, OrphanBlock)
+	}
+	return nil
+}
+
+// Daemon is a merlin instance.
+type Daemon struct {
+	opts            Options
+	grpcServer      *grpc.Server
+	httpServer      *http.Server
+	grpcOverHTTP    bool
+	mux             *muxListener
+	ipvs            ipvs.IPVS
+	reconciler      reconciler.Reconciler
+	store           store.Store
+	collector       prometheus.Collector
+	healthMetrics   prometheus.Collector
+	orphans         *orphanChecker
+	syncDaemon      *syncDaemonManager
+	vips            *vipManager
+	bgp             *bgpAnnouncer
+	subscribeStopCh chan struct{}
+	heartbeatStopCh chan struct{}
+	heartbeatDoneCh chan struct{}
+	certs           *certReloader
+	clientCAs       *x509.CertPool
+	tlsStopCh       chan struct{}
+	forwardCreds    grpc.DialOption
+	api             types.MerlinServer
+	interceptor     grpc.UnaryServerInterceptor
+	health          *health.Server
+	healthStopCh    chan struct{}
+	auditMu         sync.Mutex
+	started         time.Time
+	leadership      leadership
+	active          *activePassive
+}
+
+// New merlin instance with the given options, which is run with Start.
+func New(opts Options) *Daemon {
+	opts.setDefaults()
+	return &Daemon{opts: opts}
+}
+
+// Reconciler of local IPVS, which is a stub if reconciling is disabled. It's nil until started.
+func (d *Daemon) Reconciler() reconciler.Reconciler {
+	return d.reconciler
+}
+
+// Store merlin is using. It's nil until started.
+func (d *Daemon) Store() store.Store {
+	return d.store
+}
+
+// Health returns an error if the store is unreachable, its watch is failing, or IPVS hasn't been reconciled
+// within HealthMaxSyncAge. Reconciles aren't checked while paused for maintenance.
+func (d *Daemon) Health() error {
+	if err := d.checkStore(); err != nil {
+		return err
+	}
+	if err := d.store.WatchError(); err != nil {
+		return fmt.Errorf("store watch is failing: %v", err)
+	}
+
+	state := d.reconciler.State()
+	if !d.opts.Reconcile || state.Paused {
+		return nil
+	}
+	lastSync := state.LastSync
+	if lastSync.IsZero() {
+		lastSync = d.started
+	}
+	if age := time.Since(lastSync); age > d.opts.HealthMaxSyncAge {
+		return fmt.Errorf("ipvs hasn't been reconciled for %v", age.Round(time.Second))
+	}
+	return nil
+}
+
+// Ready returns an error until the store is reachable and IPVS has been reconciled with it.
+func (d *Daemon) Ready() error {
+	if err := d.checkStore(); err != nil {
+		return err
+	}
+	if d.opts.Reconcile && d.reconciler.State().LastSync.IsZero() {
+		return errors.New("ipvs hasn't been reconciled with the store yet")
+	}
+	return nil
+}
+
+func (d *Daemon) checkStore() error {
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	defer cancel()
+	if _, err := d.store.GetMaintenance(ctx, d.opts.NodeName); err != nil {
+		return fmt.Errorf("store is unreachable: %v", err)
+	}
+	return nil
+}
+
+// Start merlin, returning once it's serving the API and reconciling in the background.
+func (d *Daemon) Start() (err error) {
+	lis := d.opts.Listener
+	defer func() {
+		if err != nil && lis != nil {
+			lis.Close()
+		}
+	}()
+	if err := d.opts.validate(); err != nil {
+		return err
+	}
+	if d.opts.Mode != ModeAgent && lis == nil {
+		if lis, err = net.Listen("tcp", fmt.Sprintf(":%d", d.opts.Port)); err != nil {
+			return fmt.Errorf("failed to listen: %v", err)
+		}
+	}
+	if err := d.loadTLS(); err != nil {
+		return err
+	}
+	log.Infof("Starting merlin in %s mode", d.opts.Mode)
+	d.started = time.Now()
+
+	st, err := d.connectStore()
+	if err != nil {
+		return fmt.Errorf("unable to start store client: %v", err)
+	}
+	if d.opts.SelfTest {
+		if err := d.runSelfTest(st); err != nil && d.opts.SelfTestStrict {
+			return fmt.Errorf("refusing to start: %v", err)
+		} else if err != nil {
+			log.Warnf("Starting despite failures: %v", err)
+		}
+	}
+	if d.opts.StoreWriteRate > 0 {
+		st = store.RateLimit(st, d.opts.StoreWriteRate, d.opts.StoreWriteBurst)
+	}
+	if d.opts.Faults != nil {
+		st = faults.Store(st, *d.opts.Faults)
+	}
+	if d.opts.SlowRequestThreshold > 0 {
+		st = store.Timed(st, d.opts.SlowRequestThreshold)
+	}
+
+	if d.opts.Reconcile {
+		i := d.opts.IPVS
+		if i == nil {
+			if i, err = ipvs.New(); err != nil {
+				return fmt.Errorf("unable to init IPVS: %v", err)
+			}
+		}
+		if d.opts.Faults != nil {
+			i = faults.IPVS(i, *d.opts.Faults)
+		}
+
+		d.ipvs = i
+		nodeStore := reconciler.ForNode(st, d.opts.NodeName, d.opts.NodeLabels)
+		d.reconciler = reconciler.New(d.opts.ReconcileSyncPeriod, nodeStore, i)
+		if d.opts.ActiveElection != "" {
+			if err := d.startActiveElection(st); err != nil {
+				return err
+			}
+		}
+		if err := d.opts.Registerer.Register(d.reconciler.HealthCheckMetrics()); err != nil {
+			return fmt.Errorf("unable to register health check metrics: %v", err)
+		}
+		d.healthMetrics = d.reconciler.HealthCheckMetrics()
+		if d.opts.IPVSMetrics {
+			collector := newIPVSCollector(i, st, d.opts.IPVSMetricLabels, d.opts.IPVSMetricIDBuckets)
+			if err := d.opts.Registerer.Register(collector); err != nil {
+				return fmt.Errorf("unable to register ipvs metrics: %v", err)
+			}
+			d.collector = collector
+		}
+		if d.opts.VIPInterface != "" {
+			vips := d.opts.VIPs
+			if vips == nil {
+				if vips, err = vip.New(d.opts.VIPInterface); err != nil {
+					return fmt.Errorf("unable to init vip interface: %v", err)
+				}
+			}
+			d.vips = newVIPManager(vips, nodeStore, d.opts.ReconcileSyncPeriod, func() bool {
+				return d.reconciler.State().Paused
+			}, d.IsActive)
+		}
+		if d.opts.BGP != nil {
+			speaker, err := bgp.New(*d.opts.BGP)
+			if err != nil {
+				return fmt.Errorf("unable to start bgp: %v", err)
+			}
+			d.bgp = newBGPAnnouncer(speaker, nodeStore, d.reconciler.Health, d.opts.ReconcileSyncPeriod,
+				d.IsActive)
+			if err := d.opts.Registerer.Register(d.bgp.peerUp); err != nil {
+				return fmt.Errorf("unable to register bgp metrics: %v", err)
+			}
+		}
+		if d.opts.SyncDaemonInterface != "" {
+			role := d.opts.SyncDaemonRole
+			if d.opts.ActiveElection != "" {
+				role = types.SyncDaemon_BACKUP
+				if d.IsActive() {
+					role = types.SyncDaemon_MASTER
+				}
+			}
+			d.syncDaemon = newSyncDaemonManager(i, d.opts.SyncDaemonInterface, d.opts.SyncDaemonID, role)
+		}
+	} else {
+		d.reconciler = reconciler.NewStub()
+	}
+
+	if d.opts.Mode == ModeAll {
+		d.orphans = newOrphanChecker(d.opts.OrphanPolicy, d.opts.OrphanGracePeriod)
+		if err := d.opts.Registerer.Register(d.orphans.gauge); err != nil {
+			return fmt.Errorf("unable to register orphan metrics: %v", err)
+		}
+	}
+
+	if d.opts.DriftAlertSyncs > 0 && d.opts.DriftAlert != nil {
+		d.reconciler.SetDriftAlert(d.opts.DriftAlertSyncs, d.opts.DriftAlert)
+	}
+	if d.opts.ManualChangeAlert != nil {
+		d.reconciler.SetManualChangeAlert(d.opts.ManualChangeAlert)
+	}
+	if err := d.reconciler.Start(); err != nil {
+		return fmt.Errorf("unable to start reconciler: %v", err)
+	}
+	d.reconciler.Sync()
+	if d.vips != nil {
+		d.vips.start()
+	}
+	if d.bgp != nil {
+		d.bgp.start()
+	}
+
+	d.subscribeStopCh = make(chan struct{})
+	st.Subscribe(func() {
+		log.Info("Store updated, starting sync")
+		d.reconciler.Sync()
+		if d.vips != nil {
+			d.vips.sync()
+		}
+		if d.bgp != nil {
+			d.bgp.sync()
+		}
+	}, d.subscribeStopCh)
+
+	d.store = st
+	d.heartbeatStopCh = make(chan struct{})
+	d.heartbeatDoneCh = make(chan struct{})
+	go d.heartbeat()
+
+	if d.opts.Mode == ModeAgent {
+		return nil
+	}
+	apiOpts := server.Options{
+		Quotas:         d.opts.Quotas,
+		Limits:         d.opts.Limits,
+		BlockOrphans:   d.opts.OrphanPolicy == OrphanBlock,
+		Rules:          d.opts.Rules,
+		Admit:          d.opts.Admit,
+		IPAM:           d.opts.IPAM,
+		ApplyBatchSize: d.opts.StoreBatchSize,
+	}
+	if d.syncDaemon != nil {
+		apiOpts.SetSyncRole = d.syncDaemon.setRole
+	}
+	srv := server.New(st, d.ipvs, d.node, d.reconciler.Health, d.reconciler.Errors, apiOpts)
+
+	d.api = srv
+	d.interceptor = unaryInterceptors(advertiseVersion, logRequests, d.logSlowRequests, d.trustForwarded,
+		d.authenticate, d.audit, d.authorize, warnDeprecated, server.DryRun(srv), d.forwardWrites,
+		server.StageCandidate(srv))
+	serverOpts := []grpc.ServerOption{
+		grpc.UnaryInterceptor(d.interceptor),
+		grpc.StreamInterceptor(d.interceptStream),
+	}
+	if d.certs != nil {
+		d.tlsStopCh = make(chan struct{})
+		go d.certs.watch(d.opts.TLSReloadPeriod, d.tlsStopCh)
+		// TLS is terminated by the HTTP server when it's sharing the port
+		if d.opts.HTTPHandler == nil {
+			serverOpts = append(serverOpts, grpc.Creds(credentials.NewTLS(d.serverTLSConfig())))
+		}
+	}
+	d.grpcServer = grpc.NewServer(serverOpts...)
+	types.RegisterMerlinServer(d.grpcServer, srv)
+	d.registerHealth()
+	reflection.Register(d.grpcServer)
+	d.serve(lis)
+	return nil
+}
+
+// Invoke calls the API method of the given name, such as CreateService, in process. The request passes through the
+// same authentication, authorization, auditing and forwarding as requests to the gRPC server, with ctx's incoming
+// metadata as its headers.
+func (d *Daemon) Invoke(ctx context.Context, method string, req proto.Message) (interface{}, error) {
+	if d.api == nil {
+		return nil, status.Error(codes.Unavailable, "the API isn't served")
+	}
+	info := &grpc.UnaryServerInfo{Server: d.api, FullMethod: "/types.Merlin/" + method}
+	return d.interceptor(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return invoke(ctx, d.api, method, req)
+	})
+}
+
+// serverTLSConfig returns the TLS config the API is served with, which requires clients to present a certificate
+// signed by the client CA if it's set.
+func (d *Daemon) serverTLSConfig() *tls.Config {
+	config := &tls.Config{GetCertificate: d.certs.GetCertificate}
+	if d.clientCAs != nil {
+		config.ClientCAs = d.clientCAs
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config
+}
+
+// loadTLS loads the certificate the API is served with, and the CAs clients and the leader are verified with.
+func (d *Daemon) loadTLS() error {
+	d.forwardCreds = grpc.WithInsecure()
+	if d.opts.Mode == ModeAgent || d.opts.TLSCertFile == "" {
+		return nil
+	}
+	certs, err := newCertReloader(d.opts.TLSCertFile, d.opts.TLSKeyFile, d.opts.TLSRenewCommand,
+		d.opts.TLSRenewBefore)
+	if err != nil {
+		return fmt.Errorf("unable to load tls certificate: %v", err)
+	}
+	roots, err := certPool(d.opts.TLSCAFile)
+	if err != nil {
+		return fmt.Errorf("unable to load tls ca: %v", err)
+	}
+	d.certs = certs
+	forwardTLS := &tls.Config{RootCAs: roots}
+	if d.opts.TLSClientCAFile != "" {
+		if d.clientCAs, err = certPool(d.opts.TLSClientCAFile); err != nil {
+			return fmt.Errorf("unable to load tls client ca: %v", err)
+		}
+		forwardTLS.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return certs.GetCertificate(nil)
+		}
+	}
+	d.forwardCreds = grpc.WithTransportCredentials(credentials.NewTLS(forwardTLS))
+	return nil
+}
+
+// connectStore creates the store client, retrying with backoff for up to StoreWaitTimeout while the store is
+// unreachable, so merlin starts cleanly when it races the store at boot.
+func (d *Daemon) connectStore() (store.Store, error) {
+	if d.opts.Store != nil {
+		return d.opts.Store, nil
+	}
+
+	var etcdStore store.Store
+	connect := func() error {
+		var err error
+		etcdStore, err = store.NewStore(d.opts.StoreBackend, d.opts.StoreEndpoints, d.opts.StorePrefix,
+			d.opts.StoreEncoding)
+		return err
+	}
+	if d.opts.StoreWaitTimeout == 0 {
+		return etcdStore, connect()
+	}
+
+	b := backoff.NewExponentialBackOff()
+	b.MaxElapsedTime = d.opts.StoreWaitTimeout
+	err := backoff.RetryNotify(connect, b, func(err error, wait time.Duration) {
+		log.Warnf("Unable to connect to the store, retrying in %v: %v", wait.Round(time.Millisecond), err)
+	})
+	return etcdStore, err
+}
+
+// node returns the current state of this merlin instance.
+func (d *Daemon) node() *types.Node {
+	state := d.reconciler.State()
+	node := &types.Node{
+		Name:           d.opts.NodeName,
+		Version:        d.opts.Version,
+		StoreBackend:   d.opts.StoreBackend,
+		Reconcile:      d.opts.Reconcile,
+		LastHeartbeat:  ptypes.TimestampNow(),
+		Maintenance:    state.Paused,
+		Drift:          uint32(state.Drift),
+		Leader:         d.opts.LeaderElection && d.IsLeader(),
+		Passive:        !d.IsActive(),
+		Mode:           d.opts.Mode,
+		Labels:         d.opts.NodeLabels,
+		FailedServices: state.FailedServices,
+	}
+	if d.opts.Mode != ModeAgent {
+		node.Address = d.opts.AdvertiseAddress
+	}
+	if !state.LastSync.IsZero() {
+		node.LastSync, _ = ptypes.TimestampProto(state.LastSync)
+	}
+	return node
+}
+
+// heartbeat registers this node in the store, campaigns to be active, checks its maintenance state and sync daemon,
+// campaigns for leader, progresses rollouts, checks for orphaned servers and rolls back unconfirmed commits until
+// stopped, when it resigns leadership and as active.
+func (d *Daemon) heartbeat() {
+	defer close(d.heartbeatDoneCh)
+	period := d.opts.HeartbeatPeriod
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), period)
+		if d.opts.ActiveElection != "" {
+			d.campaignActive(ctx, d.store)
+		}
+		if paused, err := d.store.GetMaintenance(ctx, d.opts.NodeName); err != nil {
+			log.Warnf("Unable to check maintenance: %v", err)
+		} else if paused = paused || d.pausedWhilePassive(); paused != d.reconciler.State().Paused {
+			d.reconciler.SetPaused(paused)
+			if !paused {
+				d.reconciler.Sync()
+				if d.vips != nil {
+					d.vips.sync()
+				}
+			}
+		}
+		if d.syncDaemon != nil {
+			if err := d.syncDaemon.ensure(ctx); err != nil {
+				log.Warnf("Unable to check the sync daemon: %v", err)
+			}
+		}
+		if d.opts.LeaderElection {
+			d.campaign(ctx)
+		}
+		if err := d.store.PutNode(ctx, d.node(), 3*period); err != nil {
+			log.Warnf("Unable to register node: %v", err)
+		}
+		if d.opts.Mode == ModeAll && (!d.opts.LeaderElection || d.IsLeader()) {
+			if err := rollout.Check(ctx, d.store, time.Now()); err != nil {
+				log.Warnf("Unable to check rollouts: %v", err)
+			}
+			if err := d.orphans.check(ctx, d.store, time.Now()); err != nil {
+				log.Warnf("Unable to check orphaned servers: %v", err)
+			}
+			if err := server.CheckCommit(ctx, d.store, d.node, time.Now()); err != nil {
+				log.Warnf("Unable to check commit: %v", err)
+			}
+		}
+		cancel()
+
+		select {
+		case <-ticker.C:
+		case <-d.heartbeatStopCh:
+			d.resign()
+			d.resignActive()
+			return
+		}
+	}
+}
+
+// Stop merlin, waiting up to timeout in total for in-flight requests and reconciles to finish. Requests still
+// in-flight after the timeout are cancelled.
+func (d *Daemon) Stop(timeout time.Duration) error {
+	deadline := time.After(timeout)
+	d.stopHealth()
+	close(d.heartbeatStopCh)
+	close(d.subscribeStopCh)
+	// wait for leadership to be resigned, so writes move to the new leader while requests finish
+	waitUntil(func() { <-d.heartbeatDoneCh }, deadline)
+
+	d.stopServing(timeout, deadline)
+	d.closeLeaderConns()
+	if d.tlsStopCh != nil {
+		close(d.tlsStopCh)
+	}
+	if d.collector != nil {
+		d.opts.Registerer.Unregister(d.collector)
+	}
+	if d.healthMetrics != nil {
+		d.opts.Registerer.Unregister(d.healthMetrics)
+	}
+	if d.orphans != nil {
+		d.opts.Registerer.Unregister(d.orphans.gauge)
+	}
+	if d.active != nil {
+		d.opts.Registerer.Unregister(d.active.gauge)
+		d.opts.Registerer.Unregister(d.active.changes)
+	}
+	if d.vips != nil {
+		d.vips.stop()
+	}
+	if d.bgp != nil {
+		d.opts.Registerer.Unregister(d.bgp.peerUp)
+		d.bgp.stop()
+	}
+	if !waitUntil(d.reconciler.Stop, deadline) {
+		// ipvs is left open, as it's still in use
+		return fmt.Errorf("timed out after %v waiting for reconcile to finish", timeout)
+	}
+	if d.ipvs != nil {
+		d.ipvs.Close()
+	}
+	log.Infof("Stopped merlin")
+	return nil
+}
+
+// waitUntil calls fn, returning false if it hasn't returned by the deadline.
+func waitUntil(fn func(), deadline <-chan time.Time) bool {
+	done := make(chan struct{})
+	go func() {
+		fn()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-deadline:
+		return false
+	}
+}
+
+// advertiseVersion sends the API version in the response headers, so clients can detect version skew.
+func advertiseVersion(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	grpc.SetHeader(ctx, metadata.Pairs(types.APIVersionHeader, types.APIVersion))
+	return handler(ctx, req)
+}
+
+// warnDeprecated sends a warning in the response headers for each deprecated RPC or field the request uses.
+func warnDeprecated(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	for _, warning := range types.DeprecationWarnings(info.FullMethod, req) {
+		log.Debugf("%s used a deprecated feature: %s", path.Base(info.FullMethod), warning)
+		grpc.SetHeader(ctx, metadata.Pairs(types.WarningHeader, warning))
+	}
+	return handler(ctx, req)
+}
+
+func logRequests(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	resp, err := handler(ctx, req)
+	// catch any internal errors and wrap in the correct status code
+	if _, ok := status.FromError(err); !ok {
+		log.Error(err)
+		err = status.Errorf(codes.Internal, "%v", err)
+	}
+	return resp, err
+}
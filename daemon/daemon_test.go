@@ -0,0 +1,243 @@
+package daemon
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sky-uk/merlin/ipvs"
+	"github.com/sky-uk/merlin/store"
+	"github.com/sky-uk/merlin/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+)
+
+func TestDaemon(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Daemon Suite")
+}
+
+type fakeStore struct {
+	store.Store
+	mu    sync.Mutex
+	nodes []*types.Node
+}
+
+func (s *fakeStore) GetMaintenance(context.Context, string) (bool, error) {
+	return false, nil
+}
+
+func (s *fakeStore) WatchError() error {
+	return nil
+}
+
+func (s *fakeStore) Subscribe(func(), <-chan struct{}) {}
+
+func (s *fakeStore) ListServices(context.Context) ([]*types.VirtualService, error) {
+	return nil, nil
+}
+
+func (s *fakeStore) GetCandidate(context.Context) (*types.Candidate, error) {
+	return &types.Candidate{}, nil
+}
+
+func (s *fakeStore) ListAllServers(context.Context) ([]*types.RealServer, error) {
+	return nil, nil
+}
+
+func (s *fakeStore) PutNode(_ context.Context, node *types.Node, _ time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nodes = append(s.nodes, node)
+	return nil
+}
+
+func (s *fakeStore) registered() []*types.Node {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.nodes
+}
+
+type fakeIPVS struct {
+	ipvs.IPVS
+}
+
+func (fakeIPVS) ListServices(context.Context) ([]*types.VirtualService, error) {
+	return nil, nil
+}
+
+func (fakeIPVS) Close() {}
+
+var _ = Describe("Daemon", func() {
+	var (
+		st   *fakeStore
+		opts Options
+	)
+
+	BeforeEach(func() {
+		st = &fakeStore{}
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).ToNot(HaveOccurred())
+		opts = Options{
+			Listener:   lis,
+			Store:      st,
+			IPVS:       fakeIPVS{},
+			Reconcile:  true,
+			NodeName:   "node1",
+			Version:    "1.0.0",
+			Registerer: prometheus.NewRegistry(),
+		}
+	})
+
+	It("should reconcile and register the node with the given store and ipvs", func() {
+		d := New(opts)
+
+		Expect(d.Start()).To(Succeed())
+		defer d.Stop(time.Second)
+
+		Eventually(d.Ready).Should(Succeed())
+		Expect(d.Health()).To(Succeed())
+		Eventually(st.registered).ShouldNot(BeEmpty())
+		node := st.registered()[0]
+		Expect(node.Name).To(Equal("node1"))
+		Expect(node.Version).To(Equal("1.0.0"))
+		Expect(node.Mode).To(Equal(ModeAll))
+		Expect(node.Address).To(Equal("node1:0"))
+	})
+
+	It("should stop", func() {
+		d := New(opts)
+		Expect(d.Start()).To(Succeed())
+
+		Expect(d.Stop(time.Second)).To(Succeed())
+	})
+
+	It("should be the leader without leader election", func() {
+		d := New(opts)
+
+		Expect(d.IsLeader()).To(BeTrue())
+	})
+
+	It("should refuse to run as an agent without reconciling", func() {
+		opts.Mode = ModeAgent
+		opts.Reconcile = false
+
+		Expect(New(opts).Start()).To(MatchError(ContainSubstring("agent mode requires reconciling")))
+	})
+
+	It("should refuse an unknown mode", func() {
+		opts.Mode = "unknown"
+
+		Expect(New(opts).Start()).To(MatchError(ContainSubstring(`unknown mode "unknown"`)))
+	})
+
+	It("should refuse an unknown orphan policy", func() {
+		opts.OrphanPolicy = "unknown"
+
+		Expect(New(opts).Start()).To(MatchError(ContainSubstring(`unknown orphan policy "unknown"`)))
+	})
+
+	It("should refuse an unknown store encoding", func() {
+		opts.StoreEncoding = "unknown"
+
+		Expect(New(opts).Start()).To(MatchError(ContainSubstring("unknown store encoding: unknown")))
+	})
+
+	It("should invoke API methods in process through the interceptors", func() {
+		opts.APITokens = map[string]string{"deploy": "s3cret"}
+		d := New(opts)
+		_, err := d.Invoke(context.Background(), "List", &types.ListRequest{})
+		Expect(status.Code(err)).To(Equal(codes.Unavailable), "not started")
+		Expect(d.Start()).To(Succeed())
+		defer d.Stop(time.Second)
+
+		_, err = d.Invoke(context.Background(), "List", &types.ListRequest{})
+		Expect(status.Code(err)).To(Equal(codes.Unauthenticated))
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer s3cret"))
+		resp, err := d.Invoke(ctx, "List", &types.ListRequest{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp).To(BeAssignableToTypeOf(&types.ListResponse{}))
+	})
+
+	It("should serve HTTP on the API port alongside gRPC", func() {
+		opts.HTTPHandler = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.Write([]byte("ok"))
+		})
+		addr := opts.Listener.Addr().String()
+		d := New(opts)
+		Expect(d.Start()).To(Succeed())
+
+		conn, err := grpc.Dial(addr, grpc.WithInsecure())
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.Close()
+		_, err = types.NewMerlinClient(conn).List(context.Background(), &types.ListRequest{})
+		Expect(err).ToNot(HaveOccurred())
+
+		resp, err := http.Get("http://" + addr + "/health")
+		Expect(err).ToNot(HaveOccurred())
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(body)).To(Equal("ok"))
+
+		Expect(d.Stop(time.Second)).To(Succeed())
+		_, err = net.Dial("tcp", addr)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should serve the gRPC health service without a token, and reflection", func() {
+		opts.APITokens = map[string]string{"deploy": "s3cret"}
+		d := New(opts)
+		Expect(d.Start()).To(Succeed())
+		defer d.Stop(time.Second)
+		conn, err := grpc.Dial(opts.Listener.Addr().String(), grpc.WithInsecure())
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.Close()
+
+		check := func() healthpb.HealthCheckResponse_ServingStatus {
+			resp, err := healthpb.NewHealthClient(conn).Check(context.Background(),
+				&healthpb.HealthCheckRequest{Service: "types.Merlin"})
+			Expect(err).ToNot(HaveOccurred())
+			return resp.Status
+		}
+		Eventually(check, 3*time.Second).Should(Equal(healthpb.HealthCheckResponse_SERVING))
+
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer s3cret")
+		stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(stream.Send(&reflectionpb.ServerReflectionRequest{
+			MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+		})).To(Succeed())
+		resp, err := stream.Recv()
+		Expect(err).ToNot(HaveOccurred())
+		var services []string
+		for _, svc := range resp.GetListServicesResponse().GetService() {
+			services = append(services, svc.Name)
+		}
+		Expect(services).To(ContainElement("types.Merlin"))
+		stream.CloseSend()
+	})
+
+	It("should refuse a tls certificate without a key", func() {
+		opts.TLSCertFile = "tls.crt"
+
+		Expect(New(opts).Start()).To(MatchError(ContainSubstring("tls cert and key must be set together")))
+	})
+
+	It("should refuse a tls client ca without a certificate", func() {
+		opts.TLSClientCAFile = "ca.crt"
+
+		Expect(New(opts).Start()).To(MatchError(ContainSubstring("tls client ca requires a tls cert")))
+	})
+})
@@ -0,0 +1,69 @@
+package daemon
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// healthUpdatePeriod is how often the status reported by the gRPC health service is updated, about as often as load
+// balancers check it.
+const healthUpdatePeriod = time.Second
+
+// apiService is the name of the API in the gRPC health service.
+const apiService = "types.Merlin"
+
+// healthMethods of the gRPC health service need no token or permission, like /health and /ready.
+var healthMethods = map[string]bool{
+	"/grpc.health.v1.Health/Check": true,
+	"/grpc.health.v1.Health/Watch": true,
+}
+
+// registerHealth registers the standard gRPC health service, which reports merlin and its API as serving while it's
+// ready, the same as /ready, so load balancers and generic tooling can health check the API.
+func (d *Daemon) registerHealth() {
+	d.health = health.NewServer()
+	d.setServingStatus(healthpb.HealthCheckResponse_NOT_SERVING)
+	healthpb.RegisterHealthServer(d.grpcServer, d.health)
+	d.healthStopCh = make(chan struct{})
+	go d.updateHealth()
+}
+
+func (d *Daemon) updateHealth() {
+	ticker := time.NewTicker(healthUpdatePeriod)
+	defer ticker.Stop()
+	serving := false
+	for {
+		err := d.Ready()
+		if err == nil && !serving {
+			d.setServingStatus(healthpb.HealthCheckResponse_SERVING)
+		} else if err != nil && serving {
+			log.Warnf("Reporting the API as not serving: %v", err)
+			d.setServingStatus(healthpb.HealthCheckResponse_NOT_SERVING)
+		}
+		serving = err == nil
+
+		select {
+		case <-ticker.C:
+		case <-d.healthStopCh:
+			return
+		}
+	}
+}
+
+func (d *Daemon) setServingStatus(status healthpb.HealthCheckResponse_ServingStatus) {
+	d.health.SetServingStatus("", status)
+	d.health.SetServingStatus(apiService, status)
+}
+
+// stopHealth reports the API as not serving from now on, so load balancers stop sending requests while in-flight
+// ones finish.
+func (d *Daemon) stopHealth() {
+	if d.health == nil {
+		return
+	}
+	close(d.healthStopCh)
+	d.health.Shutdown()
+}
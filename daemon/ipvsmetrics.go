@@ -0,0 +1,226 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+	"github.com/sky-uk/merlin/ipvs"
+	"github.com/sky-uk/merlin/store"
+	"github.com/sky-uk/merlin/types"
+)
+
+// Labels of the IPVS metrics.
+const (
+	LabelServiceID = "service_id"
+	LabelProtocol  = "protocol"
+	LabelAddress   = "address"
+	LabelServer    = "server"
+)
+
+var (
+	serviceLabels = []string{LabelServiceID, LabelProtocol, LabelAddress}
+	serverLabels  = []string{LabelServiceID, LabelProtocol, LabelAddress, LabelServer}
+)
+
+// statsMetric is a metric of the IPVS counters of services and servers.
+type statsMetric struct {
+	valueType prometheus.ValueType
+	name      string
+	help      string
+	value     func(*types.Stats) uint64
+}
+
+var statsMetrics = []*statsMetric{
+	{valueType: prometheus.CounterValue, name: "connections_total", help: "Connections scheduled",
+		value: (*types.Stats).GetConnections},
+	{valueType: prometheus.CounterValue, name: "packets_in_total", help: "Incoming packets",
+		value: (*types.Stats).GetPacketsIn},
+	{valueType: prometheus.CounterValue, name: "packets_out_total", help: "Outgoing packets",
+		value: (*types.Stats).GetPacketsOut},
+	{valueType: prometheus.CounterValue, name: "bytes_in_total", help: "Incoming bytes",
+		value: (*types.Stats).GetBytesIn},
+	{valueType: prometheus.CounterValue, name: "bytes_out_total", help: "Outgoing bytes",
+		value: (*types.Stats).GetBytesOut},
+	{valueType: prometheus.GaugeValue, name: "connections_per_second",
+		help: "Kernel estimate of connections per second", value: (*types.Stats).GetCps},
+	{valueType: prometheus.GaugeValue, name: "packets_in_per_second",
+		help: "Kernel estimate of incoming packets per second", value: (*types.Stats).GetPpsIn},
+	{valueType: prometheus.GaugeValue, name: "packets_out_per_second",
+		help: "Kernel estimate of outgoing packets per second", value: (*types.Stats).GetPpsOut},
+	{valueType: prometheus.GaugeValue, name: "bytes_in_per_second",
+		help: "Kernel estimate of incoming bytes per second", value: (*types.Stats).GetBpsIn},
+	{valueType: prometheus.GaugeValue, name: "bytes_out_per_second",
+		help: "Kernel estimate of outgoing bytes per second", value: (*types.Stats).GetBpsOut},
+}
+
+// ipvsCollector exports the IPVS counters of every service and server when scraped. service_id is the ID of the
+// matching service in the store, empty if the service isn't managed by merlin.
+//
+// Only the collector's labels are exported, and series which only differ by the other labels are summed, so large
+// estates can drop per-server labels. If idBuckets is set, service IDs are hashed into that many buckets.
+type ipvsCollector struct {
+	ipvs      ipvs.IPVS
+	store     store.Store
+	labels    map[string]bool
+	idBuckets int
+
+	serviceMetrics      map[*statsMetric]*prometheus.Desc
+	serverMetrics       map[*statsMetric]*prometheus.Desc
+	activeConnections   *prometheus.Desc
+	inactiveConnections *prometheus.Desc
+	serverWeight        *prometheus.Desc
+}
+
+// newIPVSCollector exports the labels of the IPVS metrics, or every label if labels is empty.
+func newIPVSCollector(i ipvs.IPVS, st store.Store, labels []string, idBuckets int) *ipvsCollector {
+	c := &ipvsCollector{
+		ipvs:           i,
+		store:          st,
+		labels:         make(map[string]bool),
+		idBuckets:      idBuckets,
+		serviceMetrics: make(map[*statsMetric]*prometheus.Desc),
+		serverMetrics:  make(map[*statsMetric]*prometheus.Desc),
+	}
+	if len(labels) == 0 {
+		labels = serverLabels
+	}
+	for _, label := range labels {
+		c.labels[label] = true
+	}
+	svcLabels, srvLabels := c.exported(serviceLabels, serviceLabels), c.exported(serverLabels, serverLabels)
+	for _, m := range statsMetrics {
+		c.serviceMetrics[m] = prometheus.NewDesc("merlin_ipvs_service_"+m.name, m.help+" by the virtual service.",
+			svcLabels, nil)
+		c.serverMetrics[m] = prometheus.NewDesc("merlin_ipvs_server_"+m.name, m.help+" by the real server.",
+			srvLabels, nil)
+	}
+	c.activeConnections = prometheus.NewDesc("merlin_ipvs_server_active_connections",
+		"Established connections to the real server.", srvLabels, nil)
+	c.inactiveConnections = prometheus.NewDesc("merlin_ipvs_server_inactive_connections",
+		"Connections to the real server in any other state.", srvLabels, nil)
+	c.serverWeight = prometheus.NewDesc("merlin_ipvs_server_weight",
+		"Weight of the real server in ipvs.", srvLabels, nil)
+	return c
+}
+
+// validateMetricLabels returns an error if a label isn't a label of the IPVS metrics.
+func validateMetricLabels(labels []string) error {
+	for _, label := range labels {
+		found := false
+		for _, l := range serverLabels {
+			found = found || l == label
+		}
+		if !found {
+			return fmt.Errorf("unknown ipvs metric label %q, must be one of %s", label,
+				strings.Join(serverLabels, ", "))
+		}
+	}
+	return nil
+}
+
+// exported returns the values of the labels in names which are exported.
+func (c *ipvsCollector) exported(names, values []string) []string {
+	var kept []string
+	for i, name := range names {
+		if c.labels[name] {
+			kept = append(kept, values[i])
+		}
+	}
+	return kept
+}
+
+// serviceID returns the service_id label of a service ID.
+func (c *ipvsCollector) serviceID(id string) string {
+	if c.idBuckets <= 0 || id == "" {
+		return id
+	}
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	return fmt.Sprintf("bucket-%d", h.Sum32()%uint32(c.idBuckets))
+}
+
+func (c *ipvsCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, m := range statsMetrics {
+		ch <- c.serviceMetrics[m]
+		ch <- c.serverMetrics[m]
+	}
+	ch <- c.activeConnections
+	ch <- c.inactiveConnections
+	ch <- c.serverWeight
+}
+
+// metricSums sums the values of series with the same descriptor and label values.
+type metricSums struct {
+	keys   []string
+	series map[string]*metricSum
+}
+
+type metricSum struct {
+	desc      *prometheus.Desc
+	valueType prometheus.ValueType
+	labels    []string
+	value     float64
+}
+
+func (s *metricSums) add(desc *prometheus.Desc, valueType prometheus.ValueType, value float64, labels []string) {
+	key := desc.String() + "\x00" + strings.Join(labels, "\x00")
+	sum, ok := s.series[key]
+	if !ok {
+		sum = &metricSum{desc: desc, valueType: valueType, labels: labels}
+		s.series[key] = sum
+		s.keys = append(s.keys, key)
+	}
+	sum.value += value
+}
+
+func (c *ipvsCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	defer cancel()
+	stats, err := c.ipvs.Stats(ctx)
+	if err != nil {
+		log.Warnf("Unable to read ipvs stats for metrics: %v", err)
+		return
+	}
+	// services are still exported without IDs if the store is unavailable
+	svcs, err := c.store.ListServices(ctx)
+	if err != nil {
+		log.Warnf("Unable to list services for ipvs metrics: %v", err)
+	}
+
+	sums := &metricSums{series: make(map[string]*metricSum)}
+	for _, svcStats := range stats {
+		for _, svc := range svcs {
+			if proto.Equal(svc.Key, svcStats.Key) {
+				svcStats.Id = svc.Id
+				break
+			}
+		}
+		values := []string{c.serviceID(svcStats.Id), svcStats.Key.GetProtocol().String(),
+			fmt.Sprintf("%s:%d", svcStats.Key.GetIp(), svcStats.Key.GetPort())}
+		labels := c.exported(serviceLabels, values)
+		for _, m := range statsMetrics {
+			sums.add(c.serviceMetrics[m], m.valueType, float64(m.value(svcStats.Stats)), labels)
+		}
+
+		for _, serverStats := range svcStats.Servers {
+			labels := c.exported(serverLabels, append(values[:len(values):len(values)],
+				serverStats.Key.PrettyString()))
+			for _, m := range statsMetrics {
+				sums.add(c.serverMetrics[m], m.valueType, float64(m.value(serverStats.Stats)), labels)
+			}
+			sums.add(c.activeConnections, prometheus.GaugeValue, float64(serverStats.ActiveConnections), labels)
+			sums.add(c.inactiveConnections, prometheus.GaugeValue, float64(serverStats.InactiveConnections), labels)
+			sums.add(c.serverWeight, prometheus.GaugeValue, float64(serverStats.Weight), labels)
+		}
+	}
+
+	for _, key := range sums.keys {
+		sum := sums.series[key]
+		ch <- prometheus.MustNewConstMetric(sum.desc, sum.valueType, sum.value, sum.labels...)
+	}
+}
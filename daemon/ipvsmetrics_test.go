@@ -0,0 +1,90 @@
+package daemon
+
+import (
+	"context"
+
+	"github.com/golang/protobuf/ptypes/wrappers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/sky-uk/merlin/ipvs"
+	"github.com/sky-uk/merlin/store"
+	"github.com/sky-uk/merlin/types"
+)
+
+var _ = Describe("IPVS metrics", func() {
+	var (
+		ctx = context.Background()
+		i   ipvs.IPVS
+		st  store.Store
+	)
+
+	BeforeEach(func() {
+		i = ipvs.NewMemory()
+		st = store.NewMemory()
+		svc := &types.VirtualService{Id: "svc", Key: &types.VirtualService_Key{Ip: "10.0.0.1", Port: 80,
+			Protocol: types.Protocol_TCP}}
+		Expect(st.PutService(ctx, svc)).To(Succeed())
+		Expect(i.AddService(ctx, svc)).To(Succeed())
+		for n, ip := range []string{"172.16.0.1", "172.16.0.2"} {
+			Expect(i.AddServer(ctx, svc.Key, &types.RealServer{
+				Key:    &types.RealServer_Key{Ip: ip, Port: 8080},
+				Config: &types.RealServer_Config{Weight: &wrappers.UInt32Value{Value: uint32(n + 1)}},
+			})).To(Succeed())
+		}
+	})
+
+	// weights returns the merlin_ipvs_server_weight series, by their labels.
+	weights := func(c *ipvsCollector) map[string]float64 {
+		registry := prometheus.NewRegistry()
+		Expect(registry.Register(c)).To(Succeed())
+		families, err := registry.Gather()
+		Expect(err).NotTo(HaveOccurred())
+		series := make(map[string]float64)
+		for _, family := range families {
+			if family.GetName() == "merlin_ipvs_server_weight" {
+				for _, m := range family.Metric {
+					series[labelString(m)] = m.GetGauge().GetValue()
+				}
+			}
+		}
+		return series
+	}
+
+	It("should export every label by default", func() {
+		Expect(weights(newIPVSCollector(i, st, nil, 0))).To(Equal(map[string]float64{
+			"address=10.0.0.1:80,protocol=TCP,server=172.16.0.1:8080,service_id=svc": 1,
+			"address=10.0.0.1:80,protocol=TCP,server=172.16.0.2:8080,service_id=svc": 2,
+		}))
+	})
+
+	It("should sum series which only differ by dropped labels", func() {
+		Expect(weights(newIPVSCollector(i, st, []string{LabelServiceID}, 0))).To(Equal(map[string]float64{
+			"service_id=svc": 3,
+		}))
+	})
+
+	It("should hash service IDs into buckets", func() {
+		series := weights(newIPVSCollector(i, st, []string{LabelServiceID}, 4))
+		Expect(series).To(HaveLen(1))
+		for labels := range series {
+			Expect(labels).To(MatchRegexp(`^service_id=bucket-[0-3]$`))
+		}
+	})
+
+	It("should refuse unknown labels", func() {
+		Expect(validateMetricLabels([]string{LabelServer, "node"})).To(MatchError(ContainSubstring(`"node"`)))
+	})
+})
+
+func labelString(m *dto.Metric) string {
+	var s string
+	for _, pair := range m.Label {
+		if s != "" {
+			s += ","
+		}
+		s += pair.GetName() + "=" + pair.GetValue()
+	}
+	return s
+}
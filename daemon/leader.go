@@ -0,0 +1,260 @@
+package daemon
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"path"
+	"reflect"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/sky-uk/merlin/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// leaderElection is the name of the election for the node which performs writes and cluster-wide duties.
+	leaderElection = "api"
+	// forwardedKey is set in the metadata of forwarded requests, so they are never forwarded twice.
+	forwardedKey = "x-merlin-forwarded-by"
+	// nodeTokenKey is set in the metadata of forwarded requests to the node token, which proves they were forwarded
+	// by a merlin node.
+	nodeTokenKey = "x-merlin-node-token"
+)
+
+// untrustedForwardKey is the context key set on requests whose forwardedKey was dropped, as they didn't have the
+// node token.
+type untrustedForwardKey struct{}
+
+// writeMethods are the RPCs which change the store, which are forwarded to the leader.
+var writeMethods = map[string]bool{
+	"/types.Merlin/CreateService":    true,
+	"/types.Merlin/UpdateService":    true,
+	"/types.Merlin/DeleteService":    true,
+	"/types.Merlin/CloneService":     true,
+	"/types.Merlin/RenameService":    true,
+	"/types.Merlin/SwapServers":      true,
+	"/types.Merlin/CreateServer":     true,
+	"/types.Merlin/UpdateServer":     true,
+	"/types.Merlin/DeleteServer":     true,
+	"/types.Merlin/DrainServer":      true,
+	"/types.Merlin/UndrainServer":    true,
+	"/types.Merlin/ApplySnapshot":    true,
+	"/types.Merlin/Apply":            true,
+	"/types.Merlin/SetMaintenance":   true,
+	"/types.Merlin/RolloutService":   true,
+	"/types.Merlin/PutPool":          true,
+	"/types.Merlin/DeletePool":       true,
+	"/types.Merlin/Commit":           true,
+	"/types.Merlin/ConfirmCommit":    true,
+	"/types.Merlin/DiscardCandidate": true,
+}
+
+// nodeWriteMethods are the RPCs which change the node serving them rather than the store, so aren't forwarded.
+var nodeWriteMethods = map[string]bool{
+	"/types.Merlin/SetSyncRole": true,
+}
+
+// isWrite returns true if the method changes the store or this node, so needs write permission and is audited.
+func isWrite(fullMethod string) bool {
+	return writeMethods[fullMethod] || nodeWriteMethods[fullMethod]
+}
+
+// leadership tracks the leader of the election, and connections to it for forwarding writes.
+type leadership struct {
+	mu     sync.Mutex
+	leader string
+	conns  map[string]*grpc.ClientConn
+}
+
+// IsLeader returns true if this node should perform cluster-wide duties. Without leader election every node
+// is its own leader.
+func (d *Daemon) IsLeader() bool {
+	if !d.opts.LeaderElection {
+		return true
+	}
+	return d.leader() == d.opts.NodeName
+}
+
+func (d *Daemon) leader() string {
+	d.leadership.mu.Lock()
+	defer d.leadership.mu.Unlock()
+	return d.leadership.leader
+}
+
+// campaign for leadership, or renew it if this node is the leader. It's called on every heartbeat, so
+// leadership expires after 3 missed heartbeats.
+func (d *Daemon) campaign(ctx context.Context) {
+	leader, err := d.store.CampaignLeader(ctx, leaderElection, d.opts.NodeName, 3*d.opts.HeartbeatPeriod)
+	if err != nil {
+		// followers keep forwarding to the last known leader, which is likely still valid
+		log.Warnf("Unable to campaign for leader: %v", err)
+		if d.leader() != d.opts.NodeName {
+			return
+		}
+		// the leader steps down, as it may have expired
+		leader = ""
+	}
+
+	d.leadership.mu.Lock()
+	prev := d.leadership.leader
+	d.leadership.leader = leader
+	d.leadership.mu.Unlock()
+
+	switch {
+	case leader == prev:
+	case leader == d.opts.NodeName:
+		log.Infof("Became leader")
+	case prev == d.opts.NodeName:
+		log.Warnf("Lost leadership to %q", leader)
+	default:
+		log.Infof("Leader is %q", leader)
+	}
+}
+
+// resign leadership, so another node can take over without waiting for it to expire.
+func (d *Daemon) resign() {
+	if !d.opts.LeaderElection || d.leader() != d.opts.NodeName {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), d.opts.HeartbeatPeriod)
+	defer cancel()
+	if err := d.store.ResignLeader(ctx, leaderElection, d.opts.NodeName); err != nil {
+		log.Warnf("Unable to resign leadership: %v", err)
+		return
+	}
+	log.Infof("Resigned leadership")
+}
+
+// trustForwarded is an interceptor which drops forwardedKey from the metadata of requests without the node token,
+// so only merlin nodes can have a write handled by a node which isn't the leader, or say which node forwarded it.
+// The node token is dropped from every request, so it isn't passed on.
+func (d *Daemon) trustForwarded(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok || (len(md.Get(forwardedKey)) == 0 && len(md.Get(nodeTokenKey)) == 0) {
+		return handler(ctx, req)
+	}
+	token := md.Get(nodeTokenKey)
+	trusted := d.opts.NodeToken != "" && len(token) > 0 &&
+		subtle.ConstantTimeCompare([]byte(token[0]), []byte(d.opts.NodeToken)) == 1
+
+	md = md.Copy()
+	delete(md, nodeTokenKey)
+	if !trusted && len(md.Get(forwardedKey)) > 0 {
+		delete(md, forwardedKey)
+		ctx = context.WithValue(ctx, untrustedForwardKey{}, true)
+	}
+	return handler(metadata.NewIncomingContext(ctx, md), req)
+}
+
+// forwardWrites is an interceptor which forwards writes to the leader, if leader election is enabled and this
+// node isn't the leader. Requests which were already forwarded are handled locally if they have the node token,
+// and otherwise fail, so they can't loop while nodes disagree on the leader.
+func (d *Daemon) forwardWrites(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	if !d.opts.LeaderElection || !writeMethods[info.FullMethod] || d.IsLeader() {
+		return handler(ctx, req)
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	if len(md.Get(forwardedKey)) > 0 {
+		return handler(ctx, req)
+	}
+	if ctx.Value(untrustedForwardKey{}) != nil {
+		return nil, status.Errorf(codes.Unavailable, "a forwarded write reached %s, which isn't the leader",
+			d.opts.NodeName)
+	}
+
+	leader := d.leader()
+	if leader == "" {
+		return nil, status.Error(codes.Unavailable, "no leader has been elected to handle writes")
+	}
+	conn, err := d.leaderConn(ctx, leader)
+	if err != nil {
+		return nil, err
+	}
+
+	md = md.Copy()
+	md.Set(forwardedKey, d.opts.NodeName)
+	if d.opts.NodeToken != "" {
+		md.Set(nodeTokenKey, d.opts.NodeToken)
+	}
+	log.Debugf("Forwarding %s to leader %s", path.Base(info.FullMethod), leader)
+	return invoke(metadata.NewOutgoingContext(ctx, md), types.NewMerlinClient(conn), path.Base(info.FullMethod), req)
+}
+
+// leaderConn returns a connection to the leader at the address it registered with.
+func (d *Daemon) leaderConn(ctx context.Context, leader string) (*grpc.ClientConn, error) {
+	nodes, err := d.store.ListNodes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find leader %s: %v", leader, err)
+	}
+	var address string
+	for _, node := range nodes {
+		if node.Name == leader {
+			address = node.Address
+		}
+	}
+	if address == "" {
+		return nil, status.Errorf(codes.Unavailable, "leader %s hasn't registered its address", leader)
+	}
+
+	d.leadership.mu.Lock()
+	defer d.leadership.mu.Unlock()
+	if conn, ok := d.leadership.conns[address]; ok {
+		return conn, nil
+	}
+	// connections are made lazily by grpc, so dialing doesn't block
+	conn, err := grpc.Dial(address, d.forwardCreds)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to leader %s at %s: %v", leader, address, err)
+	}
+	if d.leadership.conns == nil {
+		d.leadership.conns = make(map[string]*grpc.ClientConn)
+	}
+	d.leadership.conns[address] = conn
+	return conn, nil
+}
+
+// closeLeaderConns closes the connections used for forwarding writes.
+func (d *Daemon) closeLeaderConns() {
+	d.leadership.mu.Lock()
+	defer d.leadership.mu.Unlock()
+	for address, conn := range d.leadership.conns {
+		conn.Close()
+		delete(d.leadership.conns, address)
+	}
+}
+
+// invoke calls the method of target with the same name as the request's RPC, so any request can be forwarded or
+// called in process without knowing its response type.
+func invoke(ctx context.Context, target interface{}, method string, req interface{}) (interface{}, error) {
+	fn := reflect.ValueOf(target).MethodByName(method)
+	if !fn.IsValid() {
+		return nil, status.Errorf(codes.Unimplemented, "unknown method %s", method)
+	}
+	out := fn.Call([]reflect.Value{reflect.ValueOf(ctx), reflect.ValueOf(req)})
+	if err, _ := out[1].Interface().(error); err != nil {
+		return nil, err
+	}
+	return out[0].Interface(), nil
+}
+
+// unaryInterceptors chains interceptors, as grpc only supports one. The first is the outermost.
+func unaryInterceptors(interceptors ...grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+		next := handler
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, inner := interceptors[i], next
+			next = func(ctx context.Context, req interface{}) (interface{}, error) {
+				return interceptor(ctx, req, info, inner)
+			}
+		}
+		return next(ctx, req)
+	}
+}
@@ -0,0 +1,68 @@
+package daemon
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sky-uk/merlin/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var _ = Describe("Forwarded writes", func() {
+	var (
+		d       *Daemon
+		handled metadata.MD
+	)
+
+	call := func(md metadata.MD) error {
+		handled = nil
+		info := &grpc.UnaryServerInfo{FullMethod: "/types.Merlin/CreateService"}
+		ctx := metadata.NewIncomingContext(context.Background(), md)
+		_, err := unaryInterceptors(d.trustForwarded, d.forwardWrites)(ctx, &types.VirtualService{Id: "web"}, info,
+			func(ctx context.Context, _ interface{}) (interface{}, error) {
+				handled, _ = metadata.FromIncomingContext(ctx)
+				return nil, nil
+			})
+		return err
+	}
+
+	BeforeEach(func() {
+		d = New(Options{NodeName: "node1", LeaderElection: true, NodeToken: "s3cret"})
+		d.leadership.leader = "node2"
+	})
+
+	It("should handle writes forwarded with the node token without forwarding them again", func() {
+		Expect(call(metadata.Pairs(forwardedKey, "node3", nodeTokenKey, "s3cret"))).To(Succeed())
+
+		Expect(handled.Get(forwardedKey)).To(Equal([]string{"node3"}))
+		Expect(handled.Get(nodeTokenKey)).To(BeEmpty(), "the token isn't passed on")
+	})
+
+	It("should refuse writes marked as forwarded without the node token on a node which isn't the leader", func() {
+		err := call(metadata.Pairs(forwardedKey, "node3", nodeTokenKey, "guess"))
+
+		Expect(status.Code(err)).To(Equal(codes.Unavailable))
+		Expect(handled).To(BeNil())
+	})
+
+	It("should drop the forwarding node of writes without the node token on the leader", func() {
+		d.leadership.leader = "node1"
+
+		Expect(call(metadata.Pairs(forwardedKey, "node3"))).To(Succeed())
+
+		Expect(handled).ToNot(BeNil())
+		Expect(handled.Get(forwardedKey)).To(BeEmpty())
+	})
+
+	It("should trust no forwarded writes without a node token", func() {
+		d.opts.NodeToken = ""
+
+		err := call(metadata.Pairs(forwardedKey, "node3", nodeTokenKey, ""))
+
+		Expect(status.Code(err)).To(Equal(codes.Unavailable))
+	})
+})
@@ -0,0 +1,217 @@
+package daemon
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// http2Preface starts every HTTP/2 connection, so every plaintext gRPC connection.
+var http2Preface = []byte("PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n")
+
+// sniffTimeout is how long a new connection has to send enough bytes to tell if it's gRPC.
+const sniffTimeout = 10 * time.Second
+
+// serve the API on lis, along with HTTPHandler if it's set. Plaintext connections are split between the two by
+// whether they start with the HTTP/2 preface. TLS connections are all served by an HTTP server, which passes
+// requests with the gRPC content type to the gRPC server, as clients such as curl negotiate HTTP/2 over TLS too.
+func (d *Daemon) serve(lis net.Listener) {
+	if d.opts.HTTPHandler == nil {
+		go func() {
+			if err := d.grpcServer.Serve(lis); err != nil {
+				log.Error(err)
+			}
+		}()
+		return
+	}
+
+	if d.certs != nil {
+		d.grpcOverHTTP = true
+		d.httpServer = &http.Server{
+			Handler:   grpcOrHTTP(d.grpcServer, d.opts.HTTPHandler, d.clientCAs != nil),
+			TLSConfig: d.serverTLSConfig(),
+		}
+		d.httpServer.TLSConfig.NextProtos = []string{"h2", "http/1.1"}
+		// HTTP endpoints such as health checks are served without a client certificate, which gRPC requests need
+		if d.clientCAs != nil {
+			d.httpServer.TLSConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		}
+		go func() {
+			if err := d.httpServer.ServeTLS(lis, "", ""); err != http.ErrServerClosed {
+				log.Error(err)
+			}
+		}()
+		return
+	}
+
+	d.mux = newMuxListener(lis)
+	d.httpServer = &http.Server{Handler: d.opts.HTTPHandler}
+	go d.mux.run()
+	go func() {
+		if err := d.grpcServer.Serve(d.mux.grpc); err != nil {
+			log.Error(err)
+		}
+	}()
+	go func() {
+		if err := d.httpServer.Serve(d.mux.http); err != http.ErrServerClosed {
+			log.Error(err)
+		}
+	}()
+}
+
+// stopServing stops the API, waiting until the deadline for in-flight requests to finish before closing their
+// connections.
+func (d *Daemon) stopServing(timeout time.Duration, deadline <-chan time.Time) {
+	if d.httpServer != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		if !waitUntil(func() { d.httpServer.Shutdown(ctx) }, deadline) {
+			log.Warnf("Timed out after %v waiting for HTTP requests to finish, closing connections", timeout)
+			d.httpServer.Close()
+		}
+		cancel()
+	}
+	if d.mux != nil {
+		d.mux.Close()
+	}
+	if d.grpcServer == nil {
+		return
+	}
+	// gRPC served by the HTTP server has already been drained by it, and can't be gracefully stopped
+	if d.grpcOverHTTP {
+		d.grpcServer.Stop()
+		return
+	}
+	if !waitUntil(d.grpcServer.GracefulStop, deadline) {
+		log.Warnf("Timed out after %v waiting for requests to finish, closing connections", timeout)
+		d.grpcServer.Stop()
+	}
+}
+
+// grpcOrHTTP passes gRPC requests to the gRPC server, and everything else to handler. If requireCert is set, gRPC
+// requests without a verified client certificate are rejected.
+func grpcOrHTTP(grpcServer *grpc.Server, handler http.Handler, requireCert bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+			if requireCert && (r.TLS == nil || len(r.TLS.VerifiedChains) == 0) {
+				w.Header().Set("Content-Type", "application/grpc")
+				w.Header().Set("Grpc-Status", strconv.Itoa(int(codes.Unauthenticated)))
+				w.Header().Set("Grpc-Message", "client certificate required")
+				return
+			}
+			grpcServer.ServeHTTP(w, r)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// muxListener splits the connections of a listener between gRPC, whose connections start with the HTTP/2
+// preface, and HTTP.
+type muxListener struct {
+	net.Listener
+	grpc *connListener
+	http *connListener
+}
+
+func newMuxListener(lis net.Listener) *muxListener {
+	return &muxListener{
+		Listener: lis,
+		grpc:     newConnListener(lis),
+		http:     newConnListener(lis),
+	}
+}
+
+// run accepts connections until the listener is closed, which closes the gRPC and HTTP listeners too.
+func (m *muxListener) run() {
+	defer m.grpc.Close()
+	defer m.http.Close()
+	for {
+		conn, err := m.Accept()
+		if err != nil {
+			return
+		}
+		go m.sniff(conn)
+	}
+}
+
+// sniff passes conn to the gRPC listener if it starts with the HTTP/2 preface, otherwise to the HTTP listener.
+// The preface is read a byte at a time, so short HTTP/1 requests don't wait for more.
+func (m *muxListener) sniff(conn net.Conn) {
+	conn.SetReadDeadline(time.Now().Add(sniffTimeout))
+	r := bufio.NewReader(conn)
+	target := m.grpc
+	for i := range http2Preface {
+		b, err := r.Peek(i + 1)
+		if err != nil {
+			conn.Close()
+			return
+		}
+		if b[i] != http2Preface[i] {
+			target = m.http
+			break
+		}
+	}
+	conn.SetReadDeadline(time.Time{})
+	target.deliver(&sniffedConn{Conn: conn, r: r})
+}
+
+// sniffedConn reads the bytes peeked by sniff before the rest of the connection.
+type sniffedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *sniffedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+// connListener accepts the connections delivered to it.
+type connListener struct {
+	addr      net.Addr
+	conns     chan net.Conn
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newConnListener(lis net.Listener) *connListener {
+	return &connListener{addr: lis.Addr(), conns: make(chan net.Conn), done: make(chan struct{})}
+}
+
+var errListenerClosed = errors.New("listener closed")
+
+func (l *connListener) deliver(conn net.Conn) {
+	select {
+	case l.conns <- conn:
+	case <-l.done:
+		conn.Close()
+	}
+}
+
+func (l *connListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.done:
+		return nil, errListenerClosed
+	}
+}
+
+func (l *connListener) Close() error {
+	l.closeOnce.Do(func() { close(l.done) })
+	return nil
+}
+
+func (l *connListener) Addr() net.Addr {
+	return l.addr
+}
@@ -0,0 +1,92 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+	"github.com/sky-uk/merlin/store"
+	"github.com/sky-uk/merlin/types"
+	"github.com/sky-uk/merlin/validation"
+)
+
+// Policies for orphaned servers, whose service no longer exists.
+const (
+	// OrphanReport logs and counts orphaned servers, leaving them in the store.
+	OrphanReport = "report"
+	// OrphanDelete deletes orphaned servers once they have been orphaned for the grace period.
+	OrphanDelete = "delete"
+	// OrphanBlock refuses to delete services which have servers, so servers can't be orphaned.
+	OrphanBlock = "block"
+)
+
+// orphanChecker finds orphaned servers in the store, applying the orphan policy to them.
+type orphanChecker struct {
+	policy string
+	grace  time.Duration
+	gauge  prometheus.Gauge
+	// seen is when each orphan was first found, by server ID
+	seen map[string]time.Time
+}
+
+func newOrphanChecker(policy string, grace time.Duration) *orphanChecker {
+	return &orphanChecker{
+		policy: policy,
+		grace:  grace,
+		gauge: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "merlin_orphaned_servers",
+			Help: "Real servers in the store whose virtual service doesn't exist.",
+		}),
+		seen: make(map[string]time.Time),
+	}
+}
+
+// check the store for orphaned servers at time now. Orphans which are deleted, or whose service is recreated,
+// are forgotten.
+func (c *orphanChecker) check(ctx context.Context, st store.Store, now time.Time) error {
+	svcs, err := st.ListServices(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to list services: %v", err)
+	}
+	servers, err := st.ListAllServers(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to list servers: %v", err)
+	}
+	exists := make(map[string]bool)
+	for _, svc := range svcs {
+		exists[svc.Id] = true
+	}
+
+	var orphans []*types.RealServer
+	seen := make(map[string]time.Time)
+	for _, server := range servers {
+		if exists[server.ServiceID] {
+			continue
+		}
+		id := validation.ServerID(server.ServiceID, server.Key)
+		first, ok := c.seen[id]
+		if !ok {
+			first = now
+			log.Warnf("Server %s is orphaned, as service %s doesn't exist", id, server.ServiceID)
+		}
+		seen[id] = first
+		if c.policy == OrphanDelete && now.Sub(first) >= c.grace {
+			orphans = append(orphans, server)
+		}
+	}
+	c.seen = seen
+	c.gauge.Set(float64(len(seen)))
+
+	for _, server := range orphans {
+		id := validation.ServerID(server.ServiceID, server.Key)
+		if err := st.DeleteServer(ctx, server.ServiceID, server.Key); err != nil {
+			return fmt.Errorf("unable to delete orphaned server %s: %v", id, err)
+		}
+		log.Infof("Deleted server %s, after being orphaned for %v", id, c.grace)
+		delete(c.seen, id)
+		c.gauge.Dec()
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package daemon
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/sky-uk/merlin/store"
+	"github.com/sky-uk/merlin/types"
+)
+
+var _ = Describe("Orphans", func() {
+	var (
+		ctx     = context.Background()
+		st      store.Store
+		now     time.Time
+		server1 *types.RealServer
+		server2 *types.RealServer
+	)
+
+	BeforeEach(func() {
+		st = store.NewMemory()
+		now = time.Now()
+		Expect(st.PutService(ctx, &types.VirtualService{Id: "svc1"})).To(Succeed())
+		server1 = &types.RealServer{ServiceID: "svc1", Key: &types.RealServer_Key{Ip: "10.0.0.1", Port: 8080}}
+		server2 = &types.RealServer{ServiceID: "svc2", Key: &types.RealServer_Key{Ip: "10.0.0.2", Port: 8080}}
+		Expect(st.PutServer(ctx, server1)).To(Succeed())
+		Expect(st.PutServer(ctx, server2)).To(Succeed())
+	})
+
+	It("should count orphaned servers without deleting them when reporting", func() {
+		c := newOrphanChecker(OrphanReport, time.Minute)
+
+		Expect(c.check(ctx, st, now)).To(Succeed())
+		Expect(c.check(ctx, st, now.Add(time.Hour))).To(Succeed())
+
+		Expect(orphaned(c)).To(Equal(1.0))
+		Expect(st.GetServer(ctx, "svc2", server2.Key)).ToNot(BeNil())
+	})
+
+	It("should delete orphaned servers after the grace period", func() {
+		c := newOrphanChecker(OrphanDelete, time.Minute)
+
+		Expect(c.check(ctx, st, now)).To(Succeed())
+		Expect(c.check(ctx, st, now.Add(30*time.Second))).To(Succeed())
+		Expect(st.GetServer(ctx, "svc2", server2.Key)).ToNot(BeNil())
+
+		Expect(c.check(ctx, st, now.Add(time.Minute))).To(Succeed())
+		Expect(st.GetServer(ctx, "svc2", server2.Key)).To(BeNil())
+		Expect(st.GetServer(ctx, "svc1", server1.Key)).ToNot(BeNil())
+		Expect(orphaned(c)).To(Equal(0.0))
+	})
+
+	It("should forget orphans whose service is recreated", func() {
+		c := newOrphanChecker(OrphanDelete, time.Minute)
+		Expect(c.check(ctx, st, now)).To(Succeed())
+
+		Expect(st.PutService(ctx, &types.VirtualService{Id: "svc2"})).To(Succeed())
+		Expect(c.check(ctx, st, now.Add(time.Minute))).To(Succeed())
+		Expect(st.DeleteService(ctx, "svc2")).To(Succeed())
+		Expect(c.check(ctx, st, now.Add(90*time.Second))).To(Succeed())
+
+		Expect(st.GetServer(ctx, "svc2", server2.Key)).ToNot(BeNil())
+		Expect(orphaned(c)).To(Equal(1.0))
+	})
+})
+
+func orphaned(c *orphanChecker) float64 {
+	var m dto.Metric
+	Expect(c.gauge.Write(&m)).To(Succeed())
+	return m.GetGauge().GetValue()
+}
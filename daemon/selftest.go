@@ -0,0 +1,128 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/sky-uk/merlin/hostcheck"
+	"github.com/sky-uk/merlin/store"
+)
+
+// maxClockSkew is how far the clock can be from the store's before it's reported.
+const maxClockSkew = 5 * time.Second
+
+// selfTest logs the result of each startup check, counting the failures. Failures prevent merlin from working,
+// while warnings are likely to cause problems.
+type selfTest struct {
+	failures int
+}
+
+func (t *selfTest) result(check, result, format string, args ...interface{}) {
+	entry := log.WithFields(log.Fields{"check": check, "result": result})
+	switch result {
+	case "fail":
+		t.failures++
+		entry.Errorf(format, args...)
+	case "warn":
+		entry.Warnf(format, args...)
+	default:
+		entry.Infof(format, args...)
+	}
+}
+
+// runSelfTest checks merlin can run on this host, returning an error if any check fails. The kernel isn't checked
+// if IPVS is stubbed, nor the clock if the store was given without endpoints.
+func (d *Daemon) runSelfTest(s store.Store) error {
+	t := &selfTest{}
+	if d.opts.Reconcile && d.opts.IPVS == nil {
+		t.checkKernel()
+		t.checkCapabilities()
+	}
+	t.checkStore(s, d.opts.NodeName, d.opts.StoreBackend)
+	if len(d.opts.StoreEndpoints) > 0 {
+		t.checkClock(d.opts.StoreEndpoints[0])
+	}
+
+	if t.failures > 0 {
+		return fmt.Errorf("%d self-test checks failed", t.failures)
+	}
+	return nil
+}
+
+func (t *selfTest) checkKernel() {
+	if !hostcheck.IPVSLoaded() {
+		t.result("kernel", "fail", "ip_vs kernel module isn't loaded, load it with `modprobe ip_vs`")
+	} else {
+		t.result("kernel", "ok", "ip_vs kernel module is loaded")
+	}
+
+	for _, s := range hostcheck.RecommendedSysctls {
+		value, err := s.Read()
+		switch {
+		case err != nil:
+			t.result("sysctl", "warn", "unable to read sysctl %s: %v", s.Name, err)
+		case value != s.Value:
+			t.result("sysctl", "warn", "sysctl %s is %s, set it to %s: %s", s.Name, value, s.Value, s.Reason)
+		default:
+			t.result("sysctl", "ok", "sysctl %s is %s", s.Name, s.Value)
+		}
+	}
+}
+
+func (t *selfTest) checkCapabilities() {
+	netAdmin, err := hostcheck.HasNetAdmin(os.Getpid())
+	switch {
+	case err != nil:
+		t.result("capabilities", "warn", "unable to read capabilities: %v", err)
+	case !netAdmin:
+		t.result("capabilities", "fail", "CAP_NET_ADMIN is required to configure ipvs, run as root or grant it")
+	default:
+		t.result("capabilities", "ok", "CAP_NET_ADMIN is available")
+	}
+}
+
+func (t *selfTest) checkStore(s store.Store, nodeName, storeBackend string) {
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	defer cancel()
+	start := time.Now()
+	if _, err := s.GetMaintenance(ctx, nodeName); err != nil {
+		t.result("store", "fail", "%s store is unreachable: %v", storeBackend, err)
+		return
+	}
+	t.result("store", "ok", "%s store responded in %v", storeBackend, time.Since(start).Round(time.Millisecond))
+}
+
+// checkClock compares the clock with the Date header of the store endpoint, as skew makes heartbeat and
+// sync times misleading.
+func (t *selfTest) checkClock(endpoint string) {
+	client := &http.Client{Timeout: checkTimeout}
+	start := time.Now()
+	resp, err := client.Get(strings.TrimSuffix(endpoint, "/") + "/version")
+	if err != nil {
+		t.result("clock", "warn", "unable to compare the clock with the store: %v", err)
+		return
+	}
+	resp.Body.Close()
+	storeTime, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		t.result("clock", "warn", "unable to compare the clock with the store, it has no Date header")
+		return
+	}
+
+	// the Date header has second precision, and was set while the request was in flight
+	rtt := time.Since(start)
+	skew := start.Add(rtt / 2).Sub(storeTime)
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > maxClockSkew+time.Second {
+		t.result("clock", "warn", "clock differs from the store by %v, check NTP is running", skew.Round(time.Second))
+		return
+	}
+	t.result("clock", "ok", "clock is within %v of the store", maxClockSkew)
+}
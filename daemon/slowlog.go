@@ -0,0 +1,45 @@
+package daemon
+
+import (
+	"context"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/wrappers"
+	log "github.com/sirupsen/logrus"
+	"github.com/sky-uk/merlin/store"
+	"github.com/sky-uk/merlin/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// logSlowRequests logs requests which take at least SlowRequestThreshold, with the object they're for and how long
+// each of their store operations took.
+func (d *Daemon) logSlowRequests(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	if d.opts.SlowRequestThreshold == 0 {
+		return handler(ctx, req)
+	}
+	timing := &store.Timing{}
+	start := time.Now()
+	resp, err := handler(store.WithTiming(ctx, timing), req)
+	if elapsed := time.Since(start); elapsed >= d.opts.SlowRequestThreshold {
+		log.Warnf("Slow request %s %s took %v with code %v, %v", info.FullMethod, requestKey(req), elapsed,
+			status.Code(err), timing)
+	}
+	return resp, err
+}
+
+// requestKey returns the key of the object a request is for, or "" if it isn't for one.
+func requestKey(req interface{}) string {
+	switch r := req.(type) {
+	case *types.RealServer:
+		return r.GetServiceID() + "/" + r.GetKey().PrettyString()
+	case *wrappers.StringValue:
+		return r.GetValue()
+	case interface{ GetId() string }:
+		return r.GetId()
+	case interface{ GetServiceID() string }:
+		return r.GetServiceID()
+	}
+	return ""
+}
@@ -0,0 +1,18 @@
+package daemon
+
+import (
+	"github.com/golang/protobuf/ptypes/wrappers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sky-uk/merlin/types"
+)
+
+var _ = Describe("Slow request logging", func() {
+	It("should log the key of the object a request is for", func() {
+		Expect(requestKey(&types.VirtualService{Id: "web"})).To(Equal("web"))
+		Expect(requestKey(&wrappers.StringValue{Value: "web"})).To(Equal("web"))
+		Expect(requestKey(&types.RealServer{ServiceID: "web", Key: &types.RealServer_Key{Ip: "10.0.0.1", Port: 80}})).
+			To(Equal("web/10.0.0.1:80"))
+		Expect(requestKey(&types.ListRequest{})).To(Equal(""))
+	})
+})
@@ -0,0 +1,82 @@
+package daemon
+
+import (
+	"context"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/sky-uk/merlin/rbac"
+	"github.com/sky-uk/merlin/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// interceptStream is the interceptor of streaming RPCs, which only read. Like the unary interceptors, it advertises
+// the API version, authenticates the client, and with a policy only sends the services the client can read.
+func (d *Daemon) interceptStream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler) error {
+	ss.SetHeader(metadata.Pairs(types.APIVersionHeader, types.APIVersion))
+	ctx, err := d.authenticated(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	stream := &authorizedStream{ServerStream: ss, ctx: ctx}
+	client := clientIdentity(ctx)
+	if policy := d.opts.Policy; policy != nil && !policy.Allowed(client, rbac.Read, "") {
+		stream.readable = func(serviceID string) bool {
+			return policy.Allowed(client, rbac.Read, serviceID)
+		}
+	}
+
+	err = handler(srv, stream)
+	if _, ok := status.FromError(err); !ok {
+		log.Error(err)
+		err = status.Errorf(codes.Internal, "%v", err)
+	}
+	return err
+}
+
+// authorizedStream is a stream with the authenticated client in its context, which filters the changes it sends
+// by whether the client can read their services, if readable is set.
+type authorizedStream struct {
+	grpc.ServerStream
+	ctx      context.Context
+	readable func(serviceID string) bool
+}
+
+func (s *authorizedStream) Context() context.Context {
+	return s.ctx
+}
+
+// SendMsg drops the changes of watch events and the connections of services the client can't read. Events left
+// without changes aren't sent, except the first.
+func (s *authorizedStream) SendMsg(m interface{}) error {
+	if s.readable == nil {
+		return s.ServerStream.SendMsg(m)
+	}
+	if conn, ok := m.(*types.Connection); ok {
+		if !s.readable(conn.ServiceId) {
+			return nil
+		}
+		return s.ServerStream.SendMsg(m)
+	}
+	event, ok := m.(*types.WatchEvent)
+	if !ok {
+		return s.ServerStream.SendMsg(m)
+	}
+	var changes []*types.Change
+	for _, change := range event.Changes {
+		id := change.GetService().GetId()
+		if change.Server != nil {
+			id = change.Server.ServiceID
+		}
+		if s.readable(id) {
+			changes = append(changes, change)
+		}
+	}
+	if len(changes) == 0 && !event.Initial {
+		return nil
+	}
+	return s.ServerStream.SendMsg(&types.WatchEvent{Changes: changes, Initial: event.Initial})
+}
@@ -0,0 +1,111 @@
+package daemon
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sky-uk/merlin/rbac"
+	"github.com/sky-uk/merlin/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// fakeServerStream records the messages sent to it.
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	sent []interface{}
+}
+
+func (s *fakeServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *fakeServerStream) SetHeader(metadata.MD) error {
+	return nil
+}
+
+func (s *fakeServerStream) SendMsg(m interface{}) error {
+	s.sent = append(s.sent, m)
+	return nil
+}
+
+var _ = Describe("Streams", func() {
+	var (
+		d      *Daemon
+		stream *fakeServerStream
+	)
+
+	change := func(serviceID string) *types.Change {
+		return &types.Change{Action: types.Change_CREATE, Service: &types.VirtualService{Id: serviceID}}
+	}
+	watch := func(token string, events ...*types.WatchEvent) error {
+		stream.ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+		info := &grpc.StreamServerInfo{FullMethod: "/types.Merlin/Watch", IsServerStream: true}
+		return d.interceptStream(nil, stream, info, func(_ interface{}, ss grpc.ServerStream) error {
+			for _, event := range events {
+				Expect(ss.SendMsg(event)).To(Succeed())
+			}
+			return nil
+		})
+	}
+
+	BeforeEach(func() {
+		policy, err := rbac.ParsePolicy([]byte(`
+rules:
+- clients: [payments]
+  operations: [read]
+  services: [payments-]
+- clients: [ops]
+  operations: [read]
+`))
+		Expect(err).ToNot(HaveOccurred())
+		d = &Daemon{opts: Options{
+			APITokens: map[string]string{"payments": "p4y", "ops": "0ps"},
+			Policy:    policy,
+		}}
+		stream = &fakeServerStream{}
+	})
+
+	It("should authenticate streams", func() {
+		Expect(status.Code(watch("wrong"))).To(Equal(codes.Unauthenticated))
+	})
+
+	It("should only send changes to the services the client can read", func() {
+		Expect(watch("p4y",
+			&types.WatchEvent{Initial: true, Changes: []*types.Change{change("payments-web"), change("search")}},
+			&types.WatchEvent{Changes: []*types.Change{change("search")}},
+			&types.WatchEvent{Changes: []*types.Change{change("payments-api")}},
+		)).To(Succeed())
+
+		Expect(stream.sent).To(Equal([]interface{}{
+			&types.WatchEvent{Initial: true, Changes: []*types.Change{change("payments-web")}},
+			&types.WatchEvent{Changes: []*types.Change{change("payments-api")}},
+		}))
+	})
+
+	It("should send every change to clients which can read every service", func() {
+		event := &types.WatchEvent{Changes: []*types.Change{change("search")}}
+		Expect(watch("0ps", event)).To(Succeed())
+
+		Expect(stream.sent).To(Equal([]interface{}{event}))
+	})
+
+	It("should only send the connections of the services the client can read", func() {
+		stream.ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer p4y"))
+		info := &grpc.StreamServerInfo{FullMethod: "/types.Merlin/ListConnections", IsServerStream: true}
+		conn := &types.Connection{ServiceId: "payments-web"}
+
+		Expect(d.interceptStream(nil, stream, info, func(_ interface{}, ss grpc.ServerStream) error {
+			Expect(ss.SendMsg(conn)).To(Succeed())
+			Expect(ss.SendMsg(&types.Connection{ServiceId: "search"})).To(Succeed())
+			Expect(ss.SendMsg(&types.Connection{})).To(Succeed())
+			return nil
+		})).To(Succeed())
+
+		Expect(stream.sent).To(Equal([]interface{}{conn}))
+	})
+})
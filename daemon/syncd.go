@@ -0,0 +1,74 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	log "github.com/sirupsen/logrus"
+	"github.com/sky-uk/merlin/ipvs"
+	"github.com/sky-uk/merlin/types"
+)
+
+// syncDaemonManager keeps the IPVS connection sync daemon of this node running in its role, so a backup which takes
+// over the VIPs of its master inherits its connections. The daemon is left running when merlin stops, so restarting
+// merlin doesn't interrupt the sync.
+type syncDaemonManager struct {
+	ipvs  ipvs.IPVS
+	iface string
+	id    uint32
+	mu    sync.Mutex
+	role  types.SyncDaemon_Role
+}
+
+func newSyncDaemonManager(i ipvs.IPVS, iface string, id uint32, role types.SyncDaemon_Role) *syncDaemonManager {
+	return &syncDaemonManager{ipvs: i, iface: iface, id: id, role: role}
+}
+
+// setRole switches the sync daemon to role, stopping it if the role is unset. The role is kept if switching fails,
+// so it's retried by the next ensure.
+func (m *syncDaemonManager) setRole(ctx context.Context, role types.SyncDaemon_Role) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if role != m.role {
+		log.Infof("Switching the sync daemon from %v to %v", m.role, role)
+	}
+	m.role = role
+	return m.ensureLocked(ctx)
+}
+
+// ensure the kernel is running the sync daemon in this node's role, and no other. A daemon which was stopped, such as
+// by ip_vs being reloaded, or changed with ipvsadm, is restarted.
+func (m *syncDaemonManager) ensure(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.ensureLocked(ctx)
+}
+
+func (m *syncDaemonManager) ensureLocked(ctx context.Context) error {
+	desired := &types.SyncDaemon{Role: m.role, Interface: m.iface, SyncId: m.id}
+	running, err := m.ipvs.SyncDaemons(ctx)
+	if err != nil {
+		return err
+	}
+	started := false
+	for _, daemon := range running {
+		if proto.Equal(daemon, desired) {
+			started = true
+			continue
+		}
+		log.Infof("Stopping the %v sync daemon on %s with sync ID %d", daemon.Role, daemon.Interface, daemon.SyncId)
+		if err := m.ipvs.StopSyncDaemon(ctx, daemon.Role); err != nil {
+			return fmt.Errorf("unable to stop the %v sync daemon: %v", daemon.Role, err)
+		}
+	}
+	if started || m.role == types.SyncDaemon_UNSET_SYNC_ROLE {
+		return nil
+	}
+	log.Infof("Starting the %v sync daemon on %s with sync ID %d", m.role, m.iface, m.id)
+	if err := m.ipvs.StartSyncDaemon(ctx, desired); err != nil {
+		return fmt.Errorf("unable to start the %v sync daemon: %v", m.role, err)
+	}
+	return nil
+}
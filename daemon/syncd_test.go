@@ -0,0 +1,53 @@
+package daemon
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sky-uk/merlin/ipvs"
+	"github.com/sky-uk/merlin/types"
+)
+
+var _ = Describe("Sync daemon", func() {
+	var (
+		ctx    = context.Background()
+		kernel ipvs.IPVS
+		m      *syncDaemonManager
+	)
+
+	master := &types.SyncDaemon{Role: types.SyncDaemon_MASTER, Interface: "eth0", SyncId: 7}
+	backup := &types.SyncDaemon{Role: types.SyncDaemon_BACKUP, Interface: "eth0", SyncId: 7}
+
+	BeforeEach(func() {
+		kernel = ipvs.NewMemory()
+		m = newSyncDaemonManager(kernel, "eth0", 7, types.SyncDaemon_MASTER)
+	})
+
+	It("should start the sync daemon in its role", func() {
+		Expect(m.ensure(ctx)).To(Succeed())
+		Expect(m.ensure(ctx)).To(Succeed())
+
+		Expect(kernel.SyncDaemons(ctx)).To(Equal([]*types.SyncDaemon{master}))
+	})
+
+	It("should switch roles, stopping the daemon of the old role", func() {
+		Expect(m.ensure(ctx)).To(Succeed())
+
+		Expect(m.setRole(ctx, types.SyncDaemon_BACKUP)).To(Succeed())
+		Expect(kernel.SyncDaemons(ctx)).To(Equal([]*types.SyncDaemon{backup}))
+
+		Expect(m.setRole(ctx, types.SyncDaemon_UNSET_SYNC_ROLE)).To(Succeed())
+		Expect(kernel.SyncDaemons(ctx)).To(BeEmpty())
+	})
+
+	It("should restart daemons changed by something else", func() {
+		Expect(kernel.StartSyncDaemon(ctx, &types.SyncDaemon{Role: types.SyncDaemon_MASTER, Interface: "eth1"})).
+			To(Succeed())
+		Expect(kernel.StartSyncDaemon(ctx, backup)).To(Succeed())
+
+		Expect(m.ensure(ctx)).To(Succeed())
+
+		Expect(kernel.SyncDaemons(ctx)).To(Equal([]*types.SyncDaemon{master}))
+	})
+})
@@ -0,0 +1,178 @@
+package daemon
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// renewTimeout is how long the renew command can run for.
+	renewTimeout = 5 * time.Minute
+	// renewRetryPeriod is how long to wait before running the renew command again after it fails, so an ACME CA's
+	// rate limits aren't exhausted.
+	renewRetryPeriod = 10 * time.Minute
+)
+
+// certReloader serves the certificate of a cert and key file, reloading it when either changes, so short-lived
+// certificates can be rotated without restarting the API.
+type certReloader struct {
+	certFile string
+	keyFile  string
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+	leaf     *x509.Certificate
+	// version of the files the certificate was loaded from
+	version string
+
+	// renewCommand obtains or renews the certificate, such as an ACME client, if set.
+	renewCommand string
+	// renewBefore is how long before the certificate expires to renew it, or 0 for a third of its lifetime.
+	renewBefore time.Duration
+	// nextRenewal is the earliest time to run renewCommand again after it failed.
+	nextRenewal time.Time
+}
+
+// newCertReloader loads the certificate of certFile and keyFile. If renewCommand is set, it's run first if
+// certFile doesn't exist, and then whenever the certificate is within renewBefore of expiring.
+func newCertReloader(certFile, keyFile, renewCommand string, renewBefore time.Duration) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile, renewCommand: renewCommand, renewBefore: renewBefore}
+	if _, err := os.Stat(certFile); os.IsNotExist(err) && renewCommand != "" {
+		log.Infof("Obtaining TLS certificate, as %s doesn't exist", certFile)
+		if err := r.runRenewCommand(); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// reload the certificate if its files have changed since it was loaded, returning true if it was. The current
+// certificate is kept if the files can't be loaded, such as when only one of them has been replaced so far, and
+// loading is retried on the next reload.
+func (r *certReloader) reload() (bool, error) {
+	var version string
+	for _, file := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return false, err
+		}
+		version += fmt.Sprintf("%d:%d;", info.ModTime().UnixNano(), info.Size())
+	}
+
+	r.mu.RLock()
+	unchanged := version == r.version
+	r.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return false, err
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return false, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cert = &cert
+	r.leaf = leaf
+	r.version = version
+	return true, nil
+}
+
+// renewalDue returns true if the certificate should be renewed at now.
+func (r *certReloader) renewalDue(now time.Time) bool {
+	if r.renewCommand == "" || now.Before(r.nextRenewal) {
+		return false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	before := r.renewBefore
+	if before == 0 {
+		before = r.leaf.NotAfter.Sub(r.leaf.NotBefore) / 3
+	}
+	return !now.Before(r.leaf.NotAfter.Add(-before))
+}
+
+// renew the certificate with the renew command, retrying no sooner than renewRetryPeriod if it fails. The files
+// it writes are loaded by the next reload.
+func (r *certReloader) renew(now time.Time) {
+	r.mu.RLock()
+	expiry := r.leaf.NotAfter
+	r.mu.RUnlock()
+	log.Infof("Renewing TLS certificate, which expires at %v", expiry)
+	if err := r.runRenewCommand(); err != nil {
+		r.nextRenewal = now.Add(renewRetryPeriod)
+		log.Warnf("Unable to renew TLS certificate, retrying in %v: %v", renewRetryPeriod, err)
+	}
+}
+
+func (r *certReloader) runRenewCommand() error {
+	ctx, cancel := context.WithTimeout(context.Background(), renewTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "sh", "-c", r.renewCommand).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("tls renew command failed: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	log.Debugf("TLS renew command output: %s", out)
+	return nil
+}
+
+// GetCertificate returns the current certificate, for tls.Config.
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// watch the files every period until stopped, reloading the certificate when they change, and renewing it when
+// it's due. New connections use the reloaded certificate, while existing connections are unaffected.
+func (r *certReloader) watch(period time.Duration, stopCh <-chan struct{}) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if now := time.Now(); r.renewalDue(now) {
+				r.renew(now)
+			}
+			if reloaded, err := r.reload(); err != nil {
+				log.Warnf("Unable to reload TLS certificate, still serving the current one: %v", err)
+			} else if reloaded {
+				log.Infof("Reloaded TLS certificate from %s", r.certFile)
+			}
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+// certPool returns the certificates of a PEM file, or nil for the system roots if file is empty.
+func certPool(file string) (*x509.CertPool, error) {
+	if file == "" {
+		return nil, nil
+	}
+	pem, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", file)
+	}
+	return pool, nil
+}
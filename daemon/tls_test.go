@@ -0,0 +1,249 @@
+package daemon
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sky-uk/merlin/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+)
+
+var _ = Describe("TLS", func() {
+	var (
+		dir      string
+		certFile string
+		keyFile  string
+		modTime  time.Time
+	)
+
+	// writeCert writes a self-signed certificate for name, with a later modification time than the last one.
+	writeCert := func(name string) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(1),
+			Subject:      pkix.Name{CommonName: name},
+			NotBefore:    time.Now(),
+			NotAfter:     time.Now().Add(time.Hour),
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+		Expect(err).ToNot(HaveOccurred())
+		keyDER, err := x509.MarshalECPrivateKey(key)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+			0600)).To(Succeed())
+		Expect(ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+			0600)).To(Succeed())
+		modTime = modTime.Add(time.Second)
+		Expect(os.Chtimes(certFile, modTime, modTime)).To(Succeed())
+		Expect(os.Chtimes(keyFile, modTime, modTime)).To(Succeed())
+	}
+
+	commonName := func(r *certReloader) string {
+		cert, err := r.GetCertificate(nil)
+		Expect(err).ToNot(HaveOccurred())
+		parsed, err := x509.ParseCertificate(cert.Certificate[0])
+		Expect(err).ToNot(HaveOccurred())
+		return parsed.Subject.CommonName
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "merlin-tls")
+		Expect(err).ToNot(HaveOccurred())
+		certFile = filepath.Join(dir, "tls.crt")
+		keyFile = filepath.Join(dir, "tls.key")
+		modTime = time.Now().Add(-time.Hour)
+		writeCert("first")
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("should reload the certificate when it changes", func() {
+		r, err := newCertReloader(certFile, keyFile, "", 0)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(r.reload()).To(BeFalse())
+
+		writeCert("second")
+
+		Expect(r.reload()).To(BeTrue())
+		Expect(commonName(r)).To(Equal("second"))
+	})
+
+	It("should keep the current certificate until both files are replaced", func() {
+		r, err := newCertReloader(certFile, keyFile, "", 0)
+		Expect(err).ToNot(HaveOccurred())
+		key, err := ioutil.ReadFile(keyFile)
+		Expect(err).ToNot(HaveOccurred())
+
+		writeCert("second")
+		Expect(ioutil.WriteFile(keyFile, key, 0600)).To(Succeed())
+
+		_, err = r.reload()
+		Expect(err).To(HaveOccurred())
+		Expect(commonName(r)).To(Equal("first"))
+	})
+
+	It("should obtain the certificate with the renew command if it doesn't exist", func() {
+		writeCert("obtained")
+		newCert, newKey := filepath.Join(dir, "new.crt"), filepath.Join(dir, "new.key")
+		Expect(os.Rename(certFile, newCert)).To(Succeed())
+		Expect(os.Rename(keyFile, newKey)).To(Succeed())
+		command := fmt.Sprintf("cp %s %s && cp %s %s", newCert, certFile, newKey, keyFile)
+
+		r, err := newCertReloader(certFile, keyFile, command, 0)
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(commonName(r)).To(Equal("obtained"))
+	})
+
+	It("should renew the certificate in the last third of its lifetime by default", func() {
+		r, err := newCertReloader(certFile, keyFile, "true", 0)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(r.renewalDue(time.Now().Add(30 * time.Minute))).To(BeFalse())
+		Expect(r.renewalDue(time.Now().Add(45 * time.Minute))).To(BeTrue())
+	})
+
+	It("should renew the certificate within the renew before period", func() {
+		r, err := newCertReloader(certFile, keyFile, "true", 50*time.Minute)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(r.renewalDue(time.Now().Add(15 * time.Minute))).To(BeTrue())
+	})
+
+	It("should wait before retrying a failed renewal", func() {
+		r, err := newCertReloader(certFile, keyFile, "exit 1", 0)
+		Expect(err).ToNot(HaveOccurred())
+		due := time.Now().Add(45 * time.Minute)
+
+		r.renew(due)
+
+		Expect(r.renewalDue(due)).To(BeFalse())
+		Expect(r.renewalDue(due.Add(renewRetryPeriod))).To(BeTrue())
+	})
+
+	It("should refuse to start without a valid certificate", func() {
+		Expect(ioutil.WriteFile(certFile, []byte("invalid"), 0600)).To(Succeed())
+
+		_, err := newCertReloader(certFile, keyFile, "", 0)
+
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should serve HTTP on the API port alongside gRPC over TLS", func() {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).ToNot(HaveOccurred())
+		addr := lis.Addr().String()
+		d := New(Options{
+			Listener:    lis,
+			Store:       &fakeStore{},
+			NodeName:    "node1",
+			Registerer:  prometheus.NewRegistry(),
+			TLSCertFile: certFile,
+			TLSKeyFile:  keyFile,
+			HTTPHandler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(r.Proto))
+			}),
+		})
+		Expect(d.Start()).To(Succeed())
+		clientTLS := &tls.Config{InsecureSkipVerify: true}
+
+		conn, err := grpc.Dial(addr, grpc.WithTransportCredentials(credentials.NewTLS(clientTLS)))
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.Close()
+		_, err = types.NewMerlinClient(conn).List(context.Background(), &types.ListRequest{})
+		Expect(err).ToNot(HaveOccurred())
+
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS}}
+		resp, err := client.Get("https://" + addr + "/health")
+		Expect(err).ToNot(HaveOccurred())
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(body)).To(Equal("HTTP/1.1"))
+
+		Expect(d.Stop(time.Second)).To(Succeed())
+	})
+
+	Context("with a client CA", func() {
+		// the self-signed certificate is its own CA, so it's presented by clients too
+		start := func(handler http.Handler) (string, *Daemon) {
+			lis, err := net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).ToNot(HaveOccurred())
+			d := New(Options{
+				Listener:        lis,
+				Store:           &fakeStore{},
+				NodeName:        "node1",
+				Registerer:      prometheus.NewRegistry(),
+				TLSCertFile:     certFile,
+				TLSKeyFile:      keyFile,
+				TLSClientCAFile: certFile,
+				HTTPHandler:     handler,
+			})
+			Expect(d.Start()).To(Succeed())
+			return lis.Addr().String(), d
+		}
+		list := func(addr string, clientTLS *tls.Config) error {
+			conn, err := grpc.Dial(addr, grpc.WithTransportCredentials(credentials.NewTLS(clientTLS)))
+			Expect(err).ToNot(HaveOccurred())
+			defer conn.Close()
+			_, err = types.NewMerlinClient(conn).List(context.Background(), &types.ListRequest{})
+			return err
+		}
+		withCert := func() *tls.Config {
+			cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+			Expect(err).ToNot(HaveOccurred())
+			return &tls.Config{InsecureSkipVerify: true, Certificates: []tls.Certificate{cert}}
+		}
+
+		It("should only serve clients with a certificate signed by it", func() {
+			addr, d := start(nil)
+
+			Expect(list(addr, &tls.Config{InsecureSkipVerify: true})).ToNot(Succeed())
+			Expect(list(addr, withCert())).To(Succeed())
+
+			Expect(d.Stop(time.Second)).To(Succeed())
+		})
+
+		It("should serve HTTP on the API port without a client certificate", func() {
+			addr, d := start(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			clientTLS := &tls.Config{InsecureSkipVerify: true}
+
+			err := list(addr, clientTLS)
+			Expect(status.Code(err)).To(Equal(codes.Unauthenticated))
+			Expect(list(addr, withCert())).To(Succeed())
+
+			client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS}}
+			resp, err := client.Get("https://" + addr + "/health")
+			Expect(err).ToNot(HaveOccurred())
+			resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			Expect(d.Stop(time.Second)).To(Succeed())
+		})
+	})
+})
@@ -0,0 +1,108 @@
+package daemon
+
+import (
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/sky-uk/merlin/reconciler"
+	"github.com/sky-uk/merlin/vip"
+)
+
+// vipSyncTimeout is how long configuring the VIPs can take.
+const vipSyncTimeout = 30 * time.Second
+
+// vipManager configures the VIP of every service reconciled onto this node on an interface, when the store changes
+// and every period. VIPs are left configured when merlin stops, so restarting it doesn't drop traffic, unless this
+// node is passive.
+type vipManager struct {
+	vips   vip.Interface
+	store  reconciler.Store
+	period time.Duration
+	// paused returns true while this node is in maintenance, when VIPs are left untouched like IPVS.
+	paused func() bool
+	// active returns false while this node is passive, when its VIPs are deleted so only the active node has them.
+	active func() bool
+	syncCh chan struct{}
+	stopCh chan struct{}
+	doneCh chan struct{}
+}
+
+func newVIPManager(vips vip.Interface, st reconciler.Store, period time.Duration, paused,
+	active func() bool) *vipManager {
+	return &vipManager{
+		vips:   vips,
+		store:  st,
+		period: period,
+		paused: paused,
+		active: active,
+		syncCh: make(chan struct{}, 1),
+		stopCh: make(chan struct{}),
+		doneCh: make(chan struct{}),
+	}
+}
+
+func (m *vipManager) start() {
+	m.sync()
+	go m.run()
+}
+
+// sync the VIPs in the background, unless a sync is already pending.
+func (m *vipManager) sync() {
+	select {
+	case m.syncCh <- struct{}{}:
+	default:
+	}
+}
+
+func (m *vipManager) run() {
+	defer close(m.doneCh)
+	ticker := time.NewTicker(m.period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.syncCh:
+		case <-ticker.C:
+		case <-m.stopCh:
+			return
+		}
+		if m.paused() && m.active() {
+			continue
+		}
+		m.syncVIPs()
+	}
+}
+
+func (m *vipManager) syncVIPs() {
+	ctx, cancel := context.WithTimeout(context.Background(), vipSyncTimeout)
+	defer cancel()
+	if err := m.configure(ctx); err != nil {
+		log.Warnf("Unable to configure VIPs: %v", err)
+	}
+}
+
+func (m *vipManager) configure(ctx context.Context) error {
+	if !m.active() {
+		return vip.Sync(ctx, m.vips, nil)
+	}
+	svcs, err := m.store.ListServices(ctx)
+	if err != nil {
+		return err
+	}
+	var vips []string
+	for _, svc := range svcs {
+		vips = append(vips, svc.GetKey().GetIp())
+	}
+	return vip.Sync(ctx, m.vips, vips)
+}
+
+// stop syncing, waiting for an in-flight sync to finish, and close the interface. The VIPs are deleted if this node
+// is passive, such as after resigning as active, so the node taking over is the only one with them.
+func (m *vipManager) stop() {
+	close(m.stopCh)
+	<-m.doneCh
+	if !m.active() {
+		m.syncVIPs()
+	}
+	m.vips.Close()
+}
@@ -0,0 +1,91 @@
+package daemon
+
+import (
+	"context"
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sky-uk/merlin/store"
+	"github.com/sky-uk/merlin/types"
+	"github.com/sky-uk/merlin/vip"
+)
+
+var _ = Describe("VIPs", func() {
+	var (
+		ctx    = context.Background()
+		st     store.Store
+		vips   vip.Interface
+		paused bool
+		active bool
+		m      *vipManager
+	)
+
+	configured := func() []string {
+		ips, err := vips.List(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		var addrs []string
+		for _, ip := range ips {
+			addrs = append(addrs, ip.String())
+		}
+		return addrs
+	}
+	service := func(id, ip string) *types.VirtualService {
+		return &types.VirtualService{Id: id, Key: &types.VirtualService_Key{Ip: ip, Port: 80}}
+	}
+
+	BeforeEach(func() {
+		st = store.NewMemory()
+		vips = vip.NewMemory()
+		paused = false
+		active = true
+		m = newVIPManager(vips, st, time.Hour, func() bool { return paused }, func() bool { return active })
+		Expect(st.PutService(ctx, service("web", "10.0.0.1"))).To(Succeed())
+		Expect(st.PutService(ctx, service("web-tls", "10.0.0.1"))).To(Succeed())
+		Expect(vips.Add(ctx, net.ParseIP("10.0.0.9"))).To(Succeed())
+	})
+
+	AfterEach(func() {
+		if m != nil {
+			m.stop()
+		}
+	})
+
+	It("should configure the VIPs of services and delete the rest", func() {
+		m.start()
+
+		Eventually(configured).Should(ConsistOf("10.0.0.1"))
+
+		Expect(st.PutService(ctx, service("api", "10.0.0.2"))).To(Succeed())
+		m.sync()
+
+		Eventually(configured).Should(ConsistOf("10.0.0.1", "10.0.0.2"))
+	})
+
+	It("should leave VIPs untouched while paused", func() {
+		paused = true
+		m.start()
+
+		Consistently(configured, 100*time.Millisecond).Should(ConsistOf("10.0.0.9"))
+	})
+
+	It("should delete the VIPs while passive, even if paused", func() {
+		active = false
+		paused = true
+		m.start()
+
+		Eventually(configured).Should(BeEmpty())
+	})
+
+	It("should delete the VIPs when stopped after becoming passive", func() {
+		m.start()
+		Eventually(configured).Should(ConsistOf("10.0.0.1"))
+
+		active = false
+		m.stop()
+		m = nil
+
+		Expect(configured()).To(BeEmpty())
+	})
+})
@@ -3,6 +3,7 @@ package api
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"time"
 
 	"testing"
@@ -20,6 +21,7 @@ import (
 	"github.com/sky-uk/merlin/types"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 )
 
@@ -144,6 +146,12 @@ func testApi(storeBackend string) {
 				Key:    validKey,
 				Config: &types.VirtualService_Config{},
 			}),
+			Entry("invalid label", &types.VirtualService{
+				Id:     "service1",
+				Key:    validKey,
+				Config: &types.VirtualService_Config{Scheduler: "sh"},
+				Labels: map[string]string{"team": "pay ments"},
+			}),
 		)
 
 		It("should return codes.AlreadyExists if already exists", func() {
@@ -188,6 +196,44 @@ func testApi(storeBackend string) {
 		})
 	})
 
+	Describe("List", func() {
+		BeforeEach(func() {
+			_, err := client.CreateService(ctx, &types.VirtualService{
+				Id:     "service1",
+				Key:    validKey,
+				Config: validConfig,
+				Labels: map[string]string{"team": "payments"},
+			})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		DescribeTable("filters services", func(req *types.ListRequest, count int) {
+			resp, err := client.List(ctx, req)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resp.Items).To(HaveLen(count))
+		},
+			Entry("no selectors", &types.ListRequest{}, 1),
+			Entry("matching label selector", &types.ListRequest{LabelSelector: "team=payments"}, 1),
+			Entry("other label selector", &types.ListRequest{LabelSelector: "team=search"}, 0),
+			Entry("matching field selector", &types.ListRequest{
+				FieldSelector: fmt.Sprintf("ip=%s,port=%d", validKey.Ip, validKey.Port)}, 1),
+			Entry("other field selector", &types.ListRequest{FieldSelector: "scheduler=not-a-scheduler"}, 0),
+		)
+
+		DescribeTable("should return codes.InvalidArgument for invalid selectors", func(req *types.ListRequest) {
+			_, err := client.List(ctx, req)
+			status, ok := status.FromError(err)
+
+			Expect(ok).To(BeTrue(), "got grpc status error")
+			Expect(status.Code()).To(Equal(codes.InvalidArgument),
+				"expected InvalidArgument, but got %v", err)
+		},
+			Entry("invalid label selector", &types.ListRequest{LabelSelector: "team=pay ments"}),
+			Entry("unknown field", &types.ListRequest{FieldSelector: "weight=1"}),
+		)
+	})
+
 	Context("Real Servers", func() {
 
 		var (
@@ -202,7 +248,7 @@ func testApi(storeBackend string) {
 			}
 			validServerConfig = &types.RealServer_Config{
 				Weight:  &wrappers.UInt32Value{Value: 2},
-				Forward: types.ForwardMethod_ROUTE,
+				Forward: types.ForwardMethod_MASQ,
 			}
 		)
 
@@ -428,7 +474,7 @@ func testApi(storeBackend string) {
 				_, err = client.UpdateServer(ctx, update)
 				Expect(err).ToNot(HaveOccurred())
 
-				resp, err := client.List(ctx, &empty.Empty{})
+				resp, err := client.List(ctx, &types.ListRequest{})
 				Expect(err).ToNot(HaveOccurred())
 				Expect(resp.Items).To(HaveLen(1))
 				item := resp.Items[0]
@@ -509,6 +555,219 @@ func testApi(storeBackend string) {
 				}),
 			)
 		})
+
+		Describe("CloneService and RenameService", func() {
+			BeforeEach(func() {
+				_, err := client.CreateServer(ctx, &types.RealServer{
+					ServiceID: service.Id,
+					Key:       validServerKey,
+					Config:    validServerConfig,
+				})
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			listServiceIDs := func() map[string]int {
+				resp, err := client.List(ctx, &types.ListRequest{})
+				Expect(err).ToNot(HaveOccurred())
+				ids := make(map[string]int)
+				for _, item := range resp.Items {
+					ids[item.Service.Id] = len(item.Servers)
+				}
+				return ids
+			}
+
+			It("should clone a service and its servers with a new key", func() {
+				_, err := client.CloneService(ctx, &types.CloneServiceRequest{
+					Id:    service.Id,
+					NewId: "service2",
+					Key:   &types.VirtualService_Key{Port: 8081},
+				})
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(listServiceIDs()).To(Equal(map[string]int{"service1": 1, "service2": 1}))
+			})
+
+			It("should rename a service and its servers", func() {
+				_, err := client.RenameService(ctx, &types.RenameServiceRequest{Id: service.Id, NewId: "service2"})
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(listServiceIDs()).To(Equal(map[string]int{"service2": 1}))
+			})
+
+			DescribeTable("should return an error code", func(call func() error, code codes.Code) {
+				err := call()
+				status, ok := status.FromError(err)
+
+				Expect(ok).To(BeTrue(), "got grpc status error")
+				Expect(status.Code()).To(Equal(code), "expected %v, but got %v", code, err)
+			},
+				Entry("clone with the same key", func() error {
+					_, err := client.CloneService(ctx, &types.CloneServiceRequest{Id: service.Id, NewId: "service2"})
+					return err
+				}, codes.InvalidArgument),
+				Entry("clone of a missing service", func() error {
+					_, err := client.CloneService(ctx, &types.CloneServiceRequest{Id: "missing", NewId: "service2",
+						Key: &types.VirtualService_Key{Port: 8081}})
+					return err
+				}, codes.NotFound),
+				Entry("rename to an existing service", func() error {
+					_, err := client.RenameService(ctx, &types.RenameServiceRequest{Id: service.Id, NewId: service.Id})
+					return err
+				}, codes.AlreadyExists),
+				Entry("rename without a new id", func() error {
+					_, err := client.RenameService(ctx, &types.RenameServiceRequest{Id: service.Id})
+					return err
+				}, codes.InvalidArgument),
+			)
+		})
+	})
+
+	Describe("Stats", func() {
+		It("should return codes.FailedPrecondition if ipvs is disabled", func() {
+			_, err := client.Stats(ctx, &empty.Empty{})
+			status, ok := status.FromError(err)
+
+			Expect(ok).To(BeTrue(), "got grpc status error")
+			Expect(status.Code()).To(Equal(codes.FailedPrecondition),
+				"expected FailedPrecondition, but got %v", err)
+		})
+	})
+
+	Describe("GetNodeState", func() {
+		It("should return codes.FailedPrecondition if ipvs is disabled", func() {
+			_, err := client.GetNodeState(ctx, &empty.Empty{})
+
+			Expect(status.Code(err)).To(Equal(codes.FailedPrecondition),
+				"expected FailedPrecondition, but got %v", err)
+		})
+	})
+
+	Describe("DescribeService", func() {
+		It("should return the service, its servers and its history", func() {
+			_, err := client.CreateService(ctx, &types.VirtualService{Id: "service1", Key: validKey, Config: validConfig})
+			Expect(err).ToNot(HaveOccurred())
+			server := &types.RealServer{
+				ServiceID: "service1",
+				Key:       &types.RealServer_Key{Ip: "172.16.1.1", Port: 8080},
+				Config: &types.RealServer_Config{
+					Weight:  &wrappers.UInt32Value{Value: 1},
+					Forward: types.ForwardMethod_ROUTE,
+				},
+			}
+			_, err = client.CreateServer(ctx, server)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = client.DrainServer(ctx, server)
+			Expect(err).ToNot(HaveOccurred())
+
+			resp, err := client.DescribeService(ctx, &types.DescribeServiceRequest{Id: "service1", History: 2})
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resp.Service.Id).To(Equal("service1"))
+			Expect(resp.Servers).To(HaveLen(1))
+			Expect(resp.Servers[0].Server.Config.Weight.GetValue()).To(BeZero())
+			Expect(resp.Errors).To(BeEmpty())
+			Expect(resp.History).To(HaveLen(2))
+			Expect(resp.History[0].Change.Action).To(Equal(types.Change_CREATE))
+			Expect(resp.History[0].Change.Server.Key).To(Equal(server.Key))
+			Expect(resp.History[1].Change.Action).To(Equal(types.Change_UPDATE))
+		})
+
+		It("should return codes.NotFound if the service doesn't exist", func() {
+			_, err := client.DescribeService(ctx, &types.DescribeServiceRequest{Id: "service1"})
+
+			Expect(status.Code(err)).To(Equal(codes.NotFound), "expected NotFound, but got %v", err)
+		})
+	})
+
+	Describe("/ready", func() {
+		It("should return 200 once the store is reachable", func() {
+			resp, err := http.Get("http://localhost:" + MerlinHealthPort() + "/ready")
+
+			Expect(err).ToNot(HaveOccurred())
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		})
+
+		It("should return 500 if the store is unreachable", func() {
+			StopEtcd()
+
+			resp, err := http.Get("http://localhost:" + MerlinHealthPort() + "/ready")
+
+			Expect(err).ToNot(HaveOccurred())
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+		})
+	})
+
+	Describe("Info", func() {
+		It("should return the node and a count of the store contents", func() {
+			_, err := client.CreateService(ctx, &types.VirtualService{Id: "service1", Key: validKey, Config: validConfig})
+			Expect(err).ToNot(HaveOccurred())
+
+			info, err := client.Info(ctx, &empty.Empty{})
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.Node.StoreBackend).To(Equal(storeBackend))
+			Expect(info.Node.Reconcile).To(BeFalse())
+			Expect(info.StoreError).To(BeEmpty())
+			Expect(info.Services).To(Equal(uint32(1)))
+			Expect(info.Servers).To(BeZero())
+		})
+
+		It("should advertise the API version", func() {
+			var header metadata.MD
+
+			_, err := client.Info(ctx, &empty.Empty{}, grpc.Header(&header))
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(header.Get(types.APIVersionHeader)).To(Equal([]string{types.APIVersion}))
+		})
+	})
+
+	Describe("ListNodes", func() {
+		It("should return the registered nodes", func() {
+			Eventually(func() []*types.Node {
+				resp, err := client.ListNodes(ctx, &empty.Empty{})
+				Expect(err).ToNot(HaveOccurred())
+				return resp.Nodes
+			}).Should(HaveLen(1))
+		})
+
+		It("should mark the elected leader with the address writes are forwarded to", func() {
+			Eventually(func() bool {
+				resp, err := client.ListNodes(ctx, &empty.Empty{})
+				Expect(err).ToNot(HaveOccurred())
+				return len(resp.Nodes) == 1 && resp.Nodes[0].Leader
+			}, 3*time.Second).Should(BeTrue())
+
+			resp, err := client.ListNodes(ctx, &empty.Empty{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resp.Nodes[0].Address).To(Equal(MerlinNodeName + ":" + MerlinPort()))
+		})
+	})
+
+	Describe("SetMaintenance", func() {
+		It("should pause the node on its next heartbeat", func() {
+			Eventually(func() error {
+				_, err := client.SetMaintenance(ctx, &types.SetMaintenanceRequest{Node: MerlinNodeName, Enabled: true})
+				return err
+			}).Should(Succeed())
+
+			Eventually(func() bool {
+				resp, err := client.ListNodes(ctx, &empty.Empty{})
+				Expect(err).ToNot(HaveOccurred())
+				return len(resp.Nodes) == 1 && resp.Nodes[0].Maintenance
+			}, 3*time.Second).Should(BeTrue())
+		})
+
+		It("should return codes.NotFound for an unregistered node", func() {
+			_, err := client.SetMaintenance(ctx, &types.SetMaintenanceRequest{Node: "unknown", Enabled: true})
+			status, ok := status.FromError(err)
+
+			Expect(ok).To(BeTrue(), "got grpc status error")
+			Expect(status.Code()).To(Equal(codes.NotFound),
+				"expected NotFound, but got %v", err)
+		})
 	})
 }
 
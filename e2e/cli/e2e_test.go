@@ -3,9 +3,11 @@ package cli
 import (
 	"bytes"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"strings"
+	"syscall"
 
 	"net/http"
 	"time"
@@ -14,6 +16,7 @@ import (
 
 	"github.com/coreos/etcd/clientv3"
 	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
 	. "github.com/sky-uk/merlin/e2e"
 )
@@ -80,6 +83,87 @@ func testCli(storeBackend string, etcdDel func(string)) {
 			Expect(out).NotTo(ContainElement(MatchRegexp(`.*service1.*`)))
 		})
 
+		It("can clone a service with its servers", func() {
+			meradm("service", "add", "service1", "tcp", "10.1.1.1:888", "-s=wrr")
+			meradm("server", "add", "service1", "172.16.1.1:555", "-w=2", "-f=masq")
+			meradm("service", "clone", "service1", "service2", "--port=889")
+
+			out := meradmList("--field-selector=id=service2")
+
+			Expect(out).To(ContainElement(MatchRegexp(`.*service2.*TCP.*10.1.1.1:889.*wrr.*`)))
+			Expect(out).To(ContainElement(MatchRegexp(`.*172.16.1.1:555.*MASQ.*2.*`)))
+			Expect(meradmList()).To(ContainElement(MatchRegexp(`.*service1.*TCP.*10.1.1.1:888.*`)))
+		})
+
+		It("can rename a service with its servers", func() {
+			meradm("service", "add", "service1", "tcp", "10.1.1.1:888", "-s=wrr")
+			meradm("server", "add", "service1", "172.16.1.1:555", "-w=2", "-f=masq")
+			meradm("service", "rename", "service1", "service2")
+
+			out := meradmList()
+
+			Expect(out).To(ContainElement(MatchRegexp(`.*service2.*TCP.*10.1.1.1:888.*wrr.*`)))
+			Expect(out).To(ContainElement(MatchRegexp(`.*172.16.1.1:555.*MASQ.*2.*`)))
+			Expect(out).NotTo(ContainElement(MatchRegexp(`.*service1.*`)))
+		})
+
+		It("can label a service", func() {
+			meradm("service", "add", "service1", "tcp", "10.1.1.1:888", "-s=wrr", "-l=team=payments")
+			Expect(meradmList()).To(ContainElement(MatchRegexp(`.*service1.*team=payments.*`)))
+
+			meradm("service", "edit", "service1", "-l=team=search,env=prod")
+			Expect(meradmList()).To(ContainElement(MatchRegexp(`.*service1.*env=prod,team=search.*`)))
+		})
+
+		It("can filter and sort the list", func() {
+			meradm("service", "add", "service1", "tcp", "10.1.1.2:888", "-s=wrr", "-l=team=payments")
+			meradm("service", "add", "service2", "udp", "10.1.1.1:999", "-s=rr", "-l=team=search")
+
+			out := meradmList("-l", "team=search")
+			Expect(out).To(ContainElement(ContainSubstring("service2")))
+			Expect(out).ToNot(ContainElement(ContainSubstring("service1")))
+
+			out = meradmList("--field-selector", "protocol=tcp,port=888")
+			Expect(out).To(ContainElement(ContainSubstring("service1")))
+			Expect(out).ToNot(ContainElement(ContainSubstring("service2")))
+
+			out = meradmList("--sort-by", "ip")
+			Expect(out[3]).To(ContainSubstring("service2"))
+			Expect(out[4]).To(ContainSubstring("service1"))
+		})
+
+		Context("delete by selector", func() {
+			BeforeEach(func() {
+				meradm("service", "add", "service1", "tcp", "10.1.1.1:888", "-s=wrr", "-l=team=payments")
+				meradm("service", "add", "service2", "tcp", "10.1.1.2:888", "-s=wrr", "-l=team=payments")
+				meradm("service", "add", "service3", "tcp", "10.1.1.3:888", "-s=wrr", "-l=team=search")
+				meradm("server", "add", "service1", "172.16.1.1:555", "-w=1", "-f=masq")
+			})
+
+			It("deletes matching services and their servers", func() {
+				out := meradm("delete", "services", "-l", "team=payments", "--servers", "--yes")
+				Expect(out).To(ContainSubstring("Deleted 2 services and 1 servers"))
+
+				list := meradmList()
+				Expect(list).ToNot(ContainElement(ContainSubstring("service1")))
+				Expect(list).ToNot(ContainElement(ContainSubstring("service2")))
+				Expect(list).ToNot(ContainElement(ContainSubstring("172.16.1.1")))
+				Expect(list).To(ContainElement(ContainSubstring("service3")))
+			})
+
+			It("doesn't delete on a dry run", func() {
+				out := meradm("delete", "services", "-l", "team=payments", "--dry-run")
+				Expect(out).To(ContainSubstring("Would delete 2 services"))
+
+				Expect(meradmList()).To(ContainElement(ContainSubstring("service1")))
+			})
+
+			It("requires a selector", func() {
+				_, err := meradmErrored("delete", "services", "--yes")
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
 		It("fails if required fields are unset", func() {
 			_, err := meradmErrored("service", "add", "service1", "tcp")
 			Expect(err).To(HaveOccurred(), "should have failed validation")
@@ -113,6 +197,23 @@ func testCli(storeBackend string, etcdDel func(string)) {
 				Expect(out).To(ContainElement(MatchRegexp(`.*172.16.1.1:555.*MASQ.*2.*`)))
 			})
 
+			It("lists connections and health as unknown without IPVS", func() {
+				meradm("server", "add", "service1", "172.16.1.1:555", "-w=2", "-f=masq")
+
+				out := meradmList()
+
+				Expect(out[0]).To(MatchRegexp(`ActiveConn\s+InActConn\s+Health`))
+				Expect(out).To(ContainElement(MatchRegexp(`.*172.16.1.1:555\s+MASQ\s+2\s+-\s+-\s+-\s*$`)))
+				Expect(out).ToNot(ContainElement(ContainSubstring("\033[")))
+			})
+
+			It("resolves a hostname", func() {
+				out := meradm("server", "add", "service1", "localhost:555", "-w=2", "-f=masq", "--resolve=4")
+
+				Expect(out).To(BeEmpty())
+				Expect(meradmList()).To(ContainElement(MatchRegexp(`.*127.0.0.1:555.*MASQ.*2.*`)))
+			})
+
 			It("syncs the reconciler", func() {
 				// flush stderr so far
 				MerlinStderr()
@@ -123,16 +224,16 @@ func testCli(storeBackend string, etcdDel func(string)) {
 		})
 
 		It("can edit a server", func() {
-			meradm("server", "add", "service1", "172.16.1.1:555", "-w=2", "-f=masq",
+			meradm("server", "add", "service1", "172.16.1.1:888", "-w=2", "-f=masq",
 				"--health-endpoint=http://:556/health", "--health-period=5s", "--health-timeout=1s",
 				"--health-up=2", "--health-down=1")
-			meradm("server", "edit", "service1", "172.16.1.1:555", "-w=5")
-			meradm("server", "edit", "service1", "172.16.1.1:555", "-f=route")
-			meradm("server", "edit", "service1", "172.16.1.1:555", "--health-period=10s")
+			meradm("server", "edit", "service1", "172.16.1.1:888", "-w=5")
+			meradm("server", "edit", "service1", "172.16.1.1:888", "-f=route")
+			meradm("server", "edit", "service1", "172.16.1.1:888", "--health-period=10s")
 
 			out := meradmList()
 
-			Expect(out).To(ContainElement(MatchRegexp(`.*172.16.1.1:555.*ROUTE.*5.*`)))
+			Expect(out).To(ContainElement(MatchRegexp(`.*172.16.1.1:888.*ROUTE.*5.*`)))
 			Expect(out).ToNot(ContainElement(MatchRegexp(`.*MASQ.*`)))
 			Expect(out).ToNot(ContainElement(MatchRegexp(`.* 2 .*`)))
 			Expect(out).To(ContainElement(MatchRegexp(`http://:556/health.*10s.*1s.*2/1`)))
@@ -148,13 +249,13 @@ func testCli(storeBackend string, etcdDel func(string)) {
 		})
 
 		It("can optionally set flags", func() {
-			meradm("server", "add", "service1", "172.16.1.1:555", "-f=masq", "-w=1")
-			meradm("server", "edit", "service1", "172.16.1.1:555", "-f=route")
-			meradm("server", "edit", "service1", "172.16.1.1:555", "-w=4")
+			meradm("server", "add", "service1", "172.16.1.1:888", "-f=masq", "-w=1")
+			meradm("server", "edit", "service1", "172.16.1.1:888", "-f=route")
+			meradm("server", "edit", "service1", "172.16.1.1:888", "-w=4")
 
 			out := meradmList()
 
-			Expect(out).To(ContainElement(MatchRegexp(`.*172.16.1.1:555.*ROUTE.*4.*`)))
+			Expect(out).To(ContainElement(MatchRegexp(`.*172.16.1.1:888.*ROUTE.*4.*`)))
 		})
 
 		It("requires flags are set when adding a service", func() {
@@ -162,6 +263,29 @@ func testCli(storeBackend string, etcdDel func(string)) {
 			Expect(err).To(HaveOccurred())
 		})
 
+		It("can drain and undrain a server", func() {
+			meradm("server", "add", "service1", "172.16.1.1:555", "-w=3", "-f=masq")
+			meradm("server", "drain", "service1", "172.16.1.1:555")
+
+			out := meradmList()
+
+			Expect(out).To(ContainElement(MatchRegexp(`.*172.16.1.1:555.*MASQ.*0 \(drained from 3\).*`)))
+
+			meradm("server", "undrain", "service1", "172.16.1.1:555")
+
+			out = meradmList()
+
+			Expect(out).To(ContainElement(MatchRegexp(`.*172.16.1.1:555.*MASQ.*3.*`)))
+			Expect(out).ToNot(ContainElement(ContainSubstring("drained")))
+		})
+
+		It("fails to undrain a server that isn't drained", func() {
+			meradm("server", "add", "service1", "172.16.1.1:555", "-w=3", "-f=masq")
+
+			_, err := meradmErrored("server", "undrain", "service1", "172.16.1.1:555")
+			Expect(err).To(HaveOccurred())
+		})
+
 		It("can disable a healthcheck", func() {
 			meradm("server", "add", "service1", "172.16.1.1:555", "-f=masq", "-w=1",
 				"--health-endpoint=http://:556/health", "--health-period=5s", "--health-timeout=1s",
@@ -174,6 +298,375 @@ func testCli(storeBackend string, etcdDel func(string)) {
 		})
 	})
 
+	Describe("status", func() {
+		It("shows the store health and registered nodes", func() {
+			meradm("service", "add", "service1", "tcp", "10.1.1.1:888", "-s=wrr")
+
+			out := meradm("status")
+
+			Expect(out).To(MatchRegexp(`Store: +%s, healthy`, storeBackend))
+			Expect(out).To(MatchRegexp(`Services: +1`))
+			Expect(out).To(ContainSubstring("reconcile disabled"))
+		})
+	})
+
+	Describe("completion", func() {
+		BeforeEach(func() {
+			meradm("service", "add", "service1", "tcp", "10.1.1.1:888", "-s=wrr")
+			meradm("service", "add", "other", "tcp", "10.1.1.2:888", "-s=wrr")
+			meradm("server", "add", "service1", "172.16.1.1:555", "-w=2", "-f=masq")
+		})
+
+		It("completes service ids", func() {
+			Expect(meradmComplete("service", "edit", "serv")).To(Equal([]string{"service1"}))
+		})
+
+		It("completes server addresses of the service", func() {
+			Expect(meradmComplete("server", "drain", "service1", "")).To(Equal([]string{"172.16.1.1:555"}))
+		})
+
+		It("completes node names", func() {
+			Expect(meradmComplete("nodes", "maintenance", "")).To(Equal([]string{MerlinNodeName}))
+		})
+	})
+
+	Describe("shell", func() {
+		It("runs commands with flags reset between them", func() {
+			out, err := meradmInput("service add service1 tcp 10.1.1.1:888 -s=wrr -l team=payments\n"+
+				"service add service2 tcp 10.1.1.2:888 -s=rr\n"+
+				"list -l 'team=payments'\n",
+				"shell", "-H=localhost", "-P="+MerlinPort())
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(out).To(ContainSubstring("service1"))
+			Expect(out).ToNot(ContainSubstring("service2"))
+		})
+	})
+
+	DescribeTable("exits with a code for the type of failure", func(code int, args ...string) {
+		meradm("service", "add", "service1", "tcp", "10.1.1.1:888", "-s=wrr")
+
+		_, err := meradmErrored(args...)
+
+		Expect(err).To(HaveOccurred())
+		Expect(err.(*exec.ExitError).Sys().(syscall.WaitStatus).ExitStatus()).To(Equal(code))
+	},
+		Entry("usage", 2, "service", "add", "service2"),
+		Entry("validation", 3, "service", "add", "service2", "tcp", "10.1.1.2:888", "-s=wrr", "-l=team=a b"),
+		Entry("not found", 4, "service", "edit", "service2", "-s=rr"),
+		Entry("already exists", 5, "service", "add", "service1", "tcp", "10.1.1.1:888", "-s=wrr"),
+	)
+
+	It("exits with a code for merlin being unavailable", func() {
+		_, err := meradmRaw("list", "--timeout=1s", "-H=localhost", "-P=1")
+
+		Expect(err).To(HaveOccurred())
+		Expect(err.(*exec.ExitError).Sys().(syscall.WaitStatus).ExitStatus()).To(Equal(7))
+	})
+
+	It("retries requests until merlin is available", func() {
+		start := time.Now()
+		_, err := meradmRaw("list", "--retries=2", "--wait-for-ready", "--timeout=200ms", "-H=localhost", "-P=1")
+
+		Expect(err).To(HaveOccurred())
+		Expect(err.(*exec.ExitError).Sys().(syscall.WaitStatus).ExitStatus()).To(Equal(7))
+		Expect(time.Since(start)).To(BeNumerically(">=", 600*time.Millisecond))
+	})
+
+	Describe("doctor", func() {
+		It("checks the connection and store health", func() {
+			out := meradm("doctor", "--local=false")
+
+			Expect(out).To(ContainSubstring("[ok]   connected to merlin " + MerlinNodeName))
+			Expect(out).To(ContainSubstring("[ok]   " + storeBackend + " store is healthy"))
+		})
+
+		It("fails if merlin is unreachable", func() {
+			out, err := meradmRaw("doctor", "--local=false", "-H=localhost", "-P=1", "--timeout=1s")
+
+			Expect(err).To(HaveOccurred())
+			Expect(out).To(ContainSubstring("[fail] unable to connect to merlin at localhost:1"))
+		})
+	})
+
+	Describe("nodes", func() {
+		It("shows the elected leader", func() {
+			Eventually(func() string { return meradm("nodes") }, 3*time.Second).Should(
+				MatchRegexp(`%s .* leader`, MerlinNodeName))
+		})
+
+		It("lists the registered nodes and their maintenance state", func() {
+			Eventually(func() string { return meradm("nodes") }).Should(ContainSubstring(MerlinNodeName))
+
+			meradm("nodes", "maintenance", MerlinNodeName, "on")
+
+			Eventually(func() string { return meradm("nodes") }, 3*time.Second).Should(
+				MatchRegexp(`%s .* paused`, MerlinNodeName))
+
+			meradm("nodes", "maintenance", MerlinNodeName, "off")
+
+			Eventually(func() string { return meradm("nodes") }, 3*time.Second).ShouldNot(ContainSubstring("paused"))
+		})
+	})
+
+	Describe("state", func() {
+		It("fails if IPVS is disabled on the node", func() {
+			_, err := meradmErrored("state")
+
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*exec.ExitError).Sys().(syscall.WaitStatus).ExitStatus()).To(Equal(3))
+		})
+	})
+
+	Describe("describe service", func() {
+		It("shows the service, its servers and its history", func() {
+			meradm("service", "add", "service1", "tcp", "10.1.1.1:888", "-s=wrr", "-l=team=payments")
+			meradm("server", "add", "service1", "172.16.1.1:555", "-w=2", "-f=masq")
+
+			out := meradm("describe", "service", "service1")
+
+			Expect(out).To(MatchRegexp(`Address:\s+10.1.1.1:888`))
+			Expect(out).To(MatchRegexp(`Labels:\s+team=payments`))
+			Expect(out).To(MatchRegexp(`172.16.1.1:555\s+MASQ\s+2\s`))
+			Expect(out).To(ContainSubstring("create service service1 wrr"))
+			Expect(out).To(ContainSubstring("create server 172.16.1.1:555 MASQ weight:2"))
+			Expect(out).To(MatchRegexp(`Errors on .*:\n  <none>`))
+		})
+
+		It("fails if the service doesn't exist", func() {
+			_, err := meradmErrored("describe", "service", "service1")
+
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*exec.ExitError).Sys().(syscall.WaitStatus).ExitStatus()).To(Equal(4))
+		})
+	})
+
+	Describe("bench", func() {
+		It("creates, lists and deletes synthetic services", func() {
+			out := meradm("bench", "--services=3", "--servers=2", "--list-iterations=2")
+
+			Expect(out).To(MatchRegexp(`create services\s+3\s`))
+			Expect(out).To(MatchRegexp(`create servers\s+6\s`))
+			Expect(out).To(MatchRegexp(`converge\s.*skipped, IPVS is disabled`))
+			Expect(out).To(MatchRegexp(`list\s+2\s`))
+			Expect(out).To(MatchRegexp(`delete services\s+3\s`))
+			Expect(meradmList()).ToNot(ContainElement(ContainSubstring("meradm-bench")))
+		})
+
+		It("keeps the services if asked", func() {
+			meradm("bench", "--services=1", "--servers=1", "--keep")
+
+			Expect(meradmList()).To(ContainElement(ContainSubstring("meradm-bench-0")))
+		})
+	})
+
+	Describe("export and import", func() {
+		var exportFile string
+
+		BeforeEach(func() {
+			meradm("service", "add", "service1", "tcp", "10.1.1.1:888", "-s=wrr", "-b=flag-1,flag-2")
+			meradm("server", "add", "service1", "172.16.1.1:555", "-w=2", "-f=masq")
+
+			f, err := ioutil.TempFile("", "merlin-export")
+			Expect(err).ToNot(HaveOccurred())
+			f.Close()
+			exportFile = f.Name()
+			meradm("export", "-o", exportFile)
+		})
+
+		AfterEach(func() {
+			os.Remove(exportFile)
+		})
+
+		It("restores exported state", func() {
+			before := meradmList()
+			meradm("server", "del", "service1", "172.16.1.1:555")
+			meradm("service", "del", "service1")
+
+			out := meradm("import", exportFile)
+
+			Expect(out).To(ContainSubstring("create service service1"))
+			Expect(out).To(ContainSubstring("create server service1"))
+			Expect(meradmList()).To(Equal(before))
+		})
+
+		It("makes no changes when state already matches", func() {
+			out := meradm("import", exportFile)
+
+			Expect(strings.TrimSpace(out)).To(BeEmpty())
+		})
+
+		It("only deletes extra state when pruning", func() {
+			meradm("service", "add", "service2", "udp", "10.1.1.2:999", "-s=rr")
+
+			meradm("import", exportFile)
+			Expect(meradmList()).To(ContainElement(ContainSubstring("service2")))
+
+			out := meradm("import", "--prune", exportFile)
+			Expect(out).To(ContainSubstring("delete service service2"))
+			Expect(meradmList()).ToNot(ContainElement(ContainSubstring("service2")))
+		})
+
+		It("doesn't apply changes on a dry run", func() {
+			meradm("server", "edit", "service1", "172.16.1.1:555", "-w=5")
+
+			out := meradm("import", "--dry-run", exportFile)
+
+			Expect(out).To(ContainSubstring("(dry run) update server service1"))
+			Expect(meradmList()).To(ContainElement(MatchRegexp(`.*172.16.1.1:555.*MASQ.*5.*`)))
+		})
+
+		It("validates exported state without contacting merlin", func() {
+			out, err := meradmRaw("validate", "-f", exportFile, "-H=localhost", "-P=1")
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(out).To(ContainSubstring("is valid: 1 services, 1 servers"))
+		})
+
+		It("fails to validate a server without a service", func() {
+			invalid := "servers:\n- serviceID: missing\n  key: {ip: 172.16.1.1, port: 555}\n" +
+				"  config: {weight: 1, forward: ROUTE}\n"
+			Expect(ioutil.WriteFile(exportFile, []byte(invalid), 0600)).To(Succeed())
+
+			_, err := meradmRaw("validate", "-f", exportFile)
+
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("imports ipvsadm rules with mapped service IDs", func() {
+			rules := "-A -t 10.1.1.1:888 -s wrr -b flag-1,flag-2\n" +
+				"-a -t 10.1.1.1:888 -r 172.16.1.1:555 -m -w 2\n" +
+				"-A -u 10.1.1.2:999 -s rr\n" +
+				"-a -u 10.1.1.2:999 -r 172.16.1.2:999 -g -w 1\n"
+			Expect(ioutil.WriteFile(exportFile, []byte(rules), 0600)).To(Succeed())
+			idMap := exportFile + ".ids"
+			Expect(ioutil.WriteFile(idMap, []byte("tcp/10.1.1.1:888: service1\n"), 0600)).To(Succeed())
+			defer os.Remove(idMap)
+
+			out := meradm("import", "--format", "ipvsadm-save", "--id-map", idMap, exportFile)
+
+			Expect(out).ToNot(ContainSubstring("service1"))
+			Expect(out).To(ContainSubstring("create service udp-10-1-1-2-999"))
+			Expect(out).To(ContainSubstring("create server udp-10-1-1-2-999"))
+			Expect(meradmList()).To(ContainElement(MatchRegexp(`.*172.16.1.2:999.*ROUTE.*1.*`)))
+		})
+
+		It("exports ipvsadm rules which can be imported", func() {
+			out := meradm("export", "--format", "ipvsadm-save")
+
+			Expect(out).To(Equal("-A -t 10.1.1.1:888 -s wrr -b flag-1,flag-2\n" +
+				"-a -t 10.1.1.1:888 -r 172.16.1.1:555 -m -w 2\n"))
+
+			Expect(ioutil.WriteFile(exportFile, []byte(out), 0600)).To(Succeed())
+			Expect(meradm("import", "--format", "ipvsadm-save", "--dry-run", exportFile)).To(
+				ContainSubstring("create service tcp-10-1-1-1-888"))
+		})
+
+		It("imports keepalived virtual servers with their health checks", func() {
+			conf := "vrrp_instance VI_1 {\n  state MASTER\n}\n" +
+				"virtual_server 10.1.1.2 999 {\n  delay_loop 5\n  lb_algo rr\n  lb_kind DR\n  protocol UDP\n" +
+				"  real_server 172.16.1.2 999 {\n    weight 3\n" +
+				"    HTTP_GET {\n      url { path /health }\n      connect_port 556\n      connect_timeout 1\n" +
+				"      retry 2\n    }\n  }\n}\n"
+			Expect(ioutil.WriteFile(exportFile, []byte(conf), 0600)).To(Succeed())
+
+			out := meradm("import", "--format", "keepalived", exportFile)
+
+			Expect(out).To(ContainSubstring("create service udp-10-1-1-2-999"))
+			list := meradmList()
+			Expect(list).To(ContainElement(MatchRegexp(`.*172.16.1.2:999.*ROUTE.*3.*`)))
+			Expect(list).To(ContainElement(MatchRegexp(`http://:556/health.*5s.*1s.*1/2`)))
+		})
+
+		It("exports keepalived configuration which can be imported", func() {
+			out := meradm("export", "--format", "keepalived")
+
+			Expect(out).To(ContainSubstring("virtual_server 10.1.1.1 888 {\n    lb_algo wrr\n    protocol TCP\n"))
+			Expect(out).To(ContainSubstring("real_server 172.16.1.1 555 {\n        lb_kind NAT\n        weight 2\n"))
+
+			Expect(ioutil.WriteFile(exportFile, []byte(out), 0600)).To(Succeed())
+			Expect(meradm("import", "--format", "keepalived", "--dry-run", exportFile)).To(
+				ContainSubstring("create service tcp-10-1-1-1-888"))
+		})
+
+		It("fails to import unsupported ipvsadm rules", func() {
+			Expect(ioutil.WriteFile(exportFile, []byte("-A -f 1 -s rr\n"), 0600)).To(Succeed())
+
+			_, err := meradmRaw("import", "--format", "ipvsadm-save", exportFile)
+
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("connection settings", func() {
+		var configFile string
+
+		BeforeEach(func() {
+			meradm("service", "add", "service1", "tcp", "10.1.1.1:888", "-s=wrr")
+
+			f, err := ioutil.TempFile("", "meradm-config")
+			Expect(err).ToNot(HaveOccurred())
+			fmt.Fprintf(f, "current-context: local\ncontexts:\n  local:\n    host: localhost\n    port: %s\n"+
+				"  broken:\n    host: localhost\n    port: 1\n", MerlinPort())
+			f.Close()
+			configFile = f.Name()
+		})
+
+		AfterEach(func() {
+			os.Remove(configFile)
+		})
+
+		It("connects using the current context", func() {
+			out, err := meradmRaw("list", "--config", configFile)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(out).To(ContainSubstring("service1"))
+		})
+
+		It("connects using the selected context", func() {
+			_, err := meradmRaw("list", "--config", configFile, "--context", "broken", "--timeout", "1s")
+
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("lets flags override the context", func() {
+			out, err := meradmRaw("list", "--config", configFile, "--context", "broken", "-P", MerlinPort())
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(out).To(ContainSubstring("service1"))
+		})
+
+		It("fails to connect with TLS to a plaintext server", func() {
+			_, err := meradmErrored("list", "--tls", "--insecure-skip-verify", "--timeout", "1s")
+
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("requires both the client certificate and key", func() {
+			_, err := meradmErrored("list", "--tls-cert", configFile)
+
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("sends a token from the token command", func() {
+			out := meradm("list", "--token-command", "echo secret")
+
+			Expect(out).To(ContainSubstring("service1"))
+		})
+
+		It("fails if the token command fails", func() {
+			_, err := meradmErrored("list", "--token-command", "exit 1")
+
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("fails on an unknown context", func() {
+			_, err := meradmRaw("list", "--config", configFile, "--context", "missing")
+
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
 	Describe("External Updates", func() {
 		It("should run reconciler if etcd is updated elsewhere", func() {
 			meradm("service", "add", "service1", "tcp", "10.1.1.1:888", "-s=wrr", "-b=flag-1,flag-2")
@@ -239,8 +732,8 @@ var _ = Describe("E2E With Meradm/Merlin/Etcd", func() {
 	})
 })
 
-func meradmList() []string {
-	return strings.Split(strings.TrimSpace(meradm("list")), "\n")
+func meradmList(args ...string) []string {
+	return strings.Split(strings.TrimSpace(meradm(append([]string{"list"}, args...)...)), "\n")
 }
 
 func meradm(args ...string) string {
@@ -254,7 +747,25 @@ func meradmErrored(args ...string) (string, error) {
 	if merlinPort != "" {
 		args = append(args, "-H=localhost", "-P="+merlinPort)
 	}
+	return meradmRaw(args...)
+}
+
+// meradmComplete returns the completions of the last argument.
+func meradmComplete(args ...string) []string {
+	out, err := meradmRaw(append([]string{"-H=localhost", "-P=" + MerlinPort(), "__complete"}, args...)...)
+	Expect(err).ToNot(HaveOccurred())
+	return strings.Fields(out)
+}
+
+// meradmRaw runs meradm without adding the merlin host and port.
+func meradmRaw(args ...string) (string, error) {
+	return meradmInput("", args...)
+}
+
+// meradmInput runs meradm with input on stdin, without adding the merlin host and port.
+func meradmInput(input string, args ...string) (string, error) {
 	c := exec.Command("meradm", args...)
+	c.Stdin = strings.NewReader(input)
 	c.Stderr = os.Stderr
 	var output bytes.Buffer
 	c.Stdout = &output
@@ -15,4 +15,28 @@ var _ = Describe("merlin", func() {
 			Expect(string(out)).To(ContainSubstring("version"))
 		})
 	})
+
+	Describe("--mode", func() {
+		It("should refuse to run as an agent without reconciling", func() {
+			out, err := exec.Command("merlin", "--mode=agent", "--reconcile=false").CombinedOutput()
+			Expect(err).To(HaveOccurred())
+			Expect(string(out)).To(ContainSubstring("--mode=agent requires --reconcile"))
+		})
+
+		It("should refuse an unknown mode", func() {
+			out, err := exec.Command("merlin", "--mode=unknown").CombinedOutput()
+			Expect(err).To(HaveOccurred())
+			Expect(string(out)).To(ContainSubstring(`unknown --mode "unknown"`))
+		})
+	})
+
+	Describe("--store-wait-timeout", func() {
+		It("should retry connecting to the store until the timeout", func() {
+			out, err := exec.Command("merlin", "--store-endpoints=http://127.0.0.1:1", "--store-wait-timeout=1s",
+				"--reconcile=false", "--port=0", "--health-port=0").CombinedOutput()
+			Expect(err).To(HaveOccurred())
+			Expect(string(out)).To(ContainSubstring("Unable to connect to the store, retrying"))
+			Expect(string(out)).To(ContainSubstring("unable to start store client"))
+		})
+	})
 })
@@ -1,6 +1,6 @@
 // Package e2e sets up end to end tests with a stubbed IPVS so it can run in build environments.
 // The main purpose is to test the client/server -> store CRUD functionality.
-// The actual specs are located in api/ and meradm/.
+// The actual specs are located in api/ and meradm/, and the processes are run by harness/.
 package e2e
 
 import (
@@ -14,17 +14,12 @@ import (
 
 	"strconv"
 
-	"bytes"
 	"crypto/sha256"
 	"io"
-	"net"
 	"net/http"
-	"strings"
-	"sync"
-	"syscall"
-	"time"
 
 	. "github.com/onsi/gomega"
+	"github.com/sky-uk/merlin/e2e/harness"
 )
 
 const (
@@ -36,18 +31,14 @@ const (
 	workingDir = "../.."
 	buildDir   = workingDir + "/build"
 	etcdBinary = buildDir + "/etcd"
+
+	// MerlinNodeName is the name merlin registers itself as in the store.
+	MerlinNodeName = "merlin-e2e"
 )
 
 var (
-	etcdListenPort   string
-	etcdPeerPort     string
-	merlinPort       string
-	merlinHealthPort string
-	dataDir          string
-	etcd             *exec.Cmd
-	merlin           *exec.Cmd
-	merlinStdout     safeBuffer
-	merlinStderr     safeBuffer
+	etcd   *harness.Etcd
+	merlin *harness.Merlin
 )
 
 func SetupE2E() {
@@ -126,161 +117,52 @@ func downloadEtcdBinary() {
 }
 
 func EtcdPort() string {
-	return etcdListenPort
+	return strconv.Itoa(etcd.Port)
 }
 
 func StartEtcd() {
 	var err error
-	dataDir, err = ioutil.TempDir(buildDir, "")
+	etcd, err = harness.StartEtcd(harness.EtcdOptions{Binary: etcdBinary, TempDir: buildDir, Output: os.Stdout})
 	if err != nil {
 		panic(err)
 	}
-
-	ports := findFreePorts(2)
-	etcdListenPort = strconv.Itoa(ports[0])
-	etcdPeerPort = strconv.Itoa(ports[1])
-
-	etcd = exec.Command(etcdBinary,
-		"-name=etcd0",
-		"-data-dir="+dataDir,
-		"-advertise-client-urls=http://127.0.0.1:"+etcdListenPort,
-		"-listen-client-urls=http://0.0.0.0:"+etcdListenPort,
-		"-initial-advertise-peer-urls=http://127.0.0.1:"+etcdPeerPort,
-		"-listen-peer-urls=http://0.0.0.0:"+etcdPeerPort,
-		"-initial-cluster-token=etcd-cluster-1",
-		"-initial-cluster=etcd0=http://127.0.0.1:"+etcdPeerPort,
-		"-initial-cluster-state=new")
-	etcd.Stdout = os.Stdout
-	etcd.Stderr = os.Stderr
-	if err := etcd.Start(); err != nil {
-		panic(err)
-	}
-	waitForServerToStart("etcd", etcdListenPort, "/health")
 }
 
 func StopEtcd() {
-	stopServer(etcd, false)
-	os.RemoveAll(dataDir)
+	etcd.Stop()
 }
 
 func MerlinPort() string {
-	return merlinPort
+	return strconv.Itoa(merlin.Port)
+}
+
+func MerlinHealthPort() string {
+	return strconv.Itoa(merlin.HealthPort)
 }
 
 // MerlinStdout is the stdout of the merlin process. Subsequent calls only return new output.
 func MerlinStdout() []string {
-	s, _ := ioutil.ReadAll(&merlinStdout)
-	return strings.Split(string(s), "\n")
+	return merlin.Stdout()
 }
 
 // MerlinStderr is the stderr of the merlin process. Subsequent calls only return new output.
 func MerlinStderr() []string {
-	s, _ := ioutil.ReadAll(&merlinStderr)
-	return strings.Split(string(s), "\n")
+	return merlin.Stderr()
 }
 
 func StartMerlin(storeBackend string) {
-	ports := findFreePorts(2)
-	merlinPort = strconv.Itoa(ports[0])
-	merlinHealthPort = strconv.Itoa(ports[1])
-
-	merlin = exec.Command("merlin",
-		"--port="+merlinPort,
-		"--health-port="+merlinHealthPort,
-		"--store-endpoints=http://127.0.0.1:"+etcdListenPort,
-		"--store-backend="+storeBackend,
-		"--reconcile=false",
-		"--debug")
-
-	// wire up pipes so we can save and assert on output, while preserving stderr/stdout
-	prOut, pwOut := io.Pipe()
-	prErr, pwErr := io.Pipe()
-	teeOut := io.TeeReader(prOut, os.Stdout)
-	teeErr := io.TeeReader(prErr, os.Stderr)
-	go func() {
-		io.Copy(&merlinStdout, teeOut)
-	}()
-	go func() {
-		io.Copy(&merlinStderr, teeErr)
-	}()
-
-	merlin.Stdout = pwOut
-	merlin.Stderr = pwErr
-	if err := merlin.Start(); err != nil {
-		panic(err.Error())
+	var err error
+	merlin, err = harness.StartMerlin(harness.MerlinOptions{
+		StoreEndpoint: etcd.Endpoint(),
+		StoreBackend:  storeBackend,
+		NodeName:      MerlinNodeName,
+		Flags:         []string{"--heartbeat-period=1s", "--leader-election", "--debug"},
+	})
+	if err != nil {
+		panic(err)
 	}
-	waitForServerToStart("merlin", merlinHealthPort, "/health")
 }
 
 func StopMerlin() {
-	stopServer(merlin, true)
-}
-
-func waitForServerToStart(name, port, healthPath string) {
-	fmt.Fprintf(os.Stderr, "waiting for %s to come up...\n", name)
-	const maxTries = 20
-	const delay = 100 * time.Millisecond
-	for i := 0; i < maxTries; i++ {
-		var up bool
-		func() {
-			resp, err := http.Get("http://localhost:" + port + healthPath)
-			if err != nil {
-				return
-			}
-			defer resp.Body.Close()
-			if resp.StatusCode != 200 {
-				return
-			}
-			up = true
-		}()
-		if up {
-			fmt.Fprintf(os.Stderr, "%s is up\n", name)
-			return
-		}
-		time.Sleep(delay)
-	}
-	panic(name + " did not start up")
-}
-
-func stopServer(cmd *exec.Cmd, checkExitCode bool) {
-	if cmd.Process != nil {
-		cmd.Process.Signal(syscall.SIGTERM)
-	}
-	err := cmd.Wait()
-	if checkExitCode {
-		Expect(err).ToNot(HaveOccurred(), cmd.Path+" exited with unexpected error")
-	} else {
-		fmt.Fprintf(os.Stderr, "%s exited with %v (ignored)\n", cmd.Path, err)
-	}
-}
-
-func findFreePorts(num int) []int {
-	var ports []int
-	for i := 0; i < num; i++ {
-		l, err := net.Listen("tcp", "127.0.0.1:0")
-		if err != nil {
-			panic(err)
-		}
-		defer l.Close()
-		port := l.Addr().(*net.TCPAddr).Port
-		ports = append(ports, port)
-	}
-	return ports
-}
-
-type safeBuffer struct {
-	buf bytes.Buffer
-	sync.Mutex
-}
-
-func (s *safeBuffer) Read(p []byte) (n int, err error) {
-	s.Lock()
-	defer s.Unlock()
-	return s.buf.Read(p)
-}
-
-func (s *safeBuffer) Write(p []byte) (n int, err error) {
-	s.Lock()
-	defer s.Unlock()
-	return s.buf.Write(p)
+	Expect(merlin.Stop()).To(Succeed())
 }
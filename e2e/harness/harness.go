@@ -0,0 +1,286 @@
+// Package harness runs etcd and merlin as separate processes for integration tests, so projects which build on
+// merlin can test against a real instance. merlin's own end to end tests use it.
+package harness
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/sky-uk/merlin/types"
+	"google.golang.org/grpc"
+)
+
+// defaultStartTimeout is how long to wait for etcd and merlin to become healthy.
+const defaultStartTimeout = 5 * time.Second
+
+// EtcdOptions for starting etcd.
+type EtcdOptions struct {
+	// Binary of etcd, defaults to etcd on the PATH.
+	Binary string
+	// TempDir to create the data directory in, defaults to the system temporary directory.
+	TempDir string
+	// Output of etcd, defaults to os.Stderr.
+	Output io.Writer
+	// StartTimeout is how long to wait for etcd to become healthy.
+	StartTimeout time.Duration
+}
+
+// Etcd is a single node etcd cluster.
+type Etcd struct {
+	// Port etcd clients connect to.
+	Port    int
+	cmd     *exec.Cmd
+	dataDir string
+}
+
+// StartEtcd starts etcd on free ports, with a new data directory, returning once it's healthy.
+func StartEtcd(opts EtcdOptions) (*Etcd, error) {
+	if opts.Binary == "" {
+		opts.Binary = "etcd"
+	}
+	if opts.Output == nil {
+		opts.Output = os.Stderr
+	}
+	if opts.StartTimeout == 0 {
+		opts.StartTimeout = defaultStartTimeout
+	}
+
+	dataDir, err := ioutil.TempDir(opts.TempDir, "etcd")
+	if err != nil {
+		return nil, err
+	}
+	ports, err := FreePorts(2)
+	if err != nil {
+		return nil, err
+	}
+	clientURL := fmt.Sprintf("http://127.0.0.1:%d", ports[0])
+	peerURL := fmt.Sprintf("http://127.0.0.1:%d", ports[1])
+
+	cmd := exec.Command(opts.Binary,
+		"-name=etcd0",
+		"-data-dir="+dataDir,
+		"-advertise-client-urls="+clientURL,
+		fmt.Sprintf("-listen-client-urls=http://0.0.0.0:%d", ports[0]),
+		"-initial-advertise-peer-urls="+peerURL,
+		fmt.Sprintf("-listen-peer-urls=http://0.0.0.0:%d", ports[1]),
+		"-initial-cluster-token=etcd-cluster-1",
+		"-initial-cluster=etcd0="+peerURL,
+		"-initial-cluster-state=new")
+	cmd.Stdout = opts.Output
+	cmd.Stderr = opts.Output
+	if err := cmd.Start(); err != nil {
+		os.RemoveAll(dataDir)
+		return nil, fmt.Errorf("unable to start etcd: %v", err)
+	}
+
+	e := &Etcd{Port: ports[0], cmd: cmd, dataDir: dataDir}
+	if err := WaitForHealthy("etcd", ports[0], "/health", opts.StartTimeout); err != nil {
+		e.Stop()
+		return nil, err
+	}
+	return e, nil
+}
+
+// Endpoint clients connect to etcd with.
+func (e *Etcd) Endpoint() string {
+	return fmt.Sprintf("http://127.0.0.1:%d", e.Port)
+}
+
+// Stop etcd and remove its data. etcd doesn't exit cleanly when signalled, so its exit status is ignored.
+func (e *Etcd) Stop() {
+	stop(e.cmd)
+	os.RemoveAll(e.dataDir)
+}
+
+// MerlinOptions for starting merlin.
+type MerlinOptions struct {
+	// Binary of merlin, defaults to merlin on the PATH.
+	Binary string
+	// StoreEndpoint of etcd, such as Etcd.Endpoint().
+	StoreEndpoint string
+	// StoreBackend is etcd2 or etcd3, defaults to etcd2.
+	StoreBackend string
+	// NodeName merlin registers in the store with, defaults to merlin-harness.
+	NodeName string
+	// FakeIPVS reconciles an in-memory IPVS, so reconciling can be tested without root. Otherwise merlin runs
+	// with --reconcile=false.
+	FakeIPVS bool
+	// Flags to add to the command line, which take precedence over the flags set by the harness.
+	Flags []string
+	// Output of merlin, in addition to recording it. Defaults to os.Stderr.
+	Output io.Writer
+	// StartTimeout is how long to wait for merlin to become healthy.
+	StartTimeout time.Duration
+	// DialOptions used by Dial, such as credentials for TLS. Defaults to an insecure connection.
+	DialOptions []grpc.DialOption
+}
+
+// Merlin is a running merlin process.
+type Merlin struct {
+	// Port of the API.
+	Port int
+	// HealthPort of /health, /metrics, and the other HTTP endpoints.
+	HealthPort int
+	cmd        *exec.Cmd
+	opts       MerlinOptions
+	stdout     safeBuffer
+	stderr     safeBuffer
+}
+
+// StartMerlin starts merlin on free ports, returning once it's healthy.
+func StartMerlin(opts MerlinOptions) (*Merlin, error) {
+	if opts.Binary == "" {
+		opts.Binary = "merlin"
+	}
+	if opts.StoreBackend == "" {
+		opts.StoreBackend = "etcd2"
+	}
+	if opts.NodeName == "" {
+		opts.NodeName = "merlin-harness"
+	}
+	if opts.Output == nil {
+		opts.Output = os.Stderr
+	}
+	if opts.StartTimeout == 0 {
+		opts.StartTimeout = defaultStartTimeout
+	}
+	if len(opts.DialOptions) == 0 {
+		opts.DialOptions = []grpc.DialOption{grpc.WithInsecure()}
+	}
+
+	ports, err := FreePorts(2)
+	if err != nil {
+		return nil, err
+	}
+	m := &Merlin{Port: ports[0], HealthPort: ports[1], opts: opts}
+
+	args := []string{
+		"--port=" + strconv.Itoa(m.Port),
+		"--health-port=" + strconv.Itoa(m.HealthPort),
+		"--store-endpoints=" + opts.StoreEndpoint,
+		"--store-backend=" + opts.StoreBackend,
+		"--node-name=" + opts.NodeName,
+	}
+	if opts.FakeIPVS {
+		args = append(args, "--fake-ipvs")
+	} else {
+		args = append(args, "--reconcile=false")
+	}
+	// later flags take precedence
+	args = append(args, opts.Flags...)
+
+	m.cmd = exec.Command(opts.Binary, args...)
+	m.cmd.Stdout = io.MultiWriter(&m.stdout, opts.Output)
+	m.cmd.Stderr = io.MultiWriter(&m.stderr, opts.Output)
+	if err := m.cmd.Start(); err != nil {
+		return nil, fmt.Errorf("unable to start merlin: %v", err)
+	}
+	if err := WaitForHealthy("merlin", m.HealthPort, "/health", opts.StartTimeout); err != nil {
+		stop(m.cmd)
+		return nil, err
+	}
+	return m, nil
+}
+
+// Address of the merlin API.
+func (m *Merlin) Address() string {
+	return fmt.Sprintf("localhost:%d", m.Port)
+}
+
+// Dial connects a client to merlin with the DialOptions. The connection should be closed when finished.
+func (m *Merlin) Dial() (types.MerlinClient, *grpc.ClientConn, error) {
+	conn, err := grpc.Dial(m.Address(), m.opts.DialOptions...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return types.NewMerlinClient(conn), conn, nil
+}
+
+// Stdout of merlin. Subsequent calls only return new output.
+func (m *Merlin) Stdout() []string {
+	return m.stdout.lines()
+}
+
+// Stderr of merlin, where it logs. Subsequent calls only return new output.
+func (m *Merlin) Stderr() []string {
+	return m.stderr.lines()
+}
+
+// Stop merlin, returning an error if it doesn't exit cleanly.
+func (m *Merlin) Stop() error {
+	if err := stop(m.cmd); err != nil {
+		return fmt.Errorf("merlin exited with an unexpected error: %v", err)
+	}
+	return nil
+}
+
+// WaitForHealthy waits until the path on localhost:port returns 200, or returns an error after timeout.
+func WaitForHealthy(name string, port int, path string, timeout time.Duration) error {
+	const delay = 100 * time.Millisecond
+	url := fmt.Sprintf("http://localhost:%d%s", port, path)
+	client := &http.Client{Timeout: delay}
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if resp, err := client.Get(url); err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return nil
+			}
+		}
+		time.Sleep(delay)
+	}
+	return fmt.Errorf("%s didn't become healthy within %v", name, timeout)
+}
+
+// FreePorts returns num ports which are free to listen on.
+func FreePorts(num int) ([]int, error) {
+	var ports []int
+	for i := 0; i < num; i++ {
+		// the listeners are kept open until all the ports are found, so they are distinct
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			return nil, err
+		}
+		defer l.Close()
+		ports = append(ports, l.Addr().(*net.TCPAddr).Port)
+	}
+	return ports, nil
+}
+
+func stop(cmd *exec.Cmd) error {
+	if cmd.Process != nil {
+		cmd.Process.Signal(syscall.SIGTERM)
+	}
+	return cmd.Wait()
+}
+
+type safeBuffer struct {
+	buf bytes.Buffer
+	sync.Mutex
+}
+
+func (s *safeBuffer) Write(p []byte) (n int, err error) {
+	s.Lock()
+	defer s.Unlock()
+	return s.buf.Write(p)
+}
+
+// lines returns the output written since the last call.
+func (s *safeBuffer) lines() []string {
+	s.Lock()
+	defer s.Unlock()
+	out := s.buf.String()
+	s.buf.Reset()
+	return strings.Split(out, "\n")
+}
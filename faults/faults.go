@@ -0,0 +1,275 @@
+// Package faults wraps the store and IPVS to inject failures, for testing alerting and recovery in staging.
+package faults
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/sky-uk/merlin/ipvs"
+	"github.com/sky-uk/merlin/store"
+	"github.com/sky-uk/merlin/types"
+)
+
+var (
+	// ErrStoreTimeout is returned by store requests which are failed.
+	ErrStoreTimeout = errors.New("injected fault: store request timed out")
+	// ErrNetlink is returned by IPVS requests which are failed.
+	ErrNetlink = errors.New("injected fault: netlink request failed")
+)
+
+// Config of the faults to inject. Rates are the fraction of requests to fail, from 0 to 1.
+type Config struct {
+	// StoreErrorRate of store requests fail with ErrStoreTimeout.
+	StoreErrorRate float64
+	// StoreDelay is added to every store request.
+	StoreDelay time.Duration
+	// WatchDelay delays notifying subscribers of changes to the store.
+	WatchDelay time.Duration
+	// IPVSErrorRate of IPVS requests fail with ErrNetlink.
+	IPVSErrorRate float64
+}
+
+func inject(rate float64) bool {
+	return rate > 0 && rand.Float64() < rate
+}
+
+type faultyStore struct {
+	store.Store
+	config Config
+}
+
+// Store returns a store which injects the configured store faults into the requests made to s.
+func Store(s store.Store, config Config) store.Store {
+	return &faultyStore{Store: s, config: config}
+}
+
+// fault delays the request, then fails it at the configured rate.
+func (s *faultyStore) fault(ctx context.Context) error {
+	if s.config.StoreDelay > 0 {
+		select {
+		case <-time.After(s.config.StoreDelay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if inject(s.config.StoreErrorRate) {
+		return ErrStoreTimeout
+	}
+	return nil
+}
+
+func (s *faultyStore) GetService(ctx context.Context, serviceID string) (*types.VirtualService, error) {
+	if err := s.fault(ctx); err != nil {
+		return nil, err
+	}
+	return s.Store.GetService(ctx, serviceID)
+}
+
+func (s *faultyStore) PutService(ctx context.Context, service *types.VirtualService) error {
+	if err := s.fault(ctx); err != nil {
+		return err
+	}
+	return s.Store.PutService(ctx, service)
+}
+
+func (s *faultyStore) DeleteService(ctx context.Context, serviceID string) error {
+	if err := s.fault(ctx); err != nil {
+		return err
+	}
+	return s.Store.DeleteService(ctx, serviceID)
+}
+
+func (s *faultyStore) GetServer(ctx context.Context, serviceID string,
+	key *types.RealServer_Key) (*types.RealServer, error) {
+	if err := s.fault(ctx); err != nil {
+		return nil, err
+	}
+	return s.Store.GetServer(ctx, serviceID, key)
+}
+
+func (s *faultyStore) PutServer(ctx context.Context, server *types.RealServer) error {
+	if err := s.fault(ctx); err != nil {
+		return err
+	}
+	return s.Store.PutServer(ctx, server)
+}
+
+func (s *faultyStore) DeleteServer(ctx context.Context, serviceID string, key *types.RealServer_Key) error {
+	if err := s.fault(ctx); err != nil {
+		return err
+	}
+	return s.Store.DeleteServer(ctx, serviceID, key)
+}
+
+func (s *faultyStore) ListServices(ctx context.Context) ([]*types.VirtualService, error) {
+	if err := s.fault(ctx); err != nil {
+		return nil, err
+	}
+	return s.Store.ListServices(ctx)
+}
+
+func (s *faultyStore) ListServers(ctx context.Context, serviceID string) ([]*types.RealServer, error) {
+	if err := s.fault(ctx); err != nil {
+		return nil, err
+	}
+	return s.Store.ListServers(ctx, serviceID)
+}
+
+func (s *faultyStore) ListAllServers(ctx context.Context) ([]*types.RealServer, error) {
+	if err := s.fault(ctx); err != nil {
+		return nil, err
+	}
+	return s.Store.ListAllServers(ctx)
+}
+
+func (s *faultyStore) Apply(ctx context.Context, changes []*types.Change) error {
+	if err := s.fault(ctx); err != nil {
+		return err
+	}
+	return s.Store.Apply(ctx, changes)
+}
+
+func (s *faultyStore) PutNode(ctx context.Context, node *types.Node, ttl time.Duration) error {
+	if err := s.fault(ctx); err != nil {
+		return err
+	}
+	return s.Store.PutNode(ctx, node, ttl)
+}
+
+func (s *faultyStore) ListNodes(ctx context.Context) ([]*types.Node, error) {
+	if err := s.fault(ctx); err != nil {
+		return nil, err
+	}
+	return s.Store.ListNodes(ctx)
+}
+
+func (s *faultyStore) GetMaintenance(ctx context.Context, node string) (bool, error) {
+	if err := s.fault(ctx); err != nil {
+		return false, err
+	}
+	return s.Store.GetMaintenance(ctx, node)
+}
+
+func (s *faultyStore) Subscribe(subscriber func(), stopCh <-chan struct{}) {
+	if s.config.WatchDelay == 0 {
+		s.Store.Subscribe(subscriber, stopCh)
+		return
+	}
+	s.Store.Subscribe(func() {
+		time.AfterFunc(s.config.WatchDelay, subscriber)
+	}, stopCh)
+}
+
+type faultyIPVS struct {
+	ipvs.IPVS
+	config Config
+}
+
+// IPVS returns an IPVS which fails requests made to i at the configured rate. Listing and stats requests are
+// failed, as well as changes.
+func IPVS(i ipvs.IPVS, config Config) ipvs.IPVS {
+	return &faultyIPVS{IPVS: i, config: config}
+}
+
+func (i *faultyIPVS) fault(op string) error {
+	if inject(i.config.IPVSErrorRate) {
+		return fmt.Errorf("unable to %s: %v", op, ErrNetlink)
+	}
+	return nil
+}
+
+func (i *faultyIPVS) AddService(ctx context.Context, svc *types.VirtualService) error {
+	if err := i.fault("add service"); err != nil {
+		return err
+	}
+	return i.IPVS.AddService(ctx, svc)
+}
+
+func (i *faultyIPVS) UpdateService(ctx context.Context, svc *types.VirtualService) error {
+	if err := i.fault("update service"); err != nil {
+		return err
+	}
+	return i.IPVS.UpdateService(ctx, svc)
+}
+
+func (i *faultyIPVS) DeleteService(ctx context.Context, key *types.VirtualService_Key) error {
+	if err := i.fault("delete service"); err != nil {
+		return err
+	}
+	return i.IPVS.DeleteService(ctx, key)
+}
+
+func (i *faultyIPVS) ListServices(ctx context.Context) ([]*types.VirtualService, error) {
+	if err := i.fault("list services"); err != nil {
+		return nil, err
+	}
+	return i.IPVS.ListServices(ctx)
+}
+
+func (i *faultyIPVS) AddServer(ctx context.Context, key *types.VirtualService_Key, server *types.RealServer) error {
+	if err := i.fault("add server"); err != nil {
+		return err
+	}
+	return i.IPVS.AddServer(ctx, key, server)
+}
+
+func (i *faultyIPVS) UpdateServer(ctx context.Context, key *types.VirtualService_Key,
+	server *types.RealServer) error {
+	if err := i.fault("update server"); err != nil {
+		return err
+	}
+	return i.IPVS.UpdateServer(ctx, key, server)
+}
+
+func (i *faultyIPVS) DeleteServer(ctx context.Context, key *types.VirtualService_Key,
+	server *types.RealServer) error {
+	if err := i.fault("delete server"); err != nil {
+		return err
+	}
+	return i.IPVS.DeleteServer(ctx, key, server)
+}
+
+func (i *faultyIPVS) ListServers(ctx context.Context, key *types.VirtualService_Key) ([]*types.RealServer, error) {
+	if err := i.fault("list servers"); err != nil {
+		return nil, err
+	}
+	return i.IPVS.ListServers(ctx, key)
+}
+
+func (i *faultyIPVS) Stats(ctx context.Context) ([]*types.ServiceStats, error) {
+	if err := i.fault("read stats"); err != nil {
+		return nil, err
+	}
+	return i.IPVS.Stats(ctx)
+}
+
+func (i *faultyIPVS) Connections(ctx context.Context, fn func(*types.Connection) error) error {
+	if err := i.fault("list connections"); err != nil {
+		return err
+	}
+	return i.IPVS.Connections(ctx, fn)
+}
+
+func (i *faultyIPVS) SyncDaemons(ctx context.Context) ([]*types.SyncDaemon, error) {
+	if err := i.fault("list sync daemons"); err != nil {
+		return nil, err
+	}
+	return i.IPVS.SyncDaemons(ctx)
+}
+
+func (i *faultyIPVS) StartSyncDaemon(ctx context.Context, daemon *types.SyncDaemon) error {
+	if err := i.fault("start sync daemon"); err != nil {
+		return err
+	}
+	return i.IPVS.StartSyncDaemon(ctx, daemon)
+}
+
+func (i *faultyIPVS) StopSyncDaemon(ctx context.Context, role types.SyncDaemon_Role) error {
+	if err := i.fault("stop sync daemon"); err != nil {
+		return err
+	}
+	return i.IPVS.StopSyncDaemon(ctx, role)
+}
@@ -0,0 +1,108 @@
+package faults
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sky-uk/merlin/ipvs"
+	"github.com/sky-uk/merlin/store"
+	"github.com/sky-uk/merlin/types"
+)
+
+func TestFaults(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Faults Suite")
+}
+
+type fakeStore struct {
+	store.Store
+	subscriber func()
+}
+
+func (s *fakeStore) ListServices(context.Context) ([]*types.VirtualService, error) {
+	return []*types.VirtualService{{Id: "svc1"}}, nil
+}
+
+func (s *fakeStore) Subscribe(subscriber func(), _ <-chan struct{}) {
+	s.subscriber = subscriber
+}
+
+type fakeIPVS struct {
+	ipvs.IPVS
+}
+
+func (fakeIPVS) ListServices(context.Context) ([]*types.VirtualService, error) {
+	return []*types.VirtualService{{Id: "svc1"}}, nil
+}
+
+var _ = Describe("Faults", func() {
+	var ctx context.Context
+
+	BeforeEach(func() {
+		ctx = context.Background()
+	})
+
+	Describe("Store", func() {
+		It("should pass requests through without faults", func() {
+			s := Store(&fakeStore{}, Config{})
+
+			services, err := s.ListServices(ctx)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(services).To(HaveLen(1))
+		})
+
+		It("should fail requests at the error rate", func() {
+			s := Store(&fakeStore{}, Config{StoreErrorRate: 1})
+
+			_, err := s.ListServices(ctx)
+
+			Expect(err).To(Equal(ErrStoreTimeout))
+		})
+
+		It("should delay requests until the context is done", func() {
+			s := Store(&fakeStore{}, Config{StoreDelay: time.Hour})
+			ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+			defer cancel()
+
+			_, err := s.ListServices(ctx)
+
+			Expect(err).To(Equal(context.DeadlineExceeded))
+		})
+
+		It("should delay notifying subscribers", func() {
+			backing := &fakeStore{}
+			s := Store(backing, Config{WatchDelay: 50 * time.Millisecond})
+			notified := make(chan struct{}, 1)
+			s.Subscribe(func() { notified <- struct{}{} }, nil)
+
+			start := time.Now()
+			backing.subscriber()
+
+			Eventually(notified).Should(Receive())
+			Expect(time.Since(start)).To(BeNumerically(">=", 50*time.Millisecond))
+		})
+	})
+
+	Describe("IPVS", func() {
+		It("should pass requests through without faults", func() {
+			i := IPVS(fakeIPVS{}, Config{})
+
+			services, err := i.ListServices(ctx)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(services).To(HaveLen(1))
+		})
+
+		It("should fail requests at the error rate", func() {
+			i := IPVS(fakeIPVS{}, Config{IPVSErrorRate: 1})
+
+			_, err := i.ListServices(ctx)
+
+			Expect(err).To(MatchError(ContainSubstring(ErrNetlink.Error())))
+		})
+	})
+})
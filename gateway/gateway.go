@@ -0,0 +1,299 @@
+// Package gateway serves the service and server RPCs of the API as REST with JSON bodies, for tooling which can't
+// speak gRPC:
+//
+//	GET    /api/v1/services                                 List, filtered by the query parameters of ListRequest
+//	POST   /api/v1/services                                 CreateService
+//	GET    /api/v1/services/{id}                            DescribeService, with ?history=n
+//	PUT    /api/v1/services/{id}                            UpdateService
+//	DELETE /api/v1/services/{id}                            DeleteService
+//	GET    /api/v1/services/{id}/stats                      GetServerStats, of the node serving the request
+//	POST   /api/v1/services/{id}/servers                    CreateServer
+//	GET    /api/v1/services/{id}/servers/{ip:port}          GetServer
+//	PUT    /api/v1/services/{id}/servers/{ip:port}          UpdateServer
+//	DELETE /api/v1/services/{id}/servers/{ip:port}          DeleteServer
+//	POST   /api/v1/services/{id}/servers/{ip:port}/drain    DrainServer
+//	POST   /api/v1/services/{id}/servers/{ip:port}/undrain  UndrainServer
+//
+// Bodies and responses are the JSON encoding of the RPC's request and response messages. Errors respond with the
+// HTTP status of their gRPC code, and a body of the form {"code": "NotFound", "message": "..."}.
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/sky-uk/merlin/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Prefix of the gateway's paths.
+const Prefix = "/api/v1/"
+
+// maxRequestSize of a request body.
+const maxRequestSize = 4 << 20
+
+// Invoker calls the API method of the given name, such as CreateService, with the request's headers as incoming
+// metadata. daemon.Daemon.Invoke is one.
+type Invoker func(ctx context.Context, method string, req proto.Message) (interface{}, error)
+
+var (
+	marshaler   = &jsonpb.Marshaler{OrigName: true}
+	unmarshaler = &jsonpb.Unmarshaler{}
+)
+
+// New returns a handler of the gateway's paths, which calls invoke. The Authorization header and headers starting
+// with X-Merlin, such as types.CandidateHeader, are passed on as metadata.
+func New(invoke Invoker) http.Handler {
+	return &gateway{invoke: invoke}
+}
+
+type gateway struct {
+	invoke Invoker
+}
+
+// route of a request, with the request message to call its method with.
+type route struct {
+	method string
+	req    proto.Message
+	// body is decoded into the request message if set.
+	body bool
+}
+
+func (g *gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rt, err := routeOf(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if rt.body {
+		// the path sets the IDs, so they're kept if the body doesn't have them
+		ids := proto.Clone(rt.req)
+		if err := unmarshaler.Unmarshal(io.LimitReader(r.Body, maxRequestSize), rt.req); err != nil && err != io.EOF {
+			writeError(w, status.Errorf(codes.InvalidArgument, "invalid request body: %v", err))
+			return
+		}
+		if err := mergeIDs(rt.req, ids); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+
+	resp, err := g.invoke(metadata.NewIncomingContext(r.Context(), headerMetadata(r.Header)), rt.method, rt.req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	js, err := marshaler.MarshalToString(resp.(proto.Message))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	io.WriteString(w, js+"\n")
+}
+
+// routeOf returns the route of a request, or a status error if there isn't one.
+func routeOf(r *http.Request) (*route, error) {
+	if !strings.HasPrefix(r.URL.Path, Prefix+"services") {
+		return nil, status.Errorf(codes.NotFound, "unknown path %s", r.URL.Path)
+	}
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, Prefix), "/"), "/")
+	notAllowed := status.Errorf(codes.Unimplemented, "%s isn't allowed on %s", r.Method, r.URL.Path)
+
+	switch {
+	case len(parts) == 1:
+		switch r.Method {
+		case http.MethodGet:
+			req, err := listRequest(r)
+			return &route{method: "List", req: req}, err
+		case http.MethodPost:
+			return &route{method: "CreateService", req: &types.VirtualService{}, body: true}, nil
+		}
+		return nil, notAllowed
+
+	case len(parts) == 2:
+		id := parts[1]
+		switch r.Method {
+		case http.MethodGet:
+			history, err := uintParam(r, "history")
+			return &route{method: "DescribeService", req: &types.DescribeServiceRequest{Id: id, History: history}},
+				err
+		case http.MethodPut:
+			return &route{method: "UpdateService", req: &types.VirtualService{Id: id}, body: true}, nil
+		case http.MethodDelete:
+			return &route{method: "DeleteService", req: &wrappers.StringValue{Value: id}}, nil
+		}
+		return nil, notAllowed
+
+	case len(parts) == 3 && parts[2] == "stats":
+		if r.Method == http.MethodGet {
+			return &route{method: "GetServerStats", req: &wrappers.StringValue{Value: parts[1]}}, nil
+		}
+		return nil, notAllowed
+
+	case len(parts) == 3 && parts[2] == "servers":
+		if r.Method == http.MethodPost {
+			return &route{method: "CreateServer", req: &types.RealServer{ServiceID: parts[1]}, body: true}, nil
+		}
+		return nil, notAllowed
+
+	case (len(parts) == 4 || len(parts) == 5) && parts[2] == "servers":
+		key, err := serverKey(parts[3])
+		if err != nil {
+			return nil, err
+		}
+		server := &types.RealServer{ServiceID: parts[1], Key: key}
+		switch {
+		case len(parts) == 4 && r.Method == http.MethodGet:
+			return &route{method: "GetServer", req: server}, nil
+		case len(parts) == 4 && r.Method == http.MethodPut:
+			return &route{method: "UpdateServer", req: server, body: true}, nil
+		case len(parts) == 4 && r.Method == http.MethodDelete:
+			return &route{method: "DeleteServer", req: server}, nil
+		case len(parts) == 5 && parts[4] == "drain" && r.Method == http.MethodPost:
+			return &route{method: "DrainServer", req: server}, nil
+		case len(parts) == 5 && parts[4] == "undrain" && r.Method == http.MethodPost:
+			return &route{method: "UndrainServer", req: server}, nil
+		}
+		return nil, notAllowed
+	}
+	return nil, status.Errorf(codes.NotFound, "unknown path %s", r.URL.Path)
+}
+
+func listRequest(r *http.Request) (*types.ListRequest, error) {
+	q := r.URL.Query()
+	pageSize, err := uintParam(r, "page_size")
+	if err != nil {
+		return nil, err
+	}
+	return &types.ListRequest{
+		LabelSelector:       q.Get("label_selector"),
+		FieldSelector:       q.Get("field_selector"),
+		PageSize:            int32(pageSize),
+		PageToken:           q.Get("page_token"),
+		IdPrefix:            q.Get("id_prefix"),
+		ServerLabelSelector: q.Get("server_label_selector"),
+	}, nil
+}
+
+func uintParam(r *http.Request, name string) (uint32, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseUint(value, 10, 31)
+	if err != nil {
+		return 0, status.Errorf(codes.InvalidArgument, "invalid %s %q", name, value)
+	}
+	return uint32(n), nil
+}
+
+// serverKey parses the ip:port of a server, such as 10.0.0.1:8080 or [2001:db8::1]:8080.
+func serverKey(ipPort string) (*types.RealServer_Key, error) {
+	host, port, err := net.SplitHostPort(ipPort)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid server %q, must be ip:port", ipPort)
+	}
+	p, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid port of server %q", ipPort)
+	}
+	return &types.RealServer_Key{Ip: host, Port: uint32(p)}, nil
+}
+
+// mergeIDs sets the service ID, and server key, of req from those of the path, which the body must either omit or
+// match.
+func mergeIDs(req, path proto.Message) error {
+	switch r := req.(type) {
+	case *types.VirtualService:
+		id := path.(*types.VirtualService).Id
+		if r.Id != "" && id != "" && r.Id != id {
+			return status.Errorf(codes.InvalidArgument, "id %q of the body doesn't match the path", r.Id)
+		}
+		if id != "" {
+			r.Id = id
+		}
+	case *types.RealServer:
+		p := path.(*types.RealServer)
+		if r.ServiceID != "" && r.ServiceID != p.ServiceID {
+			return status.Errorf(codes.InvalidArgument, "serviceID %q of the body doesn't match the path",
+				r.ServiceID)
+		}
+		r.ServiceID = p.ServiceID
+		if p.Key != nil {
+			if r.Key != nil && !proto.Equal(r.Key, p.Key) {
+				return status.Errorf(codes.InvalidArgument, "key %s of the body doesn't match the path",
+					r.Key.PrettyString())
+			}
+			r.Key = p.Key
+		}
+	}
+	return nil
+}
+
+// metadataHeaders are the request headers passed on as metadata. Others are dropped, so REST clients can't set
+// metadata only merlin nodes send, such as which node forwarded a write.
+var metadataHeaders = map[string]bool{
+	"authorization":       true,
+	types.CandidateHeader: true,
+	types.DryRunHeader:    true,
+}
+
+// headerMetadata returns the headers passed on as metadata.
+func headerMetadata(header http.Header) metadata.MD {
+	md := metadata.MD{}
+	for name, values := range header {
+		name = strings.ToLower(name)
+		if metadataHeaders[name] {
+			md.Append(name, values...)
+		}
+	}
+	return md
+}
+
+// httpStatus of each gRPC code, as mapped by grpc-gateway.
+var httpStatus = map[codes.Code]int{
+	codes.OK:                 http.StatusOK,
+	codes.Canceled:           499,
+	codes.Unknown:            http.StatusInternalServerError,
+	codes.InvalidArgument:    http.StatusBadRequest,
+	codes.DeadlineExceeded:   http.StatusGatewayTimeout,
+	codes.NotFound:           http.StatusNotFound,
+	codes.AlreadyExists:      http.StatusConflict,
+	codes.PermissionDenied:   http.StatusForbidden,
+	codes.ResourceExhausted:  http.StatusTooManyRequests,
+	codes.FailedPrecondition: http.StatusBadRequest,
+	codes.Aborted:            http.StatusConflict,
+	codes.OutOfRange:         http.StatusBadRequest,
+	codes.Unimplemented:      http.StatusNotImplemented,
+	codes.Internal:           http.StatusInternalServerError,
+	codes.Unavailable:        http.StatusServiceUnavailable,
+	codes.DataLoss:           http.StatusInternalServerError,
+	codes.Unauthenticated:    http.StatusUnauthorized,
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	s, ok := status.FromError(err)
+	if !ok {
+		s = status.New(codes.Internal, err.Error())
+	}
+	code, ok := httpStatus[s.Code()]
+	if !ok {
+		code = http.StatusInternalServerError
+	}
+	body, _ := json.Marshal(map[string]string{"code": s.Code().String(), "message": s.Message()})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	fmt.Fprintf(w, "%s\n", body)
+}
@@ -0,0 +1,166 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sky-uk/merlin/server"
+	"github.com/sky-uk/merlin/store"
+	"github.com/sky-uk/merlin/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestGateway(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Gateway Suite")
+}
+
+var _ = Describe("Gateway", func() {
+	var (
+		ts      *httptest.Server
+		st      store.Store
+		headers metadata.MD
+		extra   http.Header
+	)
+
+	do := func(method, path, body string) (int, map[string]interface{}) {
+		req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		Expect(err).ToNot(HaveOccurred())
+		req.Header.Set("Authorization", "Bearer s3cret")
+		req.Header.Set("Accept", "application/json")
+		for name, values := range extra {
+			req.Header[name] = values
+		}
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+		data, err := ioutil.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.Header.Get("Content-Type")).To(Equal("application/json"))
+		var decoded map[string]interface{}
+		Expect(json.Unmarshal(data, &decoded)).To(Succeed(), string(data))
+		return resp.StatusCode, decoded
+	}
+	servers := func(id string) []*types.RealServer {
+		servers, err := st.ListServers(context.Background(), id)
+		Expect(err).ToNot(HaveOccurred())
+		return servers
+	}
+
+	BeforeEach(func() {
+		extra = nil
+		st = store.NewMemory()
+		node := func() *types.Node { return &types.Node{Name: "node"} }
+		health := func(string, *types.RealServer_Key) types.Health { return types.Health_UNCHECKED }
+		errors := func(string) []string { return nil }
+		srv := server.New(st, nil, node, health, errors, server.Options{})
+		ts = httptest.NewServer(New(func(ctx context.Context, method string, req proto.Message) (interface{}, error) {
+			headers, _ = metadata.FromIncomingContext(ctx)
+			out := reflect.ValueOf(srv).MethodByName(method).Call(
+				[]reflect.Value{reflect.ValueOf(ctx), reflect.ValueOf(req)})
+			err, _ := out[1].Interface().(error)
+			return out[0].Interface(), err
+		}))
+
+		code, _ := do(http.MethodPost, "/api/v1/services",
+			`{"id": "web", "key": {"ip": "10.0.0.1", "port": 80, "protocol": "TCP"}, "config": {"scheduler": "sh"}}`)
+		Expect(code).To(Equal(http.StatusOK))
+	})
+
+	AfterEach(func() {
+		ts.Close()
+	})
+
+	It("should serve services", func() {
+		code, body := do(http.MethodGet, "/api/v1/services/web", "")
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(body["service"]).To(HaveKeyWithValue("id", "web"))
+
+		code, _ = do(http.MethodPut, "/api/v1/services/web", `{"config": {"scheduler": "wrr"}}`)
+		Expect(code).To(Equal(http.StatusOK))
+		svc, err := st.GetService(context.Background(), "web")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(svc.Config.Scheduler).To(Equal("wrr"))
+
+		code, body = do(http.MethodGet, "/api/v1/services?field_selector=port%3D80&id_prefix=we", "")
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(body["items"]).To(HaveLen(1))
+		Expect(body["total_size"]).To(BeEquivalentTo(1))
+
+		code, _ = do(http.MethodDelete, "/api/v1/services/web", "")
+		Expect(code).To(Equal(http.StatusOK))
+		code, body = do(http.MethodGet, "/api/v1/services/web", "")
+		Expect(code).To(Equal(http.StatusNotFound))
+		Expect(body).To(HaveKeyWithValue("code", "NotFound"))
+	})
+
+	It("should serve the servers of a service", func() {
+		code, _ := do(http.MethodPost, "/api/v1/services/web/servers",
+			`{"key": {"ip": "172.16.0.1", "port": 8080}, "config": {"weight": 1, "forward": "MASQ"}}`)
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(servers("web")).To(HaveLen(1))
+		Expect(servers("web")[0].ServiceID).To(Equal("web"))
+
+		code, body := do(http.MethodGet, "/api/v1/services/web/servers/172.16.0.1:8080", "")
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(body["config"]).To(HaveKeyWithValue("forward", "MASQ"))
+
+		code, _ = do(http.MethodPost, "/api/v1/services/web/servers/172.16.0.1:8080/drain", "")
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(servers("web")[0].Config.Weight.GetValue()).To(BeZero())
+
+		code, _ = do(http.MethodDelete, "/api/v1/services/web/servers/172.16.0.1:8080", "")
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(servers("web")).To(BeEmpty())
+	})
+
+	It("should pass on the authorization and merlin request headers as metadata", func() {
+		extra = http.Header{}
+		extra.Set(types.CandidateHeader, "true")
+		extra.Set("X-Merlin-Forwarded-By", "node2")
+		extra.Set("X-Merlin-Node-Token", "guess")
+
+		do(http.MethodGet, "/api/v1/services", "")
+
+		Expect(headers).To(Equal(metadata.Pairs("authorization", "Bearer s3cret", types.CandidateHeader, "true")))
+	})
+
+	It("should refuse bodies with different ids to the path", func() {
+		code, body := do(http.MethodPut, "/api/v1/services/web", `{"id": "other"}`)
+		Expect(code).To(Equal(http.StatusBadRequest))
+		Expect(body["message"]).To(ContainSubstring("doesn't match the path"))
+
+		code, _ = do(http.MethodPost, "/api/v1/services/web/servers", `{"serviceID": "other"}`)
+		Expect(code).To(Equal(http.StatusBadRequest))
+	})
+
+	It("should refuse invalid requests", func() {
+		code, _ := do(http.MethodPost, "/api/v1/services", `{"id": `)
+		Expect(code).To(Equal(http.StatusBadRequest))
+		code, _ = do(http.MethodDelete, "/api/v1/services/web/servers/172.16.0.1", "")
+		Expect(code).To(Equal(http.StatusBadRequest))
+		code, _ = do(http.MethodPatch, "/api/v1/services/web", "")
+		Expect(code).To(Equal(http.StatusNotImplemented))
+		code, _ = do(http.MethodGet, "/api/v1/pools", "")
+		Expect(code).To(Equal(http.StatusNotFound))
+	})
+
+	It("should respond with the HTTP status of gRPC errors", func() {
+		rec := httptest.NewRecorder()
+		writeError(rec, status.Error(codes.PermissionDenied, "deploy can't write service web"))
+
+		Expect(rec.Code).To(Equal(http.StatusForbidden))
+		Expect(rec.Body.String()).To(MatchJSON(`{"code": "PermissionDenied", "message": "deploy can't write service web"}`))
+	})
+})
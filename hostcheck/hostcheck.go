@@ -0,0 +1,66 @@
+// Package hostcheck checks the kernel setup of an IPVS node, for merlin's self-test and meradm doctor.
+package hostcheck
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// capNetAdmin is the bit of CAP_NET_ADMIN in a capability set, required to configure IPVS.
+const capNetAdmin = 12
+
+// Sysctl is a sysctl setting recommended on IPVS nodes.
+type Sysctl struct {
+	Name   string
+	Value  string
+	Reason string
+}
+
+// RecommendedSysctls are the sysctl settings recommended on IPVS nodes.
+var RecommendedSysctls = []Sysctl{
+	{"net.ipv4.ip_forward", "1", "required to forward packets to MASQ real servers"},
+	{"net.ipv4.vs.expire_nodest_conn", "1",
+		"drops connections to deleted real servers immediately, instead of when they time out"},
+}
+
+// Read returns the current value of the sysctl.
+func (s Sysctl) Read() (string, error) {
+	raw, err := ioutil.ReadFile("/proc/sys/" + strings.Replace(s.Name, ".", "/", -1))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(raw)), nil
+}
+
+// IPVSLoaded returns true if the ip_vs kernel module is loaded.
+func IPVSLoaded() bool {
+	_, err := os.Stat("/proc/net/ip_vs")
+	return err == nil
+}
+
+// HasNetAdmin returns true if the process has CAP_NET_ADMIN in its effective capabilities.
+func HasNetAdmin(pid int) (bool, error) {
+	raw, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		return false, err
+	}
+	caps, err := effectiveCapabilities(string(raw))
+	if err != nil {
+		return false, err
+	}
+	return caps&(1<<capNetAdmin) != 0, nil
+}
+
+// effectiveCapabilities returns the CapEff bit set of a process status.
+func effectiveCapabilities(status string) (uint64, error) {
+	for _, line := range strings.Split(status, "\n") {
+		if strings.HasPrefix(line, "CapEff:") {
+			return strconv.ParseUint(strings.TrimSpace(strings.TrimPrefix(line, "CapEff:")), 16, 64)
+		}
+	}
+	return 0, errors.New("no CapEff in process status")
+}
@@ -0,0 +1,28 @@
+package hostcheck
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestHostcheck(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Hostcheck Suite")
+}
+
+var _ = Describe("effectiveCapabilities", func() {
+	It("should parse the CapEff bit set of a process status", func() {
+		caps, err := effectiveCapabilities("Name:\tmerlin\nCapInh:\t0000000000000000\nCapEff:\t0000000000001000\n")
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(caps & (1 << capNetAdmin)).ToNot(BeZero())
+	})
+
+	It("should fail without a CapEff line", func() {
+		_, err := effectiveCapabilities("Name:\tmerlin\n")
+
+		Expect(err).To(HaveOccurred())
+	})
+})
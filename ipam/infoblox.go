@@ -0,0 +1,96 @@
+package ipam
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sky-uk/merlin/types"
+)
+
+// infobloxVersion of the WAPI used.
+const infobloxVersion = "v2.7"
+
+// Infoblox allocates IPs from the networks of an Infoblox grid, reserving them as fixed addresses. Each CIDR of a
+// pool must be a network in Infoblox.
+type Infoblox struct {
+	client *client
+}
+
+// NewInfoblox calls the grid master at url, such as https://infoblox.example.com, authenticating as username
+// and waiting up to timeout for each request. If caFile is set, https urls are verified with its certificates
+// instead of the system's.
+func NewInfoblox(url, username, password, caFile string, timeout time.Duration) (*Infoblox, error) {
+	c, err := newClient(url, caFile, timeout, func(req *http.Request) {
+		req.SetBasicAuth(username, password)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Infoblox{client: c}, nil
+}
+
+// fixedAddress returns the object type and address field of fixed addresses of the CIDR or IP's version.
+func fixedAddress(addr string) (string, string) {
+	if strings.Contains(addr, ":") {
+		return "ipv6fixedaddress", "ipv6addr"
+	}
+	return "fixedaddress", "ipv4addr"
+}
+
+// Allocate reserves the next available IP of the pool's networks as a fixed address.
+func (b *Infoblox) Allocate(ctx context.Context, pool *types.VIPPool, serviceID string) (string, error) {
+	return allocate(pool.Cidrs, pool.Name, func(cidr string) (string, error) {
+		object, field := fixedAddress(cidr)
+		req := map[string]string{
+			field:     "func:nextavailableip:" + cidr,
+			"comment": Description(serviceID),
+		}
+		// fixed addresses must be matched to a client, which a VIP never is
+		if field == "ipv4addr" {
+			req["mac"] = "00:00:00:00:00:00"
+		} else {
+			req["duid"] = "00:00"
+		}
+
+		var created map[string]interface{}
+		path := "/wapi/" + infobloxVersion + "/" + object + "?_return_fields=" + field
+		code, err := b.client.do(ctx, http.MethodPost, path, req, &created)
+		// Infoblox responds with 400 Bad Request if the network is full, which is also its response to invalid
+		// requests, so the error says which
+		if code == http.StatusBadRequest && strings.Contains(err.Error(), "Cannot find 1 available IP") {
+			return "", errFull
+		}
+		if err != nil {
+			return "", err
+		}
+		ip := net.ParseIP(fmt.Sprint(created[field]))
+		if ip == nil {
+			return "", fmt.Errorf("invalid address %v", created[field])
+		}
+		return ip.String(), nil
+	})
+}
+
+// Release deletes the fixed addresses reserved for the service.
+func (b *Infoblox) Release(ctx context.Context, serviceID, ip string) error {
+	object, field := fixedAddress(ip)
+	query := url.Values{field: {ip}, "comment": {Description(serviceID)}}
+	var refs []struct {
+		Ref string `json:"_ref"`
+	}
+	path := "/wapi/" + infobloxVersion + "/" + object + "?" + query.Encode()
+	if _, err := b.client.do(ctx, http.MethodGet, path, nil, &refs); err != nil {
+		return err
+	}
+	for _, ref := range refs {
+		if _, err := b.client.do(ctx, http.MethodDelete, "/wapi/"+infobloxVersion+"/"+ref.Ref, nil, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+// Package ipam allocates the IPs of services in pools from an external address registry, such as NetBox or
+// Infoblox, so the registry records every IP merlin uses and which service it's for.
+//
+// Each driver creates an address in the registry for every allocated IP, described as "merlin service <id>", and
+// only deletes addresses with that description when releasing them, so addresses created by anything else are
+// never touched.
+package ipam
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxResponseSize of a registry response.
+const maxResponseSize = 4 << 20
+
+// Description of the addresses allocated to a service.
+func Description(serviceID string) string {
+	return "merlin service " + serviceID
+}
+
+// errFull is returned by drivers when a CIDR has no free IPs, so the next CIDR of the pool is tried.
+var errFull = status.Error(codes.ResourceExhausted, "no free IPs")
+
+// client calls a registry's JSON API.
+type client struct {
+	url       string
+	client    *http.Client
+	authorize func(*http.Request)
+}
+
+func newClient(url, caFile string, timeout time.Duration, authorize func(*http.Request)) (*client, error) {
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return nil, fmt.Errorf("url %q must be http or https", url)
+	}
+	transport := &http.Transport{}
+	if caFile != "" {
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read CA certificate: %v", err)
+		}
+		config := &tls.Config{RootCAs: x509.NewCertPool()}
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", caFile)
+		}
+		transport.TLSClientConfig = config
+	}
+	return &client{
+		url:       strings.TrimSuffix(url, "/"),
+		client:    &http.Client{Transport: transport, Timeout: timeout},
+		authorize: authorize,
+	}, nil
+}
+
+// do sends in as JSON to the path, decoding the response into out if it's set. It returns the status code of the
+// response, and an error if it isn't 2xx.
+func (c *client) do(ctx context.Context, method, path string, in, out interface{}) (int, error) {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return 0, err
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, c.url+path, body)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	c.authorize(req)
+
+	resp, err := c.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return resp.StatusCode, fmt.Errorf("unable to read response: %v", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(data)))
+	}
+	if out != nil && resp.StatusCode != http.StatusNoContent {
+		if err := json.Unmarshal(data, out); err != nil {
+			return resp.StatusCode, fmt.Errorf("unable to decode response: %v", err)
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+// allocate tries each CIDR in turn until one has a free IP.
+func allocate(cidrs []string, poolName string, allocate func(cidr string) (string, error)) (string, error) {
+	for _, cidr := range cidrs {
+		ip, err := allocate(cidr)
+		if err == errFull {
+			continue
+		}
+		if err != nil {
+			return "", status.Errorf(codes.Unavailable, "unable to allocate from %s of pool %s: %v", cidr,
+				poolName, err)
+		}
+		return ip, nil
+	}
+	return "", status.Errorf(codes.ResourceExhausted, "pool %s has no free IPs", poolName)
+}
@@ -0,0 +1,197 @@
+package ipam
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sky-uk/merlin/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestIPAM(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "IPAM Suite")
+}
+
+// request made to a fake registry
+type request struct {
+	method string
+	uri    string
+	auth   string
+	body   map[string]string
+}
+
+var _ = Describe("Drivers", func() {
+	var (
+		ctx      = context.Background()
+		ts       *httptest.Server
+		requests []request
+		handle   func(w http.ResponseWriter, r *http.Request)
+		pool     = &types.VIPPool{Name: "edge", Cidrs: []string{"10.0.0.0/30", "10.0.1.0/30"}}
+	)
+
+	BeforeEach(func() {
+		requests = nil
+		ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			req := request{method: r.Method, uri: r.URL.RequestURI(), auth: r.Header.Get("Authorization")}
+			if r.Method == http.MethodPost {
+				Expect(json.NewDecoder(r.Body).Decode(&req.body)).To(Succeed())
+			}
+			requests = append(requests, req)
+			handle(w, r)
+		}))
+	})
+
+	AfterEach(func() {
+		ts.Close()
+	})
+
+	Describe("NetBox", func() {
+		var netbox *NetBox
+
+		BeforeEach(func() {
+			var err error
+			netbox, err = NewNetBox(ts.URL+"/", "secret", "", time.Second)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("should allocate from the first prefix with available IPs", func() {
+			handle = func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/api/ipam/prefixes/":
+					id := map[string]string{"10.0.0.0/30": "1", "10.0.1.0/30": "2"}[r.URL.Query().Get("prefix")]
+					w.Write([]byte(`{"results": [{"id": ` + id + `}]}`))
+				case "/api/ipam/prefixes/1/available-ips/":
+					w.WriteHeader(http.StatusConflict)
+				case "/api/ipam/prefixes/2/available-ips/":
+					w.WriteHeader(http.StatusCreated)
+					w.Write([]byte(`{"id": 7, "address": "10.0.1.1/30"}`))
+				}
+			}
+
+			ip, err := netbox.Allocate(ctx, pool, "web")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ip).To(Equal("10.0.1.1"))
+			Expect(requests).To(HaveLen(4))
+			Expect(requests[3]).To(Equal(request{
+				method: http.MethodPost,
+				uri:    "/api/ipam/prefixes/2/available-ips/",
+				auth:   "Token secret",
+				body:   map[string]string{"description": "merlin service web"},
+			}))
+		})
+
+		It("should fail when every prefix is full", func() {
+			handle = func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/api/ipam/prefixes/" {
+					w.Write([]byte(`{"results": [{"id": 1}]}`))
+					return
+				}
+				w.WriteHeader(http.StatusNoContent)
+			}
+
+			_, err := netbox.Allocate(ctx, pool, "web")
+			Expect(status.Code(err)).To(Equal(codes.ResourceExhausted))
+		})
+
+		It("should fail when a prefix doesn't exist", func() {
+			handle = func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"results": []}`))
+			}
+
+			_, err := netbox.Allocate(ctx, pool, "web")
+			Expect(status.Code(err)).To(Equal(codes.Unavailable))
+			Expect(err.Error()).To(ContainSubstring("prefix 10.0.0.0/30 doesn't exist"))
+		})
+
+		It("should only release addresses allocated to the service", func() {
+			handle = func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodGet {
+					w.Write([]byte(`{"results": [
+						{"id": 7, "address": "10.0.1.1/30", "description": "merlin service web"},
+						{"id": 8, "address": "10.0.1.1/30", "description": "someone else's"}]}`))
+					return
+				}
+				w.WriteHeader(http.StatusNoContent)
+			}
+
+			Expect(netbox.Release(ctx, "web", "10.0.1.1")).To(Succeed())
+			Expect(requests).To(HaveLen(2))
+			Expect(requests[0].uri).To(Equal("/api/ipam/ip-addresses/?address=10.0.1.1"))
+			Expect(requests[1].method).To(Equal(http.MethodDelete))
+			Expect(requests[1].uri).To(Equal("/api/ipam/ip-addresses/7/"))
+		})
+	})
+
+	Describe("Infoblox", func() {
+		var infoblox *Infoblox
+
+		BeforeEach(func() {
+			var err error
+			infoblox, err = NewInfoblox(ts.URL, "merlin", "secret", "", time.Second)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("should reserve the next available IP as a fixed address", func() {
+			handle = func(w http.ResponseWriter, r *http.Request) {
+				if requests[len(requests)-1].body["ipv4addr"] == "func:nextavailableip:10.0.0.0/30" {
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write([]byte(`{"Error": "AdmConDataError: None (IBDataConflictError: ` +
+						`IB.Data.Conflict:Cannot find 1 available IP address(es) in this network)"}`))
+					return
+				}
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte(`{"_ref": "fixedaddress/abc:10.0.1.1/default", "ipv4addr": "10.0.1.1"}`))
+			}
+
+			ip, err := infoblox.Allocate(ctx, pool, "web")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ip).To(Equal("10.0.1.1"))
+			Expect(requests).To(HaveLen(2))
+			Expect(requests[1].uri).To(Equal("/wapi/v2.7/fixedaddress?_return_fields=ipv4addr"))
+			Expect(requests[1].auth).To(HavePrefix("Basic "))
+			Expect(requests[1].body).To(Equal(map[string]string{
+				"ipv4addr": "func:nextavailableip:10.0.1.0/30",
+				"mac":      "00:00:00:00:00:00",
+				"comment":  "merlin service web",
+			}))
+		})
+
+		It("should fail on other errors", func() {
+			handle = func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"Error": "AdmConDataNotFoundError: network not found"}`))
+			}
+
+			_, err := infoblox.Allocate(ctx, pool, "web")
+			Expect(status.Code(err)).To(Equal(codes.Unavailable))
+		})
+
+		It("should delete the fixed addresses of the service", func() {
+			handle = func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodGet {
+					w.Write([]byte(`[{"_ref": "ipv6fixedaddress/abc:2001%3Adb8%3A%3A1/default"}]`))
+				}
+			}
+
+			Expect(infoblox.Release(ctx, "web", "2001:db8::1")).To(Succeed())
+			Expect(requests).To(HaveLen(2))
+			Expect(requests[0].uri).To(Equal(
+				"/wapi/v2.7/ipv6fixedaddress?comment=merlin+service+web&ipv6addr=2001%3Adb8%3A%3A1"))
+			Expect(requests[1].method).To(Equal(http.MethodDelete))
+			Expect(requests[1].uri).To(Equal("/wapi/v2.7/ipv6fixedaddress/abc:2001%3Adb8%3A%3A1/default"))
+		})
+	})
+
+	It("should reject urls which aren't http", func() {
+		_, err := NewNetBox("netbox.example.com", "secret", "", time.Second)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,92 @@
+package ipam
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sky-uk/merlin/types"
+)
+
+// NetBox allocates IPs from the prefixes of a NetBox registry. Each CIDR of a pool must be a prefix in NetBox.
+type NetBox struct {
+	client *client
+}
+
+// NewNetBox calls the NetBox at url, such as https://netbox.example.com, authenticating with the API token and
+// waiting up to timeout for each request. If caFile is set, https urls are verified with its certificates instead
+// of the system's.
+func NewNetBox(url, token, caFile string, timeout time.Duration) (*NetBox, error) {
+	c, err := newClient(url, caFile, timeout, func(req *http.Request) {
+		req.Header.Set("Authorization", "Token "+token)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &NetBox{client: c}, nil
+}
+
+type netBoxList struct {
+	Results []struct {
+		ID          int    `json:"id"`
+		Address     string `json:"address"`
+		Description string `json:"description"`
+	} `json:"results"`
+}
+
+// Allocate creates the first available IP address of the pool's prefixes in NetBox.
+func (n *NetBox) Allocate(ctx context.Context, pool *types.VIPPool, serviceID string) (string, error) {
+	return allocate(pool.Cidrs, pool.Name, func(cidr string) (string, error) {
+		var prefixes netBoxList
+		if _, err := n.client.do(ctx, http.MethodGet, "/api/ipam/prefixes/?prefix="+url.QueryEscape(cidr), nil,
+			&prefixes); err != nil {
+			return "", err
+		}
+		if len(prefixes.Results) == 0 {
+			return "", fmt.Errorf("prefix %s doesn't exist", cidr)
+		}
+
+		var addr struct {
+			Address string `json:"address"`
+		}
+		path := fmt.Sprintf("/api/ipam/prefixes/%d/available-ips/", prefixes.Results[0].ID)
+		code, err := n.client.do(ctx, http.MethodPost, path, map[string]string{
+			"description": Description(serviceID),
+		}, &addr)
+		// NetBox responds with 409 Conflict, or 204 No Content in older versions, if the prefix is full
+		if code == http.StatusConflict || code == http.StatusNoContent {
+			return "", errFull
+		}
+		if err != nil {
+			return "", err
+		}
+		ip, _, err := net.ParseCIDR(addr.Address)
+		if err != nil {
+			return "", fmt.Errorf("invalid address %q: %v", addr.Address, err)
+		}
+		return ip.String(), nil
+	})
+}
+
+// Release deletes the IP addresses in NetBox which were allocated to the service.
+func (n *NetBox) Release(ctx context.Context, serviceID, ip string) error {
+	var addrs netBoxList
+	if _, err := n.client.do(ctx, http.MethodGet, "/api/ipam/ip-addresses/?address="+url.QueryEscape(ip), nil,
+		&addrs); err != nil {
+		return err
+	}
+	for _, addr := range addrs.Results {
+		if addr.Description != Description(serviceID) || !strings.HasPrefix(addr.Address, ip+"/") {
+			continue
+		}
+		path := fmt.Sprintf("/api/ipam/ip-addresses/%d/", addr.ID)
+		if _, err := n.client.do(ctx, http.MethodDelete, path, nil, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package ipvs
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/sky-uk/merlin/types"
+)
+
+// connTable is the kernel's IPVS connection table, which netlink can't list.
+const connTable = "/proc/net/ip_vs_conn"
+
+var connProtocols = map[string]types.Protocol{
+	"TCP": types.Protocol_TCP,
+	"UDP": types.Protocol_UDP,
+}
+
+func (s *shim) Connections(ctx context.Context, fn func(*types.Connection) error) error {
+	f, err := os.Open(connTable)
+	if err != nil {
+		return fmt.Errorf("unable to read connection table: %v", err)
+	}
+	defer f.Close()
+	return readConnections(ctx, f, fn)
+}
+
+// readConnections calls fn with each connection of a table in the format of /proc/net/ip_vs_conn, skipping the
+// protocols merlin doesn't manage.
+func readConnections(ctx context.Context, r io.Reader, fn func(*types.Connection) error) error {
+	scanner := bufio.NewScanner(r)
+	// skip the header
+	if !scanner.Scan() {
+		return scanner.Err()
+	}
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		conn, err := parseConnection(scanner.Text())
+		if err != nil {
+			return err
+		}
+		if conn == nil {
+			continue
+		}
+		if err := fn(conn); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// parseConnection parses a line of the connection table:
+//
+//	Pro FromIP   FPrt ToIP     TPrt DestIP   DPrt State       Expires PEName PEData
+//	TCP 0A000001 D431 0A0A0A0A 0050 AC100101 1F90 ESTABLISHED     899
+//
+// IPv4 addresses and ports are in hex, IPv6 addresses aren't.
+func parseConnection(line string) (*types.Connection, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 9 {
+		return nil, fmt.Errorf("unexpected connection %q", line)
+	}
+	protocol, ok := connProtocols[fields[0]]
+	if !ok {
+		return nil, nil
+	}
+	var addrs [3]struct {
+		ip   string
+		port uint32
+	}
+	for i := range addrs {
+		ip, port, err := parseConnAddress(fields[1+2*i], fields[2+2*i])
+		if err != nil {
+			return nil, fmt.Errorf("unexpected connection %q: %v", line, err)
+		}
+		addrs[i].ip, addrs[i].port = ip, port
+	}
+	expires, err := strconv.ParseUint(fields[8], 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("unexpected connection %q: %v", line, err)
+	}
+
+	return &types.Connection{
+		Service:    &types.VirtualService_Key{Ip: addrs[1].ip, Port: addrs[1].port, Protocol: protocol},
+		ClientIp:   addrs[0].ip,
+		ClientPort: addrs[0].port,
+		Server:     &types.RealServer_Key{Ip: addrs[2].ip, Port: addrs[2].port},
+		State:      fields[7],
+		Expires:    ptypes.DurationProto(time.Duration(expires) * time.Second),
+		Template:   addrs[0].port == 0,
+	}, nil
+}
+
+func parseConnAddress(hexIP, hexPort string) (string, uint32, error) {
+	port, err := strconv.ParseUint(hexPort, 16, 16)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid port %q", hexPort)
+	}
+	if strings.Contains(hexIP, ":") {
+		ip := net.ParseIP(hexIP)
+		if ip == nil {
+			return "", 0, fmt.Errorf("invalid ip %q", hexIP)
+		}
+		return ip.String(), uint32(port), nil
+	}
+	b, err := strconv.ParseUint(hexIP, 16, 32)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid ip %q", hexIP)
+	}
+	ip := make(net.IP, net.IPv4len)
+	binary.BigEndian.PutUint32(ip, uint32(b))
+	return ip.String(), uint32(port), nil
+}
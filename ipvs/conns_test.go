@@ -0,0 +1,59 @@
+package ipvs
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sky-uk/merlin/types"
+)
+
+var _ = Describe("Connection table", func() {
+	read := func(table string) ([]*types.Connection, error) {
+		var conns []*types.Connection
+		err := readConnections(context.Background(), strings.NewReader(table), func(conn *types.Connection) error {
+			conns = append(conns, conn)
+			return nil
+		})
+		return conns, err
+	}
+
+	It("should parse connections and persistence templates", func() {
+		conns, err := read(`Pro FromIP   FPrt ToIP     TPrt DestIP   DPrt State       Expires PEName PEData
+TCP 0A000001 D431 0A0A0A0A 0050 AC100101 1F90 ESTABLISHED     899
+UDP 0A000002 0035 0A0A0A0A 0035 AC100102 0035 UDP             119
+TCP 0A000001 0000 0A0A0A0A 0050 AC100101 1F90 NONE            359
+SCTP 0A000001 0050 0A0A0A0A 0050 AC100101 0050 ESTABLISHED     10
+TCP 2001:0db8:0000:0000:0000:0000:0000:0001 D431 2001:0db8:0000:0000:0000:0000:0000:000a 0050 ` +
+			`2001:0db8:0000:0000:0000:0000:0000:0064 1F90 TIME_WAIT        60
+`)
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(conns).To(HaveLen(4))
+		conn := conns[0]
+		Expect(conn.ClientIp).To(Equal("10.0.0.1"))
+		Expect(conn.ClientPort).To(Equal(uint32(54321)))
+		Expect(conn.Service.PrettyString()).To(Equal("10.10.10.10:80 TCP"))
+		Expect(conn.Server.PrettyString()).To(Equal("172.16.1.1:8080"))
+		Expect(conn.State).To(Equal("ESTABLISHED"))
+		Expect(ptypes.Duration(conn.Expires)).To(Equal(899 * time.Second))
+		Expect(conn.Template).To(BeFalse())
+
+		Expect(conns[1].Service.Protocol).To(Equal(types.Protocol_UDP))
+		Expect(conns[2].Template).To(BeTrue())
+		Expect(conns[3].ClientIp).To(Equal("2001:db8::1"))
+		Expect(conns[3].Service.Ip).To(Equal("2001:db8::a"))
+		Expect(conns[3].Server.Ip).To(Equal("2001:db8::64"))
+	})
+
+	It("should fail on unexpected lines", func() {
+		_, err := read(`Pro FromIP   FPrt ToIP     TPrt DestIP   DPrt State       Expires PEName PEData
+TCP 0A000001
+`)
+
+		Expect(err).To(HaveOccurred())
+	})
+})
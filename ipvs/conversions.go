@@ -22,6 +22,14 @@ const (
 	ipVsSvcFSchedShPort     = ipVsSvcFSched2 /* SH use port */
 )
 
+// Tunnel values are found in ip_vs.h in the kernel.
+const (
+	ipVsConnFTunnelTypeIPIP    = 0      /* IPIP */
+	ipVsConnFTunnelTypeGUE     = 1      /* GUE */
+	ipVsTunnelEncapFlagCsum    = 1 << 0 /* UDP checksum */
+	ipVsTunnelEncapFlagRemCsum = 1 << 1 /* remote checksum offload */
+)
+
 var (
 	schedulerFlags = map[string]uint32{
 		"flag-1": ipVsSvcFSched1,
@@ -31,11 +39,25 @@ var (
 	schedulerFlagsInverted map[uint32]string
 
 	forwardingMethods = map[types.ForwardMethod]uint32{
-		types.ForwardMethod_ROUTE:  ipvs.ConnectionFlagDirectRoute,
-		types.ForwardMethod_MASQ:   ipvs.ConnectionFlagMasq,
-		types.ForwardMethod_TUNNEL: ipvs.ConnectionFlagTunnel,
+		types.ForwardMethod_ROUTE:     ipvs.ConnectionFlagDirectRoute,
+		types.ForwardMethod_MASQ:      ipvs.ConnectionFlagMasq,
+		types.ForwardMethod_TUNNEL:    ipvs.ConnectionFlagTunnel,
+		types.ForwardMethod_LOCALNODE: ipvs.ConnectionFlagLocalNode,
 	}
 	forwardingMethodsInverted map[uint32]types.ForwardMethod
+
+	tunnelTypes = map[types.TunnelType]uint8{
+		types.TunnelType_IPIP: ipVsConnFTunnelTypeIPIP,
+		types.TunnelType_GUE:  ipVsConnFTunnelTypeGUE,
+	}
+	tunnelTypesInverted map[uint8]types.TunnelType
+
+	tunnelChecksums = map[types.TunnelChecksum]uint16{
+		types.TunnelChecksum_NO_CHECKSUM:     0,
+		types.TunnelChecksum_CHECKSUM:        ipVsTunnelEncapFlagCsum,
+		types.TunnelChecksum_REMOTE_CHECKSUM: ipVsTunnelEncapFlagRemCsum,
+	}
+	tunnelChecksumsInverted map[uint16]types.TunnelChecksum
 )
 
 func init() {
@@ -47,6 +69,14 @@ func init() {
 	for k, v := range forwardingMethods {
 		forwardingMethodsInverted[v] = k
 	}
+	tunnelTypesInverted = make(map[uint8]types.TunnelType)
+	for k, v := range tunnelTypes {
+		tunnelTypesInverted[v] = k
+	}
+	tunnelChecksumsInverted = make(map[uint16]types.TunnelChecksum)
+	for k, v := range tunnelChecksums {
+		tunnelChecksumsInverted[v] = k
+	}
 }
 
 func toProtocolBits(protocol types.Protocol) (uint16, error) {
@@ -94,3 +124,32 @@ func fromFlagBits(flagbits uint32) []string {
 	sort.Strings(flags)
 	return flags
 }
+
+// toTunnel returns the tunnel of a server config, which is zero for IPIP.
+func toTunnel(config *types.RealServer_Config) (tunnel, error) {
+	tunType, ok := tunnelTypes[config.TunnelType]
+	if !ok {
+		return tunnel{}, fmt.Errorf("invalid tunnel type %q", config.TunnelType)
+	}
+	flags, ok := tunnelChecksums[config.TunnelChecksum]
+	if !ok {
+		return tunnel{}, fmt.Errorf("invalid tunnel checksum %q", config.TunnelChecksum)
+	}
+	return tunnel{Type: tunType, Port: uint16(config.TunnelPort), Flags: flags}, nil
+}
+
+// fromTunnel sets the tunnel of a server config.
+func fromTunnel(tun tunnel, config *types.RealServer_Config) error {
+	tunType, ok := tunnelTypesInverted[tun.Type]
+	if !ok {
+		return fmt.Errorf("unexpected tunnel type %d", tun.Type)
+	}
+	checksum, ok := tunnelChecksumsInverted[tun.Flags]
+	if !ok {
+		return fmt.Errorf("unexpected tunnel flags %#x", tun.Flags)
+	}
+	config.TunnelType = tunType
+	config.TunnelPort = uint32(tun.Port)
+	config.TunnelChecksum = checksum
+	return nil
+}
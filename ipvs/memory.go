@@ -0,0 +1,253 @@
+package ipvs
+
+import (
+	"context"
+	"sync"
+	"syscall"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/sky-uk/merlin/types"
+)
+
+type memoryService struct {
+	svc     *types.VirtualService
+	servers []*types.RealServer
+}
+
+type memory struct {
+	mu       sync.Mutex
+	services []*memoryService
+	daemons  []*types.SyncDaemon
+}
+
+// NewMemory returns an IPVS which keeps services and servers in memory instead of the kernel, for tests which
+// reconcile without root. Only the fields IPVS stores are kept, and errors match the kernel's.
+func NewMemory() IPVS {
+	return &memory{}
+}
+
+func (m *memory) Close() {}
+
+func (m *memory) find(key *types.VirtualService_Key) (int, *memoryService) {
+	for i, s := range m.services {
+		if proto.Equal(s.svc.Key, key) {
+			return i, s
+		}
+	}
+	return -1, nil
+}
+
+// kernelService returns the fields of svc which IPVS stores.
+func kernelService(svc *types.VirtualService) *types.VirtualService {
+	return &types.VirtualService{
+		Key: proto.Clone(svc.Key).(*types.VirtualService_Key),
+		Config: &types.VirtualService_Config{
+			Scheduler: svc.GetConfig().GetScheduler(),
+			Flags:     append([]string(nil), svc.GetConfig().GetFlags()...),
+			Ops:       svc.GetConfig().GetOps(),
+		},
+	}
+}
+
+// kernelServer returns the fields of server which IPVS stores.
+func kernelServer(server *types.RealServer) *types.RealServer {
+	return &types.RealServer{
+		Key: proto.Clone(server.Key).(*types.RealServer_Key),
+		Config: &types.RealServer_Config{
+			Weight:         &wrappers.UInt32Value{Value: server.GetConfig().GetWeight().GetValue()},
+			Forward:        server.GetConfig().GetForward(),
+			TunnelType:     server.GetConfig().GetTunnelType(),
+			TunnelPort:     server.GetConfig().GetTunnelPort(),
+			TunnelChecksum: server.GetConfig().GetTunnelChecksum(),
+		},
+	}
+}
+
+// validTunnel is false if the server is tunneled with GUE without a port, which the kernel rejects.
+func validTunnel(server *types.RealServer) bool {
+	return server.GetConfig().GetForward() != types.ForwardMethod_TUNNEL ||
+		server.GetConfig().GetTunnelType() != types.TunnelType_GUE || server.GetConfig().GetTunnelPort() != 0
+}
+
+func (m *memory) AddService(_ context.Context, svc *types.VirtualService) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, s := m.find(svc.Key); s != nil {
+		return syscall.EEXIST
+	}
+	if svc.GetConfig().GetOps() && svc.Key.Protocol != types.Protocol_UDP {
+		return syscall.EINVAL
+	}
+	m.services = append(m.services, &memoryService{svc: kernelService(svc)})
+	return nil
+}
+
+func (m *memory) UpdateService(_ context.Context, svc *types.VirtualService) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, s := m.find(svc.Key)
+	if s == nil {
+		return syscall.ESRCH
+	}
+	s.svc = kernelService(svc)
+	return nil
+}
+
+func (m *memory) DeleteService(_ context.Context, key *types.VirtualService_Key) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i, s := m.find(key)
+	if s == nil {
+		return syscall.ESRCH
+	}
+	m.services = append(m.services[:i], m.services[i+1:]...)
+	return nil
+}
+
+func (m *memory) ListServices(context.Context) ([]*types.VirtualService, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var svcs []*types.VirtualService
+	for _, s := range m.services {
+		svcs = append(svcs, proto.Clone(s.svc).(*types.VirtualService))
+	}
+	return svcs, nil
+}
+
+func (m *memory) findServer(key *types.VirtualService_Key, server *types.RealServer) (*memoryService, int,
+	error) {
+	_, s := m.find(key)
+	if s == nil {
+		return nil, -1, syscall.ESRCH
+	}
+	for i, existing := range s.servers {
+		if proto.Equal(existing.Key, server.Key) {
+			return s, i, nil
+		}
+	}
+	return s, -1, nil
+}
+
+func (m *memory) AddServer(_ context.Context, key *types.VirtualService_Key, server *types.RealServer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, i, err := m.findServer(key, server)
+	if err != nil {
+		return err
+	}
+	if i >= 0 {
+		return syscall.EEXIST
+	}
+	if !validTunnel(server) {
+		return syscall.EINVAL
+	}
+	s.servers = append(s.servers, kernelServer(server))
+	return nil
+}
+
+func (m *memory) UpdateServer(_ context.Context, key *types.VirtualService_Key, server *types.RealServer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, i, err := m.findServer(key, server)
+	if err != nil {
+		return err
+	}
+	if i < 0 {
+		return syscall.ENOENT
+	}
+	if !validTunnel(server) {
+		return syscall.EINVAL
+	}
+	s.servers[i] = kernelServer(server)
+	return nil
+}
+
+func (m *memory) DeleteServer(_ context.Context, key *types.VirtualService_Key, server *types.RealServer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, i, err := m.findServer(key, server)
+	if err != nil {
+		return err
+	}
+	if i < 0 {
+		return syscall.ENOENT
+	}
+	s.servers = append(s.servers[:i], s.servers[i+1:]...)
+	return nil
+}
+
+func (m *memory) ListServers(_ context.Context, key *types.VirtualService_Key) ([]*types.RealServer, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, s := m.find(key)
+	if s == nil {
+		return nil, syscall.ESRCH
+	}
+	var servers []*types.RealServer
+	for _, server := range s.servers {
+		servers = append(servers, proto.Clone(server).(*types.RealServer))
+	}
+	return servers, nil
+}
+
+// Stats returns zero counters, as no traffic passes through memory.
+func (m *memory) Stats(context.Context) ([]*types.ServiceStats, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var stats []*types.ServiceStats
+	for _, s := range m.services {
+		svcStats := &types.ServiceStats{Key: proto.Clone(s.svc.Key).(*types.VirtualService_Key), Stats: &types.Stats{}}
+		for _, server := range s.servers {
+			svcStats.Servers = append(svcStats.Servers, &types.ServerStats{
+				Key:    proto.Clone(server.Key).(*types.RealServer_Key),
+				Weight: server.Config.Weight.Value,
+				Stats:  &types.Stats{},
+			})
+		}
+		stats = append(stats, svcStats)
+	}
+	return stats, nil
+}
+
+// Connections has none, as no traffic passes through memory.
+func (m *memory) Connections(context.Context, func(*types.Connection) error) error {
+	return nil
+}
+
+func (m *memory) SyncDaemons(context.Context) ([]*types.SyncDaemon, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var daemons []*types.SyncDaemon
+	for _, d := range m.daemons {
+		daemons = append(daemons, proto.Clone(d).(*types.SyncDaemon))
+	}
+	return daemons, nil
+}
+
+func (m *memory) StartSyncDaemon(_ context.Context, daemon *types.SyncDaemon) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if daemon.Role == types.SyncDaemon_UNSET_SYNC_ROLE || daemon.Interface == "" {
+		return syscall.EINVAL
+	}
+	for _, d := range m.daemons {
+		if d.Role == daemon.Role {
+			return syscall.EEXIST
+		}
+	}
+	m.daemons = append(m.daemons, proto.Clone(daemon).(*types.SyncDaemon))
+	return nil
+}
+
+func (m *memory) StopSyncDaemon(_ context.Context, role types.SyncDaemon_Role) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, d := range m.daemons {
+		if d.Role == role {
+			m.daemons = append(m.daemons[:i], m.daemons[i+1:]...)
+			return nil
+		}
+	}
+	return syscall.ESRCH
+}
@@ -0,0 +1,94 @@
+package ipvs
+
+import (
+	"context"
+	"syscall"
+
+	"github.com/golang/protobuf/ptypes/wrappers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sky-uk/merlin/types"
+)
+
+var _ = Describe("IPVS Memory", func() {
+	var (
+		mem    IPVS
+		svc    *types.VirtualService
+		server *types.RealServer
+		ctx    context.Context
+	)
+
+	BeforeEach(func() {
+		mem = NewMemory()
+		svc = &types.VirtualService{
+			Id:  "svc1",
+			Key: &types.VirtualService_Key{Ip: "10.10.10.10", Port: 555, Protocol: types.Protocol_TCP},
+			Config: &types.VirtualService_Config{
+				Scheduler: "sh",
+				Flags:     []string{"flag-1"},
+			},
+		}
+		server = &types.RealServer{
+			ServiceID: "svc1",
+			Key:       &types.RealServer_Key{Ip: "172.16.10.10", Port: 999},
+			Config: &types.RealServer_Config{
+				Weight:  &wrappers.UInt32Value{Value: 2},
+				Forward: types.ForwardMethod_MASQ,
+			},
+		}
+		ctx = context.Background()
+	})
+
+	It("should only keep the fields ipvs stores", func() {
+		Expect(mem.AddService(ctx, svc)).To(Succeed())
+		Expect(mem.AddServer(ctx, svc.Key, server)).To(Succeed())
+
+		svcs, err := mem.ListServices(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(svcs).To(HaveLen(1))
+		Expect(svcs[0].Id).To(BeEmpty())
+		Expect(svcs[0].Config.Scheduler).To(Equal("sh"))
+		servers, err := mem.ListServers(ctx, svc.Key)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(servers).To(HaveLen(1))
+		Expect(servers[0].ServiceID).To(BeEmpty())
+		Expect(servers[0].Config.Weight.Value).To(Equal(uint32(2)))
+	})
+
+	It("should fail like the kernel", func() {
+		Expect(mem.AddService(ctx, svc)).To(Succeed())
+
+		Expect(mem.AddService(ctx, svc)).To(Equal(syscall.EEXIST))
+		Expect(mem.UpdateServer(ctx, svc.Key, server)).To(Equal(syscall.ENOENT))
+		Expect(mem.DeleteService(ctx, &types.VirtualService_Key{Ip: "10.0.0.1"})).To(Equal(syscall.ESRCH))
+
+		server.Config.Forward = types.ForwardMethod_TUNNEL
+		server.Config.TunnelType = types.TunnelType_GUE
+		Expect(mem.AddServer(ctx, svc.Key, server)).To(Equal(syscall.EINVAL))
+	})
+
+	It("should delete services with their servers", func() {
+		Expect(mem.AddService(ctx, svc)).To(Succeed())
+		Expect(mem.AddServer(ctx, svc.Key, server)).To(Succeed())
+
+		Expect(mem.DeleteService(ctx, svc.Key)).To(Succeed())
+
+		svcs, _ := mem.ListServices(ctx)
+		Expect(svcs).To(BeEmpty())
+		_, err := mem.ListServers(ctx, svc.Key)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should run one sync daemon of each role", func() {
+		master := &types.SyncDaemon{Role: types.SyncDaemon_MASTER, Interface: "eth0", SyncId: 7}
+		Expect(mem.StartSyncDaemon(ctx, master)).To(Succeed())
+
+		Expect(mem.StartSyncDaemon(ctx, master)).To(Equal(syscall.EEXIST))
+		Expect(mem.StartSyncDaemon(ctx, &types.SyncDaemon{Role: types.SyncDaemon_BACKUP})).To(Equal(syscall.EINVAL))
+		Expect(mem.StopSyncDaemon(ctx, types.SyncDaemon_BACKUP)).To(Equal(syscall.ESRCH))
+		Expect(mem.SyncDaemons(ctx)).To(Equal([]*types.SyncDaemon{master}))
+
+		Expect(mem.StopSyncDaemon(ctx, types.SyncDaemon_MASTER)).To(Succeed())
+		Expect(mem.SyncDaemons(ctx)).To(BeEmpty())
+	})
+})
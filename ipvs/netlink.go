@@ -0,0 +1,220 @@
+package ipvs
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"github.com/docker/libnetwork/ipvs"
+)
+
+// Generic netlink values are found in genetlink.h, and IPVS values in ip_vs.h.
+const (
+	genlIDCtrl         = 0x10
+	genlHdrLen         = 4
+	ctrlCmdGetFamily   = 3
+	ctrlAttrFamilyID   = 1
+	ctrlAttrFamilyName = 2
+	nlaHdrLen          = 4
+	nlaTypeMask        = 0x3fff
+
+	ipvsGenlName        = "IPVS"
+	ipvsGenlVersion     = 1
+	ipvsCmdAttrService  = 1
+	ipvsSvcAttrAF       = 1
+	ipvsSvcAttrProtocol = 2
+	ipvsSvcAttrAddr     = 3
+	ipvsSvcAttrPort     = 4
+)
+
+// nativeEndian is the byte order of netlink headers and most attributes.
+var nativeEndian binary.ByteOrder = binary.LittleEndian
+
+func init() {
+	x := uint16(1)
+	if *(*byte)(unsafe.Pointer(&x)) == 0 {
+		nativeEndian = binary.BigEndian
+	}
+}
+
+// genlHandle sends the IPVS commands which libnetwork/ipvs doesn't support.
+type genlHandle interface {
+	Close()
+	NewDestination(*ipvs.Service, *ipvs.Destination, tunnel) error
+	UpdateDestination(*ipvs.Service, *ipvs.Destination, tunnel) error
+	// GetTunnels returns the tunnel of each destination of the service, by ip:port.
+	GetTunnels(*ipvs.Service) (map[string]tunnel, error)
+	NewDaemon(syncDaemon) error
+	DelDaemon(state uint32) error
+	GetDaemons() ([]syncDaemon, error)
+}
+
+// netlinkHandle is a genlHandle which talks generic netlink to IPVS directly.
+type netlinkHandle struct {
+	mu     sync.Mutex
+	fd     int
+	family uint16
+	seq    uint32
+}
+
+func newNetlinkHandle() (*netlinkHandle, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW|syscall.SOCK_CLOEXEC, syscall.NETLINK_GENERIC)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open netlink socket: %v", err)
+	}
+	if err := syscall.Bind(fd, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("unable to bind netlink socket: %v", err)
+	}
+	return &netlinkHandle{fd: fd}, nil
+}
+
+func (h *netlinkHandle) Close() {
+	syscall.Close(h.fd)
+}
+
+// request sends an IPVS command, resolving the IPVS family on first use so merlin starts without ip_vs loaded.
+func (h *netlinkHandle) request(cmd uint8, flags uint16, attrs ...[]byte) ([][]byte, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.family == 0 {
+		replies, err := h.exchange(genlIDCtrl, ctrlCmdGetFamily, 1, 0,
+			netlinkAttr(ctrlAttrFamilyName, append([]byte(ipvsGenlName), 0)))
+		if err != nil {
+			return nil, fmt.Errorf("unable to find the IPVS netlink family, is ip_vs loaded? %v", err)
+		}
+		for _, reply := range replies {
+			if id := parseAttrs(reply)[ctrlAttrFamilyID]; len(id) >= 2 {
+				h.family = nativeEndian.Uint16(id)
+			}
+		}
+		if h.family == 0 {
+			return nil, errors.New("unable to find the IPVS netlink family")
+		}
+	}
+	return h.exchange(h.family, cmd, ipvsGenlVersion, flags, attrs...)
+}
+
+// exchange sends a generic netlink message, and returns the payload of each reply until it's acked or done.
+func (h *netlinkHandle) exchange(family uint16, cmd, version uint8, flags uint16, attrs ...[]byte) ([][]byte,
+	error) {
+	h.seq++
+	msg := make([]byte, syscall.NLMSG_HDRLEN+genlHdrLen)
+	nativeEndian.PutUint16(msg[4:6], family)
+	nativeEndian.PutUint16(msg[6:8], syscall.NLM_F_REQUEST|flags)
+	nativeEndian.PutUint32(msg[8:12], h.seq)
+	msg[syscall.NLMSG_HDRLEN] = cmd
+	msg[syscall.NLMSG_HDRLEN+1] = version
+	for _, attr := range attrs {
+		msg = append(msg, attr...)
+	}
+	nativeEndian.PutUint32(msg[0:4], uint32(len(msg)))
+	if err := syscall.Sendto(h.fd, msg, 0, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}); err != nil {
+		return nil, err
+	}
+
+	var replies [][]byte
+	buf := make([]byte, 1<<16)
+	for {
+		n, _, err := syscall.Recvfrom(h.fd, buf, 0)
+		if err != nil {
+			return nil, err
+		}
+		msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range msgs {
+			if m.Header.Seq != h.seq {
+				continue
+			}
+			switch m.Header.Type {
+			case syscall.NLMSG_DONE:
+				return replies, nil
+			case syscall.NLMSG_ERROR:
+				if len(m.Data) < 4 {
+					return nil, errors.New("truncated netlink error")
+				}
+				if errno := int32(nativeEndian.Uint32(m.Data[:4])); errno != 0 {
+					return nil, syscall.Errno(-errno)
+				}
+				return replies, nil
+			}
+			if len(m.Data) >= genlHdrLen {
+				replies = append(replies, append([]byte(nil), m.Data[genlHdrLen:]...))
+			}
+			if m.Header.Flags&syscall.NLM_F_MULTI == 0 && flags&syscall.NLM_F_ACK == 0 {
+				return replies, nil
+			}
+		}
+	}
+}
+
+func serviceAttr(svc *ipvs.Service) []byte {
+	return netlinkAttr(ipvsCmdAttrService,
+		netlinkAttr(ipvsSvcAttrAF, nativeUint16(svc.AddressFamily)),
+		netlinkAttr(ipvsSvcAttrProtocol, nativeUint16(svc.Protocol)),
+		netlinkAttr(ipvsSvcAttrAddr, ipBytes(svc.Address)),
+		netlinkAttr(ipvsSvcAttrPort, bigUint16(svc.Port)),
+	)
+}
+
+// netlinkAttr returns an attribute holding data, or the attributes nested in it.
+func netlinkAttr(typ uint16, data ...[]byte) []byte {
+	attr := make([]byte, nlaHdrLen)
+	for _, d := range data {
+		attr = append(attr, d...)
+	}
+	nativeEndian.PutUint16(attr[0:2], uint16(len(attr)))
+	nativeEndian.PutUint16(attr[2:4], typ)
+	return append(attr, make([]byte, nlaAlign(len(attr))-len(attr))...)
+}
+
+func parseAttrs(b []byte) map[uint16][]byte {
+	attrs := make(map[uint16][]byte)
+	for len(b) >= nlaHdrLen {
+		l := int(nativeEndian.Uint16(b[0:2]))
+		if l < nlaHdrLen || l > len(b) {
+			break
+		}
+		attrs[nativeEndian.Uint16(b[2:4])&nlaTypeMask] = b[nlaHdrLen:l]
+		if nlaAlign(l) >= len(b) {
+			break
+		}
+		b = b[nlaAlign(l):]
+	}
+	return attrs
+}
+
+func nlaAlign(l int) int {
+	return (l + syscall.NLMSG_ALIGNTO - 1) &^ (syscall.NLMSG_ALIGNTO - 1)
+}
+
+func ipBytes(ip net.IP) []byte {
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4
+	}
+	return ip.To16()
+}
+
+func nativeUint16(v uint16) []byte {
+	b := make([]byte, 2)
+	nativeEndian.PutUint16(b, v)
+	return b
+}
+
+func nativeUint32(v uint32) []byte {
+	b := make([]byte, 4)
+	nativeEndian.PutUint32(b, v)
+	return b
+}
+
+func bigUint16(v uint16) []byte {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, v)
+	return b
+}
@@ -26,6 +26,13 @@ type IPVS interface {
 	UpdateServer(ctx context.Context, key *types.VirtualService_Key, server *types.RealServer) error
 	DeleteServer(ctx context.Context, key *types.VirtualService_Key, server *types.RealServer) error
 	ListServers(ctx context.Context, key *types.VirtualService_Key) ([]*types.RealServer, error)
+	Stats(ctx context.Context) ([]*types.ServiceStats, error)
+	// Connections calls fn with each connection in the connection table, stopping if it returns an error.
+	Connections(ctx context.Context, fn func(*types.Connection) error) error
+	// SyncDaemons returns the connection sync daemons running in the kernel.
+	SyncDaemons(ctx context.Context) ([]*types.SyncDaemon, error)
+	StartSyncDaemon(ctx context.Context, daemon *types.SyncDaemon) error
+	StopSyncDaemon(ctx context.Context, role types.SyncDaemon_Role) error
 }
 
 // ipvsHandle for libnetwork/ipvs.
@@ -43,21 +50,29 @@ type ipvsHandle interface {
 
 type shim struct {
 	handle ipvsHandle
+	genl   genlHandle
 }
 
-// New IPVS shim. This creates an underlying netlink socket. Call Close() to release the associated resources.
+// New IPVS shim. This creates underlying netlink sockets. Call Close() to release the associated resources.
 func New() (IPVS, error) {
 	h, err := ipvs.New("")
 	if err != nil {
 		return nil, fmt.Errorf("unable to init ipvs: %v", err)
 	}
+	g, err := newNetlinkHandle()
+	if err != nil {
+		h.Close()
+		return nil, fmt.Errorf("unable to init ipvs netlink: %v", err)
+	}
 	return &shim{
 		handle: h,
+		genl:   g,
 	}, nil
 }
 
 func (s *shim) Close() {
 	s.handle.Close()
+	s.genl.Close()
 }
 
 func createHandleServiceKey(key *types.VirtualService_Key) (*ipvs.Service, error) {
@@ -81,6 +96,9 @@ func createHandleService(svc *types.VirtualService) (*ipvs.Service, error) {
 	}
 	ipvsSvc.SchedName = svc.Config.Scheduler
 	ipvsSvc.Flags = toFlagBits(svc.Config.Flags)
+	if svc.Config.Ops {
+		ipvsSvc.Flags |= ipVsSvcFOnePacket
+	}
 	return ipvsSvc, nil
 }
 
@@ -144,6 +162,7 @@ func (s *shim) ListServices(ctx context.Context) ([]*types.VirtualService, error
 			Config: &types.VirtualService_Config{
 				Scheduler: hSvc.SchedName,
 				Flags:     fromFlagBits(hSvc.Flags),
+				Ops:       hSvc.Flags&ipVsSvcFOnePacket != 0,
 			},
 		}
 		svcs = append(svcs, svc)
@@ -187,25 +206,42 @@ func createHandleServiceKeyAndDestination(key *types.VirtualService_Key, server
 	return svc, dest, nil
 }
 
+// AddServer adds the destination with libnetwork/ipvs, unless it has a tunnel other than IPIP which libnetwork/ipvs
+// can't set.
 func (s *shim) AddServer(ctx context.Context, key *types.VirtualService_Key, server *types.RealServer) error {
 	svc, dest, err := createHandleServiceKeyAndDestination(key, server, true)
 	if err != nil {
 		return err
 	}
+	tun, err := toTunnel(server.Config)
+	if err != nil {
+		return err
+	}
 
 	_, err = performAsync(ctx, func() (interface{}, error) {
+		if tun != (tunnel{}) {
+			return nil, s.genl.NewDestination(svc, dest, tun)
+		}
 		return nil, s.handle.NewDestination(svc, dest)
 	})
 	return err
 }
 
+// UpdateServer updates the destination like AddServer. Updating it with libnetwork/ipvs resets its tunnel to IPIP.
 func (s *shim) UpdateServer(ctx context.Context, key *types.VirtualService_Key, server *types.RealServer) error {
 	svc, dest, err := createHandleServiceKeyAndDestination(key, server, true)
 	if err != nil {
 		return err
 	}
+	tun, err := toTunnel(server.Config)
+	if err != nil {
+		return err
+	}
 
 	_, err = performAsync(ctx, func() (interface{}, error) {
+		if tun != (tunnel{}) {
+			return nil, s.genl.UpdateDestination(svc, dest, tun)
+		}
 		return nil, s.handle.UpdateDestination(svc, dest)
 	})
 	return err
@@ -237,6 +273,21 @@ func (s *shim) ListServers(ctx context.Context, key *types.VirtualService_Key) (
 	}
 	destinations := val.([]*ipvs.Destination)
 
+	// libnetwork/ipvs doesn't report tunnels, so they're listed separately if there are any tunneled destinations
+	var tunnels map[string]tunnel
+	for _, dest := range destinations {
+		if dest.ConnectionFlags&ipvs.ConnectionFlagFwdMask == ipvs.ConnectionFlagTunnel {
+			val, err := performAsync(ctx, func() (interface{}, error) {
+				return s.genl.GetTunnels(svc)
+			})
+			if err != nil {
+				return nil, fmt.Errorf("unable to list tunnels: %v", err)
+			}
+			tunnels = val.(map[string]tunnel)
+			break
+		}
+	}
+
 	var servers []*types.RealServer
 	for _, dest := range destinations {
 		fwdBits := dest.ConnectionFlags & ipvs.ConnectionFlagFwdMask
@@ -254,12 +305,80 @@ func (s *shim) ListServers(ctx context.Context, key *types.VirtualService_Key) (
 				Forward: fwd,
 			},
 		}
+		if fwd == types.ForwardMethod_TUNNEL {
+			if err := fromTunnel(tunnels[tunnelKey(dest.Address, dest.Port)], server.Config); err != nil {
+				return nil, fmt.Errorf("unable to list backends, %v", err)
+			}
+		}
 		servers = append(servers, server)
 	}
 
 	return servers, nil
 }
 
+func (s *shim) Stats(ctx context.Context) ([]*types.ServiceStats, error) {
+	val, err := performAsync(ctx, func() (interface{}, error) {
+		return s.handle.GetServices()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list services: %v", err)
+	}
+	services := val.([]*ipvs.Service)
+
+	var stats []*types.ServiceStats
+	for _, hSvc := range services {
+		protocol, err := fromProtocolBits(hSvc.Protocol)
+		if err != nil {
+			return nil, err
+		}
+		svcStats := &types.ServiceStats{
+			Key: &types.VirtualService_Key{
+				Ip:       hSvc.Address.String(),
+				Port:     uint32(hSvc.Port),
+				Protocol: protocol,
+			},
+			Stats: fromHandleStats(hSvc.Stats),
+		}
+
+		val, err := performAsync(ctx, func() (interface{}, error) {
+			return s.handle.GetDestinations(hSvc)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list destinations of %s: %v", svcStats.Key.PrettyString(), err)
+		}
+		for _, dest := range val.([]*ipvs.Destination) {
+			svcStats.Servers = append(svcStats.Servers, &types.ServerStats{
+				Key: &types.RealServer_Key{
+					Ip:   dest.Address.String(),
+					Port: uint32(dest.Port),
+				},
+				Weight:              uint32(dest.Weight),
+				ActiveConnections:   uint32(dest.ActiveConnections),
+				InactiveConnections: uint32(dest.InactiveConnections),
+				Stats:               fromHandleStats(ipvs.SvcStats(dest.Stats)),
+			})
+		}
+		stats = append(stats, svcStats)
+	}
+
+	return stats, nil
+}
+
+func fromHandleStats(s ipvs.SvcStats) *types.Stats {
+	return &types.Stats{
+		Connections: uint64(s.Connections),
+		PacketsIn:   uint64(s.PacketsIn),
+		PacketsOut:  uint64(s.PacketsOut),
+		BytesIn:     s.BytesIn,
+		BytesOut:    s.BytesOut,
+		Cps:         uint64(s.CPS),
+		PpsIn:       uint64(s.PPSIn),
+		PpsOut:      uint64(s.PPSOut),
+		BpsIn:       uint64(s.BPSIn),
+		BpsOut:      uint64(s.BPSOut),
+	}
+}
+
 func performAsync(ctx context.Context, fn func() (interface{}, error)) (interface{}, error) {
 	c := make(chan struct {
 		v interface{}
@@ -29,6 +29,7 @@ var _ = Describe("IPVS Shim", func() {
 	var (
 		ipvsShim IPVS
 		hMock    *handleMock
+		gMock    *genlMock
 		svc      *types.VirtualService
 		hSvc     *ipvs.Service
 		hSvcKey  *ipvs.Service
@@ -40,7 +41,8 @@ var _ = Describe("IPVS Shim", func() {
 
 	BeforeEach(func() {
 		hMock = &handleMock{}
-		ipvsShim = &shim{handle: hMock}
+		gMock = &genlMock{}
+		ipvsShim = &shim{handle: hMock, genl: gMock}
 
 		// virtual service fixtures
 		svc = &types.VirtualService{
@@ -137,6 +139,19 @@ var _ = Describe("IPVS Shim", func() {
 			Expect(err).ToNot(HaveOccurred())
 			hMock.AssertExpectations(GinkgoT())
 		})
+
+		It("should set the one-packet scheduling flag", func() {
+			svc.Key.Protocol = types.Protocol_UDP
+			svc.Config.Ops = true
+			hSvc.Protocol = syscall.IPPROTO_UDP
+			hSvc.Flags |= ipVsSvcFOnePacket
+			hMock.On("NewService", hSvc).Return(nil)
+
+			err := ipvsShim.AddService(ctx, svc)
+
+			Expect(err).ToNot(HaveOccurred())
+			hMock.AssertExpectations(GinkgoT())
+		})
 	})
 
 	Describe("UpdateService", func() {
@@ -174,6 +189,17 @@ var _ = Describe("IPVS Shim", func() {
 			Expect(svcs).To(HaveLen(1))
 			Expect(svcs).To(ContainElement(svc))
 		})
+
+		It("should report one-packet scheduling", func() {
+			hSvc.Flags |= ipVsSvcFOnePacket | ipVsSvcFHashed
+			hMock.On("GetServices").Return([]*ipvs.Service{hSvc}, nil)
+
+			svcs, err := ipvsShim.ListServices(ctx)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(svcs[0].Config.Ops).To(BeTrue())
+			Expect(svcs[0].Config.Flags).To(Equal([]string{"flag-2", "flag-3"}))
+		})
 	})
 
 	Describe("AddServer", func() {
@@ -185,6 +211,22 @@ var _ = Describe("IPVS Shim", func() {
 			Expect(err).ToNot(HaveOccurred())
 			hMock.AssertExpectations(GinkgoT())
 		})
+
+		It("should add with the tunnel over netlink, which libipvs can't set", func() {
+			server.Config.Forward = types.ForwardMethod_TUNNEL
+			server.Config.TunnelType = types.TunnelType_GUE
+			server.Config.TunnelPort = 6080
+			server.Config.TunnelChecksum = types.TunnelChecksum_REMOTE_CHECKSUM
+			hDest.ConnectionFlags = ipvs.ConnectionFlagTunnel
+			gMock.On("NewDestination", hSvcKey, hDest,
+				tunnel{Type: ipVsConnFTunnelTypeGUE, Port: 6080, Flags: ipVsTunnelEncapFlagRemCsum}).Return(nil)
+
+			err := ipvsShim.AddServer(ctx, svc.Key, server)
+
+			Expect(err).ToNot(HaveOccurred())
+			gMock.AssertExpectations(GinkgoT())
+			hMock.AssertExpectations(GinkgoT())
+		})
 	})
 
 	Describe("UpdateServer", func() {
@@ -219,6 +261,95 @@ var _ = Describe("IPVS Shim", func() {
 			Expect(servers).To(HaveLen(1))
 			Expect(servers).To(ContainElement(server))
 		})
+
+		It("should report the tunnels of tunneled destinations", func() {
+			hDest.ConnectionFlags = ipvs.ConnectionFlagTunnel
+			hMock.On("GetDestinations", hSvcKey).Return([]*ipvs.Destination{hDest}, nil)
+			gMock.On("GetTunnels", hSvcKey).Return(map[string]tunnel{
+				"172.16.10.10:999": {Type: ipVsConnFTunnelTypeGUE, Port: 6080, Flags: ipVsTunnelEncapFlagCsum},
+			}, nil)
+
+			servers, err := ipvsShim.ListServers(ctx, svc.Key)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(servers).To(HaveLen(1))
+			Expect(servers[0].Config.Forward).To(Equal(types.ForwardMethod_TUNNEL))
+			Expect(servers[0].Config.TunnelType).To(Equal(types.TunnelType_GUE))
+			Expect(servers[0].Config.TunnelPort).To(Equal(uint32(6080)))
+			Expect(servers[0].Config.TunnelChecksum).To(Equal(types.TunnelChecksum_CHECKSUM))
+		})
+	})
+
+	Describe("Stats", func() {
+		It("should return the counters of services and destinations in libipvs", func() {
+			hSvc.Stats = ipvs.SvcStats{Connections: 10, PacketsIn: 100, BytesIn: 1000, CPS: 1}
+			hDest.ActiveConnections = 3
+			hDest.InactiveConnections = 4
+			hDest.Stats = ipvs.DstStats{Connections: 5, PacketsOut: 50, BytesOut: 500}
+			hMock.On("GetServices").Return([]*ipvs.Service{hSvc}, nil)
+			hMock.On("GetDestinations", hSvc).Return([]*ipvs.Destination{hDest}, nil)
+
+			stats, err := ipvsShim.Stats(ctx)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stats).To(Equal([]*types.ServiceStats{{
+				Key:   svc.Key,
+				Stats: &types.Stats{Connections: 10, PacketsIn: 100, BytesIn: 1000, Cps: 1},
+				Servers: []*types.ServerStats{{
+					Key:                 server.Key,
+					Weight:              2,
+					ActiveConnections:   3,
+					InactiveConnections: 4,
+					Stats:               &types.Stats{Connections: 5, PacketsOut: 50, BytesOut: 500},
+				}},
+			}}))
+		})
+	})
+
+	Describe("SyncDaemons", func() {
+		It("should list the sync daemons over netlink", func() {
+			gMock.On("GetDaemons").Return([]syncDaemon{
+				{State: ipVsStateMaster, Interface: "eth0", SyncID: 7},
+				{State: ipVsStateBackup, Interface: "eth1", SyncID: 8},
+			}, nil)
+
+			daemons, err := ipvsShim.SyncDaemons(ctx)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(daemons).To(Equal([]*types.SyncDaemon{
+				{Role: types.SyncDaemon_MASTER, Interface: "eth0", SyncId: 7},
+				{Role: types.SyncDaemon_BACKUP, Interface: "eth1", SyncId: 8},
+			}))
+		})
+	})
+
+	Describe("StartSyncDaemon", func() {
+		It("should start the sync daemon over netlink", func() {
+			gMock.On("NewDaemon", syncDaemon{State: ipVsStateBackup, Interface: "eth0", SyncID: 7}).Return(nil)
+
+			err := ipvsShim.StartSyncDaemon(ctx,
+				&types.SyncDaemon{Role: types.SyncDaemon_BACKUP, Interface: "eth0", SyncId: 7})
+
+			Expect(err).ToNot(HaveOccurred())
+			gMock.AssertExpectations(GinkgoT())
+		})
+
+		It("should reject an unset role", func() {
+			err := ipvsShim.StartSyncDaemon(ctx, &types.SyncDaemon{Interface: "eth0"})
+
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("StopSyncDaemon", func() {
+		It("should stop the sync daemon over netlink", func() {
+			gMock.On("DelDaemon", uint32(ipVsStateMaster)).Return(nil)
+
+			err := ipvsShim.StopSyncDaemon(ctx, types.SyncDaemon_MASTER)
+
+			Expect(err).ToNot(HaveOccurred())
+			gMock.AssertExpectations(GinkgoT())
+		})
 	})
 
 	DescribeTable("Flagbits Conversion", func(flagbits int, flags []string) {
@@ -283,3 +414,41 @@ func (m *handleMock) DelDestination(s *ipvs.Service, d *ipvs.Destination) error
 	args := m.Called(s, d)
 	return args.Error(0)
 }
+
+type genlMock struct {
+	mock.Mock
+}
+
+func (m *genlMock) Close() {
+	m.Called()
+}
+
+func (m *genlMock) NewDestination(s *ipvs.Service, d *ipvs.Destination, t tunnel) error {
+	args := m.Called(s, d, t)
+	return args.Error(0)
+}
+
+func (m *genlMock) UpdateDestination(s *ipvs.Service, d *ipvs.Destination, t tunnel) error {
+	args := m.Called(s, d, t)
+	return args.Error(0)
+}
+
+func (m *genlMock) GetTunnels(s *ipvs.Service) (map[string]tunnel, error) {
+	args := m.Called(s)
+	return args.Get(0).(map[string]tunnel), args.Error(1)
+}
+
+func (m *genlMock) NewDaemon(d syncDaemon) error {
+	args := m.Called(d)
+	return args.Error(0)
+}
+
+func (m *genlMock) DelDaemon(state uint32) error {
+	args := m.Called(state)
+	return args.Error(0)
+}
+
+func (m *genlMock) GetDaemons() ([]syncDaemon, error) {
+	args := m.Called()
+	return args.Get(0).([]syncDaemon), args.Error(1)
+}
@@ -0,0 +1,125 @@
+package ipvs
+
+import (
+	"context"
+	"fmt"
+	"syscall"
+
+	"github.com/sky-uk/merlin/types"
+)
+
+// Sync daemon values are found in ip_vs.h.
+const (
+	ipvsCmdNewDaemon       = 9
+	ipvsCmdDelDaemon       = 10
+	ipvsCmdGetDaemon       = 11
+	ipvsCmdAttrDaemon      = 3
+	ipvsDaemonAttrState    = 1
+	ipvsDaemonAttrMcastIfn = 2
+	ipvsDaemonAttrSyncID   = 3
+
+	ipVsStateMaster = 1
+	ipVsStateBackup = 2
+)
+
+var (
+	syncStates = map[types.SyncDaemon_Role]uint32{
+		types.SyncDaemon_MASTER: ipVsStateMaster,
+		types.SyncDaemon_BACKUP: ipVsStateBackup,
+	}
+	syncStatesInverted = map[uint32]types.SyncDaemon_Role{
+		ipVsStateMaster: types.SyncDaemon_MASTER,
+		ipVsStateBackup: types.SyncDaemon_BACKUP,
+	}
+)
+
+// syncDaemon of the kernel, which runs a thread for each state.
+type syncDaemon struct {
+	State     uint32
+	Interface string
+	SyncID    uint32
+}
+
+func (h *netlinkHandle) NewDaemon(d syncDaemon) error {
+	_, err := h.request(ipvsCmdNewDaemon, syscall.NLM_F_ACK, netlinkAttr(ipvsCmdAttrDaemon,
+		netlinkAttr(ipvsDaemonAttrState, nativeUint32(d.State)),
+		netlinkAttr(ipvsDaemonAttrMcastIfn, append([]byte(d.Interface), 0)),
+		netlinkAttr(ipvsDaemonAttrSyncID, nativeUint32(d.SyncID)),
+	))
+	return err
+}
+
+func (h *netlinkHandle) DelDaemon(state uint32) error {
+	_, err := h.request(ipvsCmdDelDaemon, syscall.NLM_F_ACK, netlinkAttr(ipvsCmdAttrDaemon,
+		netlinkAttr(ipvsDaemonAttrState, nativeUint32(state)),
+	))
+	return err
+}
+
+func (h *netlinkHandle) GetDaemons() ([]syncDaemon, error) {
+	replies, err := h.request(ipvsCmdGetDaemon, syscall.NLM_F_DUMP)
+	if err != nil {
+		return nil, err
+	}
+	var daemons []syncDaemon
+	for _, reply := range replies {
+		daemons = append(daemons, parseDaemon(parseAttrs(parseAttrs(reply)[ipvsCmdAttrDaemon])))
+	}
+	return daemons, nil
+}
+
+func parseDaemon(attrs map[uint16][]byte) syncDaemon {
+	var d syncDaemon
+	if b := attrs[ipvsDaemonAttrState]; len(b) >= 4 {
+		d.State = nativeEndian.Uint32(b)
+	}
+	ifn := attrs[ipvsDaemonAttrMcastIfn]
+	for len(ifn) > 0 && ifn[len(ifn)-1] == 0 {
+		ifn = ifn[:len(ifn)-1]
+	}
+	d.Interface = string(ifn)
+	if b := attrs[ipvsDaemonAttrSyncID]; len(b) >= 4 {
+		d.SyncID = nativeEndian.Uint32(b)
+	}
+	return d
+}
+
+func (s *shim) SyncDaemons(ctx context.Context) ([]*types.SyncDaemon, error) {
+	val, err := performAsync(ctx, func() (interface{}, error) {
+		return s.genl.GetDaemons()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sync daemons: %v", err)
+	}
+	var daemons []*types.SyncDaemon
+	for _, d := range val.([]syncDaemon) {
+		role, ok := syncStatesInverted[d.State]
+		if !ok {
+			return nil, fmt.Errorf("unexpected sync daemon state %d", d.State)
+		}
+		daemons = append(daemons, &types.SyncDaemon{Role: role, Interface: d.Interface, SyncId: d.SyncID})
+	}
+	return daemons, nil
+}
+
+func (s *shim) StartSyncDaemon(ctx context.Context, daemon *types.SyncDaemon) error {
+	state, ok := syncStates[daemon.Role]
+	if !ok {
+		return fmt.Errorf("invalid sync daemon role %q", daemon.Role)
+	}
+	_, err := performAsync(ctx, func() (interface{}, error) {
+		return nil, s.genl.NewDaemon(syncDaemon{State: state, Interface: daemon.Interface, SyncID: daemon.SyncId})
+	})
+	return err
+}
+
+func (s *shim) StopSyncDaemon(ctx context.Context, role types.SyncDaemon_Role) error {
+	state, ok := syncStates[role]
+	if !ok {
+		return fmt.Errorf("invalid sync daemon role %q", role)
+	}
+	_, err := performAsync(ctx, func() (interface{}, error) {
+		return nil, s.genl.DelDaemon(state)
+	})
+	return err
+}
@@ -0,0 +1,105 @@
+package ipvs
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"strconv"
+	"syscall"
+
+	"github.com/docker/libnetwork/ipvs"
+)
+
+// Destination values are found in ip_vs.h. The tunnel attributes were added in kernel 5.2, and earlier kernels
+// ignore them.
+const (
+	ipvsCmdNewDest         = 5
+	ipvsCmdSetDest         = 6
+	ipvsCmdGetDest         = 8
+	ipvsCmdAttrDest        = 2
+	ipvsDestAttrAddr       = 1
+	ipvsDestAttrPort       = 2
+	ipvsDestAttrFwdMethod  = 3
+	ipvsDestAttrWeight     = 4
+	ipvsDestAttrUThresh    = 5
+	ipvsDestAttrLThresh    = 6
+	ipvsDestAttrAddrFamily = 11
+	ipvsDestAttrTunType    = 13
+	ipvsDestAttrTunPort    = 14
+	ipvsDestAttrTunFlags   = 15
+)
+
+// tunnel encapsulation of a destination.
+type tunnel struct {
+	Type  uint8
+	Port  uint16
+	Flags uint16
+}
+
+func (h *netlinkHandle) NewDestination(svc *ipvs.Service, dest *ipvs.Destination, tun tunnel) error {
+	_, err := h.request(ipvsCmdNewDest, syscall.NLM_F_ACK, serviceAttr(svc), destinationAttr(dest, tun))
+	return err
+}
+
+func (h *netlinkHandle) UpdateDestination(svc *ipvs.Service, dest *ipvs.Destination, tun tunnel) error {
+	_, err := h.request(ipvsCmdSetDest, syscall.NLM_F_ACK, serviceAttr(svc), destinationAttr(dest, tun))
+	return err
+}
+
+func (h *netlinkHandle) GetTunnels(svc *ipvs.Service) (map[string]tunnel, error) {
+	replies, err := h.request(ipvsCmdGetDest, syscall.NLM_F_DUMP, serviceAttr(svc))
+	if err != nil {
+		return nil, err
+	}
+	tunnels := make(map[string]tunnel)
+	for _, reply := range replies {
+		key, tun, err := parseDestination(parseAttrs(parseAttrs(reply)[ipvsCmdAttrDest]))
+		if err != nil {
+			return nil, err
+		}
+		tunnels[key] = tun
+	}
+	return tunnels, nil
+}
+
+func destinationAttr(dest *ipvs.Destination, tun tunnel) []byte {
+	return netlinkAttr(ipvsCmdAttrDest,
+		netlinkAttr(ipvsDestAttrAddrFamily, nativeUint16(dest.AddressFamily)),
+		netlinkAttr(ipvsDestAttrAddr, ipBytes(dest.Address)),
+		netlinkAttr(ipvsDestAttrPort, bigUint16(dest.Port)),
+		netlinkAttr(ipvsDestAttrFwdMethod, nativeUint32(dest.ConnectionFlags&ipvs.ConnectionFlagFwdMask)),
+		netlinkAttr(ipvsDestAttrWeight, nativeUint32(uint32(dest.Weight))),
+		netlinkAttr(ipvsDestAttrUThresh, nativeUint32(dest.UpperThreshold)),
+		netlinkAttr(ipvsDestAttrLThresh, nativeUint32(dest.LowerThreshold)),
+		netlinkAttr(ipvsDestAttrTunType, []byte{tun.Type}),
+		netlinkAttr(ipvsDestAttrTunPort, bigUint16(tun.Port)),
+		netlinkAttr(ipvsDestAttrTunFlags, nativeUint16(tun.Flags)),
+	)
+}
+
+// parseDestination returns the ip:port and tunnel of a destination. The tunnel is zero on kernels without one.
+func parseDestination(attrs map[uint16][]byte) (string, tunnel, error) {
+	addr, port := attrs[ipvsDestAttrAddr], attrs[ipvsDestAttrPort]
+	if len(addr) < net.IPv4len || len(port) < 2 {
+		return "", tunnel{}, errors.New("destination without an address")
+	}
+	ip := net.IP(addr[:net.IPv4len])
+	if family := attrs[ipvsDestAttrAddrFamily]; len(family) >= 2 && nativeEndian.Uint16(family) == syscall.AF_INET6 {
+		ip = net.IP(addr[:net.IPv6len])
+	}
+	var tun tunnel
+	if b := attrs[ipvsDestAttrTunType]; len(b) >= 1 {
+		tun.Type = b[0]
+	}
+	if b := attrs[ipvsDestAttrTunPort]; len(b) >= 2 {
+		tun.Port = binary.BigEndian.Uint16(b)
+	}
+	if b := attrs[ipvsDestAttrTunFlags]; len(b) >= 2 {
+		tun.Flags = nativeEndian.Uint16(b)
+	}
+	return tunnelKey(ip, binary.BigEndian.Uint16(port)), tun, nil
+}
+
+func tunnelKey(ip net.IP, port uint16) string {
+	return net.JoinHostPort(ip.String(), strconv.Itoa(int(port)))
+}
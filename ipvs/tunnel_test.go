@@ -0,0 +1,46 @@
+package ipvs
+
+import (
+	"net"
+	"syscall"
+
+	"github.com/docker/libnetwork/ipvs"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Netlink Tunnels", func() {
+	It("should encode destinations with their tunnel so they parse back", func() {
+		dest := &ipvs.Destination{
+			Address:         net.ParseIP("172.16.10.10"),
+			Port:            999,
+			AddressFamily:   syscall.AF_INET,
+			ConnectionFlags: ipvs.ConnectionFlagTunnel,
+			Weight:          2,
+		}
+		tun := tunnel{Type: ipVsConnFTunnelTypeGUE, Port: 6080, Flags: ipVsTunnelEncapFlagCsum}
+
+		attrs := parseAttrs(destinationAttr(dest, tun))
+		key, parsed, err := parseDestination(parseAttrs(attrs[ipvsCmdAttrDest]))
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(key).To(Equal("172.16.10.10:999"))
+		Expect(parsed).To(Equal(tun))
+		Expect(parseAttrs(attrs[ipvsCmdAttrDest])[ipvsDestAttrTunPort]).To(Equal([]byte{0x17, 0xc0}))
+	})
+
+	It("should parse destinations without a tunnel as IPIP", func() {
+		attrs := parseAttrs(append(netlinkAttr(ipvsDestAttrAddr, net.ParseIP("172.16.10.10").To4()),
+			netlinkAttr(ipvsDestAttrPort, bigUint16(999))...))
+
+		key, parsed, err := parseDestination(attrs)
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(key).To(Equal("172.16.10.10:999"))
+		Expect(parsed).To(Equal(tunnel{}))
+	})
+
+	It("should pad attributes to 4 bytes", func() {
+		Expect(netlinkAttr(ipvsDestAttrTunType, []byte{1})).To(HaveLen(8))
+	})
+})
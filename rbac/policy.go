@@ -0,0 +1,123 @@
+// Package rbac authorizes API clients to read and write services, so teams sharing a merlin cluster can be
+// restricted to their own services.
+package rbac
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Operation a client performs on services.
+type Operation string
+
+const (
+	// Read lists and describes services.
+	Read Operation = "read"
+	// Write changes services, and everything else in the store.
+	Write Operation = "write"
+)
+
+// AnyClient matches every client in a rule, including those which didn't authenticate.
+const AnyClient = "*"
+
+// Policy allows clients to perform operations on services. Anything not allowed by one of its rules is denied.
+type Policy struct {
+	Rules []*Rule `yaml:"rules"`
+}
+
+// Rule allows its clients to perform its operations on its services.
+type Rule struct {
+	// Clients are the names of API tokens, or the common names of TLS client certificates, the rule applies to.
+	Clients []string `yaml:"clients"`
+	// Operations the clients can perform.
+	Operations []Operation `yaml:"operations"`
+	// Services are the prefixes of the service IDs the clients can perform the operations on, every service if
+	// empty.
+	Services []string `yaml:"services,omitempty"`
+}
+
+// LoadPolicy reads a policy from a yaml file of the form:
+//
+//	rules:
+//	- clients: [deploy-payments, payments.example.com]
+//	  operations: [read, write]
+//	  services: [payments-]
+//	- clients: ["*"]
+//	  operations: [read]
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := ParsePolicy(data)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %v", path, err)
+	}
+	return policy, nil
+}
+
+// ParsePolicy parses a policy in the format read by LoadPolicy.
+func ParsePolicy(data []byte) (*Policy, error) {
+	policy := &Policy{}
+	if err := yaml.UnmarshalStrict(data, policy); err != nil {
+		return nil, err
+	}
+	for i, r := range policy.Rules {
+		if len(r.Clients) == 0 {
+			return nil, fmt.Errorf("rule %d has no clients", i+1)
+		}
+		if len(r.Operations) == 0 {
+			return nil, fmt.Errorf("rule %d has no operations", i+1)
+		}
+		for _, op := range r.Operations {
+			if op != Read && op != Write {
+				return nil, fmt.Errorf("rule %d has unknown operation %q, must be read or write", i+1, op)
+			}
+		}
+	}
+	return policy, nil
+}
+
+// Allowed returns true if the client can perform op on the service, or on every service if serviceID is empty.
+func (p *Policy) Allowed(client string, op Operation, serviceID string) bool {
+	for _, r := range p.Rules {
+		if r.matches(client, op) && r.covers(serviceID) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rule) matches(client string, op Operation) bool {
+	clientMatches := false
+	for _, c := range r.Clients {
+		if c == AnyClient || (c == client && client != "") {
+			clientMatches = true
+		}
+	}
+	for _, o := range r.Operations {
+		if clientMatches && o == op {
+			return true
+		}
+	}
+	return false
+}
+
+// covers returns true if the rule applies to the service, or to every service if serviceID is empty.
+func (r *Rule) covers(serviceID string) bool {
+	if len(r.Services) == 0 {
+		return true
+	}
+	if serviceID == "" {
+		return false
+	}
+	for _, prefix := range r.Services {
+		if strings.HasPrefix(serviceID, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package rbac
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestRBAC(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "RBAC Suite")
+}
+
+var _ = Describe("Policy", func() {
+	const policyYAML = `
+rules:
+- clients: [payments]
+  operations: [read, write]
+  services: [payments-, checkout]
+- clients: [ops]
+  operations: [write]
+- clients: ["*"]
+  operations: [read]
+  services: [public-]
+`
+	var policy *Policy
+
+	BeforeEach(func() {
+		var err error
+		policy, err = ParsePolicy([]byte(policyYAML))
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should allow clients their operations on services with the rule's prefixes", func() {
+		Expect(policy.Allowed("payments", Write, "payments-web")).To(BeTrue())
+		Expect(policy.Allowed("payments", Read, "checkout-api")).To(BeTrue())
+		Expect(policy.Allowed("payments", Write, "search-web")).To(BeFalse())
+		Expect(policy.Allowed("search", Write, "payments-web")).To(BeFalse())
+	})
+
+	It("should only allow operations on every service by rules without prefixes", func() {
+		Expect(policy.Allowed("ops", Write, "")).To(BeTrue())
+		Expect(policy.Allowed("ops", Read, "payments-web")).To(BeFalse(), "write doesn't imply read")
+		Expect(policy.Allowed("payments", Read, "")).To(BeFalse())
+	})
+
+	It("should apply rules for any client to anonymous clients too", func() {
+		Expect(policy.Allowed("", Read, "public-web")).To(BeTrue())
+		Expect(policy.Allowed("payments", Read, "public-web")).To(BeTrue())
+		Expect(policy.Allowed("", Read, "payments-web")).To(BeFalse())
+	})
+
+	It("should refuse unknown operations", func() {
+		_, err := ParsePolicy([]byte("rules:\n- clients: [ops]\n  operations: [delete]\n"))
+		Expect(err).To(MatchError(ContainSubstring(`unknown operation "delete"`)))
+	})
+
+	It("should refuse rules without clients", func() {
+		_, err := ParsePolicy([]byte("rules:\n- operations: [read]\n"))
+		Expect(err).To(MatchError(ContainSubstring("rule 1 has no clients")))
+	})
+})
@@ -0,0 +1,129 @@
+package reconciler
+
+import (
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/sky-uk/merlin/types"
+)
+
+// Dump is the state of the reconciler, for debugging.
+type Dump struct {
+	// Desired is the store state read by the last reconcile, with the weight of unhealthy servers set to 0.
+	Desired *types.Snapshot
+	// Actual is the state of IPVS. Services have the ID of the matching desired service, or none if unmanaged.
+	Actual *types.Snapshot
+	// Diff is the changes the next reconcile would make to IPVS, if the store is unchanged.
+	Diff []*types.Change
+}
+
+func (r *reconciler) Dump() (*Dump, error) {
+	r.mu.Lock()
+	desired := proto.Clone(r.desired).(*types.Snapshot)
+	r.mu.Unlock()
+	for _, server := range desired.Servers {
+		if r.checker.IsDown(server.ServiceID, server.Key) {
+			server.Config.Weight = &wrappers.UInt32Value{Value: 0}
+		}
+	}
+
+	services, err := r.listIPVSServices()
+	if err != nil {
+		return nil, fmt.Errorf("unable to list ipvs services: %v", err)
+	}
+	actual := &types.Snapshot{}
+	for _, svc := range services {
+		svc.NormalizeFlags()
+		for _, desiredService := range desired.Services {
+			if proto.Equal(svc.Key, desiredService.Key) {
+				svc.Id = desiredService.Id
+				break
+			}
+		}
+		actual.Services = append(actual.Services, svc)
+		if svc.Id == "" {
+			continue
+		}
+
+		servers, err := r.listIPVSServers(svc.Key)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list ipvs servers of %s: %v", svc.Key.PrettyString(), err)
+		}
+		for _, server := range servers {
+			server.ServiceID = svc.Id
+		}
+		actual.Servers = append(actual.Servers, servers...)
+	}
+
+	return &Dump{Desired: desired, Actual: actual, Diff: diff(desired, actual)}, nil
+}
+
+// diff returns the changes to make actual match desired, in the order reconcile makes them. The servers of
+// services which aren't desired are deleted with the service, so aren't included.
+func diff(desired, actual *types.Snapshot) []*types.Change {
+	serversOf := func(snapshot *types.Snapshot, serviceID string) []*types.RealServer {
+		var servers []*types.RealServer
+		for _, server := range snapshot.Servers {
+			if server.ServiceID == serviceID {
+				servers = append(servers, server)
+			}
+		}
+		return servers
+	}
+
+	var changes []*types.Change
+	for _, desiredService := range desired.Services {
+		var match *types.VirtualService
+		for _, svc := range actual.Services {
+			if proto.Equal(desiredService.Key, svc.Key) {
+				match = svc
+				break
+			}
+		}
+		if match == nil {
+			changes = append(changes, &types.Change{Action: types.Change_CREATE, Service: desiredService})
+		} else if !proto.Equal(desiredService.Config, match.Config) {
+			changes = append(changes, &types.Change{Action: types.Change_UPDATE, Service: desiredService})
+		}
+
+		var actualServers []*types.RealServer
+		if match != nil {
+			actualServers = serversOf(actual, match.Id)
+		}
+		for _, desiredServer := range serversOf(desired, desiredService.Id) {
+			var serverMatch *types.RealServer
+			for _, server := range actualServers {
+				if proto.Equal(desiredServer.Key, server.Key) {
+					serverMatch = server
+					break
+				}
+			}
+			if serverMatch == nil {
+				changes = append(changes, &types.Change{Action: types.Change_CREATE, Server: desiredServer})
+			} else if !proto.Equal(desiredServer.Config, serverMatch.Config) {
+				changes = append(changes, &types.Change{Action: types.Change_UPDATE, Server: desiredServer})
+			}
+		}
+		for _, server := range actualServers {
+			var found bool
+			for _, desiredServer := range serversOf(desired, desiredService.Id) {
+				found = found || proto.Equal(server.Key, desiredServer.Key)
+			}
+			if !found {
+				changes = append(changes, &types.Change{Action: types.Change_DELETE, Server: server})
+			}
+		}
+	}
+
+	for _, svc := range actual.Services {
+		var found bool
+		for _, desiredService := range desired.Services {
+			found = found || proto.Equal(svc.Key, desiredService.Key)
+		}
+		if !found {
+			changes = append(changes, &types.Change{Action: types.Change_DELETE, Service: svc})
+		}
+	}
+	return changes
+}
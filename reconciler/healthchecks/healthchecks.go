@@ -6,6 +6,7 @@ import (
 
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/url"
 
@@ -13,6 +14,7 @@ import (
 
 	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/ptypes"
+	"github.com/prometheus/client_golang/prometheus"
 	log "github.com/sirupsen/logrus"
 	"github.com/sky-uk/merlin/types"
 )
@@ -34,6 +36,8 @@ type TransitionFunc func(state ServerStatus)
 type Checker interface {
 	// IsDown returns true if the server is down.
 	IsDown(serviceID string, key *types.RealServer_Key) bool
+	// Health returns the health of the server, or UNSET_HEALTH if it has no health check.
+	Health(serviceID string, key *types.RealServer_Key) types.Health
 	// SetHealthCheck on the given server, replacing any existing health check.
 	SetHealthCheck(serviceID string, key *types.RealServer_Key, check *types.RealServer_HealthCheck,
 		fn TransitionFunc) error
@@ -41,6 +45,9 @@ type Checker interface {
 	RemHealthCheck(serviceID string, key *types.RealServer_Key)
 	// Stop all health checks. Use at shutdown.
 	Stop()
+	// Collector exports metrics of the health checks: how many ran and failed, the servers which changed health,
+	// and the healthy servers of each service.
+	prometheus.Collector
 }
 
 type checkKey struct {
@@ -49,7 +56,8 @@ type checkKey struct {
 }
 
 type checker struct {
-	checks map[checkKey]*check
+	checks  map[checkKey]*check
+	metrics *metrics
 	sync.Mutex
 }
 
@@ -58,6 +66,7 @@ type check struct {
 	serverIP    string
 	healthCheck *types.RealServer_HealthCheck
 	stopCh      chan struct{}
+	metrics     *metrics
 
 	// mutable state
 	state *checkState
@@ -79,7 +88,8 @@ type checkState struct {
 // New creates a new checker.
 func New() Checker {
 	return &checker{
-		checks: make(map[checkKey]*check),
+		checks:  make(map[checkKey]*check),
+		metrics: newMetrics(),
 	}
 }
 
@@ -101,6 +111,27 @@ func (c *checker) IsDown(serviceID string, key *types.RealServer_Key) bool {
 	return check.state.status == ServerDown
 }
 
+func (c *checker) Health(serviceID string, key *types.RealServer_Key) types.Health {
+	c.Lock()
+	defer c.Unlock()
+
+	for checkKey, check := range c.checks {
+		if checkKey.serviceID != serviceID || !proto.Equal(checkKey.key, key) {
+			continue
+		}
+		if check.healthCheck.Endpoint.GetValue() == "" {
+			return types.Health_UNCHECKED
+		}
+		check.state.Lock()
+		defer check.state.Unlock()
+		if check.state.status == ServerDown {
+			return types.Health_DOWN
+		}
+		return types.Health_UP
+	}
+	return types.Health_UNSET_HEALTH
+}
+
 func validateHealthCheck(check *types.RealServer_HealthCheck) error {
 	if check == nil {
 		return errors.New("check should be non-nil")
@@ -113,7 +144,7 @@ func validateHealthCheck(check *types.RealServer_HealthCheck) error {
 		return fmt.Errorf("endpoint not a URL: %v", err)
 	}
 	switch u.Scheme {
-	case "http":
+	case "http", "tcp", "udp":
 		// supported
 	default:
 		return fmt.Errorf("unsupported scheme %s", u.Scheme)
@@ -168,6 +199,7 @@ func (c *checker) SetHealthCheck(serviceID string, key *types.RealServer_Key, he
 		healthCheck: healthCheck,
 		state:       state,
 		stopCh:      make(chan struct{}),
+		metrics:     c.metrics,
 	}
 
 	c.checks[checkKey] = check
@@ -236,24 +268,45 @@ func (c *check) performHealthCheck() {
 	if err != nil {
 		panic(err)
 	}
-	if checkURL.Scheme != "http" {
+	timeout, err := ptypes.Duration(c.healthCheck.Timeout)
+	if err != nil {
+		panic(err)
+	}
+	addr := net.JoinHostPort(c.serverIP, checkURL.Port())
+
+	c.metrics.checks.Inc()
+	var reason string
+	switch checkURL.Scheme {
+	case "http":
+		reason = c.checkHTTP(addr, checkURL.Path, timeout)
+	case "tcp":
+		reason = checkTCP(addr, timeout)
+	case "udp":
+		reason = checkUDP(addr, timeout)
+	default:
 		panic("unsupported health check scheme " + checkURL.Scheme)
 	}
+	if reason != "" {
+		c.metrics.failures.WithLabelValues(reason).Inc()
+		c.markServerDown()
+		return
+	}
+	c.markServerUp()
+}
 
+// checkHTTP gets the path from the server, returning the reason it failed or "" if it responded with the expected
+// status.
+func (c *check) checkHTTP(addr, path string, timeout time.Duration) string {
 	// Create a custom transport so we don't reuse prior connections - which might hide connectivity problems.
 	tr := &http.Transport{
 		DisableKeepAlives: true,
 	}
-	timeout, err := ptypes.Duration(c.healthCheck.Timeout)
-	if err != nil {
-		panic(err)
-	}
 	client := http.Client{
 		Transport: tr,
 		Timeout:   timeout,
 	}
 
-	serverURL, err := url.Parse(fmt.Sprintf("http://%s:%s%s", c.serverIP, checkURL.Port(), checkURL.Path))
+	serverURL, err := url.Parse(fmt.Sprintf("http://%s%s", addr, path))
 	if err != nil {
 		panic(err)
 	}
@@ -261,33 +314,76 @@ func (c *check) performHealthCheck() {
 	resp, err := client.Get(serverURL.String())
 	if err != nil {
 		log.Infof("%s inaccessible: %v", serverURL, err)
-		c.markServerDown()
-		return
+		return failureReason(err)
 	}
 	defer resp.Body.Close()
-	if resp.StatusCode < 200 || 300 <= resp.StatusCode {
+	expected := resp.StatusCode >= 200 && resp.StatusCode < 300
+	if c.healthCheck.ExpectedStatus != 0 {
+		expected = uint32(resp.StatusCode) == c.healthCheck.ExpectedStatus
+	}
+	if !expected {
 		body, _ := ioutil.ReadAll(resp.Body)
 		log.Infof("%s returned %d: %s", serverURL, resp.StatusCode, string(body))
-		c.markServerDown()
-		return
+		return failureStatus
 	}
-	c.markServerUp()
+	return ""
+}
+
+// checkTCP connects to the server, returning the reason it failed or "" if it accepted the connection.
+func checkTCP(addr string, timeout time.Duration) string {
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		log.Infof("tcp://%s inaccessible: %v", addr, err)
+		return failureReason(err)
+	}
+	conn.Close()
+	return ""
+}
+
+// checkUDP sends an empty datagram to the server, returning the reason it failed if the port is unreachable, or ""
+// if it replied or nothing came back before the timeout. Like other UDP checks, a server which silently drops
+// datagrams can't be told apart from one which ignores them.
+func checkUDP(addr string, timeout time.Duration) string {
+	conn, err := net.DialTimeout("udp", addr, timeout)
+	if err != nil {
+		log.Infof("udp://%s inaccessible: %v", addr, err)
+		return failureReason(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+	if _, err := conn.Write(nil); err != nil {
+		log.Infof("udp://%s inaccessible: %v", addr, err)
+		return failureReason(err)
+	}
+	if _, err := conn.Read(make([]byte, 1)); err != nil {
+		if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+			return ""
+		}
+		log.Infof("udp://%s inaccessible: %v", addr, err)
+		return failureReason(err)
+	}
+	return ""
 }
 
 func (c *check) markServerDown() {
-	c.state.resetOrIncrement(ServerUp, ServerDown, c.healthCheck.DownThreshold)
+	if c.state.resetOrIncrement(ServerUp, ServerDown, c.healthCheck.DownThreshold) {
+		c.metrics.transitions.WithLabelValues("down").Inc()
+	}
 }
 
 func (c *check) markServerUp() {
-	c.state.resetOrIncrement(ServerDown, ServerUp, c.healthCheck.UpThreshold)
+	if c.state.resetOrIncrement(ServerDown, ServerUp, c.healthCheck.UpThreshold) {
+		c.metrics.transitions.WithLabelValues("up").Inc()
+	}
 }
 
-func (s *checkState) resetOrIncrement(incrementStatus, nextStatus ServerStatus, transitionThreshold uint32) {
+// resetOrIncrement returns true if the status changed.
+func (s *checkState) resetOrIncrement(incrementStatus, nextStatus ServerStatus, transitionThreshold uint32) bool {
 	s.Lock()
 	defer s.Unlock()
 	if s.status != incrementStatus {
 		s.transitionCount = 0
-		return
+		return false
 	}
 
 	s.transitionCount++
@@ -295,5 +391,7 @@ func (s *checkState) resetOrIncrement(incrementStatus, nextStatus ServerStatus,
 		s.status = nextStatus
 		s.transitionCount = 0
 		s.transitionFn(s.status)
+		return true
 	}
+	return false
 }
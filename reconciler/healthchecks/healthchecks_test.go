@@ -9,6 +9,7 @@ import (
 	"sync"
 
 	"fmt"
+	"net"
 	"net/url"
 
 	"github.com/golang/protobuf/proto"
@@ -17,6 +18,7 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sky-uk/merlin/types"
 )
 
@@ -123,6 +125,17 @@ var _ = Describe("HealthChecks", func() {
 			close(done)
 		}, 1.0)
 
+		It("should report the health of servers", func(done Done) {
+			checker.SetHealthCheck(serviceID, localServer1, check, stubTransitionFn)
+			checker.SetHealthCheck(serviceID, localServer2, &types.RealServer_HealthCheck{}, stubTransitionFn)
+
+			time.Sleep(waitForUp)
+			Expect(checker.Health(serviceID, &types.RealServer_Key{Ip: "127.0.0.1"})).To(Equal(types.Health_UP))
+			Expect(checker.Health(serviceID, localServer2)).To(Equal(types.Health_UNCHECKED))
+			Expect(checker.Health("unknown", localServer1)).To(Equal(types.Health_UNSET_HEALTH))
+			close(done)
+		}, 1.0)
+
 		It("should consider server down before health check happens", func(done Done) {
 			checker.SetHealthCheck(serviceID, localServer1, check, stubTransitionFn)
 
@@ -144,6 +157,24 @@ var _ = Describe("HealthChecks", func() {
 			close(done)
 		})
 
+		It("should export metrics of the checks", func(done Done) {
+			checker.SetHealthCheck(serviceID, localServer1, check, stubTransitionFn)
+			checker.SetHealthCheck(serviceID, localServer2, &types.RealServer_HealthCheck{}, stubTransitionFn)
+			time.Sleep(waitForUp)
+			setServerStatus(http.StatusInternalServerError)
+			time.Sleep(waitForDown)
+
+			registry := prometheus.NewRegistry()
+			Expect(registry.Register(checker)).To(Succeed())
+			Expect(metricValue(registry, "merlin_healthchecks_total", "")).To(BeNumerically(">=", 6))
+			Expect(metricValue(registry, "merlin_healthcheck_failures_total", "status")).To(BeNumerically(">=", 2))
+			Expect(metricValue(registry, "merlin_healthcheck_transitions_total", "up")).To(Equal(1.0))
+			Expect(metricValue(registry, "merlin_healthcheck_transitions_total", "down")).To(Equal(1.0))
+			Expect(metricValue(registry, "merlin_service_healthy_servers", serviceID)).To(Equal(1.0))
+			Expect(metricValue(registry, "merlin_service_servers", serviceID)).To(Equal(2.0))
+			close(done)
+		}, 1.0)
+
 		It("can update to a passing health check", func(done Done) {
 			brokenCheck := proto.Clone(check).(*types.RealServer_HealthCheck)
 			brokenCheck.Endpoint = &wrappers.StringValue{Value: "http://:9999/nowhere"}
@@ -248,6 +279,58 @@ var _ = Describe("HealthChecks", func() {
 		}, 1.0)
 	})
 
+	Context("expected status", func() {
+		BeforeEach(func() {
+			setServerStatus(http.StatusServiceUnavailable)
+			check.ExpectedStatus = http.StatusServiceUnavailable
+		})
+
+		It("should only report server up if it returns the expected status", func(done Done) {
+			checker.SetHealthCheck(serviceID, localServer1, check, stubTransitionFn)
+			time.Sleep(waitForUp)
+			Expect(checker.IsDown(serviceID, localServer1)).To(BeFalse())
+
+			setServerStatus(http.StatusOK)
+			time.Sleep(waitForDown)
+			Expect(checker.IsDown(serviceID, localServer1)).To(BeTrue())
+			close(done)
+		}, 1.0)
+	})
+
+	Context("tcp and udp", func() {
+		It("should report server up while it accepts tcp connections", func(done Done) {
+			lis, err := net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).ToNot(HaveOccurred())
+			_, port, _ := net.SplitHostPort(lis.Addr().String())
+			check.Endpoint = &wrappers.StringValue{Value: "tcp://:" + port}
+			checker.SetHealthCheck(serviceID, localServer1, check, stubTransitionFn)
+
+			time.Sleep(waitForUp)
+			Expect(checker.IsDown(serviceID, localServer1)).To(BeFalse())
+
+			lis.Close()
+			time.Sleep(waitForDown)
+			Expect(checker.IsDown(serviceID, localServer1)).To(BeTrue())
+			close(done)
+		}, 1.0)
+
+		It("should report server up until its udp port is unreachable", func(done Done) {
+			conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+			Expect(err).ToNot(HaveOccurred())
+			_, port, _ := net.SplitHostPort(conn.LocalAddr().String())
+			check.Endpoint = &wrappers.StringValue{Value: "udp://:" + port}
+			checker.SetHealthCheck(serviceID, localServer1, check, stubTransitionFn)
+
+			time.Sleep(waitForUp)
+			Expect(checker.IsDown(serviceID, localServer1)).To(BeFalse())
+
+			conn.Close()
+			time.Sleep(waitForDown)
+			Expect(checker.IsDown(serviceID, localServer1)).To(BeTrue())
+			close(done)
+		}, 1.0)
+	})
+
 	Context("transition function", func() {
 		var (
 			called        bool
@@ -340,3 +423,24 @@ var _ = Describe("HealthChecks", func() {
 		})
 	})
 })
+
+// metricValue returns the value of a metric's series with the label value, or its only series if label is empty.
+func metricValue(registry *prometheus.Registry, name, label string) float64 {
+	families, err := registry.Gather()
+	Expect(err).NotTo(HaveOccurred())
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, m := range family.Metric {
+			if label == "" || m.Label[0].GetValue() == label {
+				if m.Counter != nil {
+					return m.Counter.GetValue()
+				}
+				return m.Gauge.GetValue()
+			}
+		}
+	}
+	Fail(fmt.Sprintf("metric %s{%s} not found", name, label))
+	return 0
+}
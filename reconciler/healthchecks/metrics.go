@@ -0,0 +1,94 @@
+package healthchecks
+
+import (
+	"net"
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Reasons a health check fails.
+const (
+	failureTimeout = "timeout"
+	failureConnect = "connect"
+	failureStatus  = "status"
+)
+
+// metrics of the health checks.
+type metrics struct {
+	checks         prometheus.Counter
+	failures       *prometheus.CounterVec
+	transitions    *prometheus.CounterVec
+	healthyServers *prometheus.Desc
+	checkedServers *prometheus.Desc
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		checks: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "merlin_healthchecks_total",
+			Help: "Health checks performed against real servers.",
+		}),
+		failures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "merlin_healthcheck_failures_total",
+			Help: "Failed health checks, by whether they timed out, couldn't connect, or returned a non-2xx status.",
+		}, []string{"reason"}),
+		transitions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "merlin_healthcheck_transitions_total",
+			Help: "Real servers which changed health, by the state they changed to.",
+		}, []string{"state"}),
+		healthyServers: prometheus.NewDesc("merlin_service_healthy_servers",
+			"Real servers of the virtual service which are up, or have no health check.", []string{"service_id"}, nil),
+		checkedServers: prometheus.NewDesc("merlin_service_servers",
+			"Real servers of the virtual service known to the health checker.", []string{"service_id"}, nil),
+	}
+}
+
+// failureReason returns the reason label of a failed request.
+func failureReason(err error) string {
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		return failureTimeout
+	}
+	return failureConnect
+}
+
+func (c *checker) Describe(ch chan<- *prometheus.Desc) {
+	c.metrics.checks.Describe(ch)
+	c.metrics.failures.Describe(ch)
+	c.metrics.transitions.Describe(ch)
+	ch <- c.metrics.healthyServers
+	ch <- c.metrics.checkedServers
+}
+
+func (c *checker) Collect(ch chan<- prometheus.Metric) {
+	c.metrics.checks.Collect(ch)
+	c.metrics.failures.Collect(ch)
+	c.metrics.transitions.Collect(ch)
+
+	healthy := make(map[string]int)
+	servers := make(map[string]int)
+	c.Lock()
+	for key, check := range c.checks {
+		servers[key.serviceID]++
+		if check.healthCheck.Endpoint.GetValue() == "" {
+			healthy[key.serviceID]++
+			continue
+		}
+		check.state.Lock()
+		if check.state.status == ServerUp {
+			healthy[key.serviceID]++
+		}
+		check.state.Unlock()
+	}
+	c.Unlock()
+
+	var ids []string
+	for id := range servers {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		ch <- prometheus.MustNewConstMetric(c.metrics.healthyServers, prometheus.GaugeValue, float64(healthy[id]), id)
+		ch <- prometheus.MustNewConstMetric(c.metrics.checkedServers, prometheus.GaugeValue, float64(servers[id]), id)
+	}
+}
@@ -0,0 +1,147 @@
+package reconciler
+
+import (
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+	log "github.com/sirupsen/logrus"
+	"github.com/sky-uk/merlin/types"
+)
+
+// ManualChange is a change to IPVS which the reconciler didn't make, such as by running ipvsadm by hand. It's
+// reported before the reconciler reverts it to match the store.
+type ManualChange struct {
+	// ServiceID of the service merlin applied, or empty if the service was added by hand.
+	ServiceID string
+	// Service is the key of the changed service, or the service of the changed server.
+	Service *types.VirtualService_Key
+	// Server is the key of the changed server, or nil if the service itself was changed.
+	Server *types.RealServer_Key
+	// Action is CREATE if it was added by hand, UPDATE if its config was changed, or DELETE if it was deleted.
+	Action types.Change_Action
+	// Applied is the config merlin applied, empty if it was added by hand.
+	Applied string
+	// Actual is the config found in IPVS, empty if it was deleted by hand.
+	Actual string
+}
+
+func (c ManualChange) String() string {
+	what := fmt.Sprintf("service %s", c.Service.PrettyString())
+	if c.ServiceID != "" {
+		what = fmt.Sprintf("service %s (%s)", c.ServiceID, c.Service.PrettyString())
+	}
+	if c.Server != nil {
+		what = fmt.Sprintf("server %s of %s", c.Server.PrettyString(), what)
+	}
+	switch c.Action {
+	case types.Change_CREATE:
+		return fmt.Sprintf("%s was added with [%s]", what, c.Actual)
+	case types.Change_DELETE:
+		return fmt.Sprintf("%s was deleted", what)
+	default:
+		return fmt.Sprintf("%s was changed from [%s] to [%s]", what, c.Applied, c.Actual)
+	}
+}
+
+// ManualChangeFunc is called with each manual change found in IPVS.
+type ManualChangeFunc func(change ManualChange)
+
+// appliedState is the IPVS state the reconciler left, by service key, so changes made since can be found.
+type appliedState struct {
+	services map[string]*types.VirtualService
+	// servers of each service, which are missing for services whose servers weren't reconciled.
+	servers map[string][]*types.RealServer
+}
+
+func newAppliedState() *appliedState {
+	return &appliedState{
+		services: make(map[string]*types.VirtualService),
+		servers:  make(map[string][]*types.RealServer),
+	}
+}
+
+// setServerConfig records a server config applied outside of a reconcile, such as by a health check.
+func (a *appliedState) setServerConfig(serviceKey *types.VirtualService_Key, server *types.RealServer) {
+	for _, s := range a.servers[serviceKey.PrettyString()] {
+		if proto.Equal(s.Key, server.Key) {
+			s.Config = proto.Clone(server.Config).(*types.RealServer_Config)
+		}
+	}
+}
+
+// manualServiceChanges returns the services added, changed or deleted in IPVS since they were applied.
+func (a *appliedState) manualServiceChanges(actual []*types.VirtualService) []ManualChange {
+	var changes []ManualChange
+	found := make(map[string]bool)
+	for _, svc := range actual {
+		key := svc.Key.PrettyString()
+		found[key] = true
+		applied := a.services[key]
+		if applied == nil {
+			changes = append(changes, ManualChange{Service: svc.Key, Action: types.Change_CREATE,
+				Actual: svc.Config.PrettyString()})
+		} else if !proto.Equal(applied.Config, svc.Config) {
+			changes = append(changes, ManualChange{ServiceID: applied.Id, Service: svc.Key,
+				Action: types.Change_UPDATE, Applied: applied.Config.PrettyString(), Actual: svc.Config.PrettyString()})
+		}
+	}
+	for key, applied := range a.services {
+		if !found[key] {
+			changes = append(changes, ManualChange{ServiceID: applied.Id, Service: applied.Key,
+				Action: types.Change_DELETE, Applied: applied.Config.PrettyString()})
+		}
+	}
+	return changes
+}
+
+// manualServerChanges returns the servers of a service added, changed or deleted in IPVS since they were applied.
+// Nothing is returned if the service's servers weren't applied.
+func (a *appliedState) manualServerChanges(svc *types.VirtualService_Key, actual []*types.RealServer) []ManualChange {
+	appliedService := a.services[svc.PrettyString()]
+	applied, ok := a.servers[svc.PrettyString()]
+	if !ok || appliedService == nil {
+		return nil
+	}
+
+	var changes []ManualChange
+	for _, server := range actual {
+		var match *types.RealServer
+		for _, s := range applied {
+			if proto.Equal(s.Key, server.Key) {
+				match = s
+				break
+			}
+		}
+		if match == nil {
+			changes = append(changes, ManualChange{ServiceID: appliedService.Id, Service: svc, Server: server.Key,
+				Action: types.Change_CREATE, Actual: server.Config.PrettyString()})
+		} else if !proto.Equal(match.Config, server.Config) {
+			changes = append(changes, ManualChange{ServiceID: appliedService.Id, Service: svc, Server: server.Key,
+				Action: types.Change_UPDATE, Applied: match.Config.PrettyString(), Actual: server.Config.PrettyString()})
+		}
+	}
+	for _, s := range applied {
+		var found bool
+		for _, server := range actual {
+			found = found || proto.Equal(s.Key, server.Key)
+		}
+		if !found {
+			changes = append(changes, ManualChange{ServiceID: appliedService.Id, Service: svc, Server: s.Key,
+				Action: types.Change_DELETE, Applied: s.Config.PrettyString()})
+		}
+	}
+	return changes
+}
+
+// reportManualChanges logs the changes, and calls the manual change func if it's set.
+func (r *reconciler) reportManualChanges(changes []ManualChange) {
+	r.mu.Lock()
+	alert := r.manualChangeAlert
+	r.mu.Unlock()
+	for _, change := range changes {
+		log.Warnf("Reverting manual change to IPVS: %s", change)
+		if alert != nil {
+			alert(change)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package reconciler
+
+import (
+	"context"
+	"math"
+
+	"github.com/golang/protobuf/ptypes/wrappers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sky-uk/merlin/ipvs"
+	"github.com/sky-uk/merlin/reconciler/healthchecks"
+	"github.com/sky-uk/merlin/store"
+	"github.com/sky-uk/merlin/types"
+)
+
+var _ = Describe("Manual changes", func() {
+	var (
+		ctx     = context.Background()
+		st      store.Store
+		i       ipvs.IPVS
+		r       *reconciler
+		changes []ManualChange
+		svc     *types.VirtualService
+		server  *types.RealServer
+	)
+
+	reported := func(changes []ManualChange) []string {
+		var reported []string
+		for _, change := range changes {
+			reported = append(reported, change.String())
+		}
+		return reported
+	}
+
+	BeforeEach(func() {
+		st = store.NewMemory()
+		i = ipvs.NewMemory()
+		r = New(math.MaxInt64, st, i).(*reconciler)
+		changes = nil
+		r.SetManualChangeAlert(func(change ManualChange) { changes = append(changes, change) })
+
+		svc = &types.VirtualService{
+			Id:     "web",
+			Key:    &types.VirtualService_Key{Ip: "10.10.10.1", Port: 80, Protocol: types.Protocol_TCP},
+			Config: &types.VirtualService_Config{Scheduler: "wrr"},
+		}
+		server = &types.RealServer{
+			ServiceID:   "web",
+			Key:         &types.RealServer_Key{Ip: "172.16.1.1", Port: 8080},
+			Config:      &types.RealServer_Config{Weight: &wrappers.UInt32Value{Value: 1}, Forward: types.ForwardMethod_ROUTE},
+			HealthCheck: &types.RealServer_HealthCheck{},
+		}
+		Expect(st.PutService(ctx, svc)).To(Succeed())
+		Expect(st.PutServer(ctx, server)).To(Succeed())
+	})
+
+	It("should not report changes made before the first reconcile", func() {
+		Expect(i.AddService(ctx, &types.VirtualService{
+			Key:    &types.VirtualService_Key{Ip: "10.10.10.2", Port: 80, Protocol: types.Protocol_TCP},
+			Config: &types.VirtualService_Config{Scheduler: "rr"},
+		})).To(Succeed())
+
+		r.reconcile()
+
+		Expect(changes).To(BeEmpty())
+	})
+
+	It("should not report changes from the store", func() {
+		r.reconcile()
+		svc.Config.Scheduler = "sh"
+		Expect(st.PutService(ctx, svc)).To(Succeed())
+
+		r.reconcile()
+
+		Expect(changes).To(BeEmpty())
+	})
+
+	It("should report services added, changed and deleted by hand before reverting them", func() {
+		r.reconcile()
+		added := &types.VirtualService{
+			Key:    &types.VirtualService_Key{Ip: "10.10.10.2", Port: 80, Protocol: types.Protocol_TCP},
+			Config: &types.VirtualService_Config{Scheduler: "rr"},
+		}
+		Expect(i.AddService(ctx, added)).To(Succeed())
+		changed := &types.VirtualService{Key: svc.Key, Config: &types.VirtualService_Config{Scheduler: "lc"}}
+		Expect(i.UpdateService(ctx, changed)).To(Succeed())
+
+		r.reconcile()
+
+		Expect(reported(changes)).To(ConsistOf(
+			"service 10.10.10.2:80 TCP was added with [rr ()]",
+			"service web (10.10.10.1:80 TCP) was changed from [wrr ()] to [lc ()]",
+		))
+		Expect(i.ListServices(ctx)).To(HaveLen(1), "reverted")
+
+		Expect(i.DeleteService(ctx, svc.Key)).To(Succeed())
+		changes = nil
+		r.reconcile()
+
+		Expect(changes).To(HaveLen(1))
+		Expect(changes[0].String()).To(Equal("service web (10.10.10.1:80 TCP) was deleted"))
+	})
+
+	It("should report servers added, changed and deleted by hand", func() {
+		r.reconcile()
+		Expect(i.AddServer(ctx, svc.Key, &types.RealServer{
+			Key:    &types.RealServer_Key{Ip: "172.16.1.2", Port: 8080},
+			Config: &types.RealServer_Config{Weight: &wrappers.UInt32Value{Value: 5}, Forward: types.ForwardMethod_ROUTE},
+		})).To(Succeed())
+		Expect(i.UpdateServer(ctx, svc.Key, &types.RealServer{
+			Key:    server.Key,
+			Config: &types.RealServer_Config{Weight: &wrappers.UInt32Value{Value: 0}, Forward: types.ForwardMethod_ROUTE},
+		})).To(Succeed())
+
+		r.reconcile()
+
+		Expect(reported(changes)).To(ConsistOf(
+			"server 172.16.1.2:8080 of service web (10.10.10.1:80 TCP) was added with [ROUTE weight:5]",
+			"server 172.16.1.1:8080 of service web (10.10.10.1:80 TCP) was changed from [ROUTE weight:1] to "+
+				"[ROUTE weight:0]",
+		))
+
+		Expect(i.DeleteServer(ctx, svc.Key, server)).To(Succeed())
+		changes = nil
+		r.reconcile()
+
+		Expect(changes).To(HaveLen(1))
+		Expect(changes[0].Action).To(Equal(types.Change_DELETE))
+		Expect(changes[0].Server.PrettyString()).To(Equal("172.16.1.1:8080"))
+	})
+
+	It("should not report weights changed by health checks", func() {
+		r.reconcile()
+		transition := r.createHealthStateWeightUpdater(svc.Key, server)
+
+		transition(healthchecks.ServerDown)
+		r.reconcile()
+
+		Expect(changes).To(BeEmpty())
+	})
+})
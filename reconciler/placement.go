@@ -0,0 +1,59 @@
+package reconciler
+
+import (
+	"context"
+
+	"github.com/golang/protobuf/proto"
+	log "github.com/sirupsen/logrus"
+	"github.com/sky-uk/merlin/types"
+)
+
+// nodeStore lists the services as a node should reconcile them.
+type nodeStore struct {
+	Store
+	name   string
+	labels map[string]string
+}
+
+// ForNode returns a store which lists the services as the named node should reconcile them. Services whose node
+// selector doesn't match labels aren't listed, so they're removed from its IPVS, and services with a rollout it's
+// a canary of have the config of the rollout.
+func ForNode(store Store, name string, labels map[string]string) Store {
+	return &nodeStore{Store: store, name: name, labels: labels}
+}
+
+func (s *nodeStore) ListServices(ctx context.Context) ([]*types.VirtualService, error) {
+	svcs, err := s.Store.ListServices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var selected []*types.VirtualService
+	for _, svc := range svcs {
+		selector, err := types.ParseSelector(svc.NodeSelector)
+		if err != nil {
+			log.Warnf("Not reconciling %s, its node selector is invalid: %v", svc.Id, err)
+			continue
+		}
+		if !selector.Matches(s.labels) {
+			continue
+		}
+		if s.isCanary(svc.Rollout) {
+			svc = proto.Clone(svc).(*types.VirtualService)
+			svc.Config = svc.Rollout.Config
+		}
+		selected = append(selected, svc)
+	}
+	return selected, nil
+}
+
+func (s *nodeStore) isCanary(rollout *types.Rollout) bool {
+	if rollout.GetState() != types.Rollout_CANARY {
+		return false
+	}
+	for _, node := range rollout.CanaryNodes {
+		if node == s.name {
+			return true
+		}
+	}
+	return false
+}
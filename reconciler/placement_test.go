@@ -0,0 +1,53 @@
+package reconciler
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sky-uk/merlin/types"
+	"github.com/stretchr/testify/mock"
+)
+
+var _ = Describe("ForNode", func() {
+	var store *storeMock
+
+	BeforeEach(func() {
+		store = &storeMock{}
+	})
+
+	It("should only list the services selecting the node", func() {
+		everywhere := &types.VirtualService{Id: "everywhere"}
+		edge := &types.VirtualService{Id: "edge", NodeSelector: "pool=edge"}
+		internal := &types.VirtualService{Id: "internal", NodeSelector: "pool=internal"}
+		invalid := &types.VirtualService{Id: "invalid", NodeSelector: "pool=edge pool"}
+		store.On("ListServices", mock.Anything).Return(
+			[]*types.VirtualService{everywhere, edge, internal, invalid}, nil)
+
+		svcs, err := ForNode(store, "node1", map[string]string{"pool": "edge"}).ListServices(context.Background())
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(svcs).To(Equal([]*types.VirtualService{everywhere, edge}))
+	})
+
+	It("should list the config of rollouts the node is a canary of", func() {
+		current := &types.VirtualService_Config{Scheduler: "sh"}
+		next := &types.VirtualService_Config{Scheduler: "wrr"}
+		store.On("ListServices", mock.Anything).Return([]*types.VirtualService{
+			{Id: "canary", Config: current, Rollout: &types.Rollout{
+				Config: next, CanaryNodes: []string{"node1"}, State: types.Rollout_CANARY}},
+			{Id: "other", Config: current, Rollout: &types.Rollout{
+				Config: next, CanaryNodes: []string{"node2"}, State: types.Rollout_CANARY}},
+			{Id: "halted", Config: current, Rollout: &types.Rollout{
+				Config: next, CanaryNodes: []string{"node1"}, State: types.Rollout_HALTED}},
+		}, nil)
+
+		svcs, err := ForNode(store, "node1", nil).ListServices(context.Background())
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(svcs).To(HaveLen(3))
+		Expect(svcs[0].Config).To(Equal(next))
+		Expect(svcs[1].Config).To(Equal(current))
+		Expect(svcs[2].Config).To(Equal(current))
+	})
+})
@@ -1,6 +1,8 @@
 package reconciler
 
 import (
+	"sort"
+	"sync"
 	"time"
 
 	"context"
@@ -9,6 +11,7 @@ import (
 
 	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/prometheus/client_golang/prometheus"
 	log "github.com/sirupsen/logrus"
 	"github.com/sky-uk/merlin/ipvs"
 	"github.com/sky-uk/merlin/reconciler/healthchecks"
@@ -28,6 +31,52 @@ type reconciler struct {
 	checker healthchecks.Checker
 	flush   bool
 	stopCh  chan struct{}
+	// doneCh is closed when the reconcile loop exits.
+	doneCh chan struct{}
+
+	mu    sync.Mutex
+	state State
+	// errors of each service since its last reconcile.
+	errors map[string][]string
+	// desired is the store state read by the last reconcile.
+	desired *types.Snapshot
+	// driftSyncs is the number of consecutive reconciles each service has drifted in, by key.
+	driftSyncs map[string]int
+	driftAlert DriftAlertFunc
+	alertSyncs int
+	// applied is the IPVS state left by the last reconcile, nil until the first, and applying is the state being
+	// applied by the current reconcile.
+	applied           *appliedState
+	applying          *appliedState
+	manualChangeAlert ManualChangeFunc
+}
+
+// DriftAlert is raised when a service has drifted from the store in consecutive reconciles, meaning it isn't
+// converging, for example because something else is changing IPVS or changes are failing.
+type DriftAlert struct {
+	// ServiceID is the ID of the service, or empty if the service isn't in the store and keeps reappearing.
+	ServiceID string
+	// Service is the key of the service in IPVS.
+	Service *types.VirtualService_Key
+	// Syncs is the number of consecutive reconciles the service has drifted in.
+	Syncs int
+	// Reasons are the changes and errors of the last reconcile.
+	Reasons []string
+}
+
+// DriftAlertFunc is called with a service which has drifted in consecutive reconciles.
+type DriftAlertFunc func(alert DriftAlert)
+
+// State of the reconciler.
+type State struct {
+	// LastSync is when IPVS was last reconciled with the store, or the zero time if it hasn't been.
+	LastSync time.Time
+	// Drift is the number of changes made to IPVS by the last sync.
+	Drift int
+	// Paused is true if reconciliation is paused for maintenance.
+	Paused bool
+	// FailedServices are the IDs of the services with errors in the last sync.
+	FailedServices []string
 }
 
 // Store expected store interface for reconciler.
@@ -39,8 +88,26 @@ type Store interface {
 // Reconciler reconciles store with local IPVS state.
 type Reconciler interface {
 	Start() error
+	// Stop reconciling, waiting for an in-flight reconcile to finish.
 	Stop()
 	Sync()
+	// SetPaused pauses or resumes reconciliation, leaving IPVS untouched while paused.
+	SetPaused(paused bool)
+	State() State
+	// Health returns the health of a real server, or UNSET_HEALTH if it isn't known.
+	Health(serviceID string, key *types.RealServer_Key) types.Health
+	// Errors returns the errors reconciling a service, since it was last reconciled.
+	Errors(serviceID string) []string
+	// Dump returns the desired state from the last reconcile, the actual state of IPVS, and the difference.
+	Dump() (*Dump, error)
+	// SetDriftAlert calls alert once a service has drifted in the given number of consecutive reconciles. It's
+	// called again only after the service converges and drifts again.
+	SetDriftAlert(syncs int, alert DriftAlertFunc)
+	// SetManualChangeAlert calls alert with each change made to IPVS since the last reconcile which the reconciler
+	// didn't make, before reverting it. Changes made before the first reconcile aren't known, so aren't reported.
+	SetManualChangeAlert(alert ManualChangeFunc)
+	// HealthCheckMetrics returns the metrics of the health checks of real servers, nil if it doesn't check them.
+	HealthCheckMetrics() prometheus.Collector
 }
 
 // New returns a reconciler that populates the ipvs state periodically and on demand.
@@ -52,12 +119,18 @@ func New(period time.Duration, store Store, ipvs ipvs.IPVS) Reconciler {
 		ipvs:    ipvs,
 		checker: healthchecks.New(),
 		stopCh:  make(chan struct{}),
+		errors:  make(map[string][]string),
+		desired: &types.Snapshot{},
+
+		driftSyncs: make(map[string]int),
 	}
 }
 
 func (r *reconciler) Start() error {
 	log.Debug("Starting reconciler loop")
+	r.doneCh = make(chan struct{})
 	go func() {
+		defer close(r.doneCh)
 		for {
 			t := time.NewTimer(r.period)
 			select {
@@ -106,6 +179,10 @@ func (r *reconciler) createHealthStateWeightUpdater(serviceKey *types.VirtualSer
 	server := proto.Clone(originalServer).(*types.RealServer)
 
 	return func(state healthchecks.ServerStatus) {
+		if r.State().Paused {
+			log.Infof("Ignoring health transition of %v, paused for maintenance", server.PrettyString())
+			return
+		}
 		serverCopy := proto.Clone(server).(*types.RealServer)
 		switch state {
 		case healthchecks.ServerDown:
@@ -118,6 +195,18 @@ func (r *reconciler) createHealthStateWeightUpdater(serviceKey *types.VirtualSer
 
 		if err := r.updateIPVSServer(serviceKey, serverCopy); err != nil {
 			log.Warnf("Unable to update the weight for %v: %v", serverCopy, err)
+			r.mu.Lock()
+			r.errors[server.ServiceID] = append(r.errors[server.ServiceID],
+				fmt.Sprintf("unable to update the weight of %s: %v", server.Key.PrettyString(), err))
+			r.mu.Unlock()
+			return
+		}
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		for _, applied := range []*appliedState{r.applied, r.applying} {
+			if applied != nil {
+				applied.setServerConfig(serviceKey, serverCopy)
+			}
 		}
 	}
 }
@@ -125,15 +214,76 @@ func (r *reconciler) createHealthStateWeightUpdater(serviceKey *types.VirtualSer
 func (r *reconciler) Stop() {
 	close(r.stopCh)
 	r.checker.Stop()
+	if r.doneCh != nil {
+		<-r.doneCh
+	}
 }
 
 func (r *reconciler) Sync() {
 	go func() { r.syncCh <- struct{}{} }()
 }
 
+func (r *reconciler) SetPaused(paused bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.state.Paused != paused {
+		log.Infof("Reconciler paused: %v", paused)
+	}
+	r.state.Paused = paused
+}
+
+func (r *reconciler) State() State {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.state
+}
+
+func (r *reconciler) Health(serviceID string, key *types.RealServer_Key) types.Health {
+	return r.checker.Health(serviceID, key)
+}
+
+func (r *reconciler) HealthCheckMetrics() prometheus.Collector {
+	return r.checker
+}
+
+func (r *reconciler) Errors(serviceID string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.errors[serviceID]...)
+}
+
+func (r *reconciler) SetDriftAlert(syncs int, alert DriftAlertFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.alertSyncs = syncs
+	r.driftAlert = alert
+}
+
+func (r *reconciler) SetManualChangeAlert(alert ManualChangeFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.manualChangeAlert = alert
+}
+
 func (r *reconciler) reconcile() {
+	if r.State().Paused {
+		log.Debug("Skipping reconcile, paused for maintenance")
+		return
+	}
 	log.Debug("Starting reconcile")
 	defer log.Debug("Finished reconcile")
+	drift := 0
+	errors := make(map[string][]string)
+	desired := &types.Snapshot{}
+	// drifted are the services changed or failing in this reconcile, by key
+	drifted := make(map[string]*DriftAlert)
+	driftedBy := func(svc *types.VirtualService, format string, args ...interface{}) {
+		key := svc.Key.PrettyString()
+		if drifted[key] == nil {
+			drifted[key] = &DriftAlert{ServiceID: svc.Id, Service: svc.Key}
+		}
+		drifted[key].Reasons = append(drifted[key].Reasons, fmt.Sprintf(format, args...))
+	}
 
 	desiredServices, err := r.listStoreServices()
 	if err != nil {
@@ -146,25 +296,44 @@ func (r *reconciler) reconcile() {
 		log.Panicf("Unable to populate: %v", err)
 		return
 	}
+	for _, actual := range actualServices {
+		actual.NormalizeFlags()
+	}
+
+	r.mu.Lock()
+	applied := r.applied
+	applying := newAppliedState()
+	r.applying = applying
+	r.mu.Unlock()
+	if applied != nil {
+		r.reportManualChanges(applied.manualServiceChanges(actualServices))
+	}
 
 	// create or update services
 	for _, desiredService := range desiredServices {
-		desiredService.SortFlags()
+		desiredService.NormalizeFlags()
+		desired.Services = append(desired.Services, proto.Clone(desiredService).(*types.VirtualService))
+		r.mu.Lock()
+		applying.services[desiredService.Key.PrettyString()] = proto.Clone(desiredService).(*types.VirtualService)
+		r.mu.Unlock()
 		var match *types.VirtualService
 		for _, actual := range actualServices {
 			if proto.Equal(desiredService.Key, actual.Key) {
 				match = actual
-				match.SortFlags()
 				break
 			}
 		}
 
 		if match == nil {
+			drift++
+			driftedBy(desiredService, "added service")
 			log.Infof("Adding virtual service: %s", desiredService.PrettyString())
 			if err := r.addIPVSService(desiredService); err != nil {
 				log.Panicf("Unable to add service: %v", err)
 			}
 		} else if !proto.Equal(desiredService.Config, match.Config) {
+			drift++
+			driftedBy(desiredService, "updated service from [%v]", match.Config.PrettyString())
 			log.Infof("Updating virtual service %q: [%v] to [%v]", desiredService.Id, match.Config.PrettyString(),
 				desiredService.Config.PrettyString())
 			if err := r.updateIPVSService(desiredService); err != nil {
@@ -175,6 +344,9 @@ func (r *reconciler) reconcile() {
 		desiredServers, err := r.listStoreServers(desiredService.Id)
 		if err != nil {
 			log.Errorf("Unable to list servers in store for %s: %v", desiredService.Key.PrettyString(), err)
+			errors[desiredService.Id] = append(errors[desiredService.Id],
+				fmt.Sprintf("unable to list servers in store: %v", err))
+			driftedBy(desiredService, "unable to list servers in store: %v", err)
 			continue
 		}
 		actualServers, err := r.listIPVSServers(desiredService.Key)
@@ -182,9 +354,16 @@ func (r *reconciler) reconcile() {
 			log.Panicf("Unable to list servers in ipvs for %v: %v", desiredService.Key.PrettyString(), err)
 			continue
 		}
+		if applied != nil && match != nil {
+			r.reportManualChanges(applied.manualServerChanges(desiredService.Key, actualServers))
+		}
+		r.mu.Lock()
+		applying.servers[desiredService.Key.PrettyString()] = nil
+		r.mu.Unlock()
 
 		// update servers
 		for _, desiredServer := range desiredServers {
+			desired.Servers = append(desired.Servers, proto.Clone(desiredServer).(*types.RealServer))
 			var match *types.RealServer
 			for _, actualServer := range actualServers {
 				if proto.Equal(desiredServer.Key, actualServer.Key) {
@@ -200,13 +379,23 @@ func (r *reconciler) reconcile() {
 				desiredServer.Config.Weight = &wrappers.UInt32Value{Value: 0}
 			}
 
+			r.mu.Lock()
+			key := desiredService.Key.PrettyString()
+			applying.servers[key] = append(applying.servers[key], proto.Clone(desiredServer).(*types.RealServer))
+			r.mu.Unlock()
+
 			// update IPVS
 			if match == nil {
+				drift++
+				driftedBy(desiredService, "added server %s", desiredServer.Key.PrettyString())
 				log.Infof("Adding real server: %v", desiredServer.PrettyString())
 				if err := r.addIPVSServer(desiredService.Key, desiredServer); err != nil {
 					log.Panicf("Unable to add server: %v", err)
 				}
 			} else if !proto.Equal(desiredServer.Config, match.Config) {
+				drift++
+				driftedBy(desiredService, "updated server %s from [%v]", desiredServer.Key.PrettyString(),
+					match.Config.PrettyString())
 				log.Infof("Updating real server: %v", desiredServer.PrettyString())
 				if err := r.updateIPVSServer(desiredService.Key, desiredServer); err != nil {
 					log.Panicf("Unable to update server: %v", err)
@@ -224,6 +413,8 @@ func (r *reconciler) reconcile() {
 				}
 			}
 			if !found {
+				drift++
+				driftedBy(desiredService, "deleted server %s", actualServer.Key.PrettyString())
 				log.Infof("Deleting real server: %v", actualServer.PrettyString())
 				// remove health check
 				r.checker.RemHealthCheck(desiredService.Id, actualServer.Key)
@@ -245,12 +436,51 @@ func (r *reconciler) reconcile() {
 			}
 		}
 		if !found {
+			drift++
+			driftedBy(actual, "deleted service not in the store")
 			log.Infof("Deleting virtual service: %v", actual.PrettyString())
 			if err := r.deleteIPVSService(actual.Key); err != nil {
 				log.Panicf("Unable to delete service: %v", err)
 			}
 		}
 	}
+
+	r.mu.Lock()
+	r.state.LastSync = time.Now()
+	r.state.Drift = drift
+	r.errors = errors
+	r.state.FailedServices = nil
+	for id := range errors {
+		r.state.FailedServices = append(r.state.FailedServices, id)
+	}
+	sort.Strings(r.state.FailedServices)
+	r.desired = desired
+	r.applied = applying
+	r.applying = nil
+	r.mu.Unlock()
+
+	r.alertPersistentDrift(drifted)
+}
+
+// alertPersistentDrift counts the consecutive reconciles each service has drifted in, alerting when the count
+// reaches the threshold. Services which didn't drift have converged, so their count is reset.
+func (r *reconciler) alertPersistentDrift(drifted map[string]*DriftAlert) {
+	r.mu.Lock()
+	alert, threshold := r.driftAlert, r.alertSyncs
+	r.mu.Unlock()
+
+	for key := range r.driftSyncs {
+		if drifted[key] == nil {
+			delete(r.driftSyncs, key)
+		}
+	}
+	for key, d := range drifted {
+		r.driftSyncs[key]++
+		d.Syncs = r.driftSyncs[key]
+		if alert != nil && d.Syncs == threshold {
+			alert(*d)
+		}
+	}
 }
 
 func (r *reconciler) listStoreServices() ([]*types.VirtualService, error) {
@@ -1,6 +1,7 @@
 package reconciler
 
 import (
+	"errors"
 	"testing"
 
 	"context"
@@ -15,6 +16,7 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sky-uk/merlin/reconciler/healthchecks"
 	"github.com/sky-uk/merlin/types"
 	"github.com/stretchr/testify/mock"
@@ -92,6 +94,34 @@ var _ = Describe("Reconciler", func() {
 
 			checkerMock.AssertExpectations(GinkgoT())
 		})
+
+		It("should wait for an in-flight reconcile on stop", func() {
+			storeMock := &storeMock{}
+			ipvsMock := &ipvsMock{}
+			r := New(math.MaxInt64, storeMock, ipvsMock).(*reconciler)
+			reconciling := make(chan struct{})
+			release := make(chan struct{})
+			storeMock.On("ListServices", mock.Anything).Return([]*types.VirtualService{}, nil).Once()
+			storeMock.On("ListServices", mock.Anything).Return([]*types.VirtualService{}, nil).Run(
+				func(_ mock.Arguments) {
+					close(reconciling)
+					<-release
+				})
+			ipvsMock.On("ListServices", mock.Anything).Return([]*types.VirtualService{}, nil)
+
+			Expect(r.Start()).To(Succeed())
+			r.Sync()
+			Eventually(reconciling).Should(BeClosed())
+			stopped := make(chan struct{})
+			go func() {
+				r.Stop()
+				close(stopped)
+			}()
+
+			Consistently(stopped, 100*time.Millisecond).ShouldNot(BeClosed())
+			close(release)
+			Eventually(stopped).Should(BeClosed())
+		})
 	})
 
 	Describe("HealthStateWeightUpdater", func() {
@@ -126,6 +156,51 @@ var _ = Describe("Reconciler", func() {
 
 			ipvs.AssertExpectations(GinkgoT())
 		})
+
+		It("should record an error if the weight can't be updated", func() {
+			ipvs.On("UpdateServer", mock.Anything, service.Key, server).Return(errors.New("netlink failure"))
+
+			fn := r.createHealthStateWeightUpdater(service.Key, server)
+			fn(healthchecks.ServerUp)
+
+			Expect(r.Errors(service.Id)).To(ConsistOf(ContainSubstring("netlink failure")))
+		})
+	})
+
+	Describe("Dump", func() {
+		It("should return the last desired state, ipvs, and the changes to reconcile them", func() {
+			ipvsMock := &ipvsMock{}
+			checkerMock := &checkerMock{}
+			r := New(math.MaxInt64, &storeMock{}, ipvsMock).(*reconciler)
+			r.checker = checkerMock
+			r.desired = &types.Snapshot{Services: []*types.VirtualService{service}, Servers: []*types.RealServer{server}}
+
+			actualService := proto.Clone(service).(*types.VirtualService)
+			actualService.Id = ""
+			actualService.Config.Flags = nil
+			unmanaged := &types.VirtualService{
+				Key:    &types.VirtualService_Key{Ip: "10.10.10.2", Port: 101, Protocol: types.Protocol_TCP},
+				Config: &types.VirtualService_Config{Scheduler: "rr"},
+			}
+			ipvsMock.On("ListServices", mock.Anything).Return([]*types.VirtualService{actualService, unmanaged}, nil)
+			ipvsMock.On("ListServers", mock.Anything, service.Key).Return([]*types.RealServer{}, nil)
+			checkerMock.On("IsDown", service.Id, server.Key).Return(true)
+
+			dump, err := r.Dump()
+
+			Expect(err).ToNot(HaveOccurred())
+			downServer := proto.Clone(server).(*types.RealServer)
+			downServer.Config.Weight = &wrappers.UInt32Value{Value: 0}
+			Expect(dump.Desired.Servers).To(Equal([]*types.RealServer{downServer}))
+			Expect(dump.Actual.Services).To(HaveLen(2))
+			Expect(dump.Actual.Services[0].Id).To(Equal(service.Id))
+			Expect(dump.Actual.Services[1].Id).To(BeEmpty())
+			Expect(dump.Diff).To(Equal([]*types.Change{
+				{Action: types.Change_UPDATE, Service: service},
+				{Action: types.Change_CREATE, Server: downServer},
+				{Action: types.Change_DELETE, Service: unmanaged},
+			}))
+		})
 	})
 
 	Describe("reconcile", func() {
@@ -399,17 +474,92 @@ var _ = Describe("Reconciler", func() {
 			}
 
 			// reconcile
+			Expect(r.State().LastSync).To(BeZero())
 			r.reconcile()
 
 			// ensure expected outcomes
 			storeMock.AssertExpectations(GinkgoT())
 			ipvsMock.AssertExpectations(GinkgoT())
 			checkerMock.AssertExpectations(GinkgoT())
+			Expect(r.State().LastSync).ToNot(BeZero())
+			Expect(r.State().Drift).To(Equal(len(c.createdServices) + len(c.updatedServices) +
+				len(c.deletedServices) + countServers(c.createdServers) + countServers(c.updatedServers) +
+				countServers(c.deletedServers)))
 		},
 			cases...)
+
+		It("should leave ipvs untouched while paused", func() {
+			storeMock := &storeMock{}
+			ipvsMock := &ipvsMock{}
+			r := New(math.MaxInt64, storeMock, ipvsMock).(*reconciler)
+			r.SetPaused(true)
+
+			r.reconcile()
+
+			storeMock.AssertExpectations(GinkgoT())
+			ipvsMock.AssertExpectations(GinkgoT())
+			Expect(r.State().LastSync).To(BeZero())
+		})
+
+		It("should record errors of a service until it's next reconciled", func() {
+			storeMock := &storeMock{}
+			ipvsMock := &ipvsMock{}
+			r := New(math.MaxInt64, storeMock, ipvsMock).(*reconciler)
+			svc := proto.Clone(service).(*types.VirtualService)
+			storeMock.On("ListServices", mock.Anything).Return([]*types.VirtualService{svc}, nil)
+			ipvsMock.On("ListServices", mock.Anything).Return([]*types.VirtualService{service}, nil)
+			listServers := storeMock.On("ListServers", mock.Anything, service.Id).
+				Return([]*types.RealServer{}, errors.New("etcd unavailable"))
+
+			r.reconcile()
+			Expect(r.Errors(service.Id)).To(ConsistOf(ContainSubstring("etcd unavailable")))
+
+			listServers.Return([]*types.RealServer{}, nil)
+			ipvsMock.On("ListServers", mock.Anything, service.Key).Return([]*types.RealServer{}, nil)
+			r.reconcile()
+			Expect(r.Errors(service.Id)).To(BeEmpty())
+		})
+
+		It("should alert once a service drifts in consecutive reconciles", func() {
+			storeMock := &storeMock{}
+			ipvsMock := &ipvsMock{}
+			r := New(math.MaxInt64, storeMock, ipvsMock).(*reconciler)
+			var alerts []DriftAlert
+			r.SetDriftAlert(2, func(alert DriftAlert) { alerts = append(alerts, alert) })
+			svc := proto.Clone(service).(*types.VirtualService)
+			storeMock.On("ListServices", mock.Anything).Return([]*types.VirtualService{svc}, nil)
+			ipvsMock.On("ListServices", mock.Anything).Return([]*types.VirtualService{service}, nil)
+			listServers := storeMock.On("ListServers", mock.Anything, service.Id).
+				Return([]*types.RealServer{}, errors.New("etcd unavailable"))
+
+			r.reconcile()
+			Expect(alerts).To(BeEmpty())
+
+			r.reconcile()
+			Expect(alerts).To(HaveLen(1))
+			Expect(alerts[0].ServiceID).To(Equal(service.Id))
+			Expect(alerts[0].Syncs).To(Equal(2))
+			Expect(alerts[0].Reasons).To(ConsistOf(ContainSubstring("etcd unavailable")))
+
+			r.reconcile()
+			Expect(alerts).To(HaveLen(1), "only alerted once while drifting")
+
+			listServers.Return([]*types.RealServer{}, nil)
+			ipvsMock.On("ListServers", mock.Anything, service.Key).Return([]*types.RealServer{}, nil)
+			r.reconcile()
+			Expect(r.driftSyncs).To(BeEmpty(), "converged")
+		})
 	})
 })
 
+func countServers(s servers) int {
+	count := 0
+	for _, servers := range s {
+		count += len(servers)
+	}
+	return count
+}
+
 func copyServices(services []*types.VirtualService) []*types.VirtualService {
 	var copiedServices []*types.VirtualService
 	for _, service := range services {
@@ -492,6 +642,31 @@ func (i *ipvsMock) ListServers(ctx context.Context, key *types.VirtualService_Ke
 	return args.Get(0).([]*types.RealServer), args.Error(1)
 }
 
+func (i *ipvsMock) Stats(ctx context.Context) ([]*types.ServiceStats, error) {
+	args := i.Called(ctx)
+	return args.Get(0).([]*types.ServiceStats), args.Error(1)
+}
+
+func (i *ipvsMock) Connections(ctx context.Context, fn func(*types.Connection) error) error {
+	args := i.Called(ctx, fn)
+	return args.Error(0)
+}
+
+func (i *ipvsMock) SyncDaemons(ctx context.Context) ([]*types.SyncDaemon, error) {
+	args := i.Called(ctx)
+	return args.Get(0).([]*types.SyncDaemon), args.Error(1)
+}
+
+func (i *ipvsMock) StartSyncDaemon(ctx context.Context, daemon *types.SyncDaemon) error {
+	args := i.Called(ctx, daemon)
+	return args.Error(0)
+}
+
+func (i *ipvsMock) StopSyncDaemon(ctx context.Context, role types.SyncDaemon_Role) error {
+	args := i.Called(ctx, role)
+	return args.Error(0)
+}
+
 type checkerMock struct {
 	mock.Mock
 }
@@ -501,6 +676,11 @@ func (m *checkerMock) IsDown(serviceID string, key *types.RealServer_Key) bool {
 	return args.Bool(0)
 }
 
+func (m *checkerMock) Health(serviceID string, key *types.RealServer_Key) types.Health {
+	args := m.Called(serviceID, key)
+	return args.Get(0).(types.Health)
+}
+
 func (m *checkerMock) SetHealthCheck(serviceID string, key *types.RealServer_Key, check *types.RealServer_HealthCheck,
 	transitionFunc healthchecks.TransitionFunc) error {
 	args := m.Called(serviceID, key, check, transitionFunc)
@@ -514,3 +694,7 @@ func (m *checkerMock) RemHealthCheck(serviceID string, key *types.RealServer_Key
 func (m *checkerMock) Stop() {
 	m.Called()
 }
+
+func (m *checkerMock) Describe(chan<- *prometheus.Desc) {}
+
+func (m *checkerMock) Collect(chan<- prometheus.Metric) {}
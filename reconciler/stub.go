@@ -1,10 +1,16 @@
 package reconciler
 
 import (
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
 	log "github.com/sirupsen/logrus"
+	"github.com/sky-uk/merlin/types"
 )
 
-type stub struct{}
+type stub struct {
+	paused bool
+}
 
 // NewStub returns a reconciler which doesn't do anything.
 func NewStub() Reconciler {
@@ -23,3 +29,34 @@ func (s *stub) Stop() {
 func (s *stub) Sync() {
 	log.Debug("stub-reconciler: Sync()")
 }
+
+func (s *stub) SetPaused(paused bool) {
+	log.Debugf("stub-reconciler: SetPaused(%v)", paused)
+	s.paused = paused
+}
+
+func (s *stub) State() State {
+	return State{Paused: s.paused}
+}
+
+func (s *stub) Health(_ string, _ *types.RealServer_Key) types.Health {
+	return types.Health_UNSET_HEALTH
+}
+
+func (s *stub) Errors(_ string) []string {
+	return nil
+}
+
+func (s *stub) Dump() (*Dump, error) {
+	return nil, errors.New("reconcile is disabled")
+}
+
+func (s *stub) SetDriftAlert(_ int, _ DriftAlertFunc) {
+}
+
+func (s *stub) SetManualChangeAlert(_ ManualChangeFunc) {
+}
+
+func (s *stub) HealthCheckMetrics() prometheus.Collector {
+	return nil
+}
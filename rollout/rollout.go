@@ -0,0 +1,130 @@
+// Package rollout progresses staged rollouts of service configs. A rollout is reconciled by its canary nodes first,
+// and is promoted to every node once the canaries have converged and stayed healthy for the bake time. Rollouts
+// halt if a canary fails, reverting the canaries to the config of the service.
+package rollout
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	log "github.com/sirupsen/logrus"
+	"github.com/sky-uk/merlin/store"
+	"github.com/sky-uk/merlin/types"
+)
+
+// Check progresses every rollout in the store at time now: promoting, halting, or recording the convergence of
+// each as needed. Nodes checking at the same time make the same changes, but with leader election only the leader
+// checks.
+func Check(ctx context.Context, st store.Store, now time.Time) error {
+	svcs, err := st.ListServices(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to list services: %v", err)
+	}
+	var nodes []*types.Node
+	for _, svc := range svcs {
+		if svc.Rollout.GetState() != types.Rollout_CANARY {
+			continue
+		}
+		if nodes == nil {
+			if nodes, err = st.ListNodes(ctx); err != nil {
+				return fmt.Errorf("unable to list nodes: %v", err)
+			}
+		}
+		if next := progress(svc, nodes, now); next != nil {
+			if err := st.PutService(ctx, next); err != nil {
+				return fmt.Errorf("unable to update rollout of %s: %v", svc.Id, err)
+			}
+		}
+	}
+	return nil
+}
+
+// progress returns the service with its rollout progressed, or nil if it hasn't changed.
+func progress(svc *types.VirtualService, nodes []*types.Node, now time.Time) *types.VirtualService {
+	next := proto.Clone(svc).(*types.VirtualService)
+	r := next.Rollout
+	started, _ := ptypes.Timestamp(r.Started)
+	timeout, _ := ptypes.Duration(r.Timeout)
+	bake, _ := ptypes.Duration(r.Bake)
+
+	if reason := canaryFailure(svc.Id, r, nodes, started); reason != "" {
+		r.State = types.Rollout_HALTED
+		r.Message = reason
+		log.Warnf("Halted rollout of %s: %s", svc.Id, reason)
+		return next
+	}
+
+	if r.Converged == nil {
+		if !canariesConverged(r, nodes, started) {
+			if now.Sub(started) > timeout {
+				r.State = types.Rollout_HALTED
+				r.Message = fmt.Sprintf("canaries didn't converge within %v", timeout)
+				log.Warnf("Halted rollout of %s: %s", svc.Id, r.Message)
+				return next
+			}
+			return nil
+		}
+		r.Converged, _ = ptypes.TimestampProto(now)
+		log.Infof("Canaries of %s converged, baking for %v", svc.Id, bake)
+		return next
+	}
+
+	converged, _ := ptypes.Timestamp(r.Converged)
+	if now.Sub(converged) < bake {
+		return nil
+	}
+	next.Config = r.Config
+	next.Rollout = nil
+	log.Infof("Promoted rollout of %s to every node: %v", svc.Id, next.Config.PrettyString())
+	return next
+}
+
+// canaryFailure returns why a canary failed since the rollout started, or empty if none have.
+func canaryFailure(serviceID string, r *types.Rollout, nodes []*types.Node, started time.Time) string {
+	for _, name := range r.CanaryNodes {
+		node := findNode(nodes, name)
+		if node == nil {
+			return fmt.Sprintf("canary %s is no longer running", name)
+		}
+		if node.Maintenance {
+			return fmt.Sprintf("canary %s is in maintenance", name)
+		}
+		if !syncedSince(node, started) {
+			continue
+		}
+		for _, failed := range node.FailedServices {
+			if failed == serviceID {
+				return fmt.Sprintf("canary %s failed to reconcile the service", name)
+			}
+		}
+	}
+	return ""
+}
+
+// canariesConverged returns true if every canary has synced without changes since the rollout started.
+func canariesConverged(r *types.Rollout, nodes []*types.Node, started time.Time) bool {
+	for _, name := range r.CanaryNodes {
+		node := findNode(nodes, name)
+		if node == nil || !syncedSince(node, started) || node.Drift > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func syncedSince(node *types.Node, t time.Time) bool {
+	lastSync, err := ptypes.Timestamp(node.LastSync)
+	return err == nil && lastSync.After(t)
+}
+
+func findNode(nodes []*types.Node, name string) *types.Node {
+	for _, node := range nodes {
+		if node.Name == name {
+			return node
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package rollout
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sky-uk/merlin/store"
+	"github.com/sky-uk/merlin/types"
+)
+
+func TestRollout(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Rollout Suite")
+}
+
+var _ = Describe("Check", func() {
+	var (
+		ctx     context.Context
+		st      store.Store
+		started time.Time
+		current *types.VirtualService_Config
+		next    *types.VirtualService_Config
+	)
+
+	putNode := func(name string, lastSync time.Time, drift uint32, failed ...string) {
+		ts, _ := ptypes.TimestampProto(lastSync)
+		node := &types.Node{Name: name, LastSync: ts, Drift: drift, FailedServices: failed}
+		Expect(st.PutNode(ctx, node, time.Minute)).To(Succeed())
+	}
+	service := func() *types.VirtualService {
+		svc, err := st.GetService(ctx, "svc1")
+		Expect(err).ToNot(HaveOccurred())
+		return svc
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		st = store.NewMemory()
+		started = time.Now().Add(-time.Hour)
+		current = &types.VirtualService_Config{Scheduler: "sh"}
+		next = &types.VirtualService_Config{Scheduler: "wrr"}
+		startedProto, _ := ptypes.TimestampProto(started)
+		Expect(st.PutService(ctx, &types.VirtualService{
+			Id:     "svc1",
+			Config: current,
+			Rollout: &types.Rollout{
+				Config:      next,
+				CanaryNodes: []string{"canary"},
+				State:       types.Rollout_CANARY,
+				Started:     startedProto,
+				Bake:        ptypes.DurationProto(time.Minute),
+				Timeout:     ptypes.DurationProto(5 * time.Minute),
+			},
+		})).To(Succeed())
+	})
+
+	It("should wait for the canaries to converge", func() {
+		putNode("canary", started.Add(-time.Second), 0)
+
+		Expect(Check(ctx, st, started.Add(time.Minute))).To(Succeed())
+
+		Expect(service().Rollout.State).To(Equal(types.Rollout_CANARY))
+		Expect(service().Rollout.Converged).To(BeNil())
+	})
+
+	It("should promote the config once the canaries have converged and baked", func() {
+		putNode("canary", started.Add(time.Second), 0)
+		converged := started.Add(time.Minute)
+
+		Expect(Check(ctx, st, converged)).To(Succeed())
+		Expect(service().Rollout.Converged).ToNot(BeNil())
+		Expect(Check(ctx, st, converged.Add(30*time.Second))).To(Succeed())
+		Expect(service().Rollout).ToNot(BeNil())
+		Expect(Check(ctx, st, converged.Add(time.Minute))).To(Succeed())
+
+		Expect(service().Rollout).To(BeNil())
+		Expect(service().Config.Scheduler).To(Equal("wrr"))
+	})
+
+	It("should halt if a canary fails to reconcile the service", func() {
+		putNode("canary", started.Add(time.Second), 1, "svc1")
+
+		Expect(Check(ctx, st, started.Add(time.Minute))).To(Succeed())
+
+		Expect(service().Rollout.State).To(Equal(types.Rollout_HALTED))
+		Expect(service().Rollout.Message).To(ContainSubstring("failed to reconcile"))
+		Expect(service().Config.Scheduler).To(Equal("sh"))
+	})
+
+	It("should halt if a canary stops running", func() {
+		Expect(Check(ctx, st, started.Add(time.Minute))).To(Succeed())
+
+		Expect(service().Rollout.State).To(Equal(types.Rollout_HALTED))
+		Expect(service().Rollout.Message).To(ContainSubstring("no longer running"))
+	})
+
+	It("should halt if the canaries don't converge in time", func() {
+		putNode("canary", started.Add(time.Second), 1)
+
+		Expect(Check(ctx, st, started.Add(time.Minute))).To(Succeed())
+		Expect(service().Rollout.State).To(Equal(types.Rollout_CANARY))
+		Expect(Check(ctx, st, started.Add(6*time.Minute))).To(Succeed())
+
+		Expect(service().Rollout.State).To(Equal(types.Rollout_HALTED))
+		Expect(service().Rollout.Message).To(ContainSubstring("didn't converge"))
+	})
+})
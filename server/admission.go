@@ -0,0 +1,41 @@
+package server
+
+import (
+	"context"
+
+	"github.com/sky-uk/merlin/types"
+)
+
+// AdmitFunc reviews the changes of a write before they're committed, returning the changes to make instead, or an
+// error to reject them. dryRun is true if the changes won't be committed.
+type AdmitFunc func(ctx context.Context, changes []*types.Change, dryRun bool) ([]*types.Change, error)
+
+// admit returns the changes to make after admission, which have the same actions and IDs as the given changes.
+// Changes mutated by admission are validated again.
+func (s *server) admit(ctx context.Context, dryRun bool, changes ...*types.Change) ([]*types.Change, error) {
+	if s.opts.Admit == nil || len(changes) == 0 {
+		return changes, nil
+	}
+	admitted, err := s.opts.Admit(ctx, changes, dryRun)
+	if err != nil {
+		return nil, err
+	}
+	for _, change := range admitted {
+		switch {
+		case change.Action == types.Change_DELETE:
+		case change.Service != nil:
+			if err := s.validService(change.Service); err != nil {
+				return nil, err
+			}
+		case change.Server != nil:
+			// ensure health check field always exists
+			if change.Server.HealthCheck == nil {
+				change.Server.HealthCheck = &types.RealServer_HealthCheck{}
+			}
+			if err := s.validServer(change.Server); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return admitted, nil
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"context"
+
+	"github.com/golang/protobuf/ptypes/wrappers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sky-uk/merlin/store"
+	"github.com/sky-uk/merlin/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var _ = Describe("Admission", func() {
+	var (
+		ctx      context.Context
+		st       store.Store
+		s        types.MerlinServer
+		reviewed [][]*types.Change
+		admit    func(changes []*types.Change) ([]*types.Change, error)
+		svc      *types.VirtualService
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		st = store.NewMemory()
+		reviewed = nil
+		s = New(st, nil, testNode, nil, nil, Options{
+			Admit: func(_ context.Context, changes []*types.Change, _ bool) ([]*types.Change, error) {
+				reviewed = append(reviewed, changes)
+				return admit(changes)
+			},
+		})
+		svc = testService("svc", "10.10.10.10", 80)
+	})
+
+	It("should commit admitted changes", func() {
+		admit = func(changes []*types.Change) ([]*types.Change, error) { return changes, nil }
+
+		_, err := s.CreateService(ctx, svc)
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(reviewed).To(HaveLen(1))
+		Expect(reviewed[0][0].Action).To(Equal(types.Change_CREATE))
+		Expect(st.GetService(ctx, "svc")).ToNot(BeNil())
+	})
+
+	It("should not commit denied changes", func() {
+		admit = func([]*types.Change) ([]*types.Change, error) {
+			return nil, status.Error(codes.PermissionDenied, "denied")
+		}
+
+		_, err := s.CreateService(ctx, svc)
+
+		Expect(status.Code(err)).To(Equal(codes.PermissionDenied))
+		Expect(st.GetService(ctx, "svc")).To(BeNil())
+	})
+
+	It("should commit mutated changes, once they're validated", func() {
+		admit = func(changes []*types.Change) ([]*types.Change, error) {
+			changes[0].Service.Config.Scheduler = "wrr"
+			return changes, nil
+		}
+		_, err := s.CreateService(ctx, svc)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(st.GetService(ctx, "svc")).To(WithTransform(func(svc *types.VirtualService) string {
+			return svc.Config.Scheduler
+		}, Equal("wrr")))
+
+		admit = func(changes []*types.Change) ([]*types.Change, error) {
+			changes[0].Server.Config.Weight = nil
+			return changes, nil
+		}
+		_, err = s.CreateServer(ctx, &types.RealServer{
+			ServiceID: "svc",
+			Key:       &types.RealServer_Key{Ip: "172.16.1.1", Port: 8080},
+			Config:    &types.RealServer_Config{Weight: &wrappers.UInt32Value{Value: 1}, Forward: types.ForwardMethod_ROUTE},
+		})
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+	})
+})
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	log "github.com/sirupsen/logrus"
+	"github.com/sky-uk/merlin/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *server) Apply(ctx context.Context, req *types.ApplyRequest) (*types.ApplyResponse, error) {
+	if atomic, _ := s.store.AtomicApply(); !atomic {
+		return nil, status.Error(codes.FailedPrecondition, "the store can't apply changes atomically, use etcd3")
+	}
+	for i, change := range req.Changes {
+		if (change.Service == nil) == (change.Server == nil) {
+			return nil, status.Errorf(codes.InvalidArgument, "change %d must have either a service or a server", i+1)
+		}
+		if change.Service != nil && change.Service.Id == "" {
+			return nil, status.Errorf(codes.InvalidArgument, "service of change %d must have an ID", i+1)
+		}
+		if change.Server != nil && change.Server.Key == nil {
+			return nil, status.Errorf(codes.InvalidArgument, "server of change %d must have a key", i+1)
+		}
+		// an IPAM would allocate IPs which are never released if a later change fails
+		if svc := change.Service; change.Action == types.Change_CREATE && s.opts.IPAM != nil && svc.Pool != "" &&
+			svc.GetKey().GetIp() == "" {
+			return nil, status.Errorf(codes.InvalidArgument,
+				"service %s of change %d must have an IP, as services can't be allocated IPs by Apply", svc.Id, i+1)
+		}
+	}
+
+	current, err := s.GetSnapshot(ctx, &empty.Empty{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read current state: %v", err)
+	}
+	// each change is made on a copy of the store, so it's checked against the changes before it
+	c, err := s.candidateServer(ctx, current)
+	if err != nil {
+		return nil, err
+	}
+	resp := &types.ApplyResponse{}
+	var failed *status.Status
+	for i, change := range req.Changes {
+		made, err := c.applyOne(ctx, change)
+		if err != nil {
+			st := status.Convert(err)
+			resp.Results = append(resp.Results, &types.ApplyResult{Code: st.Code().String(), Error: st.Message()})
+			if failed == nil {
+				failed = status.Newf(st.Code(), "change %d of %d failed, so none were applied: %s", i+1,
+					len(req.Changes), st.Message())
+			}
+			continue
+		}
+		resp.Results = append(resp.Results, &types.ApplyResult{Change: made, Code: codes.OK.String()})
+	}
+	if failed != nil {
+		if detailed, err := failed.WithDetails(resp); err == nil {
+			failed = detailed
+		}
+		return nil, failed.Err()
+	}
+
+	staged, err := c.GetSnapshot(ctx, &empty.Empty{})
+	if err != nil {
+		return nil, err
+	}
+	// only what the changes made is applied, so other writes made since reading the store are kept. It isn't
+	// isolated from them though: one to a key the changes touch is overwritten, and the changes were checked
+	// against the store before it.
+	if _, err := s.applyChanges(ctx, snapshotChanges(current, staged, true), req.DryRun, 0); err != nil {
+		return nil, err
+	}
+	if !req.DryRun {
+		log.Infof("Applied %d changes", len(req.Changes))
+	}
+	return resp, nil
+}
+
+// applyOne makes a change as the RPC of its action would, returning the change made.
+func (s *server) applyOne(ctx context.Context, change *types.Change) (*types.Change, error) {
+	if change.Service != nil {
+		svc := proto.Clone(change.Service).(*types.VirtualService)
+		var err error
+		switch change.Action {
+		case types.Change_CREATE:
+			_, err = s.CreateService(ctx, svc)
+		case types.Change_UPDATE:
+			if svc.Config == nil {
+				svc.Config = &types.VirtualService_Config{}
+			}
+			_, err = s.UpdateService(ctx, svc)
+		case types.Change_DELETE:
+			_, err = s.DeleteService(ctx, &wrappers.StringValue{Value: svc.Id})
+			return &types.Change{Action: change.Action, Service: &types.VirtualService{Id: svc.Id}}, err
+		default:
+			return nil, status.Errorf(codes.InvalidArgument, "unknown action %v", change.Action)
+		}
+		if err != nil {
+			return nil, err
+		}
+		made, err := s.store.GetService(ctx, svc.Id)
+		return &types.Change{Action: change.Action, Service: made}, err
+	}
+
+	server := proto.Clone(change.Server).(*types.RealServer)
+	var err error
+	switch change.Action {
+	case types.Change_CREATE:
+		_, err = s.CreateServer(ctx, server)
+	case types.Change_UPDATE:
+		if server.Config == nil {
+			server.Config = &types.RealServer_Config{}
+		}
+		_, err = s.UpdateServer(ctx, server)
+	case types.Change_DELETE:
+		_, err = s.DeleteServer(ctx, server)
+		key := &types.RealServer{ServiceID: server.ServiceID, Key: server.Key}
+		return &types.Change{Action: change.Action, Server: key}, err
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "unknown action %v", change.Action)
+	}
+	if err != nil {
+		return nil, err
+	}
+	made, err := s.store.GetServer(ctx, server.ServiceID, server.Key)
+	return &types.Change{Action: change.Action, Server: made}, err
+}
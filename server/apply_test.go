@@ -0,0 +1,146 @@
+package server
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sky-uk/merlin/store"
+	"github.com/sky-uk/merlin/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var _ = Describe("Apply", func() {
+	var (
+		ctx = context.Background()
+		st  *applyCountingStore
+		s   types.MerlinServer
+	)
+
+	BeforeEach(func() {
+		st = &applyCountingStore{Store: store.NewMemory()}
+		s = New(st, nil, testNode, nil, nil, Options{})
+		_, err := s.CreateService(ctx, testService("old", "10.10.10.10", 80))
+		Expect(err).ToNot(HaveOccurred())
+		_, err = s.CreateServer(ctx, testServer("old", "172.16.1.1"))
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should make every change in a single write", func() {
+		resp, err := s.Apply(ctx, &types.ApplyRequest{Changes: []*types.Change{
+			{Action: types.Change_CREATE, Service: testService("web", "10.10.10.10", 81)},
+			{Action: types.Change_CREATE, Server: testServer("web", "172.16.2.1")},
+			{Action: types.Change_CREATE, Server: testServer("web", "172.16.2.2")},
+			{Action: types.Change_DELETE, Server: &types.RealServer{ServiceID: "old",
+				Key: &types.RealServer_Key{Ip: "172.16.1.1", Port: 8080}}},
+		}})
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.Results).To(HaveLen(4))
+		for _, result := range resp.Results {
+			Expect(result.Code).To(Equal("OK"))
+		}
+		Expect(st.batches).To(HaveLen(1))
+		Expect(st.ListServers(ctx, "web")).To(HaveLen(2))
+		Expect(st.ListServers(ctx, "old")).To(BeEmpty())
+	})
+
+	It("should merge updates as UpdateService does", func() {
+		resp, err := s.Apply(ctx, &types.ApplyRequest{Changes: []*types.Change{{
+			Action:  types.Change_UPDATE,
+			Service: &types.VirtualService{Id: "old", Config: &types.VirtualService_Config{Scheduler: "wrr"}},
+		}}})
+
+		Expect(err).ToNot(HaveOccurred())
+		updated, err := st.GetService(ctx, "old")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(updated.Config.Scheduler).To(Equal("wrr"))
+		Expect(updated.Key.Port).To(Equal(uint32(80)))
+		Expect(resp.Results[0].Change.Service.Key.Port).To(Equal(uint32(80)))
+	})
+
+	It("should make no changes if any fails, with the result of each in the details", func() {
+		_, err := s.Apply(ctx, &types.ApplyRequest{Changes: []*types.Change{
+			{Action: types.Change_CREATE, Service: testService("web", "10.10.10.10", 81)},
+			{Action: types.Change_CREATE, Server: testServer("web", "172.16.2.1")},
+			{Action: types.Change_CREATE, Server: testServer("missing", "172.16.2.2")},
+		}})
+
+		failed, _ := status.FromError(err)
+		Expect(failed.Code()).To(Equal(codes.NotFound))
+		Expect(failed.Message()).To(HavePrefix("change 3 of 3 failed, so none were applied"))
+		Expect(failed.Details()).To(HaveLen(1))
+		results := failed.Details()[0].(*types.ApplyResponse).Results
+		Expect(results).To(HaveLen(3))
+		Expect(results[0].Code).To(Equal("OK"))
+		Expect(results[1].Code).To(Equal("OK"))
+		Expect(results[2].Code).To(Equal("NotFound"))
+		Expect(results[2].Error).To(ContainSubstring("missing"))
+		Expect(st.GetService(ctx, "web")).To(BeNil())
+		Expect(st.batches).To(BeEmpty())
+	})
+
+	It("should check each change against the changes before it", func() {
+		_, err := s.Apply(ctx, &types.ApplyRequest{Changes: []*types.Change{
+			{Action: types.Change_CREATE, Service: testService("web", "10.10.10.10", 81)},
+			{Action: types.Change_CREATE, Service: testService("web", "10.10.10.10", 82)},
+		}})
+
+		Expect(status.Code(err)).To(Equal(codes.AlreadyExists))
+	})
+
+	It("should not make the changes of a dry run", func() {
+		resp, err := s.Apply(ctx, &types.ApplyRequest{
+			Changes: []*types.Change{{Action: types.Change_CREATE, Service: testService("web", "10.10.10.10", 81)}},
+			DryRun:  true,
+		})
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.Results[0].Change.Service.Id).To(Equal("web"))
+		Expect(st.GetService(ctx, "web")).To(BeNil())
+	})
+
+	It("should reject services without an ID and servers without a key", func() {
+		_, err := s.Apply(ctx, &types.ApplyRequest{Changes: []*types.Change{
+			{Action: types.Change_DELETE, Service: &types.VirtualService{}},
+		}})
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+
+		_, err = s.Apply(ctx, &types.ApplyRequest{Changes: []*types.Change{
+			{Action: types.Change_DELETE, Server: &types.RealServer{ServiceID: "old"}},
+		}})
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+	})
+
+	It("should reject deleting a server without a key", func() {
+		_, err := s.DeleteServer(ctx, &types.RealServer{ServiceID: "old"})
+
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+		Expect(st.ListServers(ctx, "old")).To(HaveLen(1))
+	})
+
+	It("should reject changes if the store can't apply them atomically", func() {
+		st.nonAtomic = true
+
+		_, err := s.Apply(ctx, &types.ApplyRequest{Changes: []*types.Change{
+			{Action: types.Change_CREATE, Service: testService("web", "10.10.10.10", 81)},
+		}})
+
+		Expect(status.Code(err)).To(Equal(codes.FailedPrecondition))
+		Expect(st.batches).To(BeEmpty())
+	})
+
+	It("should reject more changes than the store can make at once, counting the servers of deleted services", func() {
+		st.limit = 1
+
+		_, err := s.Apply(ctx, &types.ApplyRequest{Changes: []*types.Change{
+			{Action: types.Change_DELETE, Service: &types.VirtualService{Id: "old"}},
+		}})
+
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+		Expect(err.Error()).To(ContainSubstring("more than the 1"))
+		Expect(st.batches).To(BeEmpty())
+		Expect(st.ListServers(ctx, "old")).To(HaveLen(1))
+	})
+})
@@ -0,0 +1,39 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/sky-uk/merlin/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *server) ListAuditEvents(ctx context.Context, req *types.ListAuditEventsRequest) (
+	*types.ListAuditEventsResponse, error) {
+	var since int64
+	if req.Since != nil {
+		t, err := ptypes.Timestamp(req.Since)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid since: %v", err)
+		}
+		since = t.UnixNano()
+	}
+	events, err := s.store.ListAuditEvents(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit events: %v", err)
+	}
+
+	resp := &types.ListAuditEventsResponse{}
+	for _, event := range events {
+		t, _ := ptypes.Timestamp(event.Time)
+		if t.UnixNano() >= since {
+			resp.Events = append(resp.Events, event)
+		}
+	}
+	if req.Limit > 0 && len(resp.Events) > int(req.Limit) {
+		resp.Events = resp.Events[len(resp.Events)-int(req.Limit):]
+	}
+	return resp, nil
+}
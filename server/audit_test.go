@@ -0,0 +1,52 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sky-uk/merlin/store"
+	"github.com/sky-uk/merlin/types"
+)
+
+var _ = Describe("ListAuditEvents", func() {
+	var (
+		ctx   = context.Background()
+		s     types.MerlinServer
+		start = time.Now().Add(-time.Hour)
+	)
+
+	BeforeEach(func() {
+		st := store.NewMemory()
+		s = New(st, nil, testNode, nil, nil, Options{})
+		for i, method := range []string{"CreateService", "UpdateService", "DeleteService"} {
+			ts, _ := ptypes.TimestampProto(start.Add(time.Duration(i) * time.Minute))
+			Expect(st.AddAuditEvent(ctx, &types.AuditEvent{Time: ts, Node: "node", Method: method}, time.Hour)).
+				To(Succeed())
+		}
+	})
+
+	methods := func(req *types.ListAuditEventsRequest) []string {
+		resp, err := s.ListAuditEvents(ctx, req)
+		Expect(err).ToNot(HaveOccurred())
+		var methods []string
+		for _, event := range resp.Events {
+			methods = append(methods, event.Method)
+		}
+		return methods
+	}
+
+	It("should list events oldest first", func() {
+		Expect(methods(&types.ListAuditEventsRequest{})).To(Equal([]string{"CreateService", "UpdateService",
+			"DeleteService"}))
+	})
+
+	It("should list the most recent events since a time", func() {
+		since, _ := ptypes.TimestampProto(start.Add(time.Minute))
+		Expect(methods(&types.ListAuditEventsRequest{Since: since})).To(Equal([]string{"UpdateService",
+			"DeleteService"}))
+		Expect(methods(&types.ListAuditEventsRequest{Limit: 1})).To(Equal([]string{"DeleteService"}))
+	})
+})
@@ -0,0 +1,356 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"reflect"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/empty"
+	log "github.com/sirupsen/logrus"
+	"github.com/sky-uk/merlin/store"
+	"github.com/sky-uk/merlin/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// candidateMethods can be called with the candidate header, mapped to whether they change the candidate. Reads
+// see the candidate config instead of the store.
+var candidateMethods = map[string]bool{
+	"CreateService": true,
+	"UpdateService": true,
+	"DeleteService": true,
+	"CloneService":  true,
+	"RenameService": true,
+	"SwapServers":   true,
+	"CreateServer":  true,
+	"UpdateServer":  true,
+	"DeleteServer":  true,
+	"DrainServer":   true,
+	"UndrainServer": true,
+	"ApplySnapshot": true,
+	"Apply":         true,
+	"List":          false,
+	"GetService":    false,
+	"GetServer":     false,
+	"GetSnapshot":   false,
+}
+
+// StageCandidate returns an interceptor which stages requests with the types.CandidateHeader in the candidate
+// config of srv, which must have been returned by New, instead of making them.
+func StageCandidate(srv types.MerlinServer) grpc.UnaryServerInterceptor {
+	s := srv.(*server)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+		if !types.IsCandidate(ctx) {
+			return handler(ctx, req)
+		}
+		return s.stage(ctx, path.Base(info.FullMethod), req)
+	}
+}
+
+// stage calls method on a copy of the candidate config, which starts as a copy of the store, saving the result
+// as the new candidate if the method changes it.
+func (s *server) stage(ctx context.Context, method string, req interface{}) (interface{}, error) {
+	write, ok := candidateMethods[method]
+	if !ok {
+		return nil, status.Errorf(codes.FailedPrecondition, "%s can't be staged in the candidate config", method)
+	}
+	// an IPAM would allocate IPs which are never released if the candidate is discarded
+	if svc, ok := req.(*types.VirtualService); ok && method == "CreateService" && s.opts.IPAM != nil &&
+		svc.Pool != "" && svc.GetKey().GetIp() == "" {
+		return nil, status.Error(codes.FailedPrecondition,
+			"services can't be allocated IPs by the IPAM in the candidate config, set their IP")
+	}
+	s.candidateMu.Lock()
+	defer s.candidateMu.Unlock()
+
+	cand, err := s.store.GetCandidate(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get candidate: %v", err)
+	}
+	if write && cand.Committing {
+		return nil, errCommitting
+	}
+	staged := cand.Staged
+	if staged == nil {
+		if staged, err = s.GetSnapshot(ctx, &empty.Empty{}); err != nil {
+			return nil, fmt.Errorf("failed to read current state: %v", err)
+		}
+	}
+	c, err := s.candidateServer(ctx, staged)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.call(ctx, method, req)
+	if err != nil || !write {
+		return resp, err
+	}
+	if cand.Staged, err = c.GetSnapshot(ctx, &empty.Empty{}); err != nil {
+		return nil, err
+	}
+	if err := s.putCandidate(ctx, cand, "failed to stage "+method); err != nil {
+		return nil, err
+	}
+	log.Infof("Staged %s in the candidate config", method)
+	return resp, nil
+}
+
+// errCommitting is returned when the candidate can't be changed as a commit of it hasn't finished. A commit with a
+// confirm timeout is then rolled back at its deadline, and one without can be committed again.
+var errCommitting = status.Error(codes.FailedPrecondition,
+	"the candidate config is being committed, or its commit failed and is rolled back or must be committed again")
+
+// putCandidate stores the candidate if it hasn't changed since it was read, as candidateMu doesn't serialize writes
+// made by other nodes. It fails with Aborted if it has, so the write can be retried, prefixing errors with failure.
+func (s *server) putCandidate(ctx context.Context, cand *types.Candidate, failure string) error {
+	err := s.store.PutCandidate(ctx, cand)
+	if err == store.ErrCandidateChanged {
+		return status.Errorf(codes.Aborted, "%s, as the candidate config was changed at the same time", failure)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %v", failure, err)
+	}
+	return nil
+}
+
+// call calls method of the server with req, returning its response.
+func (s *server) call(ctx context.Context, method string, req interface{}) (interface{}, error) {
+	out := reflect.ValueOf(s).MethodByName(method).Call([]reflect.Value{reflect.ValueOf(ctx), reflect.ValueOf(req)})
+	err, _ := out[1].Interface().(error)
+	return out[0].Interface(), err
+}
+
+// candidateServer returns a server backed by a copy of the staged config and the pools of the store. It isn't
+// admitted and has no IPAM, as those apply when the candidate is committed.
+func (s *server) candidateServer(ctx context.Context, staged *types.Snapshot) (*server, error) {
+	st := store.NewMemory()
+	var changes []*types.Change
+	for _, svc := range staged.Services {
+		changes = append(changes, &types.Change{Action: types.Change_CREATE, Service: svc})
+	}
+	for _, server := range staged.Servers {
+		changes = append(changes, &types.Change{Action: types.Change_CREATE, Server: server})
+	}
+	if err := st.Apply(ctx, changes); err != nil {
+		return nil, err
+	}
+	pools, err := s.store.ListPools(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pools: %v", err)
+	}
+	for _, pool := range pools {
+		if err := st.PutPool(ctx, pool); err != nil {
+			return nil, err
+		}
+	}
+
+	opts := s.opts
+	opts.Admit = nil
+	opts.IPAM = nil
+	return New(st, s.ipvs, s.node, s.health, s.errors, opts).(*server), nil
+}
+
+func (s *server) GetCandidate(ctx context.Context, _ *empty.Empty) (*types.GetCandidateResponse, error) {
+	cand, err := s.store.GetCandidate(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get candidate: %v", err)
+	}
+	resp := &types.GetCandidateResponse{ConfirmBy: cand.ConfirmBy}
+	if cand.Staged != nil {
+		if resp.Changes, err = s.diffSnapshot(ctx, cand.Staged, true); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+func (s *server) Commit(ctx context.Context, req *types.CommitRequest) (*types.CommitResponse, error) {
+	var timeout time.Duration
+	if req.ConfirmTimeout != nil {
+		var err error
+		if timeout, err = ptypes.Duration(req.ConfirmTimeout); err != nil || timeout <= 0 {
+			return nil, status.Error(codes.InvalidArgument, "confirm timeout must be positive")
+		}
+	}
+	s.candidateMu.Lock()
+	defer s.candidateMu.Unlock()
+
+	cand, err := s.store.GetCandidate(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get candidate: %v", err)
+	}
+	if cand.Staged == nil {
+		return nil, status.Error(codes.FailedPrecondition, "nothing is staged in the candidate config")
+	}
+	if cand.ConfirmBy != nil {
+		return nil, status.Error(codes.FailedPrecondition,
+			"the last commit must be confirmed or rolled back before committing again")
+	}
+	if req.DryRun {
+		applied, err := s.applySnapshot(ctx, &types.ApplySnapshotRequest{
+			Snapshot: cand.Staged,
+			Prune:    true,
+			DryRun:   true,
+		}, 0)
+		if err != nil {
+			return nil, err
+		}
+		return &types.CommitResponse{Changes: applied.Changes}, nil
+	}
+
+	// the rollback is saved before anything is applied, so a commit is never live without it
+	cand.Committing = true
+	if timeout > 0 {
+		if cand.Rollback, err = s.GetSnapshot(ctx, &empty.Empty{}); err != nil {
+			return nil, fmt.Errorf("failed to read current state: %v", err)
+		}
+		cand.ConfirmBy, _ = ptypes.TimestampProto(time.Now().Add(timeout))
+	}
+	if err := s.putCandidate(ctx, cand, "failed to start commit"); err != nil {
+		return nil, err
+	}
+
+	applied, err := s.applySnapshot(ctx, &types.ApplySnapshotRequest{Snapshot: cand.Staged, Prune: true}, 0)
+	if err != nil {
+		// a partial commit on a store which can't apply atomically keeps its rollback, which restores the state
+		// before it
+		if atomic, _ := s.store.AtomicApply(); atomic {
+			cand.Committing, cand.Rollback, cand.ConfirmBy = false, nil, nil
+			if err := s.store.PutCandidate(ctx, cand); err != nil {
+				log.Warnf("Unable to clear the rollback of a failed commit: %v", err)
+			}
+		}
+		return nil, err
+	}
+	resp := &types.CommitResponse{Changes: applied.Changes}
+
+	cand.Staged, cand.Committing = nil, false
+	if err := s.store.PutCandidate(ctx, cand); err != nil {
+		// the changes are live, so retrying isn't safe, and an unconfirmed commit is still rolled back
+		armed := "no rollback was requested"
+		if timeout > 0 {
+			armed = "its rollback is armed"
+		}
+		return nil, status.Errorf(codes.Internal, "committed %d changes and %s, but failed to clear the candidate: %v",
+			len(resp.Changes), armed, err)
+	}
+	if timeout > 0 {
+		log.Infof("Committed %d changes, which are rolled back unless confirmed within %v", len(resp.Changes),
+			timeout)
+	} else {
+		log.Infof("Committed %d changes", len(resp.Changes))
+	}
+	return resp, nil
+}
+
+func (s *server) ConfirmCommit(ctx context.Context, _ *empty.Empty) (*empty.Empty, error) {
+	s.candidateMu.Lock()
+	defer s.candidateMu.Unlock()
+
+	cand, err := s.store.GetCandidate(ctx)
+	if err != nil {
+		return emptyResponse, fmt.Errorf("failed to get candidate: %v", err)
+	}
+	if cand.ConfirmBy == nil {
+		return emptyResponse, status.Error(codes.FailedPrecondition, "no commit is waiting to be confirmed")
+	}
+	if cand.Committing {
+		return emptyResponse, errCommitting
+	}
+	// once the deadline has passed the commit is rolled back, even if that hasn't happened yet
+	confirmBy, _ := ptypes.Timestamp(cand.ConfirmBy)
+	if !time.Now().Before(confirmBy) {
+		return emptyResponse, status.Errorf(codes.FailedPrecondition,
+			"the commit wasn't confirmed by %v, so it's being rolled back", confirmBy.Format(time.RFC3339))
+	}
+	cand.Rollback, cand.ConfirmBy = nil, nil
+	if err := s.putCandidate(ctx, cand, "failed to confirm commit"); err != nil {
+		return emptyResponse, err
+	}
+	log.Infof("Confirmed commit")
+	return emptyResponse, nil
+}
+
+func (s *server) DiscardCandidate(ctx context.Context, _ *empty.Empty) (*empty.Empty, error) {
+	s.candidateMu.Lock()
+	defer s.candidateMu.Unlock()
+
+	cand, err := s.store.GetCandidate(ctx)
+	if err != nil {
+		return emptyResponse, fmt.Errorf("failed to get candidate: %v", err)
+	}
+	if cand.Staged == nil {
+		return emptyResponse, nil
+	}
+	if cand.Committing {
+		return emptyResponse, errCommitting
+	}
+	cand.Staged = nil
+	if err := s.putCandidate(ctx, cand, "failed to discard candidate"); err != nil {
+		return emptyResponse, err
+	}
+	log.Infof("Discarded the candidate config")
+	return emptyResponse, nil
+}
+
+// CheckCommit rolls back the last commit at time now, if it was made with a confirm timeout which has passed
+// without it being confirmed. Nodes checking at the same time make the same changes, but with leader election only
+// the leader checks. node returns the node the rollback is recorded in the history of services as made by.
+func CheckCommit(ctx context.Context, st store.Store, node func() *types.Node, now time.Time) error {
+	cand, err := st.GetCandidate(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to get candidate: %v", err)
+	}
+	if cand.ConfirmBy == nil {
+		return nil
+	}
+	confirmBy, _ := ptypes.Timestamp(cand.ConfirmBy)
+	if now.Before(confirmBy) {
+		return nil
+	}
+
+	rollback := cand.Rollback
+	if rollback == nil {
+		rollback = &types.Snapshot{}
+	}
+	// the rollback restores what was there before, so it isn't admitted, checked against the rules or limited.
+	// It's a single write if it fits in one, and otherwise is made in batches, as failing would retry it forever.
+	s := New(st, nil, node, nil, nil, Options{}).(*server)
+	_, batchSize := st.AtomicApply()
+	resp, err := s.applySnapshot(ctx, &types.ApplySnapshotRequest{Snapshot: rollback, Prune: true}, batchSize)
+	if err != nil {
+		return fmt.Errorf("unable to roll back unconfirmed commit: %v", err)
+	}
+	if err := clearRollback(ctx, st, cand); err != nil {
+		return fmt.Errorf("rolled back unconfirmed commit, but unable to clear it: %v", err)
+	}
+	log.Warnf("Rolled back %d changes of the last commit, as it wasn't confirmed by %v", len(resp.Changes),
+		confirmBy.Format(time.RFC3339))
+	return nil
+}
+
+// clearRollback clears the rollback of cand once it's been applied, so it isn't applied again, pruning writes made
+// since. If the candidate was changed meanwhile, such as by staging a write, it's read again and cleared if it still
+// has the same rollback.
+func clearRollback(ctx context.Context, st store.Store, cand *types.Candidate) error {
+	rollback, confirmBy := cand.Rollback, cand.ConfirmBy
+	for {
+		cand.Rollback, cand.ConfirmBy, cand.Committing = nil, nil, false
+		err := st.PutCandidate(ctx, cand)
+		if err != store.ErrCandidateChanged {
+			return err
+		}
+		if cand, err = st.GetCandidate(ctx); err != nil {
+			return err
+		}
+		if !proto.Equal(cand.ConfirmBy, confirmBy) || !proto.Equal(cand.Rollback, rollback) {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sky-uk/merlin/store"
+	"github.com/sky-uk/merlin/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// racingStore changes the candidate once right after it's read, as another node staging a write at the same time
+// would.
+type racingStore struct {
+	store.Store
+	raced bool
+}
+
+func (s *racingStore) GetCandidate(ctx context.Context) (*types.Candidate, error) {
+	cand, err := s.Store.GetCandidate(ctx)
+	if err != nil || s.raced {
+		return cand, err
+	}
+	s.raced = true
+	return cand, s.Store.PutCandidate(ctx, proto.Clone(cand).(*types.Candidate))
+}
+
+// candidateWriteStore calls before ahead of each candidate write, failing the write if it returns an error.
+type candidateWriteStore struct {
+	store.Store
+	writes int
+	before func(ctx context.Context, write int) error
+}
+
+func (s *candidateWriteStore) PutCandidate(ctx context.Context, c *types.Candidate) error {
+	s.writes++
+	if err := s.before(ctx, s.writes); err != nil {
+		return err
+	}
+	return s.Store.PutCandidate(ctx, c)
+}
+
+var _ = Describe("Candidate", func() {
+	var (
+		ctx context.Context
+		st  store.Store
+		s   types.MerlinServer
+	)
+
+	stage := func(method string, req interface{}) (interface{}, error) {
+		candidateCtx := metadata.NewIncomingContext(ctx, metadata.Pairs(types.CandidateHeader, "true"))
+		info := &grpc.UnaryServerInfo{FullMethod: "/types.Merlin/" + method}
+		return StageCandidate(s)(candidateCtx, req, info, func(context.Context, interface{}) (interface{}, error) {
+			Fail("request was made instead of staged")
+			return nil, nil
+		})
+	}
+	ids := func() []string {
+		svcs, err := st.ListServices(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		var ids []string
+		for _, svc := range svcs {
+			ids = append(ids, svc.Id)
+		}
+		return ids
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		st = store.NewMemory()
+		s = New(st, nil, testNode, nil, nil, Options{})
+		_, err := s.CreateService(ctx, testService("old", "10.0.0.1", 80))
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should stage writes until they're committed", func() {
+		_, err := stage("CreateService", testService("new", "10.0.0.2", 80))
+		Expect(err).ToNot(HaveOccurred())
+		_, err = stage("DeleteService", &wrappers.StringValue{Value: "old"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ids()).To(Equal([]string{"old"}))
+
+		list, err := stage("List", &types.ListRequest{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(list.(*types.ListResponse).Items).To(HaveLen(1))
+		Expect(list.(*types.ListResponse).Items[0].Service.Id).To(Equal("new"))
+
+		cand, err := s.GetCandidate(ctx, &empty.Empty{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cand.Changes).To(HaveLen(2))
+
+		resp, err := s.Commit(ctx, &types.CommitRequest{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.Changes).To(HaveLen(2))
+		Expect(ids()).To(Equal([]string{"new"}))
+
+		_, err = s.Commit(ctx, &types.CommitRequest{})
+		Expect(status.Code(err)).To(Equal(codes.FailedPrecondition), "the candidate is cleared")
+	})
+
+	It("should validate staged writes", func() {
+		_, err := stage("CreateService", testService("old", "10.0.0.2", 80))
+		Expect(status.Code(err)).To(Equal(codes.AlreadyExists))
+	})
+
+	It("should refuse to stage other writes", func() {
+		_, err := stage("SetMaintenance", &types.SetMaintenanceRequest{Node: "node", Enabled: true})
+		Expect(status.Code(err)).To(Equal(codes.FailedPrecondition))
+	})
+
+	It("should refuse to stage a write if the candidate is changed at the same time", func() {
+		s = New(&racingStore{Store: st}, nil, testNode, nil, nil, Options{})
+
+		_, err := stage("CreateService", testService("new", "10.0.0.2", 80))
+		Expect(status.Code(err)).To(Equal(codes.Aborted))
+
+		_, err = stage("CreateService", testService("new", "10.0.0.2", 80))
+		Expect(err).ToNot(HaveOccurred(), "a retry succeeds")
+	})
+
+	It("should refuse to commit more changes than the store can make at once", func() {
+		_, err := stage("CreateService", testService("new", "10.0.0.2", 80))
+		Expect(err).ToNot(HaveOccurred())
+		_, err = stage("DeleteService", &wrappers.StringValue{Value: "old"})
+		Expect(err).ToNot(HaveOccurred())
+		s = New(&applyCountingStore{Store: st, limit: 1}, nil, testNode, nil, nil, Options{})
+
+		_, err = s.Commit(ctx, &types.CommitRequest{})
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+		Expect(ids()).To(Equal([]string{"old"}))
+		cand, err := s.GetCandidate(ctx, &empty.Empty{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cand.Changes).To(HaveLen(2), "the candidate is kept")
+	})
+
+	It("should roll back in batches of what the store can make at once", func() {
+		_, err := stage("CreateService", testService("new", "10.0.0.2", 80))
+		Expect(err).ToNot(HaveOccurred())
+		_, err = stage("DeleteService", &wrappers.StringValue{Value: "old"})
+		Expect(err).ToNot(HaveOccurred())
+		_, err = s.Commit(ctx, &types.CommitRequest{ConfirmTimeout: ptypes.DurationProto(time.Minute)})
+		Expect(err).ToNot(HaveOccurred())
+
+		limited := &applyCountingStore{Store: st, limit: 1}
+		Expect(CheckCommit(ctx, limited, testNode, time.Now().Add(time.Hour))).To(Succeed())
+		Expect(limited.batches).To(HaveLen(2))
+		Expect(ids()).To(Equal([]string{"old"}))
+	})
+
+	It("should refuse a commit without applying it if the candidate changes before it starts", func() {
+		_, err := stage("DeleteService", &wrappers.StringValue{Value: "old"})
+		Expect(err).ToNot(HaveOccurred())
+		s = New(&racingStore{Store: st}, nil, testNode, nil, nil, Options{})
+
+		_, err = s.Commit(ctx, &types.CommitRequest{ConfirmTimeout: ptypes.DurationProto(time.Minute)})
+		Expect(status.Code(err)).To(Equal(codes.Aborted))
+		Expect(ids()).To(Equal([]string{"old"}))
+	})
+
+	It("should keep the rollback of a commit whose candidate can't be cleared", func() {
+		_, err := stage("DeleteService", &wrappers.StringValue{Value: "old"})
+		Expect(err).ToNot(HaveOccurred())
+		s = New(&candidateWriteStore{Store: st, before: func(_ context.Context, write int) error {
+			if write == 2 {
+				return errors.New("etcd timed out")
+			}
+			return nil
+		}}, nil, testNode, nil, nil, Options{})
+
+		_, err = s.Commit(ctx, &types.CommitRequest{ConfirmTimeout: ptypes.DurationProto(time.Minute)})
+		Expect(status.Code(err)).To(Equal(codes.Internal), "the commit isn't retried")
+		Expect(err.Error()).To(ContainSubstring("rollback is armed"))
+		Expect(ids()).To(BeEmpty())
+
+		Expect(CheckCommit(ctx, st, testNode, time.Now().Add(time.Hour))).To(Succeed())
+		Expect(ids()).To(Equal([]string{"old"}))
+	})
+
+	It("should discard the candidate", func() {
+		_, err := stage("DeleteService", &wrappers.StringValue{Value: "old"})
+		Expect(err).ToNot(HaveOccurred())
+		_, err = s.DiscardCandidate(ctx, &empty.Empty{})
+		Expect(err).ToNot(HaveOccurred())
+
+		cand, err := s.GetCandidate(ctx, &empty.Empty{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cand.Changes).To(BeEmpty())
+		Expect(ids()).To(Equal([]string{"old"}))
+	})
+
+	Context("with a confirm timeout", func() {
+		var confirmBy time.Time
+
+		BeforeEach(func() {
+			_, err := stage("DeleteService", &wrappers.StringValue{Value: "old"})
+			Expect(err).ToNot(HaveOccurred())
+			_, err = s.Commit(ctx, &types.CommitRequest{ConfirmTimeout: ptypes.DurationProto(time.Minute)})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ids()).To(BeEmpty())
+
+			cand, err := s.GetCandidate(ctx, &empty.Empty{})
+			Expect(err).ToNot(HaveOccurred())
+			confirmBy, err = ptypes.Timestamp(cand.ConfirmBy)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("should roll back the commit unless it's confirmed in time", func() {
+			Expect(CheckCommit(ctx, st, testNode, confirmBy.Add(-time.Second))).To(Succeed())
+			Expect(ids()).To(BeEmpty())
+
+			Expect(CheckCommit(ctx, st, testNode, confirmBy)).To(Succeed())
+			Expect(ids()).To(Equal([]string{"old"}))
+			_, err := s.ConfirmCommit(ctx, &empty.Empty{})
+			Expect(status.Code(err)).To(Equal(codes.FailedPrecondition))
+		})
+
+		It("should keep confirmed commits", func() {
+			_, err := s.ConfirmCommit(ctx, &empty.Empty{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(CheckCommit(ctx, st, testNode, confirmBy.Add(time.Hour))).To(Succeed())
+			Expect(ids()).To(BeEmpty())
+		})
+
+		It("should clear the rollback if the candidate changes as it's rolled back, keeping later writes", func() {
+			raced := &candidateWriteStore{Store: st, before: func(ctx context.Context, write int) error {
+				if write == 1 {
+					// another node stages a write as the rollback is applied
+					cand, err := st.GetCandidate(ctx)
+					Expect(err).ToNot(HaveOccurred())
+					cand.Staged = &types.Snapshot{}
+					Expect(st.PutCandidate(ctx, cand)).To(Succeed())
+				}
+				return nil
+			}}
+
+			Expect(CheckCommit(ctx, raced, testNode, confirmBy)).To(Succeed())
+			Expect(ids()).To(Equal([]string{"old"}))
+			cand, err := st.GetCandidate(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cand.ConfirmBy).To(BeNil())
+			Expect(cand.Staged).ToNot(BeNil(), "the staged write is kept")
+
+			_, err = s.CreateService(ctx, testService("later", "10.0.0.3", 80))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(CheckCommit(ctx, st, testNode, confirmBy.Add(time.Hour))).To(Succeed())
+			Expect(ids()).To(Equal([]string{"later", "old"}))
+		})
+
+		It("should refuse to commit again until the commit is confirmed", func() {
+			_, err := stage("CreateService", testService("new", "10.0.0.2", 80))
+			Expect(err).ToNot(HaveOccurred())
+			_, err = s.Commit(ctx, &types.CommitRequest{})
+			Expect(status.Code(err)).To(Equal(codes.FailedPrecondition))
+		})
+	})
+})
@@ -0,0 +1,222 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/empty"
+	log "github.com/sirupsen/logrus"
+	"github.com/sky-uk/merlin/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *server) CloneService(ctx context.Context, req *types.CloneServiceRequest) (*empty.Empty, error) {
+	svc, servers, err := s.copyService(ctx, req.Id, req.NewId)
+	if err != nil {
+		return emptyResponse, err
+	}
+
+	// the same key in IPVS can't belong to two services
+	prevKey := proto.Clone(svc.Key)
+	if ip := req.GetKey().GetIp(); ip != "" {
+		svc.Key.Ip = ip
+	}
+	if port := req.GetKey().GetPort(); port != 0 {
+		svc.Key.Port = port
+	}
+	if protocol := req.GetKey().GetProtocol(); protocol != types.Protocol_UNSET_PROTOCOL {
+		svc.Key.Protocol = protocol
+	}
+	if proto.Equal(svc.Key, prevKey) {
+		return emptyResponse, status.Errorf(codes.InvalidArgument,
+			"clone of %s requires a different ip, port or protocol", req.Id)
+	}
+	if err := s.validService(svc); err != nil {
+		return emptyResponse, err
+	}
+
+	changes := createChanges(svc, servers)
+	if changes, err = s.admit(ctx, false, changes...); err != nil {
+		return emptyResponse, err
+	}
+	if err := s.checkQuotas(ctx, changes...); err != nil {
+		return emptyResponse, err
+	}
+	if err := s.checkServerLimits(ctx, changes...); err != nil {
+		return emptyResponse, err
+	}
+	if err := s.checkOverlaps(ctx, changes...); err != nil {
+		return emptyResponse, err
+	}
+	if err := s.checkRules(ctx, changes...); err != nil {
+		return emptyResponse, err
+	}
+	if err := s.checkPools(ctx, changes...); err != nil {
+		return emptyResponse, err
+	}
+	if err := s.store.Apply(ctx, changes); err != nil {
+		return emptyResponse, fmt.Errorf("failed to clone service %s: %v", req.Id, err)
+	}
+	s.record(ctx, changes...)
+
+	log.Infof("Cloned %s with %d servers to %v", req.Id, len(servers), svc.PrettyString())
+	return emptyResponse, nil
+}
+
+func (s *server) RenameService(ctx context.Context, req *types.RenameServiceRequest) (*empty.Empty, error) {
+	svc, servers, err := s.copyService(ctx, req.Id, req.NewId)
+	if err != nil {
+		return emptyResponse, err
+	}
+	if err := s.validService(svc); err != nil {
+		return emptyResponse, err
+	}
+
+	changes := createChanges(svc, servers)
+	for _, server := range servers {
+		changes = append(changes, &types.Change{
+			Action: types.Change_DELETE,
+			Server: &types.RealServer{ServiceID: req.Id, Key: server.Key},
+		})
+	}
+	changes = append(changes, &types.Change{Action: types.Change_DELETE, Service: &types.VirtualService{Id: req.Id}})
+	if changes, err = s.admit(ctx, false, changes...); err != nil {
+		return emptyResponse, err
+	}
+	if err := s.checkRules(ctx, changes...); err != nil {
+		return emptyResponse, err
+	}
+	if err := s.checkPools(ctx, changes...); err != nil {
+		return emptyResponse, err
+	}
+
+	if err := s.store.Apply(ctx, changes); err != nil {
+		return emptyResponse, fmt.Errorf("failed to rename service %s: %v", req.Id, err)
+	}
+	s.record(ctx, changes...)
+
+	log.Infof("Renamed %s with %d servers to %s", req.Id, len(servers), req.NewId)
+	return emptyResponse, nil
+}
+
+func (s *server) SwapServers(ctx context.Context, req *types.SwapServersRequest) (*empty.Empty, error) {
+	if req.Id == req.OtherId {
+		return emptyResponse, status.Error(codes.InvalidArgument, "servers can't be swapped with the same service")
+	}
+	servers, err := s.listServersOf(ctx, req.Id)
+	if err != nil {
+		return emptyResponse, err
+	}
+	otherServers, err := s.listServersOf(ctx, req.OtherId)
+	if err != nil {
+		return emptyResponse, err
+	}
+
+	// delete every server before creating them under the other service
+	var changes []*types.Change
+	for _, server := range append(servers, otherServers...) {
+		changes = append(changes, &types.Change{
+			Action: types.Change_DELETE,
+			Server: &types.RealServer{ServiceID: server.ServiceID, Key: server.Key},
+		})
+	}
+	moves := map[string]string{req.Id: req.OtherId, req.OtherId: req.Id}
+	for _, server := range append(servers, otherServers...) {
+		server = proto.Clone(server).(*types.RealServer)
+		server.ServiceID = moves[server.ServiceID]
+		changes = append(changes, &types.Change{Action: types.Change_CREATE, Server: server})
+	}
+	if changes, err = s.admit(ctx, false, changes...); err != nil {
+		return emptyResponse, err
+	}
+	if err := s.checkQuotas(ctx, changes...); err != nil {
+		return emptyResponse, err
+	}
+	if err := s.checkServerLimits(ctx, changes...); err != nil {
+		return emptyResponse, err
+	}
+	if err := s.checkOverlaps(ctx, changes...); err != nil {
+		return emptyResponse, err
+	}
+	if err := s.checkRules(ctx, changes...); err != nil {
+		return emptyResponse, err
+	}
+	if err := s.checkPools(ctx, changes...); err != nil {
+		return emptyResponse, err
+	}
+
+	if err := s.store.Apply(ctx, changes); err != nil {
+		return emptyResponse, fmt.Errorf("failed to swap servers of %s and %s: %v", req.Id, req.OtherId, err)
+	}
+	s.record(ctx, changes...)
+
+	log.Infof("Swapped %d servers of %s with %d servers of %s", len(servers), req.Id, len(otherServers),
+		req.OtherId)
+	return emptyResponse, nil
+}
+
+// listServersOf returns the servers of a service, which must exist.
+func (s *server) listServersOf(ctx context.Context, id string) ([]*types.RealServer, error) {
+	svc, err := s.store.GetService(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check service exists: %v", err)
+	}
+	if svc == nil {
+		return nil, status.Errorf(codes.NotFound, "service %s doesn't exist", id)
+	}
+	servers, err := s.store.ListServers(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list servers of %s: %v", id, err)
+	}
+	return servers, nil
+}
+
+// copyService returns a copy of the service and its servers using newID, which must not already exist.
+func (s *server) copyService(ctx context.Context, id, newID string) (*types.VirtualService, []*types.RealServer,
+	error) {
+
+	if newID == "" {
+		return nil, nil, status.Error(codes.InvalidArgument, "new service id required")
+	}
+
+	svc, err := s.store.GetService(ctx, id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to check service exists: %v", err)
+	}
+	if svc == nil {
+		return nil, nil, status.Errorf(codes.NotFound, "service %s doesn't exist", id)
+	}
+	prev, err := s.store.GetService(ctx, newID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to check service exists: %v", err)
+	}
+	if prev != nil {
+		return nil, nil, status.Errorf(codes.AlreadyExists, "service %s already exists", newID)
+	}
+
+	servers, err := s.store.ListServers(ctx, id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list servers of %s: %v", id, err)
+	}
+
+	svc = proto.Clone(svc).(*types.VirtualService)
+	svc.Id = newID
+	var copies []*types.RealServer
+	for _, server := range servers {
+		server = proto.Clone(server).(*types.RealServer)
+		server.ServiceID = newID
+		copies = append(copies, server)
+	}
+	return svc, copies, nil
+}
+
+// createChanges returns the changes to create the service followed by its servers.
+func createChanges(svc *types.VirtualService, servers []*types.RealServer) []*types.Change {
+	changes := []*types.Change{{Action: types.Change_CREATE, Service: svc}}
+	for _, server := range servers {
+		changes = append(changes, &types.Change{Action: types.Change_CREATE, Server: server})
+	}
+	return changes
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"context"
+
+	"github.com/golang/protobuf/ptypes/wrappers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sky-uk/merlin/store"
+	"github.com/sky-uk/merlin/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var _ = Describe("SwapServers", func() {
+	var (
+		ctx context.Context
+		st  store.Store
+		s   types.MerlinServer
+	)
+
+	serverIPs := func(id string) []string {
+		servers, err := st.ListServers(ctx, id)
+		Expect(err).ToNot(HaveOccurred())
+		var ips []string
+		for _, server := range servers {
+			Expect(server.ServiceID).To(Equal(id))
+			ips = append(ips, server.Key.Ip)
+		}
+		return ips
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		st = store.NewMemory()
+		s = New(st, nil, testNode, nil, nil, Options{})
+		for i, id := range []string{"live", "green"} {
+			_, err := s.CreateService(ctx, &types.VirtualService{
+				Id:     id,
+				Key:    &types.VirtualService_Key{Ip: "10.10.10.10", Port: uint32(80 + i), Protocol: types.Protocol_TCP},
+				Config: &types.VirtualService_Config{Scheduler: "sh"},
+			})
+			Expect(err).ToNot(HaveOccurred())
+		}
+		for id, ips := range map[string][]string{"live": {"172.16.1.1"}, "green": {"172.16.2.1", "172.16.2.2"}} {
+			for _, ip := range ips {
+				_, err := s.CreateServer(ctx, &types.RealServer{
+					ServiceID: id,
+					Key:       &types.RealServer_Key{Ip: ip, Port: 8080},
+					Config: &types.RealServer_Config{
+						Weight:  &wrappers.UInt32Value{Value: 1},
+						Forward: types.ForwardMethod_MASQ,
+					},
+				})
+				Expect(err).ToNot(HaveOccurred())
+			}
+		}
+	})
+
+	It("should exchange the servers of the services", func() {
+		_, err := s.SwapServers(ctx, &types.SwapServersRequest{Id: "live", OtherId: "green"})
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(serverIPs("live")).To(ConsistOf("172.16.2.1", "172.16.2.2"))
+		Expect(serverIPs("green")).To(ConsistOf("172.16.1.1"))
+	})
+
+	It("should fail if a service doesn't exist", func() {
+		_, err := s.SwapServers(ctx, &types.SwapServersRequest{Id: "live", OtherId: "blue"})
+
+		Expect(status.Code(err)).To(Equal(codes.NotFound))
+		Expect(serverIPs("live")).To(ConsistOf("172.16.1.1"))
+	})
+
+	It("should reject swapping a service with itself", func() {
+		_, err := s.SwapServers(ctx, &types.SwapServersRequest{Id: "live", OtherId: "live"})
+
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+	})
+})
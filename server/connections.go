@@ -0,0 +1,49 @@
+package server
+
+import (
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/sky-uk/merlin/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ListConnections sends the connections as they're read from the kernel, as the table of a busy node can have
+// millions.
+func (s *server) ListConnections(req *types.ListConnectionsRequest, stream types.Merlin_ListConnectionsServer) error {
+	if s.ipvs == nil {
+		return status.Error(codes.FailedPrecondition, "ipvs is disabled on this node")
+	}
+	ctx := stream.Context()
+	svcs, err := s.store.ListServices(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list services: %v", err)
+	}
+	ids := make(map[string]string)
+	var key *types.VirtualService_Key
+	for _, svc := range svcs {
+		ids[svc.Key.PrettyString()] = svc.Id
+		if svc.Id == req.ServiceId {
+			key = svc.Key
+		}
+	}
+	if req.ServiceId != "" && key == nil {
+		return status.Errorf(codes.NotFound, "service %s doesn't exist", req.ServiceId)
+	}
+
+	err = s.ipvs.Connections(ctx, func(conn *types.Connection) error {
+		if key != nil && !proto.Equal(conn.Service, key) {
+			return nil
+		}
+		if req.Server != nil && !proto.Equal(conn.Server, req.Server) {
+			return nil
+		}
+		conn.ServiceId = ids[conn.Service.PrettyString()]
+		return stream.Send(conn)
+	})
+	if _, ok := status.FromError(err); !ok {
+		return fmt.Errorf("failed to list connections: %v", err)
+	}
+	return err
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sky-uk/merlin/ipvs"
+	"github.com/sky-uk/merlin/store"
+	"github.com/sky-uk/merlin/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// connectionsIPVS is an IPVS with a connection table.
+type connectionsIPVS struct {
+	ipvs.IPVS
+	conns []*types.Connection
+}
+
+func (i *connectionsIPVS) Connections(_ context.Context, fn func(*types.Connection) error) error {
+	for _, conn := range i.conns {
+		if err := fn(conn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// connectionsStream records the connections sent by the server.
+type connectionsStream struct {
+	grpc.ServerStream
+	sent []*types.Connection
+}
+
+func (s *connectionsStream) Context() context.Context {
+	return context.Background()
+}
+
+func (s *connectionsStream) Send(conn *types.Connection) error {
+	s.sent = append(s.sent, conn)
+	return nil
+}
+
+var _ = Describe("ListConnections", func() {
+	var (
+		ctx    = context.Background()
+		kernel *connectionsIPVS
+		s      types.MerlinServer
+		stream *connectionsStream
+	)
+
+	webKey := &types.VirtualService_Key{Ip: "10.10.10.10", Port: 80, Protocol: types.Protocol_TCP}
+	connection := func(port uint32, server string) *types.Connection {
+		return &types.Connection{
+			Service:    &types.VirtualService_Key{Ip: "10.10.10.10", Port: port, Protocol: types.Protocol_TCP},
+			ClientIp:   "10.0.0.1",
+			ClientPort: 54321,
+			Server:     &types.RealServer_Key{Ip: server, Port: 8080},
+			State:      "ESTABLISHED",
+		}
+	}
+	list := func(req *types.ListConnectionsRequest) ([]*types.Connection, error) {
+		stream = &connectionsStream{}
+		err := s.ListConnections(req, stream)
+		return stream.sent, err
+	}
+
+	BeforeEach(func() {
+		kernel = &connectionsIPVS{IPVS: ipvs.NewMemory(), conns: []*types.Connection{
+			connection(80, "172.16.1.1"),
+			connection(80, "172.16.1.2"),
+			connection(443, "172.16.1.1"),
+		}}
+		s = New(store.NewMemory(), kernel, testNode, nil, nil, Options{})
+		_, err := s.CreateService(ctx, &types.VirtualService{
+			Id:     "web",
+			Key:    webKey,
+			Config: &types.VirtualService_Config{Scheduler: "sh"},
+		})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should list every connection with the IDs of managed services", func() {
+		conns, err := list(&types.ListConnectionsRequest{})
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(conns).To(HaveLen(3))
+		Expect(conns[0].ServiceId).To(Equal("web"))
+		Expect(conns[2].ServiceId).To(BeEmpty())
+	})
+
+	It("should filter by service and server", func() {
+		conns, err := list(&types.ListConnectionsRequest{ServiceId: "web"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(conns).To(HaveLen(2))
+
+		conns, err = list(&types.ListConnectionsRequest{
+			ServiceId: "web",
+			Server:    &types.RealServer_Key{Ip: "172.16.1.2", Port: 8080},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(conns).To(HaveLen(1))
+		Expect(conns[0].Server.Ip).To(Equal("172.16.1.2"))
+	})
+
+	It("should return not found for services which don't exist", func() {
+		_, err := list(&types.ListConnectionsRequest{ServiceId: "missing"})
+
+		Expect(status.Code(err)).To(Equal(codes.NotFound))
+	})
+
+	It("should fail when ipvs is disabled", func() {
+		s = New(store.NewMemory(), nil, func() *types.Node { return &types.Node{} }, nil, nil, Options{})
+
+		_, err := list(&types.ListConnectionsRequest{})
+
+		Expect(status.Code(err)).To(Equal(codes.FailedPrecondition))
+	})
+})
@@ -0,0 +1,50 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sky-uk/merlin/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *server) DescribeService(ctx context.Context, req *types.DescribeServiceRequest) (
+	*types.DescribeServiceResponse, error) {
+
+	svc, err := s.store.GetService(ctx, req.Id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service %s: %v", req.Id, err)
+	}
+	if svc == nil {
+		return nil, status.Errorf(codes.NotFound, "service %s doesn't exist", req.Id)
+	}
+	servers, err := s.store.ListServers(ctx, req.Id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list servers of %s: %v", req.Id, err)
+	}
+
+	resp := &types.DescribeServiceResponse{
+		Node:    s.node().Name,
+		Service: svc,
+		Errors:  s.errors(req.Id),
+	}
+	for _, server := range servers {
+		resp.Servers = append(resp.Servers, &types.DescribeServiceResponse_Server{
+			Server: server,
+			Health: s.health(req.Id, server.Key),
+		})
+	}
+
+	if req.History > 0 {
+		history, err := s.store.ListHistory(ctx, req.Id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list history of %s: %v", req.Id, err)
+		}
+		if len(history) > int(req.History) {
+			history = history[len(history)-int(req.History):]
+		}
+		resp.History = history
+	}
+	return resp, nil
+}
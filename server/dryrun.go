@@ -0,0 +1,68 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/sky-uk/merlin/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DryRun returns an interceptor which validates and admits writes with the types.DryRunHeader to srv, which must
+// have been returned by New, without making them. The changes a write would make are returned in the
+// types.DryRunChangesHeader of the response.
+func DryRun(srv types.MerlinServer) grpc.UnaryServerInterceptor {
+	s := srv.(*server)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+		if !types.IsDryRun(ctx) {
+			return handler(ctx, req)
+		}
+		method := path.Base(info.FullMethod)
+		write, ok := candidateMethods[method]
+		if !ok {
+			return nil, status.Errorf(codes.FailedPrecondition, "%s can't be a dry run", method)
+		}
+		if !write {
+			return handler(ctx, req)
+		}
+		if types.IsCandidate(ctx) {
+			return nil, status.Error(codes.InvalidArgument,
+				"writes to the candidate config can't be dry runs, commit --dry-run shows what they would change")
+		}
+		return s.dryRun(ctx, method, req)
+	}
+}
+
+// dryRun calls method on a copy of the store, then admits and checks the changes it made against the store
+// without applying them. IPs allocated from pools may differ when the write is made, as the copy has no IPAM.
+func (s *server) dryRun(ctx context.Context, method string, req interface{}) (interface{}, error) {
+	current, err := s.GetSnapshot(ctx, &empty.Empty{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read current state: %v", err)
+	}
+	c, err := s.candidateServer(ctx, current)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.call(ctx, method, req)
+	if err != nil {
+		return nil, err
+	}
+	after, err := c.GetSnapshot(ctx, &empty.Empty{})
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := s.applyChanges(ctx, snapshotChanges(current, after, true), true, 0)
+	if err != nil {
+		return nil, err
+	}
+	// requests through the gateway have no transport to set headers on, so they only see the response
+	grpc.SetHeader(ctx, types.DryRunMetadata(changes))
+	return resp, nil
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sky-uk/merlin/store"
+	"github.com/sky-uk/merlin/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// headerStream records the response headers set by the server.
+type headerStream struct {
+	header metadata.MD
+}
+
+func (h *headerStream) Method() string { return "" }
+
+func (h *headerStream) SetHeader(md metadata.MD) error {
+	h.header = metadata.Join(h.header, md)
+	return nil
+}
+
+func (h *headerStream) SendHeader(md metadata.MD) error { return h.SetHeader(md) }
+
+func (h *headerStream) SetTrailer(metadata.MD) error { return nil }
+
+var _ = Describe("DryRun", func() {
+	var (
+		ctx    = context.Background()
+		st     store.Store
+		s      types.MerlinServer
+		stream *headerStream
+		admit  func(changes []*types.Change, dryRun bool) ([]*types.Change, error)
+	)
+
+	dryRun := func(method string, req interface{}, headers ...string) (interface{}, error) {
+		md := metadata.Pairs(append([]string{types.DryRunHeader, "true"}, headers...)...)
+		dryRunCtx := grpc.NewContextWithServerTransportStream(metadata.NewIncomingContext(ctx, md), stream)
+		info := &grpc.UnaryServerInfo{FullMethod: "/types.Merlin/" + method}
+		return DryRun(s)(dryRunCtx, req, info, func(context.Context, interface{}) (interface{}, error) {
+			Fail("request was made instead of a dry run")
+			return nil, nil
+		})
+	}
+	changes := func() []*types.Change {
+		changes, err := types.DryRunChanges(stream.header)
+		Expect(err).ToNot(HaveOccurred())
+		return changes
+	}
+
+	BeforeEach(func() {
+		st = store.NewMemory()
+		stream = &headerStream{}
+		admit = func(changes []*types.Change, _ bool) ([]*types.Change, error) { return changes, nil }
+		s = New(st, nil, testNode, nil, nil, Options{
+			Admit: func(_ context.Context, changes []*types.Change, dryRun bool) ([]*types.Change, error) {
+				return admit(changes, dryRun)
+			},
+		})
+		_, err := s.CreateService(ctx, testService("old", "10.10.10.10", 80))
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should return the changes a write would make without making them", func() {
+		_, err := dryRun("UpdateService", &types.VirtualService{
+			Id:     "old",
+			Config: &types.VirtualService_Config{Scheduler: "wrr"},
+		})
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(changes()).To(HaveLen(1))
+		Expect(changes()[0].Action).To(Equal(types.Change_UPDATE))
+		Expect(changes()[0].Service.Config.Scheduler).To(Equal("wrr"))
+		svc, _ := st.GetService(ctx, "old")
+		Expect(svc.Config.Scheduler).To(Equal("sh"))
+	})
+
+	It("should validate writes as if they were made", func() {
+		_, err := dryRun("CreateService", testService("old", "10.10.10.10", 81))
+		Expect(status.Code(err)).To(Equal(codes.AlreadyExists))
+
+		_, err = dryRun("UpdateService", testService("missing", "10.10.10.10", 81))
+		Expect(status.Code(err)).To(Equal(codes.NotFound))
+	})
+
+	It("should admit the changes as a dry run", func() {
+		var admittedDryRun bool
+		admit = func(_ []*types.Change, dryRun bool) ([]*types.Change, error) {
+			admittedDryRun = dryRun
+			return nil, status.Error(codes.PermissionDenied, "denied")
+		}
+
+		_, err := dryRun("CreateService", testService("new", "10.10.10.10", 81))
+
+		Expect(status.Code(err)).To(Equal(codes.PermissionDenied))
+		Expect(admittedDryRun).To(BeTrue())
+		Expect(st.GetService(ctx, "new")).To(BeNil())
+	})
+
+	It("should reject dry runs of writes to the candidate config", func() {
+		_, err := dryRun("CreateService", testService("new", "10.10.10.10", 81), types.CandidateHeader, "true")
+
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+	})
+
+	It("should reject dry runs of methods which can't be", func() {
+		_, err := dryRun("SetMaintenance", &types.SetMaintenanceRequest{})
+
+		Expect(status.Code(err)).To(Equal(codes.FailedPrecondition))
+	})
+})
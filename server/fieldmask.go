@@ -0,0 +1,84 @@
+package server
+
+import (
+	"github.com/golang/protobuf/proto"
+	"github.com/sky-uk/merlin/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maskService sets the fields of svc in the update mask of update to those of update, even if they're empty.
+func maskService(svc, update *types.VirtualService) error {
+	for _, path := range update.UpdateMask.Paths {
+		switch path {
+		case "config":
+			svc.Config = &types.VirtualService_Config{}
+			if update.Config != nil {
+				svc.Config = proto.Clone(update.Config).(*types.VirtualService_Config)
+			}
+		case "config.scheduler":
+			svc.Config.Scheduler = update.GetConfig().GetScheduler()
+		case "config.flags":
+			svc.Config.Flags = update.GetConfig().GetFlags()
+		case "config.ops":
+			svc.Config.Ops = update.GetConfig().GetOps()
+		case "labels":
+			svc.Labels = update.Labels
+		case "node_selector":
+			svc.NodeSelector = update.NodeSelector
+		case "pool":
+			svc.Pool = update.Pool
+		default:
+			return status.Errorf(codes.InvalidArgument, "field %q of the update mask can't be updated", path)
+		}
+	}
+	return nil
+}
+
+// maskServer sets the fields of server in the update mask of update to those of update, even if they're empty.
+// Setting the weight replaces the weight recorded by a drain.
+func maskServer(server, update *types.RealServer) error {
+	for _, path := range update.UpdateMask.Paths {
+		switch path {
+		case "config":
+			server.Config = &types.RealServer_Config{}
+			if update.Config != nil {
+				server.Config = proto.Clone(update.Config).(*types.RealServer_Config)
+			}
+			server.DrainedWeight = nil
+		case "config.weight":
+			server.Config.Weight = update.GetConfig().GetWeight()
+			server.DrainedWeight = nil
+		case "config.forward":
+			server.Config.Forward = update.GetConfig().GetForward()
+		case "config.tunnel_type":
+			server.Config.TunnelType = update.GetConfig().GetTunnelType()
+		case "config.tunnel_port":
+			server.Config.TunnelPort = update.GetConfig().GetTunnelPort()
+		case "config.tunnel_checksum":
+			server.Config.TunnelChecksum = update.GetConfig().GetTunnelChecksum()
+		case "health_check":
+			server.HealthCheck = &types.RealServer_HealthCheck{}
+			if update.HealthCheck != nil {
+				server.HealthCheck = proto.Clone(update.HealthCheck).(*types.RealServer_HealthCheck)
+			}
+		case "health_check.endpoint":
+			server.HealthCheck.Endpoint = update.GetHealthCheck().GetEndpoint()
+		case "health_check.period":
+			server.HealthCheck.Period = update.GetHealthCheck().GetPeriod()
+		case "health_check.timeout":
+			server.HealthCheck.Timeout = update.GetHealthCheck().GetTimeout()
+		case "health_check.up_threshold":
+			server.HealthCheck.UpThreshold = update.GetHealthCheck().GetUpThreshold()
+		case "health_check.down_threshold":
+			server.HealthCheck.DownThreshold = update.GetHealthCheck().GetDownThreshold()
+		case "health_check.expected_status":
+			server.HealthCheck.ExpectedStatus = update.GetHealthCheck().GetExpectedStatus()
+		case "labels":
+			server.Labels = update.Labels
+		default:
+			return status.Errorf(codes.InvalidArgument, "field %q of the update mask can't be updated", path)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"context"
+
+	"github.com/golang/protobuf/ptypes/wrappers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sky-uk/merlin/store"
+	"github.com/sky-uk/merlin/types"
+	"google.golang.org/genproto/protobuf/field_mask"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var _ = Describe("Updates with an update mask", func() {
+	var (
+		ctx = context.Background()
+		st  store.Store
+		s   types.MerlinServer
+		key = &types.RealServer_Key{Ip: "172.16.1.1", Port: 8080}
+	)
+
+	mask := func(paths ...string) *field_mask.FieldMask {
+		return &field_mask.FieldMask{Paths: paths}
+	}
+
+	BeforeEach(func() {
+		st = store.NewMemory()
+		s = New(st, nil, testNode, nil, nil, Options{})
+		_, err := s.CreateService(ctx, &types.VirtualService{
+			Id:     "svc",
+			Key:    &types.VirtualService_Key{Ip: "10.10.10.10", Port: 80, Protocol: types.Protocol_TCP},
+			Config: &types.VirtualService_Config{Scheduler: "sh", Flags: []string{"flag-2"}},
+			Labels: map[string]string{"team": "payments"},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		_, err = s.CreateServer(ctx, &types.RealServer{
+			ServiceID: "svc",
+			Key:       key,
+			Config: &types.RealServer_Config{
+				Weight:  &wrappers.UInt32Value{Value: 1},
+				Forward: types.ForwardMethod_MASQ,
+			},
+			HealthCheck: &types.RealServer_HealthCheck{UpThreshold: 2},
+		})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should only set the fields of the mask of a service", func() {
+		_, err := s.UpdateService(ctx, &types.VirtualService{
+			Id:         "svc",
+			Config:     &types.VirtualService_Config{Scheduler: "wrr"},
+			Labels:     map[string]string{"team": "search"},
+			UpdateMask: mask("config.scheduler"),
+		})
+
+		Expect(err).ToNot(HaveOccurred())
+		svc, _ := st.GetService(ctx, "svc")
+		Expect(svc.Config.Scheduler).To(Equal("wrr"))
+		Expect(svc.Config.Flags).To(Equal([]string{"flag-2"}))
+		Expect(svc.Labels).To(Equal(map[string]string{"team": "payments"}))
+		Expect(svc.UpdateMask).To(BeNil())
+	})
+
+	It("should set fields of the mask which are empty", func() {
+		_, err := s.UpdateService(ctx, &types.VirtualService{Id: "svc", UpdateMask: mask("config.flags", "labels")})
+
+		Expect(err).ToNot(HaveOccurred())
+		svc, _ := st.GetService(ctx, "svc")
+		Expect(svc.Config.Scheduler).To(Equal("sh"))
+		Expect(svc.Config.Flags).To(BeEmpty())
+		Expect(svc.Labels).To(BeEmpty())
+	})
+
+	It("should only set the fields of the mask of a server", func() {
+		_, err := s.UpdateServer(ctx, &types.RealServer{
+			ServiceID: "svc",
+			Key:       key,
+			Config: &types.RealServer_Config{
+				Weight:  &wrappers.UInt32Value{Value: 5},
+				Forward: types.ForwardMethod_TUNNEL,
+			},
+			HealthCheck: &types.RealServer_HealthCheck{DownThreshold: 3},
+			UpdateMask:  mask("config.weight", "health_check.down_threshold"),
+		})
+
+		Expect(err).ToNot(HaveOccurred())
+		server, _ := st.GetServer(ctx, "svc", key)
+		Expect(server.Config.Weight.GetValue()).To(BeEquivalentTo(5))
+		Expect(server.Config.Forward).To(Equal(types.ForwardMethod_MASQ))
+		Expect(server.HealthCheck.UpThreshold).To(BeEquivalentTo(2))
+		Expect(server.HealthCheck.DownThreshold).To(BeEquivalentTo(3))
+	})
+
+	It("should reject fields which can't be updated", func() {
+		_, err := s.UpdateService(ctx, &types.VirtualService{Id: "svc", UpdateMask: mask("key")})
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+
+		_, err = s.UpdateServer(ctx, &types.RealServer{ServiceID: "svc", Key: key, UpdateMask: mask("weight")})
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+	})
+})
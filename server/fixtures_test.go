@@ -0,0 +1,32 @@
+package server
+
+import (
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/sky-uk/merlin/types"
+)
+
+// testNode is the node the servers under test run on.
+func testNode() *types.Node {
+	return &types.Node{Name: "node"}
+}
+
+// testService returns a TCP service on ip:port with the sh scheduler.
+func testService(id, ip string, port uint32) *types.VirtualService {
+	return &types.VirtualService{
+		Id:     id,
+		Key:    &types.VirtualService_Key{Ip: ip, Port: port, Protocol: types.Protocol_TCP},
+		Config: &types.VirtualService_Config{Scheduler: "sh"},
+	}
+}
+
+// testServer returns a MASQ server of a service on ip:8080 with a weight of 1.
+func testServer(serviceID, ip string) *types.RealServer {
+	return &types.RealServer{
+		ServiceID: serviceID,
+		Key:       &types.RealServer_Key{Ip: ip, Port: 8080},
+		Config: &types.RealServer_Config{
+			Weight:  &wrappers.UInt32Value{Value: 1},
+			Forward: types.ForwardMethod_MASQ,
+		},
+	}
+}
@@ -0,0 +1,290 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"sort"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	log "github.com/sirupsen/logrus"
+	"github.com/sky-uk/merlin/types"
+	"github.com/sky-uk/merlin/validation"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// IPAM allocates the IPs of services in pools from an external address registry, such as ipam.NetBox, instead of
+// merlin choosing them.
+type IPAM interface {
+	// Allocate reserves a free IP of the pool for the service, returning it.
+	Allocate(ctx context.Context, pool *types.VIPPool, serviceID string) (string, error)
+	// Release the IP of a deleted service, so it can be allocated again.
+	Release(ctx context.Context, serviceID, ip string) error
+}
+
+func (s *server) PutPool(ctx context.Context, pool *types.VIPPool) (*empty.Empty, error) {
+	if err := validation.Pool(pool); err != nil {
+		return emptyResponse, err
+	}
+	s.ipamMu.Lock()
+	defer s.ipamMu.Unlock()
+
+	pools, err := s.store.ListPools(ctx)
+	if err != nil {
+		return emptyResponse, fmt.Errorf("failed to list pools: %v", err)
+	}
+	nets := poolNets(pool)
+	for _, other := range pools {
+		if other.Name == pool.Name {
+			continue
+		}
+		for _, n := range nets {
+			for _, o := range poolNets(other) {
+				if n.Contains(o.IP) || o.Contains(n.IP) {
+					return emptyResponse, status.Errorf(codes.InvalidArgument, "%s overlaps %s of pool %s", n, o,
+						other.Name)
+				}
+			}
+		}
+	}
+	svcs, err := s.store.ListServices(ctx)
+	if err != nil {
+		return emptyResponse, fmt.Errorf("failed to list services: %v", err)
+	}
+	for _, svc := range svcs {
+		if svc.Pool == pool.Name && !inNets(nets, svc.GetKey().GetIp()) {
+			return emptyResponse, status.Errorf(codes.FailedPrecondition,
+				"service %s has IP %s, which would no longer be in pool %s", svc.Id, svc.Key.Ip, pool.Name)
+		}
+	}
+
+	if err := s.store.PutPool(ctx, pool); err != nil {
+		return emptyResponse, fmt.Errorf("failed to put pool: %v", err)
+	}
+	log.Infof("Put pool %s %v", pool.Name, pool.Cidrs)
+	return emptyResponse, nil
+}
+
+func (s *server) DeletePool(ctx context.Context, name *wrappers.StringValue) (*empty.Empty, error) {
+	if name.Value == "" {
+		return emptyResponse, status.Error(codes.InvalidArgument, "pool name required")
+	}
+	s.ipamMu.Lock()
+	defer s.ipamMu.Unlock()
+
+	svcs, err := s.store.ListServices(ctx)
+	if err != nil {
+		return emptyResponse, fmt.Errorf("failed to list services: %v", err)
+	}
+	var allocated []string
+	for _, svc := range svcs {
+		if svc.Pool == name.Value {
+			allocated = append(allocated, svc.Id)
+		}
+	}
+	if len(allocated) > 0 {
+		sort.Strings(allocated)
+		return emptyResponse, status.Errorf(codes.FailedPrecondition, "pool %s has services allocated from it: %v",
+			name.Value, allocated)
+	}
+
+	if err := s.store.DeletePool(ctx, name.Value); err != nil {
+		return emptyResponse, fmt.Errorf("failed to delete pool: %v", err)
+	}
+	log.Infof("Deleted pool %s", name.Value)
+	return emptyResponse, nil
+}
+
+func (s *server) ListPools(ctx context.Context, _ *empty.Empty) (*types.ListPoolsResponse, error) {
+	pools, err := s.store.ListPools(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pools: %v", err)
+	}
+	used, err := s.usedIPs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(pools, func(i, j int) bool { return pools[i].Name < pools[j].Name })
+
+	resp := &types.ListPoolsResponse{}
+	for _, pool := range pools {
+		p := &types.ListPoolsResponse_Pool{Pool: pool}
+		nets := poolNets(pool)
+		for ip := range used {
+			if inNets(nets, ip) {
+				p.Allocated = append(p.Allocated, ip)
+			}
+		}
+		sort.Slice(p.Allocated, func(i, j int) bool {
+			return compareIPs(net.ParseIP(p.Allocated[i]), net.ParseIP(p.Allocated[j])) < 0
+		})
+		resp.Pools = append(resp.Pools, p)
+	}
+	return resp, nil
+}
+
+// getPool returns the pool with name, or a NotFound status error if it doesn't exist.
+func (s *server) getPool(ctx context.Context, name string) (*types.VIPPool, error) {
+	pools, err := s.store.ListPools(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pools: %v", err)
+	}
+	for _, pool := range pools {
+		if pool.Name == name {
+			return pool, nil
+		}
+	}
+	return nil, status.Errorf(codes.NotFound, "pool %s doesn't exist", name)
+}
+
+// usedIPs returns the IPs of every service, normalised so equal addresses are equal strings.
+func (s *server) usedIPs(ctx context.Context) (map[string]bool, error) {
+	svcs, err := s.store.ListServices(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list services: %v", err)
+	}
+	used := make(map[string]bool)
+	for _, svc := range svcs {
+		if ip := net.ParseIP(svc.GetKey().GetIp()); ip != nil {
+			used[ip.String()] = true
+		}
+	}
+	return used, nil
+}
+
+// allocateIP sets the IP of a service without one to the first IP of its pool which no service uses, or the IP
+// allocated by the IPAM if it's set. It must be called with ipamMu held until the service is stored, so concurrent
+// creates aren't allocated the same IP.
+func (s *server) allocateIP(ctx context.Context, svc *types.VirtualService) error {
+	pool, err := s.getPool(ctx, svc.Pool)
+	if err != nil {
+		return err
+	}
+	used, err := s.usedIPs(ctx)
+	if err != nil {
+		return err
+	}
+	if s.opts.IPAM != nil {
+		ip, err := s.opts.IPAM.Allocate(ctx, pool, svc.Id)
+		if err != nil {
+			return err
+		}
+		if parsed := net.ParseIP(ip); parsed == nil || used[parsed.String()] {
+			s.releaseIP(ctx, svc.Id, ip)
+			return status.Errorf(codes.Unavailable, "IPAM allocated %s from pool %s, which is invalid or in use",
+				ip, pool.Name)
+		}
+		svc.Key.Ip = ip
+		log.Infof("Allocated %s from pool %s to %s with IPAM", svc.Key.Ip, pool.Name, svc.Id)
+		return nil
+	}
+	for _, n := range poolNets(pool) {
+		for ip := n.IP; ip != nil && n.Contains(ip); ip = nextIP(ip) {
+			if !used[ip.String()] && !reservedIP(n, ip) {
+				svc.Key.Ip = ip.String()
+				log.Infof("Allocated %s from pool %s to %s", svc.Key.Ip, pool.Name, svc.Id)
+				return nil
+			}
+		}
+	}
+	return status.Errorf(codes.ResourceExhausted, "pool %s has no free IPs", pool.Name)
+}
+
+// releaseIP releases an IP allocated by the IPAM, if it's set. Failures are logged rather than returned, as the
+// service using it has already been deleted or failed to be created.
+func (s *server) releaseIP(ctx context.Context, serviceID, ip string) {
+	if s.opts.IPAM == nil {
+		return
+	}
+	if err := s.opts.IPAM.Release(ctx, serviceID, ip); err != nil {
+		log.Warnf("Failed to release %s of %s with IPAM, it must be released by hand: %v", ip, serviceID, err)
+		return
+	}
+	log.Infof("Released %s of %s with IPAM", ip, serviceID)
+}
+
+// releaseIPs releases the IPs of services in pools deleted by the changes.
+func (s *server) releaseIPs(ctx context.Context, changes ...*types.Change) {
+	for _, change := range changes {
+		if change.Action == types.Change_DELETE && change.Service.GetPool() != "" {
+			s.releaseIP(ctx, change.Service.Id, change.Service.GetKey().GetIp())
+		}
+	}
+}
+
+// checkPools returns an error if a service created or updated by the changes is in a pool which doesn't exist, or
+// has an IP outside of it.
+func (s *server) checkPools(ctx context.Context, changes ...*types.Change) error {
+	for _, change := range changes {
+		if change.Service == nil || change.Service.Pool == "" || change.Action == types.Change_DELETE {
+			continue
+		}
+		pool, err := s.getPool(ctx, change.Service.Pool)
+		if err != nil {
+			return err
+		}
+		if !inNets(poolNets(pool), change.Service.GetKey().GetIp()) {
+			return status.Errorf(codes.InvalidArgument, "IP %s of service %s isn't in pool %s",
+				change.Service.GetKey().GetIp(), change.Service.Id, pool.Name)
+		}
+	}
+	return nil
+}
+
+// poolNets returns the networks of a valid pool.
+func poolNets(pool *types.VIPPool) []*net.IPNet {
+	var nets []*net.IPNet
+	for _, cidr := range pool.Cidrs {
+		if _, n, err := net.ParseCIDR(cidr); err == nil {
+			if ip4 := n.IP.To4(); ip4 != nil {
+				n.IP = ip4
+			}
+			nets = append(nets, n)
+		}
+	}
+	return nets
+}
+
+func inNets(nets []*net.IPNet, ip string) bool {
+	parsed := net.ParseIP(ip)
+	for _, n := range nets {
+		if parsed != nil && n.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// reservedIP returns true for the network and broadcast addresses of IPv4 networks with more than two addresses.
+func reservedIP(n *net.IPNet, ip net.IP) bool {
+	ones, bits := n.Mask.Size()
+	if bits != 8*net.IPv4len || bits-ones < 2 {
+		return false
+	}
+	broadcast := make(net.IP, len(n.IP))
+	for i := range n.IP {
+		broadcast[i] = n.IP[i] | ^n.Mask[i]
+	}
+	return ip.Equal(n.IP) || ip.Equal(broadcast)
+}
+
+// nextIP returns the IP after ip, or nil if ip is the last address.
+func nextIP(ip net.IP) net.IP {
+	next := make(net.IP, len(ip))
+	copy(next, ip)
+	for i := len(next) - 1; i >= 0; i-- {
+		next[i]++
+		if next[i] != 0 {
+			return next
+		}
+	}
+	return nil
+}
+
+// compareIPs orders IPs by address, with IPv4 before IPv6 as they're mapped into ::ffff:0:0/96.
+func compareIPs(a, b net.IP) int {
+	return bytes.Compare(a.To16(), b.To16())
+}
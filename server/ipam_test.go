@@ -0,0 +1,177 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sky-uk/merlin/store"
+	"github.com/sky-uk/merlin/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var _ = Describe("IPAM", func() {
+	var (
+		ctx context.Context
+		st  store.Store
+		s   types.MerlinServer
+	)
+
+	service := func(id, ip, pool string) *types.VirtualService {
+		svc := testService(id, ip, 80)
+		svc.Pool = pool
+		return svc
+	}
+	ipOf := func(id string) string {
+		svc, err := st.GetService(ctx, id)
+		Expect(err).ToNot(HaveOccurred())
+		return svc.Key.Ip
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		st = store.NewMemory()
+		s = New(st, nil, testNode, nil, nil, Options{})
+		_, err := s.PutPool(ctx, &types.VIPPool{Name: "edge", Cidrs: []string{"10.0.0.0/30", "10.0.1.0/31"}})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should allocate free IPs from the pool", func() {
+		_, err := s.CreateService(ctx, service("fixed", "10.0.0.1", "edge"))
+		Expect(err).ToNot(HaveOccurred())
+
+		var ips []string
+		for i := 0; i < 3; i++ {
+			id := fmt.Sprintf("svc%d", i)
+			_, err := s.CreateService(ctx, service(id, "", "edge"))
+			Expect(err).ToNot(HaveOccurred())
+			ips = append(ips, ipOf(id))
+		}
+		Expect(ips).To(Equal([]string{"10.0.0.2", "10.0.1.0", "10.0.1.1"}),
+			"skips the network, broadcast and used IPs")
+
+		_, err = s.CreateService(ctx, service("full", "", "edge"))
+		Expect(status.Code(err)).To(Equal(codes.ResourceExhausted))
+	})
+
+	It("should release IPs when services are deleted", func() {
+		_, err := s.CreateService(ctx, service("a", "", "edge"))
+		Expect(err).ToNot(HaveOccurred())
+		_, err = s.DeleteService(ctx, &wrappers.StringValue{Value: "a"})
+		Expect(err).ToNot(HaveOccurred())
+		_, err = s.CreateService(ctx, service("b", "", "edge"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ipOf("b")).To(Equal("10.0.0.1"))
+	})
+
+	It("should list pools with their allocated IPs", func() {
+		_, err := s.CreateService(ctx, service("a", "", "edge"))
+		Expect(err).ToNot(HaveOccurred())
+		_, err = s.CreateService(ctx, service("b", "10.0.1.1", ""))
+		Expect(err).ToNot(HaveOccurred())
+
+		resp, err := s.ListPools(ctx, &empty.Empty{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.Pools).To(HaveLen(1))
+		Expect(resp.Pools[0].Allocated).To(Equal([]string{"10.0.0.1", "10.0.1.1"}))
+	})
+
+	It("should reject services with IPs outside of their pool", func() {
+		_, err := s.CreateService(ctx, service("a", "10.0.2.1", "edge"))
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+
+		_, err = s.CreateService(ctx, service("a", "", "missing"))
+		Expect(status.Code(err)).To(Equal(codes.NotFound))
+	})
+
+	It("should reject overlapping pools", func() {
+		_, err := s.PutPool(ctx, &types.VIPPool{Name: "other", Cidrs: []string{"10.0.0.0/24"}})
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+
+		_, err = s.PutPool(ctx, &types.VIPPool{Name: "edge", Cidrs: []string{"10.0.0.0/24"}})
+		Expect(err).ToNot(HaveOccurred(), "a pool can be widened")
+	})
+
+	It("should refuse to remove the IPs of services from a pool", func() {
+		_, err := s.CreateService(ctx, service("a", "", "edge"))
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = s.PutPool(ctx, &types.VIPPool{Name: "edge", Cidrs: []string{"10.0.1.0/31"}})
+		Expect(status.Code(err)).To(Equal(codes.FailedPrecondition))
+		_, err = s.DeletePool(ctx, &wrappers.StringValue{Value: "edge"})
+		Expect(status.Code(err)).To(Equal(codes.FailedPrecondition))
+
+		_, err = s.DeleteService(ctx, &wrappers.StringValue{Value: "a"})
+		Expect(err).ToNot(HaveOccurred())
+		_, err = s.DeletePool(ctx, &wrappers.StringValue{Value: "edge"})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	Context("with an IPAM", func() {
+		var fake *fakeIPAM
+
+		BeforeEach(func() {
+			fake = &fakeIPAM{ips: []string{"10.0.1.1", "10.0.1.0"}, released: make(map[string]string)}
+			s = New(st, nil, testNode, nil, nil, Options{IPAM: fake})
+		})
+
+		It("should allocate IPs from the IPAM, and release them when services are deleted", func() {
+			_, err := s.CreateService(ctx, service("a", "", "edge"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ipOf("a")).To(Equal("10.0.1.1"))
+
+			_, err = s.DeleteService(ctx, &wrappers.StringValue{Value: "a"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fake.released).To(Equal(map[string]string{"a": "10.0.1.1"}))
+		})
+
+		It("should release IPs of services which fail to be created", func() {
+			_, err := s.CreateService(ctx, service("a", "10.0.0.1", "edge"))
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = s.CreateService(ctx, service("a", "", "edge"))
+			Expect(status.Code(err)).To(Equal(codes.AlreadyExists))
+			Expect(fake.released).To(Equal(map[string]string{"a": "10.0.1.1"}))
+		})
+
+		It("should release IPs allocated outside of the pool", func() {
+			fake.ips = []string{"192.168.0.1"}
+			_, err := s.CreateService(ctx, service("a", "", "edge"))
+			Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+			Expect(fake.released).To(Equal(map[string]string{"a": "192.168.0.1"}))
+		})
+
+		It("should release IPs of services deleted by snapshots", func() {
+			_, err := s.CreateService(ctx, service("a", "", "edge"))
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = s.ApplySnapshot(ctx, &types.ApplySnapshotRequest{Snapshot: &types.Snapshot{}, Prune: true})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fake.released).To(Equal(map[string]string{"a": "10.0.1.1"}))
+		})
+	})
+})
+
+// fakeIPAM allocates its ips in order.
+type fakeIPAM struct {
+	ips      []string
+	released map[string]string
+}
+
+func (f *fakeIPAM) Allocate(_ context.Context, _ *types.VIPPool, _ string) (string, error) {
+	if len(f.ips) == 0 {
+		return "", status.Error(codes.ResourceExhausted, "no free IPs")
+	}
+	ip := f.ips[0]
+	f.ips = f.ips[1:]
+	return ip, nil
+}
+
+func (f *fakeIPAM) Release(_ context.Context, serviceID, ip string) error {
+	f.released[serviceID] = ip
+	return nil
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sky-uk/merlin/types"
+	"github.com/sky-uk/merlin/validation"
+)
+
+// validService returns an InvalidArgument status error if the service is invalid or exceeds the limits.
+func (s *server) validService(svc *types.VirtualService) error {
+	if err := s.opts.Limits.Service(svc); err != nil {
+		return err
+	}
+	return validation.Service(svc)
+}
+
+// validServer returns an InvalidArgument status error if the server is invalid or exceeds the limits.
+func (s *server) validServer(server *types.RealServer) error {
+	if err := s.opts.Limits.Server(server); err != nil {
+		return err
+	}
+	return validation.Server(server)
+}
+
+// checkServerLimits returns an InvalidArgument status error if the changes would add more servers to a service
+// than the limit.
+func (s *server) checkServerLimits(ctx context.Context, changes ...*types.Change) error {
+	if s.opts.Limits.MaxServersPerService == 0 {
+		return nil
+	}
+	// servers of each service after the changes, read from the store when first needed
+	servers := make(map[string]map[string]bool)
+	added := make(map[string]bool)
+	for _, change := range changes {
+		switch {
+		case change.Service != nil && change.Action == types.Change_DELETE:
+			servers[change.Service.Id] = make(map[string]bool)
+		case change.Server != nil:
+			serviceID := change.Server.ServiceID
+			if servers[serviceID] == nil {
+				current, err := s.store.ListServers(ctx, serviceID)
+				if err != nil {
+					return fmt.Errorf("failed to list servers of %s to check limits: %v", serviceID, err)
+				}
+				servers[serviceID] = make(map[string]bool)
+				for _, server := range current {
+					servers[serviceID][validation.ServerID(serviceID, server.Key)] = true
+				}
+			}
+			id := validation.ServerID(serviceID, change.Server.Key)
+			if change.Action == types.Change_DELETE {
+				delete(servers[serviceID], id)
+				continue
+			}
+			servers[serviceID][id] = true
+			added[serviceID] = true
+		}
+	}
+	for serviceID := range added {
+		if err := s.opts.Limits.Servers(serviceID, len(servers[serviceID])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
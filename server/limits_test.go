@@ -0,0 +1,73 @@
+package server
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sky-uk/merlin/store"
+	"github.com/sky-uk/merlin/types"
+	"github.com/sky-uk/merlin/validation"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var _ = Describe("Limits", func() {
+	var (
+		ctx context.Context
+		st  store.Store
+		s   types.MerlinServer
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		st = store.NewMemory()
+		s = New(st, nil, testNode, nil, nil, Options{Limits: validation.Limits{
+			MaxLabels:            1,
+			MaxServersPerService: 2,
+		}})
+	})
+
+	It("should reject services over the limits", func() {
+		svc := testService("svc", "10.10.10.10", 80)
+		svc.Labels = map[string]string{"a": "1", "b": "2"}
+		_, err := s.CreateService(ctx, svc)
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+
+		svc.Labels = map[string]string{"a": "1"}
+		_, err = s.CreateService(ctx, svc)
+		Expect(err).ToNot(HaveOccurred())
+		update := testService("svc", "10.10.10.10", 80)
+		update.Labels = map[string]string{"a": "1", "b": "2"}
+		_, err = s.UpdateService(ctx, update)
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+	})
+
+	It("should limit the servers of a service", func() {
+		_, err := s.CreateService(ctx, testService("svc", "10.10.10.10", 80))
+		Expect(err).ToNot(HaveOccurred())
+		for _, ip := range []string{"172.16.1.1", "172.16.1.2"} {
+			_, err := s.CreateServer(ctx, testServer("svc", ip))
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		_, err = s.CreateServer(ctx, testServer("svc", "172.16.1.3"))
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+		servers, err := st.ListServers(ctx, "svc")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(servers).To(HaveLen(2))
+	})
+
+	It("should reject snapshots over the limits before diffing them", func() {
+		_, err := s.ApplySnapshot(ctx, &types.ApplySnapshotRequest{Snapshot: &types.Snapshot{
+			Services: []*types.VirtualService{testService("svc", "10.10.10.10", 80)},
+			Servers: []*types.RealServer{
+				testServer("svc", "172.16.1.1"),
+				testServer("svc", "172.16.1.2"),
+				testServer("svc", "172.16.1.3"),
+			},
+		}})
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+		Expect(err.Error()).To(ContainSubstring("limited to 2 servers"))
+	})
+})
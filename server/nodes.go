@@ -0,0 +1,61 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	log "github.com/sirupsen/logrus"
+	"github.com/sky-uk/merlin/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *server) Info(ctx context.Context, _ *empty.Empty) (*types.InfoResponse, error) {
+	resp := &types.InfoResponse{Node: s.node()}
+
+	// store errors are reported rather than returned, as info is used to diagnose them
+	snapshot, err := s.GetSnapshot(ctx, &empty.Empty{})
+	if err != nil {
+		resp.StoreError = err.Error()
+		return resp, nil
+	}
+	resp.Services = uint32(len(snapshot.Services))
+	resp.Servers = uint32(len(snapshot.Servers))
+	return resp, nil
+}
+
+func (s *server) ListNodes(ctx context.Context, _ *empty.Empty) (*types.ListNodesResponse, error) {
+	nodes, err := s.store.ListNodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &types.ListNodesResponse{Nodes: nodes}, nil
+}
+
+func (s *server) SetMaintenance(ctx context.Context, req *types.SetMaintenanceRequest) (*empty.Empty, error) {
+	if req.Node == "" {
+		return emptyResponse, status.Error(codes.InvalidArgument, "node required")
+	}
+
+	// maintenance can always be disabled, so it can be cleared for nodes which are gone
+	if req.Enabled {
+		nodes, err := s.store.ListNodes(ctx)
+		if err != nil {
+			return emptyResponse, err
+		}
+		var found bool
+		for _, node := range nodes {
+			found = found || node.Name == req.Node
+		}
+		if !found {
+			return emptyResponse, status.Errorf(codes.NotFound, "node %s isn't registered", req.Node)
+		}
+	}
+
+	if err := s.store.SetMaintenance(ctx, req.Node, req.Enabled); err != nil {
+		return emptyResponse, fmt.Errorf("failed to set maintenance: %v", err)
+	}
+	log.Infof("Set maintenance of %s to %v", req.Node, req.Enabled)
+	return emptyResponse, nil
+}
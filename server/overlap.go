@@ -0,0 +1,78 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sky-uk/merlin/types"
+	"github.com/sky-uk/merlin/validation"
+)
+
+// checkOverlaps returns a codes.InvalidArgument error if the changes would give a server the address of a virtual
+// service, or a port other than its service's without MASQ, including servers and services already in the store.
+// Conflicts which only involve unchanged servers and services aren't checked, so they don't block other changes.
+func (s *server) checkOverlaps(ctx context.Context, changes ...*types.Change) error {
+	current, err := s.store.ListServices(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list services to check overlaps: %v", err)
+	}
+	services := make(map[string]*types.VirtualService)
+	for _, svc := range current {
+		services[svc.Id] = svc
+	}
+
+	servers := make(map[string]*types.RealServer)
+	deleted := make(map[string]bool)
+	var changedServices []*types.VirtualService
+	var changedServers []*types.RealServer
+	for _, change := range changes {
+		switch {
+		case change.Service != nil && change.Action == types.Change_DELETE:
+			delete(services, change.Service.Id)
+		case change.Service != nil:
+			services[change.Service.Id] = change.Service
+			changedServices = append(changedServices, change.Service)
+		case change.Server != nil && change.Action == types.Change_DELETE:
+			id := validation.ServerID(change.Server.ServiceID, change.Server.Key)
+			delete(servers, id)
+			deleted[id] = true
+		case change.Server != nil:
+			id := validation.ServerID(change.Server.ServiceID, change.Server.Key)
+			delete(deleted, id)
+			servers[id] = change.Server
+		}
+	}
+	for _, server := range servers {
+		changedServers = append(changedServers, server)
+	}
+	var allServices []*types.VirtualService
+	for _, svc := range services {
+		allServices = append(allServices, svc)
+	}
+	if err := validation.Overlaps(changedServers, allServices); err != nil {
+		return err
+	}
+	if err := validation.PortMappings(changedServers, allServices); err != nil {
+		return err
+	}
+	if len(changedServices) == 0 {
+		return nil
+	}
+
+	// changed services can overlap servers already in the store
+	stored, err := s.store.ListAllServers(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list servers to check overlaps: %v", err)
+	}
+	var allServers []*types.RealServer
+	for _, server := range stored {
+		id := validation.ServerID(server.ServiceID, server.Key)
+		if !deleted[id] && servers[id] == nil {
+			allServers = append(allServers, server)
+		}
+	}
+	if err := validation.Overlaps(allServers, changedServices); err != nil {
+		return err
+	}
+	return validation.PortMappings(allServers, changedServices)
+}
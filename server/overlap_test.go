@@ -0,0 +1,79 @@
+package server
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sky-uk/merlin/store"
+	"github.com/sky-uk/merlin/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var _ = Describe("Overlaps", func() {
+	var (
+		ctx context.Context
+		s   types.MerlinServer
+	)
+
+	newServer := func(ip string, forward types.ForwardMethod) *types.RealServer {
+		server := testServer("svc", ip)
+		server.Key.Port, server.Config.Forward = 80, forward
+		return server
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		s = New(store.NewMemory(), nil, testNode, nil, nil, Options{})
+		_, err := s.CreateService(ctx, testService("svc", "10.10.10.10", 80))
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should reject servers with the address of their service", func() {
+		_, err := s.CreateServer(ctx, newServer("10.10.10.10", types.ForwardMethod_ROUTE))
+
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+		Expect(status.Convert(err).Message()).To(ContainSubstring("has the address of its service"))
+	})
+
+	It("should allow servers with the address of their service forwarded with LOCALNODE", func() {
+		_, err := s.CreateServer(ctx, newServer("10.10.10.10", types.ForwardMethod_LOCALNODE))
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = s.UpdateServer(ctx, newServer("10.10.10.10", types.ForwardMethod_ROUTE))
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+	})
+
+	It("should reject servers which are other virtual services", func() {
+		_, err := s.CreateService(ctx, testService("other", "10.10.10.11", 80))
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = s.CreateServer(ctx, newServer("10.10.10.11", types.ForwardMethod_ROUTE))
+
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+		Expect(status.Convert(err).Message()).To(ContainSubstring("is the virtual service other"))
+	})
+
+	It("should only allow servers on another port than their service when forwarded with MASQ", func() {
+		server := newServer("172.16.1.1", types.ForwardMethod_MASQ)
+		server.Key.Port = 8080
+		_, err := s.CreateServer(ctx, server)
+		Expect(err).ToNot(HaveOccurred())
+
+		server.Config.Forward = types.ForwardMethod_ROUTE
+		_, err = s.UpdateServer(ctx, server)
+
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+		Expect(status.Convert(err).Message()).To(ContainSubstring("only possible when forwarded with MASQ"))
+	})
+
+	It("should reject virtual services which are existing servers", func() {
+		_, err := s.CreateServer(ctx, newServer("172.16.1.1", types.ForwardMethod_ROUTE))
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = s.CreateService(ctx, testService("other", "172.16.1.1", 80))
+
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+	})
+})
@@ -0,0 +1,151 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/sky-uk/merlin/types"
+	"github.com/sky-uk/merlin/validation"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Quota limits the services of a namespace, and the servers of each of its services. 0 is unlimited.
+type Quota struct {
+	Services          int
+	ServersPerService int
+}
+
+// Quotas of namespaces, so one team can't exhaust the capacity of a shared director. The namespace of a service is
+// the value of its Label, or empty if it doesn't have it. Quotas are only checked when creating services and servers,
+// or moving services between namespaces, so lowering a quota doesn't block other changes.
+type Quotas struct {
+	// Label of services with their namespace.
+	Label string
+	// Default quota of namespaces without their own.
+	Default Quota
+	// Namespaces with their own quota.
+	Namespaces map[string]Quota
+}
+
+// ParseQuota parses a quota of the form services=10,servers=50, where servers is the limit per service.
+// Limits which aren't set are unlimited.
+func ParseQuota(s string) (Quota, error) {
+	var q Quota
+	for _, limit := range strings.Split(s, ",") {
+		parts := strings.SplitN(strings.TrimSpace(limit), "=", 2)
+		if len(parts) != 2 {
+			return q, fmt.Errorf("invalid quota limit %q, must be name=value", limit)
+		}
+		value, err := strconv.Atoi(parts[1])
+		if err != nil || value < 0 {
+			return q, fmt.Errorf("invalid quota limit %q, value must be a non-negative integer", limit)
+		}
+		switch parts[0] {
+		case "services":
+			q.Services = value
+		case "servers":
+			q.ServersPerService = value
+		default:
+			return q, fmt.Errorf("unknown quota limit %q, must be services or servers", parts[0])
+		}
+	}
+	return q, nil
+}
+
+func (q Quotas) enabled() bool {
+	return q.Default != Quota{} || len(q.Namespaces) > 0
+}
+
+func (q Quotas) namespace(svc *types.VirtualService) string {
+	return svc.Labels[q.Label]
+}
+
+func (q Quotas) quota(namespace string) Quota {
+	if quota, ok := q.Namespaces[namespace]; ok {
+		return quota
+	}
+	return q.Default
+}
+
+// checkQuotas returns a codes.ResourceExhausted error if the changes would exceed the quota of a namespace they
+// add services or servers to.
+func (s *server) checkQuotas(ctx context.Context, changes ...*types.Change) error {
+	quotas := s.opts.Quotas
+	if !quotas.enabled() {
+		return nil
+	}
+	current, err := s.GetSnapshot(ctx, &empty.Empty{})
+	if err != nil {
+		return fmt.Errorf("failed to read current state to check quotas: %v", err)
+	}
+
+	services := make(map[string]*types.VirtualService)
+	for _, svc := range current.Services {
+		services[svc.Id] = svc
+	}
+	servers := make(map[string]map[string]bool)
+	for _, server := range current.Servers {
+		if servers[server.ServiceID] == nil {
+			servers[server.ServiceID] = make(map[string]bool)
+		}
+		servers[server.ServiceID][validation.ServerID(server.ServiceID, server.Key)] = true
+	}
+
+	// namespaces and services which grow, so need checking
+	grownNamespaces := make(map[string]bool)
+	grownServices := make(map[string]bool)
+	for _, change := range changes {
+		switch {
+		case change.Service != nil && change.Action == types.Change_DELETE:
+			delete(services, change.Service.Id)
+			delete(servers, change.Service.Id)
+		case change.Service != nil:
+			prev := services[change.Service.Id]
+			namespace := quotas.namespace(change.Service)
+			if prev == nil || quotas.namespace(prev) != namespace {
+				grownNamespaces[namespace] = true
+				grownServices[change.Service.Id] = true
+			}
+			services[change.Service.Id] = change.Service
+		case change.Server != nil:
+			id := validation.ServerID(change.Server.ServiceID, change.Server.Key)
+			if change.Action == types.Change_DELETE {
+				delete(servers[change.Server.ServiceID], id)
+				continue
+			}
+			if servers[change.Server.ServiceID] == nil {
+				servers[change.Server.ServiceID] = make(map[string]bool)
+			}
+			if !servers[change.Server.ServiceID][id] {
+				grownServices[change.Server.ServiceID] = true
+			}
+			servers[change.Server.ServiceID][id] = true
+		}
+	}
+
+	counts := make(map[string]int)
+	for _, svc := range services {
+		counts[quotas.namespace(svc)]++
+	}
+	for namespace := range grownNamespaces {
+		if limit := quotas.quota(namespace).Services; limit > 0 && counts[namespace] > limit {
+			return status.Errorf(codes.ResourceExhausted, "namespace %q is limited to %d services", namespace, limit)
+		}
+	}
+	for id := range grownServices {
+		svc := services[id]
+		if svc == nil {
+			continue
+		}
+		namespace := quotas.namespace(svc)
+		if limit := quotas.quota(namespace).ServersPerService; limit > 0 && len(servers[id]) > limit {
+			return status.Errorf(codes.ResourceExhausted, "services in namespace %q are limited to %d servers",
+				namespace, limit)
+		}
+	}
+	return nil
+}
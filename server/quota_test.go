@@ -0,0 +1,116 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"github.com/sky-uk/merlin/store"
+	"github.com/sky-uk/merlin/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestServer(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Server Suite")
+}
+
+var _ = Describe("Quotas", func() {
+	var (
+		ctx context.Context
+		s   types.MerlinServer
+	)
+
+	service := func(id, namespace string, port uint32) *types.VirtualService {
+		svc := testService(id, "10.10.10.10", port)
+		svc.Labels = map[string]string{"namespace": namespace}
+		return svc
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		s = New(store.NewMemory(), nil, testNode, nil, nil, Options{Quotas: Quotas{
+			Label:      "namespace",
+			Default:    Quota{Services: 1, ServersPerService: 1},
+			Namespaces: map[string]Quota{"payments": {Services: 2}},
+		}})
+	})
+
+	It("should limit the services of a namespace", func() {
+		_, err := s.CreateService(ctx, service("svc1", "search", 80))
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = s.CreateService(ctx, service("svc2", "search", 81))
+
+		Expect(status.Code(err)).To(Equal(codes.ResourceExhausted))
+		_, err = s.CreateService(ctx, service("svc3", "other", 82))
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should use the quota of a namespace over the default", func() {
+		for i, id := range []string{"svc1", "svc2"} {
+			_, err := s.CreateService(ctx, service(id, "payments", uint32(80+i)))
+			Expect(err).ToNot(HaveOccurred())
+		}
+		_, err := s.CreateService(ctx, service("svc3", "payments", 82))
+		Expect(status.Code(err)).To(Equal(codes.ResourceExhausted))
+
+		for _, ip := range []string{"172.16.1.1", "172.16.1.2"} {
+			_, err := s.CreateServer(ctx, testServer("svc1", ip))
+			Expect(err).ToNot(HaveOccurred())
+		}
+	})
+
+	It("should limit the servers of each service", func() {
+		_, err := s.CreateService(ctx, service("svc1", "search", 80))
+		Expect(err).ToNot(HaveOccurred())
+		_, err = s.CreateServer(ctx, testServer("svc1", "172.16.1.1"))
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = s.CreateServer(ctx, testServer("svc1", "172.16.1.2"))
+
+		Expect(status.Code(err)).To(Equal(codes.ResourceExhausted))
+	})
+
+	It("should limit moving services to a full namespace", func() {
+		_, err := s.CreateService(ctx, service("svc1", "search", 80))
+		Expect(err).ToNot(HaveOccurred())
+		_, err = s.CreateService(ctx, service("svc2", "other", 81))
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = s.UpdateService(ctx, service("svc2", "search", 81))
+
+		Expect(status.Code(err)).To(Equal(codes.ResourceExhausted))
+	})
+
+	It("should limit snapshots, including dry runs", func() {
+		snapshot := &types.Snapshot{Services: []*types.VirtualService{
+			service("svc1", "search", 80),
+			service("svc2", "search", 81),
+		}}
+
+		_, err := s.ApplySnapshot(ctx, &types.ApplySnapshotRequest{Snapshot: snapshot, DryRun: true})
+
+		Expect(status.Code(err)).To(Equal(codes.ResourceExhausted))
+	})
+
+	DescribeTable("parses quotas", func(input string, expected Quota, valid bool) {
+		q, err := ParseQuota(input)
+
+		if valid {
+			Expect(err).ToNot(HaveOccurred())
+			Expect(q).To(Equal(expected))
+		} else {
+			Expect(err).To(HaveOccurred())
+		}
+	},
+		Entry("both limits", "services=10,servers=50", Quota{Services: 10, ServersPerService: 50}, true),
+		Entry("one limit", "servers=5", Quota{ServersPerService: 5}, true),
+		Entry("negative limit", "services=-1", Quota{}, false),
+		Entry("unknown limit", "connections=1", Quota{}, false),
+		Entry("missing value", "services", Quota{}, false),
+	)
+})
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/empty"
+	log "github.com/sirupsen/logrus"
+	"github.com/sky-uk/merlin/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultRolloutBake    = time.Minute
+	defaultRolloutTimeout = 5 * time.Minute
+)
+
+func (s *server) RolloutService(ctx context.Context, req *types.RolloutServiceRequest) (*empty.Empty, error) {
+	prev, err := s.store.GetService(ctx, req.Id)
+	if err != nil {
+		return emptyResponse, fmt.Errorf("failed to check service exists: %v", err)
+	}
+	if prev == nil {
+		return emptyResponse, status.Errorf(codes.NotFound, "service %s doesn't exist", req.Id)
+	}
+	next := proto.Clone(prev).(*types.VirtualService)
+
+	if req.Abort {
+		if prev.Rollout == nil {
+			return emptyResponse, status.Errorf(codes.FailedPrecondition, "service %s has no rollout", req.Id)
+		}
+		next.Rollout = nil
+		admitted, err := s.admit(ctx, false, &types.Change{Action: types.Change_UPDATE, Service: next})
+		if err != nil {
+			return emptyResponse, err
+		}
+		if err := s.store.PutService(ctx, admitted[0].Service); err != nil {
+			return emptyResponse, fmt.Errorf("failed to abort rollout: %v", err)
+		}
+		s.record(ctx, admitted...)
+		log.Infof("Aborted rollout of %s", req.Id)
+		return emptyResponse, nil
+	}
+
+	config := proto.Clone(prev.Config).(*types.VirtualService_Config)
+	// clear flags so they are replaced
+	if len(req.GetConfig().GetFlags()) > 0 {
+		config.Flags = nil
+	}
+	proto.Merge(config, req.Config)
+	if proto.Equal(config, prev.Config) {
+		return emptyResponse, status.Errorf(codes.InvalidArgument, "rollout doesn't change the config of %s", req.Id)
+	}
+	next.Config = config
+	if err := s.validService(next); err != nil {
+		return emptyResponse, err
+	}
+
+	canaries := req.CanaryNodes
+	if len(canaries) == 0 {
+		if canaries, err = s.chooseCanaries(ctx, prev, int(req.Canaries)); err != nil {
+			return emptyResponse, err
+		}
+	}
+	bake, timeout := defaultRolloutBake, defaultRolloutTimeout
+	if req.Bake != nil {
+		if bake, err = ptypes.Duration(req.Bake); err != nil || bake < 0 {
+			return emptyResponse, status.Errorf(codes.InvalidArgument, "invalid bake %v", req.Bake)
+		}
+	}
+	if req.Timeout != nil {
+		if timeout, err = ptypes.Duration(req.Timeout); err != nil || timeout <= 0 {
+			return emptyResponse, status.Errorf(codes.InvalidArgument, "invalid timeout %v", req.Timeout)
+		}
+	}
+
+	// the service keeps its config until the rollout is promoted
+	next.Config = prev.Config
+	next.Rollout = &types.Rollout{
+		Config:      config,
+		CanaryNodes: canaries,
+		State:       types.Rollout_CANARY,
+		Started:     ptypes.TimestampNow(),
+		Bake:        ptypes.DurationProto(bake),
+		Timeout:     ptypes.DurationProto(timeout),
+	}
+	admitted, err := s.admit(ctx, false, &types.Change{Action: types.Change_UPDATE, Service: next})
+	if err != nil {
+		return emptyResponse, err
+	}
+	if err := s.store.PutService(ctx, admitted[0].Service); err != nil {
+		return emptyResponse, fmt.Errorf("failed to start rollout: %v", err)
+	}
+
+	s.record(ctx, admitted...)
+	log.Infof("Started rollout of %s to canaries %v: %v", req.Id, canaries, config.PrettyString())
+	return emptyResponse, nil
+}
+
+// chooseCanaries returns the first num nodes by name which reconcile the service, and aren't in maintenance.
+func (s *server) chooseCanaries(ctx context.Context, svc *types.VirtualService, num int) ([]string, error) {
+	if num == 0 {
+		num = 1
+	}
+	selector, err := types.ParseSelector(svc.NodeSelector)
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := s.store.ListNodes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %v", err)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Name < nodes[j].Name })
+
+	var canaries []string
+	for _, node := range nodes {
+		if len(canaries) == num {
+			break
+		}
+		if node.Reconcile && !node.Maintenance && selector.Matches(node.Labels) {
+			canaries = append(canaries, node.Name)
+		}
+	}
+	if len(canaries) == 0 {
+		return nil, status.Errorf(codes.FailedPrecondition, "no nodes reconcile %s to be canaries", svc.Id)
+	}
+	return canaries, nil
+}
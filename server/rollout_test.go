@@ -0,0 +1,101 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sky-uk/merlin/store"
+	"github.com/sky-uk/merlin/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var _ = Describe("RolloutService", func() {
+	var (
+		ctx context.Context
+		st  store.Store
+		s   types.MerlinServer
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		st = store.NewMemory()
+		s = New(st, nil, testNode, nil, nil, Options{})
+		_, err := s.CreateService(ctx, &types.VirtualService{
+			Id:           "svc1",
+			Key:          &types.VirtualService_Key{Ip: "10.10.10.10", Port: 80, Protocol: types.Protocol_TCP},
+			Config:       &types.VirtualService_Config{Scheduler: "sh"},
+			NodeSelector: "pool=edge",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		for _, node := range []*types.Node{
+			{Name: "node3", Reconcile: true, Labels: map[string]string{"pool": "edge"}},
+			{Name: "node1", Reconcile: true, Labels: map[string]string{"pool": "internal"}},
+			{Name: "node2", Reconcile: true, Labels: map[string]string{"pool": "edge"}},
+		} {
+			Expect(st.PutNode(ctx, node, time.Minute)).To(Succeed())
+		}
+	})
+
+	rollout := func() *types.Rollout {
+		svc, err := st.GetService(ctx, "svc1")
+		Expect(err).ToNot(HaveOccurred())
+		return svc.Rollout
+	}
+
+	It("should start a rollout to canaries reconciling the service, keeping its config", func() {
+		_, err := s.RolloutService(ctx, &types.RolloutServiceRequest{
+			Id:     "svc1",
+			Config: &types.VirtualService_Config{Scheduler: "wrr"},
+		})
+
+		Expect(err).ToNot(HaveOccurred())
+		svc, _ := st.GetService(ctx, "svc1")
+		Expect(svc.Config.Scheduler).To(Equal("sh"))
+		Expect(svc.Rollout.State).To(Equal(types.Rollout_CANARY))
+		Expect(svc.Rollout.Config.Scheduler).To(Equal("wrr"))
+		Expect(svc.Rollout.CanaryNodes).To(Equal([]string{"node2"}))
+	})
+
+	It("should reject rollouts which don't change the config", func() {
+		_, err := s.RolloutService(ctx, &types.RolloutServiceRequest{
+			Id:     "svc1",
+			Config: &types.VirtualService_Config{Scheduler: "sh"},
+		})
+
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+	})
+
+	It("should abort rollouts", func() {
+		_, err := s.RolloutService(ctx, &types.RolloutServiceRequest{
+			Id:          "svc1",
+			Config:      &types.VirtualService_Config{Scheduler: "wrr"},
+			CanaryNodes: []string{"node3"},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rollout().CanaryNodes).To(Equal([]string{"node3"}))
+
+		_, err = s.RolloutService(ctx, &types.RolloutServiceRequest{Id: "svc1", Abort: true})
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rollout()).To(BeNil())
+	})
+
+	It("should replace rollouts when the config is updated", func() {
+		_, err := s.RolloutService(ctx, &types.RolloutServiceRequest{
+			Id:     "svc1",
+			Config: &types.VirtualService_Config{Scheduler: "wrr"},
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = s.UpdateService(ctx, &types.VirtualService{
+			Id:     "svc1",
+			Config: &types.VirtualService_Config{Scheduler: "rr"},
+		})
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rollout()).To(BeNil())
+	})
+})
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sky-uk/merlin/types"
+	"github.com/sky-uk/merlin/validation"
+)
+
+// checkRules returns a codes.InvalidArgument error if a service or server created or updated by the changes
+// violates one of the rules.
+func (s *server) checkRules(ctx context.Context, changes ...*types.Change) error {
+	if len(s.opts.Rules) == 0 {
+		return nil
+	}
+	services := make(map[string]*types.VirtualService)
+	for _, change := range changes {
+		if change.Service != nil && change.Action != types.Change_DELETE {
+			services[change.Service.Id] = change.Service
+		}
+	}
+
+	for _, change := range changes {
+		switch {
+		case change.Action == types.Change_DELETE:
+		case change.Service != nil:
+			if err := validation.CheckRules(s.opts.Rules, change.Service, nil); err != nil {
+				return err
+			}
+		case change.Server != nil:
+			svc := services[change.Server.ServiceID]
+			if svc == nil {
+				var err error
+				if svc, err = s.store.GetService(ctx, change.Server.ServiceID); err != nil {
+					return fmt.Errorf("failed to get service %s to check rules: %v", change.Server.ServiceID, err)
+				}
+				if svc == nil {
+					continue
+				}
+				services[svc.Id] = svc
+			}
+			if err := validation.CheckRules(s.opts.Rules, svc, change.Server); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
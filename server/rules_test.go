@@ -0,0 +1,51 @@
+package server
+
+import (
+	"context"
+
+	"github.com/golang/protobuf/ptypes/wrappers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sky-uk/merlin/store"
+	"github.com/sky-uk/merlin/types"
+	"github.com/sky-uk/merlin/validation"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var _ = Describe("Rules", func() {
+	var (
+		ctx context.Context
+		s   types.MerlinServer
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		rules, err := validation.ParseRules([]byte("rules:\n" +
+			"- name: vips\n  vip-cidrs: [10.10.0.0/16]\n" +
+			"- name: servers\n  server-cidrs: [172.16.0.0/12]\n"))
+		Expect(err).ToNot(HaveOccurred())
+		s = New(store.NewMemory(), nil, testNode, nil, nil, Options{Rules: rules})
+	})
+
+	It("should reject services which violate a rule", func() {
+		_, err := s.CreateService(ctx, testService("svc", "10.20.10.10", 80))
+
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+		Expect(status.Convert(err).Message()).To(ContainSubstring("violates rule vips"))
+	})
+
+	It("should reject servers which violate a rule", func() {
+		_, err := s.CreateService(ctx, testService("svc", "10.10.10.10", 80))
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = s.CreateServer(ctx, &types.RealServer{
+			ServiceID: "svc",
+			Key:       &types.RealServer_Key{Ip: "192.168.1.1", Port: 8080},
+			Config:    &types.RealServer_Config{Weight: &wrappers.UInt32Value{Value: 1}, Forward: types.ForwardMethod_MASQ},
+		})
+
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+		Expect(status.Convert(err).Message()).To(ContainSubstring("violates rule servers"))
+	})
+})
@@ -2,76 +2,124 @@ package server
 
 import (
 	"context"
+	"time"
 
 	"fmt"
-
-	"net"
-
-	"math"
-
-	"net/url"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
 	"github.com/golang/protobuf/ptypes/empty"
 	"github.com/golang/protobuf/ptypes/wrappers"
 	log "github.com/sirupsen/logrus"
+	"github.com/sky-uk/merlin/ipvs"
 	"github.com/sky-uk/merlin/store"
 	"github.com/sky-uk/merlin/types"
+	"github.com/sky-uk/merlin/validation"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
+// HealthFunc returns the health of a real server on this node.
+type HealthFunc func(serviceID string, key *types.RealServer_Key) types.Health
+
+// ErrorsFunc returns the errors reconciling a service on this node.
+type ErrorsFunc func(serviceID string) []string
+
 type server struct {
-	store store.Store
+	store  store.Store
+	ipvs   ipvs.IPVS
+	node   func() *types.Node
+	health HealthFunc
+	errors ErrorsFunc
+	opts   Options
+	// ipamMu serializes allocating IPs from pools with changing them.
+	ipamMu sync.Mutex
+	// candidateMu serializes changes to the candidate config.
+	candidateMu sync.Mutex
+}
+
+// Options of the server.
+type Options struct {
+	// Quotas limit the services and servers of each namespace.
+	Quotas Quotas
+	// BlockOrphans refuses to delete services which have servers, as they would be orphaned.
+	BlockOrphans bool
+	// Rules of the site which services and servers must follow.
+	Rules []validation.Rule
+	// Admit reviews every write before it's committed, such as an admission webhook.
+	Admit AdmitFunc
+	// Limits on the size of services and servers.
+	Limits validation.Limits
+	// IPAM allocates the IPs of services in pools, instead of merlin choosing them.
+	IPAM IPAM
+	// ApplyBatchSize is how many changes of a snapshot are applied to the store in each request, defaults to 1.
+	ApplyBatchSize int
+	// SetSyncRole switches the IPVS connection sync daemon of this node, which is unmanaged if nil.
+	SetSyncRole SyncRoleFunc
 }
 
-// New merlin server implementation.
-func New(store store.Store) types.MerlinServer {
+// New merlin server implementation. ipvs is used for node local requests, such as stats,
+// and may be nil if IPVS is disabled on this node. node returns the current state of this node,
+// health the health of its real servers, and errors the errors reconciling its services.
+func New(store store.Store, ipvs ipvs.IPVS, node func() *types.Node, health HealthFunc,
+	errors ErrorsFunc, opts Options) types.MerlinServer {
 	return &server{
-		store: store,
+		store:  store,
+		ipvs:   ipvs,
+		node:   node,
+		health: health,
+		errors: errors,
+		opts:   opts,
 	}
 }
 
+const (
+	// historyTTL is how long changes are kept in the store for describing services.
+	historyTTL = 7 * 24 * time.Hour
+)
+
 var (
 	emptyResponse = &empty.Empty{}
 )
 
-func validateService(service *types.VirtualService) error {
-	if len(service.Id) == 0 {
-		return status.Error(codes.InvalidArgument, "service id required")
-	}
-	if service.Key == nil {
-		return status.Error(codes.InvalidArgument, "service ip:port:protocol key required")
-	}
-	if len(service.Key.Ip) == 0 {
-		return status.Error(codes.InvalidArgument, "service IP required")
-	}
-	if net.ParseIP(service.Key.Ip) == nil {
-		return status.Error(codes.InvalidArgument, "unable to parse service IP")
+// record adds changes made to the store to the history of their services. Failures are logged rather than
+// returned, as the changes have already been made.
+func (s *server) record(ctx context.Context, changes ...*types.Change) {
+	now := ptypes.TimestampNow()
+	node := s.node().Name
+	var entries []*types.HistoryEntry
+	for _, change := range changes {
+		entries = append(entries, &types.HistoryEntry{Time: now, Node: node, Change: change})
 	}
-	if service.Key.Port == 0 {
-		return status.Error(codes.InvalidArgument, "service port required")
+	if err := s.store.AddHistory(ctx, entries, historyTTL); err != nil {
+		log.Warnf("Unable to record history of %d changes: %v", len(changes), err)
 	}
-	if service.Key.Port > math.MaxUint16 {
-		return status.Errorf(codes.InvalidArgument, "invalid port %d", service.Key.Port)
-	}
-	if service.Key.Protocol == 0 {
-		return status.Error(codes.InvalidArgument, "service protocol required")
+}
+
+func (s *server) CreateService(ctx context.Context, service *types.VirtualService) (*empty.Empty, error) {
+	if service.Pool == "" {
+		return s.createService(ctx, service)
 	}
-	if _, ok := types.Protocol_name[int32(service.Key.Protocol)]; !ok {
-		return status.Errorf(codes.InvalidArgument, "unrecognized protocol %d", service.Key.Protocol)
+	s.ipamMu.Lock()
+	defer s.ipamMu.Unlock()
+	if service.Key == nil || service.Key.Ip != "" {
+		return s.createService(ctx, service)
 	}
-	if service.Config == nil {
-		return status.Error(codes.InvalidArgument, "service config required")
+	if err := s.allocateIP(ctx, service); err != nil {
+		return emptyResponse, err
 	}
-	if service.Config.Scheduler == "" {
-		return status.Error(codes.InvalidArgument, "service scheduler required")
+	resp, err := s.createService(ctx, service)
+	if err != nil {
+		s.releaseIP(ctx, service.Id, service.Key.Ip)
 	}
-	return nil
+	return resp, err
 }
 
-func (s *server) CreateService(ctx context.Context, service *types.VirtualService) (*empty.Empty, error) {
-	if err := validateService(service); err != nil {
+func (s *server) createService(ctx context.Context, service *types.VirtualService) (*empty.Empty, error) {
+	if err := s.validService(service); err != nil {
 		return emptyResponse, err
 	}
 
@@ -82,11 +130,30 @@ func (s *server) CreateService(ctx context.Context, service *types.VirtualServic
 	if prev != nil {
 		return emptyResponse, status.Errorf(codes.AlreadyExists, "service %s already exists", service.Id)
 	}
+	admitted, err := s.admit(ctx, false, &types.Change{Action: types.Change_CREATE, Service: service})
+	if err != nil {
+		return emptyResponse, err
+	}
+	change := admitted[0]
+	service = change.Service
+	if err := s.checkQuotas(ctx, change); err != nil {
+		return emptyResponse, err
+	}
+	if err := s.checkOverlaps(ctx, change); err != nil {
+		return emptyResponse, err
+	}
+	if err := s.checkRules(ctx, change); err != nil {
+		return emptyResponse, err
+	}
+	if err := s.checkPools(ctx, change); err != nil {
+		return emptyResponse, err
+	}
 
 	if err := s.store.PutService(ctx, service); err != nil {
 		return emptyResponse, fmt.Errorf("failed to create service: %v", err)
 	}
 
+	s.record(ctx, change)
 	log.Infof("Created virtual service: %v", service.PrettyString())
 	return emptyResponse, nil
 }
@@ -101,18 +168,39 @@ func (s *server) UpdateService(ctx context.Context, update *types.VirtualService
 	}
 
 	next := proto.Clone(prev).(*types.VirtualService)
-	// clear flags so they are replaced
-	if len(update.Config.Flags) > 0 {
-		next.Config.Flags = nil
+	if update.UpdateMask != nil {
+		if err := maskService(next, update); err != nil {
+			return emptyResponse, err
+		}
+	} else {
+		mergeService(next, update)
+	}
+	// changing the config replaces any rollout of it
+	if next.Rollout != nil && !proto.Equal(prev.Config, next.Config) {
+		next.Rollout = nil
 	}
-	proto.Merge(next.Config, update.Config)
 
 	if proto.Equal(prev, next) {
 		log.Infof("No update of %s", update.Id)
 		return emptyResponse, nil
 	}
 
-	if err := validateService(next); err != nil {
+	if err := s.validService(next); err != nil {
+		return emptyResponse, err
+	}
+	admitted, err := s.admit(ctx, false, &types.Change{Action: types.Change_UPDATE, Service: next})
+	if err != nil {
+		return emptyResponse, err
+	}
+	change := admitted[0]
+	next = change.Service
+	if err := s.checkQuotas(ctx, change); err != nil {
+		return emptyResponse, err
+	}
+	if err := s.checkRules(ctx, change); err != nil {
+		return emptyResponse, err
+	}
+	if err := s.checkPools(ctx, change); err != nil {
 		return emptyResponse, err
 	}
 
@@ -120,77 +208,63 @@ func (s *server) UpdateService(ctx context.Context, update *types.VirtualService
 		return emptyResponse, fmt.Errorf("failed to update service: %v", err)
 	}
 
+	s.record(ctx, change)
 	log.Infof("Updated %v", next.PrettyString())
 	return emptyResponse, nil
 }
 
-func (s *server) DeleteService(ctx context.Context, wrappedID *wrappers.StringValue) (*empty.Empty, error) {
-	id := wrappedID.GetValue()
-	if err := s.store.DeleteService(ctx, id); err != nil {
-		return emptyResponse, fmt.Errorf("failed to delete service %s: %v", id, err)
-	}
-	log.Infof("Deleted %s", id)
-	return emptyResponse, nil
-}
-
-func validateServer(server *types.RealServer) error {
-	if len(server.ServiceID) == 0 {
-		return status.Error(codes.InvalidArgument, "service ID required")
-	}
-	if server.Key == nil {
-		return status.Error(codes.InvalidArgument, "server IP:port required")
-	}
-	if len(server.Key.Ip) == 0 {
-		return status.Error(codes.InvalidArgument, "server IP required")
-	}
-	if net.ParseIP(server.Key.Ip) == nil {
-		return status.Errorf(codes.InvalidArgument, "unable to parse server IP %s", server.Key.Ip)
-	}
-	if server.Key.Port == 0 {
-		return status.Error(codes.InvalidArgument, "server port required")
+// mergeService merges the fields of update which are set into svc.
+func mergeService(svc, update *types.VirtualService) {
+	// clear flags so they are replaced
+	if len(update.GetConfig().GetFlags()) > 0 {
+		svc.Config.Flags = nil
 	}
-	if server.Key.Port > math.MaxUint16 {
-		return status.Errorf(codes.InvalidArgument, "invalid port %d", server.Key.Port)
+	proto.Merge(svc.Config, update.Config)
+	// labels are replaced as a whole rather than merged
+	if len(update.Labels) > 0 {
+		svc.Labels = update.Labels
 	}
-	if server.Config == nil {
-		return status.Error(codes.InvalidArgument, "server config required")
+	if update.NodeSelector != "" {
+		svc.NodeSelector = update.NodeSelector
 	}
-	if server.Config.Forward == types.ForwardMethod_UNSET_FORWARD_METHOD {
-		return status.Error(codes.InvalidArgument, "server forward method required")
+	if update.Pool != "" {
+		svc.Pool = update.Pool
 	}
-	if server.Config.Weight == nil {
-		return status.Error(codes.InvalidArgument, "server weight required")
+}
+
+func (s *server) DeleteService(ctx context.Context, wrappedID *wrappers.StringValue) (*empty.Empty, error) {
+	id := wrappedID.GetValue()
+	change := &types.Change{Action: types.Change_DELETE, Service: &types.VirtualService{Id: id}}
+	if _, err := s.admit(ctx, false, change); err != nil {
+		return emptyResponse, err
 	}
-	if server.HealthCheck.Endpoint.GetValue() != "" {
-		u, err := url.Parse(server.HealthCheck.Endpoint.Value)
+	if s.opts.BlockOrphans {
+		servers, err := s.store.ListServers(ctx, id)
 		if err != nil {
-			return status.Errorf(codes.InvalidArgument, "health check endpoint %q must be a valid url: %v",
-				server.HealthCheck.Endpoint, err)
-		}
-		switch u.Scheme {
-		case "http":
-			// valid
-		default:
-			return status.Errorf(codes.InvalidArgument, "health check endpoint scheme %q not recognized",
-				u.Scheme)
-		}
-		if u.Port() == "" {
-			return status.Errorf(codes.InvalidArgument, "health check endpoint is missing port")
+			return emptyResponse, fmt.Errorf("failed to list servers of %s: %v", id, err)
 		}
-		if server.HealthCheck.GetPeriod().GetSeconds() == 0 && server.HealthCheck.GetPeriod().GetNanos() == 0 {
-			return status.Errorf(codes.InvalidArgument, "health check period is required")
+		if len(servers) > 0 {
+			return emptyResponse, status.Errorf(codes.FailedPrecondition,
+				"service %s has %d servers, which must be deleted first", id, len(servers))
 		}
-		if server.HealthCheck.GetTimeout().GetSeconds() == 0 && server.HealthCheck.GetPeriod().GetNanos() == 0 {
-			return status.Errorf(codes.InvalidArgument, "health check timeout is required")
-		}
-		if server.HealthCheck.DownThreshold == 0 {
-			return status.Errorf(codes.InvalidArgument, "health check down threshold is required and must be > 0")
-		}
-		if server.HealthCheck.UpThreshold == 0 {
-			return status.Errorf(codes.InvalidArgument, "health check up threshold is required and must be > 0")
+	}
+	// the IPAM needs the IP of the service to release it once it's deleted
+	var prev *types.VirtualService
+	if s.opts.IPAM != nil {
+		var err error
+		if prev, err = s.store.GetService(ctx, id); err != nil {
+			return emptyResponse, fmt.Errorf("failed to get service %s: %v", id, err)
 		}
 	}
-	return nil
+	if err := s.store.DeleteService(ctx, id); err != nil {
+		return emptyResponse, fmt.Errorf("failed to delete service %s: %v", id, err)
+	}
+	s.record(ctx, change)
+	log.Infof("Deleted %s", id)
+	if prev.GetPool() != "" {
+		s.releaseIP(ctx, id, prev.Key.Ip)
+	}
+	return emptyResponse, nil
 }
 
 func (s *server) CreateServer(ctx context.Context, server *types.RealServer) (*empty.Empty, error) {
@@ -199,7 +273,7 @@ func (s *server) CreateServer(ctx context.Context, server *types.RealServer) (*e
 		server.HealthCheck = &types.RealServer_HealthCheck{}
 	}
 
-	if err := validateServer(server); err != nil {
+	if err := s.validServer(server); err != nil {
 		return emptyResponse, err
 	}
 
@@ -219,11 +293,33 @@ func (s *server) CreateServer(ctx context.Context, server *types.RealServer) (*e
 	if prev != nil {
 		return emptyResponse, status.Errorf(codes.AlreadyExists, "server %v already exists", server)
 	}
+	admitted, err := s.admit(ctx, false, &types.Change{Action: types.Change_CREATE, Server: server})
+	if err != nil {
+		return emptyResponse, err
+	}
+	change := admitted[0]
+	server = change.Server
+	if err := s.checkQuotas(ctx, change); err != nil {
+		return emptyResponse, err
+	}
+	if err := s.checkServerLimits(ctx, change); err != nil {
+		return emptyResponse, err
+	}
+	if err := s.checkOverlaps(ctx, change); err != nil {
+		return emptyResponse, err
+	}
+	if err := s.checkRules(ctx, change); err != nil {
+		return emptyResponse, err
+	}
+	if err := s.checkPools(ctx, change); err != nil {
+		return emptyResponse, err
+	}
 
 	if err := s.store.PutServer(ctx, server); err != nil {
 		return emptyResponse, fmt.Errorf("failed to create server: %v", err)
 	}
 
+	s.record(ctx, change)
 	log.Infof("Created real server: %v", server.PrettyString())
 	return emptyResponse, nil
 }
@@ -239,15 +335,12 @@ func (s *server) UpdateServer(ctx context.Context, update *types.RealServer) (*e
 	}
 
 	next := proto.Clone(prev).(*types.RealServer)
-	proto.Merge(next.Config, update.Config)
-	proto.Merge(next.HealthCheck, update.HealthCheck)
-	// force update of endpoint if set - so users can disable by setting an empty value on the endpoint
-	if update.GetHealthCheck().GetEndpoint() != nil {
-		next.HealthCheck.Endpoint = update.HealthCheck.Endpoint
-	}
-	// force update if weight is set - so users can disable by setting weight to 0
-	if update.GetConfig().GetWeight() != nil {
-		next.Config.Weight = update.Config.Weight
+	if update.UpdateMask != nil {
+		if err := maskServer(next, update); err != nil {
+			return emptyResponse, err
+		}
+	} else {
+		mergeServer(next, update)
 	}
 
 	if proto.Equal(prev, next) {
@@ -255,7 +348,22 @@ func (s *server) UpdateServer(ctx context.Context, update *types.RealServer) (*e
 		return emptyResponse, nil
 	}
 
-	if err := validateServer(next); err != nil {
+	if err := s.validServer(next); err != nil {
+		return emptyResponse, err
+	}
+	admitted, err := s.admit(ctx, false, &types.Change{Action: types.Change_UPDATE, Server: next})
+	if err != nil {
+		return emptyResponse, err
+	}
+	change := admitted[0]
+	next = change.Server
+	if err := s.checkOverlaps(ctx, change); err != nil {
+		return emptyResponse, err
+	}
+	if err := s.checkRules(ctx, change); err != nil {
+		return emptyResponse, err
+	}
+	if err := s.checkPools(ctx, change); err != nil {
 		return emptyResponse, err
 	}
 
@@ -263,31 +371,317 @@ func (s *server) UpdateServer(ctx context.Context, update *types.RealServer) (*e
 		return emptyResponse, fmt.Errorf("failed to update server: %v", err)
 	}
 
+	s.record(ctx, change)
 	log.Infof("Updated %v", next.PrettyString())
 	return emptyResponse, nil
 }
 
+// mergeServer merges the fields of update which are set into server.
+func mergeServer(server, update *types.RealServer) {
+	proto.Merge(server.Config, update.Config)
+	proto.Merge(server.HealthCheck, update.HealthCheck)
+	// force update of endpoint if set - so users can disable by setting an empty value on the endpoint
+	if update.GetHealthCheck().GetEndpoint() != nil {
+		server.HealthCheck.Endpoint = update.HealthCheck.Endpoint
+	}
+	// force update if weight is set - so users can disable by setting weight to 0
+	if update.GetConfig().GetWeight() != nil {
+		server.Config.Weight = update.Config.Weight
+		// an explicit weight replaces the weight recorded by a drain
+		server.DrainedWeight = nil
+	}
+	// labels are replaced as a whole rather than merged
+	if len(update.Labels) > 0 {
+		server.Labels = update.Labels
+	}
+}
+
 func (s *server) DeleteServer(ctx context.Context, server *types.RealServer) (*empty.Empty, error) {
+	if server.Key == nil {
+		return emptyResponse, status.Error(codes.InvalidArgument, "server key required")
+	}
+	change := &types.Change{Action: types.Change_DELETE, Server: server}
+	if _, err := s.admit(ctx, false, change); err != nil {
+		return emptyResponse, err
+	}
 	if err := s.store.DeleteServer(ctx, server.ServiceID, server.Key); err != nil {
 		return emptyResponse, fmt.Errorf("failed to delete server %s: %v", server, err)
 	}
+	s.record(ctx, change)
 	log.Infof("Deleted %s/%s", server.ServiceID, server.Key.PrettyString())
 	return emptyResponse, nil
 }
 
-func (s *server) List(ctx context.Context, _ *empty.Empty) (*types.ListResponse, error) {
+func (s *server) DrainServer(ctx context.Context, server *types.RealServer) (*empty.Empty, error) {
+	prev, err := s.store.GetServer(ctx, server.ServiceID, server.Key)
+	if err != nil {
+		return emptyResponse, fmt.Errorf("failed to check server exists: %v", err)
+	}
+	if prev == nil {
+		return emptyResponse, status.Errorf(codes.NotFound, "server %s/%s doesn't exist",
+			server.ServiceID, server.Key.PrettyString())
+	}
+	if prev.DrainedWeight != nil {
+		log.Infof("%s/%s is already drained", server.ServiceID, server.Key.PrettyString())
+		return emptyResponse, nil
+	}
+
+	next := proto.Clone(prev).(*types.RealServer)
+	next.DrainedWeight = &wrappers.UInt32Value{Value: prev.Config.GetWeight().GetValue()}
+	next.Config.Weight = &wrappers.UInt32Value{Value: 0}
+
+	admitted, err := s.admit(ctx, false, &types.Change{Action: types.Change_UPDATE, Server: next})
+	if err != nil {
+		return emptyResponse, err
+	}
+	next = admitted[0].Server
+
+	if err := s.store.PutServer(ctx, next); err != nil {
+		return emptyResponse, fmt.Errorf("failed to drain server: %v", err)
+	}
+
+	s.record(ctx, admitted...)
+	log.Infof("Drained %v", next.PrettyString())
+	return emptyResponse, nil
+}
+
+func (s *server) UndrainServer(ctx context.Context, server *types.RealServer) (*empty.Empty, error) {
+	prev, err := s.store.GetServer(ctx, server.ServiceID, server.Key)
+	if err != nil {
+		return emptyResponse, fmt.Errorf("failed to check server exists: %v", err)
+	}
+	if prev == nil {
+		return emptyResponse, status.Errorf(codes.NotFound, "server %s/%s doesn't exist",
+			server.ServiceID, server.Key.PrettyString())
+	}
+	if prev.DrainedWeight == nil {
+		log.Infof("%s/%s is not drained", server.ServiceID, server.Key.PrettyString())
+		return emptyResponse, nil
+	}
+
+	next := proto.Clone(prev).(*types.RealServer)
+	next.Config.Weight = prev.DrainedWeight
+	next.DrainedWeight = nil
+
+	admitted, err := s.admit(ctx, false, &types.Change{Action: types.Change_UPDATE, Server: next})
+	if err != nil {
+		return emptyResponse, err
+	}
+	next = admitted[0].Server
+
+	if err := s.store.PutServer(ctx, next); err != nil {
+		return emptyResponse, fmt.Errorf("failed to undrain server: %v", err)
+	}
+
+	s.record(ctx, admitted...)
+	log.Infof("Undrained %v", next.PrettyString())
+	return emptyResponse, nil
+}
+
+type serviceFilterKey struct{}
+
+// WithServiceFilter returns a context whose List requests only return the services allowed returns true for, such
+// as those a client can read.
+func WithServiceFilter(ctx context.Context, allowed func(serviceID string) bool) context.Context {
+	return context.WithValue(ctx, serviceFilterKey{}, allowed)
+}
+
+// serviceFilter returns the filter of WithServiceFilter, which allows every service if ctx doesn't have one.
+func serviceFilter(ctx context.Context) func(serviceID string) bool {
+	if allowed, ok := ctx.Value(serviceFilterKey{}).(func(string) bool); ok {
+		return allowed
+	}
+	return func(string) bool { return true }
+}
+
+func (s *server) List(ctx context.Context, req *types.ListRequest) (*types.ListResponse, error) {
+	labelSelector, err := types.ParseSelector(req.LabelSelector)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	fieldSelector, err := types.ParseFieldSelector(req.FieldSelector)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	serverSelector, err := types.ParseSelector(req.ServerLabelSelector)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if req.PageSize < 0 {
+		return nil, status.Error(codes.InvalidArgument, "page size must not be negative")
+	}
+	after, err := types.ParsePageToken(req.PageToken)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
 	svcs, err := s.store.ListServices(ctx)
 	if err != nil {
 		return nil, err
 	}
+	sort.Slice(svcs, func(i, j int) bool { return svcs[i].Id < svcs[j].Id })
 
+	allowed := serviceFilter(ctx)
 	var resp types.ListResponse
 	for _, svc := range svcs {
+		if !strings.HasPrefix(svc.Id, req.IdPrefix) || !allowed(svc.Id) || !labelSelector.Matches(svc.Labels) ||
+			!fieldSelector.Matches(types.FieldsOf(svc)) {
+			continue
+		}
+		// every matching service is counted, including those on other pages
+		resp.TotalSize++
+		if (after != "" && svc.Id <= after) || resp.NextPageToken != "" {
+			continue
+		}
+		if req.PageSize > 0 && len(resp.Items) == int(req.PageSize) {
+			resp.NextPageToken = types.PageToken(resp.Items[len(resp.Items)-1].Service.Id)
+			continue
+		}
 		servers, err := s.store.ListServers(ctx, svc.Id)
 		if err != nil {
 			return nil, err
 		}
-		resp.Items = append(resp.Items, &types.ListResponse_Item{Service: svc, Servers: servers})
+		item := &types.ListResponse_Item{Service: svc}
+		for _, server := range servers {
+			if serverSelector.Matches(server.Labels) {
+				item.Servers = append(item.Servers, server)
+			}
+		}
+		resp.Items = append(resp.Items, item)
 	}
 	return &resp, nil
 }
+
+func (s *server) GetService(ctx context.Context, wrappedID *wrappers.StringValue) (*types.GetServiceResponse,
+	error) {
+	id := wrappedID.GetValue()
+	svc, err := s.store.GetService(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service %s: %v", id, err)
+	}
+	if svc == nil {
+		return nil, status.Errorf(codes.NotFound, "service %s doesn't exist", id)
+	}
+	servers, err := s.store.ListServers(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list servers of %s: %v", id, err)
+	}
+	return &types.GetServiceResponse{Service: svc, Servers: servers}, nil
+}
+
+func (s *server) GetServer(ctx context.Context, req *types.RealServer) (*types.RealServer, error) {
+	if req.Key == nil {
+		return nil, status.Error(codes.InvalidArgument, "server key is required")
+	}
+	server, err := s.store.GetServer(ctx, req.ServiceID, req.Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get server: %v", err)
+	}
+	if server == nil {
+		return nil, status.Errorf(codes.NotFound, "server %s/%s doesn't exist", req.ServiceID,
+			req.Key.PrettyString())
+	}
+	return server, nil
+}
+
+func (s *server) Stats(ctx context.Context, _ *empty.Empty) (*types.StatsResponse, error) {
+	if s.ipvs == nil {
+		return nil, status.Error(codes.FailedPrecondition, "ipvs is disabled on this node")
+	}
+
+	stats, err := s.ipvs.Stats(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ipvs stats: %v", err)
+	}
+
+	svcs, err := s.store.ListServices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, svcStats := range stats {
+		for _, svc := range svcs {
+			if proto.Equal(svc.Key, svcStats.Key) {
+				svcStats.Id = svc.Id
+				for _, serverStats := range svcStats.Servers {
+					serverStats.Health = s.health(svc.Id, serverStats.Key)
+				}
+				break
+			}
+		}
+	}
+
+	return &types.StatsResponse{Node: s.node().Name, Services: stats}, nil
+}
+
+func (s *server) GetServerStats(ctx context.Context, wrappedID *wrappers.StringValue) (*types.GetServerStatsResponse,
+	error) {
+	if s.ipvs == nil {
+		return nil, status.Error(codes.FailedPrecondition, "ipvs is disabled on this node")
+	}
+	id := wrappedID.GetValue()
+	svc, err := s.store.GetService(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service %s: %v", id, err)
+	}
+	if svc == nil {
+		return nil, status.Errorf(codes.NotFound, "service %s doesn't exist", id)
+	}
+
+	stats, err := s.ipvs.Stats(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ipvs stats: %v", err)
+	}
+	for _, svcStats := range stats {
+		if !proto.Equal(svc.Key, svcStats.Key) {
+			continue
+		}
+		svcStats.Id = svc.Id
+		for _, serverStats := range svcStats.Servers {
+			serverStats.Health = s.health(svc.Id, serverStats.Key)
+		}
+		return &types.GetServerStatsResponse{Node: s.node().Name, Service: svcStats}, nil
+	}
+	return nil, status.Errorf(codes.NotFound, "service %s isn't in ipvs on %s", id, s.node().Name)
+}
+
+func (s *server) GetNodeState(ctx context.Context, _ *empty.Empty) (*types.NodeState, error) {
+	if s.ipvs == nil {
+		return nil, status.Error(codes.FailedPrecondition, "ipvs is disabled on this node")
+	}
+
+	svcs, err := s.ipvs.ListServices(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ipvs services: %v", err)
+	}
+	storeSvcs, err := s.store.ListServices(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &types.NodeState{Node: s.node().Name}
+	for _, svc := range svcs {
+		for _, storeSvc := range storeSvcs {
+			if proto.Equal(svc.Key, storeSvc.Key) {
+				svc.Id = storeSvc.Id
+				break
+			}
+		}
+
+		servers, err := s.ipvs.ListServers(ctx, svc.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list ipvs servers of %v: %v", svc.Key.PrettyString(), err)
+		}
+		svcState := &types.NodeState_Service{Service: svc}
+		for _, server := range servers {
+			server.ServiceID = svc.Id
+			serverState := &types.NodeState_Server{Server: server}
+			if svc.Id != "" {
+				serverState.Health = s.health(svc.Id, server.Key)
+			}
+			svcState.Servers = append(svcState.Servers, serverState)
+		}
+		state.Services = append(state.Services, svcState)
+	}
+	return state, nil
+}
@@ -0,0 +1,306 @@
+package server
+
+import (
+	"context"
+
+	"github.com/golang/protobuf/ptypes/wrappers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sky-uk/merlin/ipvs"
+	"github.com/sky-uk/merlin/store"
+	"github.com/sky-uk/merlin/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var _ = Describe("DeleteService", func() {
+	var (
+		ctx context.Context
+		st  store.Store
+	)
+
+	newServer := func(opts Options) types.MerlinServer {
+		s := New(st, nil, testNode, nil, nil, opts)
+		_, err := s.CreateService(ctx, testService("svc", "10.10.10.10", 80))
+		Expect(err).ToNot(HaveOccurred())
+		_, err = s.CreateServer(ctx, &types.RealServer{
+			ServiceID: "svc",
+			Key:       &types.RealServer_Key{Ip: "172.16.1.1", Port: 8080},
+			Config:    &types.RealServer_Config{Weight: &wrappers.UInt32Value{Value: 1}, Forward: types.ForwardMethod_MASQ},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		return s
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		st = store.NewMemory()
+	})
+
+	It("should orphan the servers of the service by default", func() {
+		s := newServer(Options{})
+
+		_, err := s.DeleteService(ctx, &wrappers.StringValue{Value: "svc"})
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(st.ListAllServers(ctx)).To(HaveLen(1))
+	})
+
+	It("should refuse to orphan servers when blocking orphans", func() {
+		s := newServer(Options{BlockOrphans: true})
+
+		_, err := s.DeleteService(ctx, &wrappers.StringValue{Value: "svc"})
+
+		Expect(status.Code(err)).To(Equal(codes.FailedPrecondition))
+		Expect(st.GetService(ctx, "svc")).ToNot(BeNil())
+	})
+})
+
+type applyCountingStore struct {
+	store.Store
+	batches   [][]*types.Change
+	nonAtomic bool
+	limit     int
+}
+
+func (s *applyCountingStore) Apply(ctx context.Context, changes []*types.Change) error {
+	s.batches = append(s.batches, changes)
+	return s.Store.Apply(ctx, changes)
+}
+
+func (s *applyCountingStore) AtomicApply() (bool, int) {
+	return !s.nonAtomic, s.limit
+}
+
+var _ = Describe("GetService and GetServer", func() {
+	var (
+		ctx = context.Background()
+		s   types.MerlinServer
+		key = &types.RealServer_Key{Ip: "172.16.1.1", Port: 8080}
+	)
+
+	BeforeEach(func() {
+		s = New(store.NewMemory(), nil, testNode, nil, nil, Options{})
+		_, err := s.CreateService(ctx, testService("svc", "10.10.10.10", 80))
+		Expect(err).ToNot(HaveOccurred())
+		_, err = s.CreateServer(ctx, &types.RealServer{
+			ServiceID: "svc",
+			Key:       key,
+			Config: &types.RealServer_Config{
+				Weight:  &wrappers.UInt32Value{Value: 1},
+				Forward: types.ForwardMethod_MASQ,
+			},
+		})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should get a service with its servers", func() {
+		resp, err := s.GetService(ctx, &wrappers.StringValue{Value: "svc"})
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.Service.Id).To(Equal("svc"))
+		Expect(resp.Servers).To(HaveLen(1))
+		Expect(resp.Servers[0].Key.PrettyString()).To(Equal("172.16.1.1:8080"))
+	})
+
+	It("should get a server", func() {
+		server, err := s.GetServer(ctx, &types.RealServer{ServiceID: "svc", Key: key})
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(server.Config.Weight.GetValue()).To(BeEquivalentTo(1))
+	})
+
+	It("should fail if they don't exist", func() {
+		_, err := s.GetService(ctx, &wrappers.StringValue{Value: "other"})
+		Expect(status.Code(err)).To(Equal(codes.NotFound))
+
+		other := &types.RealServer_Key{Ip: "172.16.1.2", Port: 8080}
+		_, err = s.GetServer(ctx, &types.RealServer{ServiceID: "svc", Key: other})
+		Expect(status.Code(err)).To(Equal(codes.NotFound))
+		_, err = s.GetServer(ctx, &types.RealServer{ServiceID: "svc"})
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+	})
+})
+
+var _ = Describe("ApplySnapshot", func() {
+	It("should apply changes in batches", func() {
+		ctx := context.Background()
+		st := &applyCountingStore{Store: store.NewMemory()}
+		s := New(st, nil, testNode, nil, nil, Options{ApplyBatchSize: 2})
+		snapshot := &types.Snapshot{}
+		for i, id := range []string{"svc1", "svc2", "svc3"} {
+			snapshot.Services = append(snapshot.Services, &types.VirtualService{
+				Id:     id,
+				Key:    &types.VirtualService_Key{Ip: "10.10.10.10", Port: uint32(80 + i), Protocol: types.Protocol_TCP},
+				Config: &types.VirtualService_Config{Scheduler: "sh"},
+			})
+		}
+
+		resp, err := s.ApplySnapshot(ctx, &types.ApplySnapshotRequest{Snapshot: snapshot})
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.Changes).To(HaveLen(3))
+		Expect(st.batches).To(HaveLen(2))
+		Expect(st.batches[0]).To(HaveLen(2))
+		Expect(st.ListServices(ctx)).To(HaveLen(3))
+	})
+})
+
+var _ = Describe("List", func() {
+	var (
+		ctx context.Context
+		s   types.MerlinServer
+	)
+
+	ids := func(resp *types.ListResponse) []string {
+		var ids []string
+		for _, item := range resp.Items {
+			ids = append(ids, item.Service.Id)
+		}
+		return ids
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		s = New(store.NewMemory(), nil, testNode, nil, nil, Options{})
+		teams := map[string]string{"svc1": "payments", "svc2": "search", "svc3": "payments", "svc4": "search"}
+		for i, id := range []string{"svc3", "svc1", "svc4", "svc2"} {
+			_, err := s.CreateService(ctx, &types.VirtualService{
+				Id:     id,
+				Key:    &types.VirtualService_Key{Ip: "10.10.10.10", Port: uint32(80 + i), Protocol: types.Protocol_TCP},
+				Config: &types.VirtualService_Config{Scheduler: "sh"},
+				Labels: map[string]string{"team": teams[id]},
+			})
+			Expect(err).ToNot(HaveOccurred())
+		}
+	})
+
+	It("should list every service by ID without a page size", func() {
+		resp, err := s.List(ctx, &types.ListRequest{})
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ids(resp)).To(Equal([]string{"svc1", "svc2", "svc3", "svc4"}))
+		Expect(resp.NextPageToken).To(BeEmpty())
+	})
+
+	It("should page through the services", func() {
+		first, err := s.List(ctx, &types.ListRequest{PageSize: 3})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ids(first)).To(Equal([]string{"svc1", "svc2", "svc3"}))
+		Expect(first.NextPageToken).ToNot(BeEmpty())
+
+		second, err := s.List(ctx, &types.ListRequest{PageSize: 3, PageToken: first.NextPageToken})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ids(second)).To(Equal([]string{"svc4"}))
+		Expect(second.NextPageToken).To(BeEmpty())
+	})
+
+	It("should only count matching services in a page", func() {
+		resp, err := s.List(ctx, &types.ListRequest{LabelSelector: "team=payments", PageSize: 2})
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ids(resp)).To(Equal([]string{"svc1", "svc3"}))
+		Expect(resp.NextPageToken).To(BeEmpty())
+	})
+
+	It("should count the matching services on every page", func() {
+		resp, err := s.List(ctx, &types.ListRequest{FieldSelector: "protocol=tcp", PageSize: 1})
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ids(resp)).To(Equal([]string{"svc1"}))
+		Expect(resp.TotalSize).To(BeEquivalentTo(4))
+	})
+
+	It("should filter services by ID prefix", func() {
+		_, err := s.CreateService(ctx, testService("web", "10.10.10.11", 80))
+		Expect(err).ToNot(HaveOccurred())
+
+		resp, err := s.List(ctx, &types.ListRequest{IdPrefix: "svc", LabelSelector: "team=search"})
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ids(resp)).To(Equal([]string{"svc2", "svc4"}))
+		Expect(resp.TotalSize).To(BeEquivalentTo(2))
+	})
+
+	It("should filter the servers of each service by label", func() {
+		for i, rack := range []string{"r1", "r2", "r1"} {
+			_, err := s.CreateServer(ctx, &types.RealServer{
+				ServiceID: "svc1",
+				Key:       &types.RealServer_Key{Ip: "172.16.1.1", Port: uint32(8080 + i)},
+				Config: &types.RealServer_Config{
+					Weight:  &wrappers.UInt32Value{Value: 1},
+					Forward: types.ForwardMethod_MASQ,
+				},
+				Labels: map[string]string{"rack": rack},
+			})
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		resp, err := s.List(ctx, &types.ListRequest{ServerLabelSelector: "rack=r1"})
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ids(resp)).To(Equal([]string{"svc1", "svc2", "svc3", "svc4"}))
+		Expect(resp.Items[0].Servers).To(HaveLen(2))
+		for _, server := range resp.Items[0].Servers {
+			Expect(server.Labels).To(Equal(map[string]string{"rack": "r1"}))
+		}
+	})
+
+	It("should refuse an invalid page token", func() {
+		_, err := s.List(ctx, &types.ListRequest{PageToken: "!"})
+
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+	})
+})
+
+var _ = Describe("GetServerStats", func() {
+	var (
+		ctx = context.Background()
+		st  store.Store
+		s   types.MerlinServer
+	)
+
+	newServer := func(ipvs ipvs.IPVS) types.MerlinServer {
+		health := func(string, *types.RealServer_Key) types.Health { return types.Health_UP }
+		return New(st, ipvs, testNode, health, nil, Options{})
+	}
+
+	BeforeEach(func() {
+		st = store.NewMemory()
+		kernel := ipvs.NewMemory()
+		s = newServer(kernel)
+		svc := testService("svc", "10.10.10.10", 80)
+		_, err := s.CreateService(ctx, svc)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(kernel.AddService(ctx, svc)).To(Succeed())
+		Expect(kernel.AddServer(ctx, svc.Key, &types.RealServer{
+			Key:    &types.RealServer_Key{Ip: "172.16.1.1", Port: 8080},
+			Config: &types.RealServer_Config{Weight: &wrappers.UInt32Value{Value: 1}, Forward: types.ForwardMethod_MASQ},
+		})).To(Succeed())
+	})
+
+	It("should return the stats of each server of the service", func() {
+		resp, err := s.GetServerStats(ctx, &wrappers.StringValue{Value: "svc"})
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.Node).To(Equal("node"))
+		Expect(resp.Service.Id).To(Equal("svc"))
+		Expect(resp.Service.Servers).To(HaveLen(1))
+		Expect(resp.Service.Servers[0].Key.Ip).To(Equal("172.16.1.1"))
+		Expect(resp.Service.Servers[0].Health).To(Equal(types.Health_UP))
+	})
+
+	It("should return not found for services which don't exist or aren't in ipvs", func() {
+		_, err := s.GetServerStats(ctx, &wrappers.StringValue{Value: "missing"})
+		Expect(status.Code(err)).To(Equal(codes.NotFound))
+
+		_, err = newServer(ipvs.NewMemory()).GetServerStats(ctx, &wrappers.StringValue{Value: "svc"})
+		Expect(status.Code(err)).To(Equal(codes.NotFound))
+	})
+
+	It("should fail when ipvs is disabled", func() {
+		_, err := newServer(nil).GetServerStats(ctx, &wrappers.StringValue{Value: "svc"})
+
+		Expect(status.Code(err)).To(Equal(codes.FailedPrecondition))
+	})
+})
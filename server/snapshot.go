@@ -0,0 +1,198 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/empty"
+	log "github.com/sirupsen/logrus"
+	"github.com/sky-uk/merlin/types"
+	"github.com/sky-uk/merlin/validation"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *server) GetSnapshot(ctx context.Context, _ *empty.Empty) (*types.Snapshot, error) {
+	svcs, err := s.store.ListServices(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &types.Snapshot{Services: svcs}
+	for _, svc := range svcs {
+		servers, err := s.store.ListServers(ctx, svc.Id)
+		if err != nil {
+			return nil, err
+		}
+		snapshot.Servers = append(snapshot.Servers, servers...)
+	}
+	return snapshot, nil
+}
+
+func (s *server) ApplySnapshot(ctx context.Context, req *types.ApplySnapshotRequest) (*types.ApplySnapshotResponse,
+	error) {
+	batchSize := s.opts.ApplyBatchSize
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	return s.applySnapshot(ctx, req, batchSize)
+}
+
+// applySnapshot applies the changes of the snapshot in batches of batchSize, or in a single write if it's 0.
+func (s *server) applySnapshot(ctx context.Context, req *types.ApplySnapshotRequest,
+	batchSize int) (*types.ApplySnapshotResponse, error) {
+	changes, err := s.diffSnapshot(ctx, req.Snapshot, req.Prune)
+	if err != nil {
+		return nil, err
+	}
+	if changes, err = s.applyChanges(ctx, changes, req.DryRun, batchSize); err != nil {
+		return nil, err
+	}
+	return &types.ApplySnapshotResponse{Changes: changes}, nil
+}
+
+// applyChanges admits and checks the changes, then applies them in batches of batchSize, or in a single write if
+// it's 0. It returns the admitted changes, which aren't applied if dryRun is set.
+func (s *server) applyChanges(ctx context.Context, changes []*types.Change, dryRun bool,
+	batchSize int) ([]*types.Change, error) {
+	changes, err := s.admit(ctx, dryRun, changes...)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkQuotas(ctx, changes...); err != nil {
+		return nil, err
+	}
+	if err := s.checkServerLimits(ctx, changes...); err != nil {
+		return nil, err
+	}
+	if err := s.checkOverlaps(ctx, changes...); err != nil {
+		return nil, err
+	}
+	if err := s.checkRules(ctx, changes...); err != nil {
+		return nil, err
+	}
+	if err := s.checkPools(ctx, changes...); err != nil {
+		return nil, err
+	}
+	// check a single write fits in the store before anything is written, rather than failing as it's committed
+	if _, limit := s.store.AtomicApply(); batchSize == 0 && limit > 0 && len(changes) > limit {
+		return nil, status.Errorf(codes.InvalidArgument, "%d changes is more than the %d the store can make at once",
+			len(changes), limit)
+	}
+
+	if dryRun {
+		log.Infof("Dry run, %d changes not applied", len(changes))
+		return changes, nil
+	}
+
+	if batchSize == 0 {
+		batchSize = len(changes)
+	}
+	for i := 0; i < len(changes); i += batchSize {
+		end := i + batchSize
+		if end > len(changes) {
+			end = len(changes)
+		}
+		if err := s.store.Apply(ctx, changes[i:end]); err != nil {
+			s.record(ctx, changes[:i]...)
+			s.releaseIPs(ctx, changes[:i]...)
+			return nil, fmt.Errorf("failed to apply changes %d to %d of %d: %v", i+1, end, len(changes), err)
+		}
+		for _, change := range changes[i:end] {
+			log.Infof("Applied %s", change.PrettyString())
+		}
+	}
+	s.record(ctx, changes...)
+	s.releaseIPs(ctx, changes...)
+	return changes, nil
+}
+
+// diffSnapshot validates the snapshot and returns the changes needed to make the store match it.
+// Changes are ordered so services are created before their servers, and servers deleted before their services.
+func (s *server) diffSnapshot(ctx context.Context, snapshot *types.Snapshot, prune bool) ([]*types.Change, error) {
+	// reject oversized snapshots before reading the current state to diff them with
+	if err := s.opts.Limits.Snapshot(snapshot); err != nil {
+		return nil, err
+	}
+	current, err := s.GetSnapshot(ctx, &empty.Empty{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read current state: %v", err)
+	}
+	currentServices := make(map[string]bool)
+	for _, svc := range current.Services {
+		currentServices[svc.Id] = true
+	}
+
+	// when pruning, services not in the snapshot will be deleted
+	var exists validation.ServiceExists
+	if !prune {
+		exists = func(id string) bool { return currentServices[id] }
+	}
+	if err := validation.Snapshot(snapshot, exists); err != nil {
+		return nil, err
+	}
+
+	// ensure health check field always exists
+	for _, server := range snapshot.Servers {
+		if server.HealthCheck == nil {
+			server.HealthCheck = &types.RealServer_HealthCheck{}
+		}
+	}
+	return snapshotChanges(current, snapshot, prune), nil
+}
+
+// snapshotChanges returns the changes which turn current into desired, deleting what's only in current if prune is
+// set. Services are created before their servers, and servers deleted before their services.
+func snapshotChanges(current, desired *types.Snapshot, prune bool) []*types.Change {
+	currentServices := make(map[string]*types.VirtualService)
+	for _, svc := range current.Services {
+		currentServices[svc.Id] = svc
+	}
+	currentServers := make(map[string]*types.RealServer)
+	for _, server := range current.Servers {
+		currentServers[validation.ServerID(server.ServiceID, server.Key)] = server
+	}
+
+	var changes []*types.Change
+
+	desiredServices := make(map[string]bool)
+	for _, svc := range desired.Services {
+		desiredServices[svc.Id] = true
+
+		prev := currentServices[svc.Id]
+		if prev == nil {
+			changes = append(changes, &types.Change{Action: types.Change_CREATE, Service: svc})
+		} else if !proto.Equal(prev, svc) {
+			changes = append(changes, &types.Change{Action: types.Change_UPDATE, Service: svc})
+		}
+	}
+
+	desiredServers := make(map[string]bool)
+	for _, server := range desired.Servers {
+		id := validation.ServerID(server.ServiceID, server.Key)
+		desiredServers[id] = true
+
+		prev := currentServers[id]
+		if prev == nil {
+			changes = append(changes, &types.Change{Action: types.Change_CREATE, Server: server})
+		} else if !proto.Equal(prev, server) {
+			changes = append(changes, &types.Change{Action: types.Change_UPDATE, Server: server})
+		}
+	}
+
+	if prune {
+		for _, server := range current.Servers {
+			if !desiredServers[validation.ServerID(server.ServiceID, server.Key)] {
+				changes = append(changes, &types.Change{Action: types.Change_DELETE, Server: server})
+			}
+		}
+		for _, svc := range current.Services {
+			if !desiredServices[svc.Id] {
+				changes = append(changes, &types.Change{Action: types.Change_DELETE, Service: svc})
+			}
+		}
+	}
+
+	return changes
+}
@@ -0,0 +1,46 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	log "github.com/sirupsen/logrus"
+	"github.com/sky-uk/merlin/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// SyncRoleFunc switches the IPVS connection sync daemon of this node to the role, stopping it if the role is unset.
+type SyncRoleFunc func(ctx context.Context, role types.SyncDaemon_Role) error
+
+func (s *server) GetSyncDaemons(ctx context.Context, _ *empty.Empty) (*types.SyncDaemons, error) {
+	if s.ipvs == nil {
+		return nil, status.Error(codes.FailedPrecondition, "ipvs is disabled on this node")
+	}
+	daemons, err := s.ipvs.SyncDaemons(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sync daemons: %v", err)
+	}
+	return &types.SyncDaemons{Node: s.node().Name, Daemons: daemons}, nil
+}
+
+func (s *server) SetSyncRole(ctx context.Context, req *types.SetSyncRoleRequest) (*types.SyncDaemons, error) {
+	if s.ipvs == nil {
+		return nil, status.Error(codes.FailedPrecondition, "ipvs is disabled on this node")
+	}
+	if _, ok := types.SyncDaemon_Role_name[int32(req.Role)]; !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown sync role %v", req.Role)
+	}
+	if s.opts.SetSyncRole == nil {
+		return nil, status.Error(codes.FailedPrecondition, "the sync daemon isn't managed on this node")
+	}
+	if err := s.opts.SetSyncRole(ctx, req.Role); err != nil {
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to switch the sync daemon to %v: %v", req.Role, err)
+	}
+	log.Infof("Switched the sync daemon to %v", req.Role)
+	return s.GetSyncDaemons(ctx, emptyResponse)
+}
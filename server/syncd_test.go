@@ -0,0 +1,96 @@
+package server
+
+import (
+	"context"
+	"errors"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sky-uk/merlin/ipvs"
+	"github.com/sky-uk/merlin/store"
+	"github.com/sky-uk/merlin/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var _ = Describe("SyncDaemons", func() {
+	var (
+		ctx     = context.Background()
+		kernel  ipvs.IPVS
+		s       types.MerlinServer
+		setRole SyncRoleFunc
+	)
+
+	newServer := func(i ipvs.IPVS) types.MerlinServer {
+		return New(store.NewMemory(), i, testNode, nil, nil, Options{SetSyncRole: setRole})
+	}
+
+	BeforeEach(func() {
+		kernel = ipvs.NewMemory()
+		setRole = func(ctx context.Context, role types.SyncDaemon_Role) error {
+			if role == types.SyncDaemon_UNSET_SYNC_ROLE {
+				return kernel.StopSyncDaemon(ctx, types.SyncDaemon_MASTER)
+			}
+			return kernel.StartSyncDaemon(ctx, &types.SyncDaemon{Role: role, Interface: "eth0", SyncId: 7})
+		}
+		s = newServer(kernel)
+	})
+
+	It("should return the sync daemons running on the node", func() {
+		master := &types.SyncDaemon{Role: types.SyncDaemon_MASTER, Interface: "eth0", SyncId: 7}
+		Expect(kernel.StartSyncDaemon(ctx, master)).To(Succeed())
+
+		resp, err := s.GetSyncDaemons(ctx, &empty.Empty{})
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp).To(Equal(&types.SyncDaemons{Node: "node", Daemons: []*types.SyncDaemon{master}}))
+	})
+
+	It("should switch the role of the sync daemon, returning the daemons running after", func() {
+		resp, err := s.SetSyncRole(ctx, &types.SetSyncRoleRequest{Role: types.SyncDaemon_MASTER})
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.Daemons).To(Equal([]*types.SyncDaemon{
+			{Role: types.SyncDaemon_MASTER, Interface: "eth0", SyncId: 7},
+		}))
+
+		resp, err = s.SetSyncRole(ctx, &types.SetSyncRoleRequest{})
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.Daemons).To(BeEmpty())
+	})
+
+	It("should reject unknown roles", func() {
+		_, err := s.SetSyncRole(ctx, &types.SetSyncRoleRequest{Role: 3})
+
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+	})
+
+	It("should return errors switching the role", func() {
+		setRole = func(context.Context, types.SyncDaemon_Role) error { return errors.New("no such device") }
+		s = newServer(kernel)
+
+		_, err := s.SetSyncRole(ctx, &types.SetSyncRoleRequest{Role: types.SyncDaemon_BACKUP})
+
+		Expect(err).To(MatchError("failed to switch the sync daemon to BACKUP: no such device"))
+	})
+
+	It("should fail if the sync daemon isn't managed on the node", func() {
+		setRole = nil
+		s = newServer(kernel)
+
+		_, err := s.SetSyncRole(ctx, &types.SetSyncRoleRequest{Role: types.SyncDaemon_MASTER})
+
+		Expect(status.Code(err)).To(Equal(codes.FailedPrecondition))
+	})
+
+	It("should fail if ipvs is disabled on the node", func() {
+		s = newServer(nil)
+
+		_, err := s.GetSyncDaemons(ctx, &empty.Empty{})
+		Expect(status.Code(err)).To(Equal(codes.FailedPrecondition))
+		_, err = s.SetSyncRole(ctx, &types.SetSyncRoleRequest{Role: types.SyncDaemon_MASTER})
+		Expect(status.Code(err)).To(Equal(codes.FailedPrecondition))
+	})
+})
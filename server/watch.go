@@ -0,0 +1,76 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/sky-uk/merlin/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Watch subscribes to the store before reading it, so changes made while the initial state is being sent are in
+// the next event. Changes made close together may be sent as one event, as each event is the difference between
+// reads of the store.
+func (s *server) Watch(req *types.WatchRequest, stream types.Merlin_WatchServer) error {
+	labelSelector, err := types.ParseSelector(req.LabelSelector)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	fieldSelector, err := types.ParseFieldSelector(req.FieldSelector)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	ctx := stream.Context()
+
+	changed := make(chan struct{}, 1)
+	s.store.Subscribe(func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	}, ctx.Done())
+
+	prev := &types.Snapshot{}
+	for initial := true; ; initial = false {
+		current, err := s.watched(ctx, labelSelector, fieldSelector)
+		if err != nil {
+			return err
+		}
+		if changes := snapshotChanges(prev, current, true); initial || len(changes) > 0 {
+			if err := stream.Send(&types.WatchEvent{Changes: changes, Initial: initial}); err != nil {
+				return err
+			}
+		}
+		prev = current
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// watched returns the services matching the selectors ordered by ID, and their servers.
+func (s *server) watched(ctx context.Context, labelSelector, fieldSelector types.Selector) (*types.Snapshot, error) {
+	svcs, err := s.store.ListServices(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list services: %v", err)
+	}
+	sort.Slice(svcs, func(i, j int) bool { return svcs[i].Id < svcs[j].Id })
+	snapshot := &types.Snapshot{}
+	for _, svc := range svcs {
+		if !labelSelector.Matches(svc.Labels) || !fieldSelector.Matches(types.FieldsOf(svc)) {
+			continue
+		}
+		servers, err := s.store.ListServers(ctx, svc.Id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list servers of %s: %v", svc.Id, err)
+		}
+		snapshot.Services = append(snapshot.Services, svc)
+		snapshot.Servers = append(snapshot.Servers, servers...)
+	}
+	return snapshot, nil
+}
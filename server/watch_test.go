@@ -0,0 +1,135 @@
+package server
+
+import (
+	"context"
+
+	"github.com/golang/protobuf/ptypes/wrappers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sky-uk/merlin/store"
+	"github.com/sky-uk/merlin/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// watchStream receives the events of a watch.
+type watchStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	events chan *types.WatchEvent
+}
+
+func (s *watchStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *watchStream) Send(event *types.WatchEvent) error {
+	s.events <- event
+	return nil
+}
+
+var _ = Describe("Watch", func() {
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+		s      types.MerlinServer
+		stream *watchStream
+		done   chan error
+	)
+
+	service := func(id, ip string, labels map[string]string) *types.VirtualService {
+		svc := testService(id, ip, 80)
+		svc.Labels = labels
+		return svc
+	}
+	watch := func(req *types.WatchRequest) {
+		go func() {
+			done <- s.Watch(req, stream)
+		}()
+	}
+	next := func() *types.WatchEvent {
+		var event *types.WatchEvent
+		Eventually(stream.events).Should(Receive(&event))
+		return event
+	}
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+		s = New(store.NewMemory(), nil, testNode, nil, nil, Options{})
+		stream = &watchStream{ctx: ctx, events: make(chan *types.WatchEvent, 10)}
+		done = make(chan error, 1)
+		_, err := s.CreateService(ctx, service("web", "10.0.0.1", map[string]string{"team": "a"}))
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	It("should send the current state, then each change", func() {
+		watch(&types.WatchRequest{})
+		initial := next()
+		Expect(initial.Initial).To(BeTrue())
+		Expect(initial.Changes).To(HaveLen(1))
+		Expect(initial.Changes[0].Action).To(Equal(types.Change_CREATE))
+		Expect(initial.Changes[0].Service.Id).To(Equal("web"))
+
+		server := &types.RealServer{
+			ServiceID: "web",
+			Key:       &types.RealServer_Key{Ip: "172.16.0.1", Port: 8080},
+			Config: &types.RealServer_Config{
+				Weight:  &wrappers.UInt32Value{Value: 1},
+				Forward: types.ForwardMethod_MASQ,
+			},
+		}
+		_, err := s.CreateServer(ctx, server)
+		Expect(err).ToNot(HaveOccurred())
+		event := next()
+		Expect(event.Initial).To(BeFalse())
+		Expect(event.Changes).To(HaveLen(1))
+		Expect(event.Changes[0].Action).To(Equal(types.Change_CREATE))
+		Expect(event.Changes[0].Server.Key.Ip).To(Equal("172.16.0.1"))
+
+		_, err = s.DeleteService(ctx, &wrappers.StringValue{Value: "web"})
+		Expect(err).ToNot(HaveOccurred())
+		var actions []types.Change_Action
+		for len(actions) < 2 {
+			for _, change := range next().Changes {
+				actions = append(actions, change.Action)
+			}
+		}
+		Expect(actions).To(Equal([]types.Change_Action{types.Change_DELETE, types.Change_DELETE}))
+	})
+
+	It("should only send the services matching the selectors", func() {
+		watch(&types.WatchRequest{LabelSelector: "team=b"})
+		initial := next()
+		Expect(initial.Initial).To(BeTrue())
+		Expect(initial.Changes).To(BeEmpty())
+
+		_, err := s.CreateService(ctx, service("api", "10.0.0.2", map[string]string{"team": "b"}))
+		Expect(err).ToNot(HaveOccurred())
+		event := next()
+		Expect(event.Changes).To(HaveLen(1))
+		Expect(event.Changes[0].Service.Id).To(Equal("api"))
+
+		_, err = s.UpdateService(ctx, &types.VirtualService{Id: "web", Config: &types.VirtualService_Config{
+			Scheduler: "wrr"}})
+		Expect(err).ToNot(HaveOccurred())
+		Consistently(stream.events).ShouldNot(Receive())
+	})
+
+	It("should stop when the client cancels", func() {
+		watch(&types.WatchRequest{})
+		next()
+
+		cancel()
+		Eventually(done).Should(Receive(BeNil()))
+	})
+
+	It("should refuse invalid selectors", func() {
+		Expect(status.Code(s.Watch(&types.WatchRequest{FieldSelector: "unknown=1"}, stream))).
+			To(Equal(codes.InvalidArgument))
+	})
+})
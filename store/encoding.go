@@ -0,0 +1,109 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+)
+
+// Encoding of the values a store persists objects as.
+type Encoding string
+
+const (
+	// EncodingProto is plain protobuf, which every version of merlin reads and writes.
+	EncodingProto Encoding = "proto"
+	// EncodingBinary is protobuf after a version header, so later formats can be told apart from it.
+	EncodingBinary Encoding = "binary"
+	// EncodingJSON is the protobuf JSON mapping, for tooling which reads or writes the store directly. Fields are
+	// named as in types.proto, enums are their names, and values aren't base64 encoded in etcd2.
+	EncodingJSON Encoding = "json"
+)
+
+// binaryHeader starts values in EncodingBinary. Field number 0 is invalid in protobuf, so plain protobuf values
+// never start with a zero byte.
+const (
+	binaryHeader  = 0x00
+	binaryVersion = 0x01
+)
+
+// ParseEncoding returns the encoding named s, or EncodingProto if s is empty.
+func ParseEncoding(s string) (Encoding, error) {
+	switch e := Encoding(s); e {
+	case "":
+		return EncodingProto, nil
+	case EncodingProto, EncodingBinary, EncodingJSON:
+		return e, nil
+	default:
+		return "", fmt.Errorf("unknown store encoding: %s", s)
+	}
+}
+
+// jsonMarshaler writes EncodingJSON. Its field names are part of the format, so must not change.
+var jsonMarshaler = &jsonpb.Marshaler{OrigName: true}
+
+// jsonUnmarshaler reads EncodingJSON, ignoring unknown fields like protobuf does, so values written by newer
+// versions can be read.
+var jsonUnmarshaler = &jsonpb.Unmarshaler{AllowUnknownFields: true}
+
+func (e Encoding) marshal(pb proto.Message) ([]byte, error) {
+	if e == EncodingJSON {
+		s, err := jsonMarshaler.MarshalToString(pb)
+		return []byte(s), err
+	}
+	b, err := proto.Marshal(pb)
+	if err != nil {
+		return nil, err
+	}
+	if e == EncodingBinary {
+		b = append([]byte{binaryHeader, binaryVersion}, b...)
+	}
+	return b, nil
+}
+
+// isJSON returns true if b is in EncodingJSON. Protobuf values can't start with '{', as it's the tag of a group,
+// which proto3 doesn't have.
+func isJSON(b []byte) bool {
+	return len(b) > 0 && b[0] == '{'
+}
+
+// decode returns the protobuf of a value in any binary encoding, so a store can read values written before its encoding
+// was changed.
+func decode(b []byte) ([]byte, error) {
+	if len(b) == 0 || b[0] != binaryHeader {
+		return b, nil
+	}
+	if len(b) < 2 || b[1] != binaryVersion {
+		return nil, errors.New("unknown binary encoding version, was it written by a newer merlin?")
+	}
+	return b[2:], nil
+}
+
+// Migrate rewrites every service and server in the store, so they're persisted in its current encoding. Values are
+// read in any encoding, so merlin keeps running while they're migrated. It returns how many objects were rewritten.
+func Migrate(ctx context.Context, s Store) (int, error) {
+	svcs, err := s.ListServices(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("unable to list services: %v", err)
+	}
+	servers, err := s.ListAllServers(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("unable to list servers: %v", err)
+	}
+	n := 0
+	for _, svc := range svcs {
+		if err := s.PutService(ctx, svc); err != nil {
+			return n, err
+		}
+		n++
+	}
+	for _, server := range servers {
+		if err := s.PutServer(ctx, server); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
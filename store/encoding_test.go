@@ -0,0 +1,126 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sky-uk/merlin/types"
+)
+
+var _ = Describe("Encoding", func() {
+	var (
+		ctx    = context.Background()
+		svc    = &types.VirtualService{Id: "svc", Key: &types.VirtualService_Key{Ip: "10.0.0.1", Port: 80}}
+		server = &types.RealServer{ServiceID: "svc", Key: &types.RealServer_Key{Ip: "172.16.0.1", Port: 8080}}
+	)
+
+	It("should write binary values with a version header", func() {
+		st := NewMemoryEncoding(EncodingBinary)
+		Expect(st.PutService(ctx, svc)).To(Succeed())
+
+		raw := st.(*memoryStore).get(serviceKey("svc"))
+		Expect(raw[:2]).To(Equal([]byte{binaryHeader, binaryVersion}))
+		Expect(st.GetService(ctx, "svc")).To(equalProto(svc))
+	})
+
+	It("should read values in any encoding, and migrate them to the current one", func() {
+		st := NewMemory()
+		Expect(st.PutService(ctx, svc)).To(Succeed())
+		Expect(st.PutServer(ctx, server)).To(Succeed())
+		st.(*memoryStore).encoding = EncodingBinary
+
+		Expect(st.GetService(ctx, "svc")).To(equalProto(svc))
+		Expect(Migrate(ctx, st)).To(Equal(2))
+		Expect(st.(*memoryStore).get(serviceKey("svc"))[0]).To(BeEquivalentTo(binaryHeader))
+		Expect(st.(*memoryStore).get(serverKey("svc", server.Key))[0]).To(BeEquivalentTo(binaryHeader))
+		Expect(st.GetServer(ctx, "svc", server.Key)).To(equalProto(server))
+
+		st.(*memoryStore).encoding = EncodingProto
+		Expect(Migrate(ctx, st)).To(Equal(2))
+		Expect(st.(*memoryStore).get(serviceKey("svc"))[0]).NotTo(BeEquivalentTo(binaryHeader))
+		Expect(st.GetService(ctx, "svc")).To(equalProto(svc))
+	})
+
+	Describe("JSON", func() {
+		// external tooling relies on this format, so these must only change compatibly
+		var (
+			svcJSON = `{"id":"svc","key":{"ip":"10.0.0.1","port":80,"protocol":"TCP"},` +
+				`"config":{"scheduler":"wrr","flags":["flag-1"]},"labels":{"team":"a"},"node_selector":"pool=edge"}`
+			serverJSON = `{"serviceID":"svc","key":{"ip":"172.16.0.1","port":8080},` +
+				`"config":{"weight":2,"forward":"ROUTE"},"health_check":{"endpoint":"http://:8080/health",` +
+				`"period":"10s","timeout":"1s","up_threshold":2,"down_threshold":3}}`
+			fullSvc = &types.VirtualService{
+				Id:           "svc",
+				Key:          &types.VirtualService_Key{Ip: "10.0.0.1", Port: 80, Protocol: types.Protocol_TCP},
+				Config:       &types.VirtualService_Config{Scheduler: "wrr", Flags: []string{"flag-1"}},
+				Labels:       map[string]string{"team": "a"},
+				NodeSelector: "pool=edge",
+			}
+			fullServer = &types.RealServer{
+				ServiceID: "svc",
+				Key:       &types.RealServer_Key{Ip: "172.16.0.1", Port: 8080},
+				Config: &types.RealServer_Config{
+					Weight:  &wrappers.UInt32Value{Value: 2},
+					Forward: types.ForwardMethod_ROUTE,
+				},
+				HealthCheck: &types.RealServer_HealthCheck{
+					Endpoint:      &wrappers.StringValue{Value: "http://:8080/health"},
+					Period:        ptypes.DurationProto(10 * time.Second),
+					Timeout:       ptypes.DurationProto(time.Second),
+					UpThreshold:   2,
+					DownThreshold: 3,
+				},
+			}
+		)
+
+		It("should write services and servers with proto field names and enum names", func() {
+			st := NewMemoryEncoding(EncodingJSON)
+			Expect(st.PutService(ctx, fullSvc)).To(Succeed())
+			Expect(st.PutServer(ctx, fullServer)).To(Succeed())
+
+			Expect(string(st.(*memoryStore).get(serviceKey("svc")))).To(MatchJSON(svcJSON))
+			Expect(string(st.(*memoryStore).get(serverKey("svc", fullServer.Key)))).To(MatchJSON(serverJSON))
+			Expect(st.GetService(ctx, "svc")).To(equalProto(fullSvc))
+			Expect(st.GetServer(ctx, "svc", fullServer.Key)).To(equalProto(fullServer))
+		})
+
+		It("should read JSON written by other tools, ignoring unknown fields", func() {
+			st := NewMemory()
+			st.(*memoryStore).kvs[serviceKey("svc")] = memoryValue{value: []byte(svcJSON[:len(svcJSON)-1] +
+				`,"owner":"tooling"}`)}
+			st.(*memoryStore).kvs[serverKey("svc", fullServer.Key)] = memoryValue{value: []byte(serverJSON)}
+
+			Expect(st.GetService(ctx, "svc")).To(equalProto(fullSvc))
+			Expect(st.ListAllServers(ctx)).To(ConsistOf(equalProto(fullServer)))
+		})
+
+		It("should store JSON in etcd2 without base64 encoding it", func() {
+			b, err := EncodingJSON.marshal(fullSvc)
+			Expect(err).NotTo(HaveOccurred())
+			s := &etcd2store{encoding: EncodingJSON}
+			Expect(s.encode(b)).To(MatchJSON(svcJSON))
+			Expect(unmarshalService(base64decode(s.encode(b)))).To(equalProto(fullSvc))
+		})
+	})
+
+	It("should refuse values of an unknown version", func() {
+		_, err := decode([]byte{binaryHeader, binaryVersion + 1, 0x0a})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should parse encodings", func() {
+		Expect(ParseEncoding("")).To(Equal(EncodingProto))
+		Expect(ParseEncoding("binary")).To(Equal(EncodingBinary))
+		_, err := ParseEncoding("xml")
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+func equalProto(expected proto.Message) OmegaMatcher {
+	return WithTransform(func(actual proto.Message) bool { return proto.Equal(actual, expected) }, BeTrue())
+}
@@ -10,20 +10,22 @@ import (
 
 	"github.com/cenkalti/backoff"
 	"github.com/coreos/etcd/client"
-	"github.com/golang/protobuf/proto"
 	log "github.com/sirupsen/logrus"
 	"github.com/sky-uk/merlin/types"
 )
 
 type etcd2store struct {
+	watchState
 	c       client.Client
 	prefix  string
 	kapi    client.KeysAPI
 	getOpts *client.GetOptions
+	// encoding of values, which are base64 encoded unless they're JSON, as etcd2 values are strings
+	encoding Encoding
 }
 
-// NewEtcd2 returns a Store implementation using an etcd2 backing store.
-func NewEtcd2(endpoints []string, prefix string) (Store, error) {
+// NewEtcd2 returns a Store implementation using an etcd2 backing store, which writes values in encoding.
+func NewEtcd2(endpoints []string, prefix string, encoding Encoding) (Store, error) {
 	cfg := client.Config{
 		Endpoints:               endpoints,
 		Transport:               client.DefaultTransport,
@@ -34,11 +36,20 @@ func NewEtcd2(endpoints []string, prefix string) (Store, error) {
 	if err != nil {
 		return nil, err
 	}
-	s := &etcd2store{c: c, prefix: prefix, kapi: client.NewKeysAPI(c), getOpts: &client.GetOptions{Quorum: true}}
+	s := &etcd2store{c: c, prefix: prefix, kapi: client.NewKeysAPI(c), getOpts: &client.GetOptions{Quorum: true},
+		encoding: encoding}
 
 	return s, s.init()
 }
 
+// encode a value as an etcd2 string.
+func (s *etcd2store) encode(b []byte) string {
+	if s.encoding == EncodingJSON {
+		return string(b)
+	}
+	return base64.StdEncoding.EncodeToString(b)
+}
+
 func (s *etcd2store) init() error {
 	if !strings.HasPrefix(s.prefix, "/") {
 		s.prefix = "/" + s.prefix
@@ -59,6 +70,11 @@ func (s *etcd2store) init() error {
 		return fmt.Errorf("failed to create %s%s directory: %v", s.prefix, servers, err)
 	}
 
+	// initialize nodes directory
+	if err := s.initDir(s.prefix + nodes); err != nil {
+		return fmt.Errorf("failed to create %s%s directory: %v", s.prefix, nodes, err)
+	}
+
 	return nil
 }
 
@@ -95,12 +111,12 @@ func (s *etcd2store) GetService(ctx context.Context, serviceID string) (*types.V
 }
 
 func (s *etcd2store) PutService(ctx context.Context, service *types.VirtualService) error {
-	b, err := proto.Marshal(service)
+	b, err := s.encoding.marshal(service)
 	if err != nil {
 		panic(err)
 	}
 
-	enc := base64.StdEncoding.EncodeToString(b)
+	enc := s.encode(b)
 	if _, err := s.kapi.Set(ctx, s.serviceKey(service.Id), enc, nil); err != nil {
 		return fmt.Errorf("unable to store service %s: %v", service.Id, err)
 	}
@@ -142,12 +158,12 @@ func (s *etcd2store) PutServer(ctx context.Context, server *types.RealServer) er
 		return fmt.Errorf("unable to init %s/%s: %v", servers, server.ServiceID, err)
 	}
 
-	b, err := proto.Marshal(server)
+	b, err := s.encoding.marshal(server)
 	if err != nil {
 		panic(err)
 	}
 
-	enc := base64.StdEncoding.EncodeToString(b)
+	enc := s.encode(b)
 	key := s.serverKey(server.ServiceID, server.Key)
 	if _, err := s.kapi.Set(ctx, key, enc, nil); err != nil {
 		return fmt.Errorf("unable to store server %s: %v", key, err)
@@ -193,6 +209,291 @@ func (s *etcd2store) ListServers(ctx context.Context, serviceID string) ([]*type
 	return servers, nil
 }
 
+func (s *etcd2store) ListAllServers(ctx context.Context) ([]*types.RealServer, error) {
+	resp, err := s.kapi.Get(ctx, s.prefix+servers, &client.GetOptions{Quorum: true, Recursive: true})
+	if client.IsKeyNotFound(err) {
+		return []*types.RealServer{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to list servers: %v", err)
+	}
+
+	var servers []*types.RealServer
+	for _, dir := range resp.Node.Nodes {
+		for _, node := range dir.Nodes {
+			servers = append(servers, unmarshalServer(base64decode(node.Value)))
+		}
+	}
+	return servers, nil
+}
+
+// Apply isn't atomic, as etcd2 has no multi-key transactions.
+func (s *etcd2store) Apply(ctx context.Context, changes []*types.Change) error {
+	return applyInOrder(ctx, s, changes)
+}
+
+func (s *etcd2store) AtomicApply() (bool, int) {
+	return false, 0
+}
+
+func (s *etcd2store) nodeKey(name string) string {
+	return s.prefix + nodes + "/" + name
+}
+
+func (s *etcd2store) PutNode(ctx context.Context, node *types.Node, ttl time.Duration) error {
+	b, err := s.encoding.marshal(node)
+	if err != nil {
+		panic(err)
+	}
+
+	enc := s.encode(b)
+	if _, err := s.kapi.Set(ctx, s.nodeKey(node.Name), enc, &client.SetOptions{TTL: ttl}); err != nil {
+		return fmt.Errorf("unable to store node %s: %v", node.Name, err)
+	}
+
+	return nil
+}
+
+func (s *etcd2store) ListNodes(ctx context.Context) ([]*types.Node, error) {
+	resp, err := s.kapi.Get(ctx, s.nodeKey(""), s.getOpts)
+	if client.IsKeyNotFound(err) {
+		return []*types.Node{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to list nodes: %v", err)
+	}
+
+	var nodes []*types.Node
+	for _, node := range resp.Node.Nodes {
+		nodes = append(nodes, unmarshalNode(base64decode(node.Value)))
+	}
+	return nodes, nil
+}
+
+func (s *etcd2store) maintenanceKey(node string) string {
+	return s.prefix + maintenance + "/" + node
+}
+
+func (s *etcd2store) SetMaintenance(ctx context.Context, node string, enabled bool) error {
+	var err error
+	if enabled {
+		_, err = s.kapi.Set(ctx, s.maintenanceKey(node), "true", nil)
+	} else {
+		_, err = s.kapi.Delete(ctx, s.maintenanceKey(node), nil)
+		if client.IsKeyNotFound(err) {
+			err = nil
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("unable to set maintenance of node %s: %v", node, err)
+	}
+	return nil
+}
+
+func (s *etcd2store) GetMaintenance(ctx context.Context, node string) (bool, error) {
+	_, err := s.kapi.Get(ctx, s.maintenanceKey(node), s.getOpts)
+	if client.IsKeyNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("unable to get maintenance of node %s: %v", node, err)
+	}
+	return true, nil
+}
+
+func (s *etcd2store) poolKey(name string) string {
+	return s.prefix + pools + "/" + name
+}
+
+func (s *etcd2store) PutPool(ctx context.Context, pool *types.VIPPool) error {
+	b, err := s.encoding.marshal(pool)
+	if err != nil {
+		panic(err)
+	}
+
+	if _, err := s.kapi.Set(ctx, s.poolKey(pool.Name), s.encode(b), nil); err != nil {
+		return fmt.Errorf("unable to store pool %s: %v", pool.Name, err)
+	}
+	return nil
+}
+
+func (s *etcd2store) DeletePool(ctx context.Context, name string) error {
+	_, err := s.kapi.Delete(ctx, s.poolKey(name), nil)
+	if client.IsKeyNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+func (s *etcd2store) ListPools(ctx context.Context) ([]*types.VIPPool, error) {
+	resp, err := s.kapi.Get(ctx, s.poolKey(""), s.getOpts)
+	if client.IsKeyNotFound(err) {
+		return []*types.VIPPool{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to list pools: %v", err)
+	}
+
+	var pools []*types.VIPPool
+	for _, node := range resp.Node.Nodes {
+		pools = append(pools, unmarshalPool(base64decode(node.Value)))
+	}
+	return pools, nil
+}
+
+func (s *etcd2store) GetCandidate(ctx context.Context) (*types.Candidate, error) {
+	resp, err := s.kapi.Get(ctx, s.prefix+candidate, s.getOpts)
+	if client.IsKeyNotFound(err) {
+		return &types.Candidate{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to get candidate: %v", err)
+	}
+	c := unmarshalCandidate(base64decode(resp.Node.Value))
+	c.Revision = int64(resp.Node.ModifiedIndex)
+	return c, nil
+}
+
+func (s *etcd2store) PutCandidate(ctx context.Context, c *types.Candidate) error {
+	b, err := s.encoding.marshal(storedCandidate(c))
+	if err != nil {
+		panic(err)
+	}
+
+	opts := &client.SetOptions{PrevExist: client.PrevNoExist}
+	if c.Revision != 0 {
+		opts = &client.SetOptions{PrevIndex: uint64(c.Revision)}
+	}
+	resp, err := s.kapi.Set(ctx, s.prefix+candidate, s.encode(b), opts)
+	if isCompareFailed(err) {
+		return ErrCandidateChanged
+	}
+	if err != nil {
+		return fmt.Errorf("unable to store candidate: %v", err)
+	}
+	c.Revision = int64(resp.Node.ModifiedIndex)
+	return nil
+}
+
+func (s *etcd2store) AddAuditEvent(ctx context.Context, event *types.AuditEvent, ttl time.Duration) error {
+	b, err := s.encoding.marshal(event)
+	if err != nil {
+		panic(err)
+	}
+
+	key := s.prefix + audit + "/" + auditName(event)
+	if _, err := s.kapi.Set(ctx, key, s.encode(b), &client.SetOptions{TTL: ttl}); err != nil {
+		return fmt.Errorf("unable to store audit event %s: %v", key, err)
+	}
+	return nil
+}
+
+func (s *etcd2store) ListAuditEvents(ctx context.Context) ([]*types.AuditEvent, error) {
+	resp, err := s.kapi.Get(ctx, s.prefix+audit, &client.GetOptions{Quorum: true, Sort: true})
+	if client.IsKeyNotFound(err) {
+		return []*types.AuditEvent{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to list audit events: %v", err)
+	}
+
+	var events []*types.AuditEvent
+	for _, node := range resp.Node.Nodes {
+		events = append(events, unmarshalAuditEvent(base64decode(node.Value)))
+	}
+	return events, nil
+}
+
+func (s *etcd2store) historyKey(name string) string {
+	return s.prefix + history + "/" + name
+}
+
+func (s *etcd2store) AddHistory(ctx context.Context, entries []*types.HistoryEntry, ttl time.Duration) error {
+	for i, entry := range entries {
+		b, err := s.encoding.marshal(entry)
+		if err != nil {
+			panic(err)
+		}
+
+		enc := s.encode(b)
+		key := s.historyKey(historyName(entry, i))
+		if _, err := s.kapi.Set(ctx, key, enc, &client.SetOptions{TTL: ttl}); err != nil {
+			return fmt.Errorf("unable to store history %s: %v", key, err)
+		}
+	}
+	return nil
+}
+
+func (s *etcd2store) ListHistory(ctx context.Context, serviceID string) ([]*types.HistoryEntry, error) {
+	resp, err := s.kapi.Get(ctx, s.historyKey(serviceID), &client.GetOptions{Quorum: true, Sort: true})
+	if client.IsKeyNotFound(err) {
+		return []*types.HistoryEntry{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to list history of %s: %v", serviceID, err)
+	}
+
+	var entries []*types.HistoryEntry
+	for _, node := range resp.Node.Nodes {
+		entries = append(entries, unmarshalHistoryEntry(base64decode(node.Value)))
+	}
+	return entries, nil
+}
+
+func (s *etcd2store) leaderKey(election string) string {
+	return s.prefix + leaders + "/" + election
+}
+
+func (s *etcd2store) CampaignLeader(ctx context.Context, election, candidate string,
+	ttl time.Duration) (string, error) {
+	key := s.leaderKey(election)
+	opts := &client.SetOptions{TTL: ttl, PrevExist: client.PrevNoExist}
+	resp, err := s.kapi.Get(ctx, key, s.getOpts)
+	switch {
+	case client.IsKeyNotFound(err):
+	case err != nil:
+		return "", fmt.Errorf("unable to get leader of %s: %v", election, err)
+	case resp.Node.Value != candidate:
+		return resp.Node.Value, nil
+	default:
+		opts = &client.SetOptions{TTL: ttl, PrevIndex: resp.Node.ModifiedIndex}
+	}
+
+	_, err = s.kapi.Set(ctx, key, candidate, opts)
+	if err == nil {
+		return candidate, nil
+	}
+	if !isCompareFailed(err) {
+		return "", fmt.Errorf("unable to campaign for leader of %s: %v", election, err)
+	}
+
+	// another candidate won
+	resp, err = s.kapi.Get(ctx, key, s.getOpts)
+	if client.IsKeyNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("unable to get leader of %s: %v", election, err)
+	}
+	return resp.Node.Value, nil
+}
+
+func (s *etcd2store) ResignLeader(ctx context.Context, election, candidate string) error {
+	_, err := s.kapi.Delete(ctx, s.leaderKey(election), &client.DeleteOptions{PrevValue: candidate})
+	if err != nil && !client.IsKeyNotFound(err) && !isCompareFailed(err) {
+		return fmt.Errorf("unable to resign leader of %s: %v", election, err)
+	}
+	return nil
+}
+
+// isCompareFailed returns true if a conditional update failed because the key was changed by someone else.
+func isCompareFailed(err error) bool {
+	if cErr, ok := err.(client.Error); ok {
+		return cErr.Code == client.ErrorCodeTestFailed || cErr.Code == client.ErrorCodeNodeExist
+	}
+	return false
+}
+
 func (s *etcd2store) Subscribe(subscriber func(), stopCh <-chan struct{}) {
 	options := &client.WatcherOptions{
 		Recursive: true,
@@ -207,8 +508,10 @@ func (s *etcd2store) Subscribe(subscriber func(), stopCh <-chan struct{}) {
 
 		for {
 			select {
-			case <-respCh:
-				subscriber()
+			case resp := <-respCh:
+				if resp.Node != nil && isStateKey(s.prefix, resp.Node.Key) {
+					subscriber()
+				}
 			case <-stopCh:
 				return
 			}
@@ -219,8 +522,16 @@ func (s *etcd2store) Subscribe(subscriber func(), stopCh <-chan struct{}) {
 func (s *etcd2store) handleWatcherUpdates(ctx context.Context, watcher client.Watcher, respCh chan<- *client.Response) {
 	handler := func() error {
 		resp, err := watcher.Next(ctx)
+		if ctx.Err() == nil {
+			s.setWatchError(err)
+		}
 		if err == nil {
-			respCh <- resp
+			select {
+			case respCh <- resp:
+			case <-ctx.Done():
+				// the subscription has stopped, so nothing is receiving
+				return &backoff.PermanentError{Err: ctx.Err()}
+			}
 		} else {
 			if ctx.Err() != nil {
 				// context was cancelled, exit handler
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/cenkalti/backoff"
@@ -14,12 +15,53 @@ import (
 )
 
 type etcd3store struct {
-	client *clientv3.Client
-	prefix string
+	watchState
+	client   *clientv3.Client
+	prefix   string
+	encoding Encoding
+
+	auditLease   sharedLease
+	historyLease sharedLease
+}
+
+// leaseReuse is how long keys written with a sharedLease share it. They expire up to this long after their ttl.
+const leaseReuse = time.Hour
+
+// sharedLease is a lease shared by the keys written within leaseReuse of each other, as etcd keeps every lease in
+// memory and a busy API would otherwise create one for each write.
+type sharedLease struct {
+	mu    sync.Mutex
+	id    clientv3.LeaseID
+	ttl   time.Duration
+	until time.Time
+}
+
+// get returns a lease which expires at least ttl from now, granting a new one every leaseReuse.
+func (l *sharedLease) get(ctx context.Context, client *clientv3.Client, ttl time.Duration) (clientv3.LeaseID, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if l.id != clientv3.NoLease && l.ttl == ttl && now.Before(l.until) {
+		return l.id, nil
+	}
+	lease, err := client.Grant(ctx, int64((ttl + leaseReuse).Seconds()))
+	if err != nil {
+		return clientv3.NoLease, err
+	}
+	l.id, l.ttl, l.until = lease.ID, ttl, now.Add(leaseReuse)
+	return lease.ID, nil
+}
+
+// reset forgets the lease after a write with it failed, as it may have been revoked, so the next write gets a new
+// one.
+func (l *sharedLease) reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.id = clientv3.NoLease
 }
 
-// NewEtcd3 returns a Store implementation using an etcd3 backing store.
-func NewEtcd3(endpoints []string, prefix string) (Store, error) {
+// NewEtcd3 returns a Store implementation using an etcd3 backing store, which writes values in encoding.
+func NewEtcd3(endpoints []string, prefix string, encoding Encoding) (Store, error) {
 	cfg := clientv3.Config{
 		Endpoints:   endpoints,
 		DialTimeout: time.Second,
@@ -29,7 +71,7 @@ func NewEtcd3(endpoints []string, prefix string) (Store, error) {
 	if err != nil {
 		return nil, err
 	}
-	s := &etcd3store{client: c, prefix: prefix}
+	s := &etcd3store{client: c, prefix: prefix, encoding: encoding}
 
 	return s, s.init()
 }
@@ -59,7 +101,7 @@ func (s *etcd3store) GetService(ctx context.Context, serviceID string) (*types.V
 }
 
 func (s *etcd3store) PutService(ctx context.Context, service *types.VirtualService) error {
-	b, err := proto.Marshal(service)
+	b, err := s.encoding.marshal(service)
 	if err != nil {
 		panic(err)
 	}
@@ -101,7 +143,7 @@ func (s *etcd3store) GetServer(ctx context.Context, serviceID string, key *types
 }
 
 func (s *etcd3store) PutServer(ctx context.Context, server *types.RealServer) error {
-	b, err := proto.Marshal(server)
+	b, err := s.encoding.marshal(server)
 	if err != nil {
 		panic(err)
 	}
@@ -151,6 +193,322 @@ func (s *etcd3store) ListServers(ctx context.Context, serviceID string) ([]*type
 	return servers, nil
 }
 
+func (s *etcd3store) ListAllServers(ctx context.Context) ([]*types.RealServer, error) {
+	resp, err := s.client.Get(ctx, s.prefix+servers+"/", clientv3.WithPrefix())
+	if err != nil {
+		return nil, fmt.Errorf("unable to list servers: %v", err)
+	}
+
+	var servers []*types.RealServer
+	for _, node := range resp.Kvs {
+		servers = append(servers, unmarshalServer(node.Value))
+	}
+	return servers, nil
+}
+
+// etcd3MaxTxnOps is the most operations etcd allows in a transaction by default, set by its --max-txn-ops.
+const etcd3MaxTxnOps = 128
+
+func (s *etcd3store) Apply(ctx context.Context, changes []*types.Change) error {
+	var ops []clientv3.Op
+	for _, change := range changes {
+		switch change.Action {
+		case types.Change_CREATE, types.Change_UPDATE:
+			var key string
+			var pb proto.Message
+			if change.Service != nil {
+				key, pb = s.serviceKey(change.Service.Id), change.Service
+			} else {
+				key, pb = s.serverKey(change.Server.ServiceID, change.Server.Key), change.Server
+			}
+			b, err := s.encoding.marshal(pb)
+			if err != nil {
+				panic(err)
+			}
+			ops = append(ops, clientv3.OpPut(key, string(b)))
+		case types.Change_DELETE:
+			if change.Service != nil {
+				ops = append(ops, clientv3.OpDelete(s.serviceKey(change.Service.Id)))
+			} else {
+				ops = append(ops, clientv3.OpDelete(s.serverKey(change.Server.ServiceID, change.Server.Key)))
+			}
+		default:
+			return fmt.Errorf("unknown action %v", change.Action)
+		}
+	}
+
+	if _, err := s.client.Txn(ctx).Then(ops...).Commit(); err != nil {
+		return fmt.Errorf("unable to apply %d changes: %v", len(ops), err)
+	}
+	return nil
+}
+
+// AtomicApply is limited to the operations etcd allows in a transaction, as Apply makes one for each change.
+func (s *etcd3store) AtomicApply() (bool, int) {
+	return true, etcd3MaxTxnOps
+}
+
+func (s *etcd3store) nodeKey(name string) string {
+	return s.prefix + nodes + "/" + name
+}
+
+func (s *etcd3store) PutNode(ctx context.Context, node *types.Node, ttl time.Duration) error {
+	b, err := s.encoding.marshal(node)
+	if err != nil {
+		panic(err)
+	}
+
+	// the previous lease is left to expire, as it no longer has any keys attached
+	lease, err := s.client.Grant(ctx, int64(ttl.Seconds()))
+	if err != nil {
+		return fmt.Errorf("unable to create lease for node %s: %v", node.Name, err)
+	}
+	if _, err := s.client.Put(ctx, s.nodeKey(node.Name), string(b), clientv3.WithLease(lease.ID)); err != nil {
+		return fmt.Errorf("unable to store node %s: %v", node.Name, err)
+	}
+
+	return nil
+}
+
+func (s *etcd3store) ListNodes(ctx context.Context) ([]*types.Node, error) {
+	resp, err := s.client.Get(ctx, s.nodeKey(""), clientv3.WithPrefix())
+	if err != nil {
+		return nil, fmt.Errorf("unable to list nodes: %v", err)
+	}
+
+	var nodes []*types.Node
+	for _, kv := range resp.Kvs {
+		nodes = append(nodes, unmarshalNode(kv.Value))
+	}
+	return nodes, nil
+}
+
+func (s *etcd3store) maintenanceKey(node string) string {
+	return s.prefix + maintenance + "/" + node
+}
+
+func (s *etcd3store) SetMaintenance(ctx context.Context, node string, enabled bool) error {
+	var err error
+	if enabled {
+		_, err = s.client.Put(ctx, s.maintenanceKey(node), "true")
+	} else {
+		_, err = s.client.Delete(ctx, s.maintenanceKey(node))
+	}
+	if err != nil {
+		return fmt.Errorf("unable to set maintenance of node %s: %v", node, err)
+	}
+	return nil
+}
+
+func (s *etcd3store) GetMaintenance(ctx context.Context, node string) (bool, error) {
+	resp, err := s.client.Get(ctx, s.maintenanceKey(node))
+	if err != nil {
+		return false, fmt.Errorf("unable to get maintenance of node %s: %v", node, err)
+	}
+	return len(resp.Kvs) > 0, nil
+}
+
+func (s *etcd3store) poolKey(name string) string {
+	return s.prefix + pools + "/" + name
+}
+
+func (s *etcd3store) PutPool(ctx context.Context, pool *types.VIPPool) error {
+	b, err := s.encoding.marshal(pool)
+	if err != nil {
+		panic(err)
+	}
+
+	if _, err := s.client.Put(ctx, s.poolKey(pool.Name), string(b)); err != nil {
+		return fmt.Errorf("unable to store pool %s: %v", pool.Name, err)
+	}
+	return nil
+}
+
+func (s *etcd3store) DeletePool(ctx context.Context, name string) error {
+	_, err := s.client.Delete(ctx, s.poolKey(name))
+	return err
+}
+
+func (s *etcd3store) ListPools(ctx context.Context) ([]*types.VIPPool, error) {
+	resp, err := s.client.Get(ctx, s.poolKey(""), clientv3.WithPrefix())
+	if err != nil {
+		return nil, fmt.Errorf("unable to list pools: %v", err)
+	}
+
+	var pools []*types.VIPPool
+	for _, kv := range resp.Kvs {
+		pools = append(pools, unmarshalPool(kv.Value))
+	}
+	return pools, nil
+}
+
+func (s *etcd3store) GetCandidate(ctx context.Context) (*types.Candidate, error) {
+	resp, err := s.client.Get(ctx, s.prefix+candidate)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get candidate: %v", err)
+	}
+	if len(resp.Kvs) == 0 {
+		return &types.Candidate{}, nil
+	}
+	c := unmarshalCandidate(resp.Kvs[0].Value)
+	c.Revision = resp.Kvs[0].ModRevision
+	return c, nil
+}
+
+func (s *etcd3store) PutCandidate(ctx context.Context, c *types.Candidate) error {
+	b, err := s.encoding.marshal(storedCandidate(c))
+	if err != nil {
+		panic(err)
+	}
+
+	// a missing key has a mod revision of 0, the revision of a candidate which was never stored
+	key := s.prefix + candidate
+	txn, err := s.client.Txn(ctx).
+		If(clientv3.Compare(clientv3.ModRevision(key), "=", c.Revision)).
+		Then(clientv3.OpPut(key, string(b))).
+		Commit()
+	if err != nil {
+		return fmt.Errorf("unable to store candidate: %v", err)
+	}
+	if !txn.Succeeded {
+		return ErrCandidateChanged
+	}
+	c.Revision = txn.Header.Revision
+	return nil
+}
+
+func (s *etcd3store) AddAuditEvent(ctx context.Context, event *types.AuditEvent, ttl time.Duration) error {
+	b, err := s.encoding.marshal(event)
+	if err != nil {
+		panic(err)
+	}
+	lease, err := s.auditLease.get(ctx, s.client, ttl)
+	if err != nil {
+		return fmt.Errorf("unable to create lease for audit event: %v", err)
+	}
+
+	key := s.prefix + audit + "/" + auditName(event)
+	if _, err := s.client.Put(ctx, key, string(b), clientv3.WithLease(lease)); err != nil {
+		s.auditLease.reset()
+		return fmt.Errorf("unable to store audit event %s: %v", key, err)
+	}
+	return nil
+}
+
+func (s *etcd3store) ListAuditEvents(ctx context.Context) ([]*types.AuditEvent, error) {
+	resp, err := s.client.Get(ctx, s.prefix+audit+"/", clientv3.WithPrefix(),
+		clientv3.WithSort(clientv3.SortByKey, clientv3.SortAscend))
+	if err != nil {
+		return nil, fmt.Errorf("unable to list audit events: %v", err)
+	}
+
+	var events []*types.AuditEvent
+	for _, kv := range resp.Kvs {
+		events = append(events, unmarshalAuditEvent(kv.Value))
+	}
+	return events, nil
+}
+
+func (s *etcd3store) historyKey(name string) string {
+	return s.prefix + history + "/" + name
+}
+
+func (s *etcd3store) AddHistory(ctx context.Context, entries []*types.HistoryEntry, ttl time.Duration) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	lease, err := s.historyLease.get(ctx, s.client, ttl)
+	if err != nil {
+		return fmt.Errorf("unable to create lease for history: %v", err)
+	}
+
+	var ops []clientv3.Op
+	for i, entry := range entries {
+		b, err := s.encoding.marshal(entry)
+		if err != nil {
+			panic(err)
+		}
+		ops = append(ops, clientv3.OpPut(s.historyKey(historyName(entry, i)), string(b), clientv3.WithLease(lease)))
+	}
+	if _, err := s.client.Txn(ctx).Then(ops...).Commit(); err != nil {
+		s.historyLease.reset()
+		return fmt.Errorf("unable to store %d history entries: %v", len(ops), err)
+	}
+	return nil
+}
+
+func (s *etcd3store) ListHistory(ctx context.Context, serviceID string) ([]*types.HistoryEntry, error) {
+	resp, err := s.client.Get(ctx, s.historyKey(serviceID+"/"), clientv3.WithPrefix(),
+		clientv3.WithSort(clientv3.SortByKey, clientv3.SortAscend))
+	if err != nil {
+		return nil, fmt.Errorf("unable to list history of %s: %v", serviceID, err)
+	}
+
+	var entries []*types.HistoryEntry
+	for _, kv := range resp.Kvs {
+		entries = append(entries, unmarshalHistoryEntry(kv.Value))
+	}
+	return entries, nil
+}
+
+func (s *etcd3store) leaderKey(election string) string {
+	return s.prefix + leaders + "/" + election
+}
+
+func (s *etcd3store) CampaignLeader(ctx context.Context, election, candidate string,
+	ttl time.Duration) (string, error) {
+	key := s.leaderKey(election)
+	resp, err := s.client.Get(ctx, key)
+	if err != nil {
+		return "", fmt.Errorf("unable to get leader of %s: %v", election, err)
+	}
+	// a missing key has a mod revision of 0
+	var revision int64
+	if len(resp.Kvs) > 0 {
+		if leader := string(resp.Kvs[0].Value); leader != candidate {
+			return leader, nil
+		}
+		revision = resp.Kvs[0].ModRevision
+	}
+
+	// the previous lease is left to expire, as it no longer has any keys attached
+	lease, err := s.client.Grant(ctx, int64(ttl.Seconds()))
+	if err != nil {
+		return "", fmt.Errorf("unable to create lease for leader of %s: %v", election, err)
+	}
+	txn, err := s.client.Txn(ctx).
+		If(clientv3.Compare(clientv3.ModRevision(key), "=", revision)).
+		Then(clientv3.OpPut(key, candidate, clientv3.WithLease(lease.ID))).
+		Commit()
+	if err != nil {
+		return "", fmt.Errorf("unable to campaign for leader of %s: %v", election, err)
+	}
+	if txn.Succeeded {
+		return candidate, nil
+	}
+
+	// another candidate won
+	if resp, err = s.client.Get(ctx, key); err != nil {
+		return "", fmt.Errorf("unable to get leader of %s: %v", election, err)
+	}
+	if len(resp.Kvs) == 0 {
+		return "", nil
+	}
+	return string(resp.Kvs[0].Value), nil
+}
+
+func (s *etcd3store) ResignLeader(ctx context.Context, election, candidate string) error {
+	key := s.leaderKey(election)
+	_, err := s.client.Txn(ctx).
+		If(clientv3.Compare(clientv3.Value(key), "=", candidate)).
+		Then(clientv3.OpDelete(key)).
+		Commit()
+	if err != nil {
+		return fmt.Errorf("unable to resign leader of %s: %v", election, err)
+	}
+	return nil
+}
+
 func (s *etcd3store) Subscribe(subscriber func(), stopCh <-chan struct{}) {
 	go func() {
 		ctx, cancelFunc := context.WithCancel(context.Background())
@@ -163,8 +521,13 @@ func (s *etcd3store) Subscribe(subscriber func(), stopCh <-chan struct{}) {
 
 		for {
 			select {
-			case <-respCh:
-				subscriber()
+			case resp := <-respCh:
+				for _, event := range resp.Events {
+					if isStateKey(s.prefix, string(event.Kv.Key)) {
+						subscriber()
+						break
+					}
+				}
 			case <-stopCh:
 				return
 			}
@@ -174,9 +537,21 @@ func (s *etcd3store) Subscribe(subscriber func(), stopCh <-chan struct{}) {
 
 func (s *etcd3store) handleWatcherUpdates(ctx context.Context, watcher clientv3.WatchChan, respCh chan<- clientv3.WatchResponse) {
 	handler := func() error {
-		resp := <-watcher
+		resp, ok := <-watcher
+		if !ok {
+			// the watch is closed once the context is cancelled
+			return &backoff.PermanentError{Err: context.Canceled}
+		}
+		if ctx.Err() == nil {
+			s.setWatchError(resp.Err())
+		}
 		if resp.Err() == nil {
-			respCh <- resp
+			select {
+			case respCh <- resp:
+			case <-ctx.Done():
+				// the subscription has stopped, so nothing is receiving
+				return &backoff.PermanentError{Err: ctx.Err()}
+			}
 		} else {
 			if ctx.Err() != nil {
 				// context was cancelled, exit handler
@@ -0,0 +1,378 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/sky-uk/merlin/types"
+)
+
+type memoryValue struct {
+	value   []byte
+	expires time.Time
+}
+
+type memorySubscriber struct {
+	fn     func()
+	stopCh <-chan struct{}
+}
+
+type memoryStore struct {
+	mu          sync.Mutex
+	kvs         map[string]memoryValue
+	subscribers []memorySubscriber
+	encoding    Encoding
+	// candidateRevision counts the writes of the candidate, so they can be made conditional like in etcd
+	candidateRevision int64
+}
+
+// NewMemory returns a Store implementation which keeps state in memory, for tests which don't need a real etcd.
+// It uses the same layout as the etcd stores, and entries expire after their ttl.
+func NewMemory() Store {
+	return NewMemoryEncoding(EncodingProto)
+}
+
+// NewMemoryEncoding returns an in-memory Store which writes values in encoding.
+func NewMemoryEncoding(encoding Encoding) Store {
+	return &memoryStore{kvs: make(map[string]memoryValue), encoding: encoding}
+}
+
+func (s *memoryStore) get(key string) []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.kvs[key]
+	if !ok || (!v.expires.IsZero() && time.Now().After(v.expires)) {
+		return nil
+	}
+	return v.value
+}
+
+// list returns the values under prefix, sorted by key.
+func (s *memoryStore) list(prefix string) [][]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var keys []string
+	for key, v := range s.kvs {
+		if strings.HasPrefix(key, prefix) && (v.expires.IsZero() || time.Now().Before(v.expires)) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	values := make([][]byte, 0, len(keys))
+	for _, key := range keys {
+		values = append(values, s.kvs[key].value)
+	}
+	return values
+}
+
+// update applies fn to the key values atomically, then notifies subscribers if any of changed are state keys.
+func (s *memoryStore) update(fn func(kvs map[string]memoryValue), changed ...string) {
+	s.mu.Lock()
+	fn(s.kvs)
+	subscribers := s.subscribers
+	s.mu.Unlock()
+
+	for _, key := range changed {
+		if isStateKey("", key) {
+			s.notify(subscribers)
+			return
+		}
+	}
+}
+
+func (s *memoryStore) notify(subscribers []memorySubscriber) {
+	for _, sub := range subscribers {
+		select {
+		case <-sub.stopCh:
+		default:
+			sub.fn()
+		}
+	}
+}
+
+func (s *memoryStore) put(key string, pb proto.Message, ttl time.Duration) {
+	b, err := s.encoding.marshal(pb)
+	if err != nil {
+		panic(err)
+	}
+	v := memoryValue{value: b}
+	if ttl > 0 {
+		v.expires = time.Now().Add(ttl)
+	}
+	s.update(func(kvs map[string]memoryValue) { kvs[key] = v }, key)
+}
+
+func (s *memoryStore) delete(key string) {
+	s.update(func(kvs map[string]memoryValue) { delete(kvs, key) }, key)
+}
+
+func serviceKey(id string) string {
+	return services + "/" + id
+}
+
+func serverDir(serviceID string) string {
+	return servers + "/" + serviceID + "/"
+}
+
+func serverKey(serviceID string, key *types.RealServer_Key) string {
+	return fmt.Sprintf("%s%s:%d", serverDir(serviceID), key.Ip, key.Port)
+}
+
+func (s *memoryStore) GetService(_ context.Context, serviceID string) (*types.VirtualService, error) {
+	b := s.get(serviceKey(serviceID))
+	if b == nil {
+		return nil, nil
+	}
+	return unmarshalService(b), nil
+}
+
+func (s *memoryStore) PutService(_ context.Context, service *types.VirtualService) error {
+	s.put(serviceKey(service.Id), service, 0)
+	return nil
+}
+
+func (s *memoryStore) DeleteService(_ context.Context, serviceID string) error {
+	s.delete(serviceKey(serviceID))
+	return nil
+}
+
+func (s *memoryStore) GetServer(_ context.Context, serviceID string,
+	key *types.RealServer_Key) (*types.RealServer, error) {
+	if key == nil {
+		// can't retrieve server without a key
+		return nil, nil
+	}
+	b := s.get(serverKey(serviceID, key))
+	if b == nil {
+		return nil, nil
+	}
+	return unmarshalServer(b), nil
+}
+
+func (s *memoryStore) PutServer(_ context.Context, server *types.RealServer) error {
+	s.put(serverKey(server.ServiceID, server.Key), server, 0)
+	return nil
+}
+
+func (s *memoryStore) DeleteServer(_ context.Context, serviceID string, key *types.RealServer_Key) error {
+	if key == nil {
+		// nothing can be stored without a key
+		return nil
+	}
+	s.delete(serverKey(serviceID, key))
+	return nil
+}
+
+func (s *memoryStore) ListServices(context.Context) ([]*types.VirtualService, error) {
+	var services []*types.VirtualService
+	for _, b := range s.list(serviceKey("")) {
+		services = append(services, unmarshalService(b))
+	}
+	return services, nil
+}
+
+func (s *memoryStore) ListServers(_ context.Context, serviceID string) ([]*types.RealServer, error) {
+	servers := []*types.RealServer{}
+	for _, b := range s.list(serverDir(serviceID)) {
+		servers = append(servers, unmarshalServer(b))
+	}
+	return servers, nil
+}
+
+func (s *memoryStore) ListAllServers(_ context.Context) ([]*types.RealServer, error) {
+	all := []*types.RealServer{}
+	for _, b := range s.list(servers + "/") {
+		all = append(all, unmarshalServer(b))
+	}
+	return all, nil
+}
+
+func (s *memoryStore) Apply(_ context.Context, changes []*types.Change) error {
+	puts := make(map[string][]byte)
+	var keys []string
+	for _, change := range changes {
+		var key string
+		var pb proto.Message
+		if change.Service != nil {
+			key, pb = serviceKey(change.Service.Id), change.Service
+		} else {
+			key, pb = serverKey(change.Server.ServiceID, change.Server.Key), change.Server
+		}
+		switch change.Action {
+		case types.Change_CREATE, types.Change_UPDATE:
+			b, err := s.encoding.marshal(pb)
+			if err != nil {
+				panic(err)
+			}
+			puts[key] = b
+		case types.Change_DELETE:
+			puts[key] = nil
+		default:
+			return fmt.Errorf("unknown action %v", change.Action)
+		}
+		keys = append(keys, key)
+	}
+
+	s.update(func(kvs map[string]memoryValue) {
+		for key, b := range puts {
+			if b == nil {
+				delete(kvs, key)
+			} else {
+				kvs[key] = memoryValue{value: b}
+			}
+		}
+	}, keys...)
+	return nil
+}
+
+func (s *memoryStore) AtomicApply() (bool, int) {
+	return true, 0
+}
+
+func (s *memoryStore) PutNode(_ context.Context, node *types.Node, ttl time.Duration) error {
+	s.put(nodes+"/"+node.Name, node, ttl)
+	return nil
+}
+
+func (s *memoryStore) ListNodes(context.Context) ([]*types.Node, error) {
+	var nodeList []*types.Node
+	for _, b := range s.list(nodes + "/") {
+		nodeList = append(nodeList, unmarshalNode(b))
+	}
+	return nodeList, nil
+}
+
+func (s *memoryStore) SetMaintenance(_ context.Context, node string, enabled bool) error {
+	key := maintenance + "/" + node
+	if !enabled {
+		s.delete(key)
+		return nil
+	}
+	s.update(func(kvs map[string]memoryValue) { kvs[key] = memoryValue{value: []byte("true")} }, key)
+	return nil
+}
+
+func (s *memoryStore) GetMaintenance(_ context.Context, node string) (bool, error) {
+	return s.get(maintenance+"/"+node) != nil, nil
+}
+
+func (s *memoryStore) PutPool(_ context.Context, pool *types.VIPPool) error {
+	s.put(pools+"/"+pool.Name, pool, 0)
+	return nil
+}
+
+func (s *memoryStore) DeletePool(_ context.Context, name string) error {
+	s.delete(pools + "/" + name)
+	return nil
+}
+
+func (s *memoryStore) ListPools(context.Context) ([]*types.VIPPool, error) {
+	var poolList []*types.VIPPool
+	for _, b := range s.list(pools + "/") {
+		poolList = append(poolList, unmarshalPool(b))
+	}
+	return poolList, nil
+}
+
+func (s *memoryStore) GetCandidate(context.Context) (*types.Candidate, error) {
+	s.mu.Lock()
+	v, ok := s.kvs[candidate]
+	revision := s.candidateRevision
+	s.mu.Unlock()
+	if !ok {
+		return &types.Candidate{}, nil
+	}
+	c := unmarshalCandidate(v.value)
+	c.Revision = revision
+	return c, nil
+}
+
+func (s *memoryStore) PutCandidate(_ context.Context, c *types.Candidate) error {
+	b, err := s.encoding.marshal(storedCandidate(c))
+	if err != nil {
+		panic(err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c.Revision != s.candidateRevision {
+		return ErrCandidateChanged
+	}
+	s.candidateRevision++
+	s.kvs[candidate] = memoryValue{value: b}
+	c.Revision = s.candidateRevision
+	return nil
+}
+
+func (s *memoryStore) AddAuditEvent(_ context.Context, event *types.AuditEvent, ttl time.Duration) error {
+	s.put(audit+"/"+auditName(event), event, ttl)
+	return nil
+}
+
+func (s *memoryStore) ListAuditEvents(context.Context) ([]*types.AuditEvent, error) {
+	var events []*types.AuditEvent
+	for _, b := range s.list(audit + "/") {
+		events = append(events, unmarshalAuditEvent(b))
+	}
+	return events, nil
+}
+
+func (s *memoryStore) AddHistory(_ context.Context, entries []*types.HistoryEntry, ttl time.Duration) error {
+	for i, entry := range entries {
+		s.put(history+"/"+historyName(entry, i), entry, ttl)
+	}
+	return nil
+}
+
+func (s *memoryStore) ListHistory(_ context.Context, serviceID string) ([]*types.HistoryEntry, error) {
+	var entries []*types.HistoryEntry
+	for _, b := range s.list(history + "/" + serviceID + "/") {
+		entries = append(entries, unmarshalHistoryEntry(b))
+	}
+	return entries, nil
+}
+
+func (s *memoryStore) CampaignLeader(_ context.Context, election, candidate string,
+	ttl time.Duration) (string, error) {
+	key := leaders + "/" + election
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if v, ok := s.kvs[key]; ok && time.Now().Before(v.expires) && string(v.value) != candidate {
+		return string(v.value), nil
+	}
+	s.kvs[key] = memoryValue{value: []byte(candidate), expires: time.Now().Add(ttl)}
+	return candidate, nil
+}
+
+func (s *memoryStore) ResignLeader(_ context.Context, election, candidate string) error {
+	key := leaders + "/" + election
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if v, ok := s.kvs[key]; ok && string(v.value) == candidate {
+		delete(s.kvs, key)
+	}
+	return nil
+}
+
+func (s *memoryStore) Subscribe(subscriber func(), stopCh <-chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// forget stopped subscribers, as short-lived ones such as API watches would otherwise accumulate
+	var subscribers []memorySubscriber
+	for _, sub := range s.subscribers {
+		select {
+		case <-sub.stopCh:
+		default:
+			subscribers = append(subscribers, sub)
+		}
+	}
+	s.subscribers = append(subscribers, memorySubscriber{fn: subscriber, stopCh: stopCh})
+}
+
+func (s *memoryStore) WatchError() error {
+	return nil
+}
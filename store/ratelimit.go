@@ -0,0 +1,166 @@
+package store
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sky-uk/merlin/types"
+)
+
+// rateLimitedStore throttles the write requests made to a store.
+type rateLimitedStore struct {
+	Store
+	limiter *limiter
+}
+
+// RateLimit returns a store which limits the write requests made to s to writesPerSecond, allowing bursts of up to
+// burst writes. Writes over the limit wait until they're allowed, or their context is done. Apply is a single write
+// however many changes it makes, so bulk changes should be applied in batches.
+func RateLimit(s Store, writesPerSecond float64, burst int) Store {
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimitedStore{Store: s, limiter: &limiter{
+		rate:   writesPerSecond,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}}
+}
+
+// limiter is a token bucket, which refills at rate tokens per second up to burst tokens.
+type limiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// wait until a token is available and take it, or return the context's error if it's done first.
+func (l *limiter) wait(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+		l.last = now
+		if l.tokens >= 1 {
+			l.tokens--
+			l.mu.Unlock()
+			return nil
+		}
+		delay := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+		l.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+func (s *rateLimitedStore) PutService(ctx context.Context, service *types.VirtualService) error {
+	if err := s.limiter.wait(ctx); err != nil {
+		return err
+	}
+	return s.Store.PutService(ctx, service)
+}
+
+func (s *rateLimitedStore) DeleteService(ctx context.Context, serviceID string) error {
+	if err := s.limiter.wait(ctx); err != nil {
+		return err
+	}
+	return s.Store.DeleteService(ctx, serviceID)
+}
+
+func (s *rateLimitedStore) PutServer(ctx context.Context, server *types.RealServer) error {
+	if err := s.limiter.wait(ctx); err != nil {
+		return err
+	}
+	return s.Store.PutServer(ctx, server)
+}
+
+func (s *rateLimitedStore) DeleteServer(ctx context.Context, serviceID string, key *types.RealServer_Key) error {
+	if err := s.limiter.wait(ctx); err != nil {
+		return err
+	}
+	return s.Store.DeleteServer(ctx, serviceID, key)
+}
+
+func (s *rateLimitedStore) Apply(ctx context.Context, changes []*types.Change) error {
+	if err := s.limiter.wait(ctx); err != nil {
+		return err
+	}
+	return s.Store.Apply(ctx, changes)
+}
+
+func (s *rateLimitedStore) PutNode(ctx context.Context, node *types.Node, ttl time.Duration) error {
+	if err := s.limiter.wait(ctx); err != nil {
+		return err
+	}
+	return s.Store.PutNode(ctx, node, ttl)
+}
+
+func (s *rateLimitedStore) SetMaintenance(ctx context.Context, node string, enabled bool) error {
+	if err := s.limiter.wait(ctx); err != nil {
+		return err
+	}
+	return s.Store.SetMaintenance(ctx, node, enabled)
+}
+
+func (s *rateLimitedStore) PutPool(ctx context.Context, pool *types.VIPPool) error {
+	if err := s.limiter.wait(ctx); err != nil {
+		return err
+	}
+	return s.Store.PutPool(ctx, pool)
+}
+
+func (s *rateLimitedStore) DeletePool(ctx context.Context, name string) error {
+	if err := s.limiter.wait(ctx); err != nil {
+		return err
+	}
+	return s.Store.DeletePool(ctx, name)
+}
+
+func (s *rateLimitedStore) PutCandidate(ctx context.Context, c *types.Candidate) error {
+	if err := s.limiter.wait(ctx); err != nil {
+		return err
+	}
+	return s.Store.PutCandidate(ctx, c)
+}
+
+func (s *rateLimitedStore) AddAuditEvent(ctx context.Context, event *types.AuditEvent, ttl time.Duration) error {
+	if err := s.limiter.wait(ctx); err != nil {
+		return err
+	}
+	return s.Store.AddAuditEvent(ctx, event, ttl)
+}
+
+func (s *rateLimitedStore) AddHistory(ctx context.Context, entries []*types.HistoryEntry, ttl time.Duration) error {
+	if err := s.limiter.wait(ctx); err != nil {
+		return err
+	}
+	return s.Store.AddHistory(ctx, entries, ttl)
+}
+
+func (s *rateLimitedStore) CampaignLeader(ctx context.Context, election, candidate string,
+	ttl time.Duration) (string, error) {
+	if err := s.limiter.wait(ctx); err != nil {
+		return "", err
+	}
+	return s.Store.CampaignLeader(ctx, election, candidate, ttl)
+}
+
+func (s *rateLimitedStore) ResignLeader(ctx context.Context, election, candidate string) error {
+	if err := s.limiter.wait(ctx); err != nil {
+		return err
+	}
+	return s.Store.ResignLeader(ctx, election, candidate)
+}
@@ -0,0 +1,57 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sky-uk/merlin/types"
+)
+
+func TestStore(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Store Suite")
+}
+
+var _ = Describe("RateLimit", func() {
+	var (
+		ctx = context.Background()
+		svc = &types.VirtualService{Id: "svc"}
+	)
+
+	It("should allow a burst of writes, then limit them to the rate", func() {
+		st := RateLimit(NewMemory(), 20, 2)
+
+		start := time.Now()
+		Expect(st.PutService(ctx, svc)).To(Succeed())
+		Expect(st.PutService(ctx, svc)).To(Succeed())
+		Expect(time.Since(start)).To(BeNumerically("<", 25*time.Millisecond))
+
+		Expect(st.PutService(ctx, svc)).To(Succeed())
+		Expect(st.DeleteService(ctx, "svc")).To(Succeed())
+		Expect(time.Since(start)).To(BeNumerically(">=", 90*time.Millisecond))
+	})
+
+	It("should not limit reads", func() {
+		st := RateLimit(NewMemory(), 1, 1)
+		Expect(st.PutService(ctx, svc)).To(Succeed())
+
+		start := time.Now()
+		for i := 0; i < 3; i++ {
+			Expect(st.GetService(ctx, "svc")).ToNot(BeNil())
+		}
+		Expect(time.Since(start)).To(BeNumerically("<", 25*time.Millisecond))
+	})
+
+	It("should give up waiting when the context is done", func() {
+		st := RateLimit(NewMemory(), 0.1, 1)
+		Expect(st.PutService(ctx, svc)).To(Succeed())
+
+		timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+
+		Expect(st.PutService(timeoutCtx, svc)).To(Equal(context.DeadlineExceeded))
+	})
+})
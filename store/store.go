@@ -1,19 +1,34 @@
 package store
 
 import (
+	"bytes"
 	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/sky-uk/merlin/types"
 )
 
 const (
-	services = "/services"
-	servers  = "/servers"
+	services    = "/services"
+	servers     = "/servers"
+	nodes       = "/nodes"
+	maintenance = "/maintenance"
+	history     = "/history"
+	leaders     = "/leaders"
+	pools       = "/pools"
+	candidate   = "/candidate"
+	audit       = "/audit"
 )
 
+// ErrCandidateChanged is returned by PutCandidate if the candidate was changed since it was read.
+var ErrCandidateChanged = errors.New("candidate was changed since it was read")
+
 // Store for saving desired IPVS state.
 type Store interface {
 	GetService(ctx context.Context, serviceID string) (*types.VirtualService, error)
@@ -24,24 +39,66 @@ type Store interface {
 	DeleteServer(ctx context.Context, serviceID string, key *types.RealServer_Key) error
 	ListServices(context.Context) ([]*types.VirtualService, error)
 	ListServers(ctx context.Context, serviceID string) ([]*types.RealServer, error)
-	// Subscribe to changes. subscriber is called whenever a change occurs in the store.
+	// ListAllServers returns the servers of every service ID, including servers whose service doesn't exist.
+	ListAllServers(context.Context) ([]*types.RealServer, error)
+	// Apply makes the changes in order, as a single atomic update if the backend supports it.
+	Apply(ctx context.Context, changes []*types.Change) error
+	// AtomicApply returns true if Apply makes its changes atomically, and the most changes it can make at once, or 0
+	// if there's no limit.
+	AtomicApply() (atomic bool, limit int)
+	// PutNode registers a merlin node, which expires after ttl unless put again.
+	PutNode(ctx context.Context, node *types.Node, ttl time.Duration) error
+	ListNodes(context.Context) ([]*types.Node, error)
+	// SetMaintenance flags a node as in maintenance, which it picks up on its next heartbeat.
+	SetMaintenance(ctx context.Context, node string, enabled bool) error
+	GetMaintenance(ctx context.Context, node string) (bool, error)
+	// AddHistory records changes to services and servers, which expire after ttl.
+	AddHistory(ctx context.Context, entries []*types.HistoryEntry, ttl time.Duration) error
+	// ListHistory returns the recorded changes to a service and its servers, oldest first.
+	ListHistory(ctx context.Context, serviceID string) ([]*types.HistoryEntry, error)
+	// PutPool creates or replaces a pool of VIPs.
+	PutPool(ctx context.Context, pool *types.VIPPool) error
+	DeletePool(ctx context.Context, name string) error
+	ListPools(context.Context) ([]*types.VIPPool, error)
+	// GetCandidate returns the candidate config, which is empty if nothing has been staged or committed.
+	GetCandidate(context.Context) (*types.Candidate, error)
+	// PutCandidate replaces the candidate config read by GetCandidate, returning ErrCandidateChanged if it has
+	// changed since, such as by another node staging a change at the same time. It sets the revision of the
+	// candidate to the one written, so it can be written again.
+	PutCandidate(context.Context, *types.Candidate) error
+	// AddAuditEvent records a write made through the API, which expires after ttl.
+	AddAuditEvent(ctx context.Context, event *types.AuditEvent, ttl time.Duration) error
+	// ListAuditEvents returns the recorded writes, oldest first.
+	ListAuditEvents(context.Context) ([]*types.AuditEvent, error)
+	// CampaignLeader makes candidate the leader of an election if it has no leader, or renews its leadership if it
+	// already is. Leadership expires after ttl unless renewed. It returns the current leader.
+	CampaignLeader(ctx context.Context, election, candidate string, ttl time.Duration) (string, error)
+	// ResignLeader gives up leadership of an election, if candidate is the leader.
+	ResignLeader(ctx context.Context, election, candidate string) error
+	// Subscribe to changes of services and servers. subscriber is called whenever a change occurs in the store.
 	Subscribe(subscriber func(), stopCh <-chan struct{})
+	// WatchError returns the error of the subscription's watch if it's failing, or nil if it's healthy.
+	WatchError() error
 }
 
-// NewStore returns a Store implementation based upon the storeBackend parameter
-func NewStore(storeBackend string, endpoints []string, prefix string) (Store, error) {
+// NewStore returns a Store implementation based upon the storeBackend parameter, which writes values in encoding.
+func NewStore(storeBackend string, endpoints []string, prefix string, encoding Encoding) (Store, error) {
 
 	switch storeBackend {
 	case "etcd2":
-		return NewEtcd2(endpoints, prefix)
+		return NewEtcd2(endpoints, prefix, encoding)
 	case "etcd3":
-		return NewEtcd3(endpoints, prefix)
+		return NewEtcd3(endpoints, prefix, encoding)
 	default:
 		return nil, fmt.Errorf("unknown store backend: %s", storeBackend)
 	}
 }
 
+// base64decode returns the value of an etcd2 key, which is base64 encoded unless it's JSON.
 func base64decode(raw string) []byte {
+	if isJSON([]byte(raw)) {
+		return []byte(raw)
+	}
 	b, err := base64.StdEncoding.DecodeString(raw)
 	if err != nil {
 		panic(fmt.Errorf("unable to decode - did you break backwards compatibility?: %v", err))
@@ -49,7 +106,17 @@ func base64decode(raw string) []byte {
 	return b
 }
 
-func unmarshal(pb proto.Message, b []byte) proto.Message {
+func unmarshal(pb proto.Message, raw []byte) proto.Message {
+	if isJSON(raw) {
+		if err := jsonUnmarshaler.Unmarshal(bytes.NewReader(raw), pb); err != nil {
+			panic(fmt.Errorf("unable to unmarshal JSON - did you break backwards compatibility?: %v", err))
+		}
+		return pb
+	}
+	b, err := decode(raw)
+	if err != nil {
+		panic(fmt.Errorf("unable to decode: %v", err))
+	}
 	if err := proto.Unmarshal(b, pb); err != nil {
 		panic(fmt.Errorf("unable to unmarshal - did you break backwards compatibility?: %v", err))
 	}
@@ -65,3 +132,102 @@ func unmarshalServer(raw []byte) *types.RealServer {
 	var server types.RealServer
 	return unmarshal(&server, raw).(*types.RealServer)
 }
+
+func unmarshalNode(raw []byte) *types.Node {
+	var node types.Node
+	return unmarshal(&node, raw).(*types.Node)
+}
+
+func unmarshalPool(raw []byte) *types.VIPPool {
+	var pool types.VIPPool
+	return unmarshal(&pool, raw).(*types.VIPPool)
+}
+
+func unmarshalCandidate(raw []byte) *types.Candidate {
+	var c types.Candidate
+	return unmarshal(&c, raw).(*types.Candidate)
+}
+
+// storedCandidate returns a copy of c without its revision, which is only known once it's read.
+func storedCandidate(c *types.Candidate) *types.Candidate {
+	stored := proto.Clone(c).(*types.Candidate)
+	stored.Revision = 0
+	return stored
+}
+
+func unmarshalHistoryEntry(raw []byte) *types.HistoryEntry {
+	var entry types.HistoryEntry
+	return unmarshal(&entry, raw).(*types.HistoryEntry)
+}
+
+func unmarshalAuditEvent(raw []byte) *types.AuditEvent {
+	var event types.AuditEvent
+	return unmarshal(&event, raw).(*types.AuditEvent)
+}
+
+// auditName returns the name of an audit event, which sorts in the order the events happened.
+func auditName(event *types.AuditEvent) string {
+	t := time.Unix(event.GetTime().GetSeconds(), int64(event.GetTime().GetNanos()))
+	return fmt.Sprintf("%020d-%s", t.UnixNano(), event.Node)
+}
+
+// historyName returns the name of the i-th history entry of a batch under its service, which sorts in the order
+// the changes were made.
+func historyName(entry *types.HistoryEntry, i int) string {
+	serviceID := entry.Change.GetServer().GetServiceID()
+	if entry.Change.GetService() != nil {
+		serviceID = entry.Change.Service.Id
+	}
+	t := time.Unix(entry.GetTime().GetSeconds(), int64(entry.GetTime().GetNanos()))
+	return fmt.Sprintf("%s/%020d-%04d", serviceID, t.UnixNano(), i)
+}
+
+// applyInOrder makes each change in turn, for backends without transactions.
+func applyInOrder(ctx context.Context, s Store, changes []*types.Change) error {
+	for _, change := range changes {
+		var err error
+		switch change.Action {
+		case types.Change_CREATE, types.Change_UPDATE:
+			if change.Service != nil {
+				err = s.PutService(ctx, change.Service)
+			} else {
+				err = s.PutServer(ctx, change.Server)
+			}
+		case types.Change_DELETE:
+			if change.Service != nil {
+				err = s.DeleteService(ctx, change.Service.Id)
+			} else {
+				err = s.DeleteServer(ctx, change.Server.ServiceID, change.Server.Key)
+			}
+		default:
+			err = fmt.Errorf("unknown action %v", change.Action)
+		}
+		if err != nil {
+			return fmt.Errorf("unable to %s: %v", change.PrettyString(), err)
+		}
+	}
+	return nil
+}
+
+// watchState records whether a store watch is failing, so it can be reported by health checks.
+type watchState struct {
+	mu  sync.Mutex
+	err error
+}
+
+func (w *watchState) setWatchError(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.err = err
+}
+
+func (w *watchState) WatchError() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+// isStateKey returns true if the key is for a service or server, which subscribers are notified of.
+func isStateKey(prefix, key string) bool {
+	return strings.HasPrefix(key, prefix+services) || strings.HasPrefix(key, prefix+servers)
+}
@@ -0,0 +1,213 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/sky-uk/merlin/types"
+)
+
+// TimedOp is a store operation recorded by Timing.
+type TimedOp struct {
+	// Op is the name of the Store method, such as PutService.
+	Op string
+	// Key of the object the operation is on, empty if it's on many.
+	Key     string
+	Elapsed time.Duration
+}
+
+// Timing records the store operations made with a context, so the latency of a request can be broken down.
+type Timing struct {
+	mu  sync.Mutex
+	ops []TimedOp
+}
+
+type timingKey struct{}
+
+// WithTiming returns a context whose operations on a Timed store are recorded in t.
+func WithTiming(ctx context.Context, t *Timing) context.Context {
+	return context.WithValue(ctx, timingKey{}, t)
+}
+
+// Ops returns the recorded operations, in the order they finished.
+func (t *Timing) Ops() []TimedOp {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]TimedOp(nil), t.ops...)
+}
+
+// String summarises the operations, such as "2 store ops in 120ms: GetService web 20ms, PutService web 100ms".
+func (t *Timing) String() string {
+	ops := t.Ops()
+	var total time.Duration
+	var each []string
+	for _, op := range ops {
+		total += op.Elapsed
+		each = append(each, strings.TrimSpace(fmt.Sprintf("%s %s %v", op.Op, op.Key, op.Elapsed)))
+	}
+	if len(ops) == 0 {
+		return "0 store ops"
+	}
+	return fmt.Sprintf("%d store ops in %v: %s", len(ops), total, strings.Join(each, ", "))
+}
+
+// timedStore times the operations made to a store.
+type timedStore struct {
+	Store
+	slow time.Duration
+}
+
+// Timed returns a store which records the operations made with a context from WithTiming. Operations made
+// without one, such as by the reconciler, are logged if they take longer than slow, unless it's 0.
+func Timed(s Store, slow time.Duration) Store {
+	return &timedStore{Store: s, slow: slow}
+}
+
+func (s *timedStore) record(ctx context.Context, op, key string, start time.Time) {
+	elapsed := time.Since(start)
+	if t, ok := ctx.Value(timingKey{}).(*Timing); ok {
+		t.mu.Lock()
+		t.ops = append(t.ops, TimedOp{Op: op, Key: key, Elapsed: elapsed})
+		t.mu.Unlock()
+		return
+	}
+	if s.slow > 0 && elapsed >= s.slow {
+		log.Warnf("Slow store operation %s %s took %v", op, key, elapsed)
+	}
+}
+
+// timedServerKey is how a server is shown in timings.
+func timedServerKey(serviceID string, key *types.RealServer_Key) string {
+	return serviceID + "/" + key.PrettyString()
+}
+
+func (s *timedStore) GetService(ctx context.Context, serviceID string) (*types.VirtualService, error) {
+	defer s.record(ctx, "GetService", serviceID, time.Now())
+	return s.Store.GetService(ctx, serviceID)
+}
+
+func (s *timedStore) PutService(ctx context.Context, service *types.VirtualService) error {
+	defer s.record(ctx, "PutService", service.GetId(), time.Now())
+	return s.Store.PutService(ctx, service)
+}
+
+func (s *timedStore) DeleteService(ctx context.Context, serviceID string) error {
+	defer s.record(ctx, "DeleteService", serviceID, time.Now())
+	return s.Store.DeleteService(ctx, serviceID)
+}
+
+func (s *timedStore) GetServer(ctx context.Context, serviceID string,
+	key *types.RealServer_Key) (*types.RealServer, error) {
+	defer s.record(ctx, "GetServer", timedServerKey(serviceID, key), time.Now())
+	return s.Store.GetServer(ctx, serviceID, key)
+}
+
+func (s *timedStore) PutServer(ctx context.Context, server *types.RealServer) error {
+	defer s.record(ctx, "PutServer", timedServerKey(server.GetServiceID(), server.GetKey()), time.Now())
+	return s.Store.PutServer(ctx, server)
+}
+
+func (s *timedStore) DeleteServer(ctx context.Context, serviceID string, key *types.RealServer_Key) error {
+	defer s.record(ctx, "DeleteServer", timedServerKey(serviceID, key), time.Now())
+	return s.Store.DeleteServer(ctx, serviceID, key)
+}
+
+func (s *timedStore) ListServices(ctx context.Context) ([]*types.VirtualService, error) {
+	defer s.record(ctx, "ListServices", "", time.Now())
+	return s.Store.ListServices(ctx)
+}
+
+func (s *timedStore) ListServers(ctx context.Context, serviceID string) ([]*types.RealServer, error) {
+	defer s.record(ctx, "ListServers", serviceID, time.Now())
+	return s.Store.ListServers(ctx, serviceID)
+}
+
+func (s *timedStore) ListAllServers(ctx context.Context) ([]*types.RealServer, error) {
+	defer s.record(ctx, "ListAllServers", "", time.Now())
+	return s.Store.ListAllServers(ctx)
+}
+
+func (s *timedStore) Apply(ctx context.Context, changes []*types.Change) error {
+	defer s.record(ctx, "Apply", fmt.Sprintf("(%d changes)", len(changes)), time.Now())
+	return s.Store.Apply(ctx, changes)
+}
+
+func (s *timedStore) PutNode(ctx context.Context, node *types.Node, ttl time.Duration) error {
+	defer s.record(ctx, "PutNode", node.GetName(), time.Now())
+	return s.Store.PutNode(ctx, node, ttl)
+}
+
+func (s *timedStore) ListNodes(ctx context.Context) ([]*types.Node, error) {
+	defer s.record(ctx, "ListNodes", "", time.Now())
+	return s.Store.ListNodes(ctx)
+}
+
+func (s *timedStore) SetMaintenance(ctx context.Context, node string, enabled bool) error {
+	defer s.record(ctx, "SetMaintenance", node, time.Now())
+	return s.Store.SetMaintenance(ctx, node, enabled)
+}
+
+func (s *timedStore) GetMaintenance(ctx context.Context, node string) (bool, error) {
+	defer s.record(ctx, "GetMaintenance", node, time.Now())
+	return s.Store.GetMaintenance(ctx, node)
+}
+
+func (s *timedStore) AddHistory(ctx context.Context, entries []*types.HistoryEntry, ttl time.Duration) error {
+	defer s.record(ctx, "AddHistory", fmt.Sprintf("(%d entries)", len(entries)), time.Now())
+	return s.Store.AddHistory(ctx, entries, ttl)
+}
+
+func (s *timedStore) ListHistory(ctx context.Context, serviceID string) ([]*types.HistoryEntry, error) {
+	defer s.record(ctx, "ListHistory", serviceID, time.Now())
+	return s.Store.ListHistory(ctx, serviceID)
+}
+
+func (s *timedStore) AddAuditEvent(ctx context.Context, event *types.AuditEvent, ttl time.Duration) error {
+	defer s.record(ctx, "AddAuditEvent", event.GetMethod(), time.Now())
+	return s.Store.AddAuditEvent(ctx, event, ttl)
+}
+
+func (s *timedStore) ListAuditEvents(ctx context.Context) ([]*types.AuditEvent, error) {
+	defer s.record(ctx, "ListAuditEvents", "", time.Now())
+	return s.Store.ListAuditEvents(ctx)
+}
+
+func (s *timedStore) PutPool(ctx context.Context, pool *types.VIPPool) error {
+	defer s.record(ctx, "PutPool", pool.GetName(), time.Now())
+	return s.Store.PutPool(ctx, pool)
+}
+
+func (s *timedStore) DeletePool(ctx context.Context, name string) error {
+	defer s.record(ctx, "DeletePool", name, time.Now())
+	return s.Store.DeletePool(ctx, name)
+}
+
+func (s *timedStore) ListPools(ctx context.Context) ([]*types.VIPPool, error) {
+	defer s.record(ctx, "ListPools", "", time.Now())
+	return s.Store.ListPools(ctx)
+}
+
+func (s *timedStore) GetCandidate(ctx context.Context) (*types.Candidate, error) {
+	defer s.record(ctx, "GetCandidate", "", time.Now())
+	return s.Store.GetCandidate(ctx)
+}
+
+func (s *timedStore) PutCandidate(ctx context.Context, c *types.Candidate) error {
+	defer s.record(ctx, "PutCandidate", "", time.Now())
+	return s.Store.PutCandidate(ctx, c)
+}
+
+func (s *timedStore) CampaignLeader(ctx context.Context, election, candidate string,
+	ttl time.Duration) (string, error) {
+	defer s.record(ctx, "CampaignLeader", election, time.Now())
+	return s.Store.CampaignLeader(ctx, election, candidate, ttl)
+}
+
+func (s *timedStore) ResignLeader(ctx context.Context, election, candidate string) error {
+	defer s.record(ctx, "ResignLeader", election, time.Now())
+	return s.Store.ResignLeader(ctx, election, candidate)
+}
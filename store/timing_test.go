@@ -0,0 +1,40 @@
+package store
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sky-uk/merlin/types"
+)
+
+var _ = Describe("Timed", func() {
+	var (
+		svc    = &types.VirtualService{Id: "svc"}
+		server = &types.RealServer{ServiceID: "svc", Key: &types.RealServer_Key{Ip: "10.0.0.1", Port: 80}}
+	)
+
+	It("should record the operations made with a timing context", func() {
+		st := Timed(NewMemory(), 0)
+		timing := &Timing{}
+		ctx := WithTiming(context.Background(), timing)
+
+		Expect(st.PutService(ctx, svc)).To(Succeed())
+		Expect(st.PutServer(ctx, server)).To(Succeed())
+		_, err := st.ListServices(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = st.ListServices(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+
+		var ops []string
+		for _, op := range timing.Ops() {
+			ops = append(ops, op.Op+" "+op.Key)
+		}
+		Expect(ops).To(Equal([]string{"PutService svc", "PutServer svc/10.0.0.1:80", "ListServices "}))
+		Expect(timing.String()).To(HavePrefix("3 store ops in "))
+	})
+
+	It("should summarise no operations", func() {
+		Expect((&Timing{}).String()).To(Equal("0 store ops"))
+	})
+})
@@ -0,0 +1,21 @@
+package types
+
+import (
+	"context"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// CandidateHeader is the request metadata which stages a write in the candidate config, instead of making it.
+const CandidateHeader = "x-merlin-candidate"
+
+// WithCandidate returns a client context whose writes are staged in the candidate config.
+func WithCandidate(ctx context.Context) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, CandidateHeader, "true")
+}
+
+// IsCandidate returns true if a request to the server should be staged in the candidate config.
+func IsCandidate(ctx context.Context) bool {
+	md, _ := metadata.FromIncomingContext(ctx)
+	return len(md.Get(CandidateHeader)) > 0
+}
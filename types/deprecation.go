@@ -0,0 +1,49 @@
+package types
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// WarningHeader is the response metadata merlin sends warnings in, such as the use of a deprecated field or RPC.
+const WarningHeader = "x-merlin-warning"
+
+// Deprecation of an RPC, or of a field of its requests.
+type Deprecation struct {
+	// Method is the full name of the RPC, such as /types.Merlin/CreateService.
+	Method string
+	// Used returns true if the request uses the deprecated field. If nil, the whole RPC is deprecated.
+	Used func(req interface{}) bool
+	// Message warns clients of the deprecation, and what to use instead.
+	Message string
+}
+
+// Deprecations of the API, which merlin warns clients of when they are used. They are removed in the next major
+// APIVersion.
+var Deprecations []Deprecation
+
+// DeprecationWarnings returns the messages of the deprecations used by a request to method.
+func DeprecationWarnings(method string, req interface{}) []string {
+	var warnings []string
+	for _, d := range Deprecations {
+		if d.Method == method && (d.Used == nil || d.Used(req)) {
+			warnings = append(warnings, d.Message)
+		}
+	}
+	return warnings
+}
+
+// WarningInterceptor returns a client interceptor which passes each warning merlin responds with to warn.
+func WarningInterceptor(warn func(string)) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		var header metadata.MD
+		err := invoker(ctx, method, req, reply, cc, append(opts, grpc.Header(&header))...)
+		for _, warning := range header.Get(WarningHeader) {
+			warn(warning)
+		}
+		return err
+	}
+}
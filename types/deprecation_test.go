@@ -0,0 +1,64 @@
+package types
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+var _ = Describe("Deprecation", func() {
+	var original []Deprecation
+
+	BeforeEach(func() {
+		original = Deprecations
+		Deprecations = []Deprecation{
+			{Method: "/types.Merlin/Info", Message: "Info is deprecated"},
+			{
+				Method: "/types.Merlin/CreateService",
+				Used: func(req interface{}) bool {
+					return req.(*VirtualService).GetConfig().GetScheduler() == "old"
+				},
+				Message: "the old scheduler is deprecated",
+			},
+		}
+	})
+
+	AfterEach(func() {
+		Deprecations = original
+	})
+
+	It("should warn of deprecated RPCs", func() {
+		Expect(DeprecationWarnings("/types.Merlin/Info", nil)).To(Equal([]string{"Info is deprecated"}))
+	})
+
+	It("should only warn of deprecated fields when they are used", func() {
+		used := &VirtualService{Config: &VirtualService_Config{Scheduler: "old"}}
+		unused := &VirtualService{Config: &VirtualService_Config{Scheduler: "rr"}}
+
+		Expect(DeprecationWarnings("/types.Merlin/CreateService", used)).To(
+			Equal([]string{"the old scheduler is deprecated"}))
+		Expect(DeprecationWarnings("/types.Merlin/CreateService", unused)).To(BeEmpty())
+		Expect(DeprecationWarnings("/types.Merlin/List", unused)).To(BeEmpty())
+	})
+
+	It("should pass the warnings in the response to the client", func() {
+		var warnings []string
+		intercept := WarningInterceptor(func(warning string) { warnings = append(warnings, warning) })
+		invoker := func(_ context.Context, _ string, _, _ interface{}, _ *grpc.ClientConn,
+			opts ...grpc.CallOption) error {
+			for _, opt := range opts {
+				if h, ok := opt.(grpc.HeaderCallOption); ok {
+					*h.HeaderAddr = metadata.Pairs(WarningHeader, "first", WarningHeader, "second")
+				}
+			}
+			return nil
+		}
+
+		Expect(intercept(context.Background(), "/types.Merlin/Info", nil, nil, nil, invoker)).To(Succeed())
+
+		Expect(warnings).To(Equal([]string{"first", "second"}))
+	})
+})
@@ -0,0 +1,71 @@
+package types
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// DryRunHeader is the request metadata which validates and admits a write, without making it.
+const DryRunHeader = "x-merlin-dry-run"
+
+// DryRunChangesHeader is the response metadata of a dry run, with each change the write would make.
+const DryRunChangesHeader = "x-merlin-dry-run-changes-bin"
+
+// WithDryRun returns a client context whose writes are dry runs.
+func WithDryRun(ctx context.Context) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, DryRunHeader, "true")
+}
+
+// IsDryRun returns true if a request to the server is a dry run.
+func IsDryRun(ctx context.Context) bool {
+	md, _ := metadata.FromIncomingContext(ctx)
+	return len(md.Get(DryRunHeader)) > 0
+}
+
+// DryRunMetadata returns the response metadata of a dry run which would make changes.
+func DryRunMetadata(changes []*Change) metadata.MD {
+	md := metadata.MD{}
+	for _, change := range changes {
+		b, _ := proto.Marshal(change)
+		md.Append(DryRunChangesHeader, string(b))
+	}
+	return md
+}
+
+// DryRunChanges returns the changes in the response metadata of a dry run.
+func DryRunChanges(md metadata.MD) ([]*Change, error) {
+	var changes []*Change
+	for _, value := range md.Get(DryRunChangesHeader) {
+		change := &Change{}
+		if err := proto.Unmarshal([]byte(value), change); err != nil {
+			return nil, fmt.Errorf("invalid dry run change: %v", err)
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// DryRunInterceptor calls show with the changes each dry run of the client would make.
+func DryRunInterceptor(show func([]*Change)) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		if len(md.Get(DryRunHeader)) == 0 {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+		var header metadata.MD
+		if err := invoker(ctx, method, req, reply, cc, append(opts, grpc.Header(&header))...); err != nil {
+			return err
+		}
+		changes, err := DryRunChanges(header)
+		if err != nil {
+			return err
+		}
+		show(changes)
+		return nil
+	}
+}
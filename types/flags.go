@@ -0,0 +1,56 @@
+package types
+
+import "sort"
+
+// schedulerFlags are passed by IPVS to every scheduler, which interprets them as it likes.
+var schedulerFlags = []string{"flag-1", "flag-2", "flag-3"}
+
+// namedSchedulerFlags are the names ipvsadm gives the scheduler flags of the schedulers which use them.
+var namedSchedulerFlags = map[string]map[string]string{
+	"sh": {"sh-fallback": "flag-1", "sh-port": "flag-2"},
+	"mh": {"mh-fallback": "flag-1", "mh-port": "flag-2"},
+}
+
+// SchedulerFlag returns the IPVS scheduler flag, flag-1 to flag-3, which flag sets with the scheduler. Flags can
+// also be given by their ipvsadm names with the schedulers which use them, such as sh-port with sh. It returns
+// false if the scheduler has no such flag.
+func SchedulerFlag(scheduler, flag string) (string, bool) {
+	for _, f := range schedulerFlags {
+		if f == flag {
+			return flag, true
+		}
+	}
+	f, ok := namedSchedulerFlags[scheduler][flag]
+	return f, ok
+}
+
+// SchedulerFlags returns the flags the scheduler accepts, sorted.
+func SchedulerFlags(scheduler string) []string {
+	flags := append([]string(nil), schedulerFlags...)
+	for name := range namedSchedulerFlags[scheduler] {
+		flags = append(flags, name)
+	}
+	sort.Strings(flags)
+	return flags
+}
+
+// NormalizeFlags of the virtual service in place, naming them flag-1 to flag-3 as IPVS does, without duplicates
+// and in order, so they compare against local state. Unknown flags are kept as they are.
+func (s *VirtualService) NormalizeFlags() {
+	if s.Config == nil || len(s.Config.Flags) == 0 {
+		return
+	}
+	seen := make(map[string]bool)
+	var flags []string
+	for _, flag := range s.Config.Flags {
+		if f, ok := SchedulerFlag(s.Config.Scheduler, flag); ok {
+			flag = f
+		}
+		if !seen[flag] {
+			seen[flag] = true
+			flags = append(flags, flag)
+		}
+	}
+	sort.Strings(flags)
+	s.Config.Flags = flags
+}
@@ -0,0 +1,40 @@
+package types
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Scheduler flags", func() {
+	DescribeTable("maps flags to the IPVS scheduler flag they set", func(scheduler, flag, expected string, ok bool) {
+		f, found := SchedulerFlag(scheduler, flag)
+
+		Expect(found).To(Equal(ok))
+		Expect(f).To(Equal(expected))
+	},
+		Entry("numbered flag", "wrr", "flag-3", "flag-3", true),
+		Entry("sh-fallback", "sh", "sh-fallback", "flag-1", true),
+		Entry("sh-port", "sh", "sh-port", "flag-2", true),
+		Entry("mh-port", "mh", "mh-port", "flag-2", true),
+		Entry("flag of another scheduler", "mh", "sh-port", "", false),
+		Entry("named flag of a scheduler without any", "wrr", "sh-port", "", false),
+		Entry("unknown flag", "sh", "persistent", "", false),
+	)
+
+	It("lists the flags of a scheduler", func() {
+		Expect(SchedulerFlags("sh")).To(Equal([]string{"flag-1", "flag-2", "flag-3", "sh-fallback", "sh-port"}))
+		Expect(SchedulerFlags("rr")).To(Equal([]string{"flag-1", "flag-2", "flag-3"}))
+	})
+
+	It("normalizes flags to the numbered flags IPVS reports", func() {
+		svc := &VirtualService{Config: &VirtualService_Config{
+			Scheduler: "sh",
+			Flags:     []string{"sh-port", "flag-3", "flag-2", "unknown", "sh-fallback"},
+		}}
+
+		svc.NormalizeFlags()
+
+		Expect(svc.Config.Flags).To(Equal([]string{"flag-1", "flag-2", "flag-3", "unknown"}))
+	})
+})
@@ -0,0 +1,21 @@
+package types
+
+import (
+	"encoding/base64"
+	"errors"
+)
+
+// PageToken returns the token which continues a list of services after the service with the given ID. Tokens are
+// opaque to clients, so the encoding can change.
+func PageToken(lastID string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(lastID))
+}
+
+// ParsePageToken returns the ID of the last service listed before the page token, or "" if it's empty.
+func ParsePageToken(token string) (string, error) {
+	id, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || (token != "" && len(id) == 0) {
+		return "", errors.New("invalid page token")
+	}
+	return string(id), nil
+}
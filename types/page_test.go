@@ -0,0 +1,22 @@
+package types
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PageToken", func() {
+	It("should round trip the last ID", func() {
+		Expect(ParsePageToken(PageToken("web/prod"))).To(Equal("web/prod"))
+	})
+
+	It("should refuse an invalid token", func() {
+		_, err := ParsePageToken("not a token!")
+
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should parse an empty token as the start of the list", func() {
+		Expect(ParsePageToken("")).To(Equal(""))
+	})
+})
@@ -0,0 +1,172 @@
+package types
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Label keys and values are restricted so selectors can be parsed unambiguously.
+var (
+	labelKeyRegex   = regexp.MustCompile(`^[A-Za-z0-9]([-A-Za-z0-9_./]*[A-Za-z0-9])?$`)
+	labelValueRegex = regexp.MustCompile(`^([A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?)?$`)
+)
+
+// ValidateLabels returns an error if a label key or value contains unsupported characters.
+func ValidateLabels(labels map[string]string) error {
+	for k, v := range labels {
+		if !labelKeyRegex.MatchString(k) {
+			return fmt.Errorf("invalid label key %q", k)
+		}
+		if !labelValueRegex.MatchString(v) {
+			return fmt.Errorf("invalid value %q for label %s", v, k)
+		}
+	}
+	return nil
+}
+
+// PrettyLabels formats labels as a sorted, comma delimited list of key=value.
+func PrettyLabels(labels map[string]string) string {
+	var pairs []string
+	for k, v := range labels {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+type requirement struct {
+	key    string
+	value  string
+	negate bool
+	exists bool
+}
+
+// Selector matches labels against a set of requirements, all of which must be met.
+type Selector []requirement
+
+// ParseSelector parses a label selector, a comma delimited list of requirements, each of the form
+// key=value, key==value, key!=value, key (key exists) or !key (key doesn't exist).
+// An empty selector matches everything.
+func ParseSelector(s string) (Selector, error) {
+	return parseRequirements(s, func(r *requirement) error {
+		if !labelKeyRegex.MatchString(r.key) {
+			return fmt.Errorf("invalid label key %q in selector", r.key)
+		}
+		if !labelValueRegex.MatchString(r.value) {
+			return fmt.Errorf("invalid label value %q in selector", r.value)
+		}
+		return nil
+	})
+}
+
+// ServiceFields are the fields of a virtual service which can be used in a field selector.
+var ServiceFields = []string{"id", "ip", "port", "protocol", "scheduler"}
+
+// ParseFieldSelector parses a selector of virtual service fields, with the same syntax as ParseSelector.
+// The fields are listed in ServiceFields, and match against the values returned by FieldsOf.
+func ParseFieldSelector(s string) (Selector, error) {
+	return parseRequirements(s, func(r *requirement) error {
+		valid := false
+		for _, f := range ServiceFields {
+			valid = valid || r.key == f
+		}
+		if !valid {
+			return fmt.Errorf("unknown field %q in selector, must be one of %s", r.key,
+				strings.Join(ServiceFields, ","))
+		}
+		if r.exists {
+			return fmt.Errorf("field %s requires a value in selector", r.key)
+		}
+		if r.key == "protocol" {
+			r.value = strings.ToLower(r.value)
+		}
+		return nil
+	})
+}
+
+// FieldsOf returns the selectable fields of the virtual service.
+func FieldsOf(s *VirtualService) map[string]string {
+	return map[string]string{
+		"id":        s.Id,
+		"ip":        s.GetKey().GetIp(),
+		"port":      fmt.Sprint(s.GetKey().GetPort()),
+		"protocol":  strings.ToLower(s.GetKey().GetProtocol().String()),
+		"scheduler": s.GetConfig().GetScheduler(),
+	}
+}
+
+func parseRequirements(s string, validate func(*requirement) error) (Selector, error) {
+	var sel Selector
+	for _, term := range strings.Split(s, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+
+		var r requirement
+		switch {
+		case strings.Contains(term, "!="):
+			parts := strings.SplitN(term, "!=", 2)
+			r = requirement{key: parts[0], value: parts[1], negate: true}
+		case strings.Contains(term, "=="):
+			parts := strings.SplitN(term, "==", 2)
+			r = requirement{key: parts[0], value: parts[1]}
+		case strings.Contains(term, "="):
+			parts := strings.SplitN(term, "=", 2)
+			r = requirement{key: parts[0], value: parts[1]}
+		case strings.HasPrefix(term, "!"):
+			r = requirement{key: term[1:], exists: true, negate: true}
+		default:
+			r = requirement{key: term, exists: true}
+		}
+
+		r.key = strings.TrimSpace(r.key)
+		r.value = strings.TrimSpace(r.value)
+		if err := validate(&r); err != nil {
+			return nil, err
+		}
+		sel = append(sel, r)
+	}
+	return sel, nil
+}
+
+// Matches returns true if the labels meet every requirement of the selector.
+func (s Selector) Matches(labels map[string]string) bool {
+	for _, r := range s {
+		v, ok := labels[r.key]
+		var match bool
+		if r.exists {
+			match = ok
+		} else {
+			match = ok && v == r.value
+		}
+		if match == r.negate {
+			return false
+		}
+	}
+	return true
+}
+
+// Empty returns true if the selector has no requirements, and so matches everything.
+func (s Selector) Empty() bool {
+	return len(s) == 0
+}
+
+func (s Selector) String() string {
+	var terms []string
+	for _, r := range s {
+		switch {
+		case r.exists && r.negate:
+			terms = append(terms, "!"+r.key)
+		case r.exists:
+			terms = append(terms, r.key)
+		case r.negate:
+			terms = append(terms, r.key+"!="+r.value)
+		default:
+			terms = append(terms, r.key+"="+r.value)
+		}
+	}
+	return strings.Join(terms, ",")
+}
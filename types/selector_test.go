@@ -0,0 +1,85 @@
+package types
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Selector", func() {
+	labels := map[string]string{"team": "payments", "env": "prod"}
+
+	DescribeTable("matches labels", func(selector string, matches bool) {
+		sel, err := ParseSelector(selector)
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(sel.Matches(labels)).To(Equal(matches))
+	},
+		Entry("empty", "", true),
+		Entry("equals", "team=payments", true),
+		Entry("double equals", "team==payments", true),
+		Entry("equals other value", "team=search", false),
+		Entry("not equals", "team!=search", true),
+		Entry("not equals same value", "team!=payments", false),
+		Entry("not equals missing key", "owner!=bob", true),
+		Entry("exists", "env", true),
+		Entry("exists missing key", "owner", false),
+		Entry("doesn't exist", "!owner", true),
+		Entry("doesn't exist present key", "!env", false),
+		Entry("multiple requirements", "team=payments, env=prod", true),
+		Entry("multiple requirements one failing", "team=payments,env=dev", false),
+	)
+
+	DescribeTable("rejects invalid selectors", func(selector string) {
+		_, err := ParseSelector(selector)
+
+		Expect(err).To(HaveOccurred())
+	},
+		Entry("empty key", "=payments"),
+		Entry("invalid key", "te am=payments"),
+		Entry("invalid value", "team=pay ments"),
+	)
+
+	It("round trips to a string", func() {
+		sel, err := ParseSelector("team=payments,env!=dev,owner,!legacy")
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(sel.String()).To(Equal("team=payments,env!=dev,owner,!legacy"))
+	})
+
+	It("validates labels", func() {
+		Expect(ValidateLabels(labels)).To(Succeed())
+		Expect(ValidateLabels(map[string]string{"team": "a,b"})).ToNot(Succeed())
+		Expect(ValidateLabels(map[string]string{"": "a"})).ToNot(Succeed())
+	})
+
+	Describe("field selector", func() {
+		svc := &VirtualService{
+			Id:     "service1",
+			Key:    &VirtualService_Key{Ip: "10.1.1.1", Port: 80, Protocol: Protocol_TCP},
+			Config: &VirtualService_Config{Scheduler: "wrr"},
+		}
+
+		DescribeTable("matches fields", func(selector string, matches bool) {
+			sel, err := ParseFieldSelector(selector)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sel.Matches(FieldsOf(svc))).To(Equal(matches))
+		},
+			Entry("ip", "ip=10.1.1.1", true),
+			Entry("port", "port=80", true),
+			Entry("protocol in any case", "protocol=TCP", true),
+			Entry("scheduler", "scheduler!=wrr", false),
+			Entry("multiple fields", "ip=10.1.1.1,port=81", false),
+		)
+
+		DescribeTable("rejects invalid selectors", func(selector string) {
+			_, err := ParseFieldSelector(selector)
+
+			Expect(err).To(HaveOccurred())
+		},
+			Entry("unknown field", "weight=1"),
+			Entry("missing value", "ip"),
+		)
+	})
+})
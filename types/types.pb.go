@@ -11,7 +11,9 @@ import (
 	proto "github.com/golang/protobuf/proto"
 	duration "github.com/golang/protobuf/ptypes/duration"
 	empty "github.com/golang/protobuf/ptypes/empty"
+	timestamp "github.com/golang/protobuf/ptypes/timestamp"
 	wrappers "github.com/golang/protobuf/ptypes/wrappers"
+	field_mask "google.golang.org/genproto/protobuf/field_mask"
 	grpc "google.golang.org/grpc"
 	codes "google.golang.org/grpc/codes"
 	status "google.golang.org/grpc/status"
@@ -61,9 +63,16 @@ type ForwardMethod int32
 
 const (
 	ForwardMethod_UNSET_FORWARD_METHOD ForwardMethod = 0
-	ForwardMethod_ROUTE                ForwardMethod = 1
-	ForwardMethod_TUNNEL               ForwardMethod = 2
-	ForwardMethod_MASQ                 ForwardMethod = 3
+	// ROUTE sends packets to the server unchanged (direct routing), so it must have the port of its service.
+	ForwardMethod_ROUTE ForwardMethod = 1
+	// TUNNEL encapsulates packets in IPIP, or GUE with the tunnel type of the server, so the server must have the
+	// port of its service.
+	ForwardMethod_TUNNEL ForwardMethod = 2
+	// MASQ rewrites the destination of packets to the server (NAT), which is the only forward method where the
+	// server can have a different port to its service. Replies must be routed back through the director.
+	ForwardMethod_MASQ ForwardMethod = 3
+	// LOCALNODE delivers packets to a process on the director, so the server can have the address of its service.
+	ForwardMethod_LOCALNODE ForwardMethod = 4
 )
 
 var ForwardMethod_name = map[int32]string{
@@ -71,6 +80,7 @@ var ForwardMethod_name = map[int32]string{
 	1: "ROUTE",
 	2: "TUNNEL",
 	3: "MASQ",
+	4: "LOCALNODE",
 }
 
 var ForwardMethod_value = map[string]int32{
@@ -78,6 +88,7 @@ var ForwardMethod_value = map[string]int32{
 	"ROUTE":                1,
 	"TUNNEL":               2,
 	"MASQ":                 3,
+	"LOCALNODE":            4,
 }
 
 func (x ForwardMethod) String() string {
@@ -88,16 +99,211 @@ func (ForwardMethod) EnumDescriptor() ([]byte, []int) {
 	return fileDescriptor_2c0f90c600ad7e2e, []int{1}
 }
 
+// TunnelType encapsulates packets to TUNNEL servers. IPIP is the zero value, as it's what IPVS does without one.
+type TunnelType int32
+
+const (
+	TunnelType_IPIP TunnelType = 0
+	// GUE (generic UDP encapsulation) sends packets in UDP to the tunnel port of the server, so they can cross L3
+	// boundaries which drop IPIP, and be spread over receive queues by their source port. It needs kernel 5.2 or
+	// later.
+	TunnelType_GUE TunnelType = 1
+)
+
+var TunnelType_name = map[int32]string{
+	0: "IPIP",
+	1: "GUE",
+}
+
+var TunnelType_value = map[string]int32{
+	"IPIP": 0,
+	"GUE":  1,
+}
+
+func (x TunnelType) String() string {
+	return proto.EnumName(TunnelType_name, int32(x))
+}
+
+func (TunnelType) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_2c0f90c600ad7e2e, []int{2}
+}
+
+// TunnelChecksum of the UDP header of GUE packets.
+type TunnelChecksum int32
+
+const (
+	TunnelChecksum_NO_CHECKSUM TunnelChecksum = 0
+	// CHECKSUM sets the UDP checksum of each packet.
+	TunnelChecksum_CHECKSUM TunnelChecksum = 1
+	// REMOTE_CHECKSUM offloads the checksum of the inner packet to the server (remote checksum offload).
+	TunnelChecksum_REMOTE_CHECKSUM TunnelChecksum = 2
+)
+
+var TunnelChecksum_name = map[int32]string{
+	0: "NO_CHECKSUM",
+	1: "CHECKSUM",
+	2: "REMOTE_CHECKSUM",
+}
+
+var TunnelChecksum_value = map[string]int32{
+	"NO_CHECKSUM":     0,
+	"CHECKSUM":        1,
+	"REMOTE_CHECKSUM": 2,
+}
+
+func (x TunnelChecksum) String() string {
+	return proto.EnumName(TunnelChecksum_name, int32(x))
+}
+
+func (TunnelChecksum) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_2c0f90c600ad7e2e, []int{3}
+}
+
+// Health of a real server, according to its health check.
+type Health int32
+
+const (
+	Health_UNSET_HEALTH Health = 0
+	// UNCHECKED servers have no health check.
+	Health_UNCHECKED Health = 1
+	Health_UP        Health = 2
+	Health_DOWN      Health = 3
+)
+
+var Health_name = map[int32]string{
+	0: "UNSET_HEALTH",
+	1: "UNCHECKED",
+	2: "UP",
+	3: "DOWN",
+}
+
+var Health_value = map[string]int32{
+	"UNSET_HEALTH": 0,
+	"UNCHECKED":    1,
+	"UP":           2,
+	"DOWN":         3,
+}
+
+func (x Health) String() string {
+	return proto.EnumName(Health_name, int32(x))
+}
+
+func (Health) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_2c0f90c600ad7e2e, []int{4}
+}
+
+type Rollout_State int32
+
+const (
+	Rollout_UNSET_ROLLOUT_STATE Rollout_State = 0
+	// CANARY rollouts are reconciled by the canary nodes.
+	Rollout_CANARY Rollout_State = 1
+	// HALTED rollouts failed on a canary, so the canaries reverted to the config of the service.
+	Rollout_HALTED Rollout_State = 2
+)
+
+var Rollout_State_name = map[int32]string{
+	0: "UNSET_ROLLOUT_STATE",
+	1: "CANARY",
+	2: "HALTED",
+}
+
+var Rollout_State_value = map[string]int32{
+	"UNSET_ROLLOUT_STATE": 0,
+	"CANARY":              1,
+	"HALTED":              2,
+}
+
+func (x Rollout_State) String() string {
+	return proto.EnumName(Rollout_State_name, int32(x))
+}
+
+func (Rollout_State) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_2c0f90c600ad7e2e, []int{1, 0}
+}
+
+type Change_Action int32
+
+const (
+	Change_UNSET_ACTION Change_Action = 0
+	Change_CREATE       Change_Action = 1
+	Change_UPDATE       Change_Action = 2
+	Change_DELETE       Change_Action = 3
+)
+
+var Change_Action_name = map[int32]string{
+	0: "UNSET_ACTION",
+	1: "CREATE",
+	2: "UPDATE",
+	3: "DELETE",
+}
+
+var Change_Action_value = map[string]int32{
+	"UNSET_ACTION": 0,
+	"CREATE":       1,
+	"UPDATE":       2,
+	"DELETE":       3,
+}
+
+func (x Change_Action) String() string {
+	return proto.EnumName(Change_Action_name, int32(x))
+}
+
+func (Change_Action) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_2c0f90c600ad7e2e, []int{18, 0}
+}
+
+type SyncDaemon_Role int32
+
+const (
+	SyncDaemon_UNSET_SYNC_ROLE SyncDaemon_Role = 0
+	SyncDaemon_MASTER          SyncDaemon_Role = 1
+	SyncDaemon_BACKUP          SyncDaemon_Role = 2
+)
+
+var SyncDaemon_Role_name = map[int32]string{
+	0: "UNSET_SYNC_ROLE",
+	1: "MASTER",
+	2: "BACKUP",
+}
+
+var SyncDaemon_Role_value = map[string]int32{
+	"UNSET_SYNC_ROLE": 0,
+	"MASTER":          1,
+	"BACKUP":          2,
+}
+
+func (x SyncDaemon_Role) String() string {
+	return proto.EnumName(SyncDaemon_Role_name, int32(x))
+}
+
+func (SyncDaemon_Role) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_2c0f90c600ad7e2e, []int{43, 0}
+}
+
 type VirtualService struct {
 	// ID is a unique identifier of this virtual service to associate it with real servers.
 	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
 	// Key is the identifying part in IPVS.
 	Key *VirtualService_Key `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
 	// Config is the configurable part in IPVS.
-	Config               *VirtualService_Config `protobuf:"bytes,3,opt,name=config,proto3" json:"config,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}               `json:"-"`
-	XXX_unrecognized     []byte                 `json:"-"`
-	XXX_sizecache        int32                  `json:"-"`
+	Config *VirtualService_Config `protobuf:"bytes,3,opt,name=config,proto3" json:"config,omitempty"`
+	// Labels are arbitrary key/values used to select services, they don't affect IPVS.
+	Labels map[string]string `protobuf:"bytes,4,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	// NodeSelector restricts the nodes the service is reconciled onto to those with matching labels,
+	// e.g. "pool=edge". Empty selects every node.
+	NodeSelector string `protobuf:"bytes,5,opt,name=node_selector,json=nodeSelector,proto3" json:"node_selector,omitempty"`
+	// Rollout is a staged change of the config, unset if there isn't one.
+	Rollout *Rollout `protobuf:"bytes,6,opt,name=rollout,proto3" json:"rollout,omitempty"`
+	// Pool of VIPs the service's IP is in. If the IP is empty when the service is created, a free IP of the pool is
+	// allocated to it, and it's released when the service is deleted.
+	Pool string `protobuf:"bytes,7,opt,name=pool,proto3" json:"pool,omitempty"`
+	// UpdateMask of an UpdateService request lists the fields to set, such as config.scheduler or labels, which are
+	// set even if they're empty. Without it, the update is merged into the service. It's never stored.
+	UpdateMask           *field_mask.FieldMask `protobuf:"bytes,8,opt,name=update_mask,json=updateMask,proto3" json:"update_mask,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}              `json:"-"`
+	XXX_unrecognized     []byte                `json:"-"`
+	XXX_sizecache        int32                 `json:"-"`
 }
 
 func (m *VirtualService) Reset()         { *m = VirtualService{} }
@@ -146,6 +352,41 @@ func (m *VirtualService) GetConfig() *VirtualService_Config {
 	return nil
 }
 
+func (m *VirtualService) GetLabels() map[string]string {
+	if m != nil {
+		return m.Labels
+	}
+	return nil
+}
+
+func (m *VirtualService) GetNodeSelector() string {
+	if m != nil {
+		return m.NodeSelector
+	}
+	return ""
+}
+
+func (m *VirtualService) GetRollout() *Rollout {
+	if m != nil {
+		return m.Rollout
+	}
+	return nil
+}
+
+func (m *VirtualService) GetPool() string {
+	if m != nil {
+		return m.Pool
+	}
+	return ""
+}
+
+func (m *VirtualService) GetUpdateMask() *field_mask.FieldMask {
+	if m != nil {
+		return m.UpdateMask
+	}
+	return nil
+}
+
 type VirtualService_Key struct {
 	Ip                   string   `protobuf:"bytes,1,opt,name=ip,proto3" json:"ip,omitempty"`
 	Port                 uint32   `protobuf:"varint,2,opt,name=port,proto3" json:"port,omitempty"`
@@ -202,8 +443,11 @@ func (m *VirtualService_Key) GetProtocol() Protocol {
 }
 
 type VirtualService_Config struct {
-	Scheduler            string   `protobuf:"bytes,1,opt,name=scheduler,proto3" json:"scheduler,omitempty"`
-	Flags                []string `protobuf:"bytes,2,rep,name=flags,proto3" json:"flags,omitempty"`
+	Scheduler string   `protobuf:"bytes,1,opt,name=scheduler,proto3" json:"scheduler,omitempty"`
+	Flags     []string `protobuf:"bytes,2,rep,name=flags,proto3" json:"flags,omitempty"`
+	// Ops enables one-packet scheduling, which schedules each packet separately instead of keeping a connection
+	// for it, for UDP workloads like DNS and syslog with high packet rates. Only UDP services can have it.
+	Ops                  bool     `protobuf:"varint,3,opt,name=ops,proto3" json:"ops,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -248,6 +492,114 @@ func (m *VirtualService_Config) GetFlags() []string {
 	return nil
 }
 
+func (m *VirtualService_Config) GetOps() bool {
+	if m != nil {
+		return m.Ops
+	}
+	return false
+}
+
+// Rollout of a changed config to a service, which its canary nodes reconcile first. Once every canary has converged
+// and stayed healthy for the bake time, the config is promoted to the service on every node.
+type Rollout struct {
+	Config      *VirtualService_Config `protobuf:"bytes,1,opt,name=config,proto3" json:"config,omitempty"`
+	CanaryNodes []string               `protobuf:"bytes,2,rep,name=canary_nodes,json=canaryNodes,proto3" json:"canary_nodes,omitempty"`
+	State       Rollout_State          `protobuf:"varint,3,opt,name=state,proto3,enum=types.Rollout_State" json:"state,omitempty"`
+	Started     *timestamp.Timestamp   `protobuf:"bytes,4,opt,name=started,proto3" json:"started,omitempty"`
+	// Converged is when every canary reconciled the config, unset until they have.
+	Converged *timestamp.Timestamp `protobuf:"bytes,5,opt,name=converged,proto3" json:"converged,omitempty"`
+	// Bake is how long the canaries must stay healthy after converging, before promoting the config.
+	Bake *duration.Duration `protobuf:"bytes,6,opt,name=bake,proto3" json:"bake,omitempty"`
+	// Timeout is how long the canaries have to converge before the rollout halts.
+	Timeout *duration.Duration `protobuf:"bytes,7,opt,name=timeout,proto3" json:"timeout,omitempty"`
+	// Message is why the rollout halted.
+	Message              string   `protobuf:"bytes,8,opt,name=message,proto3" json:"message,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Rollout) Reset()         { *m = Rollout{} }
+func (m *Rollout) String() string { return proto.CompactTextString(m) }
+func (*Rollout) ProtoMessage()    {}
+func (*Rollout) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2c0f90c600ad7e2e, []int{1}
+}
+
+func (m *Rollout) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Rollout.Unmarshal(m, b)
+}
+func (m *Rollout) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Rollout.Marshal(b, m, deterministic)
+}
+func (m *Rollout) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Rollout.Merge(m, src)
+}
+func (m *Rollout) XXX_Size() int {
+	return xxx_messageInfo_Rollout.Size(m)
+}
+func (m *Rollout) XXX_DiscardUnknown() {
+	xxx_messageInfo_Rollout.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Rollout proto.InternalMessageInfo
+
+func (m *Rollout) GetConfig() *VirtualService_Config {
+	if m != nil {
+		return m.Config
+	}
+	return nil
+}
+
+func (m *Rollout) GetCanaryNodes() []string {
+	if m != nil {
+		return m.CanaryNodes
+	}
+	return nil
+}
+
+func (m *Rollout) GetState() Rollout_State {
+	if m != nil {
+		return m.State
+	}
+	return Rollout_UNSET_ROLLOUT_STATE
+}
+
+func (m *Rollout) GetStarted() *timestamp.Timestamp {
+	if m != nil {
+		return m.Started
+	}
+	return nil
+}
+
+func (m *Rollout) GetConverged() *timestamp.Timestamp {
+	if m != nil {
+		return m.Converged
+	}
+	return nil
+}
+
+func (m *Rollout) GetBake() *duration.Duration {
+	if m != nil {
+		return m.Bake
+	}
+	return nil
+}
+
+func (m *Rollout) GetTimeout() *duration.Duration {
+	if m != nil {
+		return m.Timeout
+	}
+	return nil
+}
+
+func (m *Rollout) GetMessage() string {
+	if m != nil {
+		return m.Message
+	}
+	return ""
+}
+
 type RealServer struct {
 	// ServiceID is the id of the virtual service to associate this real server with.
 	// Field may be blank if from IPVS.
@@ -257,17 +609,24 @@ type RealServer struct {
 	// Config is the configurable part in IPVS.
 	Config *RealServer_Config `protobuf:"bytes,3,opt,name=config,proto3" json:"config,omitempty"`
 	// HealthCheck is the check done by merlin against the associated real server.
-	HealthCheck          *RealServer_HealthCheck `protobuf:"bytes,4,opt,name=health_check,json=healthCheck,proto3" json:"health_check,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}                `json:"-"`
-	XXX_unrecognized     []byte                  `json:"-"`
-	XXX_sizecache        int32                   `json:"-"`
+	HealthCheck *RealServer_HealthCheck `protobuf:"bytes,4,opt,name=health_check,json=healthCheck,proto3" json:"health_check,omitempty"`
+	// DrainedWeight is the weight of the real server before it was drained. Unset if not drained.
+	DrainedWeight *wrappers.UInt32Value `protobuf:"bytes,5,opt,name=drained_weight,json=drainedWeight,proto3" json:"drained_weight,omitempty"`
+	// UpdateMask of an UpdateServer request lists the fields to set, such as config.weight or health_check.endpoint,
+	// which are set even if they're empty. Without it, the update is merged into the server. It's never stored.
+	UpdateMask *field_mask.FieldMask `protobuf:"bytes,6,opt,name=update_mask,json=updateMask,proto3" json:"update_mask,omitempty"`
+	// Labels are arbitrary key/values used to select servers, they don't affect IPVS.
+	Labels               map[string]string `protobuf:"bytes,7,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
 }
 
 func (m *RealServer) Reset()         { *m = RealServer{} }
 func (m *RealServer) String() string { return proto.CompactTextString(m) }
 func (*RealServer) ProtoMessage()    {}
 func (*RealServer) Descriptor() ([]byte, []int) {
-	return fileDescriptor_2c0f90c600ad7e2e, []int{1}
+	return fileDescriptor_2c0f90c600ad7e2e, []int{2}
 }
 
 func (m *RealServer) XXX_Unmarshal(b []byte) error {
@@ -316,6 +675,27 @@ func (m *RealServer) GetHealthCheck() *RealServer_HealthCheck {
 	return nil
 }
 
+func (m *RealServer) GetDrainedWeight() *wrappers.UInt32Value {
+	if m != nil {
+		return m.DrainedWeight
+	}
+	return nil
+}
+
+func (m *RealServer) GetUpdateMask() *field_mask.FieldMask {
+	if m != nil {
+		return m.UpdateMask
+	}
+	return nil
+}
+
+func (m *RealServer) GetLabels() map[string]string {
+	if m != nil {
+		return m.Labels
+	}
+	return nil
+}
+
 type RealServer_Key struct {
 	Ip                   string   `protobuf:"bytes,1,opt,name=ip,proto3" json:"ip,omitempty"`
 	Port                 uint32   `protobuf:"varint,2,opt,name=port,proto3" json:"port,omitempty"`
@@ -328,7 +708,7 @@ func (m *RealServer_Key) Reset()         { *m = RealServer_Key{} }
 func (m *RealServer_Key) String() string { return proto.CompactTextString(m) }
 func (*RealServer_Key) ProtoMessage()    {}
 func (*RealServer_Key) Descriptor() ([]byte, []int) {
-	return fileDescriptor_2c0f90c600ad7e2e, []int{1, 0}
+	return fileDescriptor_2c0f90c600ad7e2e, []int{2, 0}
 }
 
 func (m *RealServer_Key) XXX_Unmarshal(b []byte) error {
@@ -364,18 +744,24 @@ func (m *RealServer_Key) GetPort() uint32 {
 }
 
 type RealServer_Config struct {
-	Weight               *wrappers.UInt32Value `protobuf:"bytes,1,opt,name=weight,proto3" json:"weight,omitempty"`
-	Forward              ForwardMethod         `protobuf:"varint,2,opt,name=forward,proto3,enum=types.ForwardMethod" json:"forward,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}              `json:"-"`
-	XXX_unrecognized     []byte                `json:"-"`
-	XXX_sizecache        int32                 `json:"-"`
+	Weight  *wrappers.UInt32Value `protobuf:"bytes,1,opt,name=weight,proto3" json:"weight,omitempty"`
+	Forward ForwardMethod         `protobuf:"varint,2,opt,name=forward,proto3,enum=types.ForwardMethod" json:"forward,omitempty"`
+	// TunnelType, TunnelPort and TunnelChecksum encapsulate packets to TUNNEL servers, and must be unset for the
+	// other forward methods. Kernels before 5.2 ignore them and always use IPIP.
+	TunnelType TunnelType `protobuf:"varint,3,opt,name=tunnel_type,json=tunnelType,proto3,enum=types.TunnelType" json:"tunnel_type,omitempty"`
+	// TunnelPort is the UDP port GUE packets are sent to on the server, which GUE requires.
+	TunnelPort           uint32         `protobuf:"varint,4,opt,name=tunnel_port,json=tunnelPort,proto3" json:"tunnel_port,omitempty"`
+	TunnelChecksum       TunnelChecksum `protobuf:"varint,5,opt,name=tunnel_checksum,json=tunnelChecksum,proto3,enum=types.TunnelChecksum" json:"tunnel_checksum,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
 }
 
 func (m *RealServer_Config) Reset()         { *m = RealServer_Config{} }
 func (m *RealServer_Config) String() string { return proto.CompactTextString(m) }
 func (*RealServer_Config) ProtoMessage()    {}
 func (*RealServer_Config) Descriptor() ([]byte, []int) {
-	return fileDescriptor_2c0f90c600ad7e2e, []int{1, 1}
+	return fileDescriptor_2c0f90c600ad7e2e, []int{2, 1}
 }
 
 func (m *RealServer_Config) XXX_Unmarshal(b []byte) error {
@@ -410,24 +796,48 @@ func (m *RealServer_Config) GetForward() ForwardMethod {
 	return ForwardMethod_UNSET_FORWARD_METHOD
 }
 
+func (m *RealServer_Config) GetTunnelType() TunnelType {
+	if m != nil {
+		return m.TunnelType
+	}
+	return TunnelType_IPIP
+}
+
+func (m *RealServer_Config) GetTunnelPort() uint32 {
+	if m != nil {
+		return m.TunnelPort
+	}
+	return 0
+}
+
+func (m *RealServer_Config) GetTunnelChecksum() TunnelChecksum {
+	if m != nil {
+		return m.TunnelChecksum
+	}
+	return TunnelChecksum_NO_CHECKSUM
+}
+
 type RealServer_HealthCheck struct {
 	// Endpoint should be a valid url, expected format is <scheme>://:<port>/<path>, e.g. http://:80/health.
-	// Set to an empty string to disable health check.
-	Endpoint             *wrappers.StringValue `protobuf:"bytes,1,opt,name=endpoint,proto3" json:"endpoint,omitempty"`
-	Period               *duration.Duration    `protobuf:"bytes,2,opt,name=period,proto3" json:"period,omitempty"`
-	Timeout              *duration.Duration    `protobuf:"bytes,3,opt,name=timeout,proto3" json:"timeout,omitempty"`
-	UpThreshold          uint32                `protobuf:"varint,4,opt,name=up_threshold,json=upThreshold,proto3" json:"up_threshold,omitempty"`
-	DownThreshold        uint32                `protobuf:"varint,5,opt,name=down_threshold,json=downThreshold,proto3" json:"down_threshold,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}              `json:"-"`
-	XXX_unrecognized     []byte                `json:"-"`
-	XXX_sizecache        int32                 `json:"-"`
+	// The scheme is http to GET the path, tcp to connect, e.g. tcp://:80, or udp to send an empty datagram,
+	// which fails if the port is unreachable. Set to an empty string to disable health check.
+	Endpoint      *wrappers.StringValue `protobuf:"bytes,1,opt,name=endpoint,proto3" json:"endpoint,omitempty"`
+	Period        *duration.Duration    `protobuf:"bytes,2,opt,name=period,proto3" json:"period,omitempty"`
+	Timeout       *duration.Duration    `protobuf:"bytes,3,opt,name=timeout,proto3" json:"timeout,omitempty"`
+	UpThreshold   uint32                `protobuf:"varint,4,opt,name=up_threshold,json=upThreshold,proto3" json:"up_threshold,omitempty"`
+	DownThreshold uint32                `protobuf:"varint,5,opt,name=down_threshold,json=downThreshold,proto3" json:"down_threshold,omitempty"`
+	// ExpectedStatus of the response to an http health check, defaults to any 2xx status.
+	ExpectedStatus       uint32   `protobuf:"varint,6,opt,name=expected_status,json=expectedStatus,proto3" json:"expected_status,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
 func (m *RealServer_HealthCheck) Reset()         { *m = RealServer_HealthCheck{} }
 func (m *RealServer_HealthCheck) String() string { return proto.CompactTextString(m) }
 func (*RealServer_HealthCheck) ProtoMessage()    {}
 func (*RealServer_HealthCheck) Descriptor() ([]byte, []int) {
-	return fileDescriptor_2c0f90c600ad7e2e, []int{1, 2}
+	return fileDescriptor_2c0f90c600ad7e2e, []int{2, 2}
 }
 
 func (m *RealServer_HealthCheck) XXX_Unmarshal(b []byte) error {
@@ -483,62 +893,322 @@ func (m *RealServer_HealthCheck) GetDownThreshold() uint32 {
 	return 0
 }
 
-type ListResponse struct {
-	Items                []*ListResponse_Item `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
-	XXX_unrecognized     []byte               `json:"-"`
-	XXX_sizecache        int32                `json:"-"`
+func (m *RealServer_HealthCheck) GetExpectedStatus() uint32 {
+	if m != nil {
+		return m.ExpectedStatus
+	}
+	return 0
 }
 
-func (m *ListResponse) Reset()         { *m = ListResponse{} }
-func (m *ListResponse) String() string { return proto.CompactTextString(m) }
-func (*ListResponse) ProtoMessage()    {}
-func (*ListResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_2c0f90c600ad7e2e, []int{2}
+type CloneServiceRequest struct {
+	Id    string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	NewId string `protobuf:"bytes,2,opt,name=new_id,json=newId,proto3" json:"new_id,omitempty"`
+	// Key fields which are set replace those of the cloned service.
+	Key                  *VirtualService_Key `protobuf:"bytes,3,opt,name=key,proto3" json:"key,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}            `json:"-"`
+	XXX_unrecognized     []byte              `json:"-"`
+	XXX_sizecache        int32               `json:"-"`
 }
 
-func (m *ListResponse) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_ListResponse.Unmarshal(m, b)
+func (m *CloneServiceRequest) Reset()         { *m = CloneServiceRequest{} }
+func (m *CloneServiceRequest) String() string { return proto.CompactTextString(m) }
+func (*CloneServiceRequest) ProtoMessage()    {}
+func (*CloneServiceRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2c0f90c600ad7e2e, []int{3}
 }
-func (m *ListResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_ListResponse.Marshal(b, m, deterministic)
+
+func (m *CloneServiceRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CloneServiceRequest.Unmarshal(m, b)
 }
-func (m *ListResponse) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_ListResponse.Merge(m, src)
+func (m *CloneServiceRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CloneServiceRequest.Marshal(b, m, deterministic)
 }
-func (m *ListResponse) XXX_Size() int {
-	return xxx_messageInfo_ListResponse.Size(m)
+func (m *CloneServiceRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CloneServiceRequest.Merge(m, src)
 }
-func (m *ListResponse) XXX_DiscardUnknown() {
-	xxx_messageInfo_ListResponse.DiscardUnknown(m)
+func (m *CloneServiceRequest) XXX_Size() int {
+	return xxx_messageInfo_CloneServiceRequest.Size(m)
+}
+func (m *CloneServiceRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CloneServiceRequest.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_ListResponse proto.InternalMessageInfo
+var xxx_messageInfo_CloneServiceRequest proto.InternalMessageInfo
 
-func (m *ListResponse) GetItems() []*ListResponse_Item {
+func (m *CloneServiceRequest) GetId() string {
 	if m != nil {
-		return m.Items
+		return m.Id
 	}
-	return nil
+	return ""
 }
 
-type ListResponse_Item struct {
-	Service              *VirtualService `protobuf:"bytes,1,opt,name=service,proto3" json:"service,omitempty"`
-	Servers              []*RealServer   `protobuf:"bytes,2,rep,name=servers,proto3" json:"servers,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
-	XXX_unrecognized     []byte          `json:"-"`
-	XXX_sizecache        int32           `json:"-"`
+func (m *CloneServiceRequest) GetNewId() string {
+	if m != nil {
+		return m.NewId
+	}
+	return ""
 }
 
-func (m *ListResponse_Item) Reset()         { *m = ListResponse_Item{} }
-func (m *ListResponse_Item) String() string { return proto.CompactTextString(m) }
-func (*ListResponse_Item) ProtoMessage()    {}
-func (*ListResponse_Item) Descriptor() ([]byte, []int) {
-	return fileDescriptor_2c0f90c600ad7e2e, []int{2, 0}
+func (m *CloneServiceRequest) GetKey() *VirtualService_Key {
+	if m != nil {
+		return m.Key
+	}
+	return nil
 }
 
-func (m *ListResponse_Item) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_ListResponse_Item.Unmarshal(m, b)
+type RenameServiceRequest struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	NewId                string   `protobuf:"bytes,2,opt,name=new_id,json=newId,proto3" json:"new_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RenameServiceRequest) Reset()         { *m = RenameServiceRequest{} }
+func (m *RenameServiceRequest) String() string { return proto.CompactTextString(m) }
+func (*RenameServiceRequest) ProtoMessage()    {}
+func (*RenameServiceRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2c0f90c600ad7e2e, []int{4}
+}
+
+func (m *RenameServiceRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RenameServiceRequest.Unmarshal(m, b)
+}
+func (m *RenameServiceRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RenameServiceRequest.Marshal(b, m, deterministic)
+}
+func (m *RenameServiceRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RenameServiceRequest.Merge(m, src)
+}
+func (m *RenameServiceRequest) XXX_Size() int {
+	return xxx_messageInfo_RenameServiceRequest.Size(m)
+}
+func (m *RenameServiceRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RenameServiceRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RenameServiceRequest proto.InternalMessageInfo
+
+func (m *RenameServiceRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *RenameServiceRequest) GetNewId() string {
+	if m != nil {
+		return m.NewId
+	}
+	return ""
+}
+
+type SwapServersRequest struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	OtherId              string   `protobuf:"bytes,2,opt,name=other_id,json=otherId,proto3" json:"other_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SwapServersRequest) Reset()         { *m = SwapServersRequest{} }
+func (m *SwapServersRequest) String() string { return proto.CompactTextString(m) }
+func (*SwapServersRequest) ProtoMessage()    {}
+func (*SwapServersRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2c0f90c600ad7e2e, []int{5}
+}
+
+func (m *SwapServersRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SwapServersRequest.Unmarshal(m, b)
+}
+func (m *SwapServersRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SwapServersRequest.Marshal(b, m, deterministic)
+}
+func (m *SwapServersRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SwapServersRequest.Merge(m, src)
+}
+func (m *SwapServersRequest) XXX_Size() int {
+	return xxx_messageInfo_SwapServersRequest.Size(m)
+}
+func (m *SwapServersRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SwapServersRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SwapServersRequest proto.InternalMessageInfo
+
+func (m *SwapServersRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *SwapServersRequest) GetOtherId() string {
+	if m != nil {
+		return m.OtherId
+	}
+	return ""
+}
+
+type ListRequest struct {
+	// LabelSelector filters services by label, e.g. "team=payments,env!=prod".
+	LabelSelector string `protobuf:"bytes,1,opt,name=label_selector,json=labelSelector,proto3" json:"label_selector,omitempty"`
+	// FieldSelector filters services by id, ip, port, protocol or scheduler, e.g. "protocol=tcp,port=80".
+	FieldSelector string `protobuf:"bytes,2,opt,name=field_selector,json=fieldSelector,proto3" json:"field_selector,omitempty"`
+	// PageSize is the most services to return, ordered by ID. 0 returns them all.
+	PageSize int32 `protobuf:"varint,3,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	// PageToken continues a list from the next_page_token of a previous response.
+	PageToken string `protobuf:"bytes,4,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	// IdPrefix only returns services whose IDs start with it. Use field_selector to filter by protocol or VIP.
+	IdPrefix string `protobuf:"bytes,5,opt,name=id_prefix,json=idPrefix,proto3" json:"id_prefix,omitempty"`
+	// ServerLabelSelector filters the servers of each service by label, without filtering the services.
+	ServerLabelSelector  string   `protobuf:"bytes,6,opt,name=server_label_selector,json=serverLabelSelector,proto3" json:"server_label_selector,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListRequest) Reset()         { *m = ListRequest{} }
+func (m *ListRequest) String() string { return proto.CompactTextString(m) }
+func (*ListRequest) ProtoMessage()    {}
+func (*ListRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2c0f90c600ad7e2e, []int{6}
+}
+
+func (m *ListRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListRequest.Unmarshal(m, b)
+}
+func (m *ListRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListRequest.Marshal(b, m, deterministic)
+}
+func (m *ListRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListRequest.Merge(m, src)
+}
+func (m *ListRequest) XXX_Size() int {
+	return xxx_messageInfo_ListRequest.Size(m)
+}
+func (m *ListRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListRequest proto.InternalMessageInfo
+
+func (m *ListRequest) GetLabelSelector() string {
+	if m != nil {
+		return m.LabelSelector
+	}
+	return ""
+}
+
+func (m *ListRequest) GetFieldSelector() string {
+	if m != nil {
+		return m.FieldSelector
+	}
+	return ""
+}
+
+func (m *ListRequest) GetPageSize() int32 {
+	if m != nil {
+		return m.PageSize
+	}
+	return 0
+}
+
+func (m *ListRequest) GetPageToken() string {
+	if m != nil {
+		return m.PageToken
+	}
+	return ""
+}
+
+func (m *ListRequest) GetIdPrefix() string {
+	if m != nil {
+		return m.IdPrefix
+	}
+	return ""
+}
+
+func (m *ListRequest) GetServerLabelSelector() string {
+	if m != nil {
+		return m.ServerLabelSelector
+	}
+	return ""
+}
+
+type ListResponse struct {
+	Items []*ListResponse_Item `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
+	// NextPageToken lists the next page of services when set in page_token, empty if there are no more.
+	NextPageToken string `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+	// TotalSize is the number of services matching the request on every page.
+	TotalSize            int32    `protobuf:"varint,3,opt,name=total_size,json=totalSize,proto3" json:"total_size,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListResponse) Reset()         { *m = ListResponse{} }
+func (m *ListResponse) String() string { return proto.CompactTextString(m) }
+func (*ListResponse) ProtoMessage()    {}
+func (*ListResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2c0f90c600ad7e2e, []int{7}
+}
+
+func (m *ListResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListResponse.Unmarshal(m, b)
+}
+func (m *ListResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListResponse.Marshal(b, m, deterministic)
+}
+func (m *ListResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListResponse.Merge(m, src)
+}
+func (m *ListResponse) XXX_Size() int {
+	return xxx_messageInfo_ListResponse.Size(m)
+}
+func (m *ListResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListResponse proto.InternalMessageInfo
+
+func (m *ListResponse) GetItems() []*ListResponse_Item {
+	if m != nil {
+		return m.Items
+	}
+	return nil
+}
+
+func (m *ListResponse) GetNextPageToken() string {
+	if m != nil {
+		return m.NextPageToken
+	}
+	return ""
+}
+
+func (m *ListResponse) GetTotalSize() int32 {
+	if m != nil {
+		return m.TotalSize
+	}
+	return 0
+}
+
+type ListResponse_Item struct {
+	Service              *VirtualService `protobuf:"bytes,1,opt,name=service,proto3" json:"service,omitempty"`
+	Servers              []*RealServer   `protobuf:"bytes,2,rep,name=servers,proto3" json:"servers,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *ListResponse_Item) Reset()         { *m = ListResponse_Item{} }
+func (m *ListResponse_Item) String() string { return proto.CompactTextString(m) }
+func (*ListResponse_Item) ProtoMessage()    {}
+func (*ListResponse_Item) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2c0f90c600ad7e2e, []int{7, 0}
+}
+
+func (m *ListResponse_Item) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListResponse_Item.Unmarshal(m, b)
 }
 func (m *ListResponse_Item) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
 	return xxx_messageInfo_ListResponse_Item.Marshal(b, m, deterministic)
@@ -569,328 +1239,4144 @@ func (m *ListResponse_Item) GetServers() []*RealServer {
 	return nil
 }
 
-func init() {
-	proto.RegisterEnum("types.Protocol", Protocol_name, Protocol_value)
-	proto.RegisterEnum("types.ForwardMethod", ForwardMethod_name, ForwardMethod_value)
-	proto.RegisterType((*VirtualService)(nil), "types.VirtualService")
-	proto.RegisterType((*VirtualService_Key)(nil), "types.VirtualService.Key")
-	proto.RegisterType((*VirtualService_Config)(nil), "types.VirtualService.Config")
-	proto.RegisterType((*RealServer)(nil), "types.RealServer")
-	proto.RegisterType((*RealServer_Key)(nil), "types.RealServer.Key")
-	proto.RegisterType((*RealServer_Config)(nil), "types.RealServer.Config")
-	proto.RegisterType((*RealServer_HealthCheck)(nil), "types.RealServer.HealthCheck")
-	proto.RegisterType((*ListResponse)(nil), "types.ListResponse")
-	proto.RegisterType((*ListResponse_Item)(nil), "types.ListResponse.Item")
+// Stats are the IPVS counters and rate estimates of a virtual service or real server.
+type Stats struct {
+	Connections uint64 `protobuf:"varint,1,opt,name=connections,proto3" json:"connections,omitempty"`
+	PacketsIn   uint64 `protobuf:"varint,2,opt,name=packets_in,json=packetsIn,proto3" json:"packets_in,omitempty"`
+	PacketsOut  uint64 `protobuf:"varint,3,opt,name=packets_out,json=packetsOut,proto3" json:"packets_out,omitempty"`
+	BytesIn     uint64 `protobuf:"varint,4,opt,name=bytes_in,json=bytesIn,proto3" json:"bytes_in,omitempty"`
+	BytesOut    uint64 `protobuf:"varint,5,opt,name=bytes_out,json=bytesOut,proto3" json:"bytes_out,omitempty"`
+	// Rates are per second estimates calculated by the kernel.
+	Cps                  uint64   `protobuf:"varint,6,opt,name=cps,proto3" json:"cps,omitempty"`
+	PpsIn                uint64   `protobuf:"varint,7,opt,name=pps_in,json=ppsIn,proto3" json:"pps_in,omitempty"`
+	PpsOut               uint64   `protobuf:"varint,8,opt,name=pps_out,json=ppsOut,proto3" json:"pps_out,omitempty"`
+	BpsIn                uint64   `protobuf:"varint,9,opt,name=bps_in,json=bpsIn,proto3" json:"bps_in,omitempty"`
+	BpsOut               uint64   `protobuf:"varint,10,opt,name=bps_out,json=bpsOut,proto3" json:"bps_out,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-func init() { proto.RegisterFile("types/types.proto", fileDescriptor_2c0f90c600ad7e2e) }
+func (m *Stats) Reset()         { *m = Stats{} }
+func (m *Stats) String() string { return proto.CompactTextString(m) }
+func (*Stats) ProtoMessage()    {}
+func (*Stats) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2c0f90c600ad7e2e, []int{8}
+}
 
-var fileDescriptor_2c0f90c600ad7e2e = []byte{
-	// 748 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xa4, 0x94, 0xdf, 0x6e, 0xe2, 0x46,
-	0x14, 0xc6, 0x63, 0x0c, 0x04, 0x0e, 0x7f, 0xca, 0x4e, 0xb3, 0x95, 0xeb, 0x6e, 0x2b, 0x8a, 0x54,
-	0x35, 0xdd, 0x48, 0x66, 0x4b, 0xb6, 0x52, 0xa5, 0xf6, 0x62, 0x57, 0xc0, 0x76, 0xd3, 0x0d, 0x81,
-	0x0e, 0x90, 0x5e, 0x22, 0x2f, 0x3e, 0x60, 0x2b, 0xc6, 0x63, 0x8d, 0xc7, 0x45, 0x3c, 0x4a, 0xef,
-	0xfb, 0x26, 0x7d, 0x8f, 0xbe, 0x47, 0xef, 0x2a, 0x8f, 0x6d, 0x4c, 0x02, 0x41, 0x6d, 0xf7, 0x06,
-	0x31, 0xc7, 0xbf, 0xef, 0x9c, 0x33, 0xe7, 0x7c, 0x36, 0x3c, 0x11, 0x1b, 0x1f, 0x83, 0xb6, 0xfc,
-	0x35, 0x7c, 0xce, 0x04, 0x23, 0x05, 0x79, 0xd0, 0x3f, 0x5b, 0x32, 0xb6, 0x74, 0xb1, 0x2d, 0x83,
-	0xef, 0xc3, 0x45, 0x1b, 0x57, 0xbe, 0xd8, 0xc4, 0x8c, 0xfe, 0xc5, 0xc3, 0x87, 0x6b, 0x6e, 0xfa,
-	0x3e, 0xf2, 0xe0, 0xb1, 0xe7, 0x56, 0xc8, 0x4d, 0xe1, 0x30, 0x2f, 0x7e, 0xde, 0xfa, 0x3d, 0x07,
-	0xf5, 0x5b, 0x87, 0x8b, 0xd0, 0x74, 0xc7, 0xc8, 0x7f, 0x73, 0xe6, 0x48, 0xea, 0x90, 0x73, 0x2c,
-	0x4d, 0x69, 0x2a, 0xe7, 0x65, 0x9a, 0x73, 0x2c, 0x72, 0x01, 0xea, 0x1d, 0x6e, 0xb4, 0x5c, 0x53,
-	0x39, 0xaf, 0x74, 0x3e, 0x35, 0xe2, 0x0e, 0xef, 0x6b, 0x8c, 0x77, 0xb8, 0xa1, 0x11, 0x45, 0x5e,
-	0x42, 0x71, 0xce, 0xbc, 0x85, 0xb3, 0xd4, 0x54, 0xc9, 0x3f, 0x3b, 0xcc, 0x77, 0x25, 0x43, 0x13,
-	0x56, 0xbf, 0x05, 0xf5, 0x1d, 0x6e, 0x64, 0x65, 0x7f, 0x5b, 0xd9, 0x27, 0x04, 0xf2, 0x3e, 0xe3,
-	0x42, 0x96, 0xae, 0x51, 0xf9, 0x9f, 0x5c, 0x40, 0x49, 0x76, 0x3e, 0x67, 0xae, 0x2c, 0x51, 0xef,
-	0x7c, 0x94, 0x94, 0x18, 0x25, 0x61, 0xba, 0x05, 0xf4, 0x1f, 0xa1, 0x18, 0x57, 0x22, 0xcf, 0xa0,
-	0x1c, 0xcc, 0x6d, 0xb4, 0x42, 0x17, 0x79, 0x52, 0x21, 0x0b, 0x90, 0x33, 0x28, 0x2c, 0x5c, 0x73,
-	0x19, 0x68, 0xb9, 0xa6, 0x7a, 0x5e, 0xa6, 0xf1, 0xa1, 0xf5, 0x57, 0x1e, 0x80, 0x62, 0xdc, 0x34,
-	0x72, 0x99, 0x22, 0x6e, 0xff, 0xaa, 0xb7, 0x4d, 0x91, 0x06, 0xc8, 0xd7, 0xbb, 0x53, 0x7a, 0x9a,
-	0xb4, 0x94, 0xa9, 0xb3, 0x09, 0xbd, 0x78, 0x30, 0x21, 0x6d, 0x9f, 0xbd, 0x3f, 0x1d, 0xf2, 0x0a,
-	0xaa, 0x36, 0x9a, 0xae, 0xb0, 0x67, 0x73, 0x1b, 0xe7, 0x77, 0x5a, 0x5e, 0xea, 0x3e, 0xdf, 0xd7,
-	0xbd, 0x95, 0x54, 0x37, 0x82, 0x68, 0xc5, 0xce, 0x0e, 0xfa, 0x37, 0xff, 0x7a, 0xbe, 0xba, 0xb7,
-	0x1d, 0xd9, 0x4b, 0x28, 0xae, 0xd1, 0x59, 0xda, 0x42, 0x2a, 0xa2, 0x55, 0xc6, 0x5e, 0x32, 0x52,
-	0x2f, 0x19, 0xd3, 0x2b, 0x4f, 0x5c, 0x76, 0x6e, 0x4d, 0x37, 0x44, 0x9a, 0xb0, 0xc4, 0x80, 0xd3,
-	0x05, 0xe3, 0x6b, 0x93, 0x5b, 0x32, 0x6d, 0xbd, 0x73, 0x96, 0xf4, 0xf9, 0x26, 0x8e, 0x0e, 0x50,
-	0xd8, 0xcc, 0xa2, 0x29, 0xa4, 0xff, 0xad, 0x40, 0x65, 0xa7, 0x6f, 0xf2, 0x3d, 0x94, 0xd0, 0xb3,
-	0x7c, 0xe6, 0x78, 0x8f, 0xd7, 0x1d, 0x0b, 0xee, 0x78, 0xcb, 0xb8, 0xee, 0x96, 0x26, 0xdf, 0x42,
-	0xd1, 0x47, 0xee, 0x30, 0x6b, 0x6b, 0xd5, 0x87, 0xba, 0x5e, 0xe2, 0x7d, 0x9a, 0x80, 0xe4, 0x12,
-	0x4e, 0x85, 0xb3, 0x42, 0x16, 0x8a, 0x64, 0x19, 0x47, 0x34, 0x29, 0x49, 0xbe, 0x84, 0x6a, 0xe8,
-	0xcf, 0x84, 0xcd, 0x31, 0xb0, 0x99, 0x6b, 0xc9, 0x75, 0xd4, 0x68, 0x25, 0xf4, 0x27, 0x69, 0x88,
-	0x7c, 0x05, 0x75, 0x8b, 0xad, 0xbd, 0x1d, 0xa8, 0x20, 0xa1, 0x5a, 0x14, 0xdd, 0x62, 0xad, 0x3f,
-	0x14, 0xa8, 0x5e, 0x3b, 0x81, 0xa0, 0x18, 0xf8, 0xcc, 0x0b, 0x90, 0x18, 0x50, 0x70, 0x04, 0xae,
-	0x02, 0x4d, 0x69, 0xaa, 0x3b, 0xd6, 0xd8, 0x65, 0x8c, 0x2b, 0x81, 0x2b, 0x1a, 0x63, 0xba, 0x05,
-	0xf9, 0xe8, 0x48, 0xda, 0x70, 0x9a, 0x38, 0x31, 0x99, 0xd9, 0xd3, 0x83, 0xaf, 0x1d, 0x4d, 0x29,
-	0x72, 0x11, 0x0b, 0x90, 0xc7, 0x96, 0xaf, 0x74, 0x9e, 0xec, 0xb9, 0x89, 0xa6, 0xc4, 0xf3, 0x17,
-	0x50, 0x4a, 0xdf, 0x2d, 0x42, 0xa0, 0x3e, 0xbd, 0x19, 0xf7, 0x27, 0xb3, 0x11, 0x1d, 0x4e, 0x86,
-	0xdd, 0xe1, 0x75, 0xe3, 0x84, 0x9c, 0x82, 0x3a, 0xe9, 0x8e, 0x1a, 0x4a, 0xf4, 0x67, 0xda, 0x1b,
-	0x35, 0x72, 0xcf, 0x7f, 0x86, 0xda, 0xbd, 0x75, 0x13, 0x0d, 0xce, 0x62, 0xd9, 0x9b, 0x21, 0xfd,
-	0xf5, 0x35, 0xed, 0xcd, 0x06, 0xfd, 0xc9, 0xdb, 0x61, 0xaf, 0x71, 0x42, 0xca, 0x50, 0xa0, 0xc3,
-	0xe9, 0xa4, 0xdf, 0x50, 0x08, 0x40, 0x71, 0x32, 0xbd, 0xb9, 0xe9, 0x5f, 0x37, 0x72, 0xa4, 0x04,
-	0xf9, 0xc1, 0xeb, 0xf1, 0x2f, 0x0d, 0xb5, 0xf3, 0xa7, 0x0a, 0xc5, 0x01, 0x72, 0xd7, 0xf1, 0xc8,
-	0x2b, 0xa8, 0x75, 0x39, 0x9a, 0x02, 0xd3, 0x4f, 0xd5, 0xe1, 0x6b, 0xea, 0x9f, 0xec, 0x6d, 0xb1,
-	0x1f, 0x7d, 0x32, 0x5b, 0x27, 0x51, 0x86, 0xa9, 0x6f, 0x7d, 0x48, 0x86, 0x9f, 0xa0, 0xd6, 0x43,
-	0x17, 0xb3, 0x0c, 0x47, 0xed, 0x79, 0x24, 0xd1, 0x0f, 0x50, 0xcd, 0x2e, 0x83, 0x9c, 0xec, 0x6f,
-	0xe0, 0xb8, 0x38, 0xbb, 0xc7, 0xff, 0x10, 0x67, 0x57, 0xf8, 0xaf, 0xe2, 0xef, 0x20, 0x1f, 0xd9,
-	0x91, 0x3c, 0x42, 0xe8, 0x1f, 0x1f, 0xf0, 0x6c, 0xeb, 0xe4, 0x7d, 0x51, 0x62, 0x97, 0xff, 0x04,
-	0x00, 0x00, 0xff, 0xff, 0x98, 0x68, 0x7c, 0xf2, 0xe7, 0x06, 0x00, 0x00,
+func (m *Stats) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Stats.Unmarshal(m, b)
+}
+func (m *Stats) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Stats.Marshal(b, m, deterministic)
+}
+func (m *Stats) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Stats.Merge(m, src)
+}
+func (m *Stats) XXX_Size() int {
+	return xxx_messageInfo_Stats.Size(m)
+}
+func (m *Stats) XXX_DiscardUnknown() {
+	xxx_messageInfo_Stats.DiscardUnknown(m)
 }
 
-// Reference imports to suppress errors if they are not otherwise used.
-var _ context.Context
-var _ grpc.ClientConn
+var xxx_messageInfo_Stats proto.InternalMessageInfo
 
-// This is a compile-time assertion to ensure that this generated file
-// is compatible with the grpc package it is being compiled against.
-const _ = grpc.SupportPackageIsVersion4
+func (m *Stats) GetConnections() uint64 {
+	if m != nil {
+		return m.Connections
+	}
+	return 0
+}
 
-// MerlinClient is the client API for Merlin service.
-//
-// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
-type MerlinClient interface {
-	CreateService(ctx context.Context, in *VirtualService, opts ...grpc.CallOption) (*empty.Empty, error)
-	UpdateService(ctx context.Context, in *VirtualService, opts ...grpc.CallOption) (*empty.Empty, error)
-	DeleteService(ctx context.Context, in *wrappers.StringValue, opts ...grpc.CallOption) (*empty.Empty, error)
-	CreateServer(ctx context.Context, in *RealServer, opts ...grpc.CallOption) (*empty.Empty, error)
-	UpdateServer(ctx context.Context, in *RealServer, opts ...grpc.CallOption) (*empty.Empty, error)
-	DeleteServer(ctx context.Context, in *RealServer, opts ...grpc.CallOption) (*empty.Empty, error)
-	List(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*ListResponse, error)
+func (m *Stats) GetPacketsIn() uint64 {
+	if m != nil {
+		return m.PacketsIn
+	}
+	return 0
 }
 
-type merlinClient struct {
-	cc *grpc.ClientConn
+func (m *Stats) GetPacketsOut() uint64 {
+	if m != nil {
+		return m.PacketsOut
+	}
+	return 0
 }
 
-func NewMerlinClient(cc *grpc.ClientConn) MerlinClient {
-	return &merlinClient{cc}
+func (m *Stats) GetBytesIn() uint64 {
+	if m != nil {
+		return m.BytesIn
+	}
+	return 0
 }
 
-func (c *merlinClient) CreateService(ctx context.Context, in *VirtualService, opts ...grpc.CallOption) (*empty.Empty, error) {
-	out := new(empty.Empty)
-	err := c.cc.Invoke(ctx, "/types.Merlin/CreateService", in, out, opts...)
-	if err != nil {
-		return nil, err
+func (m *Stats) GetBytesOut() uint64 {
+	if m != nil {
+		return m.BytesOut
+	}
+	return 0
+}
+
+func (m *Stats) GetCps() uint64 {
+	if m != nil {
+		return m.Cps
+	}
+	return 0
+}
+
+func (m *Stats) GetPpsIn() uint64 {
+	if m != nil {
+		return m.PpsIn
+	}
+	return 0
+}
+
+func (m *Stats) GetPpsOut() uint64 {
+	if m != nil {
+		return m.PpsOut
+	}
+	return 0
+}
+
+func (m *Stats) GetBpsIn() uint64 {
+	if m != nil {
+		return m.BpsIn
+	}
+	return 0
+}
+
+func (m *Stats) GetBpsOut() uint64 {
+	if m != nil {
+		return m.BpsOut
+	}
+	return 0
+}
+
+type ServerStats struct {
+	Key                 *RealServer_Key `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Weight              uint32          `protobuf:"varint,2,opt,name=weight,proto3" json:"weight,omitempty"`
+	ActiveConnections   uint32          `protobuf:"varint,3,opt,name=active_connections,json=activeConnections,proto3" json:"active_connections,omitempty"`
+	InactiveConnections uint32          `protobuf:"varint,4,opt,name=inactive_connections,json=inactiveConnections,proto3" json:"inactive_connections,omitempty"`
+	Stats               *Stats          `protobuf:"bytes,5,opt,name=stats,proto3" json:"stats,omitempty"`
+	// Health of the server on the node, unset if the server isn't managed by merlin.
+	Health               Health   `protobuf:"varint,6,opt,name=health,proto3,enum=types.Health" json:"health,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ServerStats) Reset()         { *m = ServerStats{} }
+func (m *ServerStats) String() string { return proto.CompactTextString(m) }
+func (*ServerStats) ProtoMessage()    {}
+func (*ServerStats) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2c0f90c600ad7e2e, []int{9}
+}
+
+func (m *ServerStats) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ServerStats.Unmarshal(m, b)
+}
+func (m *ServerStats) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ServerStats.Marshal(b, m, deterministic)
+}
+func (m *ServerStats) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ServerStats.Merge(m, src)
+}
+func (m *ServerStats) XXX_Size() int {
+	return xxx_messageInfo_ServerStats.Size(m)
+}
+func (m *ServerStats) XXX_DiscardUnknown() {
+	xxx_messageInfo_ServerStats.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ServerStats proto.InternalMessageInfo
+
+func (m *ServerStats) GetKey() *RealServer_Key {
+	if m != nil {
+		return m.Key
+	}
+	return nil
+}
+
+func (m *ServerStats) GetWeight() uint32 {
+	if m != nil {
+		return m.Weight
+	}
+	return 0
+}
+
+func (m *ServerStats) GetActiveConnections() uint32 {
+	if m != nil {
+		return m.ActiveConnections
+	}
+	return 0
+}
+
+func (m *ServerStats) GetInactiveConnections() uint32 {
+	if m != nil {
+		return m.InactiveConnections
+	}
+	return 0
+}
+
+func (m *ServerStats) GetStats() *Stats {
+	if m != nil {
+		return m.Stats
+	}
+	return nil
+}
+
+func (m *ServerStats) GetHealth() Health {
+	if m != nil {
+		return m.Health
+	}
+	return Health_UNSET_HEALTH
+}
+
+type ServiceStats struct {
+	// ID of the matching virtual service in the store. Empty if the service isn't managed by merlin.
+	Id                   string              `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Key                  *VirtualService_Key `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	Stats                *Stats              `protobuf:"bytes,3,opt,name=stats,proto3" json:"stats,omitempty"`
+	Servers              []*ServerStats      `protobuf:"bytes,4,rep,name=servers,proto3" json:"servers,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}            `json:"-"`
+	XXX_unrecognized     []byte              `json:"-"`
+	XXX_sizecache        int32               `json:"-"`
+}
+
+func (m *ServiceStats) Reset()         { *m = ServiceStats{} }
+func (m *ServiceStats) String() string { return proto.CompactTextString(m) }
+func (*ServiceStats) ProtoMessage()    {}
+func (*ServiceStats) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2c0f90c600ad7e2e, []int{10}
+}
+
+func (m *ServiceStats) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ServiceStats.Unmarshal(m, b)
+}
+func (m *ServiceStats) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ServiceStats.Marshal(b, m, deterministic)
+}
+func (m *ServiceStats) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ServiceStats.Merge(m, src)
+}
+func (m *ServiceStats) XXX_Size() int {
+	return xxx_messageInfo_ServiceStats.Size(m)
+}
+func (m *ServiceStats) XXX_DiscardUnknown() {
+	xxx_messageInfo_ServiceStats.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ServiceStats proto.InternalMessageInfo
+
+func (m *ServiceStats) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *ServiceStats) GetKey() *VirtualService_Key {
+	if m != nil {
+		return m.Key
+	}
+	return nil
+}
+
+func (m *ServiceStats) GetStats() *Stats {
+	if m != nil {
+		return m.Stats
+	}
+	return nil
+}
+
+func (m *ServiceStats) GetServers() []*ServerStats {
+	if m != nil {
+		return m.Servers
+	}
+	return nil
+}
+
+type StatsResponse struct {
+	// Node is the hostname of the merlin instance which read the statistics.
+	Node                 string          `protobuf:"bytes,1,opt,name=node,proto3" json:"node,omitempty"`
+	Services             []*ServiceStats `protobuf:"bytes,2,rep,name=services,proto3" json:"services,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *StatsResponse) Reset()         { *m = StatsResponse{} }
+func (m *StatsResponse) String() string { return proto.CompactTextString(m) }
+func (*StatsResponse) ProtoMessage()    {}
+func (*StatsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2c0f90c600ad7e2e, []int{11}
+}
+
+func (m *StatsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StatsResponse.Unmarshal(m, b)
+}
+func (m *StatsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StatsResponse.Marshal(b, m, deterministic)
+}
+func (m *StatsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StatsResponse.Merge(m, src)
+}
+func (m *StatsResponse) XXX_Size() int {
+	return xxx_messageInfo_StatsResponse.Size(m)
+}
+func (m *StatsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_StatsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StatsResponse proto.InternalMessageInfo
+
+func (m *StatsResponse) GetNode() string {
+	if m != nil {
+		return m.Node
+	}
+	return ""
+}
+
+func (m *StatsResponse) GetServices() []*ServiceStats {
+	if m != nil {
+		return m.Services
+	}
+	return nil
+}
+
+type GetServerStatsResponse struct {
+	// Node is the hostname of the merlin instance which read the statistics.
+	Node                 string        `protobuf:"bytes,1,opt,name=node,proto3" json:"node,omitempty"`
+	Service              *ServiceStats `protobuf:"bytes,2,opt,name=service,proto3" json:"service,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
+}
+
+func (m *GetServerStatsResponse) Reset()         { *m = GetServerStatsResponse{} }
+func (m *GetServerStatsResponse) String() string { return proto.CompactTextString(m) }
+func (*GetServerStatsResponse) ProtoMessage()    {}
+func (*GetServerStatsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2c0f90c600ad7e2e, []int{12}
+}
+
+func (m *GetServerStatsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetServerStatsResponse.Unmarshal(m, b)
+}
+func (m *GetServerStatsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetServerStatsResponse.Marshal(b, m, deterministic)
+}
+func (m *GetServerStatsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetServerStatsResponse.Merge(m, src)
+}
+func (m *GetServerStatsResponse) XXX_Size() int {
+	return xxx_messageInfo_GetServerStatsResponse.Size(m)
+}
+func (m *GetServerStatsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetServerStatsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetServerStatsResponse proto.InternalMessageInfo
+
+func (m *GetServerStatsResponse) GetNode() string {
+	if m != nil {
+		return m.Node
+	}
+	return ""
+}
+
+func (m *GetServerStatsResponse) GetService() *ServiceStats {
+	if m != nil {
+		return m.Service
+	}
+	return nil
+}
+
+type ListConnectionsRequest struct {
+	// ServiceId only lists the connections of the service with this ID, if set.
+	ServiceId string `protobuf:"bytes,1,opt,name=service_id,json=serviceId,proto3" json:"service_id,omitempty"`
+	// Server only lists the connections forwarded to the real server with this key, if set.
+	Server               *RealServer_Key `protobuf:"bytes,2,opt,name=server,proto3" json:"server,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *ListConnectionsRequest) Reset()         { *m = ListConnectionsRequest{} }
+func (m *ListConnectionsRequest) String() string { return proto.CompactTextString(m) }
+func (*ListConnectionsRequest) ProtoMessage()    {}
+func (*ListConnectionsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2c0f90c600ad7e2e, []int{13}
+}
+
+func (m *ListConnectionsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListConnectionsRequest.Unmarshal(m, b)
+}
+func (m *ListConnectionsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListConnectionsRequest.Marshal(b, m, deterministic)
+}
+func (m *ListConnectionsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListConnectionsRequest.Merge(m, src)
+}
+func (m *ListConnectionsRequest) XXX_Size() int {
+	return xxx_messageInfo_ListConnectionsRequest.Size(m)
+}
+func (m *ListConnectionsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListConnectionsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListConnectionsRequest proto.InternalMessageInfo
+
+func (m *ListConnectionsRequest) GetServiceId() string {
+	if m != nil {
+		return m.ServiceId
+	}
+	return ""
+}
+
+func (m *ListConnectionsRequest) GetServer() *RealServer_Key {
+	if m != nil {
+		return m.Server
+	}
+	return nil
+}
+
+// Connection is an entry of the IPVS connection table.
+type Connection struct {
+	// ServiceId of the matching virtual service in the store. Empty if the service isn't managed by merlin.
+	ServiceId string `protobuf:"bytes,1,opt,name=service_id,json=serviceId,proto3" json:"service_id,omitempty"`
+	// Service is the key of the virtual service the client connected to.
+	Service    *VirtualService_Key `protobuf:"bytes,2,opt,name=service,proto3" json:"service,omitempty"`
+	ClientIp   string              `protobuf:"bytes,3,opt,name=client_ip,json=clientIp,proto3" json:"client_ip,omitempty"`
+	ClientPort uint32              `protobuf:"varint,4,opt,name=client_port,json=clientPort,proto3" json:"client_port,omitempty"`
+	// Server is the key of the real server the connection is forwarded to.
+	Server *RealServer_Key `protobuf:"bytes,5,opt,name=server,proto3" json:"server,omitempty"`
+	// State of the connection, such as ESTABLISHED or FIN_WAIT for TCP. UDP connections have no state, so are UDP.
+	State string `protobuf:"bytes,6,opt,name=state,proto3" json:"state,omitempty"`
+	// Expires is how long until the kernel forgets the connection, unless more packets are seen.
+	Expires *duration.Duration `protobuf:"bytes,7,opt,name=expires,proto3" json:"expires,omitempty"`
+	// Template is set on the persistence templates of services with persistence, which send new connections from
+	// the client to the same server until they expire. Templates have no client port.
+	Template             bool     `protobuf:"varint,8,opt,name=template,proto3" json:"template,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Connection) Reset()         { *m = Connection{} }
+func (m *Connection) String() string { return proto.CompactTextString(m) }
+func (*Connection) ProtoMessage()    {}
+func (*Connection) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2c0f90c600ad7e2e, []int{14}
+}
+
+func (m *Connection) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Connection.Unmarshal(m, b)
+}
+func (m *Connection) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Connection.Marshal(b, m, deterministic)
+}
+func (m *Connection) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Connection.Merge(m, src)
+}
+func (m *Connection) XXX_Size() int {
+	return xxx_messageInfo_Connection.Size(m)
+}
+func (m *Connection) XXX_DiscardUnknown() {
+	xxx_messageInfo_Connection.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Connection proto.InternalMessageInfo
+
+func (m *Connection) GetServiceId() string {
+	if m != nil {
+		return m.ServiceId
+	}
+	return ""
+}
+
+func (m *Connection) GetService() *VirtualService_Key {
+	if m != nil {
+		return m.Service
+	}
+	return nil
+}
+
+func (m *Connection) GetClientIp() string {
+	if m != nil {
+		return m.ClientIp
+	}
+	return ""
+}
+
+func (m *Connection) GetClientPort() uint32 {
+	if m != nil {
+		return m.ClientPort
+	}
+	return 0
+}
+
+func (m *Connection) GetServer() *RealServer_Key {
+	if m != nil {
+		return m.Server
+	}
+	return nil
+}
+
+func (m *Connection) GetState() string {
+	if m != nil {
+		return m.State
+	}
+	return ""
+}
+
+func (m *Connection) GetExpires() *duration.Duration {
+	if m != nil {
+		return m.Expires
+	}
+	return nil
+}
+
+func (m *Connection) GetTemplate() bool {
+	if m != nil {
+		return m.Template
+	}
+	return false
+}
+
+// NodeState is the actual state of IPVS on a node.
+type NodeState struct {
+	// Node is the hostname of the merlin instance which read IPVS.
+	Node                 string               `protobuf:"bytes,1,opt,name=node,proto3" json:"node,omitempty"`
+	Services             []*NodeState_Service `protobuf:"bytes,2,rep,name=services,proto3" json:"services,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *NodeState) Reset()         { *m = NodeState{} }
+func (m *NodeState) String() string { return proto.CompactTextString(m) }
+func (*NodeState) ProtoMessage()    {}
+func (*NodeState) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2c0f90c600ad7e2e, []int{15}
+}
+
+func (m *NodeState) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_NodeState.Unmarshal(m, b)
+}
+func (m *NodeState) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_NodeState.Marshal(b, m, deterministic)
+}
+func (m *NodeState) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NodeState.Merge(m, src)
+}
+func (m *NodeState) XXX_Size() int {
+	return xxx_messageInfo_NodeState.Size(m)
+}
+func (m *NodeState) XXX_DiscardUnknown() {
+	xxx_messageInfo_NodeState.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_NodeState proto.InternalMessageInfo
+
+func (m *NodeState) GetNode() string {
+	if m != nil {
+		return m.Node
+	}
+	return ""
+}
+
+func (m *NodeState) GetServices() []*NodeState_Service {
+	if m != nil {
+		return m.Services
+	}
+	return nil
+}
+
+type NodeState_Server struct {
+	Server *RealServer `protobuf:"bytes,1,opt,name=server,proto3" json:"server,omitempty"`
+	// Health of the server on the node, unset if the server isn't managed by merlin.
+	Health               Health   `protobuf:"varint,2,opt,name=health,proto3,enum=types.Health" json:"health,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *NodeState_Server) Reset()         { *m = NodeState_Server{} }
+func (m *NodeState_Server) String() string { return proto.CompactTextString(m) }
+func (*NodeState_Server) ProtoMessage()    {}
+func (*NodeState_Server) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2c0f90c600ad7e2e, []int{15, 0}
+}
+
+func (m *NodeState_Server) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_NodeState_Server.Unmarshal(m, b)
+}
+func (m *NodeState_Server) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_NodeState_Server.Marshal(b, m, deterministic)
+}
+func (m *NodeState_Server) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NodeState_Server.Merge(m, src)
+}
+func (m *NodeState_Server) XXX_Size() int {
+	return xxx_messageInfo_NodeState_Server.Size(m)
+}
+func (m *NodeState_Server) XXX_DiscardUnknown() {
+	xxx_messageInfo_NodeState_Server.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_NodeState_Server proto.InternalMessageInfo
+
+func (m *NodeState_Server) GetServer() *RealServer {
+	if m != nil {
+		return m.Server
+	}
+	return nil
+}
+
+func (m *NodeState_Server) GetHealth() Health {
+	if m != nil {
+		return m.Health
+	}
+	return Health_UNSET_HEALTH
+}
+
+type NodeState_Service struct {
+	// Service has the ID of the matching service in the store, empty if the service isn't managed by merlin.
+	Service              *VirtualService     `protobuf:"bytes,1,opt,name=service,proto3" json:"service,omitempty"`
+	Servers              []*NodeState_Server `protobuf:"bytes,2,rep,name=servers,proto3" json:"servers,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}            `json:"-"`
+	XXX_unrecognized     []byte              `json:"-"`
+	XXX_sizecache        int32               `json:"-"`
+}
+
+func (m *NodeState_Service) Reset()         { *m = NodeState_Service{} }
+func (m *NodeState_Service) String() string { return proto.CompactTextString(m) }
+func (*NodeState_Service) ProtoMessage()    {}
+func (*NodeState_Service) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2c0f90c600ad7e2e, []int{15, 1}
+}
+
+func (m *NodeState_Service) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_NodeState_Service.Unmarshal(m, b)
+}
+func (m *NodeState_Service) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_NodeState_Service.Marshal(b, m, deterministic)
+}
+func (m *NodeState_Service) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NodeState_Service.Merge(m, src)
+}
+func (m *NodeState_Service) XXX_Size() int {
+	return xxx_messageInfo_NodeState_Service.Size(m)
+}
+func (m *NodeState_Service) XXX_DiscardUnknown() {
+	xxx_messageInfo_NodeState_Service.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_NodeState_Service proto.InternalMessageInfo
+
+func (m *NodeState_Service) GetService() *VirtualService {
+	if m != nil {
+		return m.Service
+	}
+	return nil
+}
+
+func (m *NodeState_Service) GetServers() []*NodeState_Server {
+	if m != nil {
+		return m.Servers
+	}
+	return nil
+}
+
+// Snapshot is the desired state of an entire merlin cluster.
+type Snapshot struct {
+	Services             []*VirtualService `protobuf:"bytes,1,rep,name=services,proto3" json:"services,omitempty"`
+	Servers              []*RealServer     `protobuf:"bytes,2,rep,name=servers,proto3" json:"servers,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *Snapshot) Reset()         { *m = Snapshot{} }
+func (m *Snapshot) String() string { return proto.CompactTextString(m) }
+func (*Snapshot) ProtoMessage()    {}
+func (*Snapshot) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2c0f90c600ad7e2e, []int{16}
+}
+
+func (m *Snapshot) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Snapshot.Unmarshal(m, b)
+}
+func (m *Snapshot) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Snapshot.Marshal(b, m, deterministic)
+}
+func (m *Snapshot) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Snapshot.Merge(m, src)
+}
+func (m *Snapshot) XXX_Size() int {
+	return xxx_messageInfo_Snapshot.Size(m)
+}
+func (m *Snapshot) XXX_DiscardUnknown() {
+	xxx_messageInfo_Snapshot.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Snapshot proto.InternalMessageInfo
+
+func (m *Snapshot) GetServices() []*VirtualService {
+	if m != nil {
+		return m.Services
+	}
+	return nil
+}
+
+func (m *Snapshot) GetServers() []*RealServer {
+	if m != nil {
+		return m.Servers
+	}
+	return nil
+}
+
+type ApplySnapshotRequest struct {
+	Snapshot *Snapshot `protobuf:"bytes,1,opt,name=snapshot,proto3" json:"snapshot,omitempty"`
+	// Prune deletes any services and servers in the store which aren't in the snapshot.
+	Prune bool `protobuf:"varint,2,opt,name=prune,proto3" json:"prune,omitempty"`
+	// DryRun validates the snapshot and returns the changes, without modifying the store.
+	DryRun               bool     `protobuf:"varint,3,opt,name=dry_run,json=dryRun,proto3" json:"dry_run,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ApplySnapshotRequest) Reset()         { *m = ApplySnapshotRequest{} }
+func (m *ApplySnapshotRequest) String() string { return proto.CompactTextString(m) }
+func (*ApplySnapshotRequest) ProtoMessage()    {}
+func (*ApplySnapshotRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2c0f90c600ad7e2e, []int{17}
+}
+
+func (m *ApplySnapshotRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ApplySnapshotRequest.Unmarshal(m, b)
+}
+func (m *ApplySnapshotRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ApplySnapshotRequest.Marshal(b, m, deterministic)
+}
+func (m *ApplySnapshotRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ApplySnapshotRequest.Merge(m, src)
+}
+func (m *ApplySnapshotRequest) XXX_Size() int {
+	return xxx_messageInfo_ApplySnapshotRequest.Size(m)
+}
+func (m *ApplySnapshotRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ApplySnapshotRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ApplySnapshotRequest proto.InternalMessageInfo
+
+func (m *ApplySnapshotRequest) GetSnapshot() *Snapshot {
+	if m != nil {
+		return m.Snapshot
+	}
+	return nil
+}
+
+func (m *ApplySnapshotRequest) GetPrune() bool {
+	if m != nil {
+		return m.Prune
+	}
+	return false
+}
+
+func (m *ApplySnapshotRequest) GetDryRun() bool {
+	if m != nil {
+		return m.DryRun
+	}
+	return false
+}
+
+// Change is a modification of a single service or server in the store.
+type Change struct {
+	Action Change_Action `protobuf:"varint,1,opt,name=action,proto3,enum=types.Change_Action" json:"action,omitempty"`
+	// Only one of service or server is set.
+	Service              *VirtualService `protobuf:"bytes,2,opt,name=service,proto3" json:"service,omitempty"`
+	Server               *RealServer     `protobuf:"bytes,3,opt,name=server,proto3" json:"server,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *Change) Reset()         { *m = Change{} }
+func (m *Change) String() string { return proto.CompactTextString(m) }
+func (*Change) ProtoMessage()    {}
+func (*Change) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2c0f90c600ad7e2e, []int{18}
+}
+
+func (m *Change) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Change.Unmarshal(m, b)
+}
+func (m *Change) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Change.Marshal(b, m, deterministic)
+}
+func (m *Change) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Change.Merge(m, src)
+}
+func (m *Change) XXX_Size() int {
+	return xxx_messageInfo_Change.Size(m)
+}
+func (m *Change) XXX_DiscardUnknown() {
+	xxx_messageInfo_Change.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Change proto.InternalMessageInfo
+
+func (m *Change) GetAction() Change_Action {
+	if m != nil {
+		return m.Action
+	}
+	return Change_UNSET_ACTION
+}
+
+func (m *Change) GetService() *VirtualService {
+	if m != nil {
+		return m.Service
+	}
+	return nil
+}
+
+func (m *Change) GetServer() *RealServer {
+	if m != nil {
+		return m.Server
+	}
+	return nil
+}
+
+type ApplySnapshotResponse struct {
+	Changes              []*Change `protobuf:"bytes,1,rep,name=changes,proto3" json:"changes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
+	XXX_unrecognized     []byte    `json:"-"`
+	XXX_sizecache        int32     `json:"-"`
+}
+
+func (m *ApplySnapshotResponse) Reset()         { *m = ApplySnapshotResponse{} }
+func (m *ApplySnapshotResponse) String() string { return proto.CompactTextString(m) }
+func (*ApplySnapshotResponse) ProtoMessage()    {}
+func (*ApplySnapshotResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2c0f90c600ad7e2e, []int{19}
+}
+
+func (m *ApplySnapshotResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ApplySnapshotResponse.Unmarshal(m, b)
+}
+func (m *ApplySnapshotResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ApplySnapshotResponse.Marshal(b, m, deterministic)
+}
+func (m *ApplySnapshotResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ApplySnapshotResponse.Merge(m, src)
+}
+func (m *ApplySnapshotResponse) XXX_Size() int {
+	return xxx_messageInfo_ApplySnapshotResponse.Size(m)
+}
+func (m *ApplySnapshotResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ApplySnapshotResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ApplySnapshotResponse proto.InternalMessageInfo
+
+func (m *ApplySnapshotResponse) GetChanges() []*Change {
+	if m != nil {
+		return m.Changes
+	}
+	return nil
+}
+
+// HistoryEntry is a change made to a service or its servers, kept in the store for a limited time.
+type HistoryEntry struct {
+	Time *timestamp.Timestamp `protobuf:"bytes,1,opt,name=time,proto3" json:"time,omitempty"`
+	// Node is the hostname of the merlin instance which made the change.
+	Node                 string   `protobuf:"bytes,2,opt,name=node,proto3" json:"node,omitempty"`
+	Change               *Change  `protobuf:"bytes,3,opt,name=change,proto3" json:"change,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *HistoryEntry) Reset()         { *m = HistoryEntry{} }
+func (m *HistoryEntry) String() string { return proto.CompactTextString(m) }
+func (*HistoryEntry) ProtoMessage()    {}
+func (*HistoryEntry) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2c0f90c600ad7e2e, []int{20}
+}
+
+func (m *HistoryEntry) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_HistoryEntry.Unmarshal(m, b)
+}
+func (m *HistoryEntry) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_HistoryEntry.Marshal(b, m, deterministic)
+}
+func (m *HistoryEntry) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_HistoryEntry.Merge(m, src)
+}
+func (m *HistoryEntry) XXX_Size() int {
+	return xxx_messageInfo_HistoryEntry.Size(m)
+}
+func (m *HistoryEntry) XXX_DiscardUnknown() {
+	xxx_messageInfo_HistoryEntry.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_HistoryEntry proto.InternalMessageInfo
+
+func (m *HistoryEntry) GetTime() *timestamp.Timestamp {
+	if m != nil {
+		return m.Time
+	}
+	return nil
+}
+
+func (m *HistoryEntry) GetNode() string {
+	if m != nil {
+		return m.Node
+	}
+	return ""
+}
+
+func (m *HistoryEntry) GetChange() *Change {
+	if m != nil {
+		return m.Change
+	}
+	return nil
+}
+
+type DescribeServiceRequest struct {
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// History is the maximum number of recent changes to return, 0 for none.
+	History              uint32   `protobuf:"varint,2,opt,name=history,proto3" json:"history,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DescribeServiceRequest) Reset()         { *m = DescribeServiceRequest{} }
+func (m *DescribeServiceRequest) String() string { return proto.CompactTextString(m) }
+func (*DescribeServiceRequest) ProtoMessage()    {}
+func (*DescribeServiceRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2c0f90c600ad7e2e, []int{21}
+}
+
+func (m *DescribeServiceRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DescribeServiceRequest.Unmarshal(m, b)
+}
+func (m *DescribeServiceRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DescribeServiceRequest.Marshal(b, m, deterministic)
+}
+func (m *DescribeServiceRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DescribeServiceRequest.Merge(m, src)
+}
+func (m *DescribeServiceRequest) XXX_Size() int {
+	return xxx_messageInfo_DescribeServiceRequest.Size(m)
+}
+func (m *DescribeServiceRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DescribeServiceRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DescribeServiceRequest proto.InternalMessageInfo
+
+func (m *DescribeServiceRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *DescribeServiceRequest) GetHistory() uint32 {
+	if m != nil {
+		return m.History
+	}
+	return 0
+}
+
+type DescribeServiceResponse struct {
+	// Node is the hostname of the merlin instance which served the request.
+	Node    string                            `protobuf:"bytes,1,opt,name=node,proto3" json:"node,omitempty"`
+	Service *VirtualService                   `protobuf:"bytes,2,opt,name=service,proto3" json:"service,omitempty"`
+	Servers []*DescribeServiceResponse_Server `protobuf:"bytes,3,rep,name=servers,proto3" json:"servers,omitempty"`
+	// History is the most recent changes to the service and its servers, oldest first.
+	History []*HistoryEntry `protobuf:"bytes,4,rep,name=history,proto3" json:"history,omitempty"`
+	// Errors from the last reconcile of the service on the node.
+	Errors               []string `protobuf:"bytes,5,rep,name=errors,proto3" json:"errors,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DescribeServiceResponse) Reset()         { *m = DescribeServiceResponse{} }
+func (m *DescribeServiceResponse) String() string { return proto.CompactTextString(m) }
+func (*DescribeServiceResponse) ProtoMessage()    {}
+func (*DescribeServiceResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2c0f90c600ad7e2e, []int{22}
+}
+
+func (m *DescribeServiceResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DescribeServiceResponse.Unmarshal(m, b)
+}
+func (m *DescribeServiceResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DescribeServiceResponse.Marshal(b, m, deterministic)
+}
+func (m *DescribeServiceResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DescribeServiceResponse.Merge(m, src)
+}
+func (m *DescribeServiceResponse) XXX_Size() int {
+	return xxx_messageInfo_DescribeServiceResponse.Size(m)
+}
+func (m *DescribeServiceResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_DescribeServiceResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DescribeServiceResponse proto.InternalMessageInfo
+
+func (m *DescribeServiceResponse) GetNode() string {
+	if m != nil {
+		return m.Node
+	}
+	return ""
+}
+
+func (m *DescribeServiceResponse) GetService() *VirtualService {
+	if m != nil {
+		return m.Service
+	}
+	return nil
+}
+
+func (m *DescribeServiceResponse) GetServers() []*DescribeServiceResponse_Server {
+	if m != nil {
+		return m.Servers
+	}
+	return nil
+}
+
+func (m *DescribeServiceResponse) GetHistory() []*HistoryEntry {
+	if m != nil {
+		return m.History
+	}
+	return nil
+}
+
+func (m *DescribeServiceResponse) GetErrors() []string {
+	if m != nil {
+		return m.Errors
+	}
+	return nil
+}
+
+type DescribeServiceResponse_Server struct {
+	Server *RealServer `protobuf:"bytes,1,opt,name=server,proto3" json:"server,omitempty"`
+	// Health of the server on the node, unset if it isn't known.
+	Health               Health   `protobuf:"varint,2,opt,name=health,proto3,enum=types.Health" json:"health,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DescribeServiceResponse_Server) Reset()         { *m = DescribeServiceResponse_Server{} }
+func (m *DescribeServiceResponse_Server) String() string { return proto.CompactTextString(m) }
+func (*DescribeServiceResponse_Server) ProtoMessage()    {}
+func (*DescribeServiceResponse_Server) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2c0f90c600ad7e2e, []int{22, 0}
+}
+
+func (m *DescribeServiceResponse_Server) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DescribeServiceResponse_Server.Unmarshal(m, b)
+}
+func (m *DescribeServiceResponse_Server) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DescribeServiceResponse_Server.Marshal(b, m, deterministic)
+}
+func (m *DescribeServiceResponse_Server) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DescribeServiceResponse_Server.Merge(m, src)
+}
+func (m *DescribeServiceResponse_Server) XXX_Size() int {
+	return xxx_messageInfo_DescribeServiceResponse_Server.Size(m)
+}
+func (m *DescribeServiceResponse_Server) XXX_DiscardUnknown() {
+	xxx_messageInfo_DescribeServiceResponse_Server.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DescribeServiceResponse_Server proto.InternalMessageInfo
+
+func (m *DescribeServiceResponse_Server) GetServer() *RealServer {
+	if m != nil {
+		return m.Server
+	}
+	return nil
+}
+
+func (m *DescribeServiceResponse_Server) GetHealth() Health {
+	if m != nil {
+		return m.Health
+	}
+	return Health_UNSET_HEALTH
+}
+
+// Node is a merlin instance, registered in the store while it's running.
+type Node struct {
+	Name         string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Version      string `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
+	StoreBackend string `protobuf:"bytes,3,opt,name=store_backend,json=storeBackend,proto3" json:"store_backend,omitempty"`
+	// Reconcile is true if the node reconciles its local IPVS with the store.
+	Reconcile     bool                 `protobuf:"varint,4,opt,name=reconcile,proto3" json:"reconcile,omitempty"`
+	LastHeartbeat *timestamp.Timestamp `protobuf:"bytes,5,opt,name=last_heartbeat,json=lastHeartbeat,proto3" json:"last_heartbeat,omitempty"`
+	// LastSync is when the node last reconciled IPVS with the store, unset if it hasn't yet.
+	LastSync *timestamp.Timestamp `protobuf:"bytes,6,opt,name=last_sync,json=lastSync,proto3" json:"last_sync,omitempty"`
+	// Maintenance is true if the node has paused reconciling IPVS.
+	Maintenance bool `protobuf:"varint,7,opt,name=maintenance,proto3" json:"maintenance,omitempty"`
+	// Drift is the number of changes the last sync made to IPVS to match the store.
+	Drift uint32 `protobuf:"varint,8,opt,name=drift,proto3" json:"drift,omitempty"`
+	// Leader is true if the node is the elected leader, which performs writes when leader election is enabled.
+	Leader bool `protobuf:"varint,9,opt,name=leader,proto3" json:"leader,omitempty"`
+	// Address is the host:port other nodes forward writes to, if this node is the leader.
+	Address string `protobuf:"bytes,10,opt,name=address,proto3" json:"address,omitempty"`
+	// Mode is all if the node serves the API and reconciles, or agent if it only reconciles.
+	Mode string `protobuf:"bytes,11,opt,name=mode,proto3" json:"mode,omitempty"`
+	// Labels of the node, which services' node selectors match.
+	Labels map[string]string `protobuf:"bytes,12,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	// FailedServices are the IDs of the services which failed to reconcile in the last sync.
+	FailedServices []string `protobuf:"bytes,13,rep,name=failed_services,json=failedServices,proto3" json:"failed_services,omitempty"`
+	// Passive is true if the node is the standby of an active/passive pair, leaving the VIPs to the active node.
+	Passive              bool     `protobuf:"varint,14,opt,name=passive,proto3" json:"passive,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Node) Reset()         { *m = Node{} }
+func (m *Node) String() string { return proto.CompactTextString(m) }
+func (*Node) ProtoMessage()    {}
+func (*Node) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2c0f90c600ad7e2e, []int{23}
+}
+
+func (m *Node) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Node.Unmarshal(m, b)
+}
+func (m *Node) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Node.Marshal(b, m, deterministic)
+}
+func (m *Node) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Node.Merge(m, src)
+}
+func (m *Node) XXX_Size() int {
+	return xxx_messageInfo_Node.Size(m)
+}
+func (m *Node) XXX_DiscardUnknown() {
+	xxx_messageInfo_Node.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Node proto.InternalMessageInfo
+
+func (m *Node) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Node) GetVersion() string {
+	if m != nil {
+		return m.Version
+	}
+	return ""
+}
+
+func (m *Node) GetStoreBackend() string {
+	if m != nil {
+		return m.StoreBackend
+	}
+	return ""
+}
+
+func (m *Node) GetReconcile() bool {
+	if m != nil {
+		return m.Reconcile
+	}
+	return false
+}
+
+func (m *Node) GetLastHeartbeat() *timestamp.Timestamp {
+	if m != nil {
+		return m.LastHeartbeat
+	}
+	return nil
+}
+
+func (m *Node) GetLastSync() *timestamp.Timestamp {
+	if m != nil {
+		return m.LastSync
+	}
+	return nil
+}
+
+func (m *Node) GetMaintenance() bool {
+	if m != nil {
+		return m.Maintenance
+	}
+	return false
+}
+
+func (m *Node) GetDrift() uint32 {
+	if m != nil {
+		return m.Drift
+	}
+	return 0
+}
+
+func (m *Node) GetLeader() bool {
+	if m != nil {
+		return m.Leader
+	}
+	return false
+}
+
+func (m *Node) GetAddress() string {
+	if m != nil {
+		return m.Address
+	}
+	return ""
+}
+
+func (m *Node) GetMode() string {
+	if m != nil {
+		return m.Mode
+	}
+	return ""
+}
+
+func (m *Node) GetLabels() map[string]string {
+	if m != nil {
+		return m.Labels
+	}
+	return nil
+}
+
+func (m *Node) GetFailedServices() []string {
+	if m != nil {
+		return m.FailedServices
+	}
+	return nil
+}
+
+func (m *Node) GetPassive() bool {
+	if m != nil {
+		return m.Passive
+	}
+	return false
+}
+
+type InfoResponse struct {
+	Node *Node `protobuf:"bytes,1,opt,name=node,proto3" json:"node,omitempty"`
+	// StoreError is set if the node is unable to read from the store.
+	StoreError           string   `protobuf:"bytes,2,opt,name=store_error,json=storeError,proto3" json:"store_error,omitempty"`
+	Services             uint32   `protobuf:"varint,3,opt,name=services,proto3" json:"services,omitempty"`
+	Servers              uint32   `protobuf:"varint,4,opt,name=servers,proto3" json:"servers,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *InfoResponse) Reset()         { *m = InfoResponse{} }
+func (m *InfoResponse) String() string { return proto.CompactTextString(m) }
+func (*InfoResponse) ProtoMessage()    {}
+func (*InfoResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2c0f90c600ad7e2e, []int{24}
+}
+
+func (m *InfoResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_InfoResponse.Unmarshal(m, b)
+}
+func (m *InfoResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_InfoResponse.Marshal(b, m, deterministic)
+}
+func (m *InfoResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_InfoResponse.Merge(m, src)
+}
+func (m *InfoResponse) XXX_Size() int {
+	return xxx_messageInfo_InfoResponse.Size(m)
+}
+func (m *InfoResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_InfoResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_InfoResponse proto.InternalMessageInfo
+
+func (m *InfoResponse) GetNode() *Node {
+	if m != nil {
+		return m.Node
+	}
+	return nil
+}
+
+func (m *InfoResponse) GetStoreError() string {
+	if m != nil {
+		return m.StoreError
+	}
+	return ""
+}
+
+func (m *InfoResponse) GetServices() uint32 {
+	if m != nil {
+		return m.Services
+	}
+	return 0
+}
+
+func (m *InfoResponse) GetServers() uint32 {
+	if m != nil {
+		return m.Servers
+	}
+	return 0
+}
+
+type ListNodesResponse struct {
+	Nodes                []*Node  `protobuf:"bytes,1,rep,name=nodes,proto3" json:"nodes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListNodesResponse) Reset()         { *m = ListNodesResponse{} }
+func (m *ListNodesResponse) String() string { return proto.CompactTextString(m) }
+func (*ListNodesResponse) ProtoMessage()    {}
+func (*ListNodesResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2c0f90c600ad7e2e, []int{25}
+}
+
+func (m *ListNodesResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListNodesResponse.Unmarshal(m, b)
+}
+func (m *ListNodesResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListNodesResponse.Marshal(b, m, deterministic)
+}
+func (m *ListNodesResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListNodesResponse.Merge(m, src)
+}
+func (m *ListNodesResponse) XXX_Size() int {
+	return xxx_messageInfo_ListNodesResponse.Size(m)
+}
+func (m *ListNodesResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListNodesResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListNodesResponse proto.InternalMessageInfo
+
+func (m *ListNodesResponse) GetNodes() []*Node {
+	if m != nil {
+		return m.Nodes
+	}
+	return nil
+}
+
+type SetMaintenanceRequest struct {
+	Node                 string   `protobuf:"bytes,1,opt,name=node,proto3" json:"node,omitempty"`
+	Enabled              bool     `protobuf:"varint,2,opt,name=enabled,proto3" json:"enabled,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SetMaintenanceRequest) Reset()         { *m = SetMaintenanceRequest{} }
+func (m *SetMaintenanceRequest) String() string { return proto.CompactTextString(m) }
+func (*SetMaintenanceRequest) ProtoMessage()    {}
+func (*SetMaintenanceRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2c0f90c600ad7e2e, []int{26}
+}
+
+func (m *SetMaintenanceRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SetMaintenanceRequest.Unmarshal(m, b)
+}
+func (m *SetMaintenanceRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SetMaintenanceRequest.Marshal(b, m, deterministic)
+}
+func (m *SetMaintenanceRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SetMaintenanceRequest.Merge(m, src)
+}
+func (m *SetMaintenanceRequest) XXX_Size() int {
+	return xxx_messageInfo_SetMaintenanceRequest.Size(m)
+}
+func (m *SetMaintenanceRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SetMaintenanceRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SetMaintenanceRequest proto.InternalMessageInfo
+
+func (m *SetMaintenanceRequest) GetNode() string {
+	if m != nil {
+		return m.Node
+	}
+	return ""
+}
+
+func (m *SetMaintenanceRequest) GetEnabled() bool {
+	if m != nil {
+		return m.Enabled
+	}
+	return false
+}
+
+type RolloutServiceRequest struct {
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// Config fields which are set replace those of the service, as in UpdateService.
+	Config *VirtualService_Config `protobuf:"bytes,2,opt,name=config,proto3" json:"config,omitempty"`
+	// CanaryNodes reconcile the config first. Defaults to the first Canaries nodes which reconcile the service.
+	CanaryNodes []string `protobuf:"bytes,3,rep,name=canary_nodes,json=canaryNodes,proto3" json:"canary_nodes,omitempty"`
+	// Canaries is the number of canary nodes to choose if none are given, defaults to 1.
+	Canaries uint32 `protobuf:"varint,4,opt,name=canaries,proto3" json:"canaries,omitempty"`
+	// Bake is how long the canaries must stay healthy after converging, defaults to 1 minute.
+	Bake *duration.Duration `protobuf:"bytes,5,opt,name=bake,proto3" json:"bake,omitempty"`
+	// Timeout is how long the canaries have to converge, defaults to 5 minutes.
+	Timeout *duration.Duration `protobuf:"bytes,6,opt,name=timeout,proto3" json:"timeout,omitempty"`
+	// Abort cancels the rollout of the service, leaving its config unchanged.
+	Abort                bool     `protobuf:"varint,7,opt,name=abort,proto3" json:"abort,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RolloutServiceRequest) Reset()         { *m = RolloutServiceRequest{} }
+func (m *RolloutServiceRequest) String() string { return proto.CompactTextString(m) }
+func (*RolloutServiceRequest) ProtoMessage()    {}
+func (*RolloutServiceRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2c0f90c600ad7e2e, []int{27}
+}
+
+func (m *RolloutServiceRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RolloutServiceRequest.Unmarshal(m, b)
+}
+func (m *RolloutServiceRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RolloutServiceRequest.Marshal(b, m, deterministic)
+}
+func (m *RolloutServiceRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RolloutServiceRequest.Merge(m, src)
+}
+func (m *RolloutServiceRequest) XXX_Size() int {
+	return xxx_messageInfo_RolloutServiceRequest.Size(m)
+}
+func (m *RolloutServiceRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RolloutServiceRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RolloutServiceRequest proto.InternalMessageInfo
+
+func (m *RolloutServiceRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *RolloutServiceRequest) GetConfig() *VirtualService_Config {
+	if m != nil {
+		return m.Config
+	}
+	return nil
+}
+
+func (m *RolloutServiceRequest) GetCanaryNodes() []string {
+	if m != nil {
+		return m.CanaryNodes
+	}
+	return nil
+}
+
+func (m *RolloutServiceRequest) GetCanaries() uint32 {
+	if m != nil {
+		return m.Canaries
+	}
+	return 0
+}
+
+func (m *RolloutServiceRequest) GetBake() *duration.Duration {
+	if m != nil {
+		return m.Bake
+	}
+	return nil
+}
+
+func (m *RolloutServiceRequest) GetTimeout() *duration.Duration {
+	if m != nil {
+		return m.Timeout
+	}
+	return nil
+}
+
+func (m *RolloutServiceRequest) GetAbort() bool {
+	if m != nil {
+		return m.Abort
+	}
+	return false
+}
+
+// VIPPool is a range of IPs which services are allocated VIPs from.
+type VIPPool struct {
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// CIDRs of the pool, which are allocated from in order. The network and broadcast addresses of IPv4 CIDRs
+	// aren't allocated.
+	Cidrs                []string `protobuf:"bytes,2,rep,name=cidrs,proto3" json:"cidrs,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *VIPPool) Reset()         { *m = VIPPool{} }
+func (m *VIPPool) String() string { return proto.CompactTextString(m) }
+func (*VIPPool) ProtoMessage()    {}
+func (*VIPPool) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2c0f90c600ad7e2e, []int{28}
+}
+
+func (m *VIPPool) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_VIPPool.Unmarshal(m, b)
+}
+func (m *VIPPool) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_VIPPool.Marshal(b, m, deterministic)
+}
+func (m *VIPPool) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_VIPPool.Merge(m, src)
+}
+func (m *VIPPool) XXX_Size() int {
+	return xxx_messageInfo_VIPPool.Size(m)
+}
+func (m *VIPPool) XXX_DiscardUnknown() {
+	xxx_messageInfo_VIPPool.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_VIPPool proto.InternalMessageInfo
+
+func (m *VIPPool) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *VIPPool) GetCidrs() []string {
+	if m != nil {
+		return m.Cidrs
+	}
+	return nil
+}
+
+type ListPoolsResponse struct {
+	Pools                []*ListPoolsResponse_Pool `protobuf:"bytes,1,rep,name=pools,proto3" json:"pools,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}                  `json:"-"`
+	XXX_unrecognized     []byte                    `json:"-"`
+	XXX_sizecache        int32                     `json:"-"`
+}
+
+func (m *ListPoolsResponse) Reset()         { *m = ListPoolsResponse{} }
+func (m *ListPoolsResponse) String() string { return proto.CompactTextString(m) }
+func (*ListPoolsResponse) ProtoMessage()    {}
+func (*ListPoolsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2c0f90c600ad7e2e, []int{29}
+}
+
+func (m *ListPoolsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListPoolsResponse.Unmarshal(m, b)
+}
+func (m *ListPoolsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListPoolsResponse.Marshal(b, m, deterministic)
+}
+func (m *ListPoolsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListPoolsResponse.Merge(m, src)
+}
+func (m *ListPoolsResponse) XXX_Size() int {
+	return xxx_messageInfo_ListPoolsResponse.Size(m)
+}
+func (m *ListPoolsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListPoolsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListPoolsResponse proto.InternalMessageInfo
+
+func (m *ListPoolsResponse) GetPools() []*ListPoolsResponse_Pool {
+	if m != nil {
+		return m.Pools
+	}
+	return nil
+}
+
+type ListPoolsResponse_Pool struct {
+	Pool *VIPPool `protobuf:"bytes,1,opt,name=pool,proto3" json:"pool,omitempty"`
+	// Allocated are the IPs of the pool used by services.
+	Allocated            []string `protobuf:"bytes,2,rep,name=allocated,proto3" json:"allocated,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListPoolsResponse_Pool) Reset()         { *m = ListPoolsResponse_Pool{} }
+func (m *ListPoolsResponse_Pool) String() string { return proto.CompactTextString(m) }
+func (*ListPoolsResponse_Pool) ProtoMessage()    {}
+func (*ListPoolsResponse_Pool) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2c0f90c600ad7e2e, []int{29, 0}
+}
+
+func (m *ListPoolsResponse_Pool) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListPoolsResponse_Pool.Unmarshal(m, b)
+}
+func (m *ListPoolsResponse_Pool) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListPoolsResponse_Pool.Marshal(b, m, deterministic)
+}
+func (m *ListPoolsResponse_Pool) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListPoolsResponse_Pool.Merge(m, src)
+}
+func (m *ListPoolsResponse_Pool) XXX_Size() int {
+	return xxx_messageInfo_ListPoolsResponse_Pool.Size(m)
+}
+func (m *ListPoolsResponse_Pool) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListPoolsResponse_Pool.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListPoolsResponse_Pool proto.InternalMessageInfo
+
+func (m *ListPoolsResponse_Pool) GetPool() *VIPPool {
+	if m != nil {
+		return m.Pool
+	}
+	return nil
+}
+
+func (m *ListPoolsResponse_Pool) GetAllocated() []string {
+	if m != nil {
+		return m.Allocated
+	}
+	return nil
+}
+
+// Candidate config, which writes are staged in until it's committed.
+type Candidate struct {
+	// Staged is the config to commit, or nil if nothing is staged.
+	Staged *Snapshot `protobuf:"bytes,1,opt,name=staged,proto3" json:"staged,omitempty"`
+	// Rollback is the config before the last commit, which is restored at confirm_by unless the commit is
+	// confirmed.
+	Rollback  *Snapshot            `protobuf:"bytes,2,opt,name=rollback,proto3" json:"rollback,omitempty"`
+	ConfirmBy *timestamp.Timestamp `protobuf:"bytes,3,opt,name=confirm_by,json=confirmBy,proto3" json:"confirm_by,omitempty"`
+	// Revision of the candidate when it was read, set by the store so it's only written if it hasn't changed since.
+	// It isn't stored.
+	Revision int64 `protobuf:"varint,4,opt,name=revision,proto3" json:"revision,omitempty"`
+	// Committing is set while the staged config is being applied by a commit, whose rollback is set before it's
+	// applied.
+	Committing           bool     `protobuf:"varint,5,opt,name=committing,proto3" json:"committing,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Candidate) Reset()         { *m = Candidate{} }
+func (m *Candidate) String() string { return proto.CompactTextString(m) }
+func (*Candidate) ProtoMessage()    {}
+func (*Candidate) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2c0f90c600ad7e2e, []int{30}
+}
+
+func (m *Candidate) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Candidate.Unmarshal(m, b)
+}
+func (m *Candidate) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Candidate.Marshal(b, m, deterministic)
+}
+func (m *Candidate) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Candidate.Merge(m, src)
+}
+func (m *Candidate) XXX_Size() int {
+	return xxx_messageInfo_Candidate.Size(m)
+}
+func (m *Candidate) XXX_DiscardUnknown() {
+	xxx_messageInfo_Candidate.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Candidate proto.InternalMessageInfo
+
+func (m *Candidate) GetStaged() *Snapshot {
+	if m != nil {
+		return m.Staged
+	}
+	return nil
+}
+
+func (m *Candidate) GetRollback() *Snapshot {
+	if m != nil {
+		return m.Rollback
+	}
+	return nil
+}
+
+func (m *Candidate) GetConfirmBy() *timestamp.Timestamp {
+	if m != nil {
+		return m.ConfirmBy
+	}
+	return nil
+}
+
+func (m *Candidate) GetRevision() int64 {
+	if m != nil {
+		return m.Revision
+	}
+	return 0
+}
+
+func (m *Candidate) GetCommitting() bool {
+	if m != nil {
+		return m.Committing
+	}
+	return false
+}
+
+type GetCandidateResponse struct {
+	// Changes committing the candidate would make.
+	Changes []*Change `protobuf:"bytes,1,rep,name=changes,proto3" json:"changes,omitempty"`
+	// ConfirmBy is when the last commit is rolled back unless it's confirmed, if it's unconfirmed.
+	ConfirmBy            *timestamp.Timestamp `protobuf:"bytes,2,opt,name=confirm_by,json=confirmBy,proto3" json:"confirm_by,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *GetCandidateResponse) Reset()         { *m = GetCandidateResponse{} }
+func (m *GetCandidateResponse) String() string { return proto.CompactTextString(m) }
+func (*GetCandidateResponse) ProtoMessage()    {}
+func (*GetCandidateResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2c0f90c600ad7e2e, []int{31}
+}
+
+func (m *GetCandidateResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetCandidateResponse.Unmarshal(m, b)
+}
+func (m *GetCandidateResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetCandidateResponse.Marshal(b, m, deterministic)
+}
+func (m *GetCandidateResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetCandidateResponse.Merge(m, src)
+}
+func (m *GetCandidateResponse) XXX_Size() int {
+	return xxx_messageInfo_GetCandidateResponse.Size(m)
+}
+func (m *GetCandidateResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetCandidateResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetCandidateResponse proto.InternalMessageInfo
+
+func (m *GetCandidateResponse) GetChanges() []*Change {
+	if m != nil {
+		return m.Changes
+	}
+	return nil
+}
+
+func (m *GetCandidateResponse) GetConfirmBy() *timestamp.Timestamp {
+	if m != nil {
+		return m.ConfirmBy
+	}
+	return nil
+}
+
+type CommitRequest struct {
+	// ConfirmTimeout rolls the commit back unless it's confirmed within the timeout, if set.
+	ConfirmTimeout *duration.Duration `protobuf:"bytes,1,opt,name=confirm_timeout,json=confirmTimeout,proto3" json:"confirm_timeout,omitempty"`
+	// DryRun validates the candidate and returns the changes, without modifying the store.
+	DryRun               bool     `protobuf:"varint,2,opt,name=dry_run,json=dryRun,proto3" json:"dry_run,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CommitRequest) Reset()         { *m = CommitRequest{} }
+func (m *CommitRequest) String() string { return proto.CompactTextString(m) }
+func (*CommitRequest) ProtoMessage()    {}
+func (*CommitRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2c0f90c600ad7e2e, []int{32}
+}
+
+func (m *CommitRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CommitRequest.Unmarshal(m, b)
+}
+func (m *CommitRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CommitRequest.Marshal(b, m, deterministic)
+}
+func (m *CommitRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CommitRequest.Merge(m, src)
+}
+func (m *CommitRequest) XXX_Size() int {
+	return xxx_messageInfo_CommitRequest.Size(m)
+}
+func (m *CommitRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CommitRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CommitRequest proto.InternalMessageInfo
+
+func (m *CommitRequest) GetConfirmTimeout() *duration.Duration {
+	if m != nil {
+		return m.ConfirmTimeout
+	}
+	return nil
+}
+
+func (m *CommitRequest) GetDryRun() bool {
+	if m != nil {
+		return m.DryRun
+	}
+	return false
+}
+
+type CommitResponse struct {
+	Changes              []*Change `protobuf:"bytes,1,rep,name=changes,proto3" json:"changes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
+	XXX_unrecognized     []byte    `json:"-"`
+	XXX_sizecache        int32     `json:"-"`
+}
+
+func (m *CommitResponse) Reset()         { *m = CommitResponse{} }
+func (m *CommitResponse) String() string { return proto.CompactTextString(m) }
+func (*CommitResponse) ProtoMessage()    {}
+func (*CommitResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2c0f90c600ad7e2e, []int{33}
+}
+
+func (m *CommitResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CommitResponse.Unmarshal(m, b)
+}
+func (m *CommitResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CommitResponse.Marshal(b, m, deterministic)
+}
+func (m *CommitResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CommitResponse.Merge(m, src)
+}
+func (m *CommitResponse) XXX_Size() int {
+	return xxx_messageInfo_CommitResponse.Size(m)
+}
+func (m *CommitResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_CommitResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CommitResponse proto.InternalMessageInfo
+
+func (m *CommitResponse) GetChanges() []*Change {
+	if m != nil {
+		return m.Changes
+	}
+	return nil
+}
+
+// AuditEvent is a write made through the API, kept in the store for the audit retention period.
+type AuditEvent struct {
+	Time *timestamp.Timestamp `protobuf:"bytes,1,opt,name=time,proto3" json:"time,omitempty"`
+	// Client the request authenticated as, by the name of its token or its TLS client certificate, or empty if it
+	// didn't.
+	Client string `protobuf:"bytes,2,opt,name=client,proto3" json:"client,omitempty"`
+	// Node is the name of the merlin instance which received the request.
+	Node string `protobuf:"bytes,3,opt,name=node,proto3" json:"node,omitempty"`
+	// ForwardedBy is the node which forwarded the request to the leader, if it was forwarded.
+	ForwardedBy string `protobuf:"bytes,4,opt,name=forwarded_by,json=forwardedBy,proto3" json:"forwarded_by,omitempty"`
+	// Method is the name of the RPC, e.g. CreateService.
+	Method string `protobuf:"bytes,5,opt,name=method,proto3" json:"method,omitempty"`
+	// Request is the JSON of the request.
+	Request string `protobuf:"bytes,6,opt,name=request,proto3" json:"request,omitempty"`
+	// Code is the status code of the response, e.g. OK or PermissionDenied.
+	Code string `protobuf:"bytes,7,opt,name=code,proto3" json:"code,omitempty"`
+	// Error message of the response, if it failed.
+	Error string `protobuf:"bytes,8,opt,name=error,proto3" json:"error,omitempty"`
+	// Candidate is set if the write was staged in the candidate config, rather than made in the store.
+	Candidate bool `protobuf:"varint,9,opt,name=candidate,proto3" json:"candidate,omitempty"`
+	// DryRun is set if the write was a dry run, so it changed nothing.
+	DryRun               bool     `protobuf:"varint,10,opt,name=dry_run,json=dryRun,proto3" json:"dry_run,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AuditEvent) Reset()         { *m = AuditEvent{} }
+func (m *AuditEvent) String() string { return proto.CompactTextString(m) }
+func (*AuditEvent) ProtoMessage()    {}
+func (*AuditEvent) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2c0f90c600ad7e2e, []int{34}
+}
+
+func (m *AuditEvent) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AuditEvent.Unmarshal(m, b)
+}
+func (m *AuditEvent) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AuditEvent.Marshal(b, m, deterministic)
+}
+func (m *AuditEvent) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AuditEvent.Merge(m, src)
+}
+func (m *AuditEvent) XXX_Size() int {
+	return xxx_messageInfo_AuditEvent.Size(m)
+}
+func (m *AuditEvent) XXX_DiscardUnknown() {
+	xxx_messageInfo_AuditEvent.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AuditEvent proto.InternalMessageInfo
+
+func (m *AuditEvent) GetTime() *timestamp.Timestamp {
+	if m != nil {
+		return m.Time
+	}
+	return nil
+}
+
+func (m *AuditEvent) GetClient() string {
+	if m != nil {
+		return m.Client
+	}
+	return ""
+}
+
+func (m *AuditEvent) GetNode() string {
+	if m != nil {
+		return m.Node
+	}
+	return ""
+}
+
+func (m *AuditEvent) GetForwardedBy() string {
+	if m != nil {
+		return m.ForwardedBy
+	}
+	return ""
+}
+
+func (m *AuditEvent) GetMethod() string {
+	if m != nil {
+		return m.Method
+	}
+	return ""
+}
+
+func (m *AuditEvent) GetRequest() string {
+	if m != nil {
+		return m.Request
+	}
+	return ""
+}
+
+func (m *AuditEvent) GetCode() string {
+	if m != nil {
+		return m.Code
+	}
+	return ""
+}
+
+func (m *AuditEvent) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func (m *AuditEvent) GetCandidate() bool {
+	if m != nil {
+		return m.Candidate
+	}
+	return false
+}
+
+func (m *AuditEvent) GetDryRun() bool {
+	if m != nil {
+		return m.DryRun
+	}
+	return false
+}
+
+type ListAuditEventsRequest struct {
+	// Since only returns the events at or after this time, if set.
+	Since *timestamp.Timestamp `protobuf:"bytes,1,opt,name=since,proto3" json:"since,omitempty"`
+	// Limit is the most recent events to return, 0 for all.
+	Limit                uint32   `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListAuditEventsRequest) Reset()         { *m = ListAuditEventsRequest{} }
+func (m *ListAuditEventsRequest) String() string { return proto.CompactTextString(m) }
+func (*ListAuditEventsRequest) ProtoMessage()    {}
+func (*ListAuditEventsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2c0f90c600ad7e2e, []int{35}
+}
+
+func (m *ListAuditEventsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListAuditEventsRequest.Unmarshal(m, b)
+}
+func (m *ListAuditEventsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListAuditEventsRequest.Marshal(b, m, deterministic)
+}
+func (m *ListAuditEventsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListAuditEventsRequest.Merge(m, src)
+}
+func (m *ListAuditEventsRequest) XXX_Size() int {
+	return xxx_messageInfo_ListAuditEventsRequest.Size(m)
+}
+func (m *ListAuditEventsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListAuditEventsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListAuditEventsRequest proto.InternalMessageInfo
+
+func (m *ListAuditEventsRequest) GetSince() *timestamp.Timestamp {
+	if m != nil {
+		return m.Since
+	}
+	return nil
+}
+
+func (m *ListAuditEventsRequest) GetLimit() uint32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+type ListAuditEventsResponse struct {
+	Events               []*AuditEvent `protobuf:"bytes,1,rep,name=events,proto3" json:"events,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
+}
+
+func (m *ListAuditEventsResponse) Reset()         { *m = ListAuditEventsResponse{} }
+func (m *ListAuditEventsResponse) String() string { return proto.CompactTextString(m) }
+func (*ListAuditEventsResponse) ProtoMessage()    {}
+func (*ListAuditEventsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2c0f90c600ad7e2e, []int{36}
+}
+
+func (m *ListAuditEventsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListAuditEventsResponse.Unmarshal(m, b)
+}
+func (m *ListAuditEventsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListAuditEventsResponse.Marshal(b, m, deterministic)
+}
+func (m *ListAuditEventsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListAuditEventsResponse.Merge(m, src)
+}
+func (m *ListAuditEventsResponse) XXX_Size() int {
+	return xxx_messageInfo_ListAuditEventsResponse.Size(m)
+}
+func (m *ListAuditEventsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListAuditEventsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListAuditEventsResponse proto.InternalMessageInfo
+
+func (m *ListAuditEventsResponse) GetEvents() []*AuditEvent {
+	if m != nil {
+		return m.Events
+	}
+	return nil
+}
+
+type GetServiceResponse struct {
+	Service              *VirtualService `protobuf:"bytes,1,opt,name=service,proto3" json:"service,omitempty"`
+	Servers              []*RealServer   `protobuf:"bytes,2,rep,name=servers,proto3" json:"servers,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *GetServiceResponse) Reset()         { *m = GetServiceResponse{} }
+func (m *GetServiceResponse) String() string { return proto.CompactTextString(m) }
+func (*GetServiceResponse) ProtoMessage()    {}
+func (*GetServiceResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2c0f90c600ad7e2e, []int{37}
+}
+
+func (m *GetServiceResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetServiceResponse.Unmarshal(m, b)
+}
+func (m *GetServiceResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetServiceResponse.Marshal(b, m, deterministic)
+}
+func (m *GetServiceResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetServiceResponse.Merge(m, src)
+}
+func (m *GetServiceResponse) XXX_Size() int {
+	return xxx_messageInfo_GetServiceResponse.Size(m)
+}
+func (m *GetServiceResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetServiceResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetServiceResponse proto.InternalMessageInfo
+
+func (m *GetServiceResponse) GetService() *VirtualService {
+	if m != nil {
+		return m.Service
+	}
+	return nil
+}
+
+func (m *GetServiceResponse) GetServers() []*RealServer {
+	if m != nil {
+		return m.Servers
+	}
+	return nil
+}
+
+type WatchRequest struct {
+	// LabelSelector and FieldSelector filter the services watched, as in ListRequest.
+	LabelSelector        string   `protobuf:"bytes,1,opt,name=label_selector,json=labelSelector,proto3" json:"label_selector,omitempty"`
+	FieldSelector        string   `protobuf:"bytes,2,opt,name=field_selector,json=fieldSelector,proto3" json:"field_selector,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *WatchRequest) Reset()         { *m = WatchRequest{} }
+func (m *WatchRequest) String() string { return proto.CompactTextString(m) }
+func (*WatchRequest) ProtoMessage()    {}
+func (*WatchRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2c0f90c600ad7e2e, []int{38}
+}
+
+func (m *WatchRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WatchRequest.Unmarshal(m, b)
+}
+func (m *WatchRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WatchRequest.Marshal(b, m, deterministic)
+}
+func (m *WatchRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WatchRequest.Merge(m, src)
+}
+func (m *WatchRequest) XXX_Size() int {
+	return xxx_messageInfo_WatchRequest.Size(m)
+}
+func (m *WatchRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_WatchRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WatchRequest proto.InternalMessageInfo
+
+func (m *WatchRequest) GetLabelSelector() string {
+	if m != nil {
+		return m.LabelSelector
+	}
+	return ""
+}
+
+func (m *WatchRequest) GetFieldSelector() string {
+	if m != nil {
+		return m.FieldSelector
+	}
+	return ""
+}
+
+type WatchEvent struct {
+	// Changes since the last event. The first event creates every service and server being watched, so applying
+	// each event in turn keeps a copy of them up to date.
+	Changes []*Change `protobuf:"bytes,1,rep,name=changes,proto3" json:"changes,omitempty"`
+	// Initial is set on the first event.
+	Initial              bool     `protobuf:"varint,2,opt,name=initial,proto3" json:"initial,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *WatchEvent) Reset()         { *m = WatchEvent{} }
+func (m *WatchEvent) String() string { return proto.CompactTextString(m) }
+func (*WatchEvent) ProtoMessage()    {}
+func (*WatchEvent) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2c0f90c600ad7e2e, []int{39}
+}
+
+func (m *WatchEvent) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WatchEvent.Unmarshal(m, b)
+}
+func (m *WatchEvent) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WatchEvent.Marshal(b, m, deterministic)
+}
+func (m *WatchEvent) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WatchEvent.Merge(m, src)
+}
+func (m *WatchEvent) XXX_Size() int {
+	return xxx_messageInfo_WatchEvent.Size(m)
+}
+func (m *WatchEvent) XXX_DiscardUnknown() {
+	xxx_messageInfo_WatchEvent.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WatchEvent proto.InternalMessageInfo
+
+func (m *WatchEvent) GetChanges() []*Change {
+	if m != nil {
+		return m.Changes
+	}
+	return nil
+}
+
+func (m *WatchEvent) GetInitial() bool {
+	if m != nil {
+		return m.Initial
+	}
+	return false
+}
+
+type ApplyRequest struct {
+	// Changes to make in order. Each is made as by the RPC of its action, so updates are merged into the service or
+	// server as by UpdateService and UpdateServer, and deletes only need the service ID or server key.
+	Changes []*Change `protobuf:"bytes,1,rep,name=changes,proto3" json:"changes,omitempty"`
+	// DryRun validates the changes and returns their results, without modifying the store.
+	DryRun               bool     `protobuf:"varint,2,opt,name=dry_run,json=dryRun,proto3" json:"dry_run,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ApplyRequest) Reset()         { *m = ApplyRequest{} }
+func (m *ApplyRequest) String() string { return proto.CompactTextString(m) }
+func (*ApplyRequest) ProtoMessage()    {}
+func (*ApplyRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2c0f90c600ad7e2e, []int{40}
+}
+
+func (m *ApplyRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ApplyRequest.Unmarshal(m, b)
+}
+func (m *ApplyRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ApplyRequest.Marshal(b, m, deterministic)
+}
+func (m *ApplyRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ApplyRequest.Merge(m, src)
+}
+func (m *ApplyRequest) XXX_Size() int {
+	return xxx_messageInfo_ApplyRequest.Size(m)
+}
+func (m *ApplyRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ApplyRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ApplyRequest proto.InternalMessageInfo
+
+func (m *ApplyRequest) GetChanges() []*Change {
+	if m != nil {
+		return m.Changes
+	}
+	return nil
+}
+
+func (m *ApplyRequest) GetDryRun() bool {
+	if m != nil {
+		return m.DryRun
+	}
+	return false
+}
+
+// ApplyResult is the result of a change of an ApplyRequest.
+type ApplyResult struct {
+	// Change made, such as the service with an update merged into it, or unset if it failed.
+	Change *Change `protobuf:"bytes,1,opt,name=change,proto3" json:"change,omitempty"`
+	// Code of the change's status, e.g. OK or AlreadyExists.
+	Code string `protobuf:"bytes,2,opt,name=code,proto3" json:"code,omitempty"`
+	// Error message of the change, if it failed.
+	Error                string   `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ApplyResult) Reset()         { *m = ApplyResult{} }
+func (m *ApplyResult) String() string { return proto.CompactTextString(m) }
+func (*ApplyResult) ProtoMessage()    {}
+func (*ApplyResult) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2c0f90c600ad7e2e, []int{41}
+}
+
+func (m *ApplyResult) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ApplyResult.Unmarshal(m, b)
+}
+func (m *ApplyResult) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ApplyResult.Marshal(b, m, deterministic)
+}
+func (m *ApplyResult) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ApplyResult.Merge(m, src)
+}
+func (m *ApplyResult) XXX_Size() int {
+	return xxx_messageInfo_ApplyResult.Size(m)
+}
+func (m *ApplyResult) XXX_DiscardUnknown() {
+	xxx_messageInfo_ApplyResult.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ApplyResult proto.InternalMessageInfo
+
+func (m *ApplyResult) GetChange() *Change {
+	if m != nil {
+		return m.Change
+	}
+	return nil
+}
+
+func (m *ApplyResult) GetCode() string {
+	if m != nil {
+		return m.Code
+	}
+	return ""
+}
+
+func (m *ApplyResult) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+type ApplyResponse struct {
+	// Results of each change, in the order of the request.
+	Results              []*ApplyResult `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
+}
+
+func (m *ApplyResponse) Reset()         { *m = ApplyResponse{} }
+func (m *ApplyResponse) String() string { return proto.CompactTextString(m) }
+func (*ApplyResponse) ProtoMessage()    {}
+func (*ApplyResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2c0f90c600ad7e2e, []int{42}
+}
+
+func (m *ApplyResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ApplyResponse.Unmarshal(m, b)
+}
+func (m *ApplyResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ApplyResponse.Marshal(b, m, deterministic)
+}
+func (m *ApplyResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ApplyResponse.Merge(m, src)
+}
+func (m *ApplyResponse) XXX_Size() int {
+	return xxx_messageInfo_ApplyResponse.Size(m)
+}
+func (m *ApplyResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ApplyResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ApplyResponse proto.InternalMessageInfo
+
+func (m *ApplyResponse) GetResults() []*ApplyResult {
+	if m != nil {
+		return m.Results
+	}
+	return nil
+}
+
+// SyncDaemon is an IPVS connection sync daemon. The MASTER multicasts its connections to the BACKUP nodes with the
+// same sync ID, so a backup which takes over the VIPs keeps the established connections.
+type SyncDaemon struct {
+	Role SyncDaemon_Role `protobuf:"varint,1,opt,name=role,proto3,enum=types.SyncDaemon_Role" json:"role,omitempty"`
+	// Interface the connections are multicast on, e.g. eth0.
+	Interface string `protobuf:"bytes,2,opt,name=interface,proto3" json:"interface,omitempty"`
+	// SyncID from 0 to 255 pairs a master with its backups, so several pairs can share a network.
+	SyncId               uint32   `protobuf:"varint,3,opt,name=sync_id,json=syncId,proto3" json:"sync_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SyncDaemon) Reset()         { *m = SyncDaemon{} }
+func (m *SyncDaemon) String() string { return proto.CompactTextString(m) }
+func (*SyncDaemon) ProtoMessage()    {}
+func (*SyncDaemon) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2c0f90c600ad7e2e, []int{43}
+}
+
+func (m *SyncDaemon) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SyncDaemon.Unmarshal(m, b)
+}
+func (m *SyncDaemon) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SyncDaemon.Marshal(b, m, deterministic)
+}
+func (m *SyncDaemon) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SyncDaemon.Merge(m, src)
+}
+func (m *SyncDaemon) XXX_Size() int {
+	return xxx_messageInfo_SyncDaemon.Size(m)
+}
+func (m *SyncDaemon) XXX_DiscardUnknown() {
+	xxx_messageInfo_SyncDaemon.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SyncDaemon proto.InternalMessageInfo
+
+func (m *SyncDaemon) GetRole() SyncDaemon_Role {
+	if m != nil {
+		return m.Role
+	}
+	return SyncDaemon_UNSET_SYNC_ROLE
+}
+
+func (m *SyncDaemon) GetInterface() string {
+	if m != nil {
+		return m.Interface
+	}
+	return ""
+}
+
+func (m *SyncDaemon) GetSyncId() uint32 {
+	if m != nil {
+		return m.SyncId
+	}
+	return 0
+}
+
+type SyncDaemons struct {
+	// Node the sync daemons are running on.
+	Node                 string        `protobuf:"bytes,1,opt,name=node,proto3" json:"node,omitempty"`
+	Daemons              []*SyncDaemon `protobuf:"bytes,2,rep,name=daemons,proto3" json:"daemons,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
+}
+
+func (m *SyncDaemons) Reset()         { *m = SyncDaemons{} }
+func (m *SyncDaemons) String() string { return proto.CompactTextString(m) }
+func (*SyncDaemons) ProtoMessage()    {}
+func (*SyncDaemons) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2c0f90c600ad7e2e, []int{44}
+}
+
+func (m *SyncDaemons) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SyncDaemons.Unmarshal(m, b)
+}
+func (m *SyncDaemons) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SyncDaemons.Marshal(b, m, deterministic)
+}
+func (m *SyncDaemons) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SyncDaemons.Merge(m, src)
+}
+func (m *SyncDaemons) XXX_Size() int {
+	return xxx_messageInfo_SyncDaemons.Size(m)
+}
+func (m *SyncDaemons) XXX_DiscardUnknown() {
+	xxx_messageInfo_SyncDaemons.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SyncDaemons proto.InternalMessageInfo
+
+func (m *SyncDaemons) GetNode() string {
+	if m != nil {
+		return m.Node
+	}
+	return ""
+}
+
+func (m *SyncDaemons) GetDaemons() []*SyncDaemon {
+	if m != nil {
+		return m.Daemons
+	}
+	return nil
+}
+
+type SetSyncRoleRequest struct {
+	Role                 SyncDaemon_Role `protobuf:"varint,1,opt,name=role,proto3,enum=types.SyncDaemon_Role" json:"role,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *SetSyncRoleRequest) Reset()         { *m = SetSyncRoleRequest{} }
+func (m *SetSyncRoleRequest) String() string { return proto.CompactTextString(m) }
+func (*SetSyncRoleRequest) ProtoMessage()    {}
+func (*SetSyncRoleRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2c0f90c600ad7e2e, []int{45}
+}
+
+func (m *SetSyncRoleRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SetSyncRoleRequest.Unmarshal(m, b)
+}
+func (m *SetSyncRoleRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SetSyncRoleRequest.Marshal(b, m, deterministic)
+}
+func (m *SetSyncRoleRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SetSyncRoleRequest.Merge(m, src)
+}
+func (m *SetSyncRoleRequest) XXX_Size() int {
+	return xxx_messageInfo_SetSyncRoleRequest.Size(m)
+}
+func (m *SetSyncRoleRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SetSyncRoleRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SetSyncRoleRequest proto.InternalMessageInfo
+
+func (m *SetSyncRoleRequest) GetRole() SyncDaemon_Role {
+	if m != nil {
+		return m.Role
+	}
+	return SyncDaemon_UNSET_SYNC_ROLE
+}
+
+func init() {
+	proto.RegisterEnum("types.Protocol", Protocol_name, Protocol_value)
+	proto.RegisterEnum("types.ForwardMethod", ForwardMethod_name, ForwardMethod_value)
+	proto.RegisterEnum("types.TunnelType", TunnelType_name, TunnelType_value)
+	proto.RegisterEnum("types.TunnelChecksum", TunnelChecksum_name, TunnelChecksum_value)
+	proto.RegisterEnum("types.Health", Health_name, Health_value)
+	proto.RegisterEnum("types.Rollout_State", Rollout_State_name, Rollout_State_value)
+	proto.RegisterEnum("types.Change_Action", Change_Action_name, Change_Action_value)
+	proto.RegisterEnum("types.SyncDaemon_Role", SyncDaemon_Role_name, SyncDaemon_Role_value)
+	proto.RegisterType((*VirtualService)(nil), "types.VirtualService")
+	proto.RegisterMapType((map[string]string)(nil), "types.VirtualService.LabelsEntry")
+	proto.RegisterType((*VirtualService_Key)(nil), "types.VirtualService.Key")
+	proto.RegisterType((*VirtualService_Config)(nil), "types.VirtualService.Config")
+	proto.RegisterType((*Rollout)(nil), "types.Rollout")
+	proto.RegisterType((*RealServer)(nil), "types.RealServer")
+	proto.RegisterMapType((map[string]string)(nil), "types.RealServer.LabelsEntry")
+	proto.RegisterType((*RealServer_Key)(nil), "types.RealServer.Key")
+	proto.RegisterType((*RealServer_Config)(nil), "types.RealServer.Config")
+	proto.RegisterType((*RealServer_HealthCheck)(nil), "types.RealServer.HealthCheck")
+	proto.RegisterType((*CloneServiceRequest)(nil), "types.CloneServiceRequest")
+	proto.RegisterType((*RenameServiceRequest)(nil), "types.RenameServiceRequest")
+	proto.RegisterType((*SwapServersRequest)(nil), "types.SwapServersRequest")
+	proto.RegisterType((*ListRequest)(nil), "types.ListRequest")
+	proto.RegisterType((*ListResponse)(nil), "types.ListResponse")
+	proto.RegisterType((*ListResponse_Item)(nil), "types.ListResponse.Item")
+	proto.RegisterType((*Stats)(nil), "types.Stats")
+	proto.RegisterType((*ServerStats)(nil), "types.ServerStats")
+	proto.RegisterType((*ServiceStats)(nil), "types.ServiceStats")
+	proto.RegisterType((*StatsResponse)(nil), "types.StatsResponse")
+	proto.RegisterType((*GetServerStatsResponse)(nil), "types.GetServerStatsResponse")
+	proto.RegisterType((*ListConnectionsRequest)(nil), "types.ListConnectionsRequest")
+	proto.RegisterType((*Connection)(nil), "types.Connection")
+	proto.RegisterType((*NodeState)(nil), "types.NodeState")
+	proto.RegisterType((*NodeState_Server)(nil), "types.NodeState.Server")
+	proto.RegisterType((*NodeState_Service)(nil), "types.NodeState.Service")
+	proto.RegisterType((*Snapshot)(nil), "types.Snapshot")
+	proto.RegisterType((*ApplySnapshotRequest)(nil), "types.ApplySnapshotRequest")
+	proto.RegisterType((*Change)(nil), "types.Change")
+	proto.RegisterType((*ApplySnapshotResponse)(nil), "types.ApplySnapshotResponse")
+	proto.RegisterType((*HistoryEntry)(nil), "types.HistoryEntry")
+	proto.RegisterType((*DescribeServiceRequest)(nil), "types.DescribeServiceRequest")
+	proto.RegisterType((*DescribeServiceResponse)(nil), "types.DescribeServiceResponse")
+	proto.RegisterType((*DescribeServiceResponse_Server)(nil), "types.DescribeServiceResponse.Server")
+	proto.RegisterType((*Node)(nil), "types.Node")
+	proto.RegisterMapType((map[string]string)(nil), "types.Node.LabelsEntry")
+	proto.RegisterType((*InfoResponse)(nil), "types.InfoResponse")
+	proto.RegisterType((*ListNodesResponse)(nil), "types.ListNodesResponse")
+	proto.RegisterType((*SetMaintenanceRequest)(nil), "types.SetMaintenanceRequest")
+	proto.RegisterType((*RolloutServiceRequest)(nil), "types.RolloutServiceRequest")
+	proto.RegisterType((*VIPPool)(nil), "types.VIPPool")
+	proto.RegisterType((*ListPoolsResponse)(nil), "types.ListPoolsResponse")
+	proto.RegisterType((*ListPoolsResponse_Pool)(nil), "types.ListPoolsResponse.Pool")
+	proto.RegisterType((*Candidate)(nil), "types.Candidate")
+	proto.RegisterType((*GetCandidateResponse)(nil), "types.GetCandidateResponse")
+	proto.RegisterType((*CommitRequest)(nil), "types.CommitRequest")
+	proto.RegisterType((*CommitResponse)(nil), "types.CommitResponse")
+	proto.RegisterType((*AuditEvent)(nil), "types.AuditEvent")
+	proto.RegisterType((*ListAuditEventsRequest)(nil), "types.ListAuditEventsRequest")
+	proto.RegisterType((*ListAuditEventsResponse)(nil), "types.ListAuditEventsResponse")
+	proto.RegisterType((*GetServiceResponse)(nil), "types.GetServiceResponse")
+	proto.RegisterType((*WatchRequest)(nil), "types.WatchRequest")
+	proto.RegisterType((*WatchEvent)(nil), "types.WatchEvent")
+	proto.RegisterType((*ApplyRequest)(nil), "types.ApplyRequest")
+	proto.RegisterType((*ApplyResult)(nil), "types.ApplyResult")
+	proto.RegisterType((*ApplyResponse)(nil), "types.ApplyResponse")
+	proto.RegisterType((*SyncDaemon)(nil), "types.SyncDaemon")
+	proto.RegisterType((*SyncDaemons)(nil), "types.SyncDaemons")
+	proto.RegisterType((*SetSyncRoleRequest)(nil), "types.SetSyncRoleRequest")
+}
+
+func init() { proto.RegisterFile("types/types.proto", fileDescriptor_2c0f90c600ad7e2e) }
+
+var fileDescriptor_2c0f90c600ad7e2e = []byte{
+	// 3624 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x3a, 0xdb, 0x72, 0xdb, 0xc8,
+	0x72, 0xbc, 0x5f, 0x9a, 0x17, 0xd1, 0x23, 0xc9, 0xe6, 0x72, 0x7d, 0x91, 0x91, 0x72, 0xec, 0xb5,
+	0x77, 0x65, 0x5b, 0xf2, 0xc9, 0xb1, 0x77, 0x73, 0xbc, 0xa6, 0x49, 0xda, 0x52, 0x59, 0x12, 0x15,
+	0x90, 0xb2, 0x6b, 0x93, 0x54, 0x18, 0x08, 0x18, 0x49, 0x88, 0x41, 0x00, 0x01, 0x40, 0xdb, 0xdc,
+	0xd7, 0xa4, 0xf2, 0x9e, 0xa4, 0x2a, 0x6f, 0x49, 0x6d, 0xa5, 0xf2, 0x09, 0xa9, 0xbc, 0x27, 0x9f,
+	0x90, 0xa7, 0x7c, 0x42, 0xaa, 0xf2, 0x09, 0x79, 0x39, 0xd5, 0x73, 0x01, 0xc0, 0xab, 0x24, 0xbb,
+	0xf6, 0x85, 0x85, 0xe9, 0xe9, 0xee, 0xe9, 0xe9, 0xdb, 0x74, 0xcf, 0x10, 0xae, 0x04, 0x63, 0x97,
+	0xfa, 0x0f, 0xd9, 0xef, 0xa6, 0xeb, 0x39, 0x81, 0x43, 0xb2, 0x6c, 0xd0, 0xf8, 0xfa, 0xd4, 0x71,
+	0x4e, 0x2d, 0xfa, 0x90, 0x01, 0x8f, 0x47, 0x27, 0x0f, 0xe9, 0xd0, 0x0d, 0xc6, 0x1c, 0xa7, 0x71,
+	0x73, 0x7a, 0xf2, 0xa3, 0xa7, 0xb9, 0x2e, 0xf5, 0xfc, 0x45, 0xf3, 0xc6, 0xc8, 0xd3, 0x02, 0xd3,
+	0xb1, 0xc5, 0xfc, 0xad, 0xe9, 0xf9, 0xc0, 0x1c, 0x52, 0x3f, 0xd0, 0x86, 0xae, 0x40, 0xd8, 0x98,
+	0x46, 0x38, 0x31, 0xa9, 0x65, 0x0c, 0x86, 0x9a, 0xff, 0x9e, 0x63, 0x28, 0xff, 0x99, 0x81, 0xea,
+	0x5b, 0xd3, 0x0b, 0x46, 0x9a, 0xd5, 0xa3, 0xde, 0x07, 0x53, 0xa7, 0xa4, 0x0a, 0x29, 0xd3, 0xa8,
+	0x27, 0x37, 0x92, 0xf7, 0x8a, 0x6a, 0xca, 0x34, 0xc8, 0x03, 0x48, 0xbf, 0xa7, 0xe3, 0x7a, 0x6a,
+	0x23, 0x79, 0xaf, 0xb4, 0xf5, 0xd5, 0x26, 0xdf, 0xe4, 0x24, 0xcd, 0xe6, 0x1b, 0x3a, 0x56, 0x11,
+	0x8b, 0x3c, 0x81, 0x9c, 0xee, 0xd8, 0x27, 0xe6, 0x69, 0x3d, 0xcd, 0xf0, 0xaf, 0xcf, 0xc7, 0x6f,
+	0x31, 0x1c, 0x55, 0xe0, 0x92, 0x67, 0x90, 0xb3, 0xb4, 0x63, 0x6a, 0xf9, 0xf5, 0xcc, 0x46, 0xfa,
+	0x5e, 0x69, 0xeb, 0xf6, 0x7c, 0xaa, 0x3d, 0x86, 0xd3, 0xb1, 0x03, 0x6f, 0xac, 0x0a, 0x02, 0xf2,
+	0x07, 0x50, 0xb1, 0x1d, 0x83, 0x0e, 0x7c, 0x6a, 0x51, 0x3d, 0x70, 0xbc, 0x7a, 0x96, 0x09, 0x5e,
+	0x46, 0x60, 0x4f, 0xc0, 0xc8, 0x3d, 0xc8, 0x7b, 0x8e, 0x65, 0x39, 0xa3, 0xa0, 0x9e, 0x63, 0x62,
+	0x55, 0xc5, 0x02, 0x2a, 0x87, 0xaa, 0x72, 0x9a, 0x10, 0xc8, 0xb8, 0x8e, 0x63, 0xd5, 0xf3, 0x8c,
+	0x0b, 0xfb, 0x26, 0x3f, 0x40, 0x69, 0xe4, 0x1a, 0x5a, 0x40, 0x99, 0xe2, 0xea, 0x05, 0xc6, 0xa1,
+	0xb1, 0xc9, 0x75, 0xbb, 0x29, 0x75, 0xbb, 0xf9, 0x0a, 0x75, 0xbb, 0xaf, 0xf9, 0xef, 0x55, 0xe0,
+	0xe8, 0xf8, 0xdd, 0x78, 0x0b, 0xe9, 0x37, 0x74, 0xcc, 0x94, 0xea, 0x86, 0x4a, 0x75, 0xf9, 0x3a,
+	0x5e, 0xc0, 0xb4, 0x5a, 0x51, 0xd9, 0x37, 0x79, 0x00, 0x05, 0xc6, 0x4c, 0x77, 0x2c, 0xa6, 0xbd,
+	0xea, 0xd6, 0x8a, 0x10, 0xf3, 0x50, 0x80, 0xd5, 0x10, 0xa1, 0x71, 0x00, 0x39, 0xae, 0x44, 0x72,
+	0x1d, 0x8a, 0xbe, 0x7e, 0x46, 0x8d, 0x91, 0x45, 0x3d, 0xb1, 0x42, 0x04, 0x20, 0x6b, 0x90, 0x3d,
+	0xb1, 0xb4, 0x53, 0xbf, 0x9e, 0xda, 0x48, 0xdf, 0x2b, 0xaa, 0x7c, 0x40, 0x6a, 0x90, 0x76, 0x5c,
+	0x9f, 0xad, 0x52, 0x50, 0xf1, 0xb3, 0xf1, 0x0c, 0x4a, 0x31, 0xf5, 0x92, 0x1a, 0x37, 0x3a, 0x67,
+	0x87, 0x9f, 0xc8, 0xe8, 0x83, 0x66, 0x8d, 0x28, 0x13, 0xb9, 0xa8, 0xf2, 0xc1, 0xf7, 0xa9, 0xa7,
+	0x49, 0xe5, 0x3f, 0xd2, 0x90, 0x17, 0x8a, 0x8c, 0xd9, 0x3f, 0x79, 0x09, 0xfb, 0xdf, 0x86, 0xb2,
+	0xae, 0xd9, 0x9a, 0x37, 0x1e, 0xa0, 0xd9, 0xa4, 0xac, 0x25, 0x0e, 0x3b, 0x40, 0x10, 0xb9, 0x0f,
+	0x59, 0x3f, 0xd0, 0x02, 0x2a, 0x34, 0xb3, 0x36, 0x69, 0xc0, 0xcd, 0x1e, 0xce, 0xa9, 0x1c, 0x85,
+	0x3c, 0x81, 0xbc, 0x1f, 0x68, 0x5e, 0x40, 0x8d, 0x7a, 0x66, 0x81, 0xb1, 0xfa, 0x32, 0x52, 0x54,
+	0x89, 0x4a, 0x9e, 0x42, 0x51, 0x77, 0xec, 0x0f, 0xd4, 0x3b, 0xa5, 0x46, 0x3d, 0x7b, 0x2e, 0x5d,
+	0x84, 0x4c, 0xbe, 0x83, 0xcc, 0xb1, 0xf6, 0x9e, 0x0a, 0xdf, 0xfa, 0x6a, 0x86, 0xa8, 0x2d, 0xc2,
+	0x56, 0x65, 0x68, 0x64, 0x1b, 0xf2, 0x18, 0xa8, 0xe8, 0x8d, 0xf9, 0xf3, 0x28, 0x24, 0x26, 0xa9,
+	0x43, 0x7e, 0x48, 0x7d, 0x5f, 0x3b, 0xa5, 0xcc, 0x01, 0x8b, 0xaa, 0x1c, 0x2a, 0x4f, 0x21, 0xcb,
+	0x76, 0x4f, 0xae, 0xc1, 0xea, 0xd1, 0x41, 0xaf, 0xd3, 0x1f, 0xa8, 0xdd, 0xbd, 0xbd, 0xee, 0x51,
+	0x7f, 0xd0, 0xeb, 0x37, 0xfb, 0x9d, 0x5a, 0x82, 0x00, 0xe4, 0x5a, 0xcd, 0x83, 0xa6, 0xfa, 0x53,
+	0x2d, 0x89, 0xdf, 0x3b, 0xcd, 0xbd, 0x7e, 0xa7, 0x5d, 0x4b, 0x29, 0x7f, 0x5f, 0x00, 0x50, 0x29,
+	0xb7, 0x0a, 0xf5, 0x98, 0x23, 0x71, 0xfb, 0xec, 0xb6, 0x43, 0x47, 0x92, 0x00, 0x72, 0x37, 0x9e,
+	0x06, 0xd6, 0xa5, 0xfa, 0x43, 0xea, 0x28, 0x05, 0x3c, 0x9a, 0x4a, 0x01, 0xf5, 0x59, 0xdc, 0x29,
+	0xf3, 0xbf, 0x80, 0xf2, 0x19, 0xd5, 0xac, 0xe0, 0x6c, 0xa0, 0x9f, 0x51, 0xfd, 0xbd, 0x30, 0xda,
+	0x8d, 0x59, 0xba, 0x1d, 0x86, 0xd5, 0x42, 0x24, 0xb5, 0x74, 0x16, 0x0d, 0x48, 0x0b, 0xaa, 0x86,
+	0xa7, 0x99, 0x36, 0x35, 0x06, 0x1f, 0xa9, 0x79, 0x7a, 0x16, 0x08, 0x03, 0x5e, 0x9f, 0xd1, 0xec,
+	0xd1, 0xae, 0x1d, 0x6c, 0x6f, 0xbd, 0x45, 0xe7, 0x55, 0x2b, 0x82, 0xe6, 0x1d, 0x23, 0x99, 0x8e,
+	0xf3, 0xdc, 0x65, 0xe2, 0x9c, 0xfc, 0x26, 0x4c, 0x61, 0xf9, 0x8d, 0xf4, 0x7c, 0xe9, 0xe7, 0xa4,
+	0xaf, 0xc6, 0x37, 0x17, 0x4e, 0x0f, 0x8d, 0xbf, 0x49, 0x85, 0x21, 0xff, 0x04, 0x72, 0x62, 0x9b,
+	0xc9, 0x0b, 0x6c, 0x53, 0xe0, 0x92, 0x4d, 0xc8, 0x9f, 0x38, 0xde, 0x47, 0xcd, 0x33, 0xea, 0xa9,
+	0x89, 0x20, 0x7a, 0xc5, 0xa1, 0xfb, 0x34, 0x38, 0x73, 0x0c, 0x55, 0x22, 0x91, 0x2d, 0x28, 0x05,
+	0x23, 0xdb, 0xa6, 0xd6, 0x00, 0xd1, 0x44, 0xe0, 0x5d, 0x11, 0x34, 0x7d, 0x36, 0xd3, 0x1f, 0xbb,
+	0x54, 0x85, 0x20, 0xfc, 0x26, 0xb7, 0x42, 0x1a, 0x26, 0x7f, 0x86, 0xc9, 0x2f, 0x10, 0x0e, 0x31,
+	0xc9, 0x3d, 0x87, 0x15, 0x81, 0xc0, 0x6c, 0xed, 0x8f, 0x86, 0xcc, 0x54, 0xd5, 0xad, 0xf5, 0x09,
+	0xc6, 0x2d, 0x31, 0xa9, 0x56, 0x83, 0x89, 0x71, 0xe3, 0x5f, 0x53, 0x50, 0x8a, 0xb9, 0x01, 0x79,
+	0x0a, 0x05, 0x6a, 0x1b, 0xae, 0x63, 0xda, 0x8b, 0x95, 0xd1, 0x0b, 0x3c, 0xd3, 0x3e, 0xe5, 0xca,
+	0x08, 0xb1, 0xc9, 0x63, 0xc8, 0xb9, 0xd4, 0x33, 0x1d, 0x23, 0x3c, 0xda, 0x16, 0x46, 0xa1, 0x40,
+	0x8c, 0x47, 0x6e, 0xfa, 0xc2, 0x91, 0x7b, 0x1b, 0xca, 0x23, 0x77, 0x10, 0x9c, 0x79, 0xd4, 0x3f,
+	0x73, 0x2c, 0x43, 0xe8, 0xa4, 0x34, 0x72, 0xfb, 0x12, 0x44, 0xee, 0x40, 0xd5, 0x70, 0x3e, 0xda,
+	0x31, 0xa4, 0x2c, 0x43, 0xaa, 0x20, 0x34, 0x42, 0xbb, 0x0b, 0x2b, 0xf4, 0x93, 0x4b, 0xf5, 0x80,
+	0x1a, 0x03, 0xcc, 0x74, 0x23, 0x9f, 0x39, 0x69, 0x45, 0xad, 0x4a, 0x70, 0x8f, 0x41, 0xbf, 0x24,
+	0x99, 0x9b, 0xb0, 0xda, 0xb2, 0x1c, 0x9b, 0x8a, 0x4c, 0xad, 0xd2, 0xbf, 0x1e, 0x51, 0x3f, 0x98,
+	0x29, 0x0a, 0xd6, 0x21, 0x67, 0xd3, 0x8f, 0x03, 0xd3, 0x90, 0x1c, 0x6c, 0xfa, 0x71, 0x37, 0xac,
+	0x15, 0xd2, 0x17, 0xa9, 0x15, 0x94, 0xdf, 0xc1, 0x9a, 0x4a, 0x6d, 0x6d, 0xf8, 0x79, 0x6b, 0x29,
+	0x3f, 0x02, 0xe9, 0x7d, 0xd4, 0x5c, 0x1e, 0x5c, 0xfe, 0x22, 0xe2, 0xaf, 0xa0, 0xe0, 0x04, 0x67,
+	0xd4, 0x8b, 0xc8, 0xf3, 0x6c, 0xbc, 0x6b, 0x28, 0xff, 0x9b, 0x84, 0xd2, 0x9e, 0xe9, 0x07, 0x92,
+	0xf4, 0x0e, 0x54, 0x59, 0x54, 0x46, 0xb5, 0x04, 0x67, 0x53, 0x61, 0xd0, 0xb0, 0x98, 0xb8, 0x03,
+	0x55, 0x5e, 0x46, 0x85, 0x68, 0x9c, 0x6f, 0x85, 0x41, 0x43, 0xb4, 0xaf, 0xa1, 0xe8, 0x6a, 0xa7,
+	0x74, 0xe0, 0x9b, 0x3f, 0xf3, 0xd8, 0xc9, 0xaa, 0x05, 0x04, 0xf4, 0xcc, 0x9f, 0x29, 0xb9, 0x01,
+	0xc0, 0x26, 0x03, 0xe7, 0x3d, 0xb5, 0x99, 0x47, 0x14, 0x55, 0x86, 0xde, 0x47, 0x00, 0xd2, 0x9a,
+	0xc6, 0xc0, 0xf5, 0xe8, 0x89, 0xf9, 0x49, 0x14, 0x34, 0x05, 0xd3, 0x38, 0x64, 0x63, 0xb2, 0x05,
+	0xeb, 0x3e, 0xdb, 0xf3, 0x60, 0x4a, 0xda, 0x1c, 0x43, 0x5c, 0xe5, 0x93, 0x7b, 0x71, 0x99, 0x95,
+	0xff, 0x4b, 0x42, 0x99, 0x6f, 0xd5, 0x77, 0x1d, 0xdb, 0xa7, 0x64, 0x13, 0xb2, 0x66, 0x40, 0x87,
+	0x7e, 0x3d, 0xb9, 0x91, 0x8e, 0xe5, 0xe8, 0x38, 0xce, 0xe6, 0x6e, 0x40, 0x87, 0x2a, 0x47, 0x23,
+	0x7f, 0x08, 0x2b, 0x36, 0xfd, 0x14, 0x0c, 0x62, 0x52, 0x8b, 0x5d, 0x23, 0xf8, 0x30, 0x94, 0xfc,
+	0x06, 0x40, 0xe0, 0x04, 0x9a, 0x15, 0xdf, 0x76, 0x91, 0x41, 0x70, 0xdf, 0x0d, 0x03, 0x32, 0xc8,
+	0x95, 0x3c, 0x84, 0xbc, 0x38, 0x59, 0x44, 0xd0, 0xae, 0xcf, 0xf5, 0x15, 0x55, 0x62, 0x91, 0x07,
+	0x9c, 0x80, 0x7a, 0xbc, 0x38, 0x28, 0x6d, 0x5d, 0x99, 0xc9, 0xaf, 0xaa, 0xc4, 0x50, 0xfe, 0x31,
+	0xc5, 0x8f, 0x44, 0x9f, 0x6c, 0x40, 0x49, 0x77, 0x6c, 0x9b, 0xea, 0x18, 0x92, 0x3e, 0x5b, 0x2b,
+	0xa3, 0xc6, 0x41, 0xdc, 0x12, 0xfa, 0x7b, 0x1a, 0xf8, 0x03, 0x93, 0xef, 0x29, 0xa3, 0x16, 0x05,
+	0x64, 0xd7, 0xc6, 0x7c, 0x26, 0xa7, 0x65, 0xd4, 0x67, 0x54, 0x49, 0xd1, 0x1d, 0x05, 0xe8, 0x5f,
+	0xc7, 0xe3, 0x80, 0x32, 0xea, 0x0c, 0x9b, 0xcd, 0xb3, 0xf1, 0x2e, 0xb3, 0x22, 0x9f, 0x42, 0xca,
+	0x2c, 0x9b, 0xe3, 0xb8, 0x48, 0x57, 0x83, 0xb4, 0xee, 0xf2, 0xf8, 0xcd, 0xa8, 0xf8, 0x89, 0x6e,
+	0xee, 0xba, 0x8c, 0x4f, 0x9e, 0x01, 0xb3, 0xae, 0x8b, 0x5c, 0xae, 0x41, 0xde, 0x75, 0x39, 0x8f,
+	0x02, 0x83, 0x23, 0x16, 0x72, 0x58, 0x87, 0xdc, 0x31, 0xc7, 0x2f, 0x72, 0xfc, 0x63, 0x89, 0x7f,
+	0x2c, 0xf0, 0x81, 0xe3, 0x1f, 0x33, 0x7c, 0xe5, 0xff, 0x93, 0x50, 0xe2, 0x9a, 0xe2, 0xba, 0xb9,
+	0x1b, 0x65, 0x85, 0xe5, 0x07, 0xfa, 0xd5, 0xf0, 0xb4, 0xe1, 0xc7, 0x91, 0x18, 0x91, 0xef, 0x80,
+	0x68, 0x7a, 0x60, 0x7e, 0xa0, 0x83, 0xb8, 0x8e, 0xd3, 0x0c, 0xe7, 0x0a, 0x9f, 0x69, 0x45, 0x13,
+	0xe4, 0x31, 0xac, 0x99, 0xf6, 0x1c, 0x02, 0x9e, 0x0f, 0x57, 0x4d, 0x7b, 0x96, 0x44, 0xe1, 0x45,
+	0x9f, 0x2f, 0x4e, 0xf3, 0xb2, 0x10, 0x92, 0xc9, 0xcf, 0x8b, 0x3d, 0x9f, 0xdc, 0x81, 0x1c, 0xaf,
+	0x04, 0x98, 0x2e, 0xab, 0x5b, 0x15, 0x81, 0xc4, 0x0f, 0x09, 0x55, 0x4c, 0x2a, 0xff, 0x9c, 0x84,
+	0xb2, 0xf0, 0x2a, 0xbe, 0xfd, 0x2f, 0x6a, 0x73, 0x42, 0xc1, 0xd2, 0x8b, 0x05, 0xfb, 0x36, 0x72,
+	0x59, 0xde, 0xd5, 0x10, 0x89, 0x15, 0x19, 0x21, 0xf2, 0xd9, 0x3e, 0x54, 0x38, 0x44, 0x46, 0x28,
+	0x81, 0x0c, 0x16, 0xc3, 0x42, 0x42, 0xf6, 0x4d, 0x1e, 0x42, 0x41, 0x04, 0x84, 0x0c, 0x83, 0xd5,
+	0x18, 0x4f, 0xb9, 0x35, 0x35, 0x44, 0x52, 0xfe, 0x0c, 0xae, 0xbe, 0xa6, 0x41, 0x7c, 0xc1, 0x65,
+	0xec, 0xbf, 0x8b, 0xa2, 0x92, 0xab, 0x61, 0x2e, 0x77, 0x89, 0xa3, 0x9c, 0xc0, 0x55, 0xcc, 0x17,
+	0x31, 0x83, 0xc9, 0x4c, 0x7a, 0x03, 0x40, 0x20, 0x0d, 0x42, 0x1d, 0x87, 0xa5, 0x24, 0xd6, 0xcb,
+	0x39, 0xbe, 0xed, 0xe5, 0xd5, 0xa4, 0x40, 0x52, 0xfe, 0x3d, 0x05, 0x10, 0x2d, 0x72, 0x1e, 0xf3,
+	0xed, 0xe9, 0x4d, 0x2c, 0xb1, 0xa5, 0xc4, 0xc4, 0x50, 0xd5, 0x2d, 0x93, 0xda, 0xc1, 0xc0, 0x74,
+	0x99, 0x4d, 0x8b, 0x6a, 0x81, 0x03, 0x76, 0x5d, 0xcc, 0x01, 0x62, 0x32, 0x5e, 0xd3, 0x70, 0x10,
+	0xab, 0x69, 0xa2, 0xfd, 0x64, 0x2f, 0xb0, 0x1f, 0x3c, 0x7c, 0x79, 0x2b, 0xc3, 0x13, 0x36, 0x1f,
+	0xa0, 0xdc, 0xf4, 0x93, 0x6b, 0x7a, 0xd4, 0xbf, 0x40, 0x57, 0x20, 0x30, 0x49, 0x03, 0x0a, 0x01,
+	0x1d, 0xba, 0x16, 0x72, 0x2b, 0xb0, 0x66, 0x2e, 0x1c, 0x2b, 0xbf, 0xa4, 0xa0, 0x88, 0xbd, 0x13,
+	0x6f, 0x0e, 0xe6, 0xd9, 0xfb, 0xc9, 0x8c, 0x3b, 0xc9, 0x73, 0x20, 0xa4, 0x93, 0xa6, 0x8f, 0x7c,
+	0xaa, 0xf1, 0xa7, 0x90, 0x13, 0x0d, 0xc3, 0x37, 0xe1, 0xbe, 0x79, 0x12, 0x99, 0x93, 0x93, 0xe5,
+	0x9e, 0xa3, 0x28, 0x4d, 0x2d, 0x89, 0xd2, 0xc6, 0x10, 0xf2, 0x62, 0xc1, 0xcb, 0x1f, 0x11, 0x8f,
+	0xa7, 0x8f, 0x88, 0x6b, 0x73, 0x37, 0x13, 0x3f, 0x28, 0xfe, 0x0a, 0x0a, 0x3d, 0x5b, 0x73, 0xfd,
+	0x33, 0x07, 0xcb, 0xc1, 0x48, 0x19, 0xfc, 0x50, 0x5c, 0xb0, 0x60, 0x88, 0x76, 0xb9, 0x43, 0xc9,
+	0x83, 0xb5, 0xa6, 0xeb, 0x5a, 0x63, 0xb9, 0xa0, 0x8c, 0x95, 0x07, 0x50, 0xf0, 0x05, 0x48, 0x6c,
+	0x54, 0x76, 0xfd, 0x21, 0x66, 0x88, 0x80, 0xae, 0xe3, 0x7a, 0x23, 0x9b, 0xbb, 0x76, 0x41, 0xe5,
+	0x03, 0x4c, 0xf9, 0x86, 0x37, 0x1e, 0x78, 0x23, 0x5b, 0x74, 0xf4, 0x39, 0xc3, 0x1b, 0xab, 0x23,
+	0x5b, 0xf9, 0xef, 0x24, 0xe4, 0x5a, 0x67, 0x9a, 0x7d, 0x4a, 0xc9, 0xb7, 0x90, 0xd3, 0x58, 0xfc,
+	0xd4, 0x93, 0x13, 0xb5, 0x3f, 0x9f, 0xde, 0x6c, 0xea, 0xbc, 0xd0, 0xe5, 0x38, 0x71, 0xe5, 0xa7,
+	0x2e, 0xa4, 0xfc, 0xc8, 0x15, 0xd2, 0xe7, 0xb8, 0x82, 0xf2, 0x1c, 0x72, 0x7c, 0x35, 0x52, 0x83,
+	0x32, 0x6f, 0x58, 0x9b, 0xad, 0xfe, 0x6e, 0xf7, 0x40, 0x74, 0xaa, 0x6a, 0x07, 0xbb, 0x56, 0xd6,
+	0xa9, 0x1e, 0x1d, 0xb6, 0xf1, 0x3b, 0x85, 0xdf, 0xed, 0xce, 0x5e, 0xa7, 0xdf, 0xa9, 0xa5, 0x95,
+	0x17, 0xb0, 0x3e, 0xa5, 0x48, 0x91, 0xd2, 0xee, 0x42, 0x5e, 0x67, 0xbb, 0x91, 0x06, 0xac, 0x4c,
+	0xec, 0x51, 0x95, 0xb3, 0xca, 0x18, 0xca, 0x3b, 0xa6, 0x1f, 0x38, 0xde, 0x98, 0xd7, 0xc7, 0x9b,
+	0x90, 0xc1, 0x62, 0xbd, 0x9e, 0x5c, 0xd0, 0xf1, 0x45, 0x4d, 0x3f, 0xc3, 0x0b, 0x63, 0x29, 0x15,
+	0x8b, 0xa5, 0x3b, 0x90, 0xe3, 0xec, 0x85, 0x02, 0xa6, 0xd6, 0x16, 0x93, 0xca, 0x4b, 0xb8, 0xda,
+	0xa6, 0xbe, 0xee, 0x99, 0xc7, 0xe7, 0x55, 0xbd, 0x75, 0xc8, 0x9f, 0x71, 0x21, 0xc5, 0xb1, 0x2b,
+	0x87, 0xca, 0x7f, 0xa5, 0xe0, 0xda, 0x0c, 0x93, 0xa5, 0xa7, 0xc6, 0x25, 0x8d, 0xf9, 0x63, 0xe4,
+	0xd7, 0x69, 0xa6, 0xc8, 0x3b, 0x82, 0x60, 0xc1, 0xaa, 0xd3, 0x71, 0x85, 0x07, 0x89, 0x94, 0x3d,
+	0x33, 0x71, 0x4c, 0xc5, 0xd5, 0x1e, 0x6e, 0x08, 0x0b, 0x0c, 0xea, 0x79, 0x8e, 0x87, 0xe7, 0x3c,
+	0x5e, 0xfc, 0x88, 0xd1, 0xaf, 0x99, 0x69, 0x94, 0x5f, 0x32, 0x90, 0xc1, 0xc4, 0xc0, 0x34, 0xa6,
+	0x0d, 0x23, 0x8d, 0x69, 0x43, 0x8a, 0xba, 0xc7, 0x7d, 0x60, 0xb4, 0x88, 0x9e, 0x41, 0x0c, 0xf1,
+	0xba, 0x11, 0x65, 0xa6, 0x83, 0x63, 0x2c, 0x01, 0x6d, 0x43, 0x1c, 0x16, 0x65, 0x06, 0x7c, 0xc9,
+	0x61, 0x78, 0x91, 0xe2, 0x51, 0xdd, 0xb1, 0x75, 0xd3, 0xa2, 0xec, 0xb8, 0x28, 0xa8, 0x11, 0x80,
+	0x34, 0xb1, 0xcd, 0xf0, 0x83, 0xc1, 0x19, 0xd5, 0xbc, 0xe0, 0x98, 0x6a, 0xc1, 0x05, 0x2e, 0x9b,
+	0x2a, 0x48, 0xb1, 0x23, 0x09, 0xc8, 0x6f, 0xa1, 0xc8, 0x58, 0xf8, 0x63, 0x5b, 0xaf, 0xe7, 0xce,
+	0xa5, 0x2e, 0x20, 0x72, 0x6f, 0x6c, 0xeb, 0x58, 0x0f, 0x0f, 0x35, 0xd3, 0x0e, 0xa8, 0xad, 0xd9,
+	0x3a, 0x65, 0x07, 0x4d, 0x41, 0x8d, 0x83, 0x30, 0xc3, 0x18, 0x9e, 0x79, 0xc2, 0x8b, 0xcd, 0x8a,
+	0xca, 0x07, 0x68, 0x21, 0x8b, 0x6a, 0x06, 0xf5, 0x58, 0xad, 0x59, 0x50, 0xc5, 0x08, 0x15, 0xa5,
+	0x19, 0x86, 0x47, 0x7d, 0x9f, 0x15, 0x9b, 0x45, 0x55, 0x0e, 0x51, 0xad, 0x43, 0x74, 0xc4, 0x12,
+	0x57, 0xeb, 0x90, 0x3b, 0xa2, 0xbc, 0x23, 0x29, 0xcf, 0x24, 0xe8, 0xb9, 0x97, 0xbb, 0x77, 0x61,
+	0xe5, 0x44, 0x33, 0x2d, 0x6c, 0x77, 0x65, 0x6a, 0xae, 0x30, 0x0f, 0xa9, 0x72, 0xb0, 0xf0, 0x43,
+	0x1f, 0xe5, 0x70, 0x35, 0xdf, 0x37, 0x3f, 0xd0, 0x7a, 0x95, 0x09, 0x28, 0x87, 0x5f, 0xd2, 0x0a,
+	0xff, 0x5d, 0x12, 0xca, 0xbb, 0xf6, 0x89, 0x13, 0x06, 0xd7, 0xad, 0x58, 0x70, 0x95, 0xb6, 0x4a,
+	0x31, 0xe9, 0x45, 0xa4, 0xdd, 0x82, 0x12, 0xf7, 0x0e, 0xe6, 0xc0, 0x82, 0x23, 0x30, 0x50, 0x07,
+	0x21, 0x78, 0x5e, 0x87, 0x3b, 0xe1, 0x85, 0x72, 0xc1, 0x8f, 0xed, 0x21, 0xaa, 0x17, 0x59, 0xc0,
+	0x8b, 0xa1, 0xf2, 0x47, 0x70, 0x05, 0x0b, 0x2d, 0x5c, 0x28, 0x2a, 0xe0, 0x6e, 0x43, 0x96, 0x5f,
+	0x96, 0xf2, 0x5c, 0x37, 0x21, 0x0d, 0x9f, 0x51, 0x3a, 0xb0, 0xde, 0xa3, 0xc1, 0x7e, 0x64, 0x5d,
+	0x99, 0x6b, 0xe6, 0x65, 0x89, 0x3a, 0xe4, 0xa9, 0xad, 0x1d, 0x5b, 0xd4, 0x10, 0x87, 0x8b, 0x1c,
+	0x2a, 0xff, 0x94, 0x82, 0x75, 0x71, 0xcf, 0x7a, 0x4e, 0xce, 0x8a, 0x6e, 0x7f, 0x53, 0x5f, 0x70,
+	0xfb, 0x9b, 0x9e, 0xbd, 0xfd, 0x6d, 0x40, 0x81, 0x0d, 0x4d, 0x2a, 0x95, 0x13, 0x8e, 0xc3, 0xdb,
+	0xd7, 0xec, 0xa5, 0x6f, 0x5f, 0x73, 0x17, 0xbe, 0xc3, 0x59, 0x83, 0xac, 0x76, 0x8c, 0xc5, 0x1f,
+	0x8f, 0x18, 0x3e, 0x50, 0xb6, 0x21, 0xff, 0x76, 0xf7, 0xf0, 0xd0, 0x71, 0xac, 0xb9, 0x59, 0x64,
+	0x0d, 0xb2, 0xba, 0x69, 0x78, 0xe1, 0xd5, 0x3b, 0x1b, 0x28, 0xff, 0x90, 0xe4, 0xd6, 0x44, 0xb2,
+	0xc8, 0x9a, 0xdb, 0x90, 0x75, 0x11, 0x50, 0x4f, 0x4e, 0xdc, 0x1e, 0xce, 0x20, 0x6e, 0xe2, 0x48,
+	0xe5, 0xb8, 0x8d, 0x1d, 0xc8, 0xb0, 0xc5, 0x15, 0xf1, 0x68, 0x91, 0x9c, 0x78, 0xdb, 0x10, 0xa2,
+	0x89, 0x47, 0x8c, 0xeb, 0x50, 0xd4, 0x2c, 0xcb, 0xd1, 0xb5, 0x80, 0x1a, 0x42, 0xa0, 0x08, 0xa0,
+	0xfc, 0x4f, 0x12, 0x8a, 0x2d, 0xcd, 0x36, 0x4c, 0x43, 0x0b, 0xf0, 0x20, 0xcd, 0xf9, 0x81, 0x86,
+	0xd7, 0xe0, 0x0b, 0x0a, 0x12, 0x31, 0x8d, 0xb5, 0x0b, 0x3e, 0x9c, 0x60, 0x2e, 0xac, 0xa7, 0xe6,
+	0xa3, 0x86, 0x08, 0xe4, 0x19, 0x00, 0x33, 0xb8, 0x37, 0x1c, 0x1c, 0xcb, 0x2b, 0xa2, 0xf3, 0x2e,
+	0xd8, 0x11, 0xfb, 0xe5, 0x18, 0xcd, 0xef, 0xd1, 0x0f, 0x26, 0x4b, 0xc8, 0x68, 0xfe, 0xb4, 0x1a,
+	0x8e, 0xc9, 0x4d, 0x64, 0x3b, 0x1c, 0x9a, 0x41, 0x60, 0xda, 0xa7, 0xcc, 0x09, 0x0a, 0x6a, 0x0c,
+	0xa2, 0xfc, 0x0c, 0x6b, 0xaf, 0x69, 0x10, 0x6e, 0xee, 0xd2, 0xd5, 0xc2, 0x94, 0xdc, 0xa9, 0x4b,
+	0xc8, 0xad, 0x58, 0x50, 0x69, 0x31, 0x49, 0x64, 0xc0, 0xbc, 0x84, 0x15, 0xc9, 0x4b, 0x3a, 0x61,
+	0xf2, 0x3c, 0x27, 0xac, 0x0a, 0x8a, 0xbe, 0xf0, 0xc5, 0x58, 0xb5, 0x97, 0x9a, 0xa8, 0xf6, 0x9e,
+	0x41, 0x55, 0xae, 0x76, 0xd9, 0x8a, 0xe8, 0x5f, 0x52, 0x00, 0xcd, 0x91, 0x61, 0x06, 0x9d, 0x0f,
+	0xd4, 0x0e, 0x2e, 0x5d, 0x10, 0x5d, 0x85, 0x1c, 0x6f, 0x87, 0x44, 0xca, 0x13, 0xa3, 0x30, 0xcf,
+	0xa4, 0x63, 0x79, 0xe6, 0x36, 0x94, 0xc5, 0x05, 0x33, 0x35, 0x50, 0xa1, 0xfc, 0xf2, 0xab, 0x14,
+	0xc2, 0x5e, 0xb2, 0x7a, 0x60, 0xc8, 0xee, 0xa2, 0xc5, 0xdd, 0x97, 0x18, 0x61, 0x8a, 0xf2, 0xb8,
+	0x22, 0x45, 0xeb, 0x24, 0x87, 0xb8, 0x90, 0x8e, 0x0b, 0x89, 0x67, 0x3b, 0xfc, 0xc6, 0xf0, 0xe3,
+	0x69, 0x98, 0xbf, 0x97, 0xf0, 0x01, 0xc6, 0x81, 0x2e, 0x7d, 0x41, 0x1c, 0x66, 0x11, 0x20, 0xae,
+	0x5b, 0x98, 0xd0, 0xed, 0x5f, 0xf2, 0x5e, 0x37, 0xd2, 0x51, 0xd8, 0xeb, 0x3e, 0x82, 0xac, 0x6f,
+	0xda, 0xfa, 0x45, 0x94, 0xc5, 0x11, 0x51, 0x30, 0xcb, 0x1c, 0x9a, 0xf2, 0x3a, 0x85, 0x0f, 0x94,
+	0x36, 0x5c, 0x9b, 0x59, 0x41, 0x98, 0xf1, 0x1b, 0xc8, 0x51, 0x06, 0x11, 0x56, 0x94, 0xd5, 0x4f,
+	0x84, 0xab, 0x0a, 0x04, 0xc5, 0x03, 0xf2, 0x9a, 0x46, 0x69, 0x5a, 0x30, 0xf8, 0x75, 0xaf, 0xdb,
+	0xfe, 0x1c, 0xca, 0xef, 0xb4, 0x40, 0x3f, 0xfb, 0x55, 0xee, 0x51, 0x95, 0x2e, 0x00, 0xe3, 0xce,
+	0x3d, 0xf3, 0xc2, 0x51, 0x5b, 0x87, 0xbc, 0x69, 0x9b, 0x81, 0xa9, 0x59, 0xf2, 0x38, 0x13, 0x43,
+	0xe5, 0x10, 0xca, 0xac, 0x7f, 0x90, 0xe2, 0x5e, 0x98, 0xe5, 0xc2, 0xc0, 0xfb, 0x0b, 0x28, 0x09,
+	0x8e, 0xfe, 0xc8, 0x0a, 0x62, 0xad, 0x40, 0x72, 0x49, 0x2b, 0x10, 0xfa, 0x6c, 0x6a, 0x9e, 0xcf,
+	0xa6, 0x63, 0x3e, 0xab, 0xfc, 0x0e, 0x2a, 0x92, 0x3f, 0xb7, 0xe7, 0xb7, 0x18, 0x08, 0xb8, 0x96,
+	0x14, 0x59, 0x5e, 0x2d, 0xc5, 0xc4, 0x50, 0x25, 0x8a, 0xf2, 0x6f, 0x49, 0x00, 0xac, 0xfe, 0xda,
+	0x1a, 0x1d, 0x3a, 0x36, 0xb9, 0x0f, 0x19, 0xcf, 0xb1, 0xa8, 0xe8, 0x03, 0xaf, 0xca, 0x84, 0x1d,
+	0x22, 0xe0, 0x9b, 0x2a, 0x55, 0x19, 0x0e, 0x46, 0x8b, 0x69, 0x07, 0xd4, 0x3b, 0xd1, 0x74, 0x29,
+	0x68, 0x04, 0x40, 0x85, 0x60, 0x05, 0x8a, 0xd7, 0x30, 0xbc, 0x98, 0xc9, 0xe1, 0x70, 0xd7, 0x50,
+	0xb6, 0x21, 0x83, 0x4c, 0xc8, 0x2a, 0xac, 0xf0, 0x06, 0xaf, 0xf7, 0xd3, 0x41, 0x0b, 0x9f, 0x25,
+	0xc5, 0x6b, 0xe4, 0x7e, 0xb3, 0xd7, 0xef, 0xa8, 0xbc, 0xc7, 0x7b, 0xd9, 0x6c, 0xbd, 0x39, 0x3a,
+	0xac, 0xa5, 0x94, 0x03, 0x28, 0x45, 0x42, 0xf8, 0x73, 0x6b, 0x94, 0x07, 0x90, 0x37, 0xf8, 0xf4,
+	0x94, 0x5b, 0x46, 0x84, 0xaa, 0xc4, 0x50, 0x5e, 0x00, 0xe9, 0x51, 0x56, 0xf6, 0xb2, 0x0d, 0x09,
+	0x6b, 0x5f, 0x62, 0xf7, 0xf7, 0x1f, 0x41, 0x41, 0xbe, 0xbc, 0x13, 0x02, 0x55, 0xbe, 0x95, 0x43,
+	0xb5, 0xdb, 0xef, 0xb6, 0xba, 0x7b, 0xb5, 0x04, 0xc9, 0x43, 0xba, 0xdf, 0x3a, 0xac, 0x25, 0xf1,
+	0xe3, 0xa8, 0x7d, 0x58, 0x4b, 0xdd, 0xff, 0x09, 0x2a, 0x13, 0x8f, 0x69, 0xa4, 0x0e, 0x6b, 0x9c,
+	0xec, 0x55, 0x57, 0x7d, 0xd7, 0x54, 0xdb, 0x83, 0xfd, 0x4e, 0x7f, 0xa7, 0xdb, 0xae, 0x25, 0x48,
+	0x11, 0xb2, 0x6a, 0xf7, 0x48, 0x76, 0xba, 0xfd, 0xa3, 0x83, 0x83, 0xce, 0x5e, 0x2d, 0x45, 0x0a,
+	0x90, 0xd9, 0x6f, 0xf6, 0xfe, 0xa4, 0x96, 0x26, 0x15, 0x28, 0xee, 0x75, 0x5b, 0xcd, 0xbd, 0x83,
+	0x6e, 0xbb, 0x53, 0xcb, 0xdc, 0xbf, 0x05, 0x10, 0xbd, 0xb9, 0x21, 0xda, 0xee, 0xe1, 0xee, 0x21,
+	0x17, 0xe2, 0xf5, 0x51, 0xa7, 0x96, 0xbc, 0xdf, 0x86, 0xea, 0xe4, 0xdb, 0x19, 0x59, 0x81, 0xd2,
+	0x41, 0x77, 0xd0, 0xda, 0xe9, 0xb4, 0xde, 0xf4, 0x8e, 0xf6, 0x6b, 0x09, 0x52, 0x86, 0x42, 0x38,
+	0x4a, 0xa2, 0x75, 0xd4, 0xce, 0x7e, 0xb7, 0xdf, 0x89, 0x50, 0x52, 0xf7, 0x7f, 0x80, 0x1c, 0xef,
+	0x94, 0xa2, 0xee, 0x7c, 0xa7, 0xd3, 0xdc, 0xeb, 0xef, 0xd4, 0x12, 0x28, 0xd1, 0xd1, 0x01, 0xc3,
+	0xed, 0xb4, 0x6b, 0x49, 0x92, 0x83, 0x14, 0x1a, 0x0e, 0x65, 0x69, 0x77, 0xdf, 0x1d, 0xd4, 0xd2,
+	0x5b, 0x7f, 0xbb, 0x0a, 0xb9, 0x7d, 0xea, 0x59, 0xa6, 0x4d, 0x5e, 0x40, 0xa5, 0xe5, 0x51, 0x2d,
+	0x90, 0xbd, 0x22, 0x99, 0x9f, 0x72, 0x1a, 0x57, 0x67, 0xf2, 0x65, 0x07, 0xff, 0x21, 0xa3, 0x24,
+	0x90, 0xc3, 0x11, 0x7b, 0x5f, 0xfd, 0x6c, 0x0e, 0xaf, 0xa1, 0xd2, 0xa6, 0x16, 0x8d, 0x38, 0x2c,
+	0x7d, 0x1a, 0x5c, 0xc2, 0xa8, 0x0d, 0xe5, 0xf8, 0xa3, 0x18, 0x69, 0xc8, 0x88, 0x9e, 0x7d, 0x29,
+	0x5b, 0xc2, 0xe5, 0x15, 0x54, 0x26, 0xde, 0xbb, 0xc8, 0xd7, 0x61, 0x52, 0x9d, 0x7d, 0x05, 0x5b,
+	0xc2, 0xe7, 0x25, 0x94, 0x62, 0x0f, 0x5f, 0x44, 0xde, 0x6f, 0xce, 0x3e, 0x86, 0x2d, 0xe1, 0xf1,
+	0x03, 0x94, 0x23, 0xf3, 0x50, 0x8f, 0xcc, 0xe6, 0xf7, 0xe5, 0xc4, 0x91, 0x65, 0x3e, 0x83, 0x38,
+	0x32, 0xca, 0x65, 0x89, 0xbf, 0x87, 0x52, 0x1b, 0xdf, 0xec, 0x3f, 0x87, 0xf6, 0x8f, 0xa1, 0x72,
+	0x64, 0x1b, 0x9f, 0x4b, 0xfd, 0x18, 0x32, 0x78, 0x3c, 0x13, 0x32, 0xf1, 0x52, 0xc6, 0xd5, 0xbc,
+	0x3a, 0xe7, 0xf5, 0x4c, 0x49, 0x90, 0xdf, 0xca, 0x57, 0xa8, 0x05, 0x5c, 0x1b, 0x6b, 0x13, 0xcf,
+	0x06, 0x11, 0xe1, 0xf7, 0x50, 0x7e, 0x4d, 0x83, 0xe8, 0xee, 0x76, 0x11, 0x7d, 0x6d, 0xfa, 0x82,
+	0x53, 0x49, 0x10, 0x15, 0x56, 0xa6, 0x6e, 0x69, 0xc8, 0x8d, 0x45, 0xb7, 0x37, 0x5c, 0xfa, 0x9b,
+	0xcb, 0x2f, 0x77, 0x94, 0x04, 0x79, 0x0a, 0x25, 0x2c, 0x2a, 0xe4, 0x25, 0xe4, 0x22, 0x71, 0xa6,
+	0x6b, 0x7f, 0x25, 0x41, 0xf6, 0xc4, 0xc9, 0x15, 0xd2, 0x7e, 0x1d, 0x3f, 0xa8, 0xa6, 0xae, 0x42,
+	0x1b, 0xd7, 0xe7, 0x4f, 0x86, 0x72, 0xfc, 0x06, 0x32, 0xd8, 0x8f, 0x2f, 0x14, 0x40, 0xda, 0x21,
+	0xde, 0xb4, 0x2b, 0x09, 0xf2, 0x23, 0x14, 0xc3, 0xf6, 0x79, 0x21, 0x6d, 0xfc, 0x05, 0x74, 0xa2,
+	0xd1, 0x56, 0x12, 0x64, 0x07, 0xaa, 0x93, 0x7d, 0x34, 0x91, 0x92, 0xce, 0x6d, 0xaf, 0x97, 0x78,
+	0xd1, 0x0e, 0x54, 0x27, 0x3b, 0xe9, 0x90, 0xd3, 0xdc, 0x06, 0x7b, 0x09, 0xa7, 0x6d, 0xc8, 0x1f,
+	0x8e, 0x58, 0x6f, 0x48, 0xa6, 0x1a, 0xbe, 0xa5, 0x79, 0x0c, 0x78, 0xec, 0x31, 0xba, 0xcf, 0xcd,
+	0x86, 0x42, 0x9f, 0xc8, 0xe3, 0x62, 0xfa, 0x9c, 0xe8, 0x60, 0x95, 0x04, 0xe9, 0x40, 0x39, 0xde,
+	0x92, 0x2d, 0xe4, 0x21, 0x9d, 0x65, 0x5e, 0xff, 0xc6, 0xe2, 0x2b, 0xc7, 0xfb, 0x1d, 0x12, 0x5e,
+	0x66, 0xc7, 0x9b, 0xad, 0xc6, 0xfa, 0x14, 0x34, 0x24, 0x6c, 0x62, 0x5b, 0xc6, 0x7a, 0x2a, 0x41,
+	0xbf, 0x48, 0x80, 0x65, 0x9a, 0xac, 0xb5, 0x4d, 0x5f, 0xd7, 0x3c, 0xe3, 0xfc, 0x6d, 0x2c, 0xe6,
+	0xa2, 0xc2, 0xca, 0x54, 0xcd, 0x4f, 0xe2, 0x9d, 0xff, 0x6c, 0xb7, 0xd1, 0xb8, 0xb9, 0x68, 0x3a,
+	0xdc, 0xdc, 0x36, 0x64, 0x59, 0xbd, 0x4c, 0x64, 0x34, 0xc4, 0x6b, 0xf3, 0xc6, 0x95, 0x38, 0x90,
+	0xd1, 0x2a, 0x89, 0x47, 0x49, 0xf2, 0x1a, 0x20, 0x6a, 0x1b, 0xce, 0x71, 0x8c, 0xaf, 0x22, 0xab,
+	0xcc, 0xa6, 0x8a, 0x6d, 0x28, 0x0a, 0xf8, 0xfc, 0x04, 0x3b, 0x0b, 0x52, 0x12, 0xe4, 0x09, 0x64,
+	0x59, 0xc8, 0x87, 0x22, 0xc7, 0xeb, 0xf3, 0xc6, 0xda, 0x24, 0x30, 0x5c, 0xaa, 0x0b, 0xd5, 0xc9,
+	0xb7, 0xcd, 0x73, 0xe4, 0xbe, 0x31, 0x29, 0xf7, 0xd4, 0x83, 0x28, 0x2b, 0x17, 0x56, 0xa6, 0xde,
+	0x33, 0x27, 0xac, 0x31, 0xfb, 0xce, 0x19, 0xee, 0x26, 0x9a, 0x62, 0xda, 0x7c, 0xce, 0x25, 0x8b,
+	0x15, 0xb3, 0x8b, 0x5c, 0x83, 0xcc, 0xd4, 0x9f, 0xbe, 0x92, 0x20, 0xcf, 0xf1, 0xa1, 0x3e, 0xac,
+	0x5c, 0xa3, 0x03, 0x7e, 0xa6, 0x9a, 0x9d, 0x4f, 0x7f, 0x9c, 0x63, 0xab, 0x6c, 0xff, 0x7e, 0x00,
+	0xa1, 0xd3, 0x9a, 0x1c, 0x97, 0x2c, 0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// MerlinClient is the client API for Merlin service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type MerlinClient interface {
+	CreateService(ctx context.Context, in *VirtualService, opts ...grpc.CallOption) (*empty.Empty, error)
+	UpdateService(ctx context.Context, in *VirtualService, opts ...grpc.CallOption) (*empty.Empty, error)
+	DeleteService(ctx context.Context, in *wrappers.StringValue, opts ...grpc.CallOption) (*empty.Empty, error)
+	// CloneService copies a service and its servers to a new service, which must have a different key.
+	CloneService(ctx context.Context, in *CloneServiceRequest, opts ...grpc.CallOption) (*empty.Empty, error)
+	// RenameService changes the ID of a service and its servers, in a single store update if supported.
+	RenameService(ctx context.Context, in *RenameServiceRequest, opts ...grpc.CallOption) (*empty.Empty, error)
+	// SwapServers exchanges the real servers of two services, in a single store update if supported, for
+	// blue/green cutovers.
+	SwapServers(ctx context.Context, in *SwapServersRequest, opts ...grpc.CallOption) (*empty.Empty, error)
+	CreateServer(ctx context.Context, in *RealServer, opts ...grpc.CallOption) (*empty.Empty, error)
+	UpdateServer(ctx context.Context, in *RealServer, opts ...grpc.CallOption) (*empty.Empty, error)
+	DeleteServer(ctx context.Context, in *RealServer, opts ...grpc.CallOption) (*empty.Empty, error)
+	// DrainServer sets the weight of a real server to 0, recording its weight so it can be undrained.
+	DrainServer(ctx context.Context, in *RealServer, opts ...grpc.CallOption) (*empty.Empty, error)
+	// UndrainServer restores the weight a real server had before it was drained.
+	UndrainServer(ctx context.Context, in *RealServer, opts ...grpc.CallOption) (*empty.Empty, error)
+	// List returns the services and their servers, optionally filtered.
+	List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListResponse, error)
+	// Stats returns the IPVS traffic statistics of the node serving the request.
+	Stats(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*StatsResponse, error)
+	// GetNodeState returns the services and servers in IPVS on the node serving the request.
+	GetNodeState(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*NodeState, error)
+	// DescribeService returns a service with its servers, recent changes, and reconcile errors on the node serving
+	// the request.
+	DescribeService(ctx context.Context, in *DescribeServiceRequest, opts ...grpc.CallOption) (*DescribeServiceResponse, error)
+	// GetSnapshot returns all the services and servers in the store.
+	GetSnapshot(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*Snapshot, error)
+	// ApplySnapshot creates and updates the store to match the snapshot, deleting what isn't in it if prune is set,
+	// returning the changes made.
+	ApplySnapshot(ctx context.Context, in *ApplySnapshotRequest, opts ...grpc.CallOption) (*ApplySnapshotResponse, error)
+	// Info returns the state of the node serving the request and its view of the store.
+	Info(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*InfoResponse, error)
+	// ListNodes returns the merlin nodes registered in the store.
+	ListNodes(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*ListNodesResponse, error)
+	// SetMaintenance pauses or resumes reconciliation of IPVS on a node.
+	SetMaintenance(ctx context.Context, in *SetMaintenanceRequest, opts ...grpc.CallOption) (*empty.Empty, error)
+	// RolloutService stages a change to the config of a service, which canary nodes reconcile before the rest.
+	RolloutService(ctx context.Context, in *RolloutServiceRequest, opts ...grpc.CallOption) (*empty.Empty, error)
+	// PutPool creates or replaces a pool of VIPs, which services can be allocated an IP from.
+	PutPool(ctx context.Context, in *VIPPool, opts ...grpc.CallOption) (*empty.Empty, error)
+	// DeletePool deletes a pool of VIPs, which must have no services allocated from it.
+	DeletePool(ctx context.Context, in *wrappers.StringValue, opts ...grpc.CallOption) (*empty.Empty, error)
+	// ListPools returns the pools of VIPs, and the IPs allocated from each.
+	ListPools(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*ListPoolsResponse, error)
+	// GetCandidate returns the changes staged in the candidate config by writes with the x-merlin-candidate
+	// metadata, and the deadline to confirm the last commit by if it's unconfirmed.
+	GetCandidate(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*GetCandidateResponse, error)
+	// Commit applies the candidate config in a single write, and clears it. On etcd2, which has no transactions, the
+	// changes are written one at a time, so a commit failing partway is left partly applied.
+	Commit(ctx context.Context, in *CommitRequest, opts ...grpc.CallOption) (*CommitResponse, error)
+	// ConfirmCommit keeps a commit made with a confirm timeout, so it isn't rolled back.
+	ConfirmCommit(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*empty.Empty, error)
+	// DiscardCandidate clears the candidate config without applying it.
+	DiscardCandidate(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*empty.Empty, error)
+	// ListAuditEvents returns the writes made through the API, oldest first.
+	ListAuditEvents(ctx context.Context, in *ListAuditEventsRequest, opts ...grpc.CallOption) (*ListAuditEventsResponse, error)
+	// Watch streams the services and their servers, then the changes made to them whenever the store changes,
+	// until the client cancels it.
+	Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (Merlin_WatchClient, error)
+	// GetService returns a service by ID, with its servers.
+	GetService(ctx context.Context, in *wrappers.StringValue, opts ...grpc.CallOption) (*GetServiceResponse, error)
+	// GetServer returns the server of a service with the key of the request.
+	GetServer(ctx context.Context, in *RealServer, opts ...grpc.CallOption) (*RealServer, error)
+	// Apply makes changes to services and servers all or nothing, in a single write, returning the result of each.
+	// If any change fails, nothing is applied and the error's details have an ApplyResponse with every result.
+	// It fails with FailedPrecondition on etcd2, which can't write atomically, and with InvalidArgument on etcd3 if
+	// the changes, including the servers of deleted services, are more than the 128 operations of a transaction.
+	// Apply is atomic but not isolated: the changes are checked against the store as it's read, and overwrite writes
+	// made to the same services and servers between that read and the write.
+	Apply(ctx context.Context, in *ApplyRequest, opts ...grpc.CallOption) (*ApplyResponse, error)
+	// GetServerStats returns the IPVS counters of a service and each of its real servers, read on the node serving
+	// the request.
+	GetServerStats(ctx context.Context, in *wrappers.StringValue, opts ...grpc.CallOption) (*GetServerStatsResponse, error)
+	// ListConnections streams the IPVS connection table of the node serving the request, such as to check which
+	// server a client is persisted to or that a drained server has no connections left.
+	ListConnections(ctx context.Context, in *ListConnectionsRequest, opts ...grpc.CallOption) (Merlin_ListConnectionsClient, error)
+	// GetSyncDaemons returns the IPVS connection sync daemons running on the node serving the request.
+	GetSyncDaemons(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*SyncDaemons, error)
+	// SetSyncRole switches the IPVS connection sync daemon of the node serving the request to MASTER or BACKUP, or
+	// stops it if the role is unset, such as when a backup takes over the VIPs of its master.
+	SetSyncRole(ctx context.Context, in *SetSyncRoleRequest, opts ...grpc.CallOption) (*SyncDaemons, error)
+}
+
+type merlinClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewMerlinClient(cc *grpc.ClientConn) MerlinClient {
+	return &merlinClient{cc}
+}
+
+func (c *merlinClient) CreateService(ctx context.Context, in *VirtualService, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, "/types.Merlin/CreateService", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *merlinClient) UpdateService(ctx context.Context, in *VirtualService, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, "/types.Merlin/UpdateService", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *merlinClient) DeleteService(ctx context.Context, in *wrappers.StringValue, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, "/types.Merlin/DeleteService", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *merlinClient) CloneService(ctx context.Context, in *CloneServiceRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, "/types.Merlin/CloneService", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *merlinClient) RenameService(ctx context.Context, in *RenameServiceRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, "/types.Merlin/RenameService", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *merlinClient) SwapServers(ctx context.Context, in *SwapServersRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, "/types.Merlin/SwapServers", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *merlinClient) CreateServer(ctx context.Context, in *RealServer, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, "/types.Merlin/CreateServer", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *merlinClient) UpdateServer(ctx context.Context, in *RealServer, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, "/types.Merlin/UpdateServer", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *merlinClient) DeleteServer(ctx context.Context, in *RealServer, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, "/types.Merlin/DeleteServer", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *merlinClient) DrainServer(ctx context.Context, in *RealServer, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, "/types.Merlin/DrainServer", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *merlinClient) UndrainServer(ctx context.Context, in *RealServer, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, "/types.Merlin/UndrainServer", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *merlinClient) List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListResponse, error) {
+	out := new(ListResponse)
+	err := c.cc.Invoke(ctx, "/types.Merlin/List", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *merlinClient) Stats(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*StatsResponse, error) {
+	out := new(StatsResponse)
+	err := c.cc.Invoke(ctx, "/types.Merlin/Stats", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *merlinClient) GetNodeState(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*NodeState, error) {
+	out := new(NodeState)
+	err := c.cc.Invoke(ctx, "/types.Merlin/GetNodeState", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *merlinClient) DescribeService(ctx context.Context, in *DescribeServiceRequest, opts ...grpc.CallOption) (*DescribeServiceResponse, error) {
+	out := new(DescribeServiceResponse)
+	err := c.cc.Invoke(ctx, "/types.Merlin/DescribeService", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *merlinClient) GetSnapshot(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*Snapshot, error) {
+	out := new(Snapshot)
+	err := c.cc.Invoke(ctx, "/types.Merlin/GetSnapshot", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *merlinClient) ApplySnapshot(ctx context.Context, in *ApplySnapshotRequest, opts ...grpc.CallOption) (*ApplySnapshotResponse, error) {
+	out := new(ApplySnapshotResponse)
+	err := c.cc.Invoke(ctx, "/types.Merlin/ApplySnapshot", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *merlinClient) Info(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*InfoResponse, error) {
+	out := new(InfoResponse)
+	err := c.cc.Invoke(ctx, "/types.Merlin/Info", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *merlinClient) ListNodes(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*ListNodesResponse, error) {
+	out := new(ListNodesResponse)
+	err := c.cc.Invoke(ctx, "/types.Merlin/ListNodes", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *merlinClient) SetMaintenance(ctx context.Context, in *SetMaintenanceRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, "/types.Merlin/SetMaintenance", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *merlinClient) RolloutService(ctx context.Context, in *RolloutServiceRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, "/types.Merlin/RolloutService", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *merlinClient) PutPool(ctx context.Context, in *VIPPool, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, "/types.Merlin/PutPool", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *merlinClient) DeletePool(ctx context.Context, in *wrappers.StringValue, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, "/types.Merlin/DeletePool", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *merlinClient) ListPools(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*ListPoolsResponse, error) {
+	out := new(ListPoolsResponse)
+	err := c.cc.Invoke(ctx, "/types.Merlin/ListPools", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *merlinClient) GetCandidate(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*GetCandidateResponse, error) {
+	out := new(GetCandidateResponse)
+	err := c.cc.Invoke(ctx, "/types.Merlin/GetCandidate", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *merlinClient) Commit(ctx context.Context, in *CommitRequest, opts ...grpc.CallOption) (*CommitResponse, error) {
+	out := new(CommitResponse)
+	err := c.cc.Invoke(ctx, "/types.Merlin/Commit", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *merlinClient) ConfirmCommit(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, "/types.Merlin/ConfirmCommit", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *merlinClient) DiscardCandidate(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, "/types.Merlin/DiscardCandidate", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *merlinClient) ListAuditEvents(ctx context.Context, in *ListAuditEventsRequest, opts ...grpc.CallOption) (*ListAuditEventsResponse, error) {
+	out := new(ListAuditEventsResponse)
+	err := c.cc.Invoke(ctx, "/types.Merlin/ListAuditEvents", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *merlinClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (Merlin_WatchClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Merlin_serviceDesc.Streams[0], "/types.Merlin/Watch", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &merlinWatchClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Merlin_WatchClient interface {
+	Recv() (*WatchEvent, error)
+	grpc.ClientStream
+}
+
+type merlinWatchClient struct {
+	grpc.ClientStream
+}
+
+func (x *merlinWatchClient) Recv() (*WatchEvent, error) {
+	m := new(WatchEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *merlinClient) GetService(ctx context.Context, in *wrappers.StringValue, opts ...grpc.CallOption) (*GetServiceResponse, error) {
+	out := new(GetServiceResponse)
+	err := c.cc.Invoke(ctx, "/types.Merlin/GetService", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *merlinClient) GetServer(ctx context.Context, in *RealServer, opts ...grpc.CallOption) (*RealServer, error) {
+	out := new(RealServer)
+	err := c.cc.Invoke(ctx, "/types.Merlin/GetServer", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *merlinClient) Apply(ctx context.Context, in *ApplyRequest, opts ...grpc.CallOption) (*ApplyResponse, error) {
+	out := new(ApplyResponse)
+	err := c.cc.Invoke(ctx, "/types.Merlin/Apply", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *merlinClient) GetServerStats(ctx context.Context, in *wrappers.StringValue, opts ...grpc.CallOption) (*GetServerStatsResponse, error) {
+	out := new(GetServerStatsResponse)
+	err := c.cc.Invoke(ctx, "/types.Merlin/GetServerStats", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *merlinClient) ListConnections(ctx context.Context, in *ListConnectionsRequest, opts ...grpc.CallOption) (Merlin_ListConnectionsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Merlin_serviceDesc.Streams[1], "/types.Merlin/ListConnections", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &merlinListConnectionsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Merlin_ListConnectionsClient interface {
+	Recv() (*Connection, error)
+	grpc.ClientStream
+}
+
+type merlinListConnectionsClient struct {
+	grpc.ClientStream
+}
+
+func (x *merlinListConnectionsClient) Recv() (*Connection, error) {
+	m := new(Connection)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *merlinClient) GetSyncDaemons(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*SyncDaemons, error) {
+	out := new(SyncDaemons)
+	err := c.cc.Invoke(ctx, "/types.Merlin/GetSyncDaemons", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *merlinClient) SetSyncRole(ctx context.Context, in *SetSyncRoleRequest, opts ...grpc.CallOption) (*SyncDaemons, error) {
+	out := new(SyncDaemons)
+	err := c.cc.Invoke(ctx, "/types.Merlin/SetSyncRole", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MerlinServer is the server API for Merlin service.
+type MerlinServer interface {
+	CreateService(context.Context, *VirtualService) (*empty.Empty, error)
+	UpdateService(context.Context, *VirtualService) (*empty.Empty, error)
+	DeleteService(context.Context, *wrappers.StringValue) (*empty.Empty, error)
+	// CloneService copies a service and its servers to a new service, which must have a different key.
+	CloneService(context.Context, *CloneServiceRequest) (*empty.Empty, error)
+	// RenameService changes the ID of a service and its servers, in a single store update if supported.
+	RenameService(context.Context, *RenameServiceRequest) (*empty.Empty, error)
+	// SwapServers exchanges the real servers of two services, in a single store update if supported, for
+	// blue/green cutovers.
+	SwapServers(context.Context, *SwapServersRequest) (*empty.Empty, error)
+	CreateServer(context.Context, *RealServer) (*empty.Empty, error)
+	UpdateServer(context.Context, *RealServer) (*empty.Empty, error)
+	DeleteServer(context.Context, *RealServer) (*empty.Empty, error)
+	// DrainServer sets the weight of a real server to 0, recording its weight so it can be undrained.
+	DrainServer(context.Context, *RealServer) (*empty.Empty, error)
+	// UndrainServer restores the weight a real server had before it was drained.
+	UndrainServer(context.Context, *RealServer) (*empty.Empty, error)
+	// List returns the services and their servers, optionally filtered.
+	List(context.Context, *ListRequest) (*ListResponse, error)
+	// Stats returns the IPVS traffic statistics of the node serving the request.
+	Stats(context.Context, *empty.Empty) (*StatsResponse, error)
+	// GetNodeState returns the services and servers in IPVS on the node serving the request.
+	GetNodeState(context.Context, *empty.Empty) (*NodeState, error)
+	// DescribeService returns a service with its servers, recent changes, and reconcile errors on the node serving
+	// the request.
+	DescribeService(context.Context, *DescribeServiceRequest) (*DescribeServiceResponse, error)
+	// GetSnapshot returns all the services and servers in the store.
+	GetSnapshot(context.Context, *empty.Empty) (*Snapshot, error)
+	// ApplySnapshot creates and updates the store to match the snapshot, deleting what isn't in it if prune is set,
+	// returning the changes made.
+	ApplySnapshot(context.Context, *ApplySnapshotRequest) (*ApplySnapshotResponse, error)
+	// Info returns the state of the node serving the request and its view of the store.
+	Info(context.Context, *empty.Empty) (*InfoResponse, error)
+	// ListNodes returns the merlin nodes registered in the store.
+	ListNodes(context.Context, *empty.Empty) (*ListNodesResponse, error)
+	// SetMaintenance pauses or resumes reconciliation of IPVS on a node.
+	SetMaintenance(context.Context, *SetMaintenanceRequest) (*empty.Empty, error)
+	// RolloutService stages a change to the config of a service, which canary nodes reconcile before the rest.
+	RolloutService(context.Context, *RolloutServiceRequest) (*empty.Empty, error)
+	// PutPool creates or replaces a pool of VIPs, which services can be allocated an IP from.
+	PutPool(context.Context, *VIPPool) (*empty.Empty, error)
+	// DeletePool deletes a pool of VIPs, which must have no services allocated from it.
+	DeletePool(context.Context, *wrappers.StringValue) (*empty.Empty, error)
+	// ListPools returns the pools of VIPs, and the IPs allocated from each.
+	ListPools(context.Context, *empty.Empty) (*ListPoolsResponse, error)
+	// GetCandidate returns the changes staged in the candidate config by writes with the x-merlin-candidate
+	// metadata, and the deadline to confirm the last commit by if it's unconfirmed.
+	GetCandidate(context.Context, *empty.Empty) (*GetCandidateResponse, error)
+	// Commit applies the candidate config in a single write, and clears it. On etcd2, which has no transactions, the
+	// changes are written one at a time, so a commit failing partway is left partly applied.
+	Commit(context.Context, *CommitRequest) (*CommitResponse, error)
+	// ConfirmCommit keeps a commit made with a confirm timeout, so it isn't rolled back.
+	ConfirmCommit(context.Context, *empty.Empty) (*empty.Empty, error)
+	// DiscardCandidate clears the candidate config without applying it.
+	DiscardCandidate(context.Context, *empty.Empty) (*empty.Empty, error)
+	// ListAuditEvents returns the writes made through the API, oldest first.
+	ListAuditEvents(context.Context, *ListAuditEventsRequest) (*ListAuditEventsResponse, error)
+	// Watch streams the services and their servers, then the changes made to them whenever the store changes,
+	// until the client cancels it.
+	Watch(*WatchRequest, Merlin_WatchServer) error
+	// GetService returns a service by ID, with its servers.
+	GetService(context.Context, *wrappers.StringValue) (*GetServiceResponse, error)
+	// GetServer returns the server of a service with the key of the request.
+	GetServer(context.Context, *RealServer) (*RealServer, error)
+	// Apply makes changes to services and servers all or nothing, in a single write, returning the result of each.
+	// If any change fails, nothing is applied and the error's details have an ApplyResponse with every result.
+	// It fails with FailedPrecondition on etcd2, which can't write atomically, and with InvalidArgument on etcd3 if
+	// the changes, including the servers of deleted services, are more than the 128 operations of a transaction.
+	// Apply is atomic but not isolated: the changes are checked against the store as it's read, and overwrite writes
+	// made to the same services and servers between that read and the write.
+	Apply(context.Context, *ApplyRequest) (*ApplyResponse, error)
+	// GetServerStats returns the IPVS counters of a service and each of its real servers, read on the node serving
+	// the request.
+	GetServerStats(context.Context, *wrappers.StringValue) (*GetServerStatsResponse, error)
+	// ListConnections streams the IPVS connection table of the node serving the request, such as to check which
+	// server a client is persisted to or that a drained server has no connections left.
+	ListConnections(*ListConnectionsRequest, Merlin_ListConnectionsServer) error
+	// GetSyncDaemons returns the IPVS connection sync daemons running on the node serving the request.
+	GetSyncDaemons(context.Context, *empty.Empty) (*SyncDaemons, error)
+	// SetSyncRole switches the IPVS connection sync daemon of the node serving the request to MASTER or BACKUP, or
+	// stops it if the role is unset, such as when a backup takes over the VIPs of its master.
+	SetSyncRole(context.Context, *SetSyncRoleRequest) (*SyncDaemons, error)
+}
+
+// UnimplementedMerlinServer can be embedded to have forward compatible implementations.
+type UnimplementedMerlinServer struct {
+}
+
+func (*UnimplementedMerlinServer) CreateService(ctx context.Context, req *VirtualService) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateService not implemented")
+}
+func (*UnimplementedMerlinServer) UpdateService(ctx context.Context, req *VirtualService) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateService not implemented")
+}
+func (*UnimplementedMerlinServer) DeleteService(ctx context.Context, req *wrappers.StringValue) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteService not implemented")
+}
+func (*UnimplementedMerlinServer) CloneService(ctx context.Context, req *CloneServiceRequest) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CloneService not implemented")
+}
+func (*UnimplementedMerlinServer) RenameService(ctx context.Context, req *RenameServiceRequest) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RenameService not implemented")
+}
+func (*UnimplementedMerlinServer) SwapServers(ctx context.Context, req *SwapServersRequest) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SwapServers not implemented")
+}
+func (*UnimplementedMerlinServer) CreateServer(ctx context.Context, req *RealServer) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateServer not implemented")
+}
+func (*UnimplementedMerlinServer) UpdateServer(ctx context.Context, req *RealServer) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateServer not implemented")
+}
+func (*UnimplementedMerlinServer) DeleteServer(ctx context.Context, req *RealServer) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteServer not implemented")
+}
+func (*UnimplementedMerlinServer) DrainServer(ctx context.Context, req *RealServer) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DrainServer not implemented")
+}
+func (*UnimplementedMerlinServer) UndrainServer(ctx context.Context, req *RealServer) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UndrainServer not implemented")
+}
+func (*UnimplementedMerlinServer) List(ctx context.Context, req *ListRequest) (*ListResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method List not implemented")
+}
+func (*UnimplementedMerlinServer) Stats(ctx context.Context, req *empty.Empty) (*StatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Stats not implemented")
+}
+func (*UnimplementedMerlinServer) GetNodeState(ctx context.Context, req *empty.Empty) (*NodeState, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetNodeState not implemented")
+}
+func (*UnimplementedMerlinServer) DescribeService(ctx context.Context, req *DescribeServiceRequest) (*DescribeServiceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DescribeService not implemented")
+}
+func (*UnimplementedMerlinServer) GetSnapshot(ctx context.Context, req *empty.Empty) (*Snapshot, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSnapshot not implemented")
+}
+func (*UnimplementedMerlinServer) ApplySnapshot(ctx context.Context, req *ApplySnapshotRequest) (*ApplySnapshotResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ApplySnapshot not implemented")
+}
+func (*UnimplementedMerlinServer) Info(ctx context.Context, req *empty.Empty) (*InfoResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Info not implemented")
+}
+func (*UnimplementedMerlinServer) ListNodes(ctx context.Context, req *empty.Empty) (*ListNodesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListNodes not implemented")
+}
+func (*UnimplementedMerlinServer) SetMaintenance(ctx context.Context, req *SetMaintenanceRequest) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetMaintenance not implemented")
+}
+func (*UnimplementedMerlinServer) RolloutService(ctx context.Context, req *RolloutServiceRequest) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RolloutService not implemented")
+}
+func (*UnimplementedMerlinServer) PutPool(ctx context.Context, req *VIPPool) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PutPool not implemented")
+}
+func (*UnimplementedMerlinServer) DeletePool(ctx context.Context, req *wrappers.StringValue) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeletePool not implemented")
+}
+func (*UnimplementedMerlinServer) ListPools(ctx context.Context, req *empty.Empty) (*ListPoolsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPools not implemented")
+}
+func (*UnimplementedMerlinServer) GetCandidate(ctx context.Context, req *empty.Empty) (*GetCandidateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCandidate not implemented")
+}
+func (*UnimplementedMerlinServer) Commit(ctx context.Context, req *CommitRequest) (*CommitResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Commit not implemented")
+}
+func (*UnimplementedMerlinServer) ConfirmCommit(ctx context.Context, req *empty.Empty) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ConfirmCommit not implemented")
+}
+func (*UnimplementedMerlinServer) DiscardCandidate(ctx context.Context, req *empty.Empty) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DiscardCandidate not implemented")
+}
+func (*UnimplementedMerlinServer) ListAuditEvents(ctx context.Context, req *ListAuditEventsRequest) (*ListAuditEventsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAuditEvents not implemented")
+}
+func (*UnimplementedMerlinServer) Watch(req *WatchRequest, srv Merlin_WatchServer) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
+}
+func (*UnimplementedMerlinServer) GetService(ctx context.Context, req *wrappers.StringValue) (*GetServiceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetService not implemented")
+}
+func (*UnimplementedMerlinServer) GetServer(ctx context.Context, req *RealServer) (*RealServer, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetServer not implemented")
+}
+func (*UnimplementedMerlinServer) Apply(ctx context.Context, req *ApplyRequest) (*ApplyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Apply not implemented")
+}
+func (*UnimplementedMerlinServer) GetServerStats(ctx context.Context, req *wrappers.StringValue) (*GetServerStatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetServerStats not implemented")
+}
+func (*UnimplementedMerlinServer) ListConnections(req *ListConnectionsRequest, srv Merlin_ListConnectionsServer) error {
+	return status.Errorf(codes.Unimplemented, "method ListConnections not implemented")
+}
+func (*UnimplementedMerlinServer) GetSyncDaemons(ctx context.Context, req *empty.Empty) (*SyncDaemons, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSyncDaemons not implemented")
+}
+func (*UnimplementedMerlinServer) SetSyncRole(ctx context.Context, req *SetSyncRoleRequest) (*SyncDaemons, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetSyncRole not implemented")
+}
+
+func RegisterMerlinServer(s *grpc.Server, srv MerlinServer) {
+	s.RegisterService(&_Merlin_serviceDesc, srv)
+}
+
+func _Merlin_CreateService_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VirtualService)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MerlinServer).CreateService(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/types.Merlin/CreateService",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MerlinServer).CreateService(ctx, req.(*VirtualService))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Merlin_UpdateService_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VirtualService)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MerlinServer).UpdateService(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/types.Merlin/UpdateService",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MerlinServer).UpdateService(ctx, req.(*VirtualService))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Merlin_DeleteService_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(wrappers.StringValue)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MerlinServer).DeleteService(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/types.Merlin/DeleteService",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MerlinServer).DeleteService(ctx, req.(*wrappers.StringValue))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Merlin_CloneService_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CloneServiceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MerlinServer).CloneService(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/types.Merlin/CloneService",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MerlinServer).CloneService(ctx, req.(*CloneServiceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Merlin_RenameService_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RenameServiceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MerlinServer).RenameService(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/types.Merlin/RenameService",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MerlinServer).RenameService(ctx, req.(*RenameServiceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Merlin_SwapServers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SwapServersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MerlinServer).SwapServers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/types.Merlin/SwapServers",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MerlinServer).SwapServers(ctx, req.(*SwapServersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Merlin_CreateServer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RealServer)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MerlinServer).CreateServer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/types.Merlin/CreateServer",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MerlinServer).CreateServer(ctx, req.(*RealServer))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Merlin_UpdateServer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RealServer)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MerlinServer).UpdateServer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/types.Merlin/UpdateServer",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MerlinServer).UpdateServer(ctx, req.(*RealServer))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Merlin_DeleteServer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RealServer)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MerlinServer).DeleteServer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/types.Merlin/DeleteServer",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MerlinServer).DeleteServer(ctx, req.(*RealServer))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Merlin_DrainServer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RealServer)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MerlinServer).DrainServer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/types.Merlin/DrainServer",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MerlinServer).DrainServer(ctx, req.(*RealServer))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Merlin_UndrainServer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RealServer)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MerlinServer).UndrainServer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/types.Merlin/UndrainServer",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MerlinServer).UndrainServer(ctx, req.(*RealServer))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Merlin_List_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MerlinServer).List(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/types.Merlin/List",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MerlinServer).List(ctx, req.(*ListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Merlin_Stats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(empty.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MerlinServer).Stats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/types.Merlin/Stats",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MerlinServer).Stats(ctx, req.(*empty.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Merlin_GetNodeState_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(empty.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MerlinServer).GetNodeState(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/types.Merlin/GetNodeState",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MerlinServer).GetNodeState(ctx, req.(*empty.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Merlin_DescribeService_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DescribeServiceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MerlinServer).DescribeService(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/types.Merlin/DescribeService",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MerlinServer).DescribeService(ctx, req.(*DescribeServiceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Merlin_GetSnapshot_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(empty.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MerlinServer).GetSnapshot(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/types.Merlin/GetSnapshot",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MerlinServer).GetSnapshot(ctx, req.(*empty.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Merlin_ApplySnapshot_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ApplySnapshotRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MerlinServer).ApplySnapshot(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/types.Merlin/ApplySnapshot",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MerlinServer).ApplySnapshot(ctx, req.(*ApplySnapshotRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Merlin_Info_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(empty.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MerlinServer).Info(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/types.Merlin/Info",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MerlinServer).Info(ctx, req.(*empty.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Merlin_ListNodes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(empty.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MerlinServer).ListNodes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/types.Merlin/ListNodes",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MerlinServer).ListNodes(ctx, req.(*empty.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Merlin_SetMaintenance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetMaintenanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MerlinServer).SetMaintenance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/types.Merlin/SetMaintenance",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MerlinServer).SetMaintenance(ctx, req.(*SetMaintenanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Merlin_RolloutService_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RolloutServiceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MerlinServer).RolloutService(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/types.Merlin/RolloutService",
 	}
-	return out, nil
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MerlinServer).RolloutService(ctx, req.(*RolloutServiceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (c *merlinClient) UpdateService(ctx context.Context, in *VirtualService, opts ...grpc.CallOption) (*empty.Empty, error) {
-	out := new(empty.Empty)
-	err := c.cc.Invoke(ctx, "/types.Merlin/UpdateService", in, out, opts...)
-	if err != nil {
+func _Merlin_PutPool_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VIPPool)
+	if err := dec(in); err != nil {
 		return nil, err
 	}
-	return out, nil
+	if interceptor == nil {
+		return srv.(MerlinServer).PutPool(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/types.Merlin/PutPool",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MerlinServer).PutPool(ctx, req.(*VIPPool))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (c *merlinClient) DeleteService(ctx context.Context, in *wrappers.StringValue, opts ...grpc.CallOption) (*empty.Empty, error) {
-	out := new(empty.Empty)
-	err := c.cc.Invoke(ctx, "/types.Merlin/DeleteService", in, out, opts...)
-	if err != nil {
+func _Merlin_DeletePool_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(wrappers.StringValue)
+	if err := dec(in); err != nil {
 		return nil, err
 	}
-	return out, nil
+	if interceptor == nil {
+		return srv.(MerlinServer).DeletePool(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/types.Merlin/DeletePool",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MerlinServer).DeletePool(ctx, req.(*wrappers.StringValue))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (c *merlinClient) CreateServer(ctx context.Context, in *RealServer, opts ...grpc.CallOption) (*empty.Empty, error) {
-	out := new(empty.Empty)
-	err := c.cc.Invoke(ctx, "/types.Merlin/CreateServer", in, out, opts...)
-	if err != nil {
+func _Merlin_ListPools_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(empty.Empty)
+	if err := dec(in); err != nil {
 		return nil, err
 	}
-	return out, nil
+	if interceptor == nil {
+		return srv.(MerlinServer).ListPools(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/types.Merlin/ListPools",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MerlinServer).ListPools(ctx, req.(*empty.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (c *merlinClient) UpdateServer(ctx context.Context, in *RealServer, opts ...grpc.CallOption) (*empty.Empty, error) {
-	out := new(empty.Empty)
-	err := c.cc.Invoke(ctx, "/types.Merlin/UpdateServer", in, out, opts...)
-	if err != nil {
+func _Merlin_GetCandidate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(empty.Empty)
+	if err := dec(in); err != nil {
 		return nil, err
 	}
-	return out, nil
+	if interceptor == nil {
+		return srv.(MerlinServer).GetCandidate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/types.Merlin/GetCandidate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MerlinServer).GetCandidate(ctx, req.(*empty.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (c *merlinClient) DeleteServer(ctx context.Context, in *RealServer, opts ...grpc.CallOption) (*empty.Empty, error) {
-	out := new(empty.Empty)
-	err := c.cc.Invoke(ctx, "/types.Merlin/DeleteServer", in, out, opts...)
-	if err != nil {
+func _Merlin_Commit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CommitRequest)
+	if err := dec(in); err != nil {
 		return nil, err
 	}
-	return out, nil
+	if interceptor == nil {
+		return srv.(MerlinServer).Commit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/types.Merlin/Commit",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MerlinServer).Commit(ctx, req.(*CommitRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (c *merlinClient) List(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*ListResponse, error) {
-	out := new(ListResponse)
-	err := c.cc.Invoke(ctx, "/types.Merlin/List", in, out, opts...)
-	if err != nil {
+func _Merlin_ConfirmCommit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(empty.Empty)
+	if err := dec(in); err != nil {
 		return nil, err
 	}
-	return out, nil
-}
-
-// MerlinServer is the server API for Merlin service.
-type MerlinServer interface {
-	CreateService(context.Context, *VirtualService) (*empty.Empty, error)
-	UpdateService(context.Context, *VirtualService) (*empty.Empty, error)
-	DeleteService(context.Context, *wrappers.StringValue) (*empty.Empty, error)
-	CreateServer(context.Context, *RealServer) (*empty.Empty, error)
-	UpdateServer(context.Context, *RealServer) (*empty.Empty, error)
-	DeleteServer(context.Context, *RealServer) (*empty.Empty, error)
-	List(context.Context, *empty.Empty) (*ListResponse, error)
-}
-
-// UnimplementedMerlinServer can be embedded to have forward compatible implementations.
-type UnimplementedMerlinServer struct {
-}
-
-func (*UnimplementedMerlinServer) CreateService(ctx context.Context, req *VirtualService) (*empty.Empty, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method CreateService not implemented")
-}
-func (*UnimplementedMerlinServer) UpdateService(ctx context.Context, req *VirtualService) (*empty.Empty, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method UpdateService not implemented")
-}
-func (*UnimplementedMerlinServer) DeleteService(ctx context.Context, req *wrappers.StringValue) (*empty.Empty, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method DeleteService not implemented")
-}
-func (*UnimplementedMerlinServer) CreateServer(ctx context.Context, req *RealServer) (*empty.Empty, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method CreateServer not implemented")
-}
-func (*UnimplementedMerlinServer) UpdateServer(ctx context.Context, req *RealServer) (*empty.Empty, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method UpdateServer not implemented")
-}
-func (*UnimplementedMerlinServer) DeleteServer(ctx context.Context, req *RealServer) (*empty.Empty, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method DeleteServer not implemented")
-}
-func (*UnimplementedMerlinServer) List(ctx context.Context, req *empty.Empty) (*ListResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method List not implemented")
-}
-
-func RegisterMerlinServer(s *grpc.Server, srv MerlinServer) {
-	s.RegisterService(&_Merlin_serviceDesc, srv)
+	if interceptor == nil {
+		return srv.(MerlinServer).ConfirmCommit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/types.Merlin/ConfirmCommit",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MerlinServer).ConfirmCommit(ctx, req.(*empty.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func _Merlin_CreateService_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(VirtualService)
+func _Merlin_DiscardCandidate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(empty.Empty)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(MerlinServer).CreateService(ctx, in)
+		return srv.(MerlinServer).DiscardCandidate(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/types.Merlin/CreateService",
+		FullMethod: "/types.Merlin/DiscardCandidate",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(MerlinServer).CreateService(ctx, req.(*VirtualService))
+		return srv.(MerlinServer).DiscardCandidate(ctx, req.(*empty.Empty))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Merlin_UpdateService_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(VirtualService)
+func _Merlin_ListAuditEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAuditEventsRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(MerlinServer).UpdateService(ctx, in)
+		return srv.(MerlinServer).ListAuditEvents(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/types.Merlin/UpdateService",
+		FullMethod: "/types.Merlin/ListAuditEvents",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(MerlinServer).UpdateService(ctx, req.(*VirtualService))
+		return srv.(MerlinServer).ListAuditEvents(ctx, req.(*ListAuditEventsRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Merlin_DeleteService_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+func _Merlin_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MerlinServer).Watch(m, &merlinWatchServer{stream})
+}
+
+type Merlin_WatchServer interface {
+	Send(*WatchEvent) error
+	grpc.ServerStream
+}
+
+type merlinWatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *merlinWatchServer) Send(m *WatchEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Merlin_GetService_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(wrappers.StringValue)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(MerlinServer).DeleteService(ctx, in)
+		return srv.(MerlinServer).GetService(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/types.Merlin/DeleteService",
+		FullMethod: "/types.Merlin/GetService",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(MerlinServer).DeleteService(ctx, req.(*wrappers.StringValue))
+		return srv.(MerlinServer).GetService(ctx, req.(*wrappers.StringValue))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Merlin_CreateServer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+func _Merlin_GetServer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(RealServer)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(MerlinServer).CreateServer(ctx, in)
+		return srv.(MerlinServer).GetServer(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/types.Merlin/CreateServer",
+		FullMethod: "/types.Merlin/GetServer",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(MerlinServer).CreateServer(ctx, req.(*RealServer))
+		return srv.(MerlinServer).GetServer(ctx, req.(*RealServer))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Merlin_UpdateServer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(RealServer)
+func _Merlin_Apply_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ApplyRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(MerlinServer).UpdateServer(ctx, in)
+		return srv.(MerlinServer).Apply(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/types.Merlin/UpdateServer",
+		FullMethod: "/types.Merlin/Apply",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(MerlinServer).UpdateServer(ctx, req.(*RealServer))
+		return srv.(MerlinServer).Apply(ctx, req.(*ApplyRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Merlin_DeleteServer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(RealServer)
+func _Merlin_GetServerStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(wrappers.StringValue)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(MerlinServer).DeleteServer(ctx, in)
+		return srv.(MerlinServer).GetServerStats(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/types.Merlin/DeleteServer",
+		FullMethod: "/types.Merlin/GetServerStats",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(MerlinServer).DeleteServer(ctx, req.(*RealServer))
+		return srv.(MerlinServer).GetServerStats(ctx, req.(*wrappers.StringValue))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Merlin_List_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+func _Merlin_ListConnections_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListConnectionsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MerlinServer).ListConnections(m, &merlinListConnectionsServer{stream})
+}
+
+type Merlin_ListConnectionsServer interface {
+	Send(*Connection) error
+	grpc.ServerStream
+}
+
+type merlinListConnectionsServer struct {
+	grpc.ServerStream
+}
+
+func (x *merlinListConnectionsServer) Send(m *Connection) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Merlin_GetSyncDaemons_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(empty.Empty)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(MerlinServer).List(ctx, in)
+		return srv.(MerlinServer).GetSyncDaemons(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/types.Merlin/List",
+		FullMethod: "/types.Merlin/GetSyncDaemons",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MerlinServer).GetSyncDaemons(ctx, req.(*empty.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Merlin_SetSyncRole_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetSyncRoleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MerlinServer).SetSyncRole(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/types.Merlin/SetSyncRole",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(MerlinServer).List(ctx, req.(*empty.Empty))
+		return srv.(MerlinServer).SetSyncRole(ctx, req.(*SetSyncRoleRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
@@ -911,6 +5397,18 @@ var _Merlin_serviceDesc = grpc.ServiceDesc{
 			MethodName: "DeleteService",
 			Handler:    _Merlin_DeleteService_Handler,
 		},
+		{
+			MethodName: "CloneService",
+			Handler:    _Merlin_CloneService_Handler,
+		},
+		{
+			MethodName: "RenameService",
+			Handler:    _Merlin_RenameService_Handler,
+		},
+		{
+			MethodName: "SwapServers",
+			Handler:    _Merlin_SwapServers_Handler,
+		},
 		{
 			MethodName: "CreateServer",
 			Handler:    _Merlin_CreateServer_Handler,
@@ -923,11 +5421,122 @@ var _Merlin_serviceDesc = grpc.ServiceDesc{
 			MethodName: "DeleteServer",
 			Handler:    _Merlin_DeleteServer_Handler,
 		},
+		{
+			MethodName: "DrainServer",
+			Handler:    _Merlin_DrainServer_Handler,
+		},
+		{
+			MethodName: "UndrainServer",
+			Handler:    _Merlin_UndrainServer_Handler,
+		},
 		{
 			MethodName: "List",
 			Handler:    _Merlin_List_Handler,
 		},
+		{
+			MethodName: "Stats",
+			Handler:    _Merlin_Stats_Handler,
+		},
+		{
+			MethodName: "GetNodeState",
+			Handler:    _Merlin_GetNodeState_Handler,
+		},
+		{
+			MethodName: "DescribeService",
+			Handler:    _Merlin_DescribeService_Handler,
+		},
+		{
+			MethodName: "GetSnapshot",
+			Handler:    _Merlin_GetSnapshot_Handler,
+		},
+		{
+			MethodName: "ApplySnapshot",
+			Handler:    _Merlin_ApplySnapshot_Handler,
+		},
+		{
+			MethodName: "Info",
+			Handler:    _Merlin_Info_Handler,
+		},
+		{
+			MethodName: "ListNodes",
+			Handler:    _Merlin_ListNodes_Handler,
+		},
+		{
+			MethodName: "SetMaintenance",
+			Handler:    _Merlin_SetMaintenance_Handler,
+		},
+		{
+			MethodName: "RolloutService",
+			Handler:    _Merlin_RolloutService_Handler,
+		},
+		{
+			MethodName: "PutPool",
+			Handler:    _Merlin_PutPool_Handler,
+		},
+		{
+			MethodName: "DeletePool",
+			Handler:    _Merlin_DeletePool_Handler,
+		},
+		{
+			MethodName: "ListPools",
+			Handler:    _Merlin_ListPools_Handler,
+		},
+		{
+			MethodName: "GetCandidate",
+			Handler:    _Merlin_GetCandidate_Handler,
+		},
+		{
+			MethodName: "Commit",
+			Handler:    _Merlin_Commit_Handler,
+		},
+		{
+			MethodName: "ConfirmCommit",
+			Handler:    _Merlin_ConfirmCommit_Handler,
+		},
+		{
+			MethodName: "DiscardCandidate",
+			Handler:    _Merlin_DiscardCandidate_Handler,
+		},
+		{
+			MethodName: "ListAuditEvents",
+			Handler:    _Merlin_ListAuditEvents_Handler,
+		},
+		{
+			MethodName: "GetService",
+			Handler:    _Merlin_GetService_Handler,
+		},
+		{
+			MethodName: "GetServer",
+			Handler:    _Merlin_GetServer_Handler,
+		},
+		{
+			MethodName: "Apply",
+			Handler:    _Merlin_Apply_Handler,
+		},
+		{
+			MethodName: "GetServerStats",
+			Handler:    _Merlin_GetServerStats_Handler,
+		},
+		{
+			MethodName: "GetSyncDaemons",
+			Handler:    _Merlin_GetSyncDaemons_Handler,
+		},
+		{
+			MethodName: "SetSyncRole",
+			Handler:    _Merlin_SetSyncRole_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Watch",
+			Handler:       _Merlin_Watch_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "ListConnections",
+			Handler:       _Merlin_ListConnections_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "types/types.proto",
 }
@@ -2,25 +2,20 @@ package types
 
 import (
 	"fmt"
-	"sort"
 	"strings"
 
 	"github.com/golang/protobuf/ptypes"
 )
 
-// SortFlags of the virtual service in place.
-func (s *VirtualService) SortFlags() {
-	if s.Config != nil && len(s.Config.Flags) > 0 {
-		// flags are expected to be in order to compare against local state
-		sort.Strings(s.Config.Flags)
-	}
-}
-
 func (s *VirtualService) PrettyString() string {
 	if s == nil {
 		return "nil"
 	}
-	return fmt.Sprintf("%s [%s] [%s]", s.Id, s.GetKey().PrettyString(), s.GetConfig().PrettyString())
+	str := fmt.Sprintf("%s [%s] [%s]", s.Id, s.GetKey().PrettyString(), s.GetConfig().PrettyString())
+	if len(s.Labels) > 0 {
+		str += fmt.Sprintf(" [%s]", PrettyLabels(s.Labels))
+	}
+	return str
 }
 
 func (k *VirtualService_Key) PrettyString() string {
@@ -34,15 +29,23 @@ func (c *VirtualService_Config) PrettyString() string {
 	if c == nil {
 		return "nil"
 	}
-	return fmt.Sprintf("%s (%v)", c.Scheduler, strings.Join(c.Flags, ","))
+	str := fmt.Sprintf("%s (%v)", c.Scheduler, strings.Join(c.Flags, ","))
+	if c.Ops {
+		str += " ops"
+	}
+	return str
 }
 
 func (r *RealServer) PrettyString() string {
 	if r == nil {
 		return "nil"
 	}
-	return fmt.Sprintf("%s [-> %v] [%v] [%v]", r.ServiceID, r.GetKey().PrettyString(), r.GetConfig().PrettyString(),
+	str := fmt.Sprintf("%s [-> %v] [%v] [%v]", r.ServiceID, r.GetKey().PrettyString(), r.GetConfig().PrettyString(),
 		r.GetHealthCheck().PrettyString())
+	if len(r.Labels) > 0 {
+		str += fmt.Sprintf(" [%s]", PrettyLabels(r.Labels))
+	}
+	return str
 }
 
 func (k *RealServer_Key) PrettyString() string {
@@ -56,7 +59,14 @@ func (c *RealServer_Config) PrettyString() string {
 	if c == nil {
 		return "nil"
 	}
-	return fmt.Sprintf("%v weight:%v", c.Forward, c.Weight.GetValue())
+	str := fmt.Sprintf("%v weight:%v", c.Forward, c.Weight.GetValue())
+	if c.TunnelType != TunnelType_IPIP {
+		str += fmt.Sprintf(" tunnel:%v:%d", c.TunnelType, c.TunnelPort)
+	}
+	if c.TunnelChecksum != TunnelChecksum_NO_CHECKSUM {
+		str += fmt.Sprintf(" checksum:%v", c.TunnelChecksum)
+	}
+	return str
 }
 
 func (h *RealServer_HealthCheck) PrettyString() string {
@@ -65,6 +75,21 @@ func (h *RealServer_HealthCheck) PrettyString() string {
 	}
 	period, _ := ptypes.Duration(h.Period)
 	timeout, _ := ptypes.Duration(h.Timeout)
-	return fmt.Sprintf("%s every:%v timeout:%v up:%d down:%d", h.Endpoint.GetValue(), period, timeout,
+	str := fmt.Sprintf("%s every:%v timeout:%v up:%d down:%d", h.Endpoint.GetValue(), period, timeout,
 		h.UpThreshold, h.DownThreshold)
+	if h.ExpectedStatus != 0 {
+		str += fmt.Sprintf(" status:%d", h.ExpectedStatus)
+	}
+	return str
+}
+
+func (c *Change) PrettyString() string {
+	if c == nil {
+		return "nil"
+	}
+	action := strings.ToLower(c.Action.String())
+	if c.Service != nil {
+		return fmt.Sprintf("%s service %s", action, c.Service.PrettyString())
+	}
+	return fmt.Sprintf("%s server %s", action, c.Server.PrettyString())
 }
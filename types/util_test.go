@@ -30,6 +30,7 @@ var _ = Describe("Util", func() {
 				Scheduler: "sh",
 				Flags:     []string{"flag-1", "flag-2"},
 			},
+			Labels: map[string]string{"team": "payments"},
 		}
 
 		str := svc.PrettyString()
@@ -61,4 +62,21 @@ var _ = Describe("Util", func() {
 		Expect(str).ToNot(BeEmpty())
 		fmt.Println(str)
 	})
+	It("pretty prints Change", func() {
+		change := &Change{
+			Action: Change_DELETE,
+			Service: &VirtualService{
+				Id: "http-proxy",
+				Key: &VirtualService_Key{
+					Ip:       "10.10.10.1",
+					Port:     101,
+					Protocol: Protocol_TCP,
+				},
+			},
+		}
+
+		str := change.PrettyString()
+		Expect(str).To(HavePrefix("delete service "))
+		fmt.Println(str)
+	})
 })
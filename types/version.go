@@ -0,0 +1,120 @@
+package types
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// APIVersion is the semantic version of the merlin API. The minor version is bumped when fields or RPCs are
+	// added, and the major version when they are changed or removed.
+	APIVersion = "1.0.0"
+	// APIVersionHeader is the response metadata merlin advertises its APIVersion in.
+	APIVersionHeader = "x-merlin-api-version"
+	// MaxMinorSkew is how many minor versions clients and servers of the same major version can differ by.
+	MaxMinorSkew = 1
+)
+
+// CheckAPIVersion returns an error if version, advertised by a server, is outside the skew supported by a client
+// of APIVersion. Servers older than the client drop the fields they don't know, without an error.
+func CheckAPIVersion(version string) error {
+	if version == "" {
+		return fmt.Errorf("merlin doesn't advertise its API version, so it's older than API %s: "+
+			"fields it doesn't know may be silently lost", APIVersion)
+	}
+	major, minor, err := parseAPIVersion(version)
+	if err != nil {
+		return err
+	}
+	clientMajor, clientMinor, _ := parseAPIVersion(APIVersion)
+	skew := minor - clientMinor
+	if skew < 0 {
+		skew = -skew
+	}
+	if major != clientMajor || skew > MaxMinorSkew {
+		return fmt.Errorf("merlin API %s is outside the supported skew of this client's API %s: "+
+			"fields it doesn't know may be silently lost, upgrade the older of the two", version, APIVersion)
+	}
+	return nil
+}
+
+func parseAPIVersion(version string) (major, minor int, err error) {
+	parts := strings.Split(version, ".")
+	if len(parts) != 3 {
+		return 0, 0, fmt.Errorf("invalid API version %q, must be major.minor.patch", version)
+	}
+	if major, err = strconv.Atoi(parts[0]); err != nil {
+		return 0, 0, fmt.Errorf("invalid API version %q, must be major.minor.patch", version)
+	}
+	if minor, err = strconv.Atoi(parts[1]); err != nil {
+		return 0, 0, fmt.Errorf("invalid API version %q, must be major.minor.patch", version)
+	}
+	return major, minor, nil
+}
+
+// VersionCheckInterceptor returns a client interceptor which checks the API version merlin advertises, once per
+// connection. Unsupported versions are passed to warn after the first successful request. If strict, merlin's
+// version is checked with an Info request before any other, and requests fail with codes.FailedPrecondition if
+// it's unsupported.
+func VersionCheckInterceptor(strict bool, warn func(error)) grpc.UnaryClientInterceptor {
+	var (
+		mu      sync.Mutex
+		checked bool
+		result  error
+	)
+	check := func(header metadata.MD) {
+		mu.Lock()
+		defer mu.Unlock()
+		if checked {
+			return
+		}
+		checked = true
+		var version string
+		if values := header.Get(APIVersionHeader); len(values) > 0 {
+			version = values[0]
+		}
+		if result = CheckAPIVersion(version); result != nil && !strict {
+			warn(result)
+		}
+	}
+	checkResult := func() (bool, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return checked, result
+	}
+
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if strict {
+			done, err := checkResult()
+			if !done {
+				var header metadata.MD
+				if err := invoker(ctx, "/types.Merlin/Info", &empty.Empty{}, &InfoResponse{}, cc,
+					append(opts, grpc.Header(&header))...); err != nil {
+					return err
+				}
+				check(header)
+				_, err = checkResult()
+			}
+			if err != nil {
+				return status.Error(codes.FailedPrecondition, err.Error())
+			}
+		}
+
+		var header metadata.MD
+		err := invoker(ctx, method, req, reply, cc, append(opts, grpc.Header(&header))...)
+		if err == nil {
+			check(header)
+		}
+		return err
+	}
+}
@@ -0,0 +1,100 @@
+package types
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var _ = Describe("Version", func() {
+	DescribeTable("checks the API version is within the supported skew", func(version string, supported bool) {
+		err := CheckAPIVersion(version)
+
+		if supported {
+			Expect(err).ToNot(HaveOccurred())
+		} else {
+			Expect(err).To(HaveOccurred())
+		}
+	},
+		Entry("same version", APIVersion, true),
+		Entry("different patch", "1.0.7", true),
+		Entry("one minor newer", "1.1.0", true),
+		Entry("two minors newer", "1.2.0", false),
+		Entry("different major", "2.0.0", false),
+		Entry("not advertised", "", false),
+		Entry("invalid", "1.x", false),
+	)
+
+	Describe("VersionCheckInterceptor", func() {
+		var (
+			methods  []string
+			version  string
+			warnings []error
+		)
+
+		// invoker fakes merlin, recording the methods called and responding with version in the header.
+		invoker := func(_ context.Context, method string, _, _ interface{}, _ *grpc.ClientConn,
+			opts ...grpc.CallOption) error {
+			methods = append(methods, method)
+			for _, opt := range opts {
+				if h, ok := opt.(grpc.HeaderCallOption); ok && version != "" {
+					*h.HeaderAddr = metadata.Pairs(APIVersionHeader, version)
+				}
+			}
+			return nil
+		}
+		warn := func(err error) { warnings = append(warnings, err) }
+
+		BeforeEach(func() {
+			methods = nil
+			warnings = nil
+			version = "3.0.0"
+		})
+
+		It("should warn once of an unsupported version", func() {
+			check := VersionCheckInterceptor(false, warn)
+
+			Expect(check(context.Background(), "/types.Merlin/List", nil, nil, nil, invoker)).To(Succeed())
+			Expect(check(context.Background(), "/types.Merlin/List", nil, nil, nil, invoker)).To(Succeed())
+
+			Expect(methods).To(Equal([]string{"/types.Merlin/List", "/types.Merlin/List"}))
+			Expect(warnings).To(HaveLen(1))
+		})
+
+		It("should not warn of a supported version", func() {
+			version = APIVersion
+			check := VersionCheckInterceptor(false, warn)
+
+			Expect(check(context.Background(), "/types.Merlin/List", nil, nil, nil, invoker)).To(Succeed())
+
+			Expect(warnings).To(BeEmpty())
+		})
+
+		It("should refuse requests to an unsupported version if strict", func() {
+			check := VersionCheckInterceptor(true, warn)
+
+			err := check(context.Background(), "/types.Merlin/CreateService", nil, nil, nil, invoker)
+
+			Expect(status.Code(err)).To(Equal(codes.FailedPrecondition))
+			Expect(methods).To(Equal([]string{"/types.Merlin/Info"}))
+			Expect(warnings).To(BeEmpty())
+		})
+
+		It("should check the version once before requests if strict", func() {
+			version = APIVersion
+			check := VersionCheckInterceptor(true, warn)
+
+			Expect(check(context.Background(), "/types.Merlin/CreateService", nil, nil, nil, invoker)).To(Succeed())
+			Expect(check(context.Background(), "/types.Merlin/List", nil, nil, nil, invoker)).To(Succeed())
+
+			Expect(methods).To(Equal([]string{"/types.Merlin/Info", "/types.Merlin/CreateService",
+				"/types.Merlin/List"}))
+		})
+	})
+})
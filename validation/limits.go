@@ -0,0 +1,116 @@
+package validation
+
+import (
+	"github.com/golang/protobuf/proto"
+	"github.com/sky-uk/merlin/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Limits on the size of services and servers, so a buggy client can't write objects large enough to destabilize
+// the store and everything watching it. Limits which are 0 aren't enforced.
+type Limits struct {
+	// MaxIDLength of service IDs.
+	MaxIDLength int
+	// MaxLabels of each service and server.
+	MaxLabels int
+	// MaxLabelSize of each label key and value.
+	MaxLabelSize int
+	// MaxObjectSize of each service and server, encoded as protobuf.
+	MaxObjectSize int
+	// MaxServersPerService, which the server checks as servers are added.
+	MaxServersPerService int
+}
+
+// Service returns an InvalidArgument status error if the service exceeds the limits.
+func (l Limits) Service(svc *types.VirtualService) error {
+	if err := l.id(svc.Id); err != nil {
+		return err
+	}
+	if err := l.labels("service", svc.Labels); err != nil {
+		return err
+	}
+	return l.size("service", svc)
+}
+
+// Server returns an InvalidArgument status error if the server exceeds the limits.
+func (l Limits) Server(server *types.RealServer) error {
+	if err := l.id(server.ServiceID); err != nil {
+		return err
+	}
+	if err := l.labels("server", server.Labels); err != nil {
+		return err
+	}
+	return l.size("server", server)
+}
+
+// Snapshot returns an InvalidArgument status error if any service or server of the snapshot exceeds the limits,
+// or it has more servers of a service than MaxServersPerService.
+func (l Limits) Snapshot(snapshot *types.Snapshot) error {
+	for _, svc := range snapshot.GetServices() {
+		if err := l.Service(svc); err != nil {
+			return invalidItem("service "+truncate(svc.Id), err)
+		}
+	}
+	servers := make(map[string]int)
+	for _, server := range snapshot.GetServers() {
+		if err := l.Server(server); err != nil {
+			return invalidItem("server "+truncate(server.ServiceID)+"/"+server.Key.PrettyString(), err)
+		}
+		servers[server.ServiceID]++
+		if err := l.Servers(server.ServiceID, servers[server.ServiceID]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Servers returns an InvalidArgument status error if n servers exceeds MaxServersPerService.
+func (l Limits) Servers(serviceID string, n int) error {
+	if l.MaxServersPerService > 0 && n > l.MaxServersPerService {
+		return status.Errorf(codes.InvalidArgument, "service %s is limited to %d servers", truncate(serviceID),
+			l.MaxServersPerService)
+	}
+	return nil
+}
+
+func (l Limits) labels(kind string, labels map[string]string) error {
+	if l.MaxLabels > 0 && len(labels) > l.MaxLabels {
+		return status.Errorf(codes.InvalidArgument, "%s has %d labels, limit is %d", kind, len(labels), l.MaxLabels)
+	}
+	if l.MaxLabelSize > 0 {
+		for k, v := range labels {
+			if len(k) > l.MaxLabelSize || len(v) > l.MaxLabelSize {
+				return status.Errorf(codes.InvalidArgument, "label %s has a key or value longer than %d characters",
+					truncate(k+"="+v), l.MaxLabelSize)
+			}
+		}
+	}
+	return nil
+}
+
+func (l Limits) id(id string) error {
+	if l.MaxIDLength > 0 && len(id) > l.MaxIDLength {
+		return status.Errorf(codes.InvalidArgument, "service id %s is longer than %d characters", truncate(id),
+			l.MaxIDLength)
+	}
+	return nil
+}
+
+func (l Limits) size(kind string, pb proto.Message) error {
+	if l.MaxObjectSize == 0 {
+		return nil
+	}
+	if size := proto.Size(pb); size > l.MaxObjectSize {
+		return status.Errorf(codes.InvalidArgument, "%s is %d bytes, limit is %d", kind, size, l.MaxObjectSize)
+	}
+	return nil
+}
+
+// truncate an ID which may be pathologically long, so it can be included in errors.
+func truncate(id string) string {
+	if len(id) > 64 {
+		return id[:64] + "..."
+	}
+	return id
+}
@@ -0,0 +1,69 @@
+package validation
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sky-uk/merlin/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var _ = Describe("Limits", func() {
+	limits := Limits{MaxIDLength: 10, MaxLabels: 2, MaxLabelSize: 5, MaxObjectSize: 200, MaxServersPerService: 2}
+
+	expectInvalid := func(err error, msg string) {
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+		Expect(status.Convert(err).Message()).To(ContainSubstring(msg))
+	}
+
+	service := func() *types.VirtualService {
+		return &types.VirtualService{Id: "web", Labels: map[string]string{"team": "a"}}
+	}
+	server := func(ip string) *types.RealServer {
+		return &types.RealServer{ServiceID: "web", Key: &types.RealServer_Key{Ip: ip, Port: 80}}
+	}
+
+	It("should allow services and servers within the limits", func() {
+		Expect(limits.Service(service())).To(Succeed())
+		Expect(limits.Server(server("10.0.0.1"))).To(Succeed())
+		Expect(Limits{}.Service(&types.VirtualService{Id: strings.Repeat("a", 1000)})).To(Succeed())
+	})
+
+	It("should reject long IDs", func() {
+		svc := service()
+		svc.Id = strings.Repeat("a", 100)
+		expectInvalid(limits.Service(svc), "longer than 10 characters")
+		s := server("10.0.0.1")
+		s.ServiceID = svc.Id
+		expectInvalid(limits.Server(s), "longer than 10 characters")
+	})
+
+	It("should reject too many or too long labels", func() {
+		svc := service()
+		svc.Labels = map[string]string{"a": "1", "b": "2", "c": "3"}
+		expectInvalid(limits.Service(svc), "service has 3 labels, limit is 2")
+		svc.Labels = map[string]string{"team": "payments"}
+		expectInvalid(limits.Service(svc), "label team=payments has a key or value longer than 5 characters")
+		s := server("10.0.0.1")
+		s.Labels = map[string]string{"a": "1", "b": "2", "c": "3"}
+		expectInvalid(limits.Server(s), "server has 3 labels, limit is 2")
+	})
+
+	It("should reject large objects", func() {
+		svc := service()
+		svc.NodeSelector = strings.Repeat("a", 500)
+		expectInvalid(limits.Service(svc), "service is 5")
+	})
+
+	It("should reject snapshots with too many servers of a service", func() {
+		snapshot := &types.Snapshot{
+			Services: []*types.VirtualService{service()},
+			Servers:  []*types.RealServer{server("10.0.0.1"), server("10.0.0.2")},
+		}
+		Expect(limits.Snapshot(snapshot)).To(Succeed())
+		snapshot.Servers = append(snapshot.Servers, server("10.0.0.3"))
+		expectInvalid(limits.Snapshot(snapshot), "service web is limited to 2 servers")
+	})
+})
@@ -0,0 +1,184 @@
+package validation
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"regexp"
+
+	"github.com/sky-uk/merlin/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/yaml.v2"
+)
+
+// Rule is a site policy which services and servers must follow, on top of being valid. Rules are checked by the
+// server before it accepts a change, so they can be added by sites embedding merlin, or configured with LoadRules.
+type Rule interface {
+	// Name of the rule, which is included in its errors.
+	Name() string
+	// CheckService returns an error if the service violates the rule.
+	CheckService(svc *types.VirtualService) error
+	// CheckServer returns an error if the server of the service violates the rule.
+	CheckServer(svc *types.VirtualService, server *types.RealServer) error
+}
+
+// CheckRules returns an InvalidArgument status error naming the first rule the service violates, or its server if
+// server isn't nil.
+func CheckRules(rules []Rule, svc *types.VirtualService, server *types.RealServer) error {
+	for _, rule := range rules {
+		var err error
+		if server == nil {
+			err = rule.CheckService(svc)
+		} else {
+			err = rule.CheckServer(svc, server)
+		}
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "violates rule %s: %v", rule.Name(), err)
+		}
+	}
+	return nil
+}
+
+// rulesFile is the format of a rules file.
+type rulesFile struct {
+	Rules []*configRule `yaml:"rules"`
+}
+
+// configRule is a rule set in a rules file. Every condition which is set must hold.
+type configRule struct {
+	RuleName string `yaml:"name"`
+	// Services is a label selector of the services the rule applies to, every service if empty.
+	Services string `yaml:"services,omitempty"`
+	// IDPattern is a regular expression service IDs must match.
+	IDPattern string `yaml:"id-pattern,omitempty"`
+	// RequiredLabels services must have.
+	RequiredLabels []string `yaml:"required-labels,omitempty"`
+	// VIPCIDRs service IPs must be in one of.
+	VIPCIDRs []string `yaml:"vip-cidrs,omitempty"`
+	// ServerCIDRs server IPs must be in one of.
+	ServerCIDRs []string `yaml:"server-cidrs,omitempty"`
+
+	selector   types.Selector
+	idPattern  *regexp.Regexp
+	vipNets    []*net.IPNet
+	serverNets []*net.IPNet
+}
+
+// LoadRules reads rules from a yaml file of the form:
+//
+//	rules:
+//	- name: public-vips
+//	  services: exposure=public
+//	  id-pattern: ^[a-z0-9-]+$
+//	  required-labels: [team]
+//	  vip-cidrs: [10.1.0.0/16]
+//	  server-cidrs: [172.16.0.0/12]
+func LoadRules(path string) ([]Rule, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := ParseRules(data)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %v", path, err)
+	}
+	return rules, nil
+}
+
+// ParseRules parses rules in the format read by LoadRules.
+func ParseRules(data []byte) ([]Rule, error) {
+	var file rulesFile
+	if err := yaml.UnmarshalStrict(data, &file); err != nil {
+		return nil, err
+	}
+	var rules []Rule
+	names := make(map[string]bool)
+	for i, r := range file.Rules {
+		if r.RuleName == "" {
+			return nil, fmt.Errorf("rule %d has no name", i+1)
+		}
+		if names[r.RuleName] {
+			return nil, fmt.Errorf("duplicate rule %s", r.RuleName)
+		}
+		names[r.RuleName] = true
+		if err := r.compile(); err != nil {
+			return nil, fmt.Errorf("rule %s: %v", r.RuleName, err)
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+func (r *configRule) compile() error {
+	var err error
+	if r.selector, err = types.ParseSelector(r.Services); err != nil {
+		return fmt.Errorf("invalid services selector: %v", err)
+	}
+	if r.IDPattern != "" {
+		if r.idPattern, err = regexp.Compile(r.IDPattern); err != nil {
+			return fmt.Errorf("invalid id-pattern: %v", err)
+		}
+	}
+	if r.vipNets, err = parseCIDRs(r.VIPCIDRs); err != nil {
+		return fmt.Errorf("invalid vip-cidrs: %v", err)
+	}
+	if r.serverNets, err = parseCIDRs(r.ServerCIDRs); err != nil {
+		return fmt.Errorf("invalid server-cidrs: %v", err)
+	}
+	return nil
+}
+
+func parseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+func (r *configRule) Name() string {
+	return r.RuleName
+}
+
+func (r *configRule) CheckService(svc *types.VirtualService) error {
+	if !r.selector.Matches(svc.Labels) {
+		return nil
+	}
+	if r.idPattern != nil && !r.idPattern.MatchString(svc.Id) {
+		return fmt.Errorf("service id %q must match %s", svc.Id, r.IDPattern)
+	}
+	for _, label := range r.RequiredLabels {
+		if _, ok := svc.Labels[label]; !ok {
+			return fmt.Errorf("service must have label %q", label)
+		}
+	}
+	if len(r.vipNets) > 0 && !contains(r.vipNets, svc.GetKey().GetIp()) {
+		return fmt.Errorf("service IP %s must be in %v", svc.GetKey().GetIp(), r.VIPCIDRs)
+	}
+	return nil
+}
+
+func (r *configRule) CheckServer(svc *types.VirtualService, server *types.RealServer) error {
+	if !r.selector.Matches(svc.Labels) {
+		return nil
+	}
+	if len(r.serverNets) > 0 && !contains(r.serverNets, server.GetKey().GetIp()) {
+		return fmt.Errorf("server IP %s must be in %v", server.GetKey().GetIp(), r.ServerCIDRs)
+	}
+	return nil
+}
+
+func contains(nets []*net.IPNet, ip string) bool {
+	parsed := net.ParseIP(ip)
+	for _, n := range nets {
+		if n.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package validation
+
+import (
+	"github.com/golang/protobuf/ptypes/wrappers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"github.com/sky-uk/merlin/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var _ = Describe("Rules", func() {
+	const rulesYAML = `
+rules:
+- name: naming
+  id-pattern: ^[a-z0-9-]+$
+- name: public
+  services: exposure=public
+  required-labels: [team]
+  vip-cidrs: [10.1.0.0/16]
+  server-cidrs: [172.16.0.0/12]
+`
+	var (
+		rules []Rule
+		svc   *types.VirtualService
+	)
+
+	BeforeEach(func() {
+		var err error
+		rules, err = ParseRules([]byte(rulesYAML))
+		Expect(err).ToNot(HaveOccurred())
+		svc = &types.VirtualService{
+			Id:     "web-1",
+			Key:    &types.VirtualService_Key{Ip: "10.1.1.1", Port: 80, Protocol: types.Protocol_TCP},
+			Labels: map[string]string{"exposure": "public", "team": "payments"},
+		}
+	})
+
+	server := func(ip string) *types.RealServer {
+		return &types.RealServer{
+			ServiceID: svc.Id,
+			Key:       &types.RealServer_Key{Ip: ip, Port: 8080},
+			Config:    &types.RealServer_Config{Weight: &wrappers.UInt32Value{Value: 1}},
+		}
+	}
+
+	expectViolation := func(err error, msg string) {
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+		Expect(status.Convert(err).Message()).To(ContainSubstring(msg))
+	}
+
+	It("accepts services and servers following the rules", func() {
+		Expect(CheckRules(rules, svc, nil)).To(Succeed())
+		Expect(CheckRules(rules, svc, server("172.16.1.1"))).To(Succeed())
+	})
+
+	It("rejects service IDs not matching the pattern", func() {
+		svc.Id = "Web_1"
+
+		expectViolation(CheckRules(rules, svc, nil), `violates rule naming: service id "Web_1" must match`)
+	})
+
+	It("rejects services without required labels", func() {
+		delete(svc.Labels, "team")
+
+		expectViolation(CheckRules(rules, svc, nil), `violates rule public: service must have label "team"`)
+	})
+
+	It("rejects VIPs outside the allowed CIDRs", func() {
+		svc.Key.Ip = "10.2.1.1"
+
+		expectViolation(CheckRules(rules, svc, nil), "violates rule public: service IP 10.2.1.1 must be in")
+	})
+
+	It("rejects servers outside the allowed CIDRs", func() {
+		expectViolation(CheckRules(rules, svc, server("192.168.1.1")),
+			"violates rule public: server IP 192.168.1.1 must be in")
+	})
+
+	It("only applies rules to the services they select", func() {
+		svc.Labels = nil
+		svc.Key.Ip = "10.2.1.1"
+
+		Expect(CheckRules(rules, svc, nil)).To(Succeed())
+		Expect(CheckRules(rules, svc, server("192.168.1.1"))).To(Succeed())
+	})
+
+	DescribeTable("rejects invalid rules",
+		func(yaml, msg string) {
+			_, err := ParseRules([]byte(yaml))
+			Expect(err).To(MatchError(ContainSubstring(msg)))
+		},
+		Entry("missing name", "rules:\n- id-pattern: a", "rule 1 has no name"),
+		Entry("duplicate name", "rules:\n- name: a\n- name: a", "duplicate rule a"),
+		Entry("unknown field", "rules:\n- name: a\n  colour: red", "field colour not found"),
+		Entry("invalid selector", "rules:\n- name: a\n  services: '!'", "invalid services selector"),
+		Entry("invalid pattern", "rules:\n- name: a\n  id-pattern: '('", "invalid id-pattern"),
+		Entry("invalid CIDR", "rules:\n- name: a\n  vip-cidrs: [10.1.0.0]", "invalid vip-cidrs"),
+	)
+})
@@ -0,0 +1,290 @@
+// Package validation checks services and servers are valid before they're stored. It's shared by the merlin
+// server and meradm, so manifests can be checked offline.
+package validation
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/sky-uk/merlin/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Service returns an InvalidArgument status error if the virtual service is invalid.
+func Service(service *types.VirtualService) error {
+	if len(service.Id) == 0 {
+		return status.Error(codes.InvalidArgument, "service id required")
+	}
+	if service.Key == nil {
+		return status.Error(codes.InvalidArgument, "service ip:port:protocol key required")
+	}
+	if len(service.Key.Ip) == 0 {
+		return status.Error(codes.InvalidArgument, "service IP required")
+	}
+	if net.ParseIP(service.Key.Ip) == nil {
+		return status.Error(codes.InvalidArgument, "unable to parse service IP")
+	}
+	if service.Key.Port == 0 {
+		return status.Error(codes.InvalidArgument, "service port required")
+	}
+	if service.Key.Port > math.MaxUint16 {
+		return status.Errorf(codes.InvalidArgument, "invalid port %d", service.Key.Port)
+	}
+	if service.Key.Protocol == 0 {
+		return status.Error(codes.InvalidArgument, "service protocol required")
+	}
+	if _, ok := types.Protocol_name[int32(service.Key.Protocol)]; !ok {
+		return status.Errorf(codes.InvalidArgument, "unrecognized protocol %d", service.Key.Protocol)
+	}
+	if service.Config == nil {
+		return status.Error(codes.InvalidArgument, "service config required")
+	}
+	if service.Config.Scheduler == "" {
+		return status.Error(codes.InvalidArgument, "service scheduler required")
+	}
+	for _, flag := range service.Config.Flags {
+		if _, ok := types.SchedulerFlag(service.Config.Scheduler, flag); !ok {
+			return status.Errorf(codes.InvalidArgument, "unknown flag %q of scheduler %s, expected one of %s", flag,
+				service.Config.Scheduler, strings.Join(types.SchedulerFlags(service.Config.Scheduler), ", "))
+		}
+	}
+	if service.Config.Ops && service.Key.Protocol != types.Protocol_UDP {
+		return status.Error(codes.InvalidArgument, "one-packet scheduling is only possible with UDP services")
+	}
+	if err := types.ValidateLabels(service.Labels); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if _, err := types.ParseSelector(service.NodeSelector); err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid node selector: %v", err)
+	}
+	if service.UpdateMask != nil {
+		return status.Error(codes.InvalidArgument, "update mask is only allowed when updating a service")
+	}
+	return nil
+}
+
+// Server returns an InvalidArgument status error if the real server is invalid.
+func Server(server *types.RealServer) error {
+	if len(server.ServiceID) == 0 {
+		return status.Error(codes.InvalidArgument, "service ID required")
+	}
+	if server.Key == nil {
+		return status.Error(codes.InvalidArgument, "server IP:port required")
+	}
+	if len(server.Key.Ip) == 0 {
+		return status.Error(codes.InvalidArgument, "server IP required")
+	}
+	if net.ParseIP(server.Key.Ip) == nil {
+		return status.Errorf(codes.InvalidArgument, "unable to parse server IP %s", server.Key.Ip)
+	}
+	if server.Key.Port == 0 {
+		return status.Error(codes.InvalidArgument, "server port required")
+	}
+	if server.Key.Port > math.MaxUint16 {
+		return status.Errorf(codes.InvalidArgument, "invalid port %d", server.Key.Port)
+	}
+	if server.Config == nil {
+		return status.Error(codes.InvalidArgument, "server config required")
+	}
+	if server.Config.Forward == types.ForwardMethod_UNSET_FORWARD_METHOD {
+		return status.Error(codes.InvalidArgument, "server forward method required")
+	}
+	if server.Config.Weight == nil {
+		return status.Error(codes.InvalidArgument, "server weight required")
+	}
+	if err := tunnel(server.Config); err != nil {
+		return err
+	}
+	if err := types.ValidateLabels(server.Labels); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if server.UpdateMask != nil {
+		return status.Error(codes.InvalidArgument, "update mask is only allowed when updating a server")
+	}
+	if server.GetHealthCheck().GetEndpoint().GetValue() != "" {
+		u, err := url.Parse(server.HealthCheck.Endpoint.Value)
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "health check endpoint %q must be a valid url: %v",
+				server.HealthCheck.Endpoint, err)
+		}
+		switch u.Scheme {
+		case "http", "tcp", "udp":
+			// valid
+		default:
+			return status.Errorf(codes.InvalidArgument, "health check endpoint scheme %q not recognized",
+				u.Scheme)
+		}
+		if code := server.HealthCheck.ExpectedStatus; code != 0 && u.Scheme != "http" {
+			return status.Errorf(codes.InvalidArgument, "health check expected status requires an http endpoint")
+		} else if code != 0 && (code < 100 || code > 599) {
+			return status.Errorf(codes.InvalidArgument, "health check expected status %d must be from 100 to 599",
+				code)
+		}
+		if u.Port() == "" {
+			return status.Errorf(codes.InvalidArgument, "health check endpoint is missing port")
+		}
+		if server.HealthCheck.GetPeriod().GetSeconds() == 0 && server.HealthCheck.GetPeriod().GetNanos() == 0 {
+			return status.Errorf(codes.InvalidArgument, "health check period is required")
+		}
+		if server.HealthCheck.GetTimeout().GetSeconds() == 0 && server.HealthCheck.GetPeriod().GetNanos() == 0 {
+			return status.Errorf(codes.InvalidArgument, "health check timeout is required")
+		}
+		if server.HealthCheck.DownThreshold == 0 {
+			return status.Errorf(codes.InvalidArgument, "health check down threshold is required and must be > 0")
+		}
+		if server.HealthCheck.UpThreshold == 0 {
+			return status.Errorf(codes.InvalidArgument, "health check up threshold is required and must be > 0")
+		}
+	}
+	return nil
+}
+
+// tunnel returns an InvalidArgument status error if the tunnel of the server config is invalid.
+func tunnel(config *types.RealServer_Config) error {
+	if config.Forward != types.ForwardMethod_TUNNEL && (config.TunnelType != types.TunnelType_IPIP ||
+		config.TunnelPort != 0 || config.TunnelChecksum != types.TunnelChecksum_NO_CHECKSUM) {
+		return status.Errorf(codes.InvalidArgument, "tunnel options are only possible with TUNNEL servers, not %v",
+			config.Forward)
+	}
+	if config.TunnelType == types.TunnelType_GUE {
+		if config.TunnelPort == 0 {
+			return status.Error(codes.InvalidArgument, "GUE tunnels require a tunnel port")
+		}
+		if config.TunnelPort > math.MaxUint16 {
+			return status.Errorf(codes.InvalidArgument, "invalid tunnel port %d", config.TunnelPort)
+		}
+		return nil
+	}
+	if config.TunnelPort != 0 || config.TunnelChecksum != types.TunnelChecksum_NO_CHECKSUM {
+		return status.Errorf(codes.InvalidArgument, "tunnel port and checksum are only possible with GUE tunnels")
+	}
+	return nil
+}
+
+// Pool returns an InvalidArgument status error if the pool of VIPs is invalid.
+func Pool(pool *types.VIPPool) error {
+	if pool.Name == "" {
+		return status.Error(codes.InvalidArgument, "pool name required")
+	}
+	if len(pool.Cidrs) == 0 {
+		return status.Error(codes.InvalidArgument, "pool CIDRs required")
+	}
+	for _, cidr := range pool.Cidrs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return status.Errorf(codes.InvalidArgument, "invalid pool CIDR %q", cidr)
+		}
+	}
+	return nil
+}
+
+// ServiceExists reports whether a service exists outside of a snapshot, for servers which refer to it.
+type ServiceExists func(serviceID string) bool
+
+// Snapshot validates every service and server in the snapshot, and that they are unique. Each server's service
+// must be in the snapshot, or exist according to exists if it's not nil.
+func Snapshot(snapshot *types.Snapshot, exists ServiceExists) error {
+	if snapshot == nil {
+		return status.Error(codes.InvalidArgument, "snapshot required")
+	}
+
+	services := make(map[string]bool)
+	for _, svc := range snapshot.Services {
+		if err := Service(svc); err != nil {
+			return invalidItem("service "+svc.Id, err)
+		}
+		if services[svc.Id] {
+			return status.Errorf(codes.InvalidArgument, "duplicate service %s", svc.Id)
+		}
+		services[svc.Id] = true
+	}
+
+	servers := make(map[string]bool)
+	for _, server := range snapshot.Servers {
+		id := ServerID(server.ServiceID, server.Key)
+		if err := Server(server); err != nil {
+			return invalidItem("server "+id, err)
+		}
+		if servers[id] {
+			return status.Errorf(codes.InvalidArgument, "duplicate server %s", id)
+		}
+		servers[id] = true
+
+		if !services[server.ServiceID] && (exists == nil || !exists(server.ServiceID)) {
+			return status.Errorf(codes.InvalidArgument, "service %q of server %s doesn't exist",
+				server.ServiceID, id)
+		}
+	}
+
+	if err := Overlaps(snapshot.Servers, snapshot.Services); err != nil {
+		return err
+	}
+	return PortMappings(snapshot.Servers, snapshot.Services)
+}
+
+// Overlaps returns an InvalidArgument status error if any of the servers has the address of one of the services,
+// which loops packets back through IPVS. The exception is a server with the address of its own service and the
+// LOCALNODE forward method, which delivers packets to the director itself.
+func Overlaps(servers []*types.RealServer, services []*types.VirtualService) error {
+	vips := make(map[string][]*types.VirtualService)
+	for _, svc := range services {
+		addr := address(svc.Key.Ip, svc.Key.Port)
+		vips[addr] = append(vips[addr], svc)
+	}
+	for _, server := range servers {
+		for _, svc := range vips[address(server.Key.Ip, server.Key.Port)] {
+			id := ServerID(server.ServiceID, server.Key)
+			switch {
+			case svc.Id != server.ServiceID:
+				return status.Errorf(codes.InvalidArgument, "server %s is the virtual service %s, "+
+					"which would loop packets through IPVS", id, svc.Id)
+			case server.Config.GetForward() != types.ForwardMethod_LOCALNODE:
+				return status.Errorf(codes.InvalidArgument, "server %s has the address of its service, "+
+					"which would loop packets through IPVS unless forwarded with LOCALNODE", id)
+			}
+		}
+	}
+	return nil
+}
+
+// PortMappings returns an InvalidArgument status error if any of the servers has a different port to its service,
+// without the MASQ forward method. Only NAT rewrites the destination port of packets, the other forward methods send
+// them to the server on the port of the service. Servers whose service isn't in services aren't checked.
+func PortMappings(servers []*types.RealServer, services []*types.VirtualService) error {
+	ports := make(map[string]uint32)
+	for _, svc := range services {
+		ports[svc.Id] = svc.Key.Port
+	}
+	for _, server := range servers {
+		port, ok := ports[server.ServiceID]
+		if !ok || port == server.Key.Port || server.Config.GetForward() == types.ForwardMethod_MASQ {
+			continue
+		}
+		return status.Errorf(codes.InvalidArgument, "server %s has port %d instead of port %d of its service, "+
+			"which is only possible when forwarded with MASQ", ServerID(server.ServiceID, server.Key),
+			server.Key.Port, port)
+	}
+	return nil
+}
+
+// ServerID uniquely identifies a real server.
+func ServerID(serviceID string, key *types.RealServer_Key) string {
+	return fmt.Sprintf("%s/%s", serviceID, key.PrettyString())
+}
+
+// address of an ip and port, normalising the ip so equal addresses are equal strings.
+func address(ip string, port uint32) string {
+	if parsed := net.ParseIP(ip); parsed != nil {
+		ip = parsed.String()
+	}
+	return net.JoinHostPort(ip, strconv.Itoa(int(port)))
+}
+
+// invalidItem adds the item name to a validation error.
+func invalidItem(name string, err error) error {
+	return status.Errorf(status.Code(err), "%s: %s", name, status.Convert(err).Message())
+}
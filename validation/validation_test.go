@@ -0,0 +1,207 @@
+package validation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sky-uk/merlin/types"
+	"google.golang.org/genproto/protobuf/field_mask"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestValidation(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Validation Suite")
+}
+
+var _ = Describe("Snapshot", func() {
+	var snapshot *types.Snapshot
+
+	BeforeEach(func() {
+		snapshot = &types.Snapshot{
+			Services: []*types.VirtualService{{
+				Id:     "service1",
+				Key:    &types.VirtualService_Key{Ip: "10.1.1.1", Port: 80, Protocol: types.Protocol_TCP},
+				Config: &types.VirtualService_Config{Scheduler: "sh"},
+			}},
+			Servers: []*types.RealServer{{
+				ServiceID: "service1",
+				Key:       &types.RealServer_Key{Ip: "172.16.1.1", Port: 8080},
+				Config: &types.RealServer_Config{
+					Weight:  &wrappers.UInt32Value{Value: 1},
+					Forward: types.ForwardMethod_MASQ,
+				},
+			}},
+		}
+	})
+
+	expectInvalid := func(err error, msg string) {
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+		Expect(status.Convert(err).Message()).To(ContainSubstring(msg))
+	}
+
+	It("accepts a valid snapshot", func() {
+		Expect(Snapshot(snapshot, nil)).To(Succeed())
+	})
+
+	It("rejects invalid services, naming the service", func() {
+		snapshot.Services[0].Config.Scheduler = ""
+
+		expectInvalid(Snapshot(snapshot, nil), "service service1: service scheduler required")
+	})
+
+	It("accepts the named flags of the scheduler", func() {
+		snapshot.Services[0].Config.Flags = []string{"sh-port", "flag-3"}
+
+		Expect(Snapshot(snapshot, nil)).To(Succeed())
+	})
+
+	It("rejects flags the scheduler doesn't have", func() {
+		snapshot.Services[0].Config.Scheduler = "wrr"
+		snapshot.Services[0].Config.Flags = []string{"sh-port"}
+
+		expectInvalid(Snapshot(snapshot, nil), `unknown flag "sh-port" of scheduler wrr, expected one of flag-1`)
+	})
+
+	It("only accepts one-packet scheduling of UDP services", func() {
+		snapshot.Services[0].Config.Ops = true
+
+		expectInvalid(Snapshot(snapshot, nil), "one-packet scheduling is only possible with UDP services")
+
+		snapshot.Services[0].Key.Protocol = types.Protocol_UDP
+		Expect(Snapshot(snapshot, nil)).To(Succeed())
+	})
+
+	It("rejects invalid node selectors", func() {
+		snapshot.Services[0].NodeSelector = "pool=edge pool"
+
+		expectInvalid(Snapshot(snapshot, nil), "service service1: invalid node selector")
+	})
+
+	It("rejects update masks, which are only for updates", func() {
+		snapshot.Servers[0].UpdateMask = &field_mask.FieldMask{Paths: []string{"config.weight"}}
+
+		expectInvalid(Snapshot(snapshot, nil), "update mask is only allowed when updating a server")
+	})
+
+	It("rejects invalid servers, naming the server", func() {
+		snapshot.Servers[0].Config.Forward = types.ForwardMethod_UNSET_FORWARD_METHOD
+
+		expectInvalid(Snapshot(snapshot, nil), "server service1/172.16.1.1:8080: server forward method required")
+	})
+
+	It("rejects invalid server labels", func() {
+		snapshot.Servers[0].Labels = map[string]string{"rack": "r 1"}
+
+		expectInvalid(Snapshot(snapshot, nil), "invalid value \"r 1\" for label rack")
+	})
+
+	It("rejects duplicates", func() {
+		snapshot.Servers = append(snapshot.Servers, snapshot.Servers[0])
+
+		expectInvalid(Snapshot(snapshot, nil), "duplicate server")
+	})
+
+	It("rejects servers with the address of their service", func() {
+		snapshot.Servers[0].Key = &types.RealServer_Key{Ip: "10.1.1.1", Port: 80}
+
+		expectInvalid(Snapshot(snapshot, nil), "server service1/10.1.1.1:80 has the address of its service")
+	})
+
+	It("accepts servers with the address of their service forwarded with LOCALNODE", func() {
+		snapshot.Servers[0].Key = &types.RealServer_Key{Ip: "10.1.1.1", Port: 80}
+		snapshot.Servers[0].Config.Forward = types.ForwardMethod_LOCALNODE
+
+		Expect(Snapshot(snapshot, nil)).To(Succeed())
+	})
+
+	It("rejects servers with a different port to their service, unless forwarded with MASQ", func() {
+		for _, forward := range []types.ForwardMethod{types.ForwardMethod_ROUTE, types.ForwardMethod_TUNNEL,
+			types.ForwardMethod_LOCALNODE} {
+			snapshot.Servers[0].Config.Forward = forward
+
+			expectInvalid(Snapshot(snapshot, nil),
+				"server service1/172.16.1.1:8080 has port 8080 instead of port 80 of its service")
+		}
+
+		snapshot.Servers[0].Key.Port = 80
+		Expect(Snapshot(snapshot, nil)).To(Succeed())
+	})
+
+	It("only accepts tunnel options for TUNNEL servers", func() {
+		snapshot.Servers[0].Key.Port = 80
+		snapshot.Servers[0].Config.TunnelType = types.TunnelType_GUE
+		snapshot.Servers[0].Config.TunnelPort = 6080
+
+		expectInvalid(Snapshot(snapshot, nil), "tunnel options are only possible with TUNNEL servers, not MASQ")
+
+		snapshot.Servers[0].Config.Forward = types.ForwardMethod_TUNNEL
+		Expect(Snapshot(snapshot, nil)).To(Succeed())
+	})
+
+	It("requires a tunnel port for GUE, and only accepts a port and checksum with it", func() {
+		snapshot.Servers[0].Key.Port = 80
+		snapshot.Servers[0].Config.Forward = types.ForwardMethod_TUNNEL
+		snapshot.Servers[0].Config.TunnelChecksum = types.TunnelChecksum_REMOTE_CHECKSUM
+
+		expectInvalid(Snapshot(snapshot, nil), "tunnel port and checksum are only possible with GUE tunnels")
+
+		snapshot.Servers[0].Config.TunnelType = types.TunnelType_GUE
+		expectInvalid(Snapshot(snapshot, nil), "GUE tunnels require a tunnel port")
+
+		snapshot.Servers[0].Config.TunnelPort = 6080
+		Expect(Snapshot(snapshot, nil)).To(Succeed())
+	})
+
+	It("accepts tcp and udp health checks, and only an expected status for http ones", func() {
+		snapshot.Servers[0].HealthCheck = &types.RealServer_HealthCheck{
+			Endpoint:       &wrappers.StringValue{Value: "tcp://:8080"},
+			Period:         ptypes.DurationProto(time.Second),
+			Timeout:        ptypes.DurationProto(time.Second),
+			UpThreshold:    1,
+			DownThreshold:  1,
+			ExpectedStatus: 204,
+		}
+
+		expectInvalid(Snapshot(snapshot, nil), "health check expected status requires an http endpoint")
+
+		snapshot.Servers[0].HealthCheck.ExpectedStatus = 0
+		Expect(Snapshot(snapshot, nil)).To(Succeed())
+		snapshot.Servers[0].HealthCheck.Endpoint.Value = "udp://:8080"
+		Expect(Snapshot(snapshot, nil)).To(Succeed())
+
+		snapshot.Servers[0].HealthCheck.Endpoint.Value = "http://:8080/health"
+		snapshot.Servers[0].HealthCheck.ExpectedStatus = 600
+		expectInvalid(Snapshot(snapshot, nil), "health check expected status 600 must be from 100 to 599")
+		snapshot.Servers[0].HealthCheck.ExpectedStatus = 204
+		Expect(Snapshot(snapshot, nil)).To(Succeed())
+	})
+
+	It("rejects servers which are other virtual services", func() {
+		snapshot.Services = append(snapshot.Services, &types.VirtualService{
+			Id:     "service2",
+			Key:    &types.VirtualService_Key{Ip: "172.16.1.1", Port: 8080, Protocol: types.Protocol_TCP},
+			Config: &types.VirtualService_Config{Scheduler: "sh"},
+		})
+		snapshot.Servers[0].Config.Forward = types.ForwardMethod_LOCALNODE
+
+		expectInvalid(Snapshot(snapshot, nil), "server service1/172.16.1.1:8080 is the virtual service service2")
+	})
+
+	It("rejects servers of missing services", func() {
+		snapshot.Services = nil
+
+		expectInvalid(Snapshot(snapshot, nil), `service "service1" of server`)
+	})
+
+	It("accepts servers of services which exist elsewhere", func() {
+		snapshot.Services = nil
+
+		Expect(Snapshot(snapshot, func(id string) bool { return id == "service1" })).To(Succeed())
+	})
+})
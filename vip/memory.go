@@ -0,0 +1,61 @@
+package vip
+
+import (
+	"context"
+	"net"
+	"sync"
+	"syscall"
+)
+
+type memory struct {
+	mu    sync.Mutex
+	addrs []net.IP
+}
+
+// NewMemory returns an Interface which keeps addresses in memory instead of the kernel, for tests which configure
+// VIPs without root. Errors match the kernel's.
+func NewMemory() Interface {
+	return &memory{}
+}
+
+func (m *memory) Close() {}
+
+func (m *memory) Ensure(context.Context) error {
+	return nil
+}
+
+func (m *memory) List(context.Context) ([]net.IP, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]net.IP(nil), m.addrs...), nil
+}
+
+func (m *memory) Add(_ context.Context, ip net.IP) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.find(ip) >= 0 {
+		return syscall.EEXIST
+	}
+	m.addrs = append(m.addrs, ip)
+	return nil
+}
+
+func (m *memory) Delete(_ context.Context, ip net.IP) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := m.find(ip)
+	if i < 0 {
+		return syscall.EADDRNOTAVAIL
+	}
+	m.addrs = append(m.addrs[:i], m.addrs[i+1:]...)
+	return nil
+}
+
+func (m *memory) find(ip net.IP) int {
+	for i, addr := range m.addrs {
+		if addr.Equal(ip) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,176 @@
+package vip
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// iflaInfoKind is found in if_link.h.
+const iflaInfoKind = 1
+
+// link is an Interface of the kernel, configured over rtnetlink.
+type link struct {
+	name string
+	mu   sync.Mutex
+	fd   int
+	seq  uint32
+}
+
+// New returns the kernel's interface of the given name, which Ensure creates if it doesn't exist. Call Close() to
+// release its netlink socket.
+func New(name string) (Interface, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW|syscall.SOCK_CLOEXEC, syscall.NETLINK_ROUTE)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open netlink socket: %v", err)
+	}
+	if err := syscall.Bind(fd, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("unable to bind netlink socket: %v", err)
+	}
+	return &link{name: name, fd: fd}, nil
+}
+
+func (l *link) Close() {
+	syscall.Close(l.fd)
+}
+
+func (l *link) Ensure(context.Context) error {
+	ifi, err := net.InterfaceByName(l.name)
+	if err != nil {
+		log.Infof("Creating dummy interface %s", l.name)
+		err := l.request(syscall.RTM_NEWLINK, syscall.NLM_F_CREATE|syscall.NLM_F_EXCL, ifInfomsg(0, 0),
+			rtAttr(syscall.IFLA_IFNAME, append([]byte(l.name), 0)),
+			rtAttr(syscall.IFLA_LINKINFO, rtAttr(iflaInfoKind, []byte("dummy"))))
+		if err != nil {
+			return fmt.Errorf("unable to create dummy interface %s: %v", l.name, err)
+		}
+		if ifi, err = net.InterfaceByName(l.name); err != nil {
+			return err
+		}
+	}
+	if ifi.Flags&net.FlagUp == 0 {
+		log.Infof("Bringing up interface %s", l.name)
+		if err := l.request(syscall.RTM_NEWLINK, 0, ifInfomsg(ifi.Index, syscall.IFF_UP)); err != nil {
+			return fmt.Errorf("unable to bring up interface %s: %v", l.name, err)
+		}
+	}
+	return nil
+}
+
+func (l *link) List(context.Context) ([]net.IP, error) {
+	ifi, err := net.InterfaceByName(l.name)
+	if err != nil {
+		return nil, err
+	}
+	addrs, err := ifi.Addrs()
+	if err != nil {
+		return nil, err
+	}
+	var ips []net.IP
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && isHostAddress(ipNet) {
+			ips = append(ips, ipNet.IP)
+		}
+	}
+	return ips, nil
+}
+
+func (l *link) Add(_ context.Context, ip net.IP) error {
+	return l.requestAddress(syscall.RTM_NEWADDR, syscall.NLM_F_CREATE|syscall.NLM_F_EXCL, ip)
+}
+
+func (l *link) Delete(_ context.Context, ip net.IP) error {
+	return l.requestAddress(syscall.RTM_DELADDR, 0, ip)
+}
+
+// requestAddress adds or deletes ip as a host address. IPv6 addresses skip duplicate address detection, as VIPs are
+// expected to be on several nodes.
+func (l *link) requestAddress(typ, flags uint16, ip net.IP) error {
+	ifi, err := net.InterfaceByName(l.name)
+	if err != nil {
+		return err
+	}
+	msg := syscall.IfAddrmsg{Family: syscall.AF_INET, Prefixlen: 8 * net.IPv4len, Index: uint32(ifi.Index)}
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	} else {
+		msg.Family, msg.Prefixlen, msg.Flags = syscall.AF_INET6, 8*net.IPv6len, syscall.IFA_F_NODAD
+		ip = ip.To16()
+	}
+	b := make([]byte, syscall.SizeofIfAddrmsg)
+	*(*syscall.IfAddrmsg)(unsafe.Pointer(&b[0])) = msg
+	return l.request(typ, flags, b, rtAttr(syscall.IFA_LOCAL, ip), rtAttr(syscall.IFA_ADDRESS, ip))
+}
+
+// request sends an rtnetlink message, waiting for it to be acked.
+func (l *link) request(typ, flags uint16, data ...[]byte) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.seq++
+	msg := make([]byte, syscall.NLMSG_HDRLEN)
+	for _, d := range data {
+		msg = append(msg, d...)
+	}
+	*(*syscall.NlMsghdr)(unsafe.Pointer(&msg[0])) = syscall.NlMsghdr{
+		Len:   uint32(len(msg)),
+		Type:  typ,
+		Flags: syscall.NLM_F_REQUEST | syscall.NLM_F_ACK | flags,
+		Seq:   l.seq,
+	}
+	if err := syscall.Sendto(l.fd, msg, 0, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}); err != nil {
+		return err
+	}
+
+	buf := make([]byte, syscall.Getpagesize())
+	for {
+		n, _, err := syscall.Recvfrom(l.fd, buf, 0)
+		if err != nil {
+			return err
+		}
+		msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			return err
+		}
+		for _, m := range msgs {
+			if m.Header.Seq != l.seq || m.Header.Type != syscall.NLMSG_ERROR {
+				continue
+			}
+			if len(m.Data) < 4 {
+				return errors.New("truncated netlink error")
+			}
+			if errno := *(*int32)(unsafe.Pointer(&m.Data[0])); errno != 0 {
+				return syscall.Errno(-errno)
+			}
+			return nil
+		}
+	}
+}
+
+func ifInfomsg(index int, flags uint32) []byte {
+	b := make([]byte, syscall.SizeofIfInfomsg)
+	*(*syscall.IfInfomsg)(unsafe.Pointer(&b[0])) = syscall.IfInfomsg{
+		Family: syscall.AF_UNSPEC,
+		Index:  int32(index),
+		Flags:  flags,
+		Change: flags,
+	}
+	return b
+}
+
+// rtAttr returns an attribute holding data, or the attributes nested in it.
+func rtAttr(typ uint16, data ...[]byte) []byte {
+	attr := make([]byte, syscall.SizeofRtAttr)
+	for _, d := range data {
+		attr = append(attr, d...)
+	}
+	*(*syscall.RtAttr)(unsafe.Pointer(&attr[0])) = syscall.RtAttr{Len: uint16(len(attr)), Type: typ}
+	aligned := (len(attr) + syscall.RTA_ALIGNTO - 1) &^ (syscall.RTA_ALIGNTO - 1)
+	return append(attr, make([]byte, aligned-len(attr))...)
+}
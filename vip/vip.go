@@ -0,0 +1,76 @@
+// Package vip configures the VIPs of services on a local interface, so the node accepts the traffic IPVS balances
+// without a separate tool bringing the VIPs up.
+package vip
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Interface the VIPs are configured on.
+type Interface interface {
+	Close()
+	// Ensure the interface exists and is up, creating it as a dummy interface if it doesn't exist.
+	Ensure(ctx context.Context) error
+	// List the host addresses of the interface, which are /32 or /128. Loopback and link-local addresses aren't
+	// listed, as they're never VIPs.
+	List(ctx context.Context) ([]net.IP, error)
+	Add(ctx context.Context, ip net.IP) error
+	Delete(ctx context.Context, ip net.IP) error
+}
+
+// Sync the host addresses of the interface with vips, adding those which are missing and deleting the rest. Every
+// address is tried, returning an error for those which failed.
+func Sync(ctx context.Context, i Interface, vips []string) error {
+	if err := i.Ensure(ctx); err != nil {
+		return err
+	}
+	current, err := i.List(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to list addresses: %v", err)
+	}
+
+	desired := make(map[string]net.IP)
+	for _, vip := range vips {
+		if ip := net.ParseIP(vip); ip != nil {
+			desired[ip.String()] = ip
+		}
+	}
+	var errs []string
+	for _, ip := range current {
+		if _, ok := desired[ip.String()]; ok {
+			delete(desired, ip.String())
+			continue
+		}
+		log.Infof("Deleting VIP %s", ip)
+		if err := i.Delete(ctx, ip); err != nil {
+			errs = append(errs, fmt.Sprintf("unable to delete %s: %v", ip, err))
+		}
+	}
+	var missing []string
+	for vip := range desired {
+		missing = append(missing, vip)
+	}
+	sort.Strings(missing)
+	for _, vip := range missing {
+		log.Infof("Adding VIP %s", vip)
+		if err := i.Add(ctx, desired[vip]); err != nil {
+			errs = append(errs, fmt.Sprintf("unable to add %s: %v", vip, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to sync VIPs: %s", strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// isHostAddress returns true if addr is a host address which could be a VIP.
+func isHostAddress(addr *net.IPNet) bool {
+	ones, bits := addr.Mask.Size()
+	return ones == bits && !addr.IP.IsLoopback() && !addr.IP.IsLinkLocalUnicast()
+}
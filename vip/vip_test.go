@@ -0,0 +1,91 @@
+package vip
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+func TestVIP(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "VIP Suite")
+}
+
+// failingInterface fails to add addresses.
+type failingInterface struct {
+	Interface
+}
+
+func (failingInterface) Add(context.Context, net.IP) error {
+	return errors.New("no such device")
+}
+
+var _ = Describe("VIP", func() {
+	var (
+		ctx = context.Background()
+		i   Interface
+	)
+
+	list := func() []string {
+		ips, err := i.List(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		var addrs []string
+		for _, ip := range ips {
+			addrs = append(addrs, ip.String())
+		}
+		return addrs
+	}
+
+	BeforeEach(func() {
+		i = NewMemory()
+	})
+
+	It("should add missing VIPs and delete the rest", func() {
+		Expect(i.Add(ctx, net.ParseIP("10.0.0.1"))).To(Succeed())
+		Expect(i.Add(ctx, net.ParseIP("10.0.0.2"))).To(Succeed())
+
+		Expect(Sync(ctx, i, []string{"10.0.0.2", "10.0.0.3", "2001:db8::1", "10.0.0.3"})).To(Succeed())
+
+		Expect(list()).To(ConsistOf("10.0.0.2", "10.0.0.3", "2001:db8::1"))
+	})
+
+	It("should compare VIPs as IPs", func() {
+		Expect(i.Add(ctx, net.ParseIP("2001:db8::1"))).To(Succeed())
+
+		Expect(Sync(ctx, i, []string{"2001:0db8:0000::1"})).To(Succeed())
+
+		Expect(list()).To(ConsistOf("2001:db8::1"))
+	})
+
+	It("should try every VIP, returning the failures", func() {
+		Expect(i.Add(ctx, net.ParseIP("10.0.0.1"))).To(Succeed())
+
+		err := Sync(ctx, failingInterface{i}, []string{"10.0.0.2"})
+
+		Expect(err).To(MatchError("failed to sync VIPs: unable to add 10.0.0.2: no such device"))
+		Expect(list()).To(BeEmpty())
+	})
+
+	DescribeTable("host addresses", func(cidr string, host bool) {
+		ip, ipNet, err := net.ParseCIDR(cidr)
+		Expect(err).ToNot(HaveOccurred())
+		ipNet.IP = ip
+		Expect(isHostAddress(ipNet)).To(Equal(host))
+	},
+		Entry("IPv4", "10.0.0.1/32", true),
+		Entry("IPv6", "2001:db8::1/128", true),
+		Entry("subnet", "10.0.0.1/24", false),
+		Entry("loopback", "127.0.0.1/32", false),
+		Entry("IPv6 loopback", "::1/128", false),
+		Entry("link-local", "fe80::1/128", false),
+	)
+
+	It("should pad netlink attributes to 4 bytes", func() {
+		Expect(rtAttr(1, []byte("dummy"))).To(HaveLen(12))
+	})
+})